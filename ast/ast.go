@@ -131,10 +131,11 @@ type StringLiteral struct {
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string {
+	value := strings.ReplaceAll(sl.Value, "'", "''")
 	if sl.Unicode {
-		return "N'" + sl.Value + "'"
+		return "N'" + value + "'"
 	}
-	return "'" + sl.Value + "'"
+	return "'" + value + "'"
 }
 
 // NullLiteral represents a NULL literal.
@@ -497,7 +498,9 @@ type FunctionCall struct {
 	Token       token.Token
 	Function    Expression
 	Arguments   []Expression
+	Distinct    bool           // For aggregates such as COUNT(DISTINCT x)
 	WithinGroup []*OrderByItem // For WITHIN GROUP (ORDER BY ...) - ordered-set aggregates
+	GraphPath   bool           // WITHIN GROUP (GRAPH PATH) - aggregates over a SHORTEST_PATH
 	Over        *OverClause
 }
 
@@ -508,7 +511,11 @@ func (fc *FunctionCall) String() string {
 	for _, a := range fc.Arguments {
 		args = append(args, a.String())
 	}
-	result := fc.Function.String() + "(" + strings.Join(args, ", ") + ")"
+	distinct := ""
+	if fc.Distinct {
+		distinct = "DISTINCT "
+	}
+	result := fc.Function.String() + "(" + distinct + strings.Join(args, ", ") + ")"
 	if len(fc.WithinGroup) > 0 {
 		result += " WITHIN GROUP (ORDER BY "
 		var orderParts []string
@@ -517,6 +524,9 @@ func (fc *FunctionCall) String() string {
 		}
 		result += strings.Join(orderParts, ", ") + ")"
 	}
+	if fc.GraphPath {
+		result += " WITHIN GROUP (GRAPH PATH)"
+	}
 	if fc.Over != nil {
 		result += " " + fc.Over.String()
 	}
@@ -853,16 +863,7 @@ func (ss *SelectStatement) String() string {
 		out.WriteString(ss.ForClause.String())
 	}
 
-	if len(ss.Options) > 0 {
-		out.WriteString(" OPTION (")
-		for i, opt := range ss.Options {
-			if i > 0 {
-				out.WriteString(", ")
-			}
-			out.WriteString(opt.String())
-		}
-		out.WriteString(")")
-	}
+	out.WriteString(queryOptions(ss.Options))
 
 	if ss.Union != nil {
 		out.WriteString(" ")
@@ -876,10 +877,24 @@ func (ss *SelectStatement) String() string {
 type QueryOption struct {
 	Name        string             // RECOMPILE, MAXDOP, HASH JOIN, USE HINT, OPTIMIZE FOR, etc.
 	Value       Expression         // Optional value (e.g., 4 for MAXDOP 4)
+	Equals      bool               // Name = Value, as in MAX_GRANT_PERCENT = 25 or LABEL = 'load'
 	Hints       []string           // For USE HINT('hint1', 'hint2')
 	OptimizeFor []*OptimizeForHint // For OPTIMIZE FOR (@var = value, @var UNKNOWN)
 }
 
+// queryOptions prints an OPTION clause with a leading space, or nothing
+// when there are no options.
+func queryOptions(options []*QueryOption) string {
+	if len(options) == 0 {
+		return ""
+	}
+	parts := make([]string, len(options))
+	for i, opt := range options {
+		parts[i] = opt.String()
+	}
+	return " OPTION (" + strings.Join(parts, ", ") + ")"
+}
+
 // OptimizeForHint represents a single OPTIMIZE FOR hint binding
 type OptimizeForHint struct {
 	Variable string
@@ -906,6 +921,9 @@ func (qo *QueryOption) String() string {
 		}
 		return "OPTIMIZE FOR (" + strings.Join(bindings, ", ") + ")"
 	}
+	if qo.Value != nil && qo.Equals {
+		return qo.Name + " = " + qo.Value.String()
+	}
 	if qo.Value != nil {
 		return qo.Name + " " + qo.Value.String()
 	}
@@ -1027,6 +1045,7 @@ type TableName struct {
 	Hints          []string
 	TemporalClause *TemporalClause
 	TableSample    *TableSampleClause
+	ForPath        bool // FOR PATH: a node or edge table matched by SHORTEST_PATH
 }
 
 // TableSampleClause represents TABLESAMPLE (n PERCENT) or TABLESAMPLE (n ROWS)
@@ -1074,6 +1093,9 @@ func (tn *TableName) String() string {
 	if tn.TemporalClause != nil {
 		result += " " + tn.TemporalClause.String()
 	}
+	if tn.ForPath {
+		result += " FOR PATH"
+	}
 	if tn.Alias != nil {
 		result += " AS " + tn.Alias.Value
 	}
@@ -1266,6 +1288,85 @@ func (tvf *TableValuedFunction) String() string {
 	return out.String()
 }
 
+// BulkRowset represents OPENROWSET(BULK ...), which reads files as a
+// table: OPENROWSET(BULK 'path', FORMAT = 'PARQUET') WITH (columns) AS t.
+type BulkRowset struct {
+	Token         token.Token
+	Paths         []Expression    // BULK 'path' or BULK ('path', ...)
+	Options       OptionList      // SINGLE_CLOB, FORMATFILE = '...', DATA_SOURCE = '...', ...
+	Columns       []*RowsetColumn // WITH (...)
+	Alias         *Identifier
+	ColumnAliases []*Identifier
+}
+
+func (br *BulkRowset) tableRefNode()        {}
+func (br *BulkRowset) TokenLiteral() string { return br.Token.Literal }
+func (br *BulkRowset) String() string {
+	var out strings.Builder
+	out.WriteString("OPENROWSET(BULK ")
+	paths := make([]string, len(br.Paths))
+	for i, path := range br.Paths {
+		paths[i] = path.String()
+	}
+	if len(paths) == 1 {
+		out.WriteString(paths[0])
+	} else {
+		out.WriteString("(" + strings.Join(paths, ", ") + ")")
+	}
+	for _, opt := range br.Options {
+		out.WriteString(", ")
+		out.WriteString(opt.String())
+	}
+	out.WriteString(")")
+	if len(br.Columns) > 0 {
+		cols := make([]string, len(br.Columns))
+		for i, col := range br.Columns {
+			cols[i] = col.String()
+		}
+		out.WriteString(" WITH (" + strings.Join(cols, ", ") + ")")
+	}
+	if br.Alias != nil {
+		out.WriteString(" AS ")
+		out.WriteString(br.Alias.Value)
+		if len(br.ColumnAliases) > 0 {
+			names := make([]string, len(br.ColumnAliases))
+			for i, col := range br.ColumnAliases {
+				names[i] = col.Value
+			}
+			out.WriteString("(" + strings.Join(names, ", ") + ")")
+		}
+	}
+	return out.String()
+}
+
+// RowsetColumn is a column in the WITH schema of OPENROWSET(BULK ...).
+type RowsetColumn struct {
+	Name      string
+	DataType  *DataType
+	Collation string
+	Path      string // JSON path or column name in the file, like '$.id'
+	Ordinal   int    // position of the column in the file; 0 if not given
+}
+
+func (rc *RowsetColumn) String() string {
+	var out strings.Builder
+	out.WriteString(rc.Name)
+	out.WriteString(" ")
+	out.WriteString(rc.DataType.String())
+	if rc.Collation != "" {
+		out.WriteString(" COLLATE ")
+		out.WriteString(rc.Collation)
+	}
+	if rc.Path != "" {
+		out.WriteString(" '")
+		out.WriteString(rc.Path)
+		out.WriteString("'")
+	} else if rc.Ordinal > 0 {
+		fmt.Fprintf(&out, " %d", rc.Ordinal)
+	}
+	return out.String()
+}
+
 // PivotTable represents a PIVOT table operation.
 // Syntax: source PIVOT (aggregate(value_col) FOR pivot_col IN ([v1], [v2], ...)) AS alias
 type PivotTable struct {
@@ -1503,6 +1604,7 @@ type UpdateStatement struct {
 	Where           Expression
 	CurrentOfCursor *Identifier // WHERE CURRENT OF cursor_name
 	Output          *OutputClause
+	Options         []*QueryOption // OPTION (LABEL = '...', MAXDOP 4, etc.)
 }
 
 type SetClause struct {
@@ -1565,6 +1667,7 @@ func (us *UpdateStatement) String() string {
 		out.WriteString(" WHERE CURRENT OF ")
 		out.WriteString(us.CurrentOfCursor.String())
 	}
+	out.WriteString(queryOptions(us.Options))
 
 	return out.String()
 }
@@ -1581,6 +1684,7 @@ type DeleteStatement struct {
 	Where           Expression
 	CurrentOfCursor *Identifier // WHERE CURRENT OF cursor_name
 	Output          *OutputClause
+	Options         []*QueryOption // OPTION (LABEL = '...', MAXDOP 4, etc.)
 }
 
 func (ds *DeleteStatement) statementNode()       {}
@@ -1628,6 +1732,7 @@ func (ds *DeleteStatement) String() string {
 		out.WriteString(" WHERE CURRENT OF ")
 		out.WriteString(ds.CurrentOfCursor.String())
 	}
+	out.WriteString(queryOptions(ds.Options))
 
 	return out.String()
 }
@@ -1699,6 +1804,7 @@ type MergeStatement struct {
 	OnCondition Expression
 	WhenClauses []*MergeWhenClause
 	Output      *OutputClause
+	Options     []*QueryOption // OPTION (LABEL = '...', MAXDOP 4, etc.)
 }
 
 func (ms *MergeStatement) statementNode()       {}
@@ -1729,6 +1835,7 @@ func (ms *MergeStatement) String() string {
 		out.WriteString(" ")
 		out.WriteString(ms.Output.String())
 	}
+	out.WriteString(queryOptions(ms.Options))
 
 	return out.String()
 }
@@ -1855,12 +1962,13 @@ func (pd *ParameterDef) String() string {
 
 // DataType represents a T-SQL data type.
 type DataType struct {
-	Name      string
-	Length    *int
-	Precision *int
-	Scale     *int
-	Max       bool
-	XmlSchema string // For typed XML: XML(schema_name)
+	Name        string
+	Length      *int
+	Precision   *int
+	Scale       *int
+	Max         bool
+	XmlSchema   string // For typed XML: XML(schema_name)
+	ElementType string // For VECTOR(dimensions, float16); the dimensions are the Precision
 }
 
 func (dt *DataType) String() string {
@@ -1869,6 +1977,8 @@ func (dt *DataType) String() string {
 		result += "(" + dt.XmlSchema + ")"
 	} else if dt.Max {
 		result += "(MAX)"
+	} else if dt.Precision != nil && dt.ElementType != "" {
+		result += "(" + itoa(*dt.Precision) + ", " + dt.ElementType + ")"
 	} else if dt.Precision != nil && dt.Scale != nil {
 		result += "(" + itoa(*dt.Precision) + ", " + itoa(*dt.Scale) + ")"
 	} else if dt.Length != nil {
@@ -1926,6 +2036,7 @@ func (ds *DeclareStatement) String() string {
 type SetStatement struct {
 	Token    token.Token
 	Variable Expression
+	Operator string // "=" or "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^="
 	Value    Expression
 	Option   string // For SET options like NOCOUNT, etc.
 	OnOff    string // ON or OFF for SET options
@@ -1941,7 +2052,11 @@ func (ss *SetStatement) String() string {
 	if ss.Value == nil {
 		return "SET " + ss.Variable.String()
 	}
-	return "SET " + ss.Variable.String() + " = " + ss.Value.String()
+	op := ss.Operator
+	if op == "" {
+		op = "="
+	}
+	return "SET " + ss.Variable.String() + " " + op + " " + ss.Value.String()
 }
 
 // IfStatement represents an IF statement.
@@ -1987,15 +2102,29 @@ func (ws *WhileStatement) String() string {
 
 // BeginEndBlock represents a BEGIN...END block.
 type BeginEndBlock struct {
-	Token      token.Token
-	Statements []Statement
+	Token         token.Token
+	Statements    []Statement
+	IsAtomic      bool       // BEGIN ATOMIC in natively compiled modules
+	AtomicOptions OptionList // WITH (TRANSACTION ISOLATION LEVEL = ..., LANGUAGE = ...)
+
+	// Atomic is read from AtomicOptions, which String prints; it is nil
+	// when the block has no WITH clause.
+	Atomic *AtomicBlockOptions
 }
 
 func (be *BeginEndBlock) statementNode()       {}
 func (be *BeginEndBlock) TokenLiteral() string { return be.Token.Literal }
 func (be *BeginEndBlock) String() string {
 	var out strings.Builder
-	out.WriteString("BEGIN\n")
+	out.WriteString("BEGIN")
+	if be.IsAtomic {
+		out.WriteString(" ATOMIC")
+		if be.AtomicOptions != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(be.AtomicOptions.String())
+		}
+	}
+	out.WriteString("\n")
 	for _, s := range be.Statements {
 		out.WriteString("    ")
 		out.WriteString(s.String())
@@ -2412,6 +2541,65 @@ func (es *ExpressionStatement) String() string {
 // Stage 1: Table Infrastructure
 // -----------------------------------------------------------------------------
 
+// Option is one entry of a WITH (...) option list, such as FILLFACTOR = 80,
+// ONLINE = ON (MAXDOP = 4) or ACTIVATION (STATUS = ON).
+type Option struct {
+	Token  token.Token
+	Name   string     // As written; names of several words are joined by spaces, e.g. TRANSACTION ISOLATION LEVEL
+	Equals bool       // Name = ..., also when the value is a list, as in BOUNDING_BOX = (0, 0, 100, 100)
+	Value  Expression // nil for a bare name; keywords such as ON or SNAPSHOT are an *Identifier
+	List   OptionList // Nested list following the name, the = or the value
+}
+
+func (o *Option) String() string {
+	var out strings.Builder
+	out.WriteString(o.Name)
+	if o.Equals {
+		out.WriteString(" =")
+	}
+	if o.Value != nil {
+		if out.Len() > 0 {
+			out.WriteString(" ")
+		}
+		out.WriteString(o.Value.String())
+	}
+	if o.List != nil {
+		if out.Len() > 0 {
+			out.WriteString(" ")
+		}
+		out.WriteString(o.List.String())
+	}
+	return out.String()
+}
+
+// OptionList is a parenthesized list of options as it appears after WITH in
+// index, table, column and module definitions.
+type OptionList []*Option
+
+func (ol OptionList) String() string {
+	return "(" + ol.join() + ")"
+}
+
+// join prints the options without the parentheses, as after SET.
+func (ol OptionList) join() string {
+	parts := make([]string, len(ol))
+	for i, o := range ol {
+		parts[i] = o.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Get returns the option with the given name, compared without regard to
+// case, or nil.
+func (ol OptionList) Get(name string) *Option {
+	for _, o := range ol {
+		if strings.EqualFold(o.Name, name) {
+			return o
+		}
+	}
+	return nil
+}
+
 // ColumnDefinition represents a column definition in CREATE TABLE or table variable.
 type ColumnDefinition struct {
 	Token           token.Token
@@ -2419,6 +2607,7 @@ type ColumnDefinition struct {
 	DataType        *DataType
 	Nullable        *bool // nil = not specified, true = NULL, false = NOT NULL
 	Default         Expression
+	DefaultName     string // CONSTRAINT name DEFAULT ...
 	Identity        *IdentitySpec
 	IsRowGuidCol    bool // ROWGUIDCOL
 	IsSparse        bool // SPARSE
@@ -2427,14 +2616,20 @@ type ColumnDefinition struct {
 	Computed        Expression          // For computed columns: AS (expression)
 	IsPersisted     bool                // PERSISTED for computed columns
 	Constraints     []*ColumnConstraint // Inline constraints
-	GeneratedAlways string              // "ROW START" or "ROW END" for temporal tables
+	GeneratedAlways GeneratedAlwaysKind // GENERATED ALWAYS AS ROW START, ...
+	IsHidden        bool                // HIDDEN
 	InlineIndex     *InlineIndex        // INDEX index_name [CLUSTERED|NONCLUSTERED]
+	Masked          OptionList          // MASKED WITH (FUNCTION = '...')
+	Encrypted       OptionList          // ENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = ..., ...)
 }
 
 // InlineIndex represents an inline index definition on a column
 type InlineIndex struct {
-	Name      string
-	Clustered *bool // nil = not specified, true = CLUSTERED, false = NONCLUSTERED
+	Name        string
+	Clustered   *bool      // nil = not specified, true = CLUSTERED, false = NONCLUSTERED
+	IsHash      bool       // HASH (memory-optimized tables)
+	Options     OptionList // WITH (BUCKET_COUNT = ...)
+	BucketCount int64      // read from Options; 0 when not given
 }
 
 func (cd *ColumnDefinition) String() string {
@@ -2458,6 +2653,14 @@ func (cd *ColumnDefinition) String() string {
 		out.WriteString(cd.Collation)
 	}
 
+	if cd.GeneratedAlways != GeneratedNone {
+		out.WriteString(" GENERATED ALWAYS AS ")
+		out.WriteString(cd.GeneratedAlways.String())
+		if cd.IsHidden {
+			out.WriteString(" HIDDEN")
+		}
+	}
+
 	if cd.Nullable != nil {
 		if *cd.Nullable {
 			out.WriteString(" NULL")
@@ -2484,10 +2687,24 @@ func (cd *ColumnDefinition) String() string {
 	}
 
 	if cd.Default != nil {
+		if cd.DefaultName != "" {
+			out.WriteString(" CONSTRAINT ")
+			out.WriteString(cd.DefaultName)
+		}
 		out.WriteString(" DEFAULT ")
 		out.WriteString(cd.Default.String())
 	}
 
+	if cd.Masked != nil {
+		out.WriteString(" MASKED WITH ")
+		out.WriteString(cd.Masked.String())
+	}
+
+	if cd.Encrypted != nil {
+		out.WriteString(" ENCRYPTED WITH ")
+		out.WriteString(cd.Encrypted.String())
+	}
+
 	if cd.InlineIndex != nil {
 		out.WriteString(" INDEX ")
 		out.WriteString(cd.InlineIndex.Name)
@@ -2498,6 +2715,13 @@ func (cd *ColumnDefinition) String() string {
 				out.WriteString(" NONCLUSTERED")
 			}
 		}
+		if cd.InlineIndex.IsHash {
+			out.WriteString(" HASH")
+		}
+		if cd.InlineIndex.Options != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(cd.InlineIndex.Options.String())
+		}
 	}
 
 	for _, c := range cd.Constraints {
@@ -2529,6 +2753,9 @@ type ColumnConstraint struct {
 	CheckExpression   Expression
 	OnDelete          string // CASCADE, SET NULL, SET DEFAULT, NO ACTION
 	OnUpdate          string
+	IsHash            bool       // PRIMARY KEY NONCLUSTERED HASH
+	IndexOptions      OptionList // WITH (BUCKET_COUNT = ...)
+	BucketCount       int64      // read from IndexOptions; 0 when not given
 }
 
 type ConstraintType int
@@ -2539,8 +2766,9 @@ const (
 	ConstraintUnique
 	ConstraintCheck
 	ConstraintDefault
-	ConstraintPeriod // PERIOD FOR SYSTEM_TIME
-	ConstraintIndex  // INDEX ix_name (columns)
+	ConstraintPeriod     // PERIOD FOR SYSTEM_TIME
+	ConstraintIndex      // INDEX ix_name (columns)
+	ConstraintConnection // CONNECTION (node TO node, ...) on edge tables
 )
 
 func (cc *ColumnConstraint) String() string {
@@ -2562,6 +2790,13 @@ func (cc *ColumnConstraint) String() string {
 				out.WriteString(" NONCLUSTERED")
 			}
 		}
+		if cc.IsHash {
+			out.WriteString(" HASH")
+		}
+		if cc.IndexOptions != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(cc.IndexOptions.String())
+		}
 	case ConstraintUnique:
 		out.WriteString("UNIQUE")
 	case ConstraintCheck:
@@ -2601,7 +2836,9 @@ type TableConstraint struct {
 	Type              ConstraintType
 	Columns           []*IndexColumn // For PK, UNIQUE, FK
 	IsClustered       *bool
-	IndexOptions      string // WITH (FILLFACTOR = 90, etc.)
+	IsHash            bool       // HASH (memory-optimized tables)
+	IndexOptions      OptionList // WITH (FILLFACTOR = 90, etc.)
+	BucketCount       int64      // BUCKET_COUNT of a HASH index, read from IndexOptions
 	ReferencesTable   *QualifiedIdentifier
 	ReferencesColumns []*Identifier
 	CheckExpression   Expression
@@ -2609,12 +2846,13 @@ type TableConstraint struct {
 	ForColumn         *Identifier // For DEFAULT ... FOR column
 	OnDelete          string
 	OnUpdate          string
+	Connections       []*EdgeConnection // For CONNECTION constraints
 }
 
 func (tc *TableConstraint) String() string {
 	var out strings.Builder
 
-	if tc.Name != "" {
+	if tc.Name != "" && tc.Type != ConstraintIndex {
 		out.WriteString("CONSTRAINT ")
 		out.WriteString(tc.Name)
 		out.WriteString(" ")
@@ -2630,6 +2868,9 @@ func (tc *TableConstraint) String() string {
 				out.WriteString(" NONCLUSTERED")
 			}
 		}
+		if tc.IsHash {
+			out.WriteString(" HASH")
+		}
 		out.WriteString(" (")
 		for i, col := range tc.Columns {
 			if i > 0 {
@@ -2638,10 +2879,9 @@ func (tc *TableConstraint) String() string {
 			out.WriteString(col.String())
 		}
 		out.WriteString(")")
-		if tc.IndexOptions != "" {
-			out.WriteString(" WITH (")
-			out.WriteString(tc.IndexOptions)
-			out.WriteString(")")
+		if tc.IndexOptions != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(tc.IndexOptions.String())
 		}
 
 	case ConstraintUnique:
@@ -2653,6 +2893,9 @@ func (tc *TableConstraint) String() string {
 				out.WriteString(" NONCLUSTERED")
 			}
 		}
+		if tc.IsHash {
+			out.WriteString(" HASH")
+		}
 		out.WriteString(" (")
 		for i, col := range tc.Columns {
 			if i > 0 {
@@ -2661,10 +2904,9 @@ func (tc *TableConstraint) String() string {
 			out.WriteString(col.String())
 		}
 		out.WriteString(")")
-		if tc.IndexOptions != "" {
-			out.WriteString(" WITH (")
-			out.WriteString(tc.IndexOptions)
-			out.WriteString(")")
+		if tc.IndexOptions != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(tc.IndexOptions.String())
 		}
 
 	case ConstraintForeignKey:
@@ -2721,6 +2963,20 @@ func (tc *TableConstraint) String() string {
 		}
 		out.WriteString(")")
 
+	case ConstraintConnection:
+		out.WriteString("CONNECTION (")
+		for i, c := range tc.Connections {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(c.String())
+		}
+		out.WriteString(")")
+		if tc.OnDelete != "" {
+			out.WriteString(" ON DELETE ")
+			out.WriteString(tc.OnDelete)
+		}
+
 	case ConstraintIndex:
 		out.WriteString("INDEX ")
 		out.WriteString(tc.Name)
@@ -2731,6 +2987,9 @@ func (tc *TableConstraint) String() string {
 				out.WriteString(" NONCLUSTERED")
 			}
 		}
+		if tc.IsHash {
+			out.WriteString(" HASH")
+		}
 		out.WriteString(" (")
 		for i, col := range tc.Columns {
 			if i > 0 {
@@ -2739,6 +2998,10 @@ func (tc *TableConstraint) String() string {
 			out.WriteString(col.String())
 		}
 		out.WriteString(")")
+		if tc.IndexOptions != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(tc.IndexOptions.String())
+		}
 	}
 
 	return out.String()
@@ -2764,9 +3027,29 @@ type CreateTableStatement struct {
 	IsTemporary bool // #temp or ##global
 	Columns     []*ColumnDefinition
 	Constraints []*TableConstraint
-	AsSelect    *SelectStatement // CREATE TABLE ... AS SELECT
-	FileGroup   string           // ON [filegroup]
-	TextImageOn string           // TEXTIMAGE_ON [filegroup]
+	ColumnNames []*Identifier  // CREATE TABLE t (a, b) WITH (...) AS SELECT
+	AsSelect    Statement      // CREATE TABLE ... AS SELECT: SelectStatement or WithStatement (CTE)
+	FileGroup   string         // ON [filegroup]
+	TextImageOn string         // TEXTIMAGE_ON [filegroup]
+	IsFileTable bool           // AS FILETABLE
+	Options     OptionList     // WITH (SYSTEM_VERSIONING = ON, MEMORY_OPTIMIZED = ON, ...)
+	GraphKind   GraphTableKind // AS NODE or AS EDGE
+
+	// SystemVersioning and Ledger are read from Options, which String
+	// prints; they are nil when the option is not given.
+	SystemVersioning *SystemVersioning
+	Ledger           *LedgerOption
+
+	// Distribution, Index and Partition are the table options of Synapse
+	// dedicated pools, also read from Options.
+	Distribution *Distribution
+	Index        *TableIndex
+	Partition    *TablePartition
+
+	// MemoryOptimized and Durability are the In-Memory OLTP options, also
+	// read from Options.
+	MemoryOptimized bool
+	Durability      Durability
 }
 
 func (ct *CreateTableStatement) statementNode()       {}
@@ -2775,6 +3058,35 @@ func (ct *CreateTableStatement) String() string {
 	var out strings.Builder
 	out.WriteString("CREATE TABLE ")
 	out.WriteString(ct.Name.String())
+	if ct.IsFileTable {
+		out.WriteString(" AS FILETABLE")
+		if ct.Options != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(ct.Options.String())
+		}
+		return out.String()
+	}
+	if ct.GraphKind != GraphTableNone && len(ct.Columns) == 0 && len(ct.Constraints) == 0 {
+		out.WriteString(" AS ")
+		out.WriteString(ct.GraphKind.String())
+		return out.String()
+	}
+	if ct.AsSelect != nil {
+		if len(ct.ColumnNames) > 0 {
+			names := make([]string, len(ct.ColumnNames))
+			for i, name := range ct.ColumnNames {
+				names[i] = name.Value
+			}
+			out.WriteString(" (" + strings.Join(names, ", ") + ")")
+		}
+		if ct.Options != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(ct.Options.String())
+		}
+		out.WriteString(" AS ")
+		out.WriteString(ct.AsSelect.String())
+		return out.String()
+	}
 	out.WriteString(" (\n")
 
 	for i, col := range ct.Columns {
@@ -2796,6 +3108,10 @@ func (ct *CreateTableStatement) String() string {
 	}
 
 	out.WriteString(")")
+	if ct.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(ct.Options.String())
+	}
 	if ct.FileGroup != "" {
 		out.WriteString(" ON ")
 		out.WriteString(ct.FileGroup)
@@ -2804,6 +3120,10 @@ func (ct *CreateTableStatement) String() string {
 		out.WriteString(" TEXTIMAGE_ON ")
 		out.WriteString(ct.TextImageOn)
 	}
+	if ct.GraphKind != GraphTableNone {
+		out.WriteString(" AS ")
+		out.WriteString(ct.GraphKind.String())
+	}
 	return out.String()
 }
 
@@ -2894,14 +3214,21 @@ type AlterTableAction struct {
 	Columns        []*ColumnDefinition // For ADD with multiple columns
 	ColumnName     *Identifier
 	NewDataType    *DataType
+	Nullable       *bool  // For ALTER COLUMN: nil = not specified
+	Collation      string // For ALTER COLUMN ... COLLATE
 	Constraint     *TableConstraint
+	Constraints    []*TableConstraint // For ADD with multiple constraints
 	ConstraintName string
 	NewColumnName  *Identifier
-	TriggerName    string            // For ENABLE/DISABLE TRIGGER
-	AllTriggers    bool              // For ENABLE/DISABLE TRIGGER ALL
-	AllConstraints bool              // For CHECK/NOCHECK CONSTRAINT ALL
-	Options        map[string]string // For SET (option = value)
-	RawOptions     string            // For SWITCH, REBUILD, etc.
+	TriggerName    string     // For ENABLE/DISABLE TRIGGER
+	AllTriggers    bool       // For ENABLE/DISABLE TRIGGER ALL
+	AllConstraints bool       // For CHECK/NOCHECK CONSTRAINT ALL
+	Options        OptionList // For SET (option = value, ...)
+	RawOptions     string     // For SWITCH, REBUILD, etc.
+
+	// SystemVersioning is read from the options of SET (SYSTEM_VERSIONING
+	// = ...).
+	SystemVersioning *SystemVersioning
 }
 
 type AlterActionType int
@@ -2920,18 +3247,28 @@ const (
 	AlterNoCheckConstraint // NOCHECK CONSTRAINT name
 	AlterSwitch            // SWITCH [PARTITION n] TO target [PARTITION n]
 	AlterRebuild           // REBUILD
+	AlterDropPeriod        // DROP PERIOD FOR SYSTEM_TIME
 )
 
 func (aa *AlterTableAction) String() string {
 	switch aa.Type {
-	case AlterAddColumn:
-		return "ADD " + aa.Column.String()
+	case AlterAddColumn, AlterAddConstraint:
+		return "ADD " + aa.addedElements()
 	case AlterDropColumn:
 		return "DROP COLUMN " + aa.ColumnName.Value
 	case AlterAlterColumn:
-		return "ALTER COLUMN " + aa.ColumnName.Value + " " + aa.NewDataType.String()
-	case AlterAddConstraint:
-		return "ADD " + aa.Constraint.String()
+		result := "ALTER COLUMN " + aa.ColumnName.Value + " " + aa.NewDataType.String()
+		if aa.Collation != "" {
+			result += " COLLATE " + aa.Collation
+		}
+		if aa.Nullable != nil {
+			if *aa.Nullable {
+				result += " NULL"
+			} else {
+				result += " NOT NULL"
+			}
+		}
+		return result
 	case AlterDropConstraint:
 		return "DROP CONSTRAINT " + aa.ConstraintName
 	case AlterRenameColumn:
@@ -2947,11 +3284,7 @@ func (aa *AlterTableAction) String() string {
 		}
 		return "DISABLE TRIGGER " + aa.TriggerName
 	case AlterSetOption:
-		var parts []string
-		for k, v := range aa.Options {
-			parts = append(parts, k+" = "+v)
-		}
-		return "SET (" + strings.Join(parts, ", ") + ")"
+		return "SET " + aa.Options.String()
 	case AlterCheckConstraint:
 		if aa.AllConstraints {
 			return "CHECK CONSTRAINT ALL"
@@ -2969,10 +3302,33 @@ func (aa *AlterTableAction) String() string {
 			return "REBUILD " + aa.RawOptions
 		}
 		return "REBUILD"
+	case AlterDropPeriod:
+		return "DROP PERIOD FOR SYSTEM_TIME"
 	}
 	return ""
 }
 
+// addedElements lists the columns and constraints of an ADD action. An
+// action built by hand may set only Column or Constraint.
+func (aa *AlterTableAction) addedElements() string {
+	var parts []string
+	columns := aa.Columns
+	if len(columns) == 0 && aa.Column != nil {
+		columns = []*ColumnDefinition{aa.Column}
+	}
+	for _, col := range columns {
+		parts = append(parts, col.String())
+	}
+	constraints := aa.Constraints
+	if len(constraints) == 0 && aa.Constraint != nil {
+		constraints = []*TableConstraint{aa.Constraint}
+	}
+	for _, c := range constraints {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
 // TableTypeDefinition represents a TABLE type for table variables.
 type TableTypeDefinition struct {
 	Columns     []*ColumnDefinition
@@ -3242,7 +3598,7 @@ type CreateIndexStatement struct {
 	Columns        []*IndexColumn
 	IncludeColumns []*Identifier
 	Where          Expression
-	Options        []string    // WITH (options)
+	Options        OptionList  // WITH (options)
 	Filegroup      *Identifier // ON [filegroup]
 }
 
@@ -3287,6 +3643,10 @@ func (ci *CreateIndexStatement) String() string {
 		out.WriteString(" WHERE ")
 		out.WriteString(ci.Where.String())
 	}
+	if ci.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(ci.Options.String())
+	}
 	if ci.Filegroup != nil {
 		out.WriteString(" ON ")
 		out.WriteString(ci.Filegroup.Value)
@@ -3408,6 +3768,9 @@ type CreateTypeStatement struct {
 	BaseType    *DataType            // For alias types: FROM base_type
 	Nullable    *bool                // For alias types: NULL/NOT NULL
 	TableDef    *TableTypeDefinition // For table types: AS TABLE (...)
+	Options     OptionList           // For table types: WITH (MEMORY_OPTIMIZED = ON)
+
+	MemoryOptimized bool // read from Options
 }
 
 func (ct *CreateTypeStatement) statementNode()       {}
@@ -3420,6 +3783,10 @@ func (ct *CreateTypeStatement) String() string {
 		out.WriteString(" AS TABLE (")
 		// Table definition would be added here
 		out.WriteString("...)")
+		if ct.Options != nil {
+			out.WriteString(" WITH ")
+			out.WriteString(ct.Options.String())
+		}
 	} else {
 		out.WriteString(" FROM ")
 		out.WriteString(ct.BaseType.String())
@@ -3641,9 +4008,10 @@ type DropObjectStatement struct {
 	// For DROP INDEX: index name and table
 	IndexName *Identifier
 	TableName *QualifiedIdentifier
-	// For DROP TRIGGER: scope
+	// For DROP TRIGGER and DROP EVENT SESSION: scope
 	OnDatabase  bool // ON DATABASE
 	OnAllServer bool // ON ALL SERVER
+	OnServer    bool // ON SERVER
 }
 
 func (do *DropObjectStatement) statementNode()       {}
@@ -3675,6 +4043,9 @@ func (do *DropObjectStatement) String() string {
 	if do.OnAllServer {
 		out.WriteString(" ON ALL SERVER")
 	}
+	if do.OnServer {
+		out.WriteString(" ON SERVER")
+	}
 	return out.String()
 }
 
@@ -4960,7 +5331,7 @@ type CreateFulltextIndexStatement struct {
 	Columns     []*FulltextColumn // Column definitions
 	KeyIndex    string            // KEY INDEX index_name
 	OnCatalog   string            // ON catalog_name
-	WithOptions []string          // WITH options
+	WithOptions OptionList        // WITH (CHANGE_TRACKING = AUTO, ...)
 }
 
 // FulltextColumn represents a column in a fulltext index.
@@ -5007,6 +5378,10 @@ func (cfi *CreateFulltextIndexStatement) String() string {
 		out.WriteString(" ON ")
 		out.WriteString(cfi.OnCatalog)
 	}
+	if cfi.WithOptions != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(cfi.WithOptions.String())
+	}
 	return out.String()
 }
 
@@ -5939,3 +6314,1384 @@ func (ds *DbccStatement) String() string {
 	}
 	return out.String()
 }
+
+// -----------------------------------------------------------------------------
+// SQL Graph
+// -----------------------------------------------------------------------------
+
+// GraphTableKind tells a node table from an edge table.
+type GraphTableKind int
+
+const (
+	GraphTableNone GraphTableKind = iota
+	GraphTableNode                // AS NODE
+	GraphTableEdge                // AS EDGE
+)
+
+func (k GraphTableKind) String() string {
+	switch k {
+	case GraphTableNode:
+		return "NODE"
+	case GraphTableEdge:
+		return "EDGE"
+	}
+	return ""
+}
+
+// EdgeConnection is one pair of node tables an edge constraint allows an
+// edge to connect: CONNECTION (Person TO City).
+type EdgeConnection struct {
+	From *QualifiedIdentifier
+	To   *QualifiedIdentifier
+}
+
+func (ec *EdgeConnection) String() string {
+	return ec.From.String() + " TO " + ec.To.String()
+}
+
+// MatchExpression represents the MATCH predicate of a graph query, such as
+// MATCH(Person1-(FriendOf)->Person2 AND SHORTEST_PATH(...)).
+type MatchExpression struct {
+	Token    token.Token
+	Patterns []GraphPattern // Combined with AND
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	parts := make([]string, len(me.Patterns))
+	for i, p := range me.Patterns {
+		parts[i] = p.String()
+	}
+	return "MATCH(" + strings.Join(parts, " AND ") + ")"
+}
+
+// GraphPattern is a pattern of a MATCH predicate: a *GraphPath or a
+// *ShortestPath.
+type GraphPattern interface {
+	Node
+	graphPatternNode()
+}
+
+// GraphElement is a *GraphNode or a *GraphEdge of a path.
+type GraphElement interface {
+	Node
+	graphElementNode()
+}
+
+// GraphPath is a chain of nodes and edges, such as
+// Person1-(Likes)->Restaurant<-(LocatedIn)-City. Elements alternate between
+// nodes and edges, starting and ending with a node.
+type GraphPath struct {
+	Token    token.Token
+	Elements []GraphElement
+}
+
+func (gp *GraphPath) graphPatternNode()    {}
+func (gp *GraphPath) TokenLiteral() string { return gp.Token.Literal }
+func (gp *GraphPath) String() string {
+	var out strings.Builder
+	for _, e := range gp.Elements {
+		out.WriteString(e.String())
+	}
+	return out.String()
+}
+
+// GraphNode is the alias of a node table in a path, or LAST_NODE(alias), the
+// last node of a SHORTEST_PATH.
+type GraphNode struct {
+	Token    token.Token
+	Name     *Identifier
+	LastNode bool
+}
+
+func (gn *GraphNode) graphElementNode()    {}
+func (gn *GraphNode) TokenLiteral() string { return gn.Token.Literal }
+func (gn *GraphNode) String() string {
+	if gn.LastNode {
+		return "LAST_NODE(" + gn.Name.Value + ")"
+	}
+	return gn.Name.Value
+}
+
+// GraphDirection is the direction of an edge in a path.
+type GraphDirection int
+
+const (
+	GraphDirectionRight GraphDirection = iota // -(edge)->
+	GraphDirectionLeft                        // <-(edge)-
+)
+
+// GraphEdge is the alias of an edge table in a path.
+type GraphEdge struct {
+	Token     token.Token
+	Name      *Identifier
+	Direction GraphDirection
+}
+
+func (ge *GraphEdge) graphElementNode()    {}
+func (ge *GraphEdge) TokenLiteral() string { return ge.Token.Literal }
+func (ge *GraphEdge) String() string {
+	if ge.Direction == GraphDirectionLeft {
+		return "<-(" + ge.Name.Value + ")-"
+	}
+	return "-(" + ge.Name.Value + ")->"
+}
+
+// ShortestPath represents SHORTEST_PATH(Person1(-(FriendOf)->Person2)+): an
+// anchor node and a group of edges and nodes repeated any number of times
+// (+) or between MinHops and MaxHops times ({1,3}). The anchor is written
+// after the group when the path is matched right to left:
+// SHORTEST_PATH((Person2<-(FriendOf)-)+Person1).
+type ShortestPath struct {
+	Token       token.Token
+	Anchor      *GraphNode
+	AnchorAtEnd bool
+	Group       []GraphElement
+	Unbounded   bool // +
+	MinHops     int  // {MinHops,MaxHops}
+	MaxHops     int
+}
+
+func (sp *ShortestPath) graphPatternNode()    {}
+func (sp *ShortestPath) TokenLiteral() string { return sp.Token.Literal }
+func (sp *ShortestPath) String() string {
+	var group strings.Builder
+	group.WriteString("(")
+	for _, e := range sp.Group {
+		group.WriteString(e.String())
+	}
+	group.WriteString(")")
+	if sp.Unbounded {
+		group.WriteString("+")
+	} else {
+		fmt.Fprintf(&group, "{%d,%d}", sp.MinHops, sp.MaxHops)
+	}
+	if sp.AnchorAtEnd {
+		return "SHORTEST_PATH(" + group.String() + sp.Anchor.String() + ")"
+	}
+	return "SHORTEST_PATH(" + sp.Anchor.String() + group.String() + ")"
+}
+
+// -----------------------------------------------------------------------------
+// Temporal and ledger tables
+// -----------------------------------------------------------------------------
+
+// GeneratedAlwaysKind is the value the server fills a GENERATED ALWAYS
+// column with.
+type GeneratedAlwaysKind int
+
+const (
+	GeneratedNone                GeneratedAlwaysKind = iota
+	GeneratedRowStart                                // ROW START
+	GeneratedRowEnd                                  // ROW END
+	GeneratedTransactionIDStart                      // TRANSACTION_ID START
+	GeneratedTransactionIDEnd                        // TRANSACTION_ID END
+	GeneratedSequenceNumberStart                     // SEQUENCE_NUMBER START
+	GeneratedSequenceNumberEnd                       // SEQUENCE_NUMBER END
+)
+
+func (k GeneratedAlwaysKind) String() string {
+	switch k {
+	case GeneratedRowStart:
+		return "ROW START"
+	case GeneratedRowEnd:
+		return "ROW END"
+	case GeneratedTransactionIDStart:
+		return "TRANSACTION_ID START"
+	case GeneratedTransactionIDEnd:
+		return "TRANSACTION_ID END"
+	case GeneratedSequenceNumberStart:
+		return "SEQUENCE_NUMBER START"
+	case GeneratedSequenceNumberEnd:
+		return "SEQUENCE_NUMBER END"
+	}
+	return ""
+}
+
+// IsPeriodColumn reports whether the column is the start or end of the
+// SYSTEM_TIME period of a temporal table.
+func (k GeneratedAlwaysKind) IsPeriodColumn() bool {
+	return k == GeneratedRowStart || k == GeneratedRowEnd
+}
+
+// SystemVersioning is the SYSTEM_VERSIONING option of a temporal table.
+type SystemVersioning struct {
+	On                   bool
+	HistoryTable         *QualifiedIdentifier // HISTORY_TABLE = schema.table
+	DataConsistencyCheck *bool                // nil = not specified
+	HistoryRetention     *RetentionPeriod     // HISTORY_RETENTION_PERIOD
+}
+
+func (sv *SystemVersioning) String() string {
+	if !sv.On {
+		return "SYSTEM_VERSIONING = OFF"
+	}
+	var opts []string
+	if sv.HistoryTable != nil {
+		opts = append(opts, "HISTORY_TABLE = "+sv.HistoryTable.String())
+	}
+	if sv.DataConsistencyCheck != nil {
+		opts = append(opts, "DATA_CONSISTENCY_CHECK = "+onOff(*sv.DataConsistencyCheck))
+	}
+	if sv.HistoryRetention != nil {
+		opts = append(opts, "HISTORY_RETENTION_PERIOD = "+sv.HistoryRetention.String())
+	}
+	if len(opts) == 0 {
+		return "SYSTEM_VERSIONING = ON"
+	}
+	return "SYSTEM_VERSIONING = ON (" + strings.Join(opts, ", ") + ")"
+}
+
+// RetentionPeriod is how long a table keeps its history: INFINITE or a
+// number of days, weeks, months or years.
+type RetentionPeriod struct {
+	Infinite bool
+	Count    int64
+	Unit     string // DAY, WEEK, MONTH or YEAR, whether written singular or plural
+}
+
+func (rp *RetentionPeriod) String() string {
+	if rp.Infinite {
+		return "INFINITE"
+	}
+	if rp.Count == 1 {
+		return fmt.Sprintf("1 %s", rp.Unit)
+	}
+	return fmt.Sprintf("%d %sS", rp.Count, rp.Unit)
+}
+
+// LedgerOption is the LEDGER option of a ledger table.
+type LedgerOption struct {
+	On         bool
+	AppendOnly bool
+	View       *QualifiedIdentifier // LEDGER_VIEW = schema.view
+
+	// The column names of the ledger view, when they are given
+	TransactionIDColumn     string
+	SequenceNumberColumn    string
+	OperationTypeColumn     string
+	OperationTypeDescColumn string
+}
+
+func (lo *LedgerOption) String() string {
+	if !lo.On {
+		return "LEDGER = OFF"
+	}
+	var opts []string
+	if lo.AppendOnly {
+		opts = append(opts, "APPEND_ONLY = ON")
+	}
+	if lo.View != nil {
+		view := "LEDGER_VIEW = " + lo.View.String()
+		var cols []string
+		for _, c := range []struct{ name, value string }{
+			{"TRANSACTION_ID_COLUMN_NAME", lo.TransactionIDColumn},
+			{"SEQUENCE_NUMBER_COLUMN_NAME", lo.SequenceNumberColumn},
+			{"OPERATION_TYPE_COLUMN_NAME", lo.OperationTypeColumn},
+			{"OPERATION_TYPE_DESC_COLUMN_NAME", lo.OperationTypeDescColumn},
+		} {
+			if c.value != "" {
+				cols = append(cols, c.name+" = "+c.value)
+			}
+		}
+		if len(cols) > 0 {
+			view += " (" + strings.Join(cols, ", ") + ")"
+		}
+		opts = append(opts, view)
+	}
+	if len(opts) == 0 {
+		return "LEDGER = ON"
+	}
+	return "LEDGER = ON (" + strings.Join(opts, ", ") + ")"
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// -----------------------------------------------------------------------------
+// External data
+// -----------------------------------------------------------------------------
+
+// CreateExternalDataSourceStatement represents CREATE EXTERNAL DATA SOURCE
+// name WITH (LOCATION = '...', CREDENTIAL = name, ...).
+type CreateExternalDataSourceStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Options OptionList
+}
+
+func (s *CreateExternalDataSourceStatement) statementNode()       {}
+func (s *CreateExternalDataSourceStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateExternalDataSourceStatement) String() string {
+	return "CREATE EXTERNAL DATA SOURCE " + s.Name.Value + " WITH " + s.Options.String()
+}
+
+// AlterExternalDataSourceStatement represents ALTER EXTERNAL DATA SOURCE
+// name SET LOCATION = '...', CREDENTIAL = name, ...
+type AlterExternalDataSourceStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Options OptionList
+}
+
+func (s *AlterExternalDataSourceStatement) statementNode()       {}
+func (s *AlterExternalDataSourceStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *AlterExternalDataSourceStatement) String() string {
+	opts := make([]string, len(s.Options))
+	for i, opt := range s.Options {
+		opts[i] = opt.String()
+	}
+	return "ALTER EXTERNAL DATA SOURCE " + s.Name.Value + " SET " + strings.Join(opts, ", ")
+}
+
+// CreateExternalFileFormatStatement represents CREATE EXTERNAL FILE FORMAT
+// name WITH (FORMAT_TYPE = PARQUET, ...).
+type CreateExternalFileFormatStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Options OptionList
+}
+
+func (s *CreateExternalFileFormatStatement) statementNode()       {}
+func (s *CreateExternalFileFormatStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateExternalFileFormatStatement) String() string {
+	return "CREATE EXTERNAL FILE FORMAT " + s.Name.Value + " WITH " + s.Options.String()
+}
+
+// CreateExternalTableStatement represents CREATE EXTERNAL TABLE, with
+// column definitions, or with AS SELECT (CETAS), which writes the result of
+// the query to the location and may name its columns.
+type CreateExternalTableStatement struct {
+	Token       token.Token
+	Name        *QualifiedIdentifier
+	Columns     []*ColumnDefinition
+	ColumnNames []*Identifier // CREATE EXTERNAL TABLE t (a, b) WITH (...) AS SELECT
+	Options     OptionList    // LOCATION, DATA_SOURCE, FILE_FORMAT, REJECT_TYPE, ...
+	AsSelect    Statement     // SelectStatement or WithStatement (CTE)
+}
+
+func (s *CreateExternalTableStatement) statementNode()       {}
+func (s *CreateExternalTableStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateExternalTableStatement) String() string {
+	var out strings.Builder
+	out.WriteString("CREATE EXTERNAL TABLE ")
+	out.WriteString(s.Name.String())
+	if len(s.Columns) > 0 {
+		out.WriteString(" (\n")
+		for i, col := range s.Columns {
+			out.WriteString("    ")
+			out.WriteString(col.String())
+			if i < len(s.Columns)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(")")
+	} else if len(s.ColumnNames) > 0 {
+		names := make([]string, len(s.ColumnNames))
+		for i, name := range s.ColumnNames {
+			names[i] = name.Value
+		}
+		out.WriteString(" (" + strings.Join(names, ", ") + ")")
+	}
+	if s.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(s.Options.String())
+	}
+	if s.AsSelect != nil {
+		out.WriteString(" AS ")
+		out.WriteString(s.AsSelect.String())
+	}
+	return out.String()
+}
+
+// -----------------------------------------------------------------------------
+// Synapse and Fabric warehouses
+// -----------------------------------------------------------------------------
+
+// DistributionKind is how a dedicated pool spreads the rows of a table over
+// its distributions.
+type DistributionKind int
+
+const (
+	DistributionRoundRobin DistributionKind = iota // ROUND_ROBIN, the default
+	DistributionHash                               // HASH (columns)
+	DistributionReplicate                          // REPLICATE
+)
+
+func (k DistributionKind) String() string {
+	switch k {
+	case DistributionHash:
+		return "HASH"
+	case DistributionReplicate:
+		return "REPLICATE"
+	}
+	return "ROUND_ROBIN"
+}
+
+// Distribution is the DISTRIBUTION option of a table.
+type Distribution struct {
+	Kind    DistributionKind
+	Columns []*Identifier // HASH columns
+}
+
+// TableIndexKind is the storage of a table in a dedicated pool.
+type TableIndexKind int
+
+const (
+	TableIndexClusteredColumnstore TableIndexKind = iota // CLUSTERED COLUMNSTORE INDEX, the default
+	TableIndexHeap                                       // HEAP
+	TableIndexClustered                                  // CLUSTERED INDEX (columns)
+)
+
+func (k TableIndexKind) String() string {
+	switch k {
+	case TableIndexHeap:
+		return "HEAP"
+	case TableIndexClustered:
+		return "CLUSTERED INDEX"
+	}
+	return "CLUSTERED COLUMNSTORE INDEX"
+}
+
+// TableIndex is the index option of a table.
+type TableIndex struct {
+	Kind    TableIndexKind
+	Columns []*IndexColumn // Keys of a clustered index, or the ORDER of a columnstore index
+}
+
+// TablePartition is the PARTITION (column RANGE LEFT|RIGHT FOR VALUES
+// (...)) option of a table.
+type TablePartition struct {
+	Column *Identifier
+	Range  string // LEFT or RIGHT
+	Values []Expression
+}
+
+// CopyIntoStatement represents COPY INTO table [(columns)] FROM 'url', ...
+// [WITH (FILE_TYPE = 'CSV', CREDENTIAL = (...), ...)].
+type CopyIntoStatement struct {
+	Token   token.Token
+	Table   *QualifiedIdentifier
+	Columns []*CopyColumn
+	From    []Expression
+	Options OptionList
+}
+
+func (s *CopyIntoStatement) statementNode()       {}
+func (s *CopyIntoStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CopyIntoStatement) String() string {
+	var out strings.Builder
+	out.WriteString("COPY INTO ")
+	out.WriteString(s.Table.String())
+	if len(s.Columns) > 0 {
+		cols := make([]string, len(s.Columns))
+		for i, col := range s.Columns {
+			cols[i] = col.String()
+		}
+		out.WriteString(" (" + strings.Join(cols, ", ") + ")")
+	}
+	from := make([]string, len(s.From))
+	for i, f := range s.From {
+		from[i] = f.String()
+	}
+	out.WriteString(" FROM " + strings.Join(from, ", "))
+	if s.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(s.Options.String())
+	}
+	return out.String()
+}
+
+// CopyColumn is a target column of COPY INTO, with the value for missing
+// fields and the number of the field it is read from.
+type CopyColumn struct {
+	Name        *Identifier
+	Default     Expression
+	FieldNumber Expression
+}
+
+func (c *CopyColumn) String() string {
+	s := c.Name.String()
+	if c.Default != nil {
+		s += " DEFAULT " + c.Default.String()
+	}
+	if c.FieldNumber != nil {
+		s += " " + c.FieldNumber.String()
+	}
+	return s
+}
+
+// RenameStatement represents RENAME OBJECT [::] name TO new_name, RENAME
+// OBJECT name COLUMN column TO new_name and RENAME DATABASE [::] name TO
+// new_name.
+type RenameStatement struct {
+	Token      token.Token
+	ObjectType string // OBJECT or DATABASE
+	Name       *QualifiedIdentifier
+	Column     *Identifier // Column to rename, nil when renaming the object
+	NewName    *Identifier
+}
+
+func (s *RenameStatement) statementNode()       {}
+func (s *RenameStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *RenameStatement) String() string {
+	out := "RENAME " + s.ObjectType + " " + s.Name.String()
+	if s.Column != nil {
+		out += " COLUMN " + s.Column.String()
+	}
+	return out + " TO " + s.NewName.String()
+}
+
+// CreateRemoteTableStatement represents CREATE REMOTE TABLE name AT
+// ('connection string') [WITH (BATCH_SIZE = n)] AS SELECT ...
+type CreateRemoteTableStatement struct {
+	Token            token.Token
+	Name             *QualifiedIdentifier
+	ConnectionString Expression
+	Options          OptionList
+	AsSelect         Statement // SelectStatement or WithStatement (CTE)
+}
+
+func (s *CreateRemoteTableStatement) statementNode()       {}
+func (s *CreateRemoteTableStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateRemoteTableStatement) String() string {
+	var out strings.Builder
+	out.WriteString("CREATE REMOTE TABLE ")
+	out.WriteString(s.Name.String())
+	out.WriteString(" AT (")
+	out.WriteString(s.ConnectionString.String())
+	out.WriteString(")")
+	if s.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(s.Options.String())
+	}
+	out.WriteString(" AS ")
+	out.WriteString(s.AsSelect.String())
+	return out.String()
+}
+
+// -----------------------------------------------------------------------------
+// Row-level security
+// -----------------------------------------------------------------------------
+
+// SecurityPredicateType is the kind of a security policy predicate.
+type SecurityPredicateType int
+
+const (
+	FilterPredicate SecurityPredicateType = iota // Hides the rows the function returns nothing for
+	BlockPredicate                               // Rejects writes the function returns nothing for
+)
+
+func (t SecurityPredicateType) String() string {
+	if t == BlockPredicate {
+		return "BLOCK"
+	}
+	return "FILTER"
+}
+
+// SecurityPredicate is an ADD, ALTER or DROP FILTER|BLOCK PREDICATE entry
+// of a security policy, which binds an inline table-valued function to a
+// table.
+type SecurityPredicate struct {
+	Token     token.Token
+	Action    string // ADD, ALTER or DROP
+	Type      SecurityPredicateType
+	Function  *QualifiedIdentifier // The inline table-valued function; nil for DROP
+	Arguments []Expression         // Columns of Table or other arguments passed to Function
+	Table     *QualifiedIdentifier
+	Operation string // AFTER INSERT, AFTER UPDATE, BEFORE UPDATE or BEFORE DELETE of a block predicate; "" for all
+}
+
+func (sp *SecurityPredicate) String() string {
+	var out strings.Builder
+	out.WriteString(sp.Action)
+	out.WriteString(" ")
+	out.WriteString(sp.Type.String())
+	out.WriteString(" PREDICATE ")
+	if sp.Function != nil {
+		args := make([]string, len(sp.Arguments))
+		for i, arg := range sp.Arguments {
+			args[i] = arg.String()
+		}
+		out.WriteString(sp.Function.String())
+		out.WriteString("(" + strings.Join(args, ", ") + ") ")
+	}
+	out.WriteString("ON ")
+	out.WriteString(sp.Table.String())
+	if sp.Operation != "" {
+		out.WriteString(" ")
+		out.WriteString(sp.Operation)
+	}
+	return out.String()
+}
+
+// CreateSecurityPolicyStatement represents CREATE SECURITY POLICY name
+// ADD FILTER|BLOCK PREDICATE ..., ... [WITH (STATE = ON, SCHEMABINDING =
+// ON)] [NOT FOR REPLICATION].
+type CreateSecurityPolicyStatement struct {
+	Token             token.Token
+	Name              *QualifiedIdentifier
+	Predicates        []*SecurityPredicate
+	Options           OptionList
+	NotForReplication bool
+
+	// State and SchemaBinding are read from Options; they are nil when
+	// the option is not given.
+	State         *bool
+	SchemaBinding *bool
+}
+
+func (s *CreateSecurityPolicyStatement) statementNode()       {}
+func (s *CreateSecurityPolicyStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateSecurityPolicyStatement) String() string {
+	out := "CREATE SECURITY POLICY " + s.Name.String() + securityPolicyBody(s.Predicates, s.Options)
+	if s.NotForReplication {
+		out += " NOT FOR REPLICATION"
+	}
+	return out
+}
+
+// AlterSecurityPolicyStatement represents ALTER SECURITY POLICY name with
+// predicates to add, alter or drop, [WITH (STATE = ON|OFF)] [NOT FOR
+// REPLICATION].
+type AlterSecurityPolicyStatement struct {
+	Token             token.Token
+	Name              *QualifiedIdentifier
+	Predicates        []*SecurityPredicate
+	Options           OptionList
+	NotForReplication bool
+
+	State *bool // Read from Options; nil when not given
+}
+
+func (s *AlterSecurityPolicyStatement) statementNode()       {}
+func (s *AlterSecurityPolicyStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *AlterSecurityPolicyStatement) String() string {
+	out := "ALTER SECURITY POLICY " + s.Name.String() + securityPolicyBody(s.Predicates, s.Options)
+	if s.NotForReplication {
+		out += " NOT FOR REPLICATION"
+	}
+	return out
+}
+
+// securityPolicyBody prints the predicates and options of a security
+// policy with a leading space.
+func securityPolicyBody(predicates []*SecurityPredicate, options OptionList) string {
+	var out strings.Builder
+	for i, pred := range predicates {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(" ")
+		out.WriteString(pred.String())
+	}
+	if options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(options.String())
+	}
+	return out.String()
+}
+
+// -----------------------------------------------------------------------------
+// Extended Events and auditing
+// -----------------------------------------------------------------------------
+
+// CreateEventSessionStatement represents CREATE EVENT SESSION name ON
+// SERVER|DATABASE ADD EVENT ..., ADD TARGET ... [WITH (options)].
+type CreateEventSessionStatement struct {
+	Token   token.Token
+	Name    *Identifier
+	Scope   string // SERVER or DATABASE
+	Events  []*EventSessionEvent
+	Targets []*EventSessionTarget
+	Options OptionList
+
+	Settings *EventSessionOptions // Read from Options; nil without WITH
+}
+
+func (s *CreateEventSessionStatement) statementNode()       {}
+func (s *CreateEventSessionStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateEventSessionStatement) String() string {
+	var out strings.Builder
+	out.WriteString("CREATE EVENT SESSION " + s.Name.String() + " ON " + s.Scope)
+	out.WriteString(eventSessionChanges("ADD", s.Events, s.Targets, nil, nil))
+	if s.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(s.Options.String())
+	}
+	return out.String()
+}
+
+// AlterEventSessionStatement represents ALTER EVENT SESSION name ON
+// SERVER|DATABASE with events and targets to add or drop and new options,
+// or STATE = START|STOP.
+type AlterEventSessionStatement struct {
+	Token       token.Token
+	Name        *Identifier
+	Scope       string // SERVER or DATABASE
+	AddEvents   []*EventSessionEvent
+	DropEvents  []*QualifiedIdentifier
+	AddTargets  []*EventSessionTarget
+	DropTargets []*QualifiedIdentifier
+	Options     OptionList
+	State       string // START or STOP; "" when the state is not changed
+
+	Settings *EventSessionOptions // Read from Options; nil without WITH
+}
+
+func (s *AlterEventSessionStatement) statementNode()       {}
+func (s *AlterEventSessionStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *AlterEventSessionStatement) String() string {
+	var out strings.Builder
+	out.WriteString("ALTER EVENT SESSION " + s.Name.String() + " ON " + s.Scope)
+	if s.State != "" {
+		out.WriteString(" STATE = " + s.State)
+		return out.String()
+	}
+	out.WriteString(eventSessionChanges("ADD", s.AddEvents, s.AddTargets, s.DropEvents, s.DropTargets))
+	if s.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(s.Options.String())
+	}
+	return out.String()
+}
+
+// eventSessionChanges prints the events and targets of an event session
+// with a leading space.
+func eventSessionChanges(add string, events []*EventSessionEvent, targets []*EventSessionTarget, dropEvents, dropTargets []*QualifiedIdentifier) string {
+	var parts []string
+	for _, e := range events {
+		parts = append(parts, add+" EVENT "+e.String())
+	}
+	for _, name := range dropEvents {
+		parts = append(parts, "DROP EVENT "+name.String())
+	}
+	for _, t := range targets {
+		parts = append(parts, add+" TARGET "+t.String())
+	}
+	for _, name := range dropTargets {
+		parts = append(parts, "DROP TARGET "+name.String())
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, ", ")
+}
+
+// EventSessionEvent is an event of an event session: package.event with
+// the event fields to collect, the actions to add to it and a predicate.
+type EventSessionEvent struct {
+	Token     token.Token
+	Name      *QualifiedIdentifier // sqlserver.sql_statement_completed
+	Set       OptionList           // SET collect_statement = (1), ...
+	Actions   []*QualifiedIdentifier
+	Predicate Expression // WHERE ...
+}
+
+func (e *EventSessionEvent) String() string {
+	var parts []string
+	if len(e.Set) > 0 {
+		parts = append(parts, "SET "+e.Set.join())
+	}
+	if len(e.Actions) > 0 {
+		actions := make([]string, len(e.Actions))
+		for i, a := range e.Actions {
+			actions[i] = a.String()
+		}
+		parts = append(parts, "ACTION ("+strings.Join(actions, ", ")+")")
+	}
+	if e.Predicate != nil {
+		parts = append(parts, "WHERE "+e.Predicate.String())
+	}
+	if len(parts) == 0 {
+		return e.Name.String()
+	}
+	return e.Name.String() + " (" + strings.Join(parts, " ") + ")"
+}
+
+// EventSessionTarget is a target of an event session, such as
+// package0.event_file, with its SET fields.
+type EventSessionTarget struct {
+	Token token.Token
+	Name  *QualifiedIdentifier
+	Set   OptionList // SET filename = N'...', max_file_size = (5), ...
+}
+
+func (t *EventSessionTarget) String() string {
+	if len(t.Set) == 0 {
+		return t.Name.String()
+	}
+	return t.Name.String() + " (SET " + t.Set.join() + ")"
+}
+
+// EventSessionOptions are the WITH options of an event session. Sizes and
+// latencies keep their units, as in 4096 KB or 30 SECONDS; options that
+// are not given are empty or nil.
+type EventSessionOptions struct {
+	MaxMemory           string
+	EventRetentionMode  string // ALLOW_SINGLE_EVENT_LOSS, ALLOW_MULTIPLE_EVENT_LOSS or NO_EVENT_LOSS
+	MaxDispatchLatency  string // n SECONDS or INFINITE
+	MaxEventSize        string
+	MemoryPartitionMode string // NONE, PER_NODE or PER_CPU
+	TrackCausality      *bool
+	StartupState        *bool
+}
+
+// CreateServerAuditStatement represents CREATE SERVER AUDIT name TO
+// target [WITH (options)] [WHERE predicate].
+type CreateServerAuditStatement struct {
+	Token     token.Token
+	Name      *Identifier
+	Target    *AuditTarget
+	Options   OptionList // QUEUE_DELAY, ON_FAILURE, AUDIT_GUID, OPERATOR_AUDIT, STATE
+	Predicate Expression
+}
+
+func (s *CreateServerAuditStatement) statementNode()       {}
+func (s *CreateServerAuditStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateServerAuditStatement) String() string {
+	return "CREATE SERVER AUDIT " + s.Name.String() + serverAuditBody(s.Target, s.Options, s.Predicate)
+}
+
+// AlterServerAuditStatement represents ALTER SERVER AUDIT name with a new
+// target, options or predicate, REMOVE WHERE, or MODIFY NAME = new_name.
+type AlterServerAuditStatement struct {
+	Token       token.Token
+	Name        *Identifier
+	Target      *AuditTarget
+	Options     OptionList
+	Predicate   Expression
+	RemoveWhere bool
+	NewName     *Identifier // MODIFY NAME = new_name
+}
+
+func (s *AlterServerAuditStatement) statementNode()       {}
+func (s *AlterServerAuditStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *AlterServerAuditStatement) String() string {
+	out := "ALTER SERVER AUDIT " + s.Name.String()
+	switch {
+	case s.RemoveWhere:
+		return out + " REMOVE WHERE"
+	case s.NewName != nil:
+		return out + " MODIFY NAME = " + s.NewName.String()
+	}
+	return out + serverAuditBody(s.Target, s.Options, s.Predicate)
+}
+
+// serverAuditBody prints the target, options and predicate of a server
+// audit with a leading space.
+func serverAuditBody(target *AuditTarget, options OptionList, predicate Expression) string {
+	var out strings.Builder
+	if target != nil {
+		out.WriteString(" TO ")
+		out.WriteString(target.String())
+	}
+	if options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(options.String())
+	}
+	if predicate != nil {
+		out.WriteString(" WHERE ")
+		out.WriteString(predicate.String())
+	}
+	return out.String()
+}
+
+// AuditTarget is where a server audit writes: FILE with its options,
+// APPLICATION_LOG, SECURITY_LOG, URL or EXTERNAL_MONITOR.
+type AuditTarget struct {
+	Kind    string
+	Options OptionList // FILEPATH, MAXSIZE, MAX_ROLLOVER_FILES, MAX_FILES, RESERVE_DISK_SPACE
+}
+
+func (t *AuditTarget) String() string {
+	if t.Options != nil {
+		return t.Kind + " " + t.Options.String()
+	}
+	return t.Kind
+}
+
+// CreateAuditSpecificationStatement represents CREATE SERVER|DATABASE
+// AUDIT SPECIFICATION name FOR SERVER AUDIT audit ADD (...), ... [WITH
+// (STATE = ON|OFF)].
+type CreateAuditSpecificationStatement struct {
+	Token   token.Token
+	Scope   string // SERVER or DATABASE
+	Name    *Identifier
+	Audit   *Identifier
+	Actions []*AuditSpecificationAction
+	Options OptionList
+
+	State *bool // Read from Options; nil when not given
+}
+
+func (s *CreateAuditSpecificationStatement) statementNode()       {}
+func (s *CreateAuditSpecificationStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *CreateAuditSpecificationStatement) String() string {
+	return "CREATE " + s.Scope + " AUDIT SPECIFICATION " + s.Name.String() + auditSpecificationBody(s.Audit, s.Actions, s.Options)
+}
+
+// AlterAuditSpecificationStatement represents ALTER SERVER|DATABASE AUDIT
+// SPECIFICATION name [FOR SERVER AUDIT audit] ADD|DROP (...), ... [WITH
+// (STATE = ON|OFF)].
+type AlterAuditSpecificationStatement struct {
+	Token   token.Token
+	Scope   string // SERVER or DATABASE
+	Name    *Identifier
+	Audit   *Identifier // nil when the audit is not changed
+	Actions []*AuditSpecificationAction
+	Options OptionList
+
+	State *bool // Read from Options; nil when not given
+}
+
+func (s *AlterAuditSpecificationStatement) statementNode()       {}
+func (s *AlterAuditSpecificationStatement) TokenLiteral() string { return s.Token.Literal }
+func (s *AlterAuditSpecificationStatement) String() string {
+	return "ALTER " + s.Scope + " AUDIT SPECIFICATION " + s.Name.String() + auditSpecificationBody(s.Audit, s.Actions, s.Options)
+}
+
+// auditSpecificationBody prints the audit, actions and options of an
+// audit specification with a leading space.
+func auditSpecificationBody(audit *Identifier, actions []*AuditSpecificationAction, options OptionList) string {
+	var out strings.Builder
+	if audit != nil {
+		out.WriteString(" FOR SERVER AUDIT ")
+		out.WriteString(audit.String())
+	}
+	for i, a := range actions {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(" ")
+		out.WriteString(a.String())
+	}
+	if options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(options.String())
+	}
+	return out.String()
+}
+
+// AuditSpecificationAction is an ADD or DROP entry of an audit
+// specification: an audit action group such as FAILED_LOGIN_GROUP, or
+// database actions on a securable by principals, as in SELECT, INSERT ON
+// OBJECT::dbo.T BY public.
+type AuditSpecificationAction struct {
+	Token      token.Token
+	Action     string // ADD or DROP
+	Group      string // The audit action group; "" for database actions
+	Actions    []string
+	Class      string // OBJECT, SCHEMA or DATABASE when written as class::securable
+	Securable  *QualifiedIdentifier
+	Principals []*Identifier
+}
+
+func (a *AuditSpecificationAction) String() string {
+	if a.Group != "" {
+		return a.Action + " (" + a.Group + ")"
+	}
+	securable := a.Securable.String()
+	if a.Class != "" {
+		securable = a.Class + "::" + securable
+	}
+	principals := make([]string, len(a.Principals))
+	for i, p := range a.Principals {
+		principals[i] = p.String()
+	}
+	return a.Action + " (" + strings.Join(a.Actions, ", ") + " ON " + securable + " BY " + strings.Join(principals, ", ") + ")"
+}
+
+// -----------------------------------------------------------------------------
+// In-Memory OLTP
+// -----------------------------------------------------------------------------
+
+// Durability is the DURABILITY option of a memory-optimized table.
+type Durability int
+
+const (
+	DurabilitySchemaAndData Durability = iota // SCHEMA_AND_DATA, the default
+	DurabilitySchemaOnly                      // SCHEMA_ONLY: rows are lost on restart
+)
+
+func (d Durability) String() string {
+	if d == DurabilitySchemaOnly {
+		return "SCHEMA_ONLY"
+	}
+	return "SCHEMA_AND_DATA"
+}
+
+// AtomicBlockOptions are the options of BEGIN ATOMIC WITH (...) in a
+// natively compiled module. TRANSACTION ISOLATION LEVEL and LANGUAGE are
+// required there; the others are empty or nil when not given.
+type AtomicBlockOptions struct {
+	IsolationLevel    string // SNAPSHOT, REPEATABLE READ or SERIALIZABLE
+	Language          string // Such as us_english
+	DateFirst         int    // 1 to 7; 0 when not given
+	DateFormat        string // Such as dmy
+	DelayedDurability *bool
+}
+
+// -----------------------------------------------------------------------------
+// SQL Server 2022 and 2025 Functions
+// -----------------------------------------------------------------------------
+
+// GreatestLeastExpression is GREATEST(value, ...) or LEAST(value, ...).
+type GreatestLeastExpression struct {
+	Token     token.Token
+	Least     bool
+	Arguments []Expression
+}
+
+func (gl *GreatestLeastExpression) expressionNode()      {}
+func (gl *GreatestLeastExpression) TokenLiteral() string { return gl.Token.Literal }
+func (gl *GreatestLeastExpression) String() string {
+	name := "GREATEST"
+	if gl.Least {
+		name = "LEAST"
+	}
+	return name + "(" + joinExpressions(gl.Arguments) + ")"
+}
+
+// joinExpressions writes a list of arguments separated by commas.
+func joinExpressions(list []Expression) string {
+	parts := make([]string, len(list))
+	for i, e := range list {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DateTruncExpression is DATETRUNC(datepart, date).
+type DateTruncExpression struct {
+	Token    token.Token
+	DatePart *Identifier
+	Date     Expression
+}
+
+func (dt *DateTruncExpression) expressionNode()      {}
+func (dt *DateTruncExpression) TokenLiteral() string { return dt.Token.Literal }
+func (dt *DateTruncExpression) String() string {
+	return "DATETRUNC(" + dt.DatePart.Value + ", " + dt.Date.String() + ")"
+}
+
+// DateBucketExpression is DATE_BUCKET(datepart, number, date [, origin]).
+type DateBucketExpression struct {
+	Token    token.Token
+	DatePart *Identifier
+	Number   Expression
+	Date     Expression
+	Origin   Expression // nil for the default origin, 1900-01-01
+}
+
+func (db *DateBucketExpression) expressionNode()      {}
+func (db *DateBucketExpression) TokenLiteral() string { return db.Token.Literal }
+func (db *DateBucketExpression) String() string {
+	result := "DATE_BUCKET(" + db.DatePart.Value + ", " + db.Number.String() + ", " + db.Date.String()
+	if db.Origin != nil {
+		result += ", " + db.Origin.String()
+	}
+	return result + ")"
+}
+
+// ApproxPercentileExpression is APPROX_PERCENTILE_CONT(p) or
+// APPROX_PERCENTILE_DISC(p) WITHIN GROUP (ORDER BY expr).
+type ApproxPercentileExpression struct {
+	Token      token.Token
+	Discrete   bool
+	Percentile Expression
+	OrderBy    *OrderByItem
+}
+
+func (ap *ApproxPercentileExpression) expressionNode()      {}
+func (ap *ApproxPercentileExpression) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ApproxPercentileExpression) String() string {
+	name := "APPROX_PERCENTILE_CONT"
+	if ap.Discrete {
+		name = "APPROX_PERCENTILE_DISC"
+	}
+	return name + "(" + ap.Percentile.String() + ") WITHIN GROUP (ORDER BY " + ap.OrderBy.String() + ")"
+}
+
+// JsonNullHandling is the NULL ON NULL or ABSENT ON NULL clause of the
+// JSON constructors.
+type JsonNullHandling int
+
+const (
+	JsonNullDefault  JsonNullHandling = iota // Not given: NULL ON NULL for objects, ABSENT ON NULL for arrays
+	JsonNullOnNull                           // NULL ON NULL: nulls are written as null
+	JsonAbsentOnNull                         // ABSENT ON NULL: nulls are left out
+)
+
+func (h JsonNullHandling) String() string {
+	switch h {
+	case JsonNullOnNull:
+		return "NULL ON NULL"
+	case JsonAbsentOnNull:
+		return "ABSENT ON NULL"
+	}
+	return ""
+}
+
+// jsonArguments returns the arguments of a JSON constructor followed by
+// its null clause and RETURNING type.
+func jsonArguments(args string, onNull JsonNullHandling, returning *DataType) string {
+	parts := []string{}
+	if args != "" {
+		parts = append(parts, args)
+	}
+	if onNull != JsonNullDefault {
+		parts = append(parts, onNull.String())
+	}
+	if returning != nil {
+		parts = append(parts, "RETURNING "+returning.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// JsonObjectExpression is JSON_OBJECT('key':value, ... [null clause]
+// [RETURNING json]).
+type JsonObjectExpression struct {
+	Token     token.Token
+	Pairs     []*JsonKeyValuePair
+	OnNull    JsonNullHandling
+	Returning *DataType
+}
+
+func (jo *JsonObjectExpression) expressionNode()      {}
+func (jo *JsonObjectExpression) TokenLiteral() string { return jo.Token.Literal }
+func (jo *JsonObjectExpression) String() string {
+	pairs := make([]string, len(jo.Pairs))
+	for i, pair := range jo.Pairs {
+		pairs[i] = pair.String()
+	}
+	return "JSON_OBJECT(" + jsonArguments(strings.Join(pairs, ", "), jo.OnNull, jo.Returning) + ")"
+}
+
+// JsonArrayExpression is JSON_ARRAY(value, ... [null clause] [RETURNING
+// json]).
+type JsonArrayExpression struct {
+	Token     token.Token
+	Elements  []Expression
+	OnNull    JsonNullHandling
+	Returning *DataType
+}
+
+func (ja *JsonArrayExpression) expressionNode()      {}
+func (ja *JsonArrayExpression) TokenLiteral() string { return ja.Token.Literal }
+func (ja *JsonArrayExpression) String() string {
+	return "JSON_ARRAY(" + jsonArguments(joinExpressions(ja.Elements), ja.OnNull, ja.Returning) + ")"
+}
+
+// JsonObjectAggExpression is the aggregate JSON_OBJECTAGG(key:value [null
+// clause] [RETURNING json]) [OVER (...)].
+type JsonObjectAggExpression struct {
+	Token     token.Token
+	Key       Expression
+	Value     Expression
+	OnNull    JsonNullHandling
+	Returning *DataType
+	Over      *OverClause
+}
+
+func (jo *JsonObjectAggExpression) expressionNode()      {}
+func (jo *JsonObjectAggExpression) TokenLiteral() string { return jo.Token.Literal }
+func (jo *JsonObjectAggExpression) String() string {
+	result := "JSON_OBJECTAGG(" + jsonArguments(jo.Key.String()+":"+jo.Value.String(), jo.OnNull, jo.Returning) + ")"
+	if jo.Over != nil {
+		result += " " + jo.Over.String()
+	}
+	return result
+}
+
+// JsonArrayAggExpression is the aggregate JSON_ARRAYAGG(value [ORDER BY
+// ...] [null clause] [RETURNING json]) [OVER (...)].
+type JsonArrayAggExpression struct {
+	Token     token.Token
+	Value     Expression
+	OrderBy   []*OrderByItem
+	OnNull    JsonNullHandling
+	Returning *DataType
+	Over      *OverClause
+}
+
+func (ja *JsonArrayAggExpression) expressionNode()      {}
+func (ja *JsonArrayAggExpression) TokenLiteral() string { return ja.Token.Literal }
+func (ja *JsonArrayAggExpression) String() string {
+	args := ja.Value.String()
+	if len(ja.OrderBy) > 0 {
+		items := make([]string, len(ja.OrderBy))
+		for i, item := range ja.OrderBy {
+			items[i] = item.String()
+		}
+		args += " ORDER BY " + strings.Join(items, ", ")
+	}
+	result := "JSON_ARRAYAGG(" + jsonArguments(args, ja.OnNull, ja.Returning) + ")"
+	if ja.Over != nil {
+		result += " " + ja.Over.String()
+	}
+	return result
+}
+
+// RegexpFunction identifies a regular expression function.
+type RegexpFunction int
+
+const (
+	RegexpLike    RegexpFunction = iota // REGEXP_LIKE(source, pattern [, flags])
+	RegexpReplace                       // REGEXP_REPLACE(source, pattern [, replacement [, start [, occurrence [, flags]]]])
+	RegexpSubstr                        // REGEXP_SUBSTR(source, pattern [, start [, occurrence [, flags [, group]]]])
+	RegexpInstr                         // REGEXP_INSTR(source, pattern [, start [, occurrence [, return_option [, flags [, group]]]]])
+	RegexpCount                         // REGEXP_COUNT(source, pattern [, start [, flags]])
+)
+
+func (f RegexpFunction) String() string {
+	switch f {
+	case RegexpReplace:
+		return "REGEXP_REPLACE"
+	case RegexpSubstr:
+		return "REGEXP_SUBSTR"
+	case RegexpInstr:
+		return "REGEXP_INSTR"
+	case RegexpCount:
+		return "REGEXP_COUNT"
+	}
+	return "REGEXP_LIKE"
+}
+
+// RegexpExpression is a call of one of the regular expression functions.
+// The arguments a function does not take, or that are left out, are nil.
+type RegexpExpression struct {
+	Token        token.Token
+	Function     RegexpFunction
+	Source       Expression
+	Pattern      Expression
+	Replacement  Expression // REGEXP_REPLACE
+	Start        Expression // The position to start searching from
+	Occurrence   Expression // Which match to use
+	ReturnOption Expression // REGEXP_INSTR: 0 for the start of the match, 1 for the end
+	Flags        Expression // Such as 'i' for case-insensitive matching
+	Group        Expression // The capture group to return
+}
+
+// Arguments returns the arguments of the call in the order the function
+// takes them, up to the last one given.
+func (re *RegexpExpression) Arguments() []Expression {
+	var args []Expression
+	switch re.Function {
+	case RegexpLike:
+		args = []Expression{re.Source, re.Pattern, re.Flags}
+	case RegexpReplace:
+		args = []Expression{re.Source, re.Pattern, re.Replacement, re.Start, re.Occurrence, re.Flags}
+	case RegexpSubstr:
+		args = []Expression{re.Source, re.Pattern, re.Start, re.Occurrence, re.Flags, re.Group}
+	case RegexpInstr:
+		args = []Expression{re.Source, re.Pattern, re.Start, re.Occurrence, re.ReturnOption, re.Flags, re.Group}
+	case RegexpCount:
+		args = []Expression{re.Source, re.Pattern, re.Start, re.Flags}
+	}
+	for len(args) > 0 && args[len(args)-1] == nil {
+		args = args[:len(args)-1]
+	}
+	return args
+}
+
+func (re *RegexpExpression) expressionNode()      {}
+func (re *RegexpExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RegexpExpression) String() string {
+	return re.Function.String() + "(" + joinExpressions(re.Arguments()) + ")"
+}
+
+// VectorDistanceExpression is VECTOR_DISTANCE(metric, vector, vector).
+type VectorDistanceExpression struct {
+	Token  token.Token
+	Metric Expression // 'cosine', 'euclidean' or 'dot'
+	Left   Expression
+	Right  Expression
+}
+
+func (vd *VectorDistanceExpression) expressionNode()      {}
+func (vd *VectorDistanceExpression) TokenLiteral() string { return vd.Token.Literal }
+func (vd *VectorDistanceExpression) String() string {
+	return "VECTOR_DISTANCE(" + vd.Metric.String() + ", " + vd.Left.String() + ", " + vd.Right.String() + ")"
+}
+
+// GenerateSeries is GENERATE_SERIES(start, stop [, step]) in a FROM
+// clause. It returns a single column named value.
+type GenerateSeries struct {
+	Token         token.Token
+	Start         Expression
+	Stop          Expression
+	Step          Expression // nil for 1, or -1 when start is greater than stop
+	Alias         *Identifier
+	ColumnAliases []*Identifier
+}
+
+func (gs *GenerateSeries) tableRefNode()        {}
+func (gs *GenerateSeries) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GenerateSeries) String() string {
+	var out strings.Builder
+	out.WriteString("GENERATE_SERIES(" + gs.Start.String() + ", " + gs.Stop.String())
+	if gs.Step != nil {
+		out.WriteString(", " + gs.Step.String())
+	}
+	out.WriteString(")")
+	if gs.Alias != nil {
+		out.WriteString(" AS ")
+		out.WriteString(gs.Alias.Value)
+		if len(gs.ColumnAliases) > 0 {
+			names := make([]string, len(gs.ColumnAliases))
+			for i, col := range gs.ColumnAliases {
+				names[i] = col.Value
+			}
+			out.WriteString("(" + strings.Join(names, ", ") + ")")
+		}
+	}
+	return out.String()
+}
+
+// CreateJsonIndexStatement is CREATE JSON INDEX name ON table (column)
+// [FOR ('path', ...)] [WITH (options)] [ON filegroup].
+type CreateJsonIndexStatement struct {
+	Token     token.Token
+	Name      *Identifier
+	Table     *QualifiedIdentifier
+	Column    *Identifier
+	Paths     []string    // The SQL/JSON paths to index; all of the document when empty
+	Options   OptionList  // WITH (options)
+	Filegroup *Identifier // ON [filegroup]
+}
+
+func (ji *CreateJsonIndexStatement) statementNode()       {}
+func (ji *CreateJsonIndexStatement) TokenLiteral() string { return ji.Token.Literal }
+func (ji *CreateJsonIndexStatement) String() string {
+	var out strings.Builder
+	out.WriteString("CREATE JSON INDEX ")
+	out.WriteString(ji.Name.Value)
+	out.WriteString(" ON ")
+	out.WriteString(ji.Table.String())
+	out.WriteString(" (")
+	out.WriteString(ji.Column.Value)
+	out.WriteString(")")
+	if len(ji.Paths) > 0 {
+		paths := make([]string, len(ji.Paths))
+		for i, path := range ji.Paths {
+			paths[i] = "'" + strings.ReplaceAll(path, "'", "''") + "'"
+		}
+		out.WriteString(" FOR (" + strings.Join(paths, ", ") + ")")
+	}
+	if ji.Options != nil {
+		out.WriteString(" WITH ")
+		out.WriteString(ji.Options.String())
+	}
+	if ji.Filegroup != nil {
+		out.WriteString(" ON ")
+		out.WriteString(ji.Filegroup.Value)
+	}
+	return out.String()
+}
@@ -116,6 +116,12 @@ func TestLiteralMethods(t *testing.T) {
 		t.Error("Unicode StringLiteral String failed")
 	}
 
+	// Embedded quotes are doubled
+	qs := &StringLiteral{Token: token.Token{Literal: "'it''s'"}, Value: "it's"}
+	if qs.String() != "'it''s'" {
+		t.Errorf("expected 'it''s', got %s", qs.String())
+	}
+
 	// Null
 	nl := &NullLiteral{Token: token.Token{Literal: "NULL"}}
 	nl.expressionNode()
@@ -0,0 +1,85 @@
+package ast
+
+import "reflect"
+
+// Clone returns a deep copy of a node, or of a value that is part of one
+// such as a DataType. Everything the node refers to is copied: child
+// nodes, slices, maps, interface fields and pointers to values such as the
+// length of a DataType. A node referred to twice in the tree is copied
+// once, so the copy has the same shape as the original.
+func Clone[N any](node N) N {
+	c := cloner{copies: make(map[copyKey]reflect.Value)}
+	v := reflect.ValueOf(&node).Elem()
+	out := reflect.New(v.Type()).Elem()
+	out.Set(c.clone(v))
+	return out.Interface().(N)
+}
+
+// copyKey identifies a pointer that has been copied.
+type copyKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+type cloner struct {
+	copies map[copyKey]reflect.Value
+}
+
+func (c *cloner) clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.clone(v.Elem()))
+		return out
+	case reflect.Pointer:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := copyKey{v.Type(), v.Pointer()}
+		if out, ok := c.copies[key]; ok {
+			return out
+		}
+		out := reflect.New(v.Type().Elem())
+		c.copies[key] = out
+		out.Elem().Set(c.clone(v.Elem()))
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(c.clone(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(c.clone(iter.Key()), c.clone(iter.Value()))
+		}
+		return out
+	}
+	// Strings, numbers and booleans are values already
+	return v
+}
@@ -0,0 +1,157 @@
+package ast
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// EqualOptions are the differences Equal ignores.
+type EqualOptions struct {
+	// IgnorePositions ignores the lines and columns of tokens, so that
+	// trees parsed from differently laid out scripts can compare equal.
+	IgnorePositions bool
+
+	// IgnoreCase compares identifiers, variables and the keywords the
+	// tree keeps as written without regard to case. The values of string
+	// literals are always compared exactly.
+	IgnoreCase bool
+
+	// IgnoreQuoting compares a name that is a keyword unless it is
+	// quoted, such as [Status], equal to the keyword written bare. The
+	// tree does not keep how other names are delimited: [a]]b] and "a]b"
+	// are always equal.
+	IgnoreQuoting bool
+
+	// IgnoreTokens skips the tokens nodes keep, which hold positions and
+	// keywords as written; the other fields of a node say what it means,
+	// so EXEC and EXECUTE compare equal. It implies IgnorePositions.
+	IgnoreTokens bool
+
+	// IgnoreBlocks compares a BEGIN...END block of a single statement
+	// equal to the statement.
+	IgnoreBlocks bool
+}
+
+var (
+	tokenType         = reflect.TypeOf(token.Token{})
+	stringLiteralType = reflect.TypeOf(StringLiteral{})
+	beginEndType      = reflect.TypeOf(&BeginEndBlock{})
+)
+
+// Equal reports whether two nodes, or two values made of nodes such as
+// slices of them, have the same structure and values, but for the
+// differences opts ignores. Nil and empty slices are equal.
+func Equal(a, b any, opts EqualOptions) bool {
+	e := equality{opts: opts}
+	return e.equal(reflect.ValueOf(&a).Elem(), reflect.ValueOf(&b).Elem(), false)
+}
+
+type equality struct {
+	opts EqualOptions
+}
+
+// equal compares two values; literal is set for the value of a string
+// literal, whose case and quotes are data.
+func (e equality) equal(a, b reflect.Value, literal bool) bool {
+	if a.Kind() == reflect.Interface {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = e.unwrap(a.Elem()), e.unwrap(b.Elem())
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Pointer() == b.Pointer() || e.equal(a.Elem(), b.Elem(), literal)
+	case reflect.Struct:
+		if a.Type() == tokenType {
+			if e.opts.IgnoreTokens {
+				return true
+			}
+			return e.token(a.Interface().(token.Token), b.Interface().(token.Token))
+		}
+		for i := 0; i < a.NumField(); i++ {
+			literal := a.Type() == stringLiteralType && a.Type().Field(i).Name == "Value"
+			if !e.equal(a.Field(i), b.Field(i), literal) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !e.equal(a.Index(i), b.Index(i), literal) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, ka := range a.MapKeys() {
+			found := false
+			for _, kb := range b.MapKeys() {
+				if e.equal(ka, kb, false) {
+					found = e.equal(a.MapIndex(ka), b.MapIndex(kb), false)
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case reflect.String:
+		if literal {
+			return a.String() == b.String()
+		}
+		return e.text(a.String(), b.String())
+	}
+	return a.Equal(b)
+}
+
+func (e equality) token(a, b token.Token) bool {
+	// A name that is a keyword unless it is quoted lexes as one
+	quotedKeyword := e.opts.IgnoreQuoting && (a.Type == token.IDENT || b.Type == token.IDENT)
+	if a.Type != b.Type && !quotedKeyword {
+		return false
+	}
+	if !e.opts.IgnorePositions && (a.Line != b.Line || a.Column != b.Column) {
+		return false
+	}
+	if a.Type == token.STRING || a.Type == token.NSTRING {
+		return a.Literal == b.Literal
+	}
+	return e.text(a.Literal, b.Literal)
+}
+
+// text compares a name or keyword.
+func (e equality) text(a, b string) bool {
+	if e.opts.IgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// unwrap replaces a BEGIN...END block of a single statement with the
+// statement when blocks are ignored.
+func (e equality) unwrap(v reflect.Value) reflect.Value {
+	for e.opts.IgnoreBlocks && v.Type() == beginEndType && !v.IsNil() {
+		block := v.Interface().(*BeginEndBlock)
+		if len(block.Statements) != 1 || block.Statements[0] == nil {
+			break
+		}
+		v = reflect.ValueOf(block.Statements[0])
+	}
+	return v
+}
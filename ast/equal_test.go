@@ -0,0 +1,116 @@
+package ast_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	program, errs := tsqlparser.Parse(src)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return program
+}
+
+func TestClone(t *testing.T) {
+	program := parse(t, `
+CREATE TABLE dbo.Orders (Id INT NOT NULL, Code VARCHAR(10), Amount DECIMAL(10, 2));
+SELECT o.Id, SUM(o.Amount) AS Total FROM dbo.Orders o WHERE o.Code IN ('a', 'b') GROUP BY o.Id;
+`)
+	before := program.String()
+	clone := ast.Clone(program)
+	if !ast.Equal(program, clone, ast.EqualOptions{}) {
+		t.Fatal("clone differs from the original")
+	}
+
+	create := clone.Statements[0].(*ast.CreateTableStatement)
+	*create.Columns[2].DataType.Scale = 4
+	create.Columns[0].Name.Value = "OrderId"
+	sel := clone.Statements[1].(*ast.SelectStatement)
+	sel.Columns = append(sel.Columns[:1], sel.Columns...)
+	sel.Where.(*ast.InExpression).Values[0] = &ast.StringLiteral{Value: "z"}
+	clone.Statements = append(clone.Statements, &ast.BreakStatement{})
+
+	if got := program.String(); got != before {
+		t.Errorf("changing the clone changed the original:\n%s\nwant\n%s", got, before)
+	}
+	if ast.Equal(program, clone, ast.EqualOptions{}) {
+		t.Error("the changed clone is equal to the original")
+	}
+
+	// A node referred to twice is copied once
+	id := &ast.Identifier{Value: "x"}
+	infix := ast.Clone(&ast.InfixExpression{Left: id, Operator: "=", Right: id})
+	if infix.Left != infix.Right || infix.Left == ast.Expression(id) {
+		t.Error("shared node was not copied once")
+	}
+
+	var stmt ast.Statement = sel
+	if copied := ast.Clone(stmt); copied == stmt || !ast.Equal(copied, stmt, ast.EqualOptions{}) {
+		t.Error("clone through an interface failed")
+	}
+}
+
+func TestCloneCorpus(t *testing.T) {
+	paths, _ := filepath.Glob("../testdata/*.sql")
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		program, _ := tsqlparser.Parse(string(data))
+		clone := ast.Clone(program)
+		if clone == program || !ast.Equal(program, clone, ast.EqualOptions{}) {
+			t.Errorf("%s: clone differs from the original", filepath.Base(path))
+		}
+	}
+}
+
+func TestEqualOptions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		opts ast.EqualOptions
+		want bool
+	}{
+		{"SELECT a FROM t", "SELECT a FROM t", ast.EqualOptions{}, true},
+		{"SELECT a FROM t", "SELECT a\nFROM t", ast.EqualOptions{}, false},
+		{"SELECT a FROM t", "SELECT a\nFROM t", ast.EqualOptions{IgnorePositions: true}, true},
+		{"SELECT a FROM t", "SELECT b FROM t", ast.EqualOptions{IgnorePositions: true}, false},
+
+		{"SELECT Name FROM dbo.Users", "SELECT name FROM DBO.users", ast.EqualOptions{IgnorePositions: true}, false},
+		{"SELECT Name FROM dbo.Users", "SELECT name FROM DBO.users", ast.EqualOptions{IgnorePositions: true, IgnoreCase: true}, true},
+		{"SELECT CAST(@X AS int)", "SELECT CAST(@x AS INT)", ast.EqualOptions{IgnoreCase: true}, true},
+		{"SELECT 'Open'", "SELECT 'open'", ast.EqualOptions{IgnoreCase: true}, false},
+
+		{"SELECT [a]]b] FROM t", `SELECT "a]b" FROM t`, ast.EqualOptions{IgnorePositions: true}, true},
+		{"SELECT [a]]b] FROM t", `SELECT "a]b" FROM t`, ast.EqualOptions{IgnorePositions: true, IgnoreQuoting: true}, true},
+		{"SELECT [Status] FROM t", "SELECT Status FROM t", ast.EqualOptions{IgnorePositions: true, IgnoreQuoting: true}, true},
+		{"SELECT 'a]]b'", "SELECT 'a]b'", ast.EqualOptions{IgnoreQuoting: true}, false},
+
+		{"EXEC p", "EXECUTE p", ast.EqualOptions{IgnorePositions: true}, false},
+		{"EXEC p", "EXECUTE p", ast.EqualOptions{IgnoreTokens: true}, true},
+		{"SELECT a FROM t", "SELECT a\nFROM t", ast.EqualOptions{IgnoreTokens: true}, true},
+
+		{"IF @a = 1 BEGIN PRINT 'x' END", "IF @a = 1 PRINT 'x'", ast.EqualOptions{IgnoreTokens: true}, false},
+		{"IF @a = 1 BEGIN PRINT 'x' END", "IF @a = 1 PRINT 'x'", ast.EqualOptions{IgnoreTokens: true, IgnoreBlocks: true}, true},
+		{"IF @a = 1 BEGIN PRINT 'x'; PRINT 'y' END", "IF @a = 1 PRINT 'x'", ast.EqualOptions{IgnoreTokens: true, IgnoreBlocks: true}, false},
+	}
+	for _, tt := range tests {
+		if got := ast.Equal(parse(t, tt.a), parse(t, tt.b), tt.opts); got != tt.want {
+			t.Errorf("Equal(%q, %q, %+v) = %v, want %v", tt.a, tt.b, tt.opts, got, tt.want)
+		}
+	}
+
+	if !ast.Equal(&ast.SelectStatement{}, &ast.SelectStatement{Columns: []ast.SelectColumn{}}, ast.EqualOptions{}) {
+		t.Error("nil and empty slices differ")
+	}
+	if ast.Equal(&ast.Identifier{Value: "a"}, &ast.Variable{Name: "a"}, ast.EqualOptions{IgnoreCase: true}) {
+		t.Error("nodes of different types are equal")
+	}
+}
@@ -0,0 +1,622 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// A document is a parsed SQL file with the symbols it defines.
+type document struct {
+	uri    string
+	text   string
+	lines  []int // byte offset of the start of each line
+	tokens []token.Token
+	starts []int // byte offset of each token
+	batch  []int // batch of each token; GO starts a new batch
+
+	program *ast.Program
+	errors  []string
+
+	symbols []*DocumentSymbol // outline
+	objects []*object         // procedures, functions, views and tables
+	vars    []map[string]*declaration
+	labels  []map[string]*declaration
+}
+
+// An object is a schema object a document creates.
+type object struct {
+	name   string // lower case schema.name, with dbo for no schema
+	kind   string // PROCEDURE, FUNCTION, VIEW or TABLE
+	header string // shown on hover
+	doc    *document
+	tok    int // index of the last part of the name
+}
+
+// A declaration is a variable, parameter or label.
+type declaration struct {
+	text string // the declaration as written in hover
+	tok  int
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, text: text, lines: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	d.tokens = tsqlparser.Tokenize(text)
+	d.starts = make([]int, len(d.tokens))
+	d.batch = make([]int, len(d.tokens))
+	batch := 0
+	for i, tok := range d.tokens {
+		d.starts[i] = d.offset(tok.Line, tok.Column)
+		if tok.Type == token.GO {
+			batch++
+		}
+		d.batch[i] = batch
+	}
+	d.vars = make([]map[string]*declaration, batch+1)
+	d.labels = make([]map[string]*declaration, batch+1)
+	for i := range d.vars {
+		d.vars[i] = make(map[string]*declaration)
+		d.labels[i] = make(map[string]*declaration)
+	}
+	d.program, d.errors = tsqlparser.Parse(text)
+	d.analyze()
+	return d
+}
+
+// offset returns the byte offset of a line and a column counted in runes,
+// both one-based, as the lexer reports them.
+func (d *document) offset(line, column int) int {
+	if line < 1 {
+		return 0
+	}
+	if line > len(d.lines) {
+		return len(d.text)
+	}
+	off := d.lines[line-1]
+	for n := 1; n < column && off < len(d.text) && d.text[off] != '\n'; n++ {
+		_, size := utf8.DecodeRuneInString(d.text[off:])
+		off += size
+	}
+	return off
+}
+
+// position converts a byte offset to an LSP position, whose column counts
+// UTF-16 code units.
+func (d *document) position(off int) Position {
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > off }) - 1
+	character := 0
+	for _, r := range d.text[d.lines[line]:off] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// utf16Len returns the number of UTF-16 code units that encode r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// byteOffset converts an LSP position to a byte offset.
+func (d *document) byteOffset(p Position) int {
+	if p.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	off := d.lines[p.Line]
+	for character := 0; character < p.Character && off < len(d.text); {
+		r, size := utf8.DecodeRuneInString(d.text[off:])
+		if r == '\n' {
+			break
+		}
+		character += utf16Len(r)
+		off += size
+	}
+	return off
+}
+
+// end returns the byte offset just past token i.
+func (d *document) end(i int) int {
+	tok, off := d.tokens[i], d.starts[i]
+	rest := d.text[off:]
+	switch {
+	case tok.Type == token.EOF:
+		return off
+	case tok.Type == token.COMMENT:
+		return off + len(tok.Literal)
+	case tok.Type == token.NSTRING && len(rest) > 1:
+		return off + 1 + quotedLength(rest[1:], '\'')
+	case strings.HasPrefix(rest, "'"):
+		return off + quotedLength(rest, '\'')
+	case strings.HasPrefix(rest, "\""):
+		return off + quotedLength(rest, '"')
+	case strings.HasPrefix(rest, "["):
+		return off + quotedLength(rest, ']')
+	}
+	// The literal may differ in case from the source, but not in length
+	n := len(tok.Literal)
+	if n > len(rest) {
+		n = len(rest)
+	}
+	return off + n
+}
+
+// quotedLength returns the length of the quoted text at the start of s,
+// in which a doubled closing quote stands for itself.
+func quotedLength(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+func (d *document) tokenRange(i int) Range {
+	return Range{Start: d.position(d.starts[i]), End: d.position(d.end(i))}
+}
+
+// span returns the range from the start of token i to the end of token j.
+func (d *document) span(i, j int) Range {
+	return Range{Start: d.position(d.starts[i]), End: d.position(d.end(j))}
+}
+
+// tokenAt returns the index of the token at a position, or -1. A position
+// just past the end of a name counts as on it.
+func (d *document) tokenAt(p Position) int {
+	off := d.byteOffset(p)
+	i := sort.Search(len(d.starts), func(i int) bool { return d.starts[i] > off }) - 1
+	if i < 0 || d.tokens[i].Type == token.EOF {
+		return -1
+	}
+	if off < d.end(i) || off == d.end(i) && isName(d.tokens[i]) {
+		return i
+	}
+	return -1
+}
+
+// isName reports whether a token can name an object, variable or label.
+// Keywords can, as with a column called Name.
+func isName(tok token.Token) bool {
+	return tok.Type == token.IDENT || tok.Type == token.VARIABLE || tok.Type.IsKeyword()
+}
+
+// index returns the index of the token at a lexer position, or -1.
+func (d *document) index(tok token.Token) int {
+	if tok.Line == 0 {
+		return -1
+	}
+	off := d.offset(tok.Line, tok.Column)
+	i := sort.SearchInts(d.starts, off)
+	if i < len(d.starts) && d.starts[i] == off {
+		return i
+	}
+	return -1
+}
+
+// next returns the index of the token after i that is not a comment.
+func (d *document) next(i int) int {
+	for i++; i < len(d.tokens)-1 && d.tokens[i].Type == token.COMMENT; i++ {
+	}
+	return i
+}
+
+// prev returns the index of the token before i that is not a comment, or -1.
+func (d *document) prev(i int) int {
+	for i--; i >= 0 && d.tokens[i].Type == token.COMMENT; i-- {
+	}
+	return i
+}
+
+// find returns the index of the first variable token named name at or
+// after token i, or -1.
+func (d *document) find(i int, name string) int {
+	for ; i >= 0 && i < len(d.tokens); i++ {
+		if d.tokens[i].Type == token.VARIABLE && strings.EqualFold(d.tokens[i].Literal, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// diagnostics converts the parser's errors. Errors without a position are
+// reported at the start of the document.
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	for _, e := range d.errors {
+		var line, column int
+		msg := e
+		if n, _ := fmt.Sscanf(e, "line %d, col %d:", &line, &column); n == 2 {
+			msg = strings.TrimSpace(e[strings.Index(e, ":")+1:])
+		}
+		r := Range{}
+		if line > 0 {
+			start := d.offset(line, column)
+			r = Range{Start: d.position(start), End: d.position(start)}
+			if i := sort.SearchInts(d.starts, start); i < len(d.starts) && d.starts[i] == start {
+				r = d.tokenRange(i)
+			}
+		}
+		diags = append(diags, Diagnostic{Range: r, Severity: severityError, Source: "tsqlparser", Message: msg})
+	}
+	return diags
+}
+
+// analyze builds the outline and the definitions of the document.
+func (d *document) analyze() {
+	stmts := d.program.Statements
+	firsts := make([]int, len(stmts))
+	for n, stmt := range stmts {
+		firsts[n] = d.index(statementToken(stmt))
+	}
+	for n, stmt := range stmts {
+		first := firsts[n]
+		if first < 0 {
+			continue
+		}
+		// The statement runs up to the next one or the end of its batch
+		last := len(d.tokens) - 1
+		for _, i := range firsts[n+1:] {
+			if i > first {
+				last = i
+				break
+			}
+		}
+		for i := first; i < last; i++ {
+			if d.tokens[i].Type == token.GO {
+				last = i
+				break
+			}
+		}
+		last = d.prev(last)
+		for last > first && d.tokens[last].Type == token.SEMICOLON {
+			last = d.prev(last)
+		}
+		d.symbols = append(d.symbols, d.statement(stmt, first, last)...)
+	}
+}
+
+// statement records what a top-level statement defines and returns its
+// outline entries.
+func (d *document) statement(stmt ast.Statement, first, last int) []*DocumentSymbol {
+	var (
+		kind       string
+		name       *ast.QualifiedIdentifier
+		params     []*ast.ParameterDef
+		body       ast.Statement
+		symbolKind = symbolFunction
+	)
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		kind, name, params, body = "PROCEDURE", s.Name, s.Parameters, s.Body
+	case *ast.AlterProcedureStatement:
+		kind, name, params, body = "PROCEDURE", s.Name, s.Parameters, s.Body
+	case *ast.CreateFunctionStatement:
+		kind, name, params = "FUNCTION", s.Name, s.Parameters
+		if s.Body != nil {
+			body = s.Body
+		}
+	case *ast.AlterFunctionStatement:
+		kind, name, params = "FUNCTION", s.Name, s.Parameters
+		if s.Body != nil {
+			body = s.Body
+		}
+	case *ast.CreateTriggerStatement:
+		kind, name, body = "TRIGGER", s.Name, s.Body
+	case *ast.CreateViewStatement:
+		kind, name, symbolKind = "VIEW", s.Name, symbolClass
+	case *ast.AlterViewStatement:
+		kind, name, symbolKind = "VIEW", s.Name, symbolClass
+	case *ast.CreateTableStatement:
+		kind, name, symbolKind = "TABLE", s.Name, symbolStruct
+	default:
+		// Script-level declarations and labels belong to the batch
+		return d.declarations(stmt, first, "")
+	}
+	if name == nil {
+		return nil
+	}
+
+	// The statement's token may be the PROCEDURE of CREATE OR ALTER PROCEDURE
+	for {
+		i := d.prev(first)
+		if i < 0 || d.tokens[i].Type != token.CREATE && d.tokens[i].Type != token.ALTER && d.tokens[i].Type != token.OR {
+			break
+		}
+		first = i
+	}
+	nameTok := d.nameToken(name, first)
+	sym := &DocumentSymbol{
+		Name:           name.String(),
+		Detail:         strings.ToLower(kind),
+		Kind:           symbolKind,
+		Range:          d.span(first, last),
+		SelectionRange: d.tokenRange(nameTok),
+	}
+	header := kind + " " + name.String()
+	var defs []string
+	at := first
+	for _, p := range params {
+		defs = append(defs, p.String())
+		i := d.find(at, p.Name)
+		if i < 0 {
+			continue
+		}
+		at = i + 1
+		d.vars[d.batch[i]][strings.ToLower(p.Name)] = &declaration{text: "@parameter " + p.String(), tok: i}
+		sym.Children = append(sym.Children, &DocumentSymbol{
+			Name:           p.Name,
+			Detail:         p.DataType.String(),
+			Kind:           symbolVariable,
+			Range:          d.tokenRange(i),
+			SelectionRange: d.tokenRange(i),
+		})
+	}
+	if len(defs) > 0 {
+		if kind == "FUNCTION" {
+			header += "(" + strings.Join(defs, ", ") + ")"
+		} else {
+			header += "\n    " + strings.Join(defs, ",\n    ")
+		}
+	}
+	if f, ok := stmt.(*ast.CreateFunctionStatement); ok && f.ReturnType != nil {
+		header += "\nRETURNS " + f.ReturnType.String()
+	}
+	if body != nil {
+		sym.Children = append(sym.Children, d.declarations(body, at, name.String())...)
+	}
+	if kind != "TRIGGER" {
+		d.objects = append(d.objects, &object{
+			name:   objectName(name),
+			kind:   kind,
+			header: header,
+			doc:    d,
+			tok:    nameTok,
+		})
+	}
+	return []*DocumentSymbol{sym}
+}
+
+// declarations records the variables and labels declared in stmt and the
+// statements inside it, returning their outline entries. at is the token
+// from which to look for the names.
+func (d *document) declarations(stmt ast.Statement, at int, routine string) []*DocumentSymbol {
+	var syms []*DocumentSymbol
+	var visit func(stmt ast.Statement)
+	visit = func(stmt ast.Statement) {
+		switch s := stmt.(type) {
+		case *ast.DeclareStatement:
+			i := d.index(s.Token)
+			if i < 0 {
+				return
+			}
+			for _, v := range s.Variables {
+				j := d.find(i, v.Name)
+				if j < 0 {
+					continue
+				}
+				i = j + 1
+				typ := "TABLE"
+				if v.TableType != nil {
+					typ = v.TableType.String()
+				} else if v.DataType != nil {
+					typ = v.DataType.String()
+				}
+				d.vars[d.batch[j]][strings.ToLower(v.Name)] = &declaration{text: "DECLARE " + v.Name + " " + typ, tok: j}
+				detail := typ
+				if v.TableType != nil {
+					detail = "TABLE"
+				}
+				syms = append(syms, &DocumentSymbol{
+					Name:           v.Name,
+					Detail:         detail,
+					Kind:           symbolVariable,
+					Range:          d.tokenRange(j),
+					SelectionRange: d.tokenRange(j),
+				})
+			}
+		case *ast.LabelStatement:
+			i := d.index(s.Token)
+			if i < 0 {
+				return
+			}
+			text := s.Name.Value + ":"
+			if routine != "" {
+				text += " -- in " + routine
+			}
+			d.labels[d.batch[i]][strings.ToLower(s.Name.Value)] = &declaration{text: text, tok: i}
+			syms = append(syms, &DocumentSymbol{
+				Name:           s.Name.Value,
+				Detail:         "label",
+				Kind:           symbolKey,
+				Range:          d.tokenRange(i),
+				SelectionRange: d.tokenRange(i),
+			})
+		case *ast.BeginEndBlock:
+			for _, s := range s.Statements {
+				visit(s)
+			}
+		case *ast.IfStatement:
+			visit(s.Consequence)
+			if s.Alternative != nil {
+				visit(s.Alternative)
+			}
+		case *ast.WhileStatement:
+			visit(s.Body)
+		case *ast.TryCatchStatement:
+			visit(s.TryBlock)
+			visit(s.CatchBlock)
+		}
+	}
+	visit(stmt)
+	return syms
+}
+
+// nameToken returns the index of the last part of name, looking from
+// token i.
+func (d *document) nameToken(name *ast.QualifiedIdentifier, i int) int {
+	last := name.Parts[len(name.Parts)-1]
+	if j := d.index(last.Token); j >= 0 {
+		return j
+	}
+	for j := i; j < len(d.tokens); j++ {
+		if isName(d.tokens[j]) && strings.EqualFold(d.tokens[j].Literal, last.Value) {
+			return j
+		}
+	}
+	return i
+}
+
+// objectName normalises the name of a schema object for lookups.
+func objectName(name *ast.QualifiedIdentifier) string {
+	var parts []string
+	for _, p := range name.Parts {
+		parts = append(parts, p.Value)
+	}
+	return qualify(parts)
+}
+
+// qualify joins the last two parts of a name, adding the dbo schema to a
+// name without one.
+func qualify(parts []string) string {
+	if len(parts) == 1 {
+		parts = []string{"dbo", parts[0]}
+	}
+	if parts[len(parts)-2] == "" {
+		parts[len(parts)-2] = "dbo"
+	}
+	return strings.ToLower(parts[len(parts)-2] + "." + parts[len(parts)-1])
+}
+
+// dottedName returns the parts of the multi-part name around token i.
+func (d *document) dottedName(i int) []string {
+	first := i
+	for {
+		dot := d.prev(first)
+		if dot < 0 || d.tokens[dot].Type != token.DOT {
+			break
+		}
+		part := d.prev(dot)
+		if part < 0 || !isName(d.tokens[part]) {
+			break
+		}
+		first = part
+	}
+	var parts []string
+	for j := first; j <= i; j = d.next(d.next(j)) {
+		parts = append(parts, d.tokens[j].Literal)
+	}
+	// Name parts after the cursor belong to the same name
+	for j := i; ; {
+		dot := d.next(j)
+		if d.tokens[dot].Type != token.DOT || !isName(d.tokens[d.next(dot)]) {
+			break
+		}
+		j = d.next(dot)
+		parts = append(parts, d.tokens[j].Literal)
+	}
+	return parts
+}
+
+// statementToken returns the first token of a statement.
+func statementToken(stmt ast.Statement) token.Token {
+	switch s := stmt.(type) {
+	case *ast.SelectStatement:
+		return s.Token
+	case *ast.InsertStatement:
+		return s.Token
+	case *ast.UpdateStatement:
+		return s.Token
+	case *ast.DeleteStatement:
+		return s.Token
+	case *ast.DeclareStatement:
+		return s.Token
+	case *ast.SetStatement:
+		return s.Token
+	case *ast.IfStatement:
+		return s.Token
+	case *ast.WhileStatement:
+		return s.Token
+	case *ast.BeginEndBlock:
+		return s.Token
+	case *ast.TryCatchStatement:
+		return s.Token
+	case *ast.ReturnStatement:
+		return s.Token
+	case *ast.PrintStatement:
+		return s.Token
+	case *ast.ExecStatement:
+		return s.Token
+	case *ast.CreateProcedureStatement:
+		return s.Token
+	case *ast.AlterProcedureStatement:
+		return s.Token
+	case *ast.CreateFunctionStatement:
+		return s.Token
+	case *ast.AlterFunctionStatement:
+		return s.Token
+	case *ast.CreateTriggerStatement:
+		return s.Token
+	case *ast.CreateViewStatement:
+		return s.Token
+	case *ast.AlterViewStatement:
+		return s.Token
+	case *ast.CreateTableStatement:
+		return s.Token
+	case *ast.GoStatement:
+		return s.Token
+	case *ast.LabelStatement:
+		return s.Token
+	case *ast.GotoStatement:
+		return s.Token
+	case *ast.WithStatement:
+		return s.Token
+	case *ast.MergeStatement:
+		return s.Token
+	case *ast.ThrowStatement:
+		return s.Token
+	case *ast.RaiserrorStatement:
+		return s.Token
+	case *ast.BeginTransactionStatement:
+		return s.Token
+	case *ast.CommitTransactionStatement:
+		return s.Token
+	case *ast.RollbackTransactionStatement:
+		return s.Token
+	case *ast.DropObjectStatement:
+		return s.Token
+	case *ast.DropTableStatement:
+		return s.Token
+	case *ast.TruncateTableStatement:
+		return s.Token
+	case *ast.AlterTableStatement:
+		return s.Token
+	case *ast.CreateIndexStatement:
+		return s.Token
+	case *ast.UseStatement:
+		return s.Token
+	case *ast.SetOptionStatement:
+		return s.Token
+	case *ast.DeclareCursorStatement:
+		return s.Token
+	case *ast.FetchStatement:
+		return s.Token
+	}
+	return token.Token{}
+}
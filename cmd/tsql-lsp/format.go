@@ -0,0 +1,295 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// blocks matches the BEGIN...END blocks of the document, including
+// BEGIN TRY and BEGIN CATCH. It returns the number of blocks around each
+// token, counting a block's END as outside it, and the BEGIN and END
+// tokens of each block. CASE also ends with END, but is not a block.
+func (d *document) blocks() (depth []int, pairs [][2]int) {
+	type open struct {
+		tok   int
+		block bool
+	}
+	var stack []open
+	depth = make([]int, len(d.tokens))
+	n := 0
+	for i, tok := range d.tokens {
+		depth[i] = n
+		switch tok.Type {
+		case token.BEGIN:
+			next := d.tokens[d.next(i)]
+			switch strings.ToUpper(next.Literal) {
+			case "TRAN", "TRANSACTION", "DISTRIBUTED", "DIALOG", "CONVERSATION":
+				continue
+			}
+			stack = append(stack, open{i, true})
+			n++
+		case token.BEGIN_ATOMIC:
+			stack = append(stack, open{i, true})
+			n++
+		case token.CASE:
+			stack = append(stack, open{i, false})
+		case token.END:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if top.block {
+				n--
+				depth[i] = n
+				pairs = append(pairs, [2]int{top.tok, i})
+			}
+		}
+	}
+	return depth, pairs
+}
+
+// foldingRanges returns the blocks and block comments that span lines.
+// A block folds up to the line before its END, which stays visible.
+func (d *document) foldingRanges() []FoldingRange {
+	ranges := []FoldingRange{}
+	_, pairs := d.blocks()
+	for _, p := range pairs {
+		start, end := d.tokens[p[0]].Line-1, d.tokens[p[1]].Line-2
+		if end > start {
+			ranges = append(ranges, FoldingRange{StartLine: start, EndLine: end})
+		}
+	}
+	for i, tok := range d.tokens {
+		if tok.Type != token.COMMENT {
+			continue
+		}
+		start, end := d.position(d.starts[i]).Line, d.position(d.end(i)).Line
+		if end > start {
+			ranges = append(ranges, FoldingRange{StartLine: start, EndLine: end, Kind: "comment"})
+		}
+	}
+	return ranges
+}
+
+// format re-indents the document by its block structure, upper-cases
+// reserved words and removes trailing spaces. The first line of each
+// statement in a block is indented one level per enclosing block; the
+// lines that continue a statement move with its first line, which keeps
+// their alignment. Comments and strings are left as written. Documents
+// that do not parse are not formatted.
+func (d *document) format(opts FormattingOptions) (string, bool) {
+	if len(d.errors) > 0 {
+		return "", false
+	}
+	tabSize := opts.TabSize
+	if tabSize <= 0 {
+		tabSize = 4
+	}
+	unit := "\t"
+	if opts.InsertSpaces {
+		unit = strings.Repeat(" ", tabSize)
+	}
+
+	text := []byte(d.text)
+	for i, tok := range d.tokens {
+		if reservedWords[strings.ToUpper(tok.Literal)] && tok.Type.IsKeyword() {
+			word := d.text[d.starts[i]:d.end(i)]
+			if strings.EqualFold(word, tok.Literal) {
+				copy(text[d.starts[i]:], strings.ToUpper(word))
+			}
+		}
+	}
+
+	// Lines that begin inside a comment or string are kept, and so is the
+	// end of a line that one continues past.
+	inside := make([]bool, len(d.lines))
+	continued := make([]bool, len(d.lines))
+	first := make([]int, len(d.lines))
+	for l := range first {
+		first[l] = -1
+	}
+	for i := range d.tokens {
+		start, end := d.position(d.starts[i]).Line, d.position(d.end(i)).Line
+		if first[start] < 0 && d.tokens[i].Type != token.EOF {
+			first[start] = i
+		}
+		for l := start; l < end; l++ {
+			continued[l] = true
+			inside[l+1] = true
+		}
+	}
+
+	anchors := d.anchors()
+	depth, _ := d.blocks()
+	var out strings.Builder
+	shift := 0
+	for l, start := range d.lines {
+		end := len(text)
+		if l+1 < len(d.lines) {
+			end = d.lines[l+1]
+		}
+		line := string(text[start:end])
+		if inside[l] {
+			out.WriteString(line)
+			continue
+		}
+		newline := ""
+		if strings.HasSuffix(line, "\n") {
+			newline = "\n"
+			if strings.HasSuffix(line, "\r\n") {
+				newline = "\r\n"
+			}
+			line = line[:len(line)-len(newline)]
+		}
+		content := strings.TrimLeft(line, " \t")
+		if !continued[l] {
+			content = strings.TrimRight(content, " \t")
+		}
+		if content == "" {
+			out.WriteString(newline)
+			continue
+		}
+		old := width(line[:len(line)-len(content)], tabSize)
+		var indent int
+		i := first[l]
+		switch {
+		case i >= 0 && anchors[i]:
+			indent = depth[i] * tabSize
+			shift = indent - old
+		case i >= 0 && d.tokens[i].Type == token.COMMENT && anchors[d.next(i)]:
+			// A comment goes with the statement it introduces
+			indent = depth[d.next(i)] * tabSize
+		default:
+			indent = max(old+shift, 0)
+		}
+		out.WriteString(strings.Repeat(unit, indent/tabSize))
+		out.WriteString(strings.Repeat(" ", indent%tabSize))
+		out.WriteString(content)
+		out.WriteString(newline)
+	}
+	return out.String(), true
+}
+
+// width returns the width of leading white space, with tabs moving to
+// the next tab stop.
+func width(space string, tabSize int) int {
+	n := 0
+	for _, c := range space {
+		if c == '\t' {
+			n += tabSize - n%tabSize
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// anchors returns the tokens whose lines are indented by block depth: the
+// first tokens of the statements of the script and of blocks, and the
+// BEGIN and END of blocks.
+func (d *document) anchors() map[int]bool {
+	anchors := make(map[int]bool)
+	_, pairs := d.blocks()
+	for _, p := range pairs {
+		anchors[p[0]] = true
+		anchors[p[1]] = true
+	}
+	var visit func(stmt ast.Statement, anchor bool)
+	// The statements of a routine without BEGIN...END continue its header
+	body := func(b *ast.BeginEndBlock) {
+		if b == nil {
+			return
+		}
+		if i := d.index(b.Token); i >= 0 && d.tokens[i].Type == token.BEGIN {
+			visit(b, false)
+			return
+		}
+		for _, stmt := range b.Statements {
+			visit(stmt, false)
+		}
+	}
+	visit = func(stmt ast.Statement, anchor bool) {
+		if anchor {
+			if i := d.index(statementToken(stmt)); i >= 0 {
+				anchors[i] = true
+			}
+		}
+		switch s := stmt.(type) {
+		case *ast.BeginEndBlock:
+			for _, stmt := range s.Statements {
+				visit(stmt, true)
+			}
+		case *ast.IfStatement:
+			visit(s.Consequence, false)
+			if s.Alternative != nil {
+				visit(s.Alternative, false)
+			}
+		case *ast.WhileStatement:
+			visit(s.Body, false)
+		case *ast.TryCatchStatement:
+			visit(s.TryBlock, false)
+			visit(s.CatchBlock, false)
+		case *ast.CreateProcedureStatement:
+			body(s.Body)
+		case *ast.AlterProcedureStatement:
+			body(s.Body)
+		case *ast.CreateFunctionStatement:
+			body(s.Body)
+		case *ast.AlterFunctionStatement:
+			body(s.Body)
+		case *ast.CreateTriggerStatement:
+			body(s.Body)
+		}
+	}
+	for _, stmt := range d.program.Statements {
+		visit(stmt, true)
+	}
+	return anchors
+}
+
+// reservedWords are the reserved keywords of T-SQL, which cannot name
+// anything unless quoted and so can be upper-cased safely.
+var reservedWords = map[string]bool{
+	"ADD": true, "ALL": true, "ALTER": true, "AND": true, "ANY": true, "AS": true,
+	"ASC": true, "AUTHORIZATION": true, "BACKUP": true, "BEGIN": true, "BETWEEN": true,
+	"BREAK": true, "BROWSE": true, "BULK": true, "BY": true, "CASCADE": true,
+	"CASE": true, "CHECK": true, "CHECKPOINT": true, "CLOSE": true, "CLUSTERED": true,
+	"COALESCE": true, "COLLATE": true, "COLUMN": true, "COMMIT": true, "COMPUTE": true,
+	"CONSTRAINT": true, "CONTAINS": true, "CONTAINSTABLE": true, "CONTINUE": true,
+	"CONVERT": true, "CREATE": true, "CROSS": true, "CURRENT": true, "CURRENT_DATE": true,
+	"CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "CURRENT_USER": true, "CURSOR": true,
+	"DATABASE": true, "DBCC": true, "DEALLOCATE": true, "DECLARE": true, "DEFAULT": true,
+	"DELETE": true, "DENY": true, "DESC": true, "DISK": true, "DISTINCT": true,
+	"DISTRIBUTED": true, "DOUBLE": true, "DROP": true, "DUMP": true, "ELSE": true,
+	"END": true, "ERRLVL": true, "ESCAPE": true, "EXCEPT": true, "EXEC": true,
+	"EXECUTE": true, "EXISTS": true, "EXIT": true, "EXTERNAL": true, "FETCH": true,
+	"FILE": true, "FILLFACTOR": true, "FOR": true, "FOREIGN": true, "FREETEXT": true,
+	"FREETEXTTABLE": true, "FROM": true, "FULL": true, "FUNCTION": true, "GOTO": true,
+	"GRANT": true, "GROUP": true, "HAVING": true, "HOLDLOCK": true, "IDENTITY": true,
+	"IDENTITY_INSERT": true, "IDENTITYCOL": true, "IF": true, "IN": true, "INDEX": true,
+	"INNER": true, "INSERT": true, "INTERSECT": true, "INTO": true, "IS": true,
+	"JOIN": true, "KEY": true, "KILL": true, "LEFT": true, "LIKE": true, "LINENO": true,
+	"LOAD": true, "MERGE": true, "NATIONAL": true, "NOCHECK": true, "NONCLUSTERED": true,
+	"NOT": true, "NULL": true, "NULLIF": true, "OF": true, "OFF": true, "OFFSETS": true,
+	"ON": true, "OPEN": true, "OPENDATASOURCE": true, "OPENQUERY": true,
+	"OPENROWSET": true, "OPENXML": true, "OPTION": true, "OR": true, "ORDER": true,
+	"OUTER": true, "OVER": true, "PERCENT": true, "PIVOT": true, "PLAN": true,
+	"PRECISION": true, "PRIMARY": true, "PRINT": true, "PROC": true, "PROCEDURE": true,
+	"PUBLIC": true, "RAISERROR": true, "READ": true, "READTEXT": true,
+	"RECONFIGURE": true, "REFERENCES": true, "REPLICATION": true, "RESTORE": true,
+	"RESTRICT": true, "RETURN": true, "REVERT": true, "REVOKE": true, "RIGHT": true,
+	"ROLLBACK": true, "ROWCOUNT": true, "ROWGUIDCOL": true, "RULE": true, "SAVE": true,
+	"SCHEMA": true, "SECURITYAUDIT": true, "SELECT": true, "SEMANTICKEYPHRASETABLE": true,
+	"SEMANTICSIMILARITYDETAILSTABLE": true, "SEMANTICSIMILARITYTABLE": true,
+	"SESSION_USER": true, "SET": true, "SETUSER": true, "SHUTDOWN": true, "SOME": true,
+	"STATISTICS": true, "SYSTEM_USER": true, "TABLE": true, "TABLESAMPLE": true,
+	"TEXTSIZE": true, "THEN": true, "TO": true, "TOP": true, "TRAN": true,
+	"TRANSACTION": true, "TRIGGER": true, "TRUNCATE": true, "TRY_CONVERT": true,
+	"TSEQUAL": true, "UNION": true, "UNIQUE": true, "UNPIVOT": true, "UPDATE": true,
+	"UPDATETEXT": true, "USE": true, "USER": true, "VALUES": true, "VARYING": true,
+	"VIEW": true, "WAITFOR": true, "WHEN": true, "WHERE": true, "WHILE": true,
+	"WITH": true, "WITHIN": true, "WRITETEXT": true,
+}
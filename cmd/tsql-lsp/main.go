@@ -0,0 +1,41 @@
+// Command tsql-lsp is a Language Server Protocol server for T-SQL.
+//
+// It speaks LSP over standard input and output and provides:
+//
+//   - parse errors as diagnostics
+//   - an outline of procedures, functions, views, tables, variables and labels
+//   - go to definition for variables, labels and schema objects, looking
+//     through every .sql file in the workspace for the objects
+//   - hover showing the declaration of a variable or parameter, or the
+//     header of a procedure or function
+//   - folding of BEGIN...END and TRY...CATCH blocks and block comments
+//   - formatting, which re-indents blocks and upper-cases reserved words
+//   - completion of keywords, variables, aliases, functions and the columns
+//     of the tables created in the workspace
+//
+// Usage:
+//
+//	tsql-lsp
+//
+// For example, with Neovim's built-in client:
+//
+//	vim.lsp.start({ name = "tsql-lsp", cmd = { "tsql-lsp" }, root_dir = vim.fn.getcwd() })
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tsql-lsp\n\nServes the Language Server Protocol on standard input and output.\n")
+	}
+	flag.Parse()
+
+	if err := newServer(os.Stdout, os.Stderr).serve(os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "tsql-lsp: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/complete"
+	"github.com/ha1tch/tsqlparser/schemadiff"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// server answers the requests of one client.
+type server struct {
+	out      io.Writer
+	log      io.Writer
+	open     map[string]*document // documents open in the editor, by URI
+	files    map[string]*document // .sql files of the workspace, by URI
+	shutdown bool
+}
+
+func newServer(out, log io.Writer) *server {
+	return &server{
+		out:   out,
+		log:   log,
+		open:  make(map[string]*document),
+		files: make(map[string]*document),
+	}
+}
+
+// serve reads and answers messages until the client exits.
+func (s *server) serve(in io.Reader) error {
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(req.Method, req.Params)
+		if req.ID == nil {
+			if err != nil {
+				s.logf("%s: %v", req.Method, err)
+			}
+			continue
+		}
+		s.reply(req.ID, result, err)
+	}
+}
+
+func (s *server) reply(id json.RawMessage, result any, err error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	var msg any = response{JSONRPC: "2.0", ID: id, Result: result}
+	if err != nil {
+		var rerr *responseError
+		if !errors.As(err, &rerr) {
+			rerr = &responseError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		msg = errorResponse{JSONRPC: "2.0", ID: id, Error: rerr}
+	}
+	if err := writeMessage(s.out, msg); err != nil {
+		s.logf("write: %v", err)
+	}
+}
+
+func (s *server) notify(method string, params any) {
+	if err := writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		s.logf("write: %v", err)
+	}
+}
+
+func (s *server) logf(format string, args ...any) {
+	if s.log != nil {
+		fmt.Fprintf(s.log, "tsql-lsp: "+format+"\n", args...)
+	}
+}
+
+// handle dispatches a request or notification by method.
+func (s *server) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p InitializeParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		roots := []string{p.RootURI}
+		for _, f := range p.WorkspaceFolders {
+			roots = append(roots, f.URI)
+		}
+		s.loadWorkspace(roots)
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":           1, // full
+				"documentSymbolProvider":     true,
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"foldingRangeProvider":       true,
+				"documentFormattingProvider": true,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{".", "@"},
+				},
+			},
+			"serverInfo": map[string]string{"name": "tsql-lsp"},
+		}, nil
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.update(p.TextDocument.URI, p.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		uri := p.TextDocument.URI
+		delete(s.open, uri)
+		// The file may have been saved since the workspace was loaded
+		if _, ok := s.files[uri]; ok {
+			if data, err := os.ReadFile(uriPath(uri)); err == nil {
+				s.files[uri] = newDocument(uri, string(data))
+			}
+		}
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}})
+		return nil, nil
+	case "textDocument/documentSymbol":
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		if d.symbols == nil {
+			return []*DocumentSymbol{}, nil
+		}
+		return d.symbols, nil
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.definition(d, p.Position), nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.hover(d, p.Position), nil
+	case "textDocument/foldingRange":
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return d.foldingRanges(), nil
+	case "textDocument/formatting":
+		var p DocumentFormattingParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		text, ok := d.format(p.Options)
+		if !ok || text == d.text {
+			return []TextEdit{}, nil
+		}
+		whole := Range{End: d.position(len(d.text))}
+		return []TextEdit{{Range: whole, NewText: text}}, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(d, p.Position), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+func unmarshal(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// document returns the document a request is about.
+func (s *server) document(params json.RawMessage) (*document, error) {
+	var p DocumentParams
+	if err := unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if d, ok := s.open[p.TextDocument.URI]; ok {
+		return d, nil
+	}
+	if d, ok := s.files[p.TextDocument.URI]; ok {
+		return d, nil
+	}
+	return nil, &responseError{Code: codeInvalidParams, Message: "unknown document " + p.TextDocument.URI}
+}
+
+// update parses the new text of an open document and publishes its
+// parse errors.
+func (s *server) update(uri, text string) {
+	d := newDocument(uri, text)
+	s.open[uri] = d
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics()})
+}
+
+// loadWorkspace parses the .sql files under the workspace folders, so
+// that definitions are found in files that are not open.
+func (s *server) loadWorkspace(roots []string) {
+	for _, root := range roots {
+		dir := uriPath(root)
+		if dir == "" {
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if e.IsDir() {
+				if path != dir && strings.HasPrefix(e.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.EqualFold(filepath.Ext(path), ".sql") {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				s.logf("%v", err)
+				return nil
+			}
+			uri := pathURI(path)
+			s.files[uri] = newDocument(uri, string(data))
+			return nil
+		})
+	}
+}
+
+// documents returns every document, preferring the open version of a
+// workspace file.
+func (s *server) documents() []*document {
+	var docs []*document
+	for _, d := range s.open {
+		docs = append(docs, d)
+	}
+	for uri, d := range s.files {
+		if _, ok := s.open[uri]; !ok {
+			docs = append(docs, d)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].uri < docs[j].uri })
+	return docs
+}
+
+// objects returns the schema objects called name in the workspace.
+func (s *server) objects(name string) []*object {
+	var objs []*object
+	for _, d := range s.documents() {
+		for _, o := range d.objects {
+			if o.name == name {
+				objs = append(objs, o)
+			}
+		}
+	}
+	return objs
+}
+
+// resolve finds what the token at a position refers to: a variable or
+// label declared in its batch, or a schema object anywhere in the
+// workspace.
+func (s *server) resolve(d *document, p Position) (i int, decl *declaration, objs []*object) {
+	i = d.tokenAt(p)
+	if i < 0 {
+		return -1, nil, nil
+	}
+	tok := d.tokens[i]
+	switch {
+	case tok.Type == token.VARIABLE:
+		return i, d.vars[d.batch[i]][strings.ToLower(tok.Literal)], nil
+	case !isName(tok):
+		return -1, nil, nil
+	}
+	prev, next := d.prev(i), d.next(i)
+	if prev >= 0 && d.tokens[prev].Type == token.GOTO || d.tokens[next].Type == token.COLON {
+		return i, d.labels[d.batch[i]][strings.ToLower(tok.Literal)], nil
+	}
+	return i, nil, s.objects(qualify(d.dottedName(i)))
+}
+
+func (s *server) definition(d *document, p Position) []Location {
+	locs := []Location{}
+	_, decl, objs := s.resolve(d, p)
+	if decl != nil {
+		locs = append(locs, Location{URI: d.uri, Range: d.tokenRange(decl.tok)})
+	}
+	for _, o := range objs {
+		locs = append(locs, Location{URI: o.doc.uri, Range: o.doc.tokenRange(o.tok)})
+	}
+	return locs
+}
+
+func (s *server) hover(d *document, p Position) *Hover {
+	i, decl, objs := s.resolve(d, p)
+	var text string
+	switch {
+	case decl != nil:
+		text = decl.text
+	case len(objs) > 0:
+		text = objs[0].header
+	default:
+		return nil
+	}
+	r := d.tokenRange(i)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```sql\n" + text + "\n```"},
+		Range:    &r,
+	}
+}
+
+// completionKinds maps candidate kinds to completion item kinds.
+var completionKinds = map[complete.Kind]int{
+	complete.Variable: completionVariable,
+	complete.Column:   completionField,
+	complete.Table:    completionClass,
+	complete.Function: completionFunction,
+	complete.Keyword:  completionKeyword,
+}
+
+// completion completes the word at a position, with the tables created
+// in the workspace as the catalog.
+func (s *server) completion(d *document, p Position) []CompletionItem {
+	catalog := schemadiff.NewSchema()
+	for _, doc := range s.documents() {
+		catalog.AddProgram(doc.program)
+	}
+	items := []CompletionItem{}
+	for _, c := range complete.Complete(d.text, d.byteOffset(p), catalog) {
+		items = append(items, CompletionItem{Label: c.Label, Kind: completionKinds[c.Kind], Detail: c.Detail})
+	}
+	return items
+}
+
+// uriPath returns the file path of a file URI, or "".
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
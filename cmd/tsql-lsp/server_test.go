@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const transfer = `CREATE PROCEDURE dbo.Transfer
+    @From INT,
+    @Amount DECIMAL(10, 2) OUTPUT
+AS
+BEGIN
+    DECLARE @Note NVARCHAR(50) = N'Überweisung 🙂', @i INT;
+    BEGIN TRY
+        IF @Amount > 0
+            SET @i = 1
+        GOTO done;
+    END TRY
+    BEGIN CATCH
+        THROW;
+    END CATCH
+done:
+    EXEC dbo.Audit @Note;
+END
+`
+
+const audit = `CREATE PROCEDURE dbo.Audit @Msg NVARCHAR(50)
+AS
+    INSERT INTO AuditLog (Msg) VALUES (@Msg);
+`
+
+func newTestServer(t *testing.T) (*server, *document) {
+	t.Helper()
+	s := newServer(&bytes.Buffer{}, nil)
+	s.files["file:///audit.sql"] = newDocument("file:///audit.sql", audit)
+	d := newDocument("file:///transfer.sql", transfer)
+	if len(d.errors) > 0 {
+		t.Fatalf("parser errors: %v", d.errors)
+	}
+	s.open[d.uri] = d
+	return s, d
+}
+
+func TestPositions(t *testing.T) {
+	d := newDocument("file:///x.sql", "SELECT '🙂', @x\nSELECT é")
+	// @x is the 13th rune of the line, but the emoji takes two UTF-16 units
+	i := d.find(0, "@x")
+	if got, want := d.tokenRange(i), (Range{Position{0, 13}, Position{0, 15}}); got != want {
+		t.Errorf("range of @x = %v, want %v", got, want)
+	}
+	if got := d.tokenAt(Position{0, 14}); got != i {
+		t.Errorf("token at 0:14 = %d, want %d", got, i)
+	}
+	if got := d.tokenAt(Position{0, 15}); got != i {
+		t.Errorf("token at the end of @x = %d, want %d", got, i)
+	}
+	for _, p := range []Position{{0, 0}, {0, 7}, {0, 11}, {1, 7}, {1, 8}} {
+		if got := d.position(d.byteOffset(p)); got != p {
+			t.Errorf("position(byteOffset(%v)) = %v", p, got)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	d := newDocument("file:///x.sql", "SELECT N'🙂', (1 + ) FROM t")
+	diags := d.diagnostics()
+	if len(diags) == 0 {
+		t.Fatal("no diagnostics")
+	}
+	// The parser reports column 19 in runes; the emoji is two UTF-16 units
+	if got, want := diags[0].Range, (Range{Position{0, 19}, Position{0, 20}}); got != want {
+		t.Errorf("range = %v, want %v", got, want)
+	}
+	if want := "no prefix parse function for ) found"; diags[0].Message != want {
+		t.Errorf("message = %q, want %q", diags[0].Message, want)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	_, d := newTestServer(t)
+	var got []string
+	var walk func(syms []*DocumentSymbol, indent string)
+	walk = func(syms []*DocumentSymbol, indent string) {
+		for _, sym := range syms {
+			got = append(got, fmt.Sprintf("%s%s %d %s", indent, sym.Name, sym.Kind, sym.Detail))
+			walk(sym.Children, indent+"  ")
+		}
+	}
+	walk(d.symbols, "")
+	want := []string{
+		"dbo.Transfer 12 procedure",
+		"  @From 13 INT",
+		"  @Amount 13 DECIMAL(10, 2)",
+		"  @Note 13 NVARCHAR(50)",
+		"  @i 13 INT",
+		"  done 20 label",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("symbols:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if r := d.symbols[0].Range; r.Start != (Position{0, 0}) || r.End != (Position{16, 3}) {
+		t.Errorf("procedure range = %v", r)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	s, d := newTestServer(t)
+	tests := []struct {
+		at   Position
+		want string
+	}{
+		{Position{7, 13}, "file:///transfer.sql 2:4"},   // @Amount
+		{Position{8, 17}, "file:///transfer.sql 5:52"},  // @i, after the emoji
+		{Position{15, 21}, "file:///transfer.sql 5:12"}, // @Note
+		{Position{9, 14}, "file:///transfer.sql 14:0"},  // GOTO done
+		{Position{15, 14}, "file:///audit.sql 0:21"},    // dbo.Audit
+		{Position{15, 10}, "file:///audit.sql 0:21"},    // the dbo of dbo.Audit
+		{Position{6, 4}, ""},                            // BEGIN
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, loc := range s.definition(d, tt.at) {
+			got = append(got, fmt.Sprintf("%s %d:%d", loc.URI, loc.Range.Start.Line, loc.Range.Start.Character))
+		}
+		if strings.Join(got, ", ") != tt.want {
+			t.Errorf("definition at %v = %v, want %s", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	s, d := newTestServer(t)
+	tests := []struct {
+		at   Position
+		want string
+	}{
+		{Position{15, 21}, "DECLARE @Note NVARCHAR(50)"},
+		{Position{7, 13}, "@parameter @Amount DECIMAL(10, 2) OUTPUT"},
+		{Position{15, 14}, "PROCEDURE dbo.Audit\n    @Msg NVARCHAR(50)"},
+	}
+	for _, tt := range tests {
+		h := s.hover(d, tt.at)
+		if h == nil {
+			t.Errorf("no hover at %v", tt.at)
+			continue
+		}
+		if want := "```sql\n" + tt.want + "\n```"; h.Contents.Value != want {
+			t.Errorf("hover at %v = %q, want %q", tt.at, h.Contents.Value, want)
+		}
+	}
+	if h := s.hover(d, Position{3, 0}); h != nil {
+		t.Errorf("hover on AS = %+v, want none", h)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.files["file:///tables.sql"] = newDocument("file:///tables.sql", "CREATE TABLE dbo.AuditLog (Id INT, Msg NVARCHAR(50));")
+	d := newDocument("file:///query.sql", "SELECT a.M FROM AuditLog a")
+	s.open[d.uri] = d
+
+	items := s.completion(d, Position{0, 10})
+	if len(items) != 1 || items[0] != (CompletionItem{Label: "Msg", Kind: completionField, Detail: "NVARCHAR(50)"}) {
+		t.Errorf("completion = %+v", items)
+	}
+	items = s.completion(d, Position{0, 0})
+	if len(items) == 0 || items[0].Kind != completionKeyword {
+		t.Errorf("completion at start = %+v", items)
+	}
+}
+
+func TestFoldingRanges(t *testing.T) {
+	_, d := newTestServer(t)
+	got := fmt.Sprint(d.foldingRanges())
+	// BEGIN TRY, BEGIN CATCH and the procedure's BEGIN, each up to the
+	// line before its END
+	if want := "[{6 9 } {11 12 } {4 15 }]"; got != want {
+		t.Errorf("folding ranges = %s, want %s", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	input := "create procedure dbo.P\n" +
+		"as\n" +
+		"begin   \n" +
+		"  select a,\n" +
+		"         b\n" +
+		"  from t -- where [select] = 1\n" +
+		"      if @x = 1\n" +
+		"      begin\n" +
+		"  /* keep\n" +
+		"        this */\n" +
+		"      print 'one\n" +
+		"   two'\n" +
+		"  end\n" +
+		"end\n"
+	want := "CREATE PROCEDURE dbo.P\n" +
+		"AS\n" +
+		"BEGIN\n" +
+		"    SELECT a,\n" +
+		"           b\n" +
+		"    FROM t -- where [select] = 1\n" +
+		"    IF @x = 1\n" +
+		"    BEGIN\n" +
+		"        /* keep\n" +
+		"        this */\n" +
+		"        PRINT 'one\n" +
+		"   two'\n" +
+		"    END\n" +
+		"END\n"
+	d := newDocument("file:///x.sql", input)
+	got, ok := d.format(FormattingOptions{TabSize: 4, InsertSpaces: true})
+	if !ok {
+		t.Fatalf("not formatted: %v", d.errors)
+	}
+	if got != want {
+		t.Errorf("format:\n%s\nwant:\n%s", got, want)
+	}
+	if again, _ := newDocument("file:///x.sql", got).format(FormattingOptions{TabSize: 4, InsertSpaces: true}); again != got {
+		t.Errorf("formatting is not stable:\n%s", again)
+	}
+
+	if _, ok := newDocument("file:///x.sql", "SELECT FROM WHERE").format(FormattingOptions{}); ok {
+		t.Error("formatted a document that does not parse")
+	}
+}
+
+// exchange sends messages to a server and returns what it writes.
+func exchange(t *testing.T, s *server, messages ...any) []map[string]any {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range messages {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.serve(&in); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	var out []map[string]any
+	r := bufio.NewReader(s.out.(*bytes.Buffer))
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var m map[string]any
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "audit.sql"), []byte(audit), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathURI(filepath.Join(dir, "transfer.sql"))
+	type msg = map[string]any
+	s := newServer(&bytes.Buffer{}, nil)
+	out := exchange(t, s,
+		msg{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": msg{"rootUri": pathURI(dir)}},
+		msg{"jsonrpc": "2.0", "method": "initialized", "params": msg{}},
+		msg{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": msg{
+			"textDocument": msg{"uri": uri, "version": 1, "text": "SELECT FROM"}}},
+		msg{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": msg{
+			"textDocument": msg{"uri": uri}, "contentChanges": []msg{{"text": transfer}}}},
+		msg{"jsonrpc": "2.0", "id": 2, "method": "textDocument/definition", "params": msg{
+			"textDocument": msg{"uri": uri}, "position": msg{"line": 15, "character": 14}}},
+		msg{"jsonrpc": "2.0", "id": 3, "method": "textDocument/unknown", "params": msg{}},
+		msg{"jsonrpc": "2.0", "id": 4, "method": "shutdown"},
+		msg{"jsonrpc": "2.0", "method": "exit"},
+	)
+	if len(out) != 6 {
+		t.Fatalf("got %d messages, want 6: %v", len(out), out)
+	}
+	caps := out[0]["result"].(msg)["capabilities"].(msg)
+	if caps["definitionProvider"] != true || caps["textDocumentSync"] != 1.0 {
+		t.Errorf("capabilities = %v", caps)
+	}
+	if completion, ok := caps["completionProvider"].(msg); !ok || fmt.Sprint(completion["triggerCharacters"]) != "[. @]" {
+		t.Errorf("completionProvider = %v", caps["completionProvider"])
+	}
+	if out[1]["method"] != "textDocument/publishDiagnostics" || len(out[1]["params"].(msg)["diagnostics"].([]any)) == 0 {
+		t.Errorf("no diagnostics for the opened document: %v", out[1])
+	}
+	if diags := out[2]["params"].(msg)["diagnostics"].([]any); len(diags) != 0 {
+		t.Errorf("diagnostics after the change = %v, want none", diags)
+	}
+	locs := out[3]["result"].([]any)
+	if len(locs) != 1 || !strings.HasSuffix(locs[0].(msg)["uri"].(string), "/audit.sql") {
+		t.Errorf("definition = %v, want audit.sql from the workspace", locs)
+	}
+	if e := out[4]["error"].(msg); e["code"] != float64(codeMethodNotFound) {
+		t.Errorf("unknown method error = %v", e)
+	}
+	if r, ok := out[5]["result"]; !ok || r != nil {
+		t.Errorf("shutdown response = %v, want a null result", out[5])
+	}
+}
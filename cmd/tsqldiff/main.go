@@ -0,0 +1,77 @@
+// Command tsqldiff compares two versions of a T-SQL script by their syntax
+// trees, ignoring whitespace, comments, keyword case and formatting:
+//
+//	tsqldiff old/GetOrders.sql new/GetOrders.sql
+//	git difftool -y -x tsqldiff -- procs/
+//
+// Each change is printed on a line of its own, or as a JSON document with
+// -json; see package semdiff. The exit status is 0 if the versions are
+// equivalent, 1 if they differ and 2 if there was an error.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/semdiff"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tsqldiff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print the changes as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tsqldiff [flags] old.sql new.sql\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	var programs [2]*ast.Program
+	for i, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "tsqldiff: %v\n", err)
+			return 2
+		}
+		program, errors := tsqlparser.Parse(string(data))
+		if len(errors) > 0 {
+			// A partial tree would show changes that are not there
+			for _, e := range errors {
+				fmt.Fprintf(stderr, "%s: %s\n", path, e)
+			}
+			return 2
+		}
+		programs[i] = program
+	}
+
+	diff := semdiff.Compare(programs[0], programs[1])
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			fmt.Fprintf(stderr, "tsqldiff: %v\n", err)
+			return 2
+		}
+	} else {
+		fmt.Fprint(stdout, diff)
+	}
+	if diff.Empty() {
+		return 0
+	}
+	return 1
+}
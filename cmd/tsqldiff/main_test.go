@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func diff(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	old := write(t, dir, "old.sql", "CREATE PROCEDURE dbo.P @Id INT AS\nSELECT Name FROM dbo.T WHERE Id = @Id\n")
+	reformatted := write(t, dir, "reformatted.sql", "create procedure dbo.P\n    @id int\nas\nbegin\n    -- by id\n    select name\n    from dbo.t\n    where id = @id;\nend\n")
+	changed := write(t, dir, "new.sql", "CREATE PROCEDURE dbo.P @Id INT AS\nSELECT Name, Email FROM dbo.T WHERE Id = @Id\n")
+
+	if status, out, errs := diff(old, reformatted); status != 0 || out != "" || errs != "" {
+		t.Errorf("reformatted: status %d, stdout %q, stderr %q", status, out, errs)
+	}
+
+	status, out, _ := diff(old, changed)
+	if want := "line 2: PROCEDURE dbo.P: column added: Email\n"; status != 1 || out != want {
+		t.Errorf("status %d, got %q, want %q", status, out, want)
+	}
+
+	status, out, _ = diff("-json", old, changed)
+	var doc struct {
+		Changes []struct {
+			Kind string   `json:"kind"`
+			Path []string `json:"path"`
+			What string   `json:"what"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if status != 1 || len(doc.Changes) != 1 || doc.Changes[0].Kind != "added" || doc.Changes[0].What != "column" ||
+		len(doc.Changes[0].Path) != 1 || doc.Changes[0].Path[0] != "PROCEDURE dbo.P" {
+		t.Errorf("status %d, JSON %s", status, out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	dir := t.TempDir()
+	good := write(t, dir, "good.sql", "SELECT 1")
+	bad := write(t, dir, "bad.sql", "SELECT FROM WHERE (")
+
+	if status, _, errs := diff(good, bad); status != 2 || errs == "" {
+		t.Errorf("unparsable script: status %d, stderr %q", status, errs)
+	}
+	if status, _, _ := diff(good); status != 2 {
+		t.Errorf("one file: status %d", status)
+	}
+	if status, _, _ := diff(good, filepath.Join(dir, "missing.sql")); status != 2 {
+		t.Errorf("missing file: status %d", status)
+	}
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/signature"
+)
+
+const (
+	defaultDecimalType = "github.com/shopspring/decimal.Decimal"
+	defaultUUIDType    = "github.com/microsoft/go-mssqldb.UniqueIdentifier"
+	mssqlPackage       = "github.com/microsoft/go-mssqldb"
+)
+
+// packageNames holds import paths whose package name differs from the
+// last element of the path.
+var packageNames = map[string]string{
+	"github.com/microsoft/go-mssqldb":  "mssql",
+	"github.com/denisenkom/go-mssqldb": "mssql",
+}
+
+// Generator produces Go wrappers for the procedures in one or more programs.
+type Generator struct {
+	Package     string
+	DecimalType string // import/path.Type used for DECIMAL, NUMERIC and MONEY
+	UUIDType    string // import/path.Type used for UNIQUEIDENTIFIER
+
+	programs   []*ast.Program
+	tableTypes map[string]*ast.CreateTypeStatement
+
+	buf      bytes.Buffer
+	imports  map[string]string // import path -> package name
+	needMap  bool
+	names    map[string]bool // Go names declared at package level
+	rowTypes map[*ast.CreateTypeStatement]string
+}
+
+// NewGenerator creates a Generator for the named Go package.
+func NewGenerator(pkg string) *Generator {
+	return &Generator{
+		Package:     pkg,
+		DecimalType: defaultDecimalType,
+		UUIDType:    defaultUUIDType,
+		tableTypes:  make(map[string]*ast.CreateTypeStatement),
+	}
+}
+
+// AddProgram adds the procedures, tables and table types in program.
+func (g *Generator) AddProgram(program *ast.Program) {
+	g.programs = append(g.programs, program)
+	for _, stmt := range program.Statements {
+		if s, ok := stmt.(*ast.CreateTypeStatement); ok && s.IsTableType && s.TableDef != nil {
+			g.tableTypes[strings.ToLower(s.Name.String())] = s
+		}
+	}
+}
+
+// Generate returns the formatted Go source for every procedure added so far.
+func (g *Generator) Generate() ([]byte, error) {
+	e := signature.NewExtractor()
+	for _, program := range g.programs {
+		e.AddProgram(program)
+	}
+	var sigs []*signature.Signature
+	for _, program := range g.programs {
+		for _, stmt := range program.Statements {
+			if sig, ok := e.Statement(stmt); ok && sig.Kind == signature.KindProcedure {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+
+	g.buf.Reset()
+	g.imports = map[string]string{"context": "context", "database/sql": "sql"}
+	g.needMap = false
+	g.names = map[string]bool{"Querier": true, "scanMaps": true}
+	g.rowTypes = make(map[*ast.CreateTypeStatement]string)
+
+	g.printf("// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn.\n")
+	g.printf("type Querier interface {\n")
+	g.printf("QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)\n")
+	g.printf("}\n\n")
+
+	var typeNames []string
+	for name := range g.tableTypes {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		t := g.tableTypes[name]
+		base := goName(t.Name.Parts[len(t.Name.Parts)-1].Value)
+		g.rowTypes[t] = g.unique(t.Name, base, func(base string) []string { return []string{base + "Row"} })[0]
+	}
+	fns := make([]string, len(sigs))
+	for i, sig := range sigs {
+		fns[i] = g.unique(sig.Name, funcName(sig.Name), func(fn string) []string { return procedureNames(fn, sig) })[0]
+	}
+
+	for _, name := range typeNames {
+		g.tableType(g.tableTypes[name])
+	}
+	for i, sig := range sigs {
+		g.procedure(fns[i], sig)
+	}
+	if g.needMap {
+		g.scanMapsHelper()
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tsqlgen. DO NOT EDIT.\n\npackage %s\n\n", g.Package)
+	out.WriteString(g.importBlock())
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+func (g *Generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *Generator) importBlock() string {
+	var std, other []string
+	for path := range g.imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	var sb strings.Builder
+	sb.WriteString("import (\n")
+	for i, group := range [][]string{std, other} {
+		if i > 0 && len(group) > 0 {
+			sb.WriteString("\n")
+		}
+		for _, path := range group {
+			if name := g.imports[path]; name != lastElement(path) {
+				fmt.Fprintf(&sb, "%s %q\n", name, path)
+			} else {
+				fmt.Fprintf(&sb, "%q\n", path)
+			}
+		}
+	}
+	sb.WriteString(")\n\n")
+	return sb.String()
+}
+
+// use records an import and returns its package name.
+func (g *Generator) use(path string) string {
+	name, ok := packageNames[path]
+	if !ok {
+		name = lastElement(path)
+	}
+	g.imports[path] = name
+	return name
+}
+
+// qualified returns the Go expression for an import/path.Type spec.
+func (g *Generator) qualified(spec string) string {
+	i := strings.LastIndex(spec, ".")
+	if i < 0 {
+		return spec // Built-in or local type such as string
+	}
+	return g.use(spec[:i]) + "." + spec[i+1:]
+}
+
+func lastElement(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func (g *Generator) tableType(t *ast.CreateTypeStatement) {
+	name := g.rowTypes[t]
+	g.printf("// %s is a row of the %s table type.\n", name, t.Name)
+	g.printf("type %s struct {\n", name)
+	fields := newFieldNames()
+	for _, col := range t.TableDef.Columns {
+		nullable := col.Nullable == nil || *col.Nullable
+		g.printf("%s %s\n", fields.add(col.Name.Value), g.goType(col.DataType, nullable, "*"))
+	}
+	g.printf("}\n\n")
+}
+
+// quotedName brackets every part of a procedure name, so that the driver
+// can call procedures whose names are not regular identifiers.
+func quotedName(name *ast.QualifiedIdentifier) string {
+	parts := make([]string, len(name.Parts))
+	for i, part := range name.Parts {
+		parts[i] = "[" + strings.ReplaceAll(part.Value, "]", "]]") + "]"
+	}
+	return strings.Join(parts, ".")
+}
+
+// unique declares the Go names made by names from base, or if one of
+// them is taken, from the name of the object with its schema and then
+// with a number as well.
+func (g *Generator) unique(name *ast.QualifiedIdentifier, base string, names func(base string) []string) []string {
+	n := len(name.Parts)
+	qualified := "Dbo" + goName(name.Parts[n-1].Value)
+	if n > 1 {
+		qualified = goName(name.Parts[n-2].Value) + goName(name.Parts[n-1].Value)
+	}
+	for i := 1; ; i++ {
+		switch {
+		case i == 2:
+			base = qualified
+		case i > 2:
+			base = fmt.Sprintf("%s%d", qualified, i-1)
+		}
+		out := names(base)
+		if !slices.ContainsFunc(out, func(name string) bool { return g.names[name] }) {
+			for _, name := range out {
+				g.names[name] = true
+			}
+			return out
+		}
+	}
+}
+
+// procedureNames returns the Go names declared for a procedure whose
+// function is fn, the function name first.
+func procedureNames(fn string, sig *signature.Signature) []string {
+	names := []string{fn, fn + "Params", fn + "Result"}
+	for i, rs := range sig.ResultSets {
+		if !hasWildcard(rs) {
+			names = append(names, rowName(fn, i, len(sig.ResultSets)))
+		}
+	}
+	if len(sig.ResultSets) > 0 {
+		names = append(names, "scan"+fn)
+	}
+	return names
+}
+
+// rowName returns the name of the row type of result set i of n.
+func rowName(fn string, i, n int) string {
+	if n == 1 {
+		return fn + "Row"
+	}
+	return fmt.Sprintf("%sRow%d", fn, i+1)
+}
+
+func (g *Generator) procedure(fn string, sig *signature.Signature) {
+	params := fn + "Params"
+	result := fn + "Result"
+
+	// Params struct
+	g.printf("// %s holds the parameters of %s.\n", params, sig.Name)
+	g.printf("type %s struct {\n", params)
+	fields := newFieldNames()
+	paramFields := make([]string, len(sig.Parameters))
+	for i, p := range sig.Parameters {
+		paramFields[i] = fields.add(strings.TrimPrefix(p.Name, "@"))
+		typ, comment := g.paramType(p), ""
+		switch {
+		case p.Output:
+			comment = " // OUTPUT"
+		case p.HasDefault():
+			comment = " // Optional; nil uses the procedure's default"
+		}
+		g.printf("%s %s%s\n", paramFields[i], typ, comment)
+	}
+	g.printf("}\n\n")
+
+	// Row structs
+	setTypes := make([]string, len(sig.ResultSets))
+	for i, rs := range sig.ResultSets {
+		if hasWildcard(rs) {
+			setTypes[i] = "[]map[string]any"
+			g.needMap = true
+			continue
+		}
+		row := rowName(fn, i, len(sig.ResultSets))
+		setTypes[i] = "[]" + row
+		g.printf("// %s is a row of result set %d of %s.\n", row, i+1, sig.Name)
+		g.printf("type %s struct {\n", row)
+		cols := newFieldNames()
+		for j, col := range rs.Columns {
+			name := col.Name
+			if name == "" {
+				name = fmt.Sprintf("Column%d", j+1)
+			}
+			nullable := col.Nullable == nil || *col.Nullable
+			g.printf("%s %s\n", cols.add(name), g.goType(col.DataType, nullable, "sql.Null"))
+		}
+		g.printf("}\n\n")
+	}
+
+	// Result struct
+	g.printf("// %s holds the result sets returned by %s.\n", result, sig.Name)
+	g.printf("type %s struct {\n", result)
+	for i, rs := range sig.ResultSets {
+		comment := ""
+		if rs.Conditional {
+			comment = " // Not returned on every code path"
+		}
+		g.printf("Set%d %s%s\n", i+1, setTypes[i], comment)
+	}
+	g.printf("}\n\n")
+
+	// Call function
+	g.printf("// %s calls %s.\n", fn, sig.Name)
+	g.printf("// OUTPUT parameters are written back to p once all result sets have been read.\n")
+	g.printf("func %s(ctx context.Context, q Querier, p *%s) (*%s, error) {\n", fn, params, result)
+	g.printf("args := []any{\n")
+	for i, p := range sig.Parameters {
+		if p.HasDefault() && !p.Output {
+			continue
+		}
+		g.printf("sql.Named(%q, %s),\n", strings.TrimPrefix(p.Name, "@"), g.paramValue(p, "p."+paramFields[i]))
+	}
+	g.printf("}\n")
+	for i, p := range sig.Parameters {
+		if p.HasDefault() && !p.Output {
+			g.printf("if p.%s != nil {\n", paramFields[i])
+			g.printf("args = append(args, sql.Named(%q, *p.%s))\n", strings.TrimPrefix(p.Name, "@"), paramFields[i])
+			g.printf("}\n")
+		}
+	}
+	g.printf("rows, err := q.QueryContext(ctx, %q, args...)\n", quotedName(sig.Name))
+	g.printf("if err != nil {\nreturn nil, err\n}\n")
+	g.printf("res := &%s{}\n", result)
+	if len(sig.ResultSets) > 0 {
+		g.printf("if err := scan%s(rows, res); err != nil {\nrows.Close()\nreturn nil, err\n}\n", fn)
+	}
+	g.printf("if err := rows.Close(); err != nil {\nreturn nil, err\n}\n")
+	g.printf("return res, nil\n}\n\n")
+
+	if len(sig.ResultSets) > 0 {
+		g.scanFunction(fn, result, sig.ResultSets, setTypes)
+	}
+}
+
+func (g *Generator) scanFunction(fn, result string, sets []*signature.ResultSet, setTypes []string) {
+	g.printf("func scan%s(rows *sql.Rows, res *%s) error {\n", fn, result)
+	for i, rs := range sets {
+		if i > 0 {
+			g.printf("if !rows.NextResultSet() {\nreturn rows.Err()\n}\n")
+		}
+		if setTypes[i] == "[]map[string]any" {
+			g.printf("set%d, err := scanMaps(rows)\n", i+1)
+			g.printf("if err != nil {\nreturn err\n}\n")
+			g.printf("res.Set%d = set%d\n", i+1, i+1)
+			continue
+		}
+		g.printf("for rows.Next() {\n")
+		g.printf("var r %s\n", strings.TrimPrefix(setTypes[i], "[]"))
+		cols := newFieldNames()
+		var dests []string
+		for j, col := range rs.Columns {
+			name := col.Name
+			if name == "" {
+				name = fmt.Sprintf("Column%d", j+1)
+			}
+			dests = append(dests, "&r."+cols.add(name))
+		}
+		g.printf("if err := rows.Scan(%s); err != nil {\nreturn err\n}\n", strings.Join(dests, ", "))
+		g.printf("res.Set%d = append(res.Set%d, r)\n", i+1, i+1)
+		g.printf("}\n")
+		g.printf("if err := rows.Err(); err != nil {\nreturn err\n}\n")
+	}
+	g.printf("return nil\n}\n\n")
+}
+
+func (g *Generator) scanMapsHelper() {
+	g.printf(`// scanMaps reads a result set whose columns are not known in advance.
+func scanMaps(rows *sql.Rows) ([]map[string]any, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []map[string]any
+	for rows.Next() {
+		values := make([]any, len(cols))
+		dests := make([]any, len(cols))
+		for i := range values {
+			dests[i] = &values[i]
+		}
+		if err := rows.Scan(dests...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(cols))
+		for i, col := range cols {
+			row[col] = values[i]
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+`)
+}
+
+func hasWildcard(rs *signature.ResultSet) bool {
+	if len(rs.Columns) == 0 {
+		return true
+	}
+	for _, col := range rs.Columns {
+		if col.IsWildcard() {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTableType finds a table type by its full or unqualified name.
+func (g *Generator) lookupTableType(name string) *ast.CreateTypeStatement {
+	name = strings.ToLower(name)
+	if t, ok := g.tableTypes[name]; ok {
+		return t
+	}
+	for _, t := range g.tableTypes {
+		parts := t.Name.Parts
+		if strings.EqualFold(parts[len(parts)-1].Value, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// paramType returns the Go type of a params struct field. OUTPUT
+// parameters can come back NULL, so they are sql.Null[T]; optional
+// parameters are pointers, nil leaving the procedure's default.
+func (g *Generator) paramType(p *signature.Parameter) string {
+	if p.TableValued {
+		if t := g.lookupTableType(p.DataType.Name); t != nil {
+			return "[]" + g.rowTypes[t]
+		}
+		return "any"
+	}
+	if p.Output {
+		return g.goType(p.DataType, true, "sql.Null")
+	}
+	typ := g.goType(p.DataType, false, "")
+	if p.HasDefault() {
+		return "*" + typ
+	}
+	return typ
+}
+
+// paramValue returns the expression passed to sql.Named for a parameter.
+func (g *Generator) paramValue(p *signature.Parameter, field string) string {
+	switch {
+	case p.TableValued:
+		typeName := p.DataType.Name
+		if t := g.lookupTableType(typeName); t != nil {
+			typeName = t.Name.String()
+		}
+		return fmt.Sprintf("%s.TVP{TypeName: %q, Value: %s}", g.use(mssqlPackage), typeName, field)
+	case p.Output:
+		return fmt.Sprintf("sql.Out{Dest: &%s, In: true}", field)
+	}
+	return field
+}
+
+// goType maps a SQL data type to a Go type. Nullable values are wrapped
+// with nullWrap: "sql.Null" for sql.Null[T], "*" for a pointer, or "" to
+// leave the type as is. Types that already represent NULL (byte slices and
+// any) are never wrapped.
+func (g *Generator) goType(dt *ast.DataType, nullable bool, nullWrap string) string {
+	if dt == nil {
+		return "any"
+	}
+	var typ string
+	switch strings.ToUpper(dt.Name) {
+	case "BIT":
+		typ = "bool"
+	case "TINYINT":
+		typ = "uint8"
+	case "SMALLINT":
+		typ = "int16"
+	case "INT", "INTEGER":
+		typ = "int32"
+	case "BIGINT":
+		typ = "int64"
+	case "REAL":
+		typ = "float32"
+	case "FLOAT":
+		typ = "float64"
+	case "DECIMAL", "DEC", "NUMERIC", "MONEY", "SMALLMONEY":
+		typ = g.qualified(g.DecimalType)
+	case "DATE", "TIME", "DATETIME", "DATETIME2", "SMALLDATETIME", "DATETIMEOFFSET":
+		typ = g.use("time") + ".Time"
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "TEXT", "NTEXT", "SYSNAME", "XML", "JSON":
+		typ = "string"
+	case "UNIQUEIDENTIFIER":
+		typ = g.qualified(g.UUIDType)
+	case "BINARY", "VARBINARY", "IMAGE", "ROWVERSION", "TIMESTAMP":
+		return "[]byte"
+	default:
+		return "any"
+	}
+	if !nullable {
+		return typ
+	}
+	switch nullWrap {
+	case "sql.Null":
+		return "sql.Null[" + typ + "]"
+	case "*":
+		return "*" + typ
+	}
+	return typ
+}
+
+// funcName returns the Go function name for a procedure. Procedures
+// outside dbo are prefixed with their schema.
+func funcName(name *ast.QualifiedIdentifier) string {
+	n := len(name.Parts)
+	fn := goName(name.Parts[n-1].Value)
+	if n > 1 && !strings.EqualFold(name.Parts[n-2].Value, "dbo") {
+		fn = goName(name.Parts[n-2].Value) + fn
+	}
+	return fn
+}
+
+// goName converts a SQL identifier to an exported Go identifier.
+func goName(s string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if sb.Len() == 0 && unicode.IsDigit(r) {
+			sb.WriteByte('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return "X"
+	}
+	return sb.String()
+}
+
+// fieldNames hands out unique Go field names within a struct.
+type fieldNames map[string]int
+
+func newFieldNames() fieldNames {
+	return make(fieldNames)
+}
+
+func (f fieldNames) add(name string) string {
+	n := goName(name)
+	f[n]++
+	if f[n] > 1 {
+		n = fmt.Sprintf("%s%d", n, f[n])
+	}
+	return n
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	goast "go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func generate(t *testing.T, g *Generator, input string) string {
+	t.Helper()
+	program, errors := tsqlparser.Parse(input)
+	if len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	g.AddProgram(program)
+	src, err := g.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v\n%s", err, src)
+	}
+	return string(src)
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, NewGenerator("store"), `
+CREATE TYPE dbo.IdList AS TABLE (Id INT NOT NULL);
+GO
+CREATE TABLE dbo.Customers (
+    Id INT IDENTITY(1,1) PRIMARY KEY,
+    Name NVARCHAR(100) NOT NULL,
+    Balance MONEY NULL
+);
+GO
+CREATE PROCEDURE dbo.GetCustomers
+    @Ids dbo.IdList READONLY,
+    @Since DATETIMEOFFSET = NULL,
+    @row_count INT OUTPUT
+AS
+BEGIN
+    SELECT Id, Name, Balance FROM dbo.Customers;
+    IF @row_count > 0
+        SELECT * FROM dbo.Unknown;
+END`)
+
+	expected := []string{
+		"package store",
+		`mssql "github.com/microsoft/go-mssqldb"`,
+		"type IdListRow struct {\n\tId int32\n}",
+		"Since    *time.Time",
+		"RowCount sql.Null[int32]",
+		"Balance sql.Null[decimal.Decimal]",
+		"Set2 []map[string]any // Not returned on every code path",
+		`sql.Named("Ids", mssql.TVP{TypeName: "dbo.IdList", Value: p.Ids})`,
+		`sql.Named("row_count", sql.Out{Dest: &p.RowCount, In: true})`,
+		`args = append(args, sql.Named("Since", *p.Since))`,
+		`q.QueryContext(ctx, "[dbo].[GetCustomers]", args...)`,
+		"if !rows.NextResultSet() {",
+		"func scanMaps(rows *sql.Rows)",
+	}
+	for _, want := range expected {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+}
+
+// stubs stand in for the packages generated code imports outside the
+// standard library.
+var stubs = map[string]string{
+	"github.com/microsoft/go-mssqldb": "package mssql\ntype TVP struct {\n\tTypeName string\n\tValue any\n}\ntype UniqueIdentifier [16]byte\n",
+	"github.com/shopspring/decimal":   "package decimal\ntype Decimal struct{}\n",
+	"github.com/google/uuid":          "package uuid\ntype UUID [16]byte\n",
+}
+
+type stubImporter struct {
+	fset *token.FileSet
+	std  types.Importer
+}
+
+func (im *stubImporter) Import(path string) (*types.Package, error) {
+	src, ok := stubs[path]
+	if !ok {
+		return im.std.Import(path)
+	}
+	f, err := parser.ParseFile(im.fset, path+".go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	return (&types.Config{}).Check(path, im.fset, []*goast.File{f}, nil)
+}
+
+// typeCheck type-checks generated code as a package of its own.
+func typeCheck(t *testing.T, src string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	conf := types.Config{Importer: &stubImporter{fset: fset, std: importer.ForCompiler(fset, "source", nil)}}
+	if _, err := conf.Check("store", fset, []*goast.File{f}, nil); err != nil {
+		t.Errorf("generated code does not compile: %v\n%s", err, src)
+	}
+}
+
+func TestGeneratedCodeCompiles(t *testing.T) {
+	src := generate(t, NewGenerator("store"), `
+CREATE TYPE dbo.IdList AS TABLE (Id INT NOT NULL, Ref UNIQUEIDENTIFIER NULL);
+GO
+CREATE PROCEDURE [dbo].[Other Proc]
+    @Ids dbo.IdList READONLY,
+    @Amount MONEY,
+    @Since DATETIME2 = NULL,
+    @Opt INT = NULL OUTPUT,
+    @Name NVARCHAR(50) OUTPUT
+AS
+BEGIN
+    SELECT Id, Ref, @Amount AS Amount FROM @Ids;
+    SELECT * FROM dbo.Unknown;
+END`)
+
+	for _, want := range []string{
+		`q.QueryContext(ctx, "[dbo].[Other Proc]", args...)`,
+		"Opt    sql.Null[int32]  // OUTPUT",
+		"Name   sql.Null[string] // OUTPUT",
+		"Since  *time.Time",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+	typeCheck(t, src)
+}
+
+func TestCollidingNames(t *testing.T) {
+	src := generate(t, NewGenerator("store"), `
+CREATE TYPE a.T AS TABLE (Id INT);
+GO
+CREATE TYPE b.T AS TABLE (Id INT);
+GO
+CREATE TYPE dbo.IdList AS TABLE (Id INT);
+GO
+CREATE PROCEDURE sales.Totals AS BEGIN SELECT 1 AS n END
+GO
+CREATE PROCEDURE dbo.SalesTotals AS BEGIN SELECT 2 AS n END
+GO
+CREATE PROCEDURE dbo.IdList @Ids dbo.IdList READONLY, @A a.T READONLY, @B b.T READONLY
+AS BEGIN SELECT Id FROM @Ids END`)
+
+	for _, want := range []string{
+		"type TRow struct",
+		"type BTRow struct",
+		"type IdListRow struct",
+		"func SalesTotals(",
+		"func DboSalesTotals(",
+		"func DboIdList(",
+		"type DboIdListRow struct",
+		"Ids []IdListRow",
+		"A   []TRow",
+		"B   []BTRow",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+	typeCheck(t, src)
+}
+
+func TestGenerateTypeOverrides(t *testing.T) {
+	g := NewGenerator("db")
+	g.DecimalType = "string"
+	g.UUIDType = "github.com/google/uuid.UUID"
+	src := generate(t, g, `
+CREATE PROCEDURE sales.Totals @Amount DECIMAL(10,2), @Ref UNIQUEIDENTIFIER
+AS SELECT CAST(1 AS BIT) AS Ok`)
+
+	for _, want := range []string{
+		"func SalesTotals(",
+		"Amount string",
+		"Ref    uuid.UUID",
+		`"github.com/google/uuid"`,
+		"Ok sql.Null[bool]",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "decimal") {
+		t.Errorf("decimal package should not be imported\n%s", src)
+	}
+	typeCheck(t, src)
+}
+
+func TestGoType(t *testing.T) {
+	g := NewGenerator("db")
+	g.imports = make(map[string]string)
+	tests := []struct {
+		name     string
+		nullable bool
+		expected string
+	}{
+		{"BIT", false, "bool"},
+		{"TINYINT", false, "uint8"},
+		{"BIGINT", true, "sql.Null[int64]"},
+		{"DATETIMEOFFSET", false, "time.Time"},
+		{"DATETIME2", true, "sql.Null[time.Time]"},
+		{"NVARCHAR", false, "string"},
+		{"VARBINARY", true, "[]byte"},
+		{"NUMERIC", false, "decimal.Decimal"},
+		{"UNIQUEIDENTIFIER", false, "mssql.UniqueIdentifier"},
+		{"SQL_VARIANT", true, "any"},
+	}
+	for _, tt := range tests {
+		got := g.goType(&ast.DataType{Name: tt.name}, tt.nullable, "sql.Null")
+		if got != tt.expected {
+			t.Errorf("goType(%s, %v) = %s, want %s", tt.name, tt.nullable, got, tt.expected)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"CustomerId", "CustomerId"},
+		{"customer_id", "CustomerId"},
+		{"Order Total", "OrderTotal"},
+		{"2ndLine", "X2ndLine"},
+		{"", "X"},
+	}
+	for _, tt := range tests {
+		if got := goName(tt.input); got != tt.expected {
+			t.Errorf("goName(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Command tsqlgen generates typed Go wrappers for T-SQL stored procedures.
+//
+// It reads CREATE PROCEDURE scripts and writes, for each procedure, a
+// params struct, one row struct per result set and a function that calls
+// the procedure through database/sql. CREATE TABLE and CREATE TYPE ... AS
+// TABLE statements found in the inputs are used to type result columns
+// and table-valued parameters.
+//
+// Usage:
+//
+//	tsqlgen -pkg store -o procs_gen.go schema.sql procs/*.sql
+//
+// The generated code targets github.com/microsoft/go-mssqldb, which is
+// needed for table-valued parameters and uniqueidentifier columns.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func main() {
+	pkg := flag.String("pkg", "db", "package name of the generated file")
+	out := flag.String("o", "", "output file (default stdout)")
+	decimal := flag.String("decimal", defaultDecimalType, "Go type for DECIMAL, NUMERIC and MONEY, as import/path.Type")
+	uuid := flag.String("uuid", defaultUUIDType, "Go type for UNIQUEIDENTIFIER, as import/path.Type")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tsqlgen [flags] file.sql...\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var programs []*ast.Program
+	failed := false
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tsqlgen: %v\n", err)
+			os.Exit(1)
+		}
+		program, errors := tsqlparser.Parse(string(data))
+		for _, e := range errors {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, e)
+			failed = true
+		}
+		programs = append(programs, program)
+	}
+	if failed {
+		os.Exit(1)
+	}
+
+	g := NewGenerator(*pkg)
+	g.DecimalType = *decimal
+	g.UUIDType = *uuid
+	for _, program := range programs {
+		g.AddProgram(program)
+	}
+	src, err := g.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsqlgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "tsqlgen: %v\n", err)
+		os.Exit(1)
+	}
+}
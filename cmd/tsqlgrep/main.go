@@ -0,0 +1,153 @@
+// Command tsqlgrep searches T-SQL scripts for code with a given structure.
+//
+// The pattern is a T-SQL statement or expression in which metavariables
+// such as $T stand for any name or expression and $... for any number of
+// list elements; see package pattern. Each match is printed with its
+// position:
+//
+//	tsqlgrep 'UPDATE $T SET $... = $...' procs/
+//	tsqlgrep -b 'CONVERT(VARCHAR, $e)' schema.sql
+//
+// Directories are searched for .sql files. The exit status is 0 if
+// something matched, 1 if nothing did and 2 if there was an error.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/pattern"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// result is a match as written by -json.
+type result struct {
+	File     string            `json:"file"`
+	Line     int               `json:"line"`
+	Column   int               `json:"column"`
+	Text     string            `json:"text"`
+	Bindings map[string]string `json:"bindings,omitempty"`
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tsqlgrep", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	bindings := flags.Bool("b", false, "print what the metavariables of each match are bound to")
+	asJSON := flags.Bool("json", false, "print matches as JSON, one object per line")
+	count := flags.Bool("c", false, "print only the number of matches in each file")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tsqlgrep [flags] pattern file|dir...\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+
+	p, err := pattern.Compile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "tsqlgrep: %v\n", err)
+		return 2
+	}
+	paths, err := files(flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(stderr, "tsqlgrep: %v\n", err)
+		return 2
+	}
+
+	status := 1
+	enc := json.NewEncoder(stdout)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "tsqlgrep: %v\n", err)
+			return 2
+		}
+		program, errors := tsqlparser.Parse(string(data))
+		for _, e := range errors {
+			// The rest of the script is still searched
+			fmt.Fprintf(stderr, "%s: %s\n", path, e)
+		}
+		matches := p.Find(program)
+		if len(matches) > 0 {
+			status = 0
+		}
+		if *count {
+			fmt.Fprintf(stdout, "%s:%d\n", path, len(matches))
+			continue
+		}
+		for _, m := range matches {
+			if *asJSON {
+				r := result{File: path, Line: m.Line, Column: m.Column, Text: m.Node.String()}
+				if len(m.Bindings) > 0 {
+					r.Bindings = make(map[string]string)
+					for name, node := range m.Bindings {
+						r.Bindings[name] = node.String()
+					}
+				}
+				enc.Encode(r)
+				continue
+			}
+			fmt.Fprintf(stdout, "%s:%d:%d: %s\n", path, m.Line, m.Column, oneLine(m.Node.String()))
+			if *bindings {
+				names := make([]string, 0, len(m.Bindings))
+				for name := range m.Bindings {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Fprintf(stdout, "\t$%s = %s\n", name, oneLine(m.Bindings[name].String()))
+				}
+			}
+		}
+	}
+	return status
+}
+
+// files expands the directories among args to the .sql files below them.
+func files(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".sql") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// oneLine collapses the white space of text so that a match prints on one
+// line.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
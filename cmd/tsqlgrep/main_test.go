@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func grep(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestSearchDirectory(t *testing.T) {
+	dir := t.TempDir()
+	a := write(t, dir, "a.sql", "UPDATE dbo.Orders SET Amount = 0;\nUPDATE dbo.Orders SET Amount = 1 WHERE Id = 2;\n")
+	b := write(t, dir, "procs/b.sql", "CREATE PROCEDURE dbo.Reset AS\n    update Totals set Sum = 0\n")
+	write(t, dir, "notes.txt", "UPDATE Ignored SET x = 1")
+
+	status, out, errs := grep("-b", "UPDATE $T SET $X = $Y", dir)
+	if status != 0 || errs != "" {
+		t.Fatalf("status %d, stderr %q", status, errs)
+	}
+	want := a + ":1:1: UPDATE dbo.Orders SET Amount = 0\n" +
+		"\t$T = dbo.Orders\n\t$X = Amount\n\t$Y = 0\n" +
+		b + ":2:5: UPDATE Totals SET Sum = 0\n" +
+		"\t$T = Totals\n\t$X = Sum\n\t$Y = 0\n"
+	if out != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	path := write(t, t.TempDir(), "q.sql", "SELECT CONVERT(VARCHAR, Amount), CONVERT(VARCHAR(5), Amount) FROM Orders")
+	status, out, _ := grep("-json", "CONVERT(VARCHAR, $e)", path)
+	if status != 0 {
+		t.Fatalf("status %d", status)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %q", out)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.File != path || r.Line != 1 || r.Column != 8 || r.Text != "CONVERT(VARCHAR, Amount)" || r.Bindings["e"] != "Amount" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	path := write(t, t.TempDir(), "q.sql", "SELECT 1")
+	if status, out, _ := grep("GETDATE()", path); status != 1 || out != "" {
+		t.Errorf("no match: status %d, output %q", status, out)
+	}
+	if status, out, _ := grep("-c", "GETDATE()", path); status != 1 || out != path+":0\n" {
+		t.Errorf("count: status %d, output %q", status, out)
+	}
+	if status, _, errs := grep("UPDATE SET", path); status != 2 || !strings.Contains(errs, "pattern") {
+		t.Errorf("bad pattern: status %d, stderr %q", status, errs)
+	}
+	if status, _, _ := grep("GETDATE()"); status != 2 {
+		t.Errorf("no files: status %d", status)
+	}
+	if status, _, _ := grep("GETDATE()", filepath.Join(t.TempDir(), "missing.sql")); status != 2 {
+		t.Errorf("missing file: status %d", status)
+	}
+}
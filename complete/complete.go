@@ -0,0 +1,425 @@
+// Package complete suggests completions at a position in a T-SQL script.
+//
+// Keywords come from the parser: the prefix of the script up to the
+// cursor is parsed, and the token types the parser would have accepted at
+// its end are offered. Names come from the script around the cursor and
+// from an optional Catalog of tables:
+//
+//	for _, c := range complete.Complete(src, offset, schema) {
+//	    fmt.Println(c.Label, c.Kind, c.Detail)
+//	}
+package complete
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Kind is the kind of thing a candidate names.
+type Kind int
+
+const (
+	Variable Kind = iota
+	Column
+	Table // a table, table alias or common table expression
+	Function
+	Keyword
+)
+
+var kindNames = [...]string{"variable", "column", "table", "function", "keyword"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// Candidate is a completion of the word at the cursor.
+type Candidate struct {
+	Label  string
+	Kind   Kind
+	Detail string // the type of a variable or column, the signature of a function
+}
+
+// Catalog describes the tables of a database. *schemadiff.Schema is a
+// Catalog.
+type Catalog interface {
+	// TableNames returns the names of the tables.
+	TableNames() []string
+	// Columns returns the columns of a table, or nil if there is no such
+	// table. The name may be qualified with a schema.
+	Columns(table string) []*ast.ColumnDefinition
+}
+
+// Complete returns the candidates for the word that ends at offset, a
+// byte offset into src, sorted by kind and label. catalog may be nil.
+func Complete(src string, offset int, catalog Catalog) []Candidate {
+	offset = max(0, min(offset, len(src)))
+	if inCommentOrString(src[:offset]) {
+		return nil
+	}
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(src[:start])
+		if !isWordRune(r) {
+			break
+		}
+		start -= size
+	}
+
+	c := &completer{
+		src:     src,
+		start:   start,
+		prefix:  strings.ToLower(src[start:offset]),
+		catalog: catalog,
+		seen:    make(map[Candidate]bool),
+	}
+	c.scan()
+
+	switch {
+	case start > 0 && src[start-1] == '.':
+		c.members(start - 1)
+	case strings.HasPrefix(c.prefix, "@"):
+		c.variables()
+	default:
+		c.names()
+	}
+
+	sort.Slice(c.out, func(i, j int) bool {
+		if c.out[i].Kind != c.out[j].Kind {
+			return c.out[i].Kind < c.out[j].Kind
+		}
+		return c.out[i].Label < c.out[j].Label
+	})
+	return c.out
+}
+
+// completer holds what is known about the script around the cursor.
+type completer struct {
+	src     string
+	start   int // start of the word at the cursor
+	prefix  string
+	catalog Catalog
+
+	tokens     []token.Token
+	starts     []int // byte offset of each token
+	cursor     int   // index of the first token at or after start
+	first, end int   // the tokens of the batch at the cursor
+	scope      []token.Token
+	script     *script
+
+	out  []Candidate
+	seen map[Candidate]bool
+}
+
+func (c *completer) add(label string, kind Kind, detail string) {
+	if !strings.HasPrefix(strings.ToLower(label), c.prefix) {
+		return
+	}
+	cand := Candidate{Label: label, Kind: kind, Detail: detail}
+	if !c.seen[cand] {
+		c.seen[cand] = true
+		c.out = append(c.out, cand)
+	}
+}
+
+// scan tokenizes the script and finds the statement at the cursor.
+func (c *completer) scan() {
+	c.tokens = tsqlparser.Tokenize(c.src)
+	c.starts = offsets(c.src, c.tokens)
+	c.cursor = sort.SearchInts(c.starts, c.start)
+	c.end = len(c.tokens)
+	for i, tok := range c.tokens {
+		if tok.Type != token.GO {
+			continue
+		}
+		if i < c.cursor {
+			c.first = i + 1
+		} else {
+			c.end = i
+			break
+		}
+	}
+	scopes := statements(c.tokens)
+	at := 0
+	if c.cursor > 0 {
+		at = scopes[c.cursor-1]
+	}
+	for i, tok := range c.tokens {
+		// The word being typed names nothing yet
+		typing := c.prefix != "" && c.starts[i] == c.start
+		if scopes[i] == at && tok.Type != token.EOF && tok.Type != token.COMMENT && !typing {
+			c.scope = append(c.scope, tok)
+		}
+	}
+}
+
+// previous returns the last token before the word at the cursor.
+func (c *completer) previous() token.Token {
+	for i := c.cursor - 1; i >= 0; i-- {
+		if c.tokens[i].Type != token.COMMENT && c.starts[i] < c.start {
+			return c.tokens[i]
+		}
+	}
+	return token.Token{Type: token.ILLEGAL}
+}
+
+// names offers what can be typed where a keyword or name is expected.
+func (c *completer) names() {
+	// A table name is the only thing that can follow these
+	switch c.previous().Type {
+	case token.FROM, token.JOIN, token.INTO, token.UPDATE, token.MERGE, token.USING, token.TABLE:
+		_, ctes := references(c.scope)
+		for _, name := range ctes {
+			c.add(name, Table, "common table expression")
+		}
+		for _, name := range c.tables() {
+			c.add(name, Table, "table")
+		}
+		return
+	}
+
+	names, expressions := false, false
+	for _, t := range c.expected() {
+		switch t {
+		case token.IDENT:
+			names = true
+		case token.VARIABLE:
+			expressions = true
+		}
+		if label := keywordLabel(t); label != "" && !functionNames[label] {
+			c.add(label, Keyword, "")
+		}
+	}
+	if !names && !expressions {
+		return
+	}
+	refs, _ := references(c.scope)
+	for _, ref := range refs {
+		if ref.alias != "" {
+			c.add(ref.alias, Table, ref.name)
+		} else if ref.name != "" {
+			c.add(last(ref.name), Table, ref.name)
+		}
+		for _, col := range c.columns(ref.name) {
+			c.add(col.Name.Value, Column, dataType(col.DataType))
+		}
+	}
+	if expressions {
+		c.variables()
+		for _, f := range functions {
+			c.add(f.name, Function, f.signature)
+		}
+	}
+}
+
+// members offers the columns of the table or alias before the dot at dot.
+func (c *completer) members(dot int) {
+	i := sort.SearchInts(c.starts, dot)
+	if i == 0 || i > len(c.tokens) {
+		return
+	}
+	qualifier := c.tokens[i-1]
+	if !isName(qualifier) {
+		return
+	}
+	refs, _ := references(c.scope)
+	for _, ref := range refs {
+		if strings.EqualFold(ref.alias, qualifier.Literal) ||
+			ref.alias == "" && strings.EqualFold(last(ref.name), qualifier.Literal) {
+			for _, col := range c.columns(ref.name) {
+				c.add(col.Name.Value, Column, dataType(col.DataType))
+			}
+			return
+		}
+	}
+}
+
+// expected returns the token types the parser accepts at the cursor. If
+// parsing stopped with an error there, the types are those it expected
+// when it failed, before it recovered and moved on.
+func (c *completer) expected() []token.Type {
+	p := parser.New(lexer.New(c.src[:c.start]))
+	p.TrackExpected()
+	p.ParseProgram()
+	errs := p.SyntaxErrors()
+	for i := len(errs) - 1; i >= 0; i-- {
+		if errs[i].Got.Type == token.EOF && errs[i].Expected != nil {
+			return errs[i].Expected
+		}
+	}
+	if at, expected := p.Expected(); at.Type == token.EOF {
+		return expected
+	}
+	return nil
+}
+
+// keywordLabel returns the word for a keyword token type, or "".
+func keywordLabel(t token.Type) string {
+	if t == token.TYPE_WARNING {
+		return "TYPE"
+	}
+	word := t.String()
+	if t.IsKeyword() && token.LookupIdent(word) == t {
+		return word
+	}
+	return ""
+}
+
+// tables returns the tables of the catalog and the temporary tables
+// created in the script.
+func (c *completer) tables() []string {
+	var names []string
+	if c.catalog != nil {
+		names = c.catalog.TableNames()
+	}
+	for name := range c.local().tables {
+		names = append(names, name)
+	}
+	return names
+}
+
+// columns returns the columns of a table of the catalog, a temporary table
+// or a table variable.
+func (c *completer) columns(table string) []*ast.ColumnDefinition {
+	if table == "" {
+		return nil
+	}
+	local := c.local()
+	for name, cols := range local.tables {
+		if strings.EqualFold(name, table) {
+			return cols
+		}
+	}
+	if d, ok := local.variables[strings.ToLower(table)]; ok && d.columns != nil {
+		return d.columns
+	}
+	if c.catalog != nil {
+		return c.catalog.Columns(table)
+	}
+	return nil
+}
+
+// variables offers the variables of the batch at the cursor.
+func (c *completer) variables() {
+	decls := c.local().variables
+	for i := c.first; i < c.end; i++ {
+		tok := c.tokens[i]
+		if tok.Type != token.VARIABLE || c.starts[i] == c.start {
+			continue
+		}
+		detail := ""
+		if d, ok := decls[strings.ToLower(tok.Literal)]; ok {
+			detail = d.dataType
+		}
+		c.add(tok.Literal, Variable, detail)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '@' || r == '#' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isName reports whether tok can name a table or alias.
+func isName(tok token.Token) bool {
+	return tok.Type == token.IDENT || tok.Type == token.VARIABLE || tok.Type.IsKeyword()
+}
+
+// last returns the last part of a dotted name.
+func last(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+func dataType(dt *ast.DataType) string {
+	if dt == nil {
+		return ""
+	}
+	return dt.String()
+}
+
+// offsets returns the byte offset of each token. The lexer counts columns
+// in runes from one.
+func offsets(src string, tokens []token.Token) []int {
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	starts := make([]int, len(tokens))
+	for i, tok := range tokens {
+		if tok.Type == token.EOF || tok.Line > len(lines) {
+			starts[i] = len(src)
+			continue
+		}
+		off := lines[max(tok.Line, 1)-1]
+		for n := 1; n < tok.Column && off < len(src) && src[off] != '\n'; n++ {
+			_, size := utf8.DecodeRuneInString(src[off:])
+			off += size
+		}
+		starts[i] = off
+	}
+	return starts
+}
+
+// inCommentOrString reports whether the end of src is inside a comment,
+// string literal or quoted identifier.
+func inCommentOrString(src string) bool {
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '\'' || src[i] == '"' || src[i] == '[':
+			end := byte(src[i])
+			if end == '[' {
+				end = ']'
+			}
+			i++
+			for ; i < len(src); i++ {
+				if src[i] != end {
+					continue
+				}
+				if i+1 < len(src) && src[i+1] == end {
+					i++
+					continue
+				}
+				break
+			}
+			if i >= len(src) {
+				return true
+			}
+		case strings.HasPrefix(src[i:], "--"):
+			n := strings.IndexByte(src[i:], '\n')
+			if n < 0 {
+				return true
+			}
+			i += n
+		case strings.HasPrefix(src[i:], "/*"):
+			// Block comments nest
+			depth := 0
+			for ; i < len(src); i++ {
+				if strings.HasPrefix(src[i:], "/*") {
+					depth++
+					i++
+				} else if strings.HasPrefix(src[i:], "*/") {
+					depth--
+					i++
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if depth > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
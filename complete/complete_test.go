@@ -0,0 +1,189 @@
+package complete
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/schemadiff"
+)
+
+var catalog = func() *schemadiff.Schema {
+	s := schemadiff.NewSchema()
+	s.AddScript("schema.sql", `
+CREATE TABLE dbo.Orders (Id INT NOT NULL, CustomerId INT NOT NULL, Amount DECIMAL(10,2), Placed DATETIME2);
+CREATE TABLE dbo.Customers (Id INT NOT NULL, Name NVARCHAR(100));
+`)
+	return s
+}()
+
+// complete completes at the | in src.
+func complete(t *testing.T, src string) []Candidate {
+	t.Helper()
+	offset := strings.Index(src, "|")
+	if offset < 0 {
+		t.Fatalf("no cursor in %q", src)
+	}
+	return Complete(src[:offset]+src[offset+1:], offset, catalog)
+}
+
+func find(cands []Candidate, label string, kind Kind) *Candidate {
+	for i := range cands {
+		if cands[i].Label == label && cands[i].Kind == kind {
+			return &cands[i]
+		}
+	}
+	return nil
+}
+
+func labels(cands []Candidate) string {
+	var out []string
+	for _, c := range cands {
+		out = append(out, c.Kind.String()+":"+c.Label)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestKeywordsAfterClause(t *testing.T) {
+	cands := complete(t, "SELECT Id FROM Orders o |")
+	for _, kw := range []string{"WHERE", "JOIN", "ORDER", "GROUP", "UNION"} {
+		if find(cands, kw, Keyword) == nil {
+			t.Errorf("missing keyword %s in %s", kw, labels(cands))
+		}
+	}
+
+	cands = complete(t, "SELECT Id FROM Orders o WH|")
+	if find(cands, "WHERE", Keyword) == nil {
+		t.Errorf("missing WHERE in %s", labels(cands))
+	}
+	for _, c := range cands {
+		if !strings.HasPrefix(c.Label, "WH") {
+			t.Errorf("%s does not match the prefix", c.Label)
+		}
+	}
+
+	cands = complete(t, "SELECT * FROM t WH|")
+	if find(cands, "WH", Table) != nil {
+		t.Errorf("the word being typed is offered as a table: %s", labels(cands))
+	}
+}
+
+func TestKeywordsAfterCreate(t *testing.T) {
+	cands := complete(t, "CREATE |")
+	for _, kw := range []string{"TABLE", "PROCEDURE", "VIEW", "TYPE"} {
+		if find(cands, kw, Keyword) == nil {
+			t.Errorf("missing keyword %s in %s", kw, labels(cands))
+		}
+	}
+	if find(cands, "SELECT", Keyword) != nil {
+		t.Errorf("SELECT offered after CREATE")
+	}
+}
+
+func TestTablesAfterFrom(t *testing.T) {
+	cands := complete(t, "WITH recent AS (SELECT * FROM Orders WHERE Placed > '2024-01-01')\nSELECT * FROM |")
+	for _, name := range []string{"recent", "dbo.Orders", "dbo.Customers"} {
+		if find(cands, name, Table) == nil {
+			t.Errorf("missing table %s in %s", name, labels(cands))
+		}
+	}
+	for _, c := range cands {
+		if c.Kind == Keyword {
+			t.Errorf("keyword %s offered for a table name", c.Label)
+		}
+	}
+
+	cands = complete(t, "CREATE TABLE #work (Id INT, Note VARCHAR(20));\nINSERT INTO #|")
+	if got := labels(cands); got != "table:#work" {
+		t.Errorf("got %s, want the temp table", got)
+	}
+}
+
+func TestColumnsOfAlias(t *testing.T) {
+	cands := complete(t, "SELECT o.| FROM dbo.Orders o JOIN Customers c ON c.Id = o.CustomerId")
+	if got := labels(cands); got != "column:Amount column:CustomerId column:Id column:Placed" {
+		t.Errorf("got %s", got)
+	}
+	if c := find(cands, "Amount", Column); c == nil || c.Detail != "DECIMAL(10, 2)" {
+		t.Errorf("Amount = %+v", c)
+	}
+
+	cands = complete(t, "SELECT c.N| FROM dbo.Orders o JOIN Customers c ON c.Id = o.CustomerId")
+	if got := labels(cands); got != "column:Name" {
+		t.Errorf("got %s", got)
+	}
+
+	cands = complete(t, "DECLARE @t TABLE (Code CHAR(3), Qty INT);\nSELECT x.| FROM @t AS x")
+	if got := labels(cands); got != "column:Code column:Qty" {
+		t.Errorf("table variable: got %s", got)
+	}
+}
+
+func TestExpressionNames(t *testing.T) {
+	src := `CREATE PROCEDURE dbo.Recent @since DATETIME2, @limit INT AS
+BEGIN
+    DECLARE @count INT = 0;
+    SELECT TOP (@limit) o.Id FROM dbo.Orders o WHERE |
+END`
+	cands := complete(t, src)
+	for _, want := range []struct {
+		label  string
+		kind   Kind
+		detail string
+	}{
+		{"@since", Variable, "DATETIME2"},
+		{"@count", Variable, "INT"},
+		{"o", Table, "dbo.Orders"},
+		{"Placed", Column, "DATETIME2"},
+		{"DATEADD", Function, "DATEADD(datepart, number, date)"},
+		{"EXISTS", Keyword, ""},
+	} {
+		c := find(cands, want.label, want.kind)
+		if c == nil {
+			t.Errorf("missing %s %s in %s", want.kind, want.label, labels(cands))
+		} else if c.Detail != want.detail {
+			t.Errorf("%s detail = %q, want %q", want.label, c.Detail, want.detail)
+		}
+	}
+	if find(cands, "c", Table) != nil {
+		t.Errorf("alias of another statement offered")
+	}
+}
+
+func TestVariablesOfBatch(t *testing.T) {
+	src := "DECLARE @old INT;\nGO\nDECLARE @total MONEY, @n INT;\nSET @total = 0;\nPRINT @t|"
+	cands := complete(t, src)
+	if got := labels(cands); got != "variable:@total" {
+		t.Fatalf("got %s", got)
+	}
+	if cands[0].Detail != "MONEY" {
+		t.Errorf("detail = %q", cands[0].Detail)
+	}
+}
+
+func TestNoCompletionInStringsAndComments(t *testing.T) {
+	for _, src := range []string{
+		"SELECT 'abc|",
+		"SELECT 1 -- FROM |",
+		"/* outer /* inner */ SEL| */",
+	} {
+		if cands := complete(t, src); len(cands) != 0 {
+			t.Errorf("%q: got %s", src, labels(cands))
+		}
+	}
+	if cands := complete(t, "SELECT 'it''s' /* x */ FROM |"); find(cands, "dbo.Orders", Table) == nil {
+		t.Errorf("closed string and comment: got %s", labels(cands))
+	}
+}
+
+func TestStatementScopes(t *testing.T) {
+	src := "SELECT * FROM Customers c\nSELECT * FROM Orders o WHERE |"
+	cands := complete(t, src)
+	if find(cands, "o", Table) == nil || find(cands, "c", Table) != nil {
+		t.Errorf("got %s", labels(cands))
+	}
+
+	src = "INSERT INTO Archive (Id) SELECT o.Id FROM Orders o WHERE o.|"
+	if cands := complete(t, src); find(cands, "Amount", Column) == nil {
+		t.Errorf("INSERT ... SELECT: got %s", labels(cands))
+	}
+}
@@ -0,0 +1,139 @@
+package complete
+
+type function struct {
+	name      string
+	signature string
+}
+
+// functions are the built-in scalar, aggregate and window functions.
+var functions = []function{
+	// Aggregates
+	{"AVG", "AVG([DISTINCT] expression)"},
+	{"COUNT", "COUNT([DISTINCT] expression | *)"},
+	{"COUNT_BIG", "COUNT_BIG([DISTINCT] expression | *)"},
+	{"MAX", "MAX(expression)"},
+	{"MIN", "MIN(expression)"},
+	{"STDEV", "STDEV(expression)"},
+	{"STRING_AGG", "STRING_AGG(expression, separator)"},
+	{"SUM", "SUM([DISTINCT] expression)"},
+	{"VAR", "VAR(expression)"},
+
+	// Window functions
+	{"CUME_DIST", "CUME_DIST() OVER (...)"},
+	{"DENSE_RANK", "DENSE_RANK() OVER (...)"},
+	{"FIRST_VALUE", "FIRST_VALUE(expression) OVER (...)"},
+	{"LAG", "LAG(expression [, offset [, default]]) OVER (...)"},
+	{"LAST_VALUE", "LAST_VALUE(expression) OVER (...)"},
+	{"LEAD", "LEAD(expression [, offset [, default]]) OVER (...)"},
+	{"NTILE", "NTILE(groups) OVER (...)"},
+	{"PERCENT_RANK", "PERCENT_RANK() OVER (...)"},
+	{"RANK", "RANK() OVER (...)"},
+	{"ROW_NUMBER", "ROW_NUMBER() OVER (...)"},
+
+	// Conversion and logic
+	{"CAST", "CAST(expression AS data_type)"},
+	{"CHOOSE", "CHOOSE(index, value1, value2 [, ...])"},
+	{"COALESCE", "COALESCE(expression1, expression2 [, ...])"},
+	{"CONVERT", "CONVERT(data_type, expression [, style])"},
+	{"IIF", "IIF(condition, true_value, false_value)"},
+	{"ISNULL", "ISNULL(expression, replacement)"},
+	{"ISNUMERIC", "ISNUMERIC(expression)"},
+	{"NULLIF", "NULLIF(expression1, expression2)"},
+	{"PARSE", "PARSE(string AS data_type [USING culture])"},
+	{"TRY_CAST", "TRY_CAST(expression AS data_type)"},
+	{"TRY_CONVERT", "TRY_CONVERT(data_type, expression [, style])"},
+	{"TRY_PARSE", "TRY_PARSE(string AS data_type [USING culture])"},
+
+	// Strings
+	{"ASCII", "ASCII(string)"},
+	{"CHAR", "CHAR(code)"},
+	{"CHARINDEX", "CHARINDEX(search, string [, start])"},
+	{"CONCAT", "CONCAT(value1, value2 [, ...])"},
+	{"CONCAT_WS", "CONCAT_WS(separator, value1, value2 [, ...])"},
+	{"DATALENGTH", "DATALENGTH(expression)"},
+	{"FORMAT", "FORMAT(value, format [, culture])"},
+	{"LEFT", "LEFT(string, length)"},
+	{"LEN", "LEN(string)"},
+	{"LOWER", "LOWER(string)"},
+	{"LTRIM", "LTRIM(string)"},
+	{"NCHAR", "NCHAR(code)"},
+	{"PATINDEX", "PATINDEX(pattern, string)"},
+	{"QUOTENAME", "QUOTENAME(string [, quote])"},
+	{"REPLACE", "REPLACE(string, search, replacement)"},
+	{"REPLICATE", "REPLICATE(string, count)"},
+	{"REVERSE", "REVERSE(string)"},
+	{"RIGHT", "RIGHT(string, length)"},
+	{"RTRIM", "RTRIM(string)"},
+	{"SPACE", "SPACE(count)"},
+	{"STR", "STR(number [, length [, decimals]])"},
+	{"STRING_SPLIT", "STRING_SPLIT(string, separator)"},
+	{"STUFF", "STUFF(string, start, length, replacement)"},
+	{"SUBSTRING", "SUBSTRING(string, start, length)"},
+	{"TRANSLATE", "TRANSLATE(string, characters, translations)"},
+	{"TRIM", "TRIM([characters FROM] string)"},
+	{"UNICODE", "UNICODE(string)"},
+	{"UPPER", "UPPER(string)"},
+
+	// Dates
+	{"DATEADD", "DATEADD(datepart, number, date)"},
+	{"DATEDIFF", "DATEDIFF(datepart, start, end)"},
+	{"DATEDIFF_BIG", "DATEDIFF_BIG(datepart, start, end)"},
+	{"DATEFROMPARTS", "DATEFROMPARTS(year, month, day)"},
+	{"DATENAME", "DATENAME(datepart, date)"},
+	{"DATEPART", "DATEPART(datepart, date)"},
+	{"DAY", "DAY(date)"},
+	{"EOMONTH", "EOMONTH(date [, months])"},
+	{"GETDATE", "GETDATE()"},
+	{"GETUTCDATE", "GETUTCDATE()"},
+	{"ISDATE", "ISDATE(expression)"},
+	{"MONTH", "MONTH(date)"},
+	{"SYSDATETIME", "SYSDATETIME()"},
+	{"SYSDATETIMEOFFSET", "SYSDATETIMEOFFSET()"},
+	{"SYSUTCDATETIME", "SYSUTCDATETIME()"},
+	{"YEAR", "YEAR(date)"},
+
+	// Mathematics
+	{"ABS", "ABS(number)"},
+	{"CEILING", "CEILING(number)"},
+	{"FLOOR", "FLOOR(number)"},
+	{"POWER", "POWER(number, exponent)"},
+	{"RAND", "RAND([seed])"},
+	{"ROUND", "ROUND(number, length [, truncate])"},
+	{"SIGN", "SIGN(number)"},
+	{"SQRT", "SQRT(number)"},
+
+	// JSON
+	{"ISJSON", "ISJSON(string)"},
+	{"JSON_MODIFY", "JSON_MODIFY(json, path, value)"},
+	{"JSON_QUERY", "JSON_QUERY(json [, path])"},
+	{"JSON_VALUE", "JSON_VALUE(json, path)"},
+	{"OPENJSON", "OPENJSON(json [, path]) [WITH (...)]"},
+
+	// System
+	{"CONTEXT_INFO", "CONTEXT_INFO()"},
+	{"ERROR_LINE", "ERROR_LINE()"},
+	{"ERROR_MESSAGE", "ERROR_MESSAGE()"},
+	{"ERROR_NUMBER", "ERROR_NUMBER()"},
+	{"ERROR_PROCEDURE", "ERROR_PROCEDURE()"},
+	{"ERROR_SEVERITY", "ERROR_SEVERITY()"},
+	{"ERROR_STATE", "ERROR_STATE()"},
+	{"HASHBYTES", "HASHBYTES(algorithm, input)"},
+	{"NEWID", "NEWID()"},
+	{"NEWSEQUENTIALID", "NEWSEQUENTIALID()"},
+	{"OBJECT_ID", "OBJECT_ID(name [, type])"},
+	{"OBJECT_NAME", "OBJECT_NAME(id [, database_id])"},
+	{"SCOPE_IDENTITY", "SCOPE_IDENTITY()"},
+	{"SESSION_CONTEXT", "SESSION_CONTEXT(key)"},
+	{"SUSER_SNAME", "SUSER_SNAME([sid])"},
+	{"XACT_STATE", "XACT_STATE()"},
+}
+
+// functionNames are the names of functions, which are offered with their
+// signatures rather than as keywords.
+var functionNames = make(map[string]bool)
+
+func init() {
+	for _, f := range functions {
+		functionNames[f.name] = true
+	}
+}
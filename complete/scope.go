@@ -0,0 +1,267 @@
+package complete
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// statements numbers the statements of a script, so that the aliases of
+// one statement are not offered in another. The script around the cursor
+// usually does not parse, so statements are told apart by their tokens:
+// a statement ends at a semicolon, GO, BEGIN, END or ELSE, and at a
+// keyword that starts a statement unless it continues the one before, as
+// SELECT does after UNION or in INSERT ... SELECT.
+func statements(tokens []token.Token) []int {
+	scopes := make([]int, len(tokens))
+	n, depth, cases := 0, 0, 0
+	var first, prev token.Type
+	fresh := true
+	for i, tok := range tokens {
+		boundary := false
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth = max(depth-1, 0)
+		case token.CASE:
+			cases++
+		case token.END:
+			if cases > 0 {
+				cases--
+			} else {
+				boundary = true
+			}
+		case token.SEMICOLON, token.GO, token.BEGIN, token.BEGIN_ATOMIC, token.ELSE:
+			boundary = true
+		}
+		if !boundary && !fresh && depth == 0 && startsStatement(tok.Type, first, prev) {
+			fresh = true
+		}
+		if fresh || boundary {
+			n++
+			first = tok.Type
+		}
+		scopes[i] = n
+		fresh = boundary
+		if tok.Type != token.COMMENT {
+			prev = tok.Type
+		}
+	}
+	return scopes
+}
+
+// startsStatement reports whether a token of type t starts a new
+// statement in a statement that began with first, after a token of type
+// prev.
+func startsStatement(t, first, prev token.Type) bool {
+	switch t {
+	case token.SELECT:
+		switch prev {
+		case token.UNION, token.ALL, token.EXCEPT, token.INTERSECT, token.RPAREN, token.FOR, token.AS:
+			return false
+		}
+		return first != token.INSERT && first != token.WITH
+	case token.INSERT, token.UPDATE, token.DELETE, token.MERGE:
+		switch prev {
+		case token.RPAREN, token.THEN, token.FOR, token.OF, token.COMMA, token.AFTER:
+			return false
+		}
+		return true
+	case token.SET:
+		return first != token.UPDATE && first != token.MERGE
+	case token.EXEC, token.EXECUTE:
+		return first != token.INSERT
+	case token.DECLARE, token.IF, token.WHILE, token.RETURN, token.PRINT, token.CREATE,
+		token.ALTER, token.DROP, token.TRUNCATE, token.THROW, token.RAISERROR, token.COMMIT,
+		token.ROLLBACK, token.GOTO:
+		return true
+	}
+	return false
+}
+
+// reference is a table named in a statement.
+type reference struct {
+	name  string // dotted name, or "" for a derived table
+	alias string
+}
+
+// references returns the tables a statement reads or writes and the
+// common table expressions it defines.
+func references(tokens []token.Token) (refs []reference, ctes []string) {
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case token.FROM:
+			for {
+				var ref reference
+				ref, i = tableReference(tokens, i+1)
+				refs = append(refs, ref)
+				if i+1 >= len(tokens) || tokens[i+1].Type != token.COMMA {
+					break
+				}
+				i++
+			}
+		case token.JOIN, token.UPDATE, token.INTO, token.USING:
+			var ref reference
+			ref, i = tableReference(tokens, i+1)
+			refs = append(refs, ref)
+		case token.MERGE:
+			if i+1 < len(tokens) && tokens[i+1].Type != token.INTO {
+				var ref reference
+				ref, i = tableReference(tokens, i+1)
+				refs = append(refs, ref)
+			}
+		case token.WITH, token.COMMA:
+			// WITH name [(columns)] AS (query) [, name AS (query)]
+			j := i + 1
+			if j >= len(tokens) || tokens[j].Type != token.IDENT {
+				continue
+			}
+			if tokens[i].Type == token.COMMA && (len(ctes) == 0 || i == 0 || tokens[i-1].Type != token.RPAREN) {
+				continue
+			}
+			k := j + 1
+			if k < len(tokens) && tokens[k].Type == token.LPAREN {
+				k = closing(tokens, k) + 1
+			}
+			if k+1 < len(tokens) && tokens[k].Type == token.AS && tokens[k+1].Type == token.LPAREN {
+				ctes = append(ctes, tokens[j].Literal)
+				// The query of the CTE is scanned like the rest
+				i = k + 1
+			}
+		}
+	}
+	return refs, ctes
+}
+
+// tableReference reads a table reference starting at tokens[i]: a name or
+// a derived table, arguments of a table-valued function, and an alias. It
+// returns the reference and the index of its last token.
+func tableReference(tokens []token.Token, i int) (reference, int) {
+	var ref reference
+	if i >= len(tokens) {
+		return ref, len(tokens) - 1
+	}
+	switch tokens[i].Type {
+	case token.LPAREN:
+		i = closing(tokens, i)
+	case token.IDENT, token.VARIABLE:
+		parts := []string{tokens[i].Literal}
+		for i+2 < len(tokens) && tokens[i+1].Type == token.DOT && isName(tokens[i+2]) {
+			parts = append(parts, tokens[i+2].Literal)
+			i += 2
+		}
+		ref.name = strings.Join(parts, ".")
+		if i+1 < len(tokens) && tokens[i+1].Type == token.LPAREN {
+			i = closing(tokens, i+1)
+		}
+	default:
+		return ref, i - 1
+	}
+	if i+1 < len(tokens) && tokens[i+1].Type == token.AS {
+		i++
+	}
+	if i+1 < len(tokens) && tokens[i+1].Type == token.IDENT {
+		i++
+		ref.alias = tokens[i].Literal
+	}
+	return ref, i
+}
+
+// closing returns the index of the parenthesis that closes tokens[i], or
+// of the last token if it is not closed.
+func closing(tokens []token.Token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// script is what the script itself declares.
+type script struct {
+	tables    map[string][]*ast.ColumnDefinition // tables created by the script, by name
+	variables map[string]*declaration            // variables of the batch at the cursor, by lower-case name
+}
+
+type declaration struct {
+	dataType string
+	columns  []*ast.ColumnDefinition // of a table variable
+}
+
+// local parses the script for the tables it creates and the types of the
+// variables and parameters declared in the batch at the cursor.
+func (c *completer) local() *script {
+	if c.script != nil {
+		return c.script
+	}
+	c.script = &script{
+		tables:    make(map[string][]*ast.ColumnDefinition),
+		variables: make(map[string]*declaration),
+	}
+	lo, hi := 0, math.MaxInt
+	if c.first > 0 {
+		lo = c.tokens[c.first-1].Line
+	}
+	if c.end < len(c.tokens) {
+		hi = c.tokens[c.end].Line
+	}
+	program, _ := tsqlparser.Parse(c.src)
+	v := &declarationFinder{script: c.script, lo: lo, hi: hi}
+	tsqlparser.Walk(v, program)
+	return c.script
+}
+
+// declarationFinder collects the declarations of a script.
+type declarationFinder struct {
+	script *script
+	lo, hi int // the lines of the batch at the cursor, exclusive
+}
+
+func (v *declarationFinder) Visit(node ast.Node) tsqlparser.Visitor {
+	switch n := node.(type) {
+	case *ast.CreateTableStatement:
+		v.script.tables[n.Name.String()] = n.Columns
+	case *ast.DeclareStatement:
+		if n.Token.Line <= v.lo || n.Token.Line >= v.hi {
+			return v
+		}
+		for _, def := range n.Variables {
+			d := &declaration{dataType: dataType(def.DataType)}
+			if def.TableType != nil {
+				d.dataType = "TABLE"
+				d.columns = def.TableType.Columns
+			}
+			v.script.variables[strings.ToLower(def.Name)] = d
+		}
+	case *ast.CreateProcedureStatement:
+		v.parameters(n.Token, n.Parameters)
+	case *ast.AlterProcedureStatement:
+		v.parameters(n.Token, n.Parameters)
+	case *ast.CreateFunctionStatement:
+		v.parameters(n.Token, n.Parameters)
+	case *ast.AlterFunctionStatement:
+		v.parameters(n.Token, n.Parameters)
+	}
+	return v
+}
+
+func (v *declarationFinder) parameters(tok token.Token, params []*ast.ParameterDef) {
+	if tok.Line <= v.lo || tok.Line >= v.hi {
+		return
+	}
+	for _, param := range params {
+		v.script.variables[strings.ToLower(param.Name)] = &declaration{dataType: dataType(param.DataType)}
+	}
+}
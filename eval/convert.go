@@ -0,0 +1,703 @@
+package eval
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// base is day zero for DATETIME and SMALLDATETIME arithmetic.
+var base = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Convert performs an explicit conversion, as CAST does.
+func Convert(v Value, t Type) (Value, error) {
+	if v.Type.Kind == KindBoolean || t.Kind == KindBoolean || t.Kind == KindNull {
+		return Value{}, fmt.Errorf("eval: cannot convert %s to %s", v.Type, t)
+	}
+	if v.Null {
+		return Null(t), nil
+	}
+	if !explicitAllowed(v.Type.Kind, t.Kind) {
+		return Value{}, sqlError(529, "Explicit conversion from data type %s to %s is not allowed.",
+			v.Type.Kind.messageName(), t.Kind.messageName())
+	}
+	switch {
+	case t.Kind.isString():
+		return toString(v, t)
+	case t.Kind.isBinary():
+		return toBinary(v, t)
+	case t.Kind == KindUniqueIdentifier:
+		return toUniqueIdentifier(v)
+	case t.Kind.isInteger():
+		return toInteger(v, t)
+	case t.Kind == KindDecimal || t.Kind.isMoney():
+		return toDecimal(v, t)
+	case t.Kind.isFloat():
+		return toFloat(v, t)
+	case t.Kind.isTemporal():
+		return toTemporal(v, t)
+	}
+	return Value{}, fmt.Errorf("eval: cannot convert %s to %s", v.Type, t)
+}
+
+// implicit converts v to t as SQL Server does when mixing operand types.
+func implicit(v Value, t Type) (Value, error) {
+	from := v.Type.Kind
+	if from == t.Kind && v.Type == t {
+		return v, nil
+	}
+	if from.isString() && t.Kind.isBinary() {
+		return Value{}, sqlError(257, "Implicit conversion from data type %s to %s is not allowed. Use the CONVERT function to run this query.",
+			from.messageName(), t.Kind.messageName())
+	}
+	if from != KindNull && !explicitAllowed(from, t.Kind) {
+		return Value{}, clash(from, t.Kind)
+	}
+	return Convert(v, t)
+}
+
+func explicitAllowed(from, to Kind) bool {
+	if from == to || from == KindNull || from.isString() || to.isString() {
+		return true
+	}
+	pair := func(a, b Kind) bool {
+		switch {
+		case a == KindUniqueIdentifier:
+			return b.isBinary()
+		case a.isTemporal() && b.isTemporal():
+			return !(a == KindTime && b == KindDate || a == KindDate && b == KindTime)
+		case a.isLegacyDateTime():
+			return b.isNumeric()
+		case a.isBinary():
+			return b.isBinary() || b.isInteger()
+		case a.isNumeric():
+			return b.isNumeric()
+		}
+		return false
+	}
+	return pair(from, to) || pair(to, from)
+}
+
+// commonType returns the type two operands are converted to when they are
+// compared or combined in CASE, COALESCE or UNION.
+func commonType(a, b Type) Type {
+	if a.Kind < b.Kind {
+		a, b = b, a
+	}
+	switch {
+	case b.Kind == KindNull || b.Kind == KindBoolean:
+		return a
+	case a.Kind == KindDecimal && b.Kind.isExact():
+		return mergeDecimal(a, exactType(b))
+	case a.Kind.isString() && b.Kind.isString(), a.Kind.isBinary() && b.Kind.isBinary():
+		if a.Kind != b.Kind {
+			// Combining fixed and varying lengths yields a varying length
+			switch a.Kind {
+			case KindChar:
+				a.Kind = KindVarChar
+			case KindNChar:
+				a.Kind = KindNVarChar
+			}
+		}
+		if b.Length == MaxLength || (a.Length != MaxLength && b.Length > a.Length) {
+			a.Length = b.Length
+		}
+	case a.Kind == b.Kind && a.Kind.isTemporal():
+		if b.Scale > a.Scale {
+			a.Scale = b.Scale
+		}
+	}
+	return a
+}
+
+// exactType returns the DECIMAL precision and scale an exact numeric type
+// converts to.
+func exactType(t Type) Type {
+	switch t.Kind {
+	case KindBit:
+		return Type{Kind: KindDecimal, Precision: 1}
+	case KindTinyInt:
+		return Type{Kind: KindDecimal, Precision: 3}
+	case KindSmallInt:
+		return Type{Kind: KindDecimal, Precision: 5}
+	case KindInt:
+		return Type{Kind: KindDecimal, Precision: 10}
+	case KindBigInt:
+		return Type{Kind: KindDecimal, Precision: 19}
+	case KindSmallMoney:
+		return Type{Kind: KindDecimal, Precision: 10, Scale: 4}
+	case KindMoney:
+		return Type{Kind: KindDecimal, Precision: 19, Scale: 4}
+	}
+	return t
+}
+
+func mergeDecimal(a, b Type) Type {
+	scale := max(a.Scale, b.Scale)
+	integral := max(a.Precision-a.Scale, b.Precision-b.Scale)
+	return decimalType(integral+scale, scale)
+}
+
+// decimalType caps a computed precision at 38, giving up scale (but
+// keeping at least 6 digits when it can) to preserve the integral part.
+func decimalType(precision, scale int) Type {
+	if precision > 38 {
+		integral := precision - scale
+		if integral < 32 {
+			scale = min(scale, 38-integral)
+		} else {
+			scale = min(scale, 6)
+		}
+		precision = 38
+	}
+	return Type{Kind: KindDecimal, Precision: precision, Scale: scale}
+}
+
+func intRange(k Kind) (int64, int64) {
+	switch k {
+	case KindBit:
+		return 0, 1
+	case KindTinyInt:
+		return 0, math.MaxUint8
+	case KindSmallInt:
+		return math.MinInt16, math.MaxInt16
+	case KindInt:
+		return math.MinInt32, math.MaxInt32
+	}
+	return math.MinInt64, math.MaxInt64
+}
+
+func intSize(k Kind) int {
+	switch k {
+	case KindBit, KindTinyInt:
+		return 1
+	case KindSmallInt:
+		return 2
+	case KindInt:
+		return 4
+	}
+	return 8
+}
+
+// format converts v to text using CONVERT style 0.
+func format(v Value) string {
+	switch v.Type.Kind {
+	case KindMoney, KindSmallMoney:
+		return v.Decimal().Rescale(2).String()
+	case KindReal:
+		return formatFloat(v.Float64(), 32)
+	case KindFloat:
+		return formatFloat(v.Float64(), 64)
+	case KindBinary, KindVarBinary:
+		return string(v.Bytes())
+	case KindDateTime, KindSmallDateTime:
+		t := v.Time()
+		hour := t.Hour() % 12
+		if hour == 0 {
+			hour = 12
+		}
+		return fmt.Sprintf("%s %2d %d %2d:%02d%s", t.Format("Jan"), t.Day(), t.Year(), hour, t.Minute(), t.Format("PM"))
+	}
+	return v.String()
+}
+
+// formatFloat formats f with at most six significant digits, writing
+// exponents with three digits as SQL Server does.
+func formatFloat(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'g', 6, bitSize)
+	mantissa, exp, ok := strings.Cut(s, "e")
+	if !ok {
+		return s
+	}
+	sign := exp[:1]
+	digits := exp[1:]
+	for len(digits) < 3 {
+		digits = "0" + digits
+	}
+	return mantissa + "e" + sign + digits
+}
+
+func toString(v Value, t Type) (Value, error) {
+	from := v.Type.Kind
+	s := format(v)
+	if from.isBinary() && t.Kind.isUnicode() {
+		s = decodeUTF16(v.Bytes())
+	} else if from.isBinary() {
+		s = decodeLatin1(v.Bytes())
+	}
+
+	runes := []rune(s)
+	length := t.Length
+	if length == MaxLength {
+		length = len(runes)
+	}
+	if len(runes) > length {
+		switch {
+		case from.isInteger() && !t.Kind.isUnicode():
+			runes = []rune("*")
+		case from.isNumeric():
+			return Value{}, sqlError(8115, "Arithmetic overflow error converting %s to data type %s.",
+				from.messageName(), t.Kind.messageName())
+		case from == KindUniqueIdentifier:
+			return Value{}, sqlError(8170, "Insufficient result space to convert uniqueidentifier value to %s.",
+				t.Kind.messageName())
+		default:
+			runes = runes[:length]
+		}
+	}
+	s = string(runes)
+	if t.Kind == KindChar || t.Kind == KindNChar {
+		s += strings.Repeat(" ", length-len(runes))
+	}
+	out := Value{Type: t, data: s}
+	if from.isString() {
+		out.collation = v.collation
+	}
+	return out, nil
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func encodeString(v Value) []byte {
+	if v.Type.Kind.isUnicode() {
+		var out []byte
+		for _, u := range utf16.Encode([]rune(v.Text())) {
+			out = binary.LittleEndian.AppendUint16(out, u)
+		}
+		return out
+	}
+	var out []byte
+	for _, r := range v.Text() {
+		if r > 0xff {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+func toBinary(v Value, t Type) (Value, error) {
+	var b []byte
+	integer := v.Type.Kind.isInteger()
+	switch {
+	case v.Type.Kind.isBinary():
+		b = v.Bytes()
+	case v.Type.Kind.isString():
+		b = encodeString(v)
+	case integer:
+		b = binary.BigEndian.AppendUint64(nil, uint64(v.Int64()))
+		b = b[8-intSize(v.Type.Kind):]
+	case v.Type.Kind == KindUniqueIdentifier:
+		b = guidBytes(v.Text())
+	}
+
+	length := t.Length
+	if length == MaxLength {
+		length = len(b)
+	}
+	switch {
+	case len(b) > length && integer:
+		// Integers keep their low-order bytes
+		b = b[len(b)-length:]
+	case len(b) > length:
+		b = b[:length]
+	case len(b) < length && t.Kind == KindBinary && integer:
+		b = append(make([]byte, length-len(b)), b...)
+	case len(b) < length && t.Kind == KindBinary:
+		b = append(append([]byte(nil), b...), make([]byte, length-len(b))...)
+	}
+	return Value{Type: t, data: b}, nil
+}
+
+// guidBytes returns the storage form of a GUID, whose first three groups
+// are little-endian.
+func guidBytes(s string) []byte {
+	raw, _ := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if len(raw) != 16 {
+		return make([]byte, 16)
+	}
+	out := append([]byte(nil), raw...)
+	for _, r := range [][2]int{{0, 4}, {4, 6}, {6, 8}} {
+		for i, j := r[0], r[1]-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
+
+func toUniqueIdentifier(v Value) (Value, error) {
+	t := Type{Kind: KindUniqueIdentifier}
+	if v.Type.Kind.isBinary() {
+		b := v.Bytes()
+		if len(b) < 16 {
+			b = append(append([]byte(nil), b...), make([]byte, 16-len(b))...)
+		}
+		raw := guidBytes(hex.EncodeToString(b[:16]))
+		return Value{Type: t, data: formatGUID(raw)}, nil
+	}
+	if v.Type.Kind == KindUniqueIdentifier {
+		return v, nil
+	}
+	s := strings.TrimSpace(v.Text())
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(raw) != 16 || len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return Value{}, sqlError(8169, "Conversion failed when converting from a character string to uniqueidentifier.")
+	}
+	return Value{Type: t, data: formatGUID(raw)}, nil
+}
+
+func formatGUID(raw []byte) string {
+	h := strings.ToUpper(hex.EncodeToString(raw))
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func conversionFailed(v Value, t Kind) *Error {
+	return sqlError(245, "Conversion failed when converting the %s value '%s' to data type %s.",
+		v.Type.Kind.messageName(), v.Text(), t.messageName())
+}
+
+func toInteger(v Value, t Type) (Value, error) {
+	var i int64
+	from := v.Type.Kind
+	switch {
+	case from.isInteger():
+		i = v.Int64()
+	case from.isMoney():
+		var ok bool
+		if i, ok = v.Decimal().Rescale(0).Int64(); !ok {
+			return Value{}, overflow(t.Kind)
+		}
+	case from == KindDecimal:
+		var ok bool
+		if i, ok = v.Decimal().Int64(); !ok {
+			return Value{}, overflow(t.Kind)
+		}
+	case from.isFloat():
+		f := math.Trunc(v.Float64())
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return Value{}, overflow(t.Kind)
+		}
+		i = int64(f)
+	case from.isString():
+		s := strings.TrimSpace(v.Text())
+		if t.Kind == KindBit {
+			switch strings.ToUpper(s) {
+			case "TRUE":
+				return Bit(true), nil
+			case "FALSE":
+				return Bit(false), nil
+			}
+		}
+		if s == "" {
+			return Value{Type: t, data: int64(0)}, nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange {
+				return Value{}, sqlError(248, "The conversion of the %s value '%s' overflowed an %s column.",
+					from.messageName(), v.Text(), t.Kind.messageName())
+			}
+			return Value{}, conversionFailed(v, t.Kind)
+		}
+		if lo, hi := intRange(t.Kind); t.Kind != KindBit && (n < lo || n > hi) {
+			return Value{}, sqlError(248, "The conversion of the %s value '%s' overflowed an %s column.",
+				from.messageName(), v.Text(), t.Kind.messageName())
+		}
+		i = n
+	case from.isBinary():
+		b := v.Bytes()
+		size := intSize(t.Kind)
+		if len(b) > size {
+			b = b[len(b)-size:]
+		}
+		for _, c := range b {
+			i = i<<8 | int64(c)
+		}
+		if size < 8 && len(b) == size && b[0]&0x80 != 0 && t.Kind != KindTinyInt {
+			i -= 1 << (8 * size)
+		}
+	case from.isLegacyDateTime():
+		i = int64(math.Floor(daysSinceBase(v.Time()) + 0.5))
+	}
+
+	if t.Kind == KindBit {
+		return Bit(i != 0), nil
+	}
+	if lo, hi := intRange(t.Kind); i < lo || i > hi {
+		if from.isInteger() {
+			return Value{}, sqlError(220, "Arithmetic overflow error for data type %s, value = %d.",
+				t.Kind.messageName(), i)
+		}
+		return Value{}, overflow(t.Kind)
+	}
+	return Value{Type: t, data: i}, nil
+}
+
+func toDecimal(v Value, t Type) (Value, error) {
+	var d Decimal
+	from := v.Type.Kind
+	switch {
+	case from.isInteger():
+		d = decimalFromInt(v.Int64())
+	case from == KindDecimal || from.isMoney():
+		d = v.Decimal()
+	case from.isFloat():
+		if !isFinite(v.Float64()) {
+			return Value{}, overflow(t.Kind)
+		}
+		d = DecimalFromFloat(v.Float64(), t.Scale)
+	case from.isString():
+		s := strings.TrimSpace(v.Text())
+		if t.Kind.isMoney() {
+			s = strings.ReplaceAll(strings.Replace(s, "$", "", 1), ",", "")
+		}
+		var err error
+		if d, err = ParseDecimal(s); err != nil {
+			if t.Kind.isMoney() {
+				return Value{}, sqlError(235, "Cannot convert a char value to money. The char value has incorrect syntax.")
+			}
+			return Value{}, sqlError(8114, "Error converting data type %s to %s.", from.messageName(), t.Kind.messageName())
+		}
+	case from.isLegacyDateTime():
+		d = DecimalFromFloat(daysSinceBase(v.Time()), t.Scale)
+	}
+
+	d = d.Rescale(t.Scale)
+	if !d.fitsPrecision(t.Precision) || !inMoneyRange(d, t.Kind) {
+		return Value{}, sqlError(8115, "Arithmetic overflow error converting %s to data type %s.",
+			from.messageName(), t.Kind.messageName())
+	}
+	return Value{Type: t, data: d}, nil
+}
+
+var (
+	moneyMax      = MakeDecimal(math.MaxInt64, 4)
+	moneyMin      = MakeDecimal(math.MinInt64, 4)
+	smallMoneyMax = MakeDecimal(math.MaxInt32, 4)
+	smallMoneyMin = MakeDecimal(math.MinInt32, 4)
+)
+
+func inMoneyRange(d Decimal, k Kind) bool {
+	switch k {
+	case KindMoney:
+		return d.Cmp(moneyMin) >= 0 && d.Cmp(moneyMax) <= 0
+	case KindSmallMoney:
+		return d.Cmp(smallMoneyMin) >= 0 && d.Cmp(smallMoneyMax) <= 0
+	}
+	return true
+}
+
+func toFloat(v Value, t Type) (Value, error) {
+	var f float64
+	from := v.Type.Kind
+	switch {
+	case from.isInteger():
+		f = float64(v.Int64())
+	case from == KindDecimal || from.isMoney():
+		f = v.Decimal().Float64()
+	case from.isFloat():
+		f = v.Float64()
+	case from.isString():
+		s := strings.TrimSpace(v.Text())
+		if s != "" {
+			var err error
+			if f, err = strconv.ParseFloat(s, 64); err != nil || !isFinite(f) || strings.ContainsAny(s, "xXnN") {
+				return Value{}, sqlError(8114, "Error converting data type %s to %s.", from.messageName(), t.Kind.messageName())
+			}
+		}
+	case from.isLegacyDateTime():
+		f = daysSinceBase(v.Time())
+	}
+	return makeFloat(f, t.Kind)
+}
+
+func makeFloat(f float64, k Kind) (Value, error) {
+	if k == KindReal {
+		if math.Abs(f) > math.MaxFloat32 {
+			return Value{}, overflow(k)
+		}
+		f = float64(float32(f))
+	}
+	if !isFinite(f) {
+		return Value{}, overflow(k)
+	}
+	return Value{Type: Type{Kind: k}, data: f}, nil
+}
+
+func daysSinceBase(t time.Time) float64 {
+	return float64(unixDay(t)-unixDayOf1900) + float64(sinceMidnight(t))/float64(24*time.Hour)
+}
+
+func toTemporal(v Value, t Type) (Value, error) {
+	var tm time.Time
+	from := v.Type.Kind
+	switch {
+	case from.isString():
+		parsed, zoned, err := parseDateTime(v.Text(), t.Kind)
+		if err != nil {
+			return Value{}, err
+		}
+		tm = parsed
+		if zoned && t.Kind != KindDateTimeOffset {
+			tm = clock(parsed)
+		}
+	case from.isTemporal():
+		tm = v.Time()
+		switch {
+		case from == KindDateTimeOffset && t.Kind != KindDateTimeOffset:
+			tm = clock(tm)
+		case from == KindTime:
+			tm = time.Date(1900, 1, 1, tm.Hour(), tm.Minute(), tm.Second(), tm.Nanosecond(), time.UTC)
+		}
+	case from.isNumeric():
+		f, err := Convert(v, typeFloat)
+		if err != nil {
+			return Value{}, err
+		}
+		n := f.Float64()
+		if n < -53690 || n >= 2958464 {
+			return Value{}, overflow(t.Kind)
+		}
+		days := math.Floor(n)
+		tm = base.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((n - days) * float64(24*time.Hour))))
+	}
+
+	if !inDateRange(tm, t.Kind) {
+		if from.isString() {
+			return Value{}, sqlError(242, "The conversion of a %s data type to a %s data type resulted in an out-of-range value.",
+				from.messageName(), t.Kind.messageName())
+		}
+		return Value{}, overflow(t.Kind)
+	}
+	return Value{Type: t, data: roundTime(tm, t.Kind, t.Scale)}, nil
+}
+
+// clock returns the wall-clock time of t without its offset.
+func clock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+func inDateRange(t time.Time, k Kind) bool {
+	switch k {
+	case KindDateTime:
+		return t.Year() >= 1753 && t.Year() <= 9999
+	case KindSmallDateTime:
+		return !t.Before(base) && t.Before(time.Date(2079, 6, 7, 0, 0, 0, 0, time.UTC))
+	}
+	return t.Year() >= 1 && t.Year() <= 9999
+}
+
+// roundTime rounds t to the accuracy of kind k.
+func roundTime(t time.Time, k Kind, scale int) time.Time {
+	switch k {
+	case KindDate:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case KindSmallDateTime:
+		minute := t.Truncate(time.Minute)
+		if t.Sub(minute) >= 29999*time.Millisecond {
+			minute = minute.Add(time.Minute)
+		}
+		return minute
+	case KindDateTime:
+		// DATETIME counts 1/300 second ticks, shown rounded to milliseconds
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		ticks := math.Round(float64(t.Sub(day)) * 300 / float64(time.Second))
+		ms := math.Round(ticks * 10 / 3)
+		return day.Add(time.Duration(ms) * time.Millisecond)
+	case KindTime:
+		t = t.Round(time.Duration(math.Pow10(9 - scale)))
+		return time.Date(1900, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+	return t.Round(time.Duration(math.Pow10(9 - scale)))
+}
+
+var (
+	dateLayouts = []string{
+		"2006-01-02", "20060102", "2006/01/02", "2006.01.02",
+		"1/2/2006", "1-2-2006", "1.2.2006",
+		"Jan 2 2006", "Jan 2, 2006", "January 2 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006",
+	}
+	timeLayouts = []string{"15:04", "15:04:05", "3PM", "3:04PM", "3:04:05PM", "3 PM", "3:04 PM", "3:04:05 PM"}
+	zoneLayouts = []string{"", " -07:00", "-07:00", "Z07:00"}
+)
+
+// parseDateTime parses the string formats SQL Server accepts under the
+// default us_english language, reporting whether an offset was given.
+func parseDateTime(s string, k Kind) (time.Time, bool, error) {
+	failed := sqlError(241, "Conversion failed when converting date and/or time from character string.")
+	text := strings.ToUpper(strings.Join(strings.Fields(s), " "))
+	if k.isLegacyDateTime() && fractionDigits(text) > 3 {
+		return time.Time{}, false, failed
+	}
+
+	parse := func(layout string) (time.Time, bool, bool) {
+		for _, zone := range zoneLayouts {
+			if t, err := time.Parse(layout+zone, text); err == nil {
+				return t, zone != "", true
+			}
+		}
+		return time.Time{}, false, false
+	}
+
+	for _, d := range dateLayouts {
+		if t, err := time.Parse(d, text); err == nil {
+			return t, false, nil
+		}
+		for _, tl := range timeLayouts {
+			for _, sep := range []string{" ", "T"} {
+				if sep == "T" && d != "2006-01-02" {
+					continue
+				}
+				if t, zoned, ok := parse(d + sep + tl); ok {
+					return t, zoned, nil
+				}
+			}
+		}
+	}
+	for _, tl := range timeLayouts {
+		if t, zoned, ok := parse(tl); ok {
+			return time.Date(1900, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()), zoned, nil
+		}
+	}
+	return time.Time{}, false, failed
+}
+
+// fractionDigits counts the digits after the seconds' decimal point.
+func fractionDigits(s string) int {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return 0
+	}
+	dot := strings.Index(s[i:], ".")
+	if dot < 0 {
+		return 0
+	}
+	n := 0
+	for _, c := range s[i+dot+1:] {
+		if c < '0' || c > '9' {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,743 @@
+package eval
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// culture holds the conventions FORMAT and PARSE follow for a .NET
+// culture name.
+type culture struct {
+	name           string
+	decimal, group string
+	currency       string
+	// Patterns for currency and percentages, with n standing for the
+	// number and $ for the currency symbol
+	currencyPositive, currencyNegative string
+	percentPositive, percentNegative   string
+	dateSep                            string
+	// Standard date and time patterns: d, D, t, T, M and Y
+	patterns             map[byte]string
+	months, abbrevMonths [12]string
+	days, abbrevDays     [7]string
+	am, pm               string
+	dayFirst             bool // Numeric dates are day/month/year
+}
+
+var englishMonths = [12]string{"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December"}
+
+var englishAbbrevMonths = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+var englishDays = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+var englishAbbrevDays = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+var cultures = map[string]*culture{
+	"en-us": {
+		name: "en-US", decimal: ".", group: ",", currency: "$",
+		currencyPositive: "$n", currencyNegative: "($n)",
+		percentPositive: "n %", percentNegative: "-n %",
+		dateSep: "/",
+		patterns: map[byte]string{
+			'd': "M/d/yyyy", 'D': "dddd, MMMM d, yyyy", 't': "h:mm tt", 'T': "h:mm:ss tt",
+			'M': "MMMM d", 'Y': "MMMM yyyy",
+		},
+		months: englishMonths, abbrevMonths: englishAbbrevMonths,
+		days: englishDays, abbrevDays: englishAbbrevDays,
+		am: "AM", pm: "PM",
+	},
+	"en-gb": {
+		name: "en-GB", decimal: ".", group: ",", currency: "£",
+		currencyPositive: "$n", currencyNegative: "-$n",
+		percentPositive: "n%", percentNegative: "-n%",
+		dateSep: "/",
+		patterns: map[byte]string{
+			'd': "dd/MM/yyyy", 'D': "dd MMMM yyyy", 't': "HH:mm", 'T': "HH:mm:ss",
+			'M': "d MMMM", 'Y': "MMMM yyyy",
+		},
+		months: englishMonths, abbrevMonths: englishAbbrevMonths,
+		days: englishDays, abbrevDays: englishAbbrevDays,
+		am: "AM", pm: "PM", dayFirst: true,
+	},
+	"de-de": {
+		name: "de-DE", decimal: ",", group: ".", currency: "€",
+		currencyPositive: "n $", currencyNegative: "-n $",
+		percentPositive: "n %", percentNegative: "-n %",
+		dateSep: ".",
+		patterns: map[byte]string{
+			'd': "dd.MM.yyyy", 'D': "dddd, d. MMMM yyyy", 't': "HH:mm", 'T': "HH:mm:ss",
+			'M': "d. MMMM", 'Y': "MMMM yyyy",
+		},
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		abbrevMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		days:         [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		abbrevDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		dayFirst:     true,
+	},
+	"fr-fr": {
+		name: "fr-FR", decimal: ",", group: "\u00a0", currency: "€",
+		currencyPositive: "n $", currencyNegative: "-n $",
+		percentPositive: "n %", percentNegative: "-n %",
+		dateSep: "/",
+		patterns: map[byte]string{
+			'd': "dd/MM/yyyy", 'D': "dddd d MMMM yyyy", 't': "HH:mm", 'T': "HH:mm:ss",
+			'M': "d MMMM", 'Y': "MMMM yyyy",
+		},
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin",
+			"juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		abbrevMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:         [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		abbrevDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		dayFirst:     true,
+	},
+}
+
+// cultureArg looks up a culture name, defaulting to en-US, the culture of
+// the default language.
+func cultureArg(args []Value, i int) (*culture, error) {
+	if len(args) <= i || args[i].Null {
+		return cultures["en-us"], nil
+	}
+	name := args[i].Text()
+	c, ok := cultures[strings.ToLower(name)]
+	if !ok {
+		return nil, sqlError(9818, "The culture parameter '%s' provided in the function call is not supported.", name)
+	}
+	return c, nil
+}
+
+// formatFunc implements FORMAT for numbers, dates and times with .NET
+// standard and custom format strings. Format strings it cannot apply give
+// NULL, as they do in SQL Server.
+func formatFunc(args []Value) (Value, error) {
+	t := Type{Kind: KindNVarChar, Length: 4000}
+	c, err := cultureArg(args, 2)
+	if err != nil {
+		return Value{}, err
+	}
+	v := args[0]
+	k := v.Type.Kind
+	if !k.isNumeric() && !k.isTemporal() && k != KindNull {
+		return Value{}, invalidArgument(v, 1, "format")
+	}
+	if v.Null || args[1].Null {
+		return Null(t), nil
+	}
+	pattern := args[1].Text()
+
+	var s string
+	var ok bool
+	if k.isNumeric() {
+		s, ok = formatNumber(v, pattern, c)
+	} else {
+		s, ok = formatDate(v, pattern, c)
+	}
+	if !ok {
+		return Null(t), nil
+	}
+	return Value{Type: t, data: s}, nil
+}
+
+// exactValue returns a numeric value as a Decimal, using the shortest
+// decimal form of floats.
+func exactValue(v Value) Decimal {
+	switch k := v.Type.Kind; {
+	case k.isInteger():
+		return decimalFromInt(v.Int64())
+	case k.isFloat():
+		d, _ := ParseDecimal(strconv.FormatFloat(v.Float64(), 'f', -1, 64))
+		return d
+	}
+	return v.Decimal()
+}
+
+// fixed formats the absolute value of d with digits decimals, grouping the
+// integer digits if group is set.
+func fixed(d Decimal, digits int, group bool, c *culture) string {
+	s := d.Rescale(digits).String()
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if group {
+		whole = groupDigits(whole, c.group)
+	}
+	if frac != "" {
+		return whole + c.decimal + frac
+	}
+	return whole
+}
+
+// applyPattern places a formatted number in a currency or percent
+// pattern.
+func applyPattern(pattern, number, symbol string) string {
+	return strings.Replace(strings.Replace(pattern, "n", number, 1), "$", symbol, 1)
+}
+
+var standardNumber = regexp.MustCompile(`^([A-Za-z])(\d{0,2})$`)
+
+func formatNumber(v Value, pattern string, c *culture) (string, bool) {
+	m := standardNumber.FindStringSubmatch(pattern)
+	if m == nil {
+		return customNumber(exactValue(v), pattern, c)
+	}
+	d := exactValue(v)
+	precision := -1
+	if m[2] != "" {
+		precision, _ = strconv.Atoi(m[2])
+	}
+	digits := func(def int) int {
+		if precision < 0 {
+			return def
+		}
+		return precision
+	}
+	neg := d.Rescale(max(digits(2), 0)).Sign() < 0
+	sign := ""
+	if neg {
+		sign = "-"
+	}
+
+	switch m[1] {
+	case "C", "c":
+		s := fixed(d, digits(2), true, c)
+		if d.Rescale(digits(2)).Sign() < 0 {
+			return applyPattern(c.currencyNegative, s, c.currency), true
+		}
+		return applyPattern(c.currencyPositive, s, c.currency), true
+	case "D", "d":
+		if !v.Type.Kind.isInteger() {
+			return "", false
+		}
+		s := strings.TrimPrefix(d.String(), "-")
+		s = strings.Repeat("0", max(digits(0)-len(s), 0)) + s
+		if d.Sign() < 0 {
+			s = "-" + s
+		}
+		return s, true
+	case "E", "e":
+		f := strconv.FormatFloat(d.Float64(), 'e', digits(6), 64)
+		mantissa, exp, _ := strings.Cut(f, "e")
+		expSign, expDigits := exp[:1], exp[1:]
+		for len(expDigits) < 3 {
+			expDigits = "0" + expDigits
+		}
+		return strings.Replace(mantissa, ".", c.decimal, 1) + m[1] + expSign + expDigits, true
+	case "F", "f":
+		return sign + fixed(d, digits(2), false, c), true
+	case "N", "n":
+		return sign + fixed(d, digits(2), true, c), true
+	case "P", "p":
+		p := d.Mul(MakeDecimal(100, 0))
+		s := fixed(p, digits(2), true, c)
+		if p.Rescale(digits(2)).Sign() < 0 {
+			return applyPattern(c.percentNegative, s, ""), true
+		}
+		return applyPattern(c.percentPositive, s, ""), true
+	case "G", "g", "R", "r":
+		if v.Type.Kind.isFloat() {
+			s := strconv.FormatFloat(v.Float64(), 'G', max(precision, -1), 64)
+			if precision == 0 {
+				s = strconv.FormatFloat(v.Float64(), 'G', -1, 64)
+			}
+			return strings.Replace(s, ".", c.decimal, 1), true
+		}
+		return strings.Replace(d.String(), ".", c.decimal, 1), true
+	case "X", "x":
+		if !v.Type.Kind.isInteger() {
+			return "", false
+		}
+		n := uint64(v.Int64())
+		if size := intSize(v.Type.Kind); size < 8 {
+			n &= 1<<(8*size) - 1
+		}
+		s := strconv.FormatUint(n, 16)
+		if m[1] == "X" {
+			s = strings.ToUpper(s)
+		}
+		return strings.Repeat("0", max(digits(0)-len(s), 0)) + s, true
+	}
+	return "", false
+}
+
+// numberToken is a piece of a custom numeric format string.
+type numberToken struct {
+	placeholder rune // '0' or '#', or zero for a literal
+	literal     string
+}
+
+// customNumber applies a custom numeric format string such as "#,##0.00"
+// or "000-00-0000". Up to three sections separated by semicolons format
+// positive, negative and zero values.
+func customNumber(d Decimal, pattern string, c *culture) (string, bool) {
+	sections := splitSections(pattern)
+	section := sections[0]
+	explicitSign := false
+	switch {
+	case d.Sign() < 0 && len(sections) > 1 && sections[1] != "":
+		section, explicitSign = sections[1], true
+		d = d.Neg()
+	case d.Sign() == 0 && len(sections) > 2:
+		section = sections[2]
+	}
+
+	var intPart, fracPart []numberToken
+	group, percent, seenPoint := false, false, false
+	for i := 0; i < len(section); i++ {
+		ch := section[i]
+		var tok numberToken
+		switch ch {
+		case '0', '#':
+			tok.placeholder = rune(ch)
+		case '.':
+			if !seenPoint {
+				seenPoint = true
+				continue
+			}
+		case ',':
+			if !seenPoint {
+				group = true
+				continue
+			}
+		case '%':
+			percent = true
+			tok.literal = "%"
+		case '\\':
+			if i+1 < len(section) {
+				i++
+				tok.literal = string(section[i])
+			}
+		case '\'', '"':
+			end := strings.IndexByte(section[i+1:], ch)
+			if end < 0 {
+				return "", false
+			}
+			tok.literal = section[i+1 : i+1+end]
+			i += end + 1
+		}
+		if tok.placeholder == 0 && tok.literal == "" {
+			tok.literal = string(ch)
+		}
+		if seenPoint {
+			fracPart = append(fracPart, tok)
+		} else {
+			intPart = append(intPart, tok)
+		}
+	}
+	if percent {
+		d = d.Mul(MakeDecimal(100, 0))
+	}
+
+	minInt, maxFrac, minFrac := 0, 0, 0
+	for i, t := range intPart {
+		if t.placeholder == '0' {
+			minInt = 0
+			for _, u := range intPart[i:] {
+				if u.placeholder != 0 {
+					minInt++
+				}
+			}
+			break
+		}
+	}
+	for _, t := range fracPart {
+		if t.placeholder != 0 {
+			maxFrac++
+			if t.placeholder == '0' {
+				minFrac = maxFrac
+			}
+		}
+	}
+
+	r := d.Rescale(maxFrac)
+	neg := r.Sign() < 0 && !explicitSign
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(r.String(), "-"), ".")
+	if whole == "0" {
+		whole = ""
+	}
+	whole = strings.Repeat("0", max(minInt-len(whole), 0)) + whole
+	for len(frac) > minFrac && strings.HasSuffix(frac, "0") {
+		frac = frac[:len(frac)-1]
+	}
+
+	var b strings.Builder
+	if neg {
+		b.WriteString("-")
+	}
+	if group {
+		// Grouped numbers go where the first placeholder is
+		placed := false
+		for _, t := range intPart {
+			switch {
+			case t.placeholder == 0:
+				b.WriteString(t.literal)
+			case !placed:
+				b.WriteString(groupDigits(whole, c.group))
+				placed = true
+			}
+		}
+	} else {
+		// Digits fill placeholders from the right; extra digits go in
+		// the leftmost one
+		digits := []rune(whole)
+		out := make([]string, len(intPart))
+		first := -1
+		for i := len(intPart) - 1; i >= 0; i-- {
+			t := intPart[i]
+			if t.placeholder == 0 {
+				out[i] = t.literal
+				continue
+			}
+			first = i
+			if len(digits) > 0 {
+				out[i] = string(digits[len(digits)-1])
+				digits = digits[:len(digits)-1]
+			}
+		}
+		if first >= 0 {
+			out[first] = string(digits) + out[first]
+		} else {
+			out = append([]string{string(digits)}, out...)
+		}
+		b.WriteString(strings.Join(out, ""))
+	}
+	if frac != "" {
+		b.WriteString(c.decimal)
+	}
+	fracDigits := []rune(frac)
+	for _, t := range fracPart {
+		if t.placeholder == 0 {
+			b.WriteString(t.literal)
+			continue
+		}
+		if len(fracDigits) > 0 {
+			b.WriteRune(fracDigits[0])
+			fracDigits = fracDigits[1:]
+		}
+	}
+	return b.String(), true
+}
+
+// splitSections splits a custom format string at semicolons outside
+// quotes.
+func splitSections(pattern string) []string {
+	var sections []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '\\':
+			i++
+		case ch == ';':
+			sections = append(sections, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return append(sections, pattern[start:])
+}
+
+// formatDate applies a .NET standard or custom date and time format
+// string.
+func formatDate(v Value, pattern string, c *culture) (string, bool) {
+	t := v.Time()
+	if len(pattern) == 1 {
+		ch := pattern[0]
+		switch ch {
+		case 'd', 'D', 't', 'T':
+			pattern = c.patterns[ch]
+		case 'f':
+			pattern = c.patterns['D'] + " " + c.patterns['t']
+		case 'F':
+			pattern = c.patterns['D'] + " " + c.patterns['T']
+		case 'g':
+			pattern = c.patterns['d'] + " " + c.patterns['t']
+		case 'G':
+			pattern = c.patterns['d'] + " " + c.patterns['T']
+		case 'M', 'm':
+			pattern = c.patterns['M']
+		case 'Y', 'y':
+			pattern = c.patterns['Y']
+		case 's':
+			pattern = "yyyy'-'MM'-'dd'T'HH':'mm':'ss"
+		case 'u':
+			pattern = "yyyy'-'MM'-'dd HH':'mm':'ss'Z'"
+		case 'o', 'O':
+			pattern = "yyyy'-'MM'-'dd'T'HH':'mm':'ss'.'fffffffK"
+		case 'R', 'r':
+			pattern = "ddd, dd MMM yyyy HH':'mm':'ss 'GMT'"
+			c = cultures["en-us"]
+		default:
+			return "", false
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		ch := pattern[i]
+		n := 1
+		for i+n < len(pattern) && pattern[i+n] == ch {
+			n++
+		}
+		switch ch {
+		case 'd':
+			switch {
+			case n == 1:
+				b.WriteString(strconv.Itoa(t.Day()))
+			case n == 2:
+				b.WriteString(pad(t.Day(), 2))
+			case n == 3:
+				b.WriteString(c.abbrevDays[t.Weekday()])
+			default:
+				b.WriteString(c.days[t.Weekday()])
+			}
+		case 'M':
+			switch {
+			case n == 1:
+				b.WriteString(strconv.Itoa(int(t.Month())))
+			case n == 2:
+				b.WriteString(pad(int(t.Month()), 2))
+			case n == 3:
+				b.WriteString(c.abbrevMonths[t.Month()-1])
+			default:
+				b.WriteString(c.months[t.Month()-1])
+			}
+		case 'y':
+			switch {
+			case n == 1:
+				b.WriteString(strconv.Itoa(t.Year() % 100))
+			case n == 2:
+				b.WriteString(pad(t.Year()%100, 2))
+			default:
+				b.WriteString(pad(t.Year(), n))
+			}
+		case 'h':
+			h := t.Hour() % 12
+			if h == 0 {
+				h = 12
+			}
+			b.WriteString(pad(h, min(n, 2)))
+		case 'H':
+			b.WriteString(pad(t.Hour(), min(n, 2)))
+		case 'm':
+			b.WriteString(pad(t.Minute(), min(n, 2)))
+		case 's':
+			b.WriteString(pad(t.Second(), min(n, 2)))
+		case 'f', 'F':
+			if n > 7 {
+				return "", false
+			}
+			digits := pad(t.Nanosecond(), 9)[:n]
+			if ch == 'F' {
+				digits = strings.TrimRight(digits, "0")
+				if digits == "" && strings.HasSuffix(b.String(), ".") {
+					s := b.String()
+					b.Reset()
+					b.WriteString(s[:len(s)-1])
+				}
+			}
+			b.WriteString(digits)
+		case 't':
+			designator := c.am
+			if t.Hour() >= 12 {
+				designator = c.pm
+			}
+			if n == 1 && designator != "" {
+				designator = designator[:1]
+			}
+			b.WriteString(designator)
+		case 'z':
+			_, offset := t.Zone()
+			sign := "+"
+			if offset < 0 {
+				sign, offset = "-", -offset
+			}
+			switch {
+			case n == 1:
+				b.WriteString(sign + strconv.Itoa(offset/3600))
+			case n == 2:
+				b.WriteString(sign + pad(offset/3600, 2))
+			default:
+				b.WriteString(sign + pad(offset/3600, 2) + ":" + pad(offset%3600/60, 2))
+			}
+		case 'K':
+			if v.Type.Kind == KindDateTimeOffset {
+				b.WriteString(t.Format("-07:00"))
+			}
+		case 'g':
+			b.WriteString("A.D.")
+		case ':':
+			b.WriteString(strings.Repeat(":", n))
+		case '/':
+			b.WriteString(strings.Repeat(c.dateSep, n))
+		case '\'', '"':
+			end := strings.IndexByte(pattern[i+1:], ch)
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(pattern[i+1 : i+1+end])
+			i += end + 2
+			continue
+		case '\\', '%':
+			if ch == '\\' && i+1 < len(pattern) {
+				b.WriteByte(pattern[i+1])
+				i += 2
+			} else {
+				i++
+			}
+			continue
+		default:
+			b.WriteString(pattern[i : i+n])
+		}
+		i += n
+	}
+	return b.String(), true
+}
+
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	return strings.Repeat("0", max(width-len(s), 0)) + s
+}
+
+var (
+	clockPattern = regexp.MustCompile(`(\d{1,2}):(\d{2})(?::(\d{2})(?:[.,](\d{1,7}))?)?\s*(am|pm)?`)
+	digitRun     = regexp.MustCompile(`\d+`)
+	wordRun      = regexp.MustCompile(`\pL+`)
+)
+
+// parseCulture reads a string as PARSE does: numbers with the culture's
+// separators and currency symbol, and dates with its field order and
+// month names.
+func parseCulture(s string, t Type, c *culture) (Value, error) {
+	failed := sqlError(9819, "Error converting string value '%s' into data type %s using culture '%s'.",
+		s, t.Kind.messageName(), c.name)
+	text := strings.TrimSpace(s)
+	switch {
+	case t.Kind.isNumeric():
+		neg := false
+		if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+			neg, text = true, text[1:len(text)-1]
+		}
+		text = strings.NewReplacer(c.currency, "", "$", "", c.group, "", " ", "").Replace(text)
+		if strings.HasSuffix(text, "-") {
+			neg, text = true, strings.TrimSuffix(text, "-")
+		}
+		if c.decimal != "." {
+			if strings.Contains(text, ".") {
+				return Value{}, failed
+			}
+			text = strings.Replace(text, c.decimal, ".", 1)
+		}
+		if neg {
+			text = "-" + strings.TrimPrefix(text, "+")
+		}
+		if text == "" {
+			return Value{}, failed
+		}
+		v, err := Convert(VarChar(text), t)
+		if err != nil {
+			return Value{}, failed
+		}
+		return v, nil
+	case t.Kind.isTemporal():
+		tm, ok := parseCultureDate(strings.ToLower(text), c)
+		if !ok {
+			return Value{}, failed
+		}
+		v, err := Convert(DateTime2(tm), t)
+		if err != nil {
+			return Value{}, failed
+		}
+		return v, nil
+	}
+	return Value{}, sqlError(9809, "The style %d is not supported for conversions from %s to %s.",
+		0, KindNVarChar.messageName(), t.Kind.messageName())
+}
+
+func parseCultureDate(s string, c *culture) (time.Time, bool) {
+	var hour, minute, second, nanos int
+	if m := clockPattern.FindStringSubmatchIndex(s); m != nil {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return s[m[2*i]:m[2*i+1]]
+		}
+		hour, _ = strconv.Atoi(group(1))
+		minute, _ = strconv.Atoi(group(2))
+		second, _ = strconv.Atoi(group(3))
+		if f := group(4); f != "" {
+			nanos, _ = strconv.Atoi(f + strings.Repeat("0", 9-len(f)))
+		}
+		switch group(5) {
+		case "pm":
+			if hour < 12 {
+				hour += 12
+			}
+		case "am":
+			if hour == 12 {
+				hour = 0
+			}
+		}
+		s = s[:m[0]] + " " + s[m[1]:]
+	}
+
+	month := 0
+	for _, word := range wordRun.FindAllString(s, -1) {
+		if m := monthNumber(word, c); m != 0 {
+			month = m
+		}
+	}
+
+	numbers := digitRun.FindAllString(s, -1)
+	value := func(i int) int {
+		n, _ := strconv.Atoi(numbers[i])
+		return n
+	}
+	var year, day int
+	switch {
+	case month != 0 && len(numbers) == 2:
+		day, year = value(0), value(1)
+		if len(numbers[0]) == 4 {
+			day, year = year, day
+		}
+		if len(numbers[1]) <= 2 && len(numbers[0]) <= 2 {
+			year, _ = strconv.Atoi(expandYear(numbers[1]))
+		}
+	case month == 0 && len(numbers) == 3:
+		switch {
+		case len(numbers[0]) == 4:
+			year, month, day = value(0), value(1), value(2)
+		case c.dayFirst:
+			day, month, year = value(0), value(1), value(2)
+		default:
+			month, day, year = value(0), value(1), value(2)
+		}
+		if len(numbers[2]) <= 2 && len(numbers[0]) != 4 {
+			year, _ = strconv.Atoi(expandYear(numbers[2]))
+		}
+	default:
+		return time.Time{}, false
+	}
+	tm, ok := makeTime(int64(year), int64(month), int64(day), int64(hour), int64(minute), int64(second), int64(nanos), time.UTC)
+	return tm, ok
+}
+
+// monthNumber returns the month a full or abbreviated month name in the
+// culture or in English stands for, or 0.
+func monthNumber(word string, c *culture) int {
+	for _, list := range [][12]string{c.months, c.abbrevMonths, englishMonths, englishAbbrevMonths} {
+		for i, name := range list {
+			if strings.EqualFold(strings.TrimSuffix(name, "."), word) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
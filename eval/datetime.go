@@ -0,0 +1,684 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateparts maps every datepart name and abbreviation to its full name.
+var dateparts = map[string]string{
+	"year": "year", "yy": "year", "yyyy": "year",
+	"quarter": "quarter", "qq": "quarter", "q": "quarter",
+	"month": "month", "mm": "month", "m": "month",
+	"dayofyear": "dayofyear", "dy": "dayofyear", "y": "dayofyear",
+	"day": "day", "dd": "day", "d": "day",
+	"week": "week", "wk": "week", "ww": "week",
+	"weekday": "weekday", "dw": "weekday", "w": "weekday",
+	"hour": "hour", "hh": "hour",
+	"minute": "minute", "mi": "minute", "n": "minute",
+	"second": "second", "ss": "second", "s": "second",
+	"millisecond": "millisecond", "ms": "millisecond",
+	"microsecond": "microsecond", "mcs": "microsecond",
+	"nanosecond": "nanosecond", "ns": "nanosecond",
+	"tzoffset": "tzoffset", "tz": "tzoffset",
+	"iso_week": "iso_week", "isowk": "iso_week", "isoww": "iso_week",
+}
+
+// clockParts are the dateparts below a day, with their length.
+var clockParts = map[string]time.Duration{
+	"hour":        time.Hour,
+	"minute":      time.Minute,
+	"second":      time.Second,
+	"millisecond": time.Millisecond,
+	"microsecond": time.Microsecond,
+	"nanosecond":  time.Nanosecond,
+}
+
+const unixDayOf1900 = -25567 // 1900-01-01 in days since the Unix epoch
+
+func datepartArg(v Value, fn string) (string, error) {
+	name := strings.ToLower(v.Text())
+	part, ok := dateparts[name]
+	if !ok {
+		return "", sqlError(155, "'%s' is not a recognized %s option.", name, fn)
+	}
+	return part, nil
+}
+
+// checkPart rejects dateparts a type does not have: parts of the clock
+// for DATE and parts of the calendar for TIME.
+func checkPart(part string, k Kind, fn string) error {
+	_, clock := clockParts[part]
+	if (k == KindDate && clock) || (k == KindTime && !clock && part != "tzoffset") ||
+		(part == "tzoffset" && fn != "datepart" && fn != "datename") {
+		return sqlError(9810, "The datepart %s is not supported by date function %s for data type %s.",
+			part, fn, k.messageName())
+	}
+	return nil
+}
+
+// dateArg converts a date argument to a date or time type. Strings are
+// read as DATETIMEOFFSET if they carry an offset and as DATETIME2
+// otherwise; numbers count days from 1900-01-01 as DATETIME.
+func dateArg(v Value, n int, fn string) (Value, error) {
+	k := v.Type.Kind
+	switch {
+	case k.isTemporal():
+		return v, nil
+	case k == KindNull:
+		return Null(Type{Kind: KindDateTime2, Scale: 7}), nil
+	case k.isString():
+		if v.Null {
+			return Null(Type{Kind: KindDateTime2, Scale: 7}), nil
+		}
+		_, zoned, err := parseDateTime(v.Text(), KindDateTime2)
+		if err != nil {
+			return Value{}, err
+		}
+		if zoned {
+			return Convert(v, Type{Kind: KindDateTimeOffset, Scale: 7})
+		}
+		return Convert(v, Type{Kind: KindDateTime2, Scale: 7})
+	case k.isNumeric():
+		return Convert(v, typeDateTime)
+	}
+	return Value{}, invalidArgument(v, n, fn)
+}
+
+// unixDay returns the day number of t's calendar date, counted from
+// 1970-01-01.
+func unixDay(t time.Time) int64 {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+	return floorDiv(d, 86400)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// sinceMidnight returns the time of day of t.
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// partValue returns the value DATEPART gives for part, with weeks
+// starting on Sunday as they do under the default language.
+func partValue(part string, t time.Time) int64 {
+	switch part {
+	case "year":
+		return int64(t.Year())
+	case "quarter":
+		return int64(t.Month()-1)/3 + 1
+	case "month":
+		return int64(t.Month())
+	case "dayofyear":
+		return int64(t.YearDay())
+	case "day":
+		return int64(t.Day())
+	case "week":
+		jan1 := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return int64(t.YearDay()-1+int(jan1.Weekday()))/7 + 1
+	case "weekday":
+		return int64(t.Weekday()) + 1
+	case "iso_week":
+		_, week := t.ISOWeek()
+		return int64(week)
+	case "hour":
+		return int64(t.Hour())
+	case "minute":
+		return int64(t.Minute())
+	case "second":
+		return int64(t.Second())
+	case "millisecond":
+		return int64(t.Nanosecond() / 1e6)
+	case "microsecond":
+		return int64(t.Nanosecond() / 1e3)
+	case "nanosecond":
+		return int64(t.Nanosecond())
+	case "tzoffset":
+		_, offset := t.Zone()
+		return int64(offset / 60)
+	}
+	return 0
+}
+
+func datePart(args []Value) (Value, error) {
+	part, err := datepartArg(args[0], "datepart")
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := dateArg(args[1], 2, "datepart")
+	if err != nil {
+		return Value{}, err
+	}
+	if err := checkPart(part, v.Type.Kind, "datepart"); err != nil {
+		return Value{}, err
+	}
+	if v.Null {
+		return Null(typeInt), nil
+	}
+	return Int(partValue(part, v.Time())), nil
+}
+
+// datePartOf returns YEAR, MONTH or DAY.
+func datePartOf(part string) Func {
+	return func(args []Value) (Value, error) {
+		return datePart([]Value{VarChar(part), args[0]})
+	}
+}
+
+func dateName(args []Value) (Value, error) {
+	part, err := datepartArg(args[0], "datename")
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := dateArg(args[1], 2, "datename")
+	if err != nil {
+		return Value{}, err
+	}
+	if err := checkPart(part, v.Type.Kind, "datename"); err != nil {
+		return Value{}, err
+	}
+	if v.Null {
+		return Null(Type{Kind: KindNVarChar, Length: 30}), nil
+	}
+	t := v.Time()
+	switch part {
+	case "month":
+		return NVarChar(t.Month().String()), nil
+	case "weekday":
+		return NVarChar(t.Weekday().String()), nil
+	case "tzoffset":
+		return NVarChar(t.Format("-07:00")), nil
+	}
+	return NVarChar(strconv.FormatInt(partValue(part, t), 10)), nil
+}
+
+// addMonths adds n months to t, moving the day back to the end of a
+// shorter month. It reports false if the year leaves 1 to 9999.
+func addMonths(t time.Time, n int64) (time.Time, bool) {
+	total := int64(t.Year())*12 + int64(t.Month()-1) + n
+	year, month := floorDiv(total, 12), time.Month(total-floorDiv(total, 12)*12+1)
+	if year < 1 || year > 9999 {
+		return time.Time{}, false
+	}
+	day := min(t.Day(), daysIn(int(year), month))
+	return time.Date(int(year), month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()), true
+}
+
+// addPart adds n of part to t, reporting false if the result leaves the
+// years 1 to 9999.
+func addPart(t time.Time, part string, n int64) (time.Time, bool) {
+	switch part {
+	case "year":
+		return addMonths(t, n*12)
+	case "quarter":
+		return addMonths(t, n*3)
+	case "month":
+		return addMonths(t, n)
+	case "week":
+		n *= 7
+		fallthrough
+	case "day", "dayofyear", "weekday":
+		if n > 4e6 || n < -4e6 {
+			return time.Time{}, false
+		}
+		r := t.AddDate(0, 0, int(n))
+		return r, r.Year() >= 1 && r.Year() <= 9999
+	}
+	unit := clockParts[part]
+	perDay := int64(24 * time.Hour / unit)
+	days := n / perDay
+	if days > 4e6 || days < -4e6 {
+		return time.Time{}, false
+	}
+	r := t.AddDate(0, 0, int(days)).Add(time.Duration(n%perDay) * unit)
+	return r, r.Year() >= 1 && r.Year() <= 9999
+}
+
+func dateAdd(args []Value) (Value, error) {
+	part, err := datepartArg(args[0], "dateadd")
+	if err != nil {
+		return Value{}, err
+	}
+	v := args[2]
+	switch k := v.Type.Kind; {
+	case k.isString(), k == KindNull:
+		// A string date is read as DATETIME
+		if v, err = Convert(v, typeDateTime); err != nil {
+			return Value{}, err
+		}
+	case k.isNumeric():
+		if v, err = Convert(v, typeDateTime); err != nil {
+			return Value{}, err
+		}
+	case !k.isTemporal():
+		return Value{}, invalidArgument(v, 3, "dateadd")
+	}
+	k := v.Type.Kind
+	if err := checkPart(part, k, "dateadd"); err != nil {
+		return Value{}, err
+	}
+	if anyNull(args[1:]) {
+		return Null(v.Type), nil
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+
+	t, ok := addPart(v.Time(), part, n)
+	if k == KindTime {
+		t, ok = time.Date(1900, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), true
+	}
+	if !ok || !inDateRange(t, k) {
+		return Value{}, sqlError(517, "Adding a value to a '%s' column caused an overflow.", k.messageName())
+	}
+	return Value{Type: v.Type, data: roundTime(t, k, v.Type.Scale)}, nil
+}
+
+// dateDiff returns DATEDIFF (KindInt) or DATEDIFF_BIG (KindBigInt), which
+// count the part boundaries crossed between two dates. DATETIMEOFFSET
+// values are compared in UTC.
+func dateDiff(result Kind) Func {
+	name := "datediff"
+	if result == KindBigInt {
+		name = "datediff_big"
+	}
+	return func(args []Value) (Value, error) {
+		part, err := datepartArg(args[0], name)
+		if err != nil {
+			return Value{}, err
+		}
+		if part == "tzoffset" {
+			return Value{}, sqlError(9810, "The datepart %s is not supported by date function %s for data type %s.",
+				part, name, KindDateTimeOffset.messageName())
+		}
+		start, err := dateArg(args[1], 2, name)
+		if err != nil {
+			return Value{}, err
+		}
+		end, err := dateArg(args[2], 3, name)
+		if err != nil {
+			return Value{}, err
+		}
+		t := Type{Kind: result}
+		if start.Null || end.Null {
+			return Null(t), nil
+		}
+
+		d := boundaries(part, utcClock(start), utcClock(end))
+		lo, hi := intRange(result)
+		if !d.IsInt64() || d.Int64() < lo || d.Int64() > hi {
+			return Value{}, sqlError(535, "The %s function resulted in an overflow. The number of dateparts separating two date/time instances is too large. Try to use %s with a less precise datepart.", name, name)
+		}
+		return Value{Type: t, data: d.Int64()}, nil
+	}
+}
+
+func utcClock(v Value) time.Time {
+	t := v.Time()
+	if v.Type.Kind == KindDateTimeOffset {
+		t = t.UTC()
+	}
+	return clock(t)
+}
+
+// boundaries counts the part boundaries between a and b.
+func boundaries(part string, a, b time.Time) *big.Int {
+	months := func(t time.Time) int64 { return int64(t.Year())*12 + int64(t.Month()-1) }
+	switch part {
+	case "year":
+		return big.NewInt(int64(b.Year() - a.Year()))
+	case "quarter":
+		return big.NewInt(months(b)/3 - months(a)/3)
+	case "month":
+		return big.NewInt(months(b) - months(a))
+	case "day", "dayofyear", "weekday":
+		return big.NewInt(unixDay(b) - unixDay(a))
+	case "week":
+		// 1970-01-04 was a Sunday
+		return big.NewInt(floorDiv(unixDay(b)-3, 7) - floorDiv(unixDay(a)-3, 7))
+	case "iso_week":
+		return big.NewInt(floorDiv(unixDay(b)-4, 7) - floorDiv(unixDay(a)-4, 7))
+	}
+	unit := clockParts[part]
+	perDay := int64(24 * time.Hour / unit)
+	d := big.NewInt(unixDay(b) - unixDay(a))
+	d.Mul(d, big.NewInt(perDay))
+	return d.Add(d, big.NewInt(int64(sinceMidnight(b)/unit-sinceMidnight(a)/unit)))
+}
+
+// truncate returns the start of the part containing t.
+func truncate(part string, t time.Time) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+	switch part {
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	case "quarter":
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, loc)
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case "day", "dayofyear":
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case "week":
+		return time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, loc)
+	case "iso_week":
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	}
+	day := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return day.Add(sinceMidnight(t).Truncate(clockParts[part]))
+}
+
+func dateTrunc(args []Value) (Value, error) {
+	part, err := datepartArg(args[0], "datetrunc")
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := dateArg(args[1], 2, "datetrunc")
+	if err != nil {
+		return Value{}, err
+	}
+	k := v.Type.Kind
+	if err := checkPart(part, k, "datetrunc"); err != nil {
+		return Value{}, err
+	}
+	tooFine := part == "weekday" || part == "nanosecond" ||
+		(part == "millisecond" && (k == KindSmallDateTime || v.Type.Scale < 3 && k != KindDateTime)) ||
+		(part == "microsecond" && (k.isLegacyDateTime() || v.Type.Scale < 6))
+	if tooFine {
+		return Value{}, sqlError(9810, "The datepart %s is not supported by date function %s for data type %s.",
+			part, "datetrunc", k.messageName())
+	}
+	if v.Null {
+		return v, nil
+	}
+	return Value{Type: v.Type, data: truncate(part, v.Time())}, nil
+}
+
+func endOfMonth(args []Value) (Value, error) {
+	t := Type{Kind: KindDate}
+	v, err := dateArg(args[0], 1, "eomonth")
+	if err != nil {
+		return Value{}, err
+	}
+	if v.Type.Kind == KindTime {
+		return Value{}, invalidArgument(v, 1, "eomonth")
+	}
+	if anyNull(args) {
+		return Null(t), nil
+	}
+	var n int64
+	if len(args) == 2 {
+		if n, err = intArg(args[1]); err != nil {
+			return Value{}, err
+		}
+	}
+	first := v.Time()
+	first = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	month, ok := addMonths(first, n)
+	if !ok {
+		return Value{}, sqlError(517, "Adding a value to a '%s' column caused an overflow.", KindDate.messageName())
+	}
+	return Value{Type: t, data: month.AddDate(0, 1, -1)}, nil
+}
+
+// parts reads the integer arguments of a FROMPARTS function, reporting
+// whether any of them is NULL.
+func parts(args []Value) ([]int64, bool, error) {
+	out := make([]int64, len(args))
+	for i, a := range args {
+		if a.Null {
+			return nil, true, nil
+		}
+		n, err := intArg(a)
+		if err != nil {
+			return nil, false, err
+		}
+		out[i] = n
+	}
+	return out, false, nil
+}
+
+func invalidParts(k Kind) *Error {
+	return sqlError(289, "Cannot construct data type %s, some of the arguments have values which are not valid.", k.messageName())
+}
+
+// makeTime builds a time from its parts, reporting false if any part is
+// out of range.
+func makeTime(year, month, day, hour, minute, second, nanos int64, loc *time.Location) (time.Time, bool) {
+	if year < 1 || year > 9999 || month < 1 || month > 12 || day < 1 ||
+		day > int64(daysIn(int(year), time.Month(month))) ||
+		hour < 0 || hour > 23 || minute < 0 || minute > 59 || second < 0 || second > 59 ||
+		nanos < 0 || nanos >= 1e9 {
+		return time.Time{}, false
+	}
+	return time.Date(int(year), time.Month(month), int(day), int(hour), int(minute), int(second), int(nanos), loc), true
+}
+
+// fractionNanos converts fractions of a second at the given precision to
+// nanoseconds, reporting false if they do not fit the precision.
+func fractionNanos(fractions, precision int64) (int64, bool) {
+	if precision < 0 || precision > 7 || fractions < 0 || fractions >= int64(pow10(int(precision)).Int64()) {
+		return 0, false
+	}
+	return fractions * pow10(9-int(precision)).Int64(), true
+}
+
+func dateFromParts(args []Value) (Value, error) {
+	t := Type{Kind: KindDate}
+	p, null, err := parts(args)
+	if err != nil || null {
+		return Null(t), err
+	}
+	tm, ok := makeTime(p[0], p[1], p[2], 0, 0, 0, 0, time.UTC)
+	if !ok {
+		return Value{}, invalidParts(t.Kind)
+	}
+	return Value{Type: t, data: tm}, nil
+}
+
+func dateTimeFromParts(args []Value) (Value, error) {
+	p, null, err := parts(args)
+	if err != nil || null {
+		return Null(typeDateTime), err
+	}
+	tm, ok := makeTime(p[0], p[1], p[2], p[3], p[4], p[5], p[6]*1e6, time.UTC)
+	if !ok || p[6] > 999 || !inDateRange(tm, KindDateTime) {
+		return Value{}, invalidParts(KindDateTime)
+	}
+	return DateTime(tm), nil
+}
+
+func smallDateTimeFromParts(args []Value) (Value, error) {
+	t := Type{Kind: KindSmallDateTime}
+	p, null, err := parts(args)
+	if err != nil || null {
+		return Null(t), err
+	}
+	tm, ok := makeTime(p[0], p[1], p[2], p[3], p[4], 0, 0, time.UTC)
+	if !ok || !inDateRange(tm, t.Kind) {
+		return Value{}, invalidParts(t.Kind)
+	}
+	return Value{Type: t, data: tm}, nil
+}
+
+func dateTime2FromParts(args []Value) (Value, error) {
+	return fromPartsScaled(args, KindDateTime2)
+}
+
+func timeFromParts(args []Value) (Value, error) {
+	return fromPartsScaled(args, KindTime)
+}
+
+func dateTimeOffsetFromParts(args []Value) (Value, error) {
+	return fromPartsScaled(args, KindDateTimeOffset)
+}
+
+// fromPartsScaled builds a TIME, DATETIME2 or DATETIMEOFFSET whose last
+// argument is the fractional seconds precision.
+func fromPartsScaled(args []Value, k Kind) (Value, error) {
+	last := args[len(args)-1]
+	if last.Null {
+		return Value{}, sqlError(10760, "Scale argument is not valid. Valid expressions for data type %s scale argument are integer constants and integer constant expressions.", k.messageName())
+	}
+	precision, err := intArg(last)
+	if err != nil {
+		return Value{}, err
+	}
+	t := Type{Kind: k, Scale: int(precision)}
+	if precision < 0 || precision > 7 {
+		return Value{}, invalidParts(k)
+	}
+	p, null, err := parts(args[:len(args)-1])
+	if err != nil || null {
+		return Null(t), err
+	}
+	if k == KindTime {
+		p = append([]int64{1900, 1, 1}, p...)
+	}
+	nanos, ok := fractionNanos(p[6], precision)
+	loc := time.UTC
+	if k == KindDateTimeOffset {
+		hours, minutes := p[7], p[8]
+		if hours < -14 || hours > 14 || minutes < -59 || minutes > 59 || hours*minutes < 0 ||
+			(hours == 14 || hours == -14) && minutes != 0 {
+			return Value{}, invalidParts(k)
+		}
+		loc = time.FixedZone("", int(hours*3600+minutes*60))
+	}
+	tm, valid := makeTime(p[0], p[1], p[2], p[3], p[4], p[5], nanos, loc)
+	if !ok || !valid {
+		return Value{}, invalidParts(k)
+	}
+	return Value{Type: t, data: tm}, nil
+}
+
+func isDate(args []Value) (Value, error) {
+	v := args[0]
+	switch k := v.Type.Kind; {
+	case v.Null:
+		return Int(0), nil
+	case k == KindDateTime || k == KindSmallDateTime:
+		return Int(1), nil
+	case k.isTemporal():
+		return Value{}, invalidArgument(v, 1, "isdate")
+	}
+	s, err := stringArg(v)
+	if err != nil {
+		return Value{}, err
+	}
+	if _, err := Convert(s, typeDateTime); err != nil {
+		return Int(0), nil
+	}
+	return Int(1), nil
+}
+
+// timeZoneArg reads an offset given as '+hh:mm' or as minutes.
+func timeZoneArg(v Value, fn string) (*time.Location, error) {
+	invalid := sqlError(9812, "The timezone provided to builtin function %s is invalid.", fn)
+	var minutes int64
+	if v.Type.Kind.isString() {
+		s := strings.TrimSpace(v.Text())
+		if len(s) != 6 || (s[0] != '+' && s[0] != '-') || s[3] != ':' {
+			return nil, invalid
+		}
+		h, err1 := strconv.Atoi(s[1:3])
+		m, err2 := strconv.Atoi(s[4:6])
+		if err1 != nil || err2 != nil || m > 59 {
+			return nil, invalid
+		}
+		minutes = int64(h*60 + m)
+		if s[0] == '-' {
+			minutes = -minutes
+		}
+	} else {
+		var err error
+		if minutes, err = intArg(v); err != nil {
+			return nil, err
+		}
+	}
+	if minutes < -14*60 || minutes > 14*60 {
+		return nil, invalid
+	}
+	return time.FixedZone("", int(minutes*60)), nil
+}
+
+// offsetArg converts the first argument of SWITCHOFFSET or
+// TODATETIMEOFFSET to DATETIMEOFFSET.
+func offsetArg(args []Value, fn string) (Value, *time.Location, error) {
+	v, err := dateArg(args[0], 1, fn)
+	if err != nil {
+		return Value{}, nil, err
+	}
+	if v.Type.Kind == KindTime || v.Type.Kind == KindDate && fn == "switchoffset" {
+		return Value{}, nil, invalidArgument(v, 1, fn)
+	}
+	scale := v.Type.Scale
+	if !v.Type.Kind.isTemporal() || v.Type.Kind.isLegacyDateTime() {
+		scale = 3
+	}
+	if v.Type.Kind == KindSmallDateTime || v.Type.Kind == KindDate {
+		scale = 0
+	}
+	t := Type{Kind: KindDateTimeOffset, Scale: scale}
+	if anyNull(args) {
+		return Null(t), nil, nil
+	}
+	loc, err := timeZoneArg(args[1], fn)
+	if err != nil {
+		return Value{}, nil, err
+	}
+	v, err = Convert(v, t)
+	return v, loc, err
+}
+
+func switchOffset(args []Value) (Value, error) {
+	v, loc, err := offsetArg(args, "switchoffset")
+	if err != nil || v.Null {
+		return v, err
+	}
+	v.data = v.Time().In(loc)
+	return v, nil
+}
+
+func toDateTimeOffset(args []Value) (Value, error) {
+	v, loc, err := offsetArg(args, "todatetimeoffset")
+	if err != nil || v.Null {
+		return v, err
+	}
+	t := v.Time()
+	v.data = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	return v, nil
+}
+
+// currentTime returns the value of GETDATE, CURRENT_TIMESTAMP and the
+// other functions that read the clock, given the current time.
+func currentTime(name string, now time.Time) (Value, error) {
+	switch name {
+	case "GETDATE", "CURRENT_TIMESTAMP":
+		return DateTime(clock(now)), nil
+	case "GETUTCDATE":
+		return DateTime(clock(now.UTC())), nil
+	case "SYSDATETIME":
+		return DateTime2(clock(now)), nil
+	case "SYSUTCDATETIME":
+		return DateTime2(clock(now.UTC())), nil
+	case "SYSDATETIMEOFFSET":
+		return Value{Type: Type{Kind: KindDateTimeOffset, Scale: 7}, data: roundTime(now, KindDateTimeOffset, 7)}, nil
+	}
+	return Value{}, fmt.Errorf("eval: unknown function %s", name)
+}
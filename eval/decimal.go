@@ -0,0 +1,216 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Decimal is an exact numeric value held as an unscaled integer and a
+// scale, the way SQL Server stores DECIMAL, NUMERIC and MONEY. The zero
+// value is 0 with scale 0.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+var bigTen = big.NewInt(10)
+
+// MakeDecimal returns unscaled × 10^-scale.
+func MakeDecimal(unscaled int64, scale int) Decimal {
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// ParseDecimal parses an optionally signed number with an optional
+// fractional part. Exponents are not accepted.
+func ParseDecimal(s string) (Decimal, error) {
+	text := strings.TrimSpace(s)
+	neg := false
+	if text != "" && (text[0] == '+' || text[0] == '-') {
+		neg = text[0] == '-'
+		text = text[1:]
+	}
+	whole, frac, _ := strings.Cut(text, ".")
+	digits := whole + frac
+	if digits == "" {
+		return Decimal{}, fmt.Errorf("eval: invalid decimal %q", s)
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return Decimal{}, fmt.Errorf("eval: invalid decimal %q", s)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		n.Neg(n)
+	}
+	return Decimal{unscaled: n, scale: len(frac)}, nil
+}
+
+// DecimalFromFloat rounds f to scale digits after the decimal point.
+func DecimalFromFloat(f float64, scale int) Decimal {
+	r, _ := new(big.Float).SetPrec(200).SetFloat64(f).Rat(nil)
+	return decimalFromRat(r, scale)
+}
+
+func decimalFromRat(r *big.Rat, scale int) Decimal {
+	num := new(big.Int).Mul(r.Num(), pow10(scale))
+	q, m := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	// Round half away from zero
+	if new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{unscaled: q, scale: scale}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int { return d.scale }
+
+// Precision returns the number of significant digits needed to hold d at
+// its current scale.
+func (d Decimal) Precision() int {
+	digits := len(new(big.Int).Abs(d.int()).String())
+	if d.int().Sign() == 0 {
+		digits = 1
+	}
+	if d.scale > digits {
+		return d.scale
+	}
+	return digits
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int { return d.int().Sign() }
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Rescale returns d with the given scale, rounding half away from zero
+// when digits are dropped.
+func (d Decimal) Rescale(scale int) Decimal {
+	if scale >= d.scale {
+		return Decimal{unscaled: new(big.Int).Mul(d.int(), pow10(scale-d.scale)), scale: scale}
+	}
+	return decimalFromRat(d.rat(), scale)
+}
+
+// Truncate returns d with the given scale, discarding extra digits.
+func (d Decimal) Truncate(scale int) Decimal {
+	if scale >= d.scale {
+		return d.Rescale(scale)
+	}
+	q := new(big.Int).Quo(d.int(), pow10(d.scale-scale))
+	return Decimal{unscaled: q, scale: scale}
+}
+
+func (d Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10(d.scale))
+}
+
+func align(a, b Decimal) (*big.Int, *big.Int, int) {
+	if a.scale > b.scale {
+		return a.int(), b.Rescale(a.scale).int(), a.scale
+	}
+	return a.Rescale(b.scale).int(), b.int(), b.scale
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{unscaled: new(big.Int).Add(x, y), scale: scale}
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{unscaled: new(big.Int).Sub(x, y), scale: scale}
+}
+
+// Mul returns d × o at scale d.Scale()+o.Scale().
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+// Quo returns d ÷ o truncated to scale digits. o must not be zero.
+func (d Decimal) Quo(o Decimal, scale int) Decimal {
+	num := new(big.Int).Mul(d.int(), pow10(scale+o.scale))
+	den := new(big.Int).Mul(o.int(), pow10(d.scale))
+	return Decimal{unscaled: num.Quo(num, den), scale: scale}
+}
+
+// Mod returns the remainder of d ÷ o, with the sign of d. o must not be
+// zero.
+func (d Decimal) Mod(o Decimal) Decimal {
+	x, y, scale := align(d, o)
+	return Decimal{unscaled: new(big.Int).Rem(x, y), scale: scale}
+}
+
+// Cmp compares d and o numerically.
+func (d Decimal) Cmp(o Decimal) int {
+	x, y, _ := align(d, o)
+	return x.Cmp(y)
+}
+
+// Float64 returns the nearest float64 value.
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// Int64 returns d truncated toward zero, and false if that does not fit
+// in an int64.
+func (d Decimal) Int64() (int64, bool) {
+	q := d.Truncate(0).int()
+	if !q.IsInt64() {
+		return 0, false
+	}
+	return q.Int64(), true
+}
+
+// String formats d with exactly Scale() fractional digits.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.int().Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// fitsPrecision reports whether d has at most precision-scale integer
+// digits.
+func (d Decimal) fitsPrecision(precision int) bool {
+	limit := pow10(precision)
+	return new(big.Int).Abs(d.int()).Cmp(limit) < 0
+}
+
+func decimalFromInt(i int64) Decimal {
+	return MakeDecimal(i, 0)
+}
+
+func isFinite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
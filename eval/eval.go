@@ -0,0 +1,1378 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// Evaluator evaluates expressions against a set of variables with known
+// values.
+type Evaluator struct {
+	// Now, when set, gives the time GETDATE, SYSDATETIME and the other
+	// current date and time functions return. When it is nil those
+	// functions are not constant.
+	Now func() time.Time
+
+	// Resolve, when set, gives the value of expressions Eval cannot
+	// evaluate itself: column references, subqueries, aggregates and calls
+	// to unknown functions. A query engine sets it to evaluate expressions
+	// against the current row.
+	Resolve func(expr ast.Expression) (Value, error)
+
+	vars  map[string]Value
+	funcs map[string]Func
+}
+
+// New returns an Evaluator with no variables.
+func New() *Evaluator {
+	return &Evaluator{vars: make(map[string]Value), funcs: make(map[string]Func)}
+}
+
+// Set gives a variable a value. Names include the leading @ and are not
+// case-sensitive.
+func (e *Evaluator) Set(name string, v Value) {
+	e.vars[strings.ToLower(name)] = v
+}
+
+// Get returns the value of a variable, and false if it has none.
+func (e *Evaluator) Get(name string) (Value, bool) {
+	v, ok := e.vars[strings.ToLower(name)]
+	return v, ok
+}
+
+// Define adds a function that unqualified calls resolve to ahead of the
+// built-in functions, for functions such as ERROR_NUMBER whose value comes
+// from the host. Names are not case-sensitive.
+func (e *Evaluator) Define(name string, fn Func) {
+	e.funcs[strings.ToUpper(name)] = fn
+}
+
+// Eval evaluates an expression that uses only literals.
+func Eval(expr ast.Expression) (Value, error) {
+	return New().Eval(expr)
+}
+
+// Eval evaluates expr. Errors that SQL Server would raise at run time are
+// returned as *Error; expressions that cannot be evaluated without a
+// database wrap ErrNotConstant.
+func (e *Evaluator) Eval(expr ast.Expression) (Value, error) {
+	switch x := expr.(type) {
+	case *ast.IntegerLiteral:
+		if x.Value > math.MaxInt32 {
+			return Numeric(decimalFromInt(x.Value)), nil
+		}
+		v := Int(x.Value)
+		v.constant = true
+		return v, nil
+	case *ast.FloatLiteral:
+		return floatLiteral(x)
+	case *ast.MoneyLiteral:
+		d, err := ParseDecimal(strings.TrimLeft(x.Value, "$"))
+		if err != nil {
+			return Value{}, err
+		}
+		return Convert(Numeric(d), typeMoney)
+	case *ast.StringLiteral:
+		if x.Unicode {
+			return NVarChar(x.Value), nil
+		}
+		return VarChar(x.Value), nil
+	case *ast.NullLiteral:
+		return Value{Null: true}, nil
+	case *ast.BinaryLiteral:
+		return binaryLiteral(x.Value)
+	case *ast.Variable:
+		if v, ok := e.Get(x.Name); ok {
+			return v, nil
+		}
+	case *ast.Identifier:
+		// CURRENT_TIMESTAMP is written without parentheses
+		if strings.EqualFold(x.Value, "CURRENT_TIMESTAMP") && e.Now != nil {
+			return currentTime("CURRENT_TIMESTAMP", e.Now())
+		}
+	case *ast.PrefixExpression:
+		return e.prefix(x)
+	case *ast.InfixExpression:
+		return e.infix(x)
+	case *ast.CollateExpression:
+		v, err := e.Eval(x.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+		if !v.Type.Kind.isString() {
+			return Value{}, sqlError(447, "Expression type %s is invalid for COLLATE clause.", v.Type.Kind.messageName())
+		}
+		v.collation = x.Collation
+		return v, nil
+	case *ast.BetweenExpression:
+		return e.between(x)
+	case *ast.InExpression:
+		if x.Subquery == nil {
+			return e.in(x)
+		}
+	case *ast.LikeExpression:
+		return e.like(x)
+	case *ast.IsNullExpression:
+		v, err := e.Eval(x.Expr)
+		if err != nil {
+			return Value{}, err
+		}
+		return Bool(v.Null != x.Not), nil
+	case *ast.IsDistinctFromExpression:
+		return e.distinct(x)
+	case *ast.CaseExpression:
+		return e.caseExpr(x)
+	case *ast.CastExpression:
+		t, err := typeOf(x.TargetType, 30)
+		if err != nil {
+			return Value{}, err
+		}
+		return e.cast(x.Expression, t, x.IsTry)
+	case *ast.ConvertExpression:
+		return e.convert(x)
+	case *ast.ParseExpression:
+		return e.parse(x)
+	case *ast.TrimExpression:
+		v, err := e.Eval(x.Expression)
+		if err != nil {
+			return Value{}, err
+		}
+		spec := x.TrimSpec
+		if spec == "" {
+			spec = "BOTH"
+		}
+		if x.Characters == nil {
+			return trim(spec, v, nil)
+		}
+		chars, err := e.Eval(x.Characters)
+		if err != nil {
+			return Value{}, err
+		}
+		return trim(spec, v, &chars)
+	case *ast.FunctionCall:
+		if x.Over == nil {
+			return e.call(x)
+		}
+	case *ast.GreatestLeastExpression:
+		values, err := e.arguments(x.Arguments)
+		if err != nil {
+			return Value{}, err
+		}
+		if x.Least {
+			return extreme(-1)(values)
+		}
+		return extreme(1)(values)
+	case *ast.DateTruncExpression:
+		v, err := e.Eval(x.Date)
+		if err != nil {
+			return Value{}, err
+		}
+		return dateTrunc([]Value{VarChar(x.DatePart.Value), v})
+	}
+	return e.unresolved(expr)
+}
+
+// unresolved hands expr to Resolve, or reports that it is not constant.
+func (e *Evaluator) unresolved(expr ast.Expression) (Value, error) {
+	if e.Resolve != nil {
+		return e.Resolve(expr)
+	}
+	return Value{}, notConstant(expr)
+}
+
+func floatLiteral(x *ast.FloatLiteral) (Value, error) {
+	text := x.Token.Literal
+	if text == "" || strings.ContainsAny(text, "eE") {
+		return Float(x.Value), nil
+	}
+	d, err := ParseDecimal(text)
+	if err != nil {
+		return Value{}, err
+	}
+	if d.Precision() > 38 {
+		return Float(x.Value), nil
+	}
+	return Numeric(d), nil
+}
+
+func binaryLiteral(text string) (Value, error) {
+	digits := text[min(2, len(text)):]
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		var c byte
+		if _, err := fmt.Sscanf(digits[2*i:2*i+2], "%02x", &c); err != nil {
+			return Value{}, fmt.Errorf("eval: invalid binary literal %s", text)
+		}
+		b[i] = c
+	}
+	return VarBinary(b), nil
+}
+
+// condition evaluates expr where a predicate is required.
+func (e *Evaluator) condition(expr ast.Expression) (Value, error) {
+	v, err := e.Eval(expr)
+	if err != nil {
+		return Value{}, err
+	}
+	if v.Type.Kind != KindBoolean {
+		return Value{}, sqlError(4145, "An expression of non-boolean type specified in a context where a condition is expected, near '%s'.", expr)
+	}
+	return v, nil
+}
+
+func (e *Evaluator) prefix(x *ast.PrefixExpression) (Value, error) {
+	op := strings.ToUpper(x.Operator)
+	if op == "NOT" {
+		v, err := e.condition(x.Right)
+		if err != nil || v.Null {
+			return v, err
+		}
+		return Bool(!v.Bool()), nil
+	}
+
+	v, err := e.Eval(x.Right)
+	if err != nil {
+		return Value{}, err
+	}
+	k := v.Type.Kind
+	switch op {
+	case "+":
+		if k.isNumeric() || k.isString() || k == KindNull {
+			return v, nil
+		}
+	case "-":
+		if v.Null && (k.isNumeric() || k == KindNull) {
+			return v, nil
+		}
+		switch {
+		case k == KindBit:
+			return Value{}, sqlError(8117, "Operand data type bit is invalid for minus operator.")
+		case k.isInteger():
+			if lo, hi := intRange(k); -v.Int64() < lo || -v.Int64() > hi || v.Int64() == math.MinInt64 {
+				return Value{}, overflow(k)
+			}
+			return Value{Type: v.Type, data: -v.Int64()}, nil
+		case k == KindDecimal || k.isMoney():
+			return Value{Type: v.Type, data: v.Decimal().Neg()}, nil
+		case k.isFloat():
+			return Value{Type: v.Type, data: -v.Float64()}, nil
+		}
+	case "~":
+		if v.Null && (k.isInteger() || k == KindNull) {
+			return v, nil
+		}
+		if k.isInteger() {
+			i := ^v.Int64()
+			switch k {
+			case KindBit:
+				i &= 1
+			case KindTinyInt:
+				i &= 0xff
+			}
+			return Value{Type: v.Type, data: i}, nil
+		}
+	}
+	return Value{}, sqlError(8117, "Operand data type %s is invalid for %s operator.", k.messageName(), operatorName(op))
+}
+
+func operatorName(op string) string {
+	switch op {
+	case "+":
+		return "add"
+	case "-":
+		return "subtract"
+	case "*":
+		return "multiply"
+	case "/":
+		return "divide"
+	case "%":
+		return "modulo"
+	case "&":
+		return "boolean AND"
+	case "|":
+		return "boolean OR"
+	case "^":
+		return "boolean XOR"
+	case "~":
+		return "bitwise NOT"
+	case "=":
+		return "equal to"
+	case "<>", "!=":
+		return "not equal to"
+	case "<":
+		return "less than"
+	case ">":
+		return "greater than"
+	case "<=":
+		return "less than or equal to"
+	case ">=":
+		return "greater than or equal to"
+	case "!<":
+		return "not less than"
+	case "!>":
+		return "not greater than"
+	}
+	return op
+}
+
+func (e *Evaluator) infix(x *ast.InfixExpression) (Value, error) {
+	switch x.Operator {
+	case "AND", "OR":
+		return e.logical(x)
+	}
+	left, err := e.Eval(x.Left)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := e.Eval(x.Right)
+	if err != nil {
+		return Value{}, err
+	}
+	switch x.Operator {
+	case "=", "<>", "!=", "<", ">", "<=", ">=", "!<", "!>":
+		return comparison(x.Operator, left, right)
+	}
+	return Arithmetic(x.Operator, left, right)
+}
+
+// logical applies AND or OR with three-valued logic, skipping the right
+// operand when the left one decides the result.
+func (e *Evaluator) logical(x *ast.InfixExpression) (Value, error) {
+	left, err := e.condition(x.Left)
+	if err != nil {
+		return Value{}, err
+	}
+	decisive := x.Operator == "OR"
+	if !left.Null && left.Bool() == decisive {
+		return left, nil
+	}
+	right, err := e.condition(x.Right)
+	if err != nil {
+		return Value{}, err
+	}
+	switch {
+	case !right.Null && right.Bool() == decisive:
+		return right, nil
+	case left.Null || right.Null:
+		return Unknown(), nil
+	}
+	return Bool(!decisive), nil
+}
+
+func comparison(op string, left, right Value) (Value, error) {
+	if left.Null || right.Null {
+		if _, err := comparable(left, right); err != nil {
+			return Value{}, err
+		}
+		return Unknown(), nil
+	}
+	c, err := compare(left, right)
+	if err != nil {
+		return Value{}, err
+	}
+	switch op {
+	case "=":
+		return Bool(c == 0), nil
+	case "<>", "!=":
+		return Bool(c != 0), nil
+	case "<":
+		return Bool(c < 0), nil
+	case ">":
+		return Bool(c > 0), nil
+	case "<=", "!>":
+		return Bool(c <= 0), nil
+	}
+	return Bool(c >= 0), nil
+}
+
+// comparable returns the type two operands are compared as.
+func comparable(a, b Value) (Type, error) {
+	if a.Type.Kind == KindBoolean || b.Type.Kind == KindBoolean {
+		return Type{}, fmt.Errorf("eval: cannot compare the result of a predicate")
+	}
+	t := commonType(a.Type, b.Type)
+	for _, k := range []Kind{a.Type.Kind, b.Type.Kind} {
+		if k != KindNull && !explicitAllowed(k, t.Kind) {
+			return Type{}, clash(a.Type.Kind, b.Type.Kind)
+		}
+	}
+	return t, nil
+}
+
+// Compare orders two values, converting them to a common type by data type
+// precedence. NULL sorts before every other value, as in ORDER BY.
+func Compare(a, b Value) (int, error) {
+	switch {
+	case a.Null && b.Null:
+		return 0, nil
+	case a.Null:
+		return -1, nil
+	case b.Null:
+		return 1, nil
+	}
+	return compare(a, b)
+}
+
+func compare(a, b Value) (int, error) {
+	t, err := comparable(a, b)
+	if err != nil {
+		return 0, err
+	}
+	x, err := implicit(a, t)
+	if err != nil {
+		return 0, err
+	}
+	y, err := implicit(b, t)
+	if err != nil {
+		return 0, err
+	}
+
+	switch k := t.Kind; {
+	case k.isInteger():
+		return cmp(x.Int64(), y.Int64()), nil
+	case k == KindDecimal || k.isMoney():
+		return x.Decimal().Cmp(y.Decimal()), nil
+	case k.isFloat():
+		return cmp(x.Float64(), y.Float64()), nil
+	case k.isString():
+		return compareStrings(x.Text(), y.Text(), caseSensitive(a.collation, b.collation)), nil
+	case k.isBinary():
+		return strings.Compare(string(x.Bytes()), string(y.Bytes())), nil
+	case k == KindUniqueIdentifier:
+		return compareGUIDs(x.Text(), y.Text()), nil
+	case k.isTemporal():
+		return x.Time().Compare(y.Time()), nil
+	}
+	return 0, fmt.Errorf("eval: cannot compare %s values", t)
+}
+
+func cmp[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// caseSensitive reports whether either collation distinguishes case; the
+// default collation does not.
+func caseSensitive(collations ...string) bool {
+	for _, c := range collations {
+		c = strings.ToUpper(c)
+		if strings.Contains(c, "_CS") || strings.HasSuffix(c, "_BIN") || strings.HasSuffix(c, "_BIN2") {
+			return true
+		}
+	}
+	return false
+}
+
+// compareStrings compares with trailing spaces ignored, as SQL Server pads
+// the shorter operand before comparing.
+func compareStrings(a, b string, sensitive bool) int {
+	a = strings.TrimRight(a, " ")
+	b = strings.TrimRight(b, " ")
+	if !sensitive {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// compareGUIDs orders uniqueidentifiers the way SQL Server does: by the
+// last group first, then the fourth, third, second and first.
+func compareGUIDs(a, b string) int {
+	groups := func(s string) []string {
+		parts := strings.Split(s, "-")
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+		return parts
+	}
+	return strings.Compare(strings.Join(groups(a), ""), strings.Join(groups(b), ""))
+}
+
+// Arithmetic applies a binary arithmetic, bitwise or concatenation
+// operator.
+func Arithmetic(op string, left, right Value) (Value, error) {
+	a, b := left.Type.Kind, right.Type.Kind
+	if a == KindBoolean || b == KindBoolean {
+		return Value{}, fmt.Errorf("eval: operator %s applied to a predicate", op)
+	}
+
+	switch {
+	case op == "+" && (a.isString() || b.isString()) && (a.isString() || a == KindNull) && (b.isString() || b == KindNull):
+		return concat(left, right)
+	case op == "+" && a.isBinary() && b.isBinary():
+		return concatBinary(left, right)
+	case op == "||" && (a.isString() || a == KindNull) && (b.isString() || b == KindNull):
+		return concat(left, right)
+	case op == "||" && (a.isBinary() || a == KindNull) && (b.isBinary() || b == KindNull):
+		return concatBinary(left, right)
+	case op == "||":
+		return Value{}, incompatible(a, b, op)
+	case a.isLegacyDateTime() || b.isLegacyDateTime():
+		return dateArithmetic(op, left, right)
+	}
+
+	t := commonType(left.Type, right.Type)
+	k := t.Kind
+	switch {
+	case k == KindNull:
+		return Value{Null: true}, nil
+	case a.isTemporal() || b.isTemporal() || a == KindUniqueIdentifier || b == KindUniqueIdentifier:
+		if !a.isNumeric() && !b.isNumeric() {
+			return Value{}, sqlError(8117, "Operand data type %s is invalid for %s operator.", k.messageName(), operatorName(op))
+		}
+		return Value{}, clash(a, b)
+	case k.isString() || k.isBinary():
+		return Value{}, incompatible(a, b, operatorName(op))
+	case a == KindBit && b == KindBit && !isBitwise(op):
+		return Value{}, sqlError(8117, "Operand data type bit is invalid for %s operator.", operatorName(op))
+	}
+	if isBitwise(op) {
+		if !(a.isInteger() || a == KindNull) || !(b.isInteger() || b == KindNull) {
+			return Value{}, incompatible(a, b, operatorName(op))
+		}
+	}
+	if op == "%" && k.isFloat() {
+		return Value{}, incompatible(a, b, operatorName(op))
+	}
+
+	// Strings in arithmetic take the numeric operand's type
+	x, err := implicit(left, typeFor(left, t))
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := implicit(right, typeFor(right, t))
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch {
+	case k.isInteger():
+		return integerArithmetic(op, x, y, k)
+	case k.isMoney():
+		return moneyArithmetic(op, x, y, k)
+	case k == KindDecimal:
+		return decimalArithmetic(op, x, y)
+	}
+	return floatArithmetic(op, x, y, k)
+}
+
+func isBitwise(op string) bool {
+	return op == "&" || op == "|" || op == "^"
+}
+
+// typeFor is the type an arithmetic operand is converted to before the
+// operation: numbers keep their own type, strings take the result type.
+func typeFor(v Value, t Type) Type {
+	if v.Type.Kind.isNumeric() {
+		return v.Type
+	}
+	return t
+}
+
+func integerArithmetic(op string, x, y Value, k Kind) (Value, error) {
+	if x.Null || y.Null {
+		return Null(Type{Kind: k}), nil
+	}
+	a, b := big.NewInt(x.Int64()), big.NewInt(y.Int64())
+	r := new(big.Int)
+	switch op {
+	case "+":
+		r.Add(a, b)
+	case "-":
+		r.Sub(a, b)
+	case "*":
+		r.Mul(a, b)
+	case "/", "%":
+		if b.Sign() == 0 {
+			return Value{}, divideByZero()
+		}
+		if op == "/" {
+			r.Quo(a, b)
+		} else {
+			r.Rem(a, b)
+		}
+	case "&":
+		r.And(a, b)
+	case "|":
+		r.Or(a, b)
+	case "^":
+		r.Xor(a, b)
+	default:
+		return Value{}, fmt.Errorf("eval: unsupported operator %s", op)
+	}
+	lo, hi := intRange(k)
+	if !r.IsInt64() || r.Int64() < lo || r.Int64() > hi {
+		return Value{}, overflow(k)
+	}
+	return Value{Type: Type{Kind: k}, data: r.Int64()}, nil
+}
+
+func moneyArithmetic(op string, x, y Value, k Kind) (Value, error) {
+	t := Type{Kind: k, Precision: 19, Scale: 4}
+	if k == KindSmallMoney {
+		t.Precision = 10
+	}
+	if x.Null || y.Null {
+		return Null(t), nil
+	}
+	a, b := toExact(x), toExact(y)
+	var r Decimal
+	switch op {
+	case "+":
+		r = a.Add(b)
+	case "-":
+		r = a.Sub(b)
+	case "*":
+		r = a.Mul(b).Rescale(4)
+	case "/", "%":
+		if b.Sign() == 0 {
+			return Value{}, divideByZero()
+		}
+		if op == "/" {
+			r = a.Quo(b, 4)
+		} else {
+			r = a.Mod(b)
+		}
+	default:
+		return Value{}, incompatible(x.Type.Kind, y.Type.Kind, operatorName(op))
+	}
+	r = r.Rescale(4)
+	if !inMoneyRange(r, k) {
+		return Value{}, overflow(k)
+	}
+	return Value{Type: t, data: r}, nil
+}
+
+func toExact(v Value) Decimal {
+	if v.Type.Kind.isInteger() {
+		return decimalFromInt(v.Int64())
+	}
+	return v.Decimal()
+}
+
+// decimalArithmetic applies op with the result precision and scale rules
+// SQL Server uses for DECIMAL operands.
+func decimalArithmetic(op string, x, y Value) (Value, error) {
+	tx, ty := operandType(x), operandType(y)
+	p1, s1, p2, s2 := tx.Precision, tx.Scale, ty.Precision, ty.Scale
+	var t Type
+	switch op {
+	case "+", "-":
+		s := max(s1, s2)
+		t = decimalType(max(p1-s1, p2-s2)+s+1, s)
+	case "*":
+		t = decimalType(p1+p2+1, s1+s2)
+	case "/":
+		s := max(6, s1+p2+1)
+		t = decimalType(p1-s1+s2+s, s)
+	case "%":
+		s := max(s1, s2)
+		t = decimalType(min(p1-s1, p2-s2)+s, s)
+	default:
+		return Value{}, incompatible(x.Type.Kind, y.Type.Kind, operatorName(op))
+	}
+	if x.Null || y.Null {
+		return Null(t), nil
+	}
+
+	a, b := toExact(x), toExact(y)
+	var r Decimal
+	switch op {
+	case "+":
+		r = a.Add(b).Rescale(t.Scale)
+	case "-":
+		r = a.Sub(b).Rescale(t.Scale)
+	case "*":
+		r = a.Mul(b).Rescale(t.Scale)
+	case "/", "%":
+		if b.Sign() == 0 {
+			return Value{}, divideByZero()
+		}
+		if op == "/" {
+			r = a.Quo(b, t.Scale)
+		} else {
+			r = a.Mod(b).Rescale(t.Scale)
+		}
+	}
+	if !r.fitsPrecision(t.Precision) {
+		return Value{}, overflow(KindDecimal)
+	}
+	return Value{Type: t, data: r}, nil
+}
+
+// operandType is the DECIMAL type of an exact operand. Integer literals
+// count only the digits they have, so 1.0 / 3 has scale 6, not 12.
+func operandType(v Value) Type {
+	if v.constant && !v.Null {
+		return Type{Kind: KindDecimal, Precision: decimalFromInt(v.Int64()).Precision()}
+	}
+	return exactType(v.Type)
+}
+
+func floatArithmetic(op string, x, y Value, k Kind) (Value, error) {
+	if x.Null || y.Null {
+		return Null(Type{Kind: k}), nil
+	}
+	a, err := Convert(x, typeFloat)
+	if err != nil {
+		return Value{}, err
+	}
+	b, err := Convert(y, typeFloat)
+	if err != nil {
+		return Value{}, err
+	}
+	var r float64
+	switch op {
+	case "+":
+		r = a.Float64() + b.Float64()
+	case "-":
+		r = a.Float64() - b.Float64()
+	case "*":
+		r = a.Float64() * b.Float64()
+	case "/":
+		if b.Float64() == 0 {
+			return Value{}, divideByZero()
+		}
+		r = a.Float64() / b.Float64()
+	default:
+		return Value{}, incompatible(x.Type.Kind, y.Type.Kind, operatorName(op))
+	}
+	return makeFloat(r, k)
+}
+
+// concat joins two strings. The result is Unicode if either operand is,
+// and values longer than 8000 bytes are truncated unless an operand is
+// (MAX).
+func concat(left, right Value) (Value, error) {
+	t := commonType(left.Type, right.Type)
+	switch t.Kind {
+	case KindChar:
+		t.Kind = KindVarChar
+	case KindNChar:
+		t.Kind = KindNVarChar
+	}
+	if left.Type.Length == MaxLength || right.Type.Length == MaxLength {
+		t.Length = MaxLength
+	} else {
+		t.Length = min(stringLengthOf(left)+stringLengthOf(right), t.maxLength())
+	}
+	if left.Null || right.Null {
+		return Null(t), nil
+	}
+	s := []rune(left.Text() + right.Text())
+	if t.Length != MaxLength && len(s) > t.Length {
+		s = s[:t.Length]
+	}
+	v := Value{Type: t, data: string(s), collation: left.collation}
+	if v.collation == "" {
+		v.collation = right.collation
+	}
+	return v, nil
+}
+
+func stringLengthOf(v Value) int {
+	if v.Type.Kind == KindNull {
+		return 0
+	}
+	return v.Type.Length
+}
+
+func concatBinary(left, right Value) (Value, error) {
+	t := Type{Kind: KindVarBinary, Length: MaxLength}
+	if left.Type.Length != MaxLength && right.Type.Length != MaxLength {
+		t.Length = min(left.Type.Length+right.Type.Length, 8000)
+	}
+	if left.Null || right.Null {
+		return Null(t), nil
+	}
+	b := append(append([]byte(nil), left.Bytes()...), right.Bytes()...)
+	if t.Length != MaxLength && len(b) > t.Length {
+		b = b[:t.Length]
+	}
+	return Value{Type: t, data: b}, nil
+}
+
+// dateArithmetic adds or subtracts DATETIME values and numbers of days.
+// Both operands are converted to the DATETIME type, measured in days from
+// 1900-01-01.
+func dateArithmetic(op string, left, right Value) (Value, error) {
+	t := commonType(left.Type, right.Type)
+	if op != "+" && op != "-" {
+		return Value{}, sqlError(8117, "Operand data type %s is invalid for %s operator.", t.Kind.messageName(), operatorName(op))
+	}
+	if !t.Kind.isLegacyDateTime() {
+		return Value{}, clash(left.Type.Kind, right.Type.Kind)
+	}
+	x, err := implicit(left, t)
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := implicit(right, t)
+	if err != nil {
+		return Value{}, err
+	}
+	if x.Null || y.Null {
+		return Null(t), nil
+	}
+	offset := y.Time().Sub(base)
+	if op == "-" {
+		offset = -offset
+	}
+	r := x.Time().Add(offset)
+	if !inDateRange(r, t.Kind) {
+		return Value{}, overflow(t.Kind)
+	}
+	return Value{Type: t, data: roundTime(r, t.Kind, 0)}, nil
+}
+
+func (e *Evaluator) between(x *ast.BetweenExpression) (Value, error) {
+	v, err := e.Eval(x.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	low, err := e.Eval(x.Low)
+	if err != nil {
+		return Value{}, err
+	}
+	high, err := e.Eval(x.High)
+	if err != nil {
+		return Value{}, err
+	}
+	lower, err := comparison(">=", v, low)
+	if err != nil {
+		return Value{}, err
+	}
+	upper, err := comparison("<=", v, high)
+	if err != nil {
+		return Value{}, err
+	}
+	r := and(lower, upper)
+	if x.Not {
+		return not(r), nil
+	}
+	return r, nil
+}
+
+func and(a, b Value) Value {
+	switch {
+	case !a.Null && !a.Bool(), !b.Null && !b.Bool():
+		return Bool(false)
+	case a.Null || b.Null:
+		return Unknown()
+	}
+	return Bool(true)
+}
+
+func not(v Value) Value {
+	if v.Null {
+		return v
+	}
+	return Bool(!v.Bool())
+}
+
+// in is true when any value matches, otherwise UNKNOWN when any value is
+// NULL.
+func (e *Evaluator) in(x *ast.InExpression) (Value, error) {
+	v, err := e.Eval(x.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	r := Bool(false)
+	for _, item := range x.Values {
+		candidate, err := e.Eval(item)
+		if err != nil {
+			return Value{}, err
+		}
+		eq, err := comparison("=", v, candidate)
+		if err != nil {
+			return Value{}, err
+		}
+		if eq.Bool() {
+			r = eq
+			break
+		}
+		if eq.Null {
+			r = eq
+		}
+	}
+	if x.Not {
+		return not(r), nil
+	}
+	return r, nil
+}
+
+func (e *Evaluator) distinct(x *ast.IsDistinctFromExpression) (Value, error) {
+	left, err := e.Eval(x.Left)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := e.Eval(x.Right)
+	if err != nil {
+		return Value{}, err
+	}
+	var same bool
+	switch {
+	case left.Null || right.Null:
+		if _, err := comparable(left, right); err != nil {
+			return Value{}, err
+		}
+		same = left.Null && right.Null
+	default:
+		c, err := compare(left, right)
+		if err != nil {
+			return Value{}, err
+		}
+		same = c == 0
+	}
+	return Bool(same == x.Not), nil
+}
+
+func (e *Evaluator) like(x *ast.LikeExpression) (Value, error) {
+	text, err := e.likeOperand(x.Expr)
+	if err != nil {
+		return Value{}, err
+	}
+	pattern, err := e.likeOperand(x.Pattern)
+	if err != nil {
+		return Value{}, err
+	}
+	var escape rune
+	if x.Escape != nil {
+		esc, err := e.likeOperand(x.Escape)
+		if err != nil {
+			return Value{}, err
+		}
+		if esc.Null {
+			return Unknown(), nil
+		}
+		runes := []rune(esc.Text())
+		if len(runes) != 1 {
+			return Value{}, sqlError(506, "The invalid escape character \"%s\" was specified in a LIKE predicate.", esc.Text())
+		}
+		escape = runes[0]
+	}
+	if text.Null || pattern.Null {
+		return Unknown(), nil
+	}
+	matched := Like(text.Text(), pattern.Text(), escape, caseSensitive(text.collation, pattern.collation))
+	return Bool(matched != x.Not), nil
+}
+
+func (e *Evaluator) likeOperand(expr ast.Expression) (Value, error) {
+	v, err := e.Eval(expr)
+	if err != nil || v.Type.Kind.isString() {
+		return v, err
+	}
+	return implicit(v, Type{Kind: KindNVarChar, Length: MaxLength})
+}
+
+// Like matches s against a LIKE pattern with the %, _, [set] and [^set]
+// wildcards. escape, when not zero, makes the following character literal.
+func Like(s, pattern string, escape rune, caseSensitive bool) bool {
+	if !caseSensitive {
+		s, pattern = strings.ToLower(s), strings.ToLower(pattern)
+	}
+	return likeMatch([]rune(s), []rune(pattern), escape)
+}
+
+func likeMatch(s, p []rune, escape rune) bool {
+	for len(p) > 0 {
+		switch {
+		case p[0] == '%':
+			for len(p) > 0 && p[0] == '%' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if likeMatch(s[i:], p, escape) {
+					return true
+				}
+			}
+			return false
+		case len(s) == 0:
+			return false
+		case escape != 0 && p[0] == escape && len(p) > 1:
+			if s[0] != p[1] {
+				return false
+			}
+			p = p[2:]
+		case p[0] == '_':
+			p = p[1:]
+		case p[0] == '[':
+			end := setEnd(p)
+			if end < 0 {
+				if s[0] != '[' {
+					return false
+				}
+				p = p[1:]
+				break
+			}
+			if !inSet(s[0], p[1:end]) {
+				return false
+			}
+			p = p[end+1:]
+		default:
+			if s[0] != p[0] {
+				return false
+			}
+			p = p[1:]
+		}
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+// setEnd returns the index of the ] closing the set at p[0], or -1.
+func setEnd(p []rune) int {
+	for i := 2; i < len(p); i++ {
+		if p[i] == ']' {
+			return i
+		}
+	}
+	return -1
+}
+
+func inSet(c rune, set []rune) bool {
+	negate := len(set) > 0 && set[0] == '^'
+	if negate {
+		set = set[1:]
+	}
+	found := false
+	for i := 0; i < len(set); i++ {
+		if i+2 < len(set) && set[i+1] == '-' {
+			if c >= set[i] && c <= set[i+2] {
+				found = true
+			}
+			i += 2
+			continue
+		}
+		if c == set[i] {
+			found = true
+		}
+	}
+	return found != negate
+}
+
+// caseExpr evaluates the first matching branch. The result has the
+// highest-precedence type of all branches, so the chosen value is
+// converted to it.
+func (e *Evaluator) caseExpr(x *ast.CaseExpression) (Value, error) {
+	var operand Value
+	if x.Operand != nil {
+		var err error
+		if operand, err = e.Eval(x.Operand); err != nil {
+			return Value{}, err
+		}
+	}
+	results := make([]ast.Expression, 0, len(x.WhenClauses)+1)
+	for _, w := range x.WhenClauses {
+		results = append(results, w.Result)
+	}
+	if x.ElseClause != nil {
+		results = append(results, x.ElseClause)
+	}
+
+	chosen := x.ElseClause
+	for _, w := range x.WhenClauses {
+		var match Value
+		var err error
+		if x.Operand != nil {
+			var candidate Value
+			if candidate, err = e.Eval(w.Condition); err != nil {
+				return Value{}, err
+			}
+			match, err = comparison("=", operand, candidate)
+		} else {
+			match, err = e.condition(w.Condition)
+		}
+		if err != nil {
+			return Value{}, err
+		}
+		if match.Bool() {
+			chosen = w.Result
+			break
+		}
+	}
+	return e.choose(chosen, results)
+}
+
+// choose evaluates chosen (nil meaning NULL) and converts it to the result
+// type of the alternatives. Branches that are not taken are evaluated only
+// to find their types; their errors are ignored.
+func (e *Evaluator) choose(chosen ast.Expression, alternatives []ast.Expression) (Value, error) {
+	v := Value{Null: true}
+	if chosen != nil {
+		var err error
+		if v, err = e.Eval(chosen); err != nil {
+			return Value{}, err
+		}
+	}
+	t := v.Type
+	for _, alt := range alternatives {
+		if alt == chosen {
+			continue
+		}
+		if other, err := e.Eval(alt); err == nil {
+			t = commonType(t, other.Type)
+		}
+	}
+	if t.Kind == KindNull || t == v.Type {
+		return v, nil
+	}
+	return implicit(v, t)
+}
+
+func (e *Evaluator) cast(expr ast.Expression, t Type, try bool) (Value, error) {
+	v, err := e.Eval(expr)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := Convert(v, t)
+	if err != nil && try && conversionError(err) {
+		return Null(t), nil
+	}
+	return r, err
+}
+
+// conversionError reports whether err is a failed conversion of a value,
+// which TRY_CAST and TRY_CONVERT turn into NULL. Disallowed conversions
+// still raise an error.
+func conversionError(err error) bool {
+	var sqlErr *Error
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.Number {
+	case 220, 235, 241, 242, 245, 248, 8114, 8115, 8169, 8170:
+		return true
+	}
+	return false
+}
+
+func (e *Evaluator) convert(x *ast.ConvertExpression) (Value, error) {
+	t, err := typeOf(x.TargetType, 30)
+	if err != nil {
+		return Value{}, err
+	}
+	style := 0
+	if x.Style != nil {
+		sv, err := e.Eval(x.Style)
+		if err != nil {
+			return Value{}, err
+		}
+		if !sv.Null {
+			n, err := intArg(sv)
+			if err != nil {
+				return Value{}, err
+			}
+			style = int(n)
+		}
+	}
+	v, err := e.Eval(x.Expression)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := ConvertStyle(v, t, style)
+	if err != nil && x.IsTry && conversionError(err) {
+		return Null(t), nil
+	}
+	return r, err
+}
+
+// parse evaluates PARSE and TRY_PARSE, which read a string using the
+// conventions of a culture.
+func (e *Evaluator) parse(x *ast.ParseExpression) (Value, error) {
+	t, err := typeOf(x.TargetType, 30)
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := e.Eval(x.Expression)
+	if err != nil {
+		return Value{}, err
+	}
+	args := []Value{v}
+	if x.Culture != nil {
+		c, err := e.Eval(x.Culture)
+		if err != nil {
+			return Value{}, err
+		}
+		args = append(args, c)
+	}
+	c, err := cultureArg(args, 1)
+	if err != nil {
+		return Value{}, err
+	}
+	if !v.Type.Kind.isString() && v.Type.Kind != KindNull {
+		return Value{}, invalidArgument(v, 1, "parse")
+	}
+	if v.Null {
+		return Null(t), nil
+	}
+	r, err := parseCulture(v.Text(), t, c)
+	var sqlErr *Error
+	if err != nil && x.IsTry && errors.As(err, &sqlErr) && sqlErr.Number == 9819 {
+		return Null(t), nil
+	}
+	return r, err
+}
+
+// call evaluates a function call. Functions whose arguments are evaluated
+// lazily and those that read the clock are handled here; the rest are
+// looked up in builtins.
+func (e *Evaluator) call(x *ast.FunctionCall) (Value, error) {
+	name := functionName(x)
+	args := x.Arguments
+	switch name {
+	case "CHOOSE":
+		if len(args) < 2 {
+			return Value{}, sqlError(189, "The choose function requires 2 to 254 arguments.")
+		}
+		index, err := e.Eval(args[0])
+		if err != nil {
+			return Value{}, err
+		}
+		var chosen ast.Expression
+		if !index.Null {
+			i, err := intArg(index)
+			if err != nil {
+				return Value{}, err
+			}
+			if i >= 1 && i < int64(len(args)) {
+				chosen = args[i]
+			}
+		}
+		return e.choose(chosen, args[1:])
+	case "GETDATE", "GETUTCDATE", "SYSDATETIME", "SYSUTCDATETIME", "SYSDATETIMEOFFSET", "CURRENT_TIMESTAMP":
+		if e.Now == nil {
+			return Value{}, notConstant(x)
+		}
+		if len(args) != 0 {
+			return Value{}, argumentCount(name, 0)
+		}
+		return currentTime(name, e.Now())
+	case "IIF":
+		if len(args) != 3 {
+			return Value{}, argumentCount(name, 3)
+		}
+		cond, err := e.condition(args[0])
+		if err != nil {
+			return Value{}, err
+		}
+		chosen := args[2]
+		if cond.Bool() {
+			chosen = args[1]
+		}
+		return e.choose(chosen, args[1:])
+	case "COALESCE":
+		if len(args) < 2 {
+			return Value{}, sqlError(189, "The coalesce function requires 2 to 2147483647 arguments.")
+		}
+		var chosen ast.Expression
+		for _, arg := range args {
+			v, err := e.Eval(arg)
+			if err != nil {
+				return Value{}, err
+			}
+			if !v.Null {
+				chosen = arg
+				break
+			}
+		}
+		return e.choose(chosen, args)
+	case "NULLIF":
+		if len(args) != 2 {
+			return Value{}, argumentCount(name, 2)
+		}
+		first, err := e.Eval(args[0])
+		if err != nil {
+			return Value{}, err
+		}
+		second, err := e.Eval(args[1])
+		if err != nil {
+			return Value{}, err
+		}
+		eq, err := comparison("=", first, second)
+		if err != nil {
+			return Value{}, err
+		}
+		if eq.Bool() {
+			return Null(first.Type), nil
+		}
+		return first, nil
+	}
+
+	_, plain := x.Function.(*ast.Identifier)
+	if fn, ok := e.funcs[name]; ok && plain {
+		values, err := e.arguments(args)
+		if err != nil {
+			return Value{}, err
+		}
+		return fn(values)
+	}
+	b, ok := builtins[name]
+	if !ok || !plain || x.WithinGroup != nil || x.Distinct {
+		return e.unresolved(x)
+	}
+	if err := b.check(name, len(args)); err != nil {
+		return Value{}, err
+	}
+	if !b.datepart {
+		values, err := e.arguments(args)
+		if err != nil {
+			return Value{}, err
+		}
+		return b.fn(values)
+	}
+	id, ok := args[0].(*ast.Identifier)
+	if !ok {
+		return Value{}, fmt.Errorf("eval: invalid datepart %s in %s", args[0], strings.ToLower(name))
+	}
+	values, err := e.arguments(args[1:])
+	if err != nil {
+		return Value{}, err
+	}
+	return b.fn(append([]Value{VarChar(id.Value)}, values...))
+}
+
+func (e *Evaluator) arguments(args []ast.Expression) ([]Value, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		v, err := e.Eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func functionName(x *ast.FunctionCall) string {
+	switch f := x.Function.(type) {
+	case *ast.Identifier:
+		return strings.ToUpper(f.Value)
+	case *ast.QualifiedIdentifier:
+		if len(f.Parts) > 0 {
+			return strings.ToUpper(f.Parts[len(f.Parts)-1].Value)
+		}
+	}
+	return ""
+}
+
+func argumentCount(name string, n int) *Error {
+	return sqlError(174, "The %s function requires %d argument(s).", strings.ToLower(name), n)
+}
@@ -0,0 +1,342 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func parseExpr(t *testing.T, input string) ast.Expression {
+	t.Helper()
+	l := lexer.New("SELECT (" + input + ")")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program.Statements[0].(*ast.SelectStatement).Columns[0].Expression
+}
+
+func TestEvalValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		typ      string
+	}{
+		// Literals
+		{"42", "42", "INT"},
+		{"3000000000", "3000000000", "DECIMAL(10, 0)"},
+		{"1.50", "1.50", "DECIMAL(3, 2)"},
+		{"1e3", "1000", "FLOAT"},
+		{"$12.5", "12.5000", "MONEY"},
+		{"N'abc'", "abc", "NVARCHAR(3)"},
+		{"0x0102", "0x0102", "VARBINARY(2)"},
+
+		// Integer arithmetic truncates
+		{"7 / 2", "3", "INT"},
+		{"-7 / 2", "-3", "INT"},
+		{"-7 % 3", "-1", "INT"},
+		{"7 / 2.0", "3.500000", "DECIMAL(8, 6)"},
+		{"1.0 / 3", "0.333333", "DECIMAL(7, 6)"},
+		{"CAST(1 AS INT) / 2.0", "0.500000", "DECIMAL(17, 6)"},
+		{"2.50 * 1.5", "3.750", "DECIMAL(6, 3)"},
+		{"$10 / 3", "3.3333", "MONEY"},
+		{"1e0 / 4", "0.25", "FLOAT"},
+		{"6 & 3", "2", "INT"},
+		{"6 | 3", "7", "INT"},
+		{"6 ^ 3", "5", "INT"},
+		{"~0", "-1", "INT"},
+
+		// Implicit conversion by data type precedence
+		{"'5' + 1", "6", "INT"},
+		{"'1.5' + 1.25", "2.75", "DECIMAL(4, 2)"},
+		{"'a' + N'b'", "ab", "NVARCHAR(2)"},
+		{"CAST('2024-01-31' AS DATETIME) + 1", "2024-02-01 00:00:00.000", "DATETIME"},
+		{"NULL + 1", "NULL", "INT"},
+		{"'a' + NULL", "NULL", "VARCHAR(1)"},
+		{"'a' || N'b' || 'c'", "abc", "NVARCHAR(3)"},
+		{"'a' || NULL", "NULL", "VARCHAR(1)"},
+
+		// Comparisons and three-valued logic
+		{"IIF('abc' = 'ABC  ', 1, 0)", "1", "INT"},
+		{"IIF('abc' COLLATE Latin1_General_CS_AS = 'ABC', 1, 0)", "0", "INT"},
+		{"IIF(10 > '9', 1, 0)", "1", "INT"},
+		{"IIF(NULL = NULL, 1, 0)", "0", "INT"},
+		{"IIF(NOT (NULL = 1), 1, 0)", "0", "INT"},
+		{"IIF(NULL = 1 OR 1 = 1, 1, 0)", "1", "INT"},
+		{"IIF(1 = 0 AND 1 / 0 = 1, 1, 0)", "0", "INT"},
+		{"IIF(2 IN (1, NULL), 1, 0)", "0", "INT"},
+		{"IIF(2 NOT IN (1, NULL), 1, 0)", "0", "INT"},
+		{"IIF(2 NOT IN (1, 3), 1, 0)", "1", "INT"},
+		{"IIF(5 BETWEEN 1 AND 10, 1, 0)", "1", "INT"},
+		{"IIF(NULL IS NOT DISTINCT FROM NULL, 1, 0)", "1", "INT"},
+		{"IIF('Smith' LIKE 's[a-m]%', 1, 0)", "1", "INT"},
+		{"IIF('50%' LIKE '50!%' ESCAPE '!', 1, 0)", "1", "INT"},
+		{"IIF('abc' LIKE 'a_', 1, 0)", "0", "INT"},
+
+		// CASE and friends take the highest-precedence result type
+		{"CASE WHEN 1 = 1 THEN 1 ELSE 2.5 END", "1.0", "DECIMAL(11, 1)"},
+		{"CASE 2 WHEN 1 THEN 'one' WHEN 2 THEN 'two' END", "two", "VARCHAR(3)"},
+		{"CASE WHEN 1 = 0 THEN 1 END", "NULL", "INT"},
+		{"COALESCE(NULL, 'x', 'longer')", "x", "VARCHAR(6)"},
+		{"COALESCE(NULL, 2, 1 / 0)", "2", "INT"},
+		{"NULLIF(1, 1)", "NULL", "INT"},
+		{"NULLIF(1, 2)", "1", "INT"},
+
+		// CAST and CONVERT
+		{"CAST('ab' AS CHAR(5)) + '|'", "ab   |", "VARCHAR(6)"},
+		{"CAST('abcdef' AS VARCHAR(3))", "abc", "VARCHAR(3)"},
+		{"CAST(123456 AS VARCHAR(3))", "*", "VARCHAR(3)"},
+		{"CAST(2.5 AS INT)", "2", "INT"},
+		{"CAST($2.5 AS INT)", "3", "INT"},
+		{"CAST(1.255 AS DECIMAL(4, 2))", "1.26", "DECIMAL(4, 2)"},
+		{"CAST(-1.255 AS DECIMAL(4, 2))", "-1.26", "DECIMAL(4, 2)"},
+		{"CAST(12.345 AS MONEY)", "12.3450", "MONEY"},
+		{"CAST($1234.567 AS VARCHAR)", "1234.57", "VARCHAR(30)"},
+		{"CAST(1234567e0 AS VARCHAR)", "1.23457e+006", "VARCHAR(30)"},
+		{"CAST('2024-03-05 14:07:09.0019' AS DATETIME2(3))", "2024-03-05 14:07:09.002", "DATETIME2(3)"},
+		{"CAST('2024-03-05 14:07:09.001' AS DATETIME)", "2024-03-05 14:07:09.000", "DATETIME"},
+		{"CAST('2024-03-05 14:07:09.002' AS DATETIME)", "2024-03-05 14:07:09.003", "DATETIME"},
+		{"CAST(CAST('2024-03-05 14:07' AS DATETIME) AS VARCHAR(20))", "Mar  5 2024  2:07PM", "VARCHAR(20)"},
+		{"CAST('20240305' AS DATE)", "2024-03-05", "DATE"},
+		{"CAST('2024-03-05T10:00:00+02:00' AS DATETIMEOFFSET(0))", "2024-03-05 10:00:00 +02:00", "DATETIMEOFFSET(0)"},
+		{"CAST('true' AS BIT)", "1", "BIT"},
+		{"CAST(42 AS BIT)", "1", "BIT"},
+		{"CAST(256 AS BINARY(2))", "0x0100", "BINARY(2)"},
+		{"CAST(0x0100 AS INT)", "256", "INT"},
+		{"CAST('A' AS VARBINARY(4))", "0x41", "VARBINARY(4)"},
+		{"CAST(N'A' AS VARBINARY(4))", "0x4100", "VARBINARY(4)"},
+		{"CAST('6f9619ff-8b86-d011-b42d-00c04fc964ff' AS UNIQUEIDENTIFIER)", "6F9619FF-8B86-D011-B42D-00C04FC964FF", "UNIQUEIDENTIFIER"},
+		{"TRY_CAST('abc' AS INT)", "NULL", "INT"},
+		{"TRY_CONVERT(DATE, '2024-02-30')", "NULL", "DATE"},
+		{"CONVERT(INT, '  12 ')", "12", "INT"},
+		{"CONVERT(DECIMAL(5, 1), 2) / 3", "0.666666", "DECIMAL(10, 6)"},
+	}
+
+	for _, tt := range tests {
+		v, err := Eval(parseExpr(t, tt.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if v.String() != tt.expected || v.Type.String() != tt.typ {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.input, tt.expected, tt.typ, v, v.Type)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		number int
+	}{
+		{"2147483647 + 1", 8115},
+		{"-(-2147483647 - 1)", 8115},
+		{"CAST(200 AS TINYINT) + CAST(100 AS TINYINT)", 8115},
+		{"1 / 0", 8134},
+		{"1.5 % 0", 8134},
+		{"'abc' + 1", 245},
+		{"CAST('1.5' AS INT)", 245},
+		{"CAST('x' AS DECIMAL(5, 2))", 8114},
+		{"CAST(1000 AS DECIMAL(4, 1))", 8115},
+		{"CAST(300 AS TINYINT)", 220},
+		{"CAST('2024-13-01' AS DATE)", 241},
+		{"CAST('2024-01-01 10:00:00.1234' AS DATETIME)", 241},
+		{"CAST(1 AS DATE)", 529},
+		{"TRY_CAST(1 AS DATE)", 529},
+		{"CAST(123456 AS NVARCHAR(3))", 8115},
+		{"'a' - 'b'", 402},
+		{"1 || 2", 402},
+		{"1e0 % 2", 402},
+		{"CAST('2024-01-01' AS DATE) + 1", 206},
+		{"CAST(1 AS BIT) + CAST(1 AS BIT)", 8117},
+		{"CASE WHEN 1 = 1 THEN 'a' ELSE 1 END", 245},
+		{"IIF(1, 2, 3)", 4145},
+	}
+
+	for _, tt := range tests {
+		_, err := Eval(parseExpr(t, tt.input))
+		var sqlErr *Error
+		if !errors.As(err, &sqlErr) {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, err)
+			continue
+		}
+		if sqlErr.Number != tt.number {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, sqlErr)
+		}
+	}
+}
+
+func TestEvalVariables(t *testing.T) {
+	e := New()
+	e.Set("@Qty", Int(3))
+	e.Set("@price", Numeric(MakeDecimal(1999, 2)))
+	e.Set("@name", Null(Type{Kind: KindNVarChar, Length: 50}))
+
+	v, err := e.Eval(parseExpr(t, "@qty * @PRICE"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.String() != "59.97" {
+		t.Errorf("expected 59.97, got %s", v)
+	}
+
+	v, err = e.Eval(parseExpr(t, "ISNULL_FALLBACK(@name)"))
+	if !errors.Is(err, ErrNotConstant) {
+		t.Errorf("expected ErrNotConstant for unknown function, got %v %v", v, err)
+	}
+	if _, err := e.Eval(parseExpr(t, "@missing + 1")); !errors.Is(err, ErrNotConstant) {
+		t.Errorf("expected ErrNotConstant for unknown variable, got %v", err)
+	}
+
+	v, err = e.Eval(parseExpr(t, "COALESCE(@name, 'anonymous')"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.String() != "anonymous" || v.Type.String() != "NVARCHAR(50)" {
+		t.Errorf("expected anonymous NVARCHAR(50), got %s %s", v, v.Type)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     Value
+		expected int
+	}{
+		{Int(1), Numeric(MakeDecimal(15, 1)), -1},
+		{VarChar("b"), NVarChar("A"), 1},
+		{VarChar("a "), VarChar("A"), 0},
+		{Null(typeInt), Int(0), -1},
+		{Null(typeInt), Value{Null: true}, 0},
+	}
+	for _, tt := range tests {
+		got, err := Compare(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("Compare(%s, %s): unexpected error: %v", tt.a, tt.b, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+
+	a, _ := Convert(VarChar("00000000-0000-0000-0000-000000000002"), Type{Kind: KindUniqueIdentifier})
+	b, _ := Convert(VarChar("01000000-0000-0000-0000-000000000001"), Type{Kind: KindUniqueIdentifier})
+	if c, _ := Compare(a, b); c != 1 {
+		t.Errorf("uniqueidentifiers should compare by their last group first, got %d", c)
+	}
+}
+
+func TestFold(t *testing.T) {
+	e := New()
+	e.Set("@debug", Bit(false))
+	e.Set("@limit", Int(10))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Price * (1 + 2)", "(Price * 3)"},
+		{"Qty > @limit * 2", "(Qty > 20)"},
+		{"@debug = 1 OR Status = 'A'", "(Status = 'A')"},
+		{"@limit > 5 AND Status = 'it''s'", "(Status = 'it''s')"},
+		{"Name + CAST(@limit AS VARCHAR(5))", "(Name + '10')"},
+		{"CAST(@limit AS BIGINT) + Id", "(CAST(10 AS BIGINT) + Id)"},
+		{"Total / 0", "(Total / 0)"},
+		{"IIF(Flag = 1, 1 / 0, 2)", "IIF((Flag = 1), (1 / 0), 2)"},
+		{"@debug = 0", "(1 = 1)"},
+	}
+	for _, tt := range tests {
+		expr := parseExpr(t, tt.input)
+		before := expr.String()
+		got := e.Fold(expr).String()
+		if got != tt.expected {
+			t.Errorf("Fold(%s) = %s, want %s", tt.input, got, tt.expected)
+		}
+		if expr.String() != before {
+			t.Errorf("Fold(%s) modified its input", tt.input)
+		}
+	}
+}
+
+func TestLiteralRoundTrip(t *testing.T) {
+	values := []string{
+		"CAST(1 AS TINYINT)",
+		"CAST(1.5 AS DECIMAL(10, 2))",
+		"-12.5",
+		"$3.25",
+		"CAST(-3 AS MONEY)",
+		"1.5e0",
+		"N'it''s'",
+		"CAST('x' AS CHAR(3))",
+		"CAST('2024-01-02 03:04:05.123' AS DATETIME)",
+		"CAST('2024-01-02 03:04:05.1234567 +05:30' AS DATETIMEOFFSET)",
+		"CAST(NULL AS DATE)",
+		"'x' COLLATE Latin1_General_CS_AS",
+	}
+	for _, input := range values {
+		v, err := Eval(parseExpr(t, input))
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		again, err := Eval(parseExpr(t, v.Literal().String()))
+		if err != nil {
+			t.Fatalf("%s: re-evaluating %s: %v", input, v.Literal(), err)
+		}
+		if again.String() != v.String() || again.Type != v.Type || again.Collation() != v.Collation() {
+			t.Errorf("%s: round trip through %s gave %s %s, want %s %s",
+				input, v.Literal(), again, again.Type, v, v.Type)
+		}
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"DECLARE @v VARCHAR", "VARCHAR(1)"},
+		{"DECLARE @v NVARCHAR(MAX)", "NVARCHAR(MAX)"},
+		{"DECLARE @v NUMERIC", "DECIMAL(18, 0)"},
+		{"DECLARE @v FLOAT(10)", "REAL"},
+		{"DECLARE @v DATETIME2", "DATETIME2(7)"},
+		{"DECLARE @v SYSNAME", "NVARCHAR(128)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		dt := program.Statements[0].(*ast.DeclareStatement).Variables[0].DataType
+		got, err := TypeOf(dt)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got.String() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	e := New()
+	e.Resolve = func(expr ast.Expression) (Value, error) {
+		if id, ok := expr.(*ast.Identifier); ok && id.Value == "qty" {
+			return Int(4), nil
+		}
+		return Value{}, ErrNotConstant
+	}
+	v, err := e.Eval(parseExpr(t, "qty * 2 + LEN('abc')"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.String() != "11" {
+		t.Errorf("expected 11, got %s", v)
+	}
+	if _, err := e.Eval(parseExpr(t, "COUNT(DISTINCT qty)")); !errors.Is(err, ErrNotConstant) {
+		t.Errorf("expected COUNT(DISTINCT qty) to be passed to Resolve, got %v", err)
+	}
+}
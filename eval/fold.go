@@ -0,0 +1,213 @@
+package eval
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Literal returns an expression that evaluates to v with the same type,
+// using a plain literal where one exists and a CAST otherwise.
+func (v Value) Literal() ast.Expression {
+	k := v.Type.Kind
+	if k == KindBoolean {
+		// Predicates have no literal form; use a comparison with the
+		// same truth value
+		right := ast.Expression(intLiteral(1))
+		switch {
+		case v.Null:
+			right = &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "NULL"}}
+		case !v.Bool():
+			right = intLiteral(0)
+		}
+		return &ast.InfixExpression{Token: token.Token{Type: token.EQ, Literal: "="}, Left: intLiteral(1), Operator: "=", Right: right}
+	}
+	if v.Null {
+		null := &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "NULL"}}
+		if k == KindNull {
+			return null
+		}
+		return castTo(null, v.Type)
+	}
+
+	switch k {
+	case KindInt:
+		return intLiteral(v.Int64())
+	case KindDecimal:
+		d := v.Decimal()
+		lit := &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: d.String()}, Value: d.Float64()}
+		if d.Sign() >= 0 && d.Scale() > 0 && d.Precision() == v.Type.Precision {
+			return lit
+		}
+		return castTo(lit, v.Type)
+	case KindMoney:
+		if v.Decimal().Sign() >= 0 {
+			text := "$" + v.Decimal().String()
+			return &ast.MoneyLiteral{Token: token.Token{Type: token.MONEY_LIT, Literal: text}, Value: text}
+		}
+	case KindFloat:
+		text := strconv.FormatFloat(v.Float64(), 'E', -1, 64)
+		return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: text}, Value: v.Float64()}
+	case KindVarChar, KindNVarChar:
+		return collate(stringLiteral(v.Text(), k.isUnicode()), v.collation)
+	case KindVarBinary:
+		text := v.String()
+		return &ast.BinaryLiteral{Token: token.Token{Type: token.BINARY, Literal: text}, Value: text}
+	}
+
+	var text ast.Expression
+	switch {
+	case k.isInteger():
+		text = intLiteral(v.Int64())
+	case k == KindDecimal || k.isMoney() || k.isFloat():
+		text = stringLiteral(v.String(), false)
+	case k.isBinary():
+		text = &ast.BinaryLiteral{Token: token.Token{Type: token.BINARY, Literal: v.String()}, Value: v.String()}
+	default:
+		text = stringLiteral(v.String(), k.isUnicode())
+	}
+	return collate(castTo(text, v.Type), v.collation)
+}
+
+func collate(expr ast.Expression, collation string) ast.Expression {
+	if collation == "" {
+		return expr
+	}
+	return &ast.CollateExpression{Token: token.Token{Type: token.COLLATE, Literal: "COLLATE"}, Expr: expr, Collation: collation}
+}
+
+func intLiteral(i int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(i, 10)}, Value: i}
+}
+
+func stringLiteral(s string, unicode bool) *ast.StringLiteral {
+	typ, literal := token.STRING, "'"+strings.ReplaceAll(s, "'", "''")+"'"
+	if unicode {
+		typ, literal = token.NSTRING, "N"+literal
+	}
+	return &ast.StringLiteral{Token: token.Token{Type: typ, Literal: literal}, Value: s, Unicode: unicode}
+}
+
+func castTo(expr ast.Expression, t Type) *ast.CastExpression {
+	return &ast.CastExpression{Token: token.Token{Type: token.CAST, Literal: "CAST"}, Expression: expr, TargetType: t.DataType()}
+}
+
+// Fold returns expr with every constant subexpression replaced by a
+// literal of the same value and type. AND and OR with a constant TRUE or
+// FALSE operand are simplified. Subexpressions that would raise an error
+// are kept so the error still happens at run time. expr is not modified.
+func (e *Evaluator) Fold(expr ast.Expression) ast.Expression {
+	if expr == nil {
+		return nil
+	}
+	v, err := e.Eval(expr)
+	if err == nil {
+		return v.Literal()
+	}
+	if !errors.Is(err, ErrNotConstant) {
+		return expr
+	}
+
+	switch x := expr.(type) {
+	case *ast.InfixExpression:
+		if x.Operator == "AND" || x.Operator == "OR" {
+			return e.foldLogical(x)
+		}
+		c := *x
+		c.Left, c.Right = e.Fold(x.Left), e.Fold(x.Right)
+		return &c
+	case *ast.PrefixExpression:
+		c := *x
+		c.Right = e.Fold(x.Right)
+		return &c
+	case *ast.CollateExpression:
+		c := *x
+		c.Expr = e.Fold(x.Expr)
+		return &c
+	case *ast.BetweenExpression:
+		c := *x
+		c.Expr, c.Low, c.High = e.Fold(x.Expr), e.Fold(x.Low), e.Fold(x.High)
+		return &c
+	case *ast.InExpression:
+		c := *x
+		c.Expr = e.Fold(x.Expr)
+		c.Values = e.foldAll(x.Values)
+		return &c
+	case *ast.LikeExpression:
+		c := *x
+		c.Expr, c.Pattern, c.Escape = e.Fold(x.Expr), e.Fold(x.Pattern), e.Fold(x.Escape)
+		return &c
+	case *ast.IsNullExpression:
+		c := *x
+		c.Expr = e.Fold(x.Expr)
+		return &c
+	case *ast.IsDistinctFromExpression:
+		c := *x
+		c.Left, c.Right = e.Fold(x.Left), e.Fold(x.Right)
+		return &c
+	case *ast.CaseExpression:
+		c := *x
+		c.Operand, c.ElseClause = e.Fold(x.Operand), e.Fold(x.ElseClause)
+		c.WhenClauses = make([]*ast.WhenClause, len(x.WhenClauses))
+		for i, w := range x.WhenClauses {
+			c.WhenClauses[i] = &ast.WhenClause{Condition: e.Fold(w.Condition), Result: e.Fold(w.Result)}
+		}
+		return &c
+	case *ast.CastExpression:
+		c := *x
+		c.Expression = e.Fold(x.Expression)
+		return &c
+	case *ast.ConvertExpression:
+		c := *x
+		c.Expression, c.Style = e.Fold(x.Expression), e.Fold(x.Style)
+		return &c
+	case *ast.ParseExpression:
+		c := *x
+		c.Expression = e.Fold(x.Expression)
+		return &c
+	case *ast.TrimExpression:
+		c := *x
+		c.Characters, c.Expression = e.Fold(x.Characters), e.Fold(x.Expression)
+		return &c
+	case *ast.FunctionCall:
+		c := *x
+		c.Arguments = e.foldAll(x.Arguments)
+		return &c
+	}
+	return expr
+}
+
+func (e *Evaluator) foldAll(exprs []ast.Expression) []ast.Expression {
+	if exprs == nil {
+		return nil
+	}
+	out := make([]ast.Expression, len(exprs))
+	for i, x := range exprs {
+		out[i] = e.Fold(x)
+	}
+	return out
+}
+
+// foldLogical drops operands of AND and OR that cannot change the result:
+// TRUE AND x is x, FALSE OR x is x. UNKNOWN operands are kept.
+func (e *Evaluator) foldLogical(x *ast.InfixExpression) ast.Expression {
+	identity := x.Operator == "AND"
+	left, right := e.Fold(x.Left), e.Fold(x.Right)
+	if e.isTruth(left, identity) {
+		return right
+	}
+	if e.isTruth(right, identity) {
+		return left
+	}
+	c := *x
+	c.Left, c.Right = left, right
+	return &c
+}
+
+func (e *Evaluator) isTruth(expr ast.Expression, truth bool) bool {
+	v, err := e.Eval(expr)
+	return err == nil && v.Type.Kind == KindBoolean && !v.Null && v.Bool() == truth
+}
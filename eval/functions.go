@@ -0,0 +1,604 @@
+package eval
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Func is a built-in function applied to evaluated arguments. A datepart
+// argument, such as the first argument of DATEADD, is passed as a VARCHAR
+// holding the datepart's name.
+type Func func(args []Value) (Value, error)
+
+type builtin struct {
+	fn       Func
+	min, max int  // Argument counts; a max of -1 allows up to 254
+	datepart bool // The first argument is a datepart name, not an expression
+}
+
+// builtins holds the deterministic built-in functions, keyed by name.
+// Functions whose arguments are evaluated lazily (IIF, COALESCE, CHOOSE)
+// and functions that read the clock are handled by Evaluator.call.
+var builtins = map[string]builtin{
+	// Logical and conversion
+	"ISNULL":    {fn: isNull, min: 2, max: 2},
+	"ISNUMERIC": {fn: isNumeric, min: 1, max: 1},
+	"GREATEST":  {fn: extreme(1), min: 1, max: -1},
+	"LEAST":     {fn: extreme(-1), min: 1, max: -1},
+
+	// Mathematical
+	"ABS":     {fn: abs, min: 1, max: 1},
+	"SIGN":    {fn: sign, min: 1, max: 1},
+	"CEILING": {fn: ceiling, min: 1, max: 1},
+	"FLOOR":   {fn: floor, min: 1, max: 1},
+	"ROUND":   {fn: round, min: 2, max: 3},
+	"POWER":   {fn: power, min: 2, max: 2},
+	"SQUARE":  {fn: floatFunc(func(x float64) float64 { return x * x }), min: 1, max: 1},
+	"SQRT":    {fn: floatFunc(math.Sqrt), min: 1, max: 1},
+	"EXP":     {fn: floatFunc(math.Exp), min: 1, max: 1},
+	"LOG":     {fn: logarithm, min: 1, max: 2},
+	"LOG10":   {fn: floatFunc(math.Log10), min: 1, max: 1},
+	"PI":      {fn: func([]Value) (Value, error) { return Float(math.Pi), nil }, min: 0, max: 0},
+	"SIN":     {fn: floatFunc(math.Sin), min: 1, max: 1},
+	"COS":     {fn: floatFunc(math.Cos), min: 1, max: 1},
+	"TAN":     {fn: floatFunc(math.Tan), min: 1, max: 1},
+	"COT":     {fn: floatFunc(func(x float64) float64 { return 1 / math.Tan(x) }), min: 1, max: 1},
+	"ASIN":    {fn: floatFunc(math.Asin), min: 1, max: 1},
+	"ACOS":    {fn: floatFunc(math.Acos), min: 1, max: 1},
+	"ATAN":    {fn: floatFunc(math.Atan), min: 1, max: 1},
+	"ATN2":    {fn: atn2, min: 2, max: 2},
+	"DEGREES": {fn: angle("degrees", 180/math.Pi), min: 1, max: 1},
+	"RADIANS": {fn: angle("radians", math.Pi/180), min: 1, max: 1},
+
+	// String
+	"LEN":           {fn: length, min: 1, max: 1},
+	"DATALENGTH":    {fn: dataLength, min: 1, max: 1},
+	"LEFT":          {fn: left, min: 2, max: 2},
+	"RIGHT":         {fn: right, min: 2, max: 2},
+	"SUBSTRING":     {fn: substring, min: 3, max: 3},
+	"UPPER":         {fn: mapText(strings.ToUpper), min: 1, max: 1},
+	"LOWER":         {fn: mapText(strings.ToLower), min: 1, max: 1},
+	"LTRIM":         {fn: trimFunc("LEADING"), min: 1, max: 2},
+	"RTRIM":         {fn: trimFunc("TRAILING"), min: 1, max: 2},
+	"TRIM":          {fn: trimFunc("BOTH"), min: 1, max: 1},
+	"REVERSE":       {fn: mapText(reverse), min: 1, max: 1},
+	"CHARINDEX":     {fn: charIndex, min: 2, max: 3},
+	"PATINDEX":      {fn: patIndex, min: 2, max: 2},
+	"REPLACE":       {fn: replace, min: 3, max: 3},
+	"TRANSLATE":     {fn: translate, min: 3, max: 3},
+	"STUFF":         {fn: stuff, min: 4, max: 4},
+	"CONCAT":        {fn: concatFunc, min: 2, max: -1},
+	"CONCAT_WS":     {fn: concatWS, min: 3, max: -1},
+	"REPLICATE":     {fn: replicate, min: 2, max: 2},
+	"SPACE":         {fn: space, min: 1, max: 1},
+	"ASCII":         {fn: ascii, min: 1, max: 1},
+	"CHAR":          {fn: char, min: 1, max: 1},
+	"UNICODE":       {fn: unicodeFunc, min: 1, max: 1},
+	"NCHAR":         {fn: nchar, min: 1, max: 1},
+	"STR":           {fn: str, min: 1, max: 3},
+	"QUOTENAME":     {fn: quoteName, min: 1, max: 2},
+	"SOUNDEX":       {fn: soundexFunc, min: 1, max: 1},
+	"DIFFERENCE":    {fn: difference, min: 2, max: 2},
+	"STRING_ESCAPE": {fn: stringEscape, min: 2, max: 2},
+	"FORMAT":        {fn: formatFunc, min: 2, max: 3},
+
+	// Date and time
+	"DATEADD":                 {fn: dateAdd, min: 3, max: 3, datepart: true},
+	"DATEDIFF":                {fn: dateDiff(KindInt), min: 3, max: 3, datepart: true},
+	"DATEDIFF_BIG":            {fn: dateDiff(KindBigInt), min: 3, max: 3, datepart: true},
+	"DATEPART":                {fn: datePart, min: 2, max: 2, datepart: true},
+	"DATENAME":                {fn: dateName, min: 2, max: 2, datepart: true},
+	"DATETRUNC":               {fn: dateTrunc, min: 2, max: 2, datepart: true},
+	"YEAR":                    {fn: datePartOf("year"), min: 1, max: 1},
+	"MONTH":                   {fn: datePartOf("month"), min: 1, max: 1},
+	"DAY":                     {fn: datePartOf("day"), min: 1, max: 1},
+	"EOMONTH":                 {fn: endOfMonth, min: 1, max: 2},
+	"DATEFROMPARTS":           {fn: dateFromParts, min: 3, max: 3},
+	"DATETIMEFROMPARTS":       {fn: dateTimeFromParts, min: 7, max: 7},
+	"DATETIME2FROMPARTS":      {fn: dateTime2FromParts, min: 8, max: 8},
+	"SMALLDATETIMEFROMPARTS":  {fn: smallDateTimeFromParts, min: 5, max: 5},
+	"TIMEFROMPARTS":           {fn: timeFromParts, min: 5, max: 5},
+	"DATETIMEOFFSETFROMPARTS": {fn: dateTimeOffsetFromParts, min: 10, max: 10},
+	"ISDATE":                  {fn: isDate, min: 1, max: 1},
+	"SWITCHOFFSET":            {fn: switchOffset, min: 2, max: 2},
+	"TODATETIMEOFFSET":        {fn: toDateTimeOffset, min: 2, max: 2},
+}
+
+// Call applies the deterministic built-in function name to args. Names
+// are not case-sensitive.
+func Call(name string, args ...Value) (Value, error) {
+	b, ok := builtins[strings.ToUpper(name)]
+	if !ok {
+		return Value{}, sqlError(195, "'%s' is not a recognized built-in function name.", name)
+	}
+	if err := b.check(name, len(args)); err != nil {
+		return Value{}, err
+	}
+	return b.fn(args)
+}
+
+func (b builtin) check(name string, n int) error {
+	name = strings.ToLower(name)
+	switch {
+	case n >= b.min && (b.max < 0 || n <= b.max):
+		return nil
+	case b.min == b.max:
+		return argumentCount(name, b.min)
+	case b.max < 0:
+		return sqlError(189, "The %s function requires %d to 254 arguments.", name, b.min)
+	}
+	return sqlError(174, "The %s function requires %d to %d arguments.", name, b.min, b.max)
+}
+
+func anyNull(args []Value) bool {
+	for _, a := range args {
+		if a.Null {
+			return true
+		}
+	}
+	return false
+}
+
+func invalidArgument(v Value, n int, name string) *Error {
+	return sqlError(8116, "Argument data type %s is invalid for argument %d of %s function.",
+		v.Type.Kind.messageName(), n, name)
+}
+
+// intArg converts an argument that must be an integer, as an INT
+// parameter would.
+func intArg(v Value) (int64, error) {
+	i, err := implicit(v, typeInt)
+	return i.Int64(), err
+}
+
+// floatArg converts an argument to FLOAT.
+func floatArg(v Value) (float64, error) {
+	f, err := implicit(v, typeFloat)
+	return f.Float64(), err
+}
+
+// numericArg returns a numeric argument as it is, and reads strings as
+// FLOAT.
+func numericArg(v Value, n int, name string) (Value, error) {
+	k := v.Type.Kind
+	switch {
+	case k.isNumeric() || k == KindNull:
+		return v, nil
+	case k.isString():
+		return implicit(v, typeFloat)
+	}
+	return Value{}, invalidArgument(v, n, name)
+}
+
+func isNull(args []Value) (Value, error) {
+	check, replacement := args[0], args[1]
+	if !check.Null {
+		return check, nil
+	}
+	if check.Type.Kind == KindNull {
+		return replacement, nil
+	}
+	return implicit(replacement, check.Type)
+}
+
+func isNumeric(args []Value) (Value, error) {
+	v := args[0]
+	switch {
+	case v.Null:
+		return Int(0), nil
+	case v.Type.Kind.isNumeric():
+		return Int(1), nil
+	case !v.Type.Kind.isString():
+		return Int(0), nil
+	}
+	if numericText(v.Text()) {
+		return Int(1), nil
+	}
+	return Int(0), nil
+}
+
+// numericText reports whether ISNUMERIC accepts s: digits with an optional
+// sign, currency symbol, thousands separators, decimal point and exponent.
+// Like the server, it accepts a lone sign, point or currency symbol.
+func numericText(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	s = strings.TrimLeft(s, "$£€¥")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "+"), "-")
+	s = strings.TrimLeft(s, "$£€¥")
+	mantissa, exponent, hasExp := strings.Cut(strings.ToUpper(s), "E")
+	if !hasExp {
+		mantissa, exponent, hasExp = strings.Cut(mantissa, "D")
+	}
+	digits, points := 0, 0
+	for _, c := range mantissa {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.':
+			points++
+		case c == ',':
+		default:
+			return false
+		}
+	}
+	if points > 1 {
+		return false
+	}
+	if hasExp {
+		exponent = strings.TrimPrefix(strings.TrimPrefix(exponent, "+"), "-")
+		if digits == 0 || exponent == "" {
+			return false
+		}
+		for _, c := range exponent {
+			if !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// extreme returns GREATEST (dir 1) or LEAST (dir -1). NULL arguments are
+// ignored; the result has the highest-precedence argument type.
+func extreme(dir int) Func {
+	return func(args []Value) (Value, error) {
+		t := args[0].Type
+		for _, a := range args[1:] {
+			t = commonType(t, a.Type)
+		}
+		best := Null(t)
+		for _, a := range args {
+			if a.Null {
+				continue
+			}
+			v, err := implicit(a, t)
+			if err != nil {
+				return Value{}, err
+			}
+			if best.Null {
+				best = v
+				continue
+			}
+			c, err := compare(v, best)
+			if err != nil {
+				return Value{}, err
+			}
+			if c*dir > 0 {
+				best = v
+			}
+		}
+		return best, nil
+	}
+}
+
+func abs(args []Value) (Value, error) {
+	v, err := numericArg(args[0], 1, "abs")
+	if err != nil || v.Null {
+		return v, err
+	}
+	k := v.Type.Kind
+	switch {
+	case k.isInteger():
+		if lo, _ := intRange(k); v.Int64() == lo && lo < 0 {
+			return Value{}, overflow(k)
+		}
+		if v.Int64() < 0 {
+			v.data = -v.Int64()
+		}
+	case k.isFloat():
+		v.data = math.Abs(v.Float64())
+	default:
+		if v.Decimal().Sign() < 0 {
+			v.data = v.Decimal().Neg()
+		}
+	}
+	return v, nil
+}
+
+func sign(args []Value) (Value, error) {
+	v, err := numericArg(args[0], 1, "sign")
+	if err != nil || v.Null {
+		return v, err
+	}
+	k := v.Type.Kind
+	switch {
+	case k.isInteger():
+		s := int64(0)
+		switch {
+		case v.Int64() > 0:
+			s = 1
+		case v.Int64() < 0:
+			s = -1
+		}
+		if k == KindBigInt {
+			return BigInt(s), nil
+		}
+		return Int(s), nil
+	case k.isFloat():
+		f := v.Float64()
+		switch {
+		case f > 0:
+			f = 1
+		case f < 0:
+			f = -1
+		}
+		return Float(f), nil
+	}
+	v.data = MakeDecimal(int64(v.Decimal().Sign()), 0).Rescale(v.Type.Scale)
+	return v, nil
+}
+
+func ceiling(args []Value) (Value, error) { return integral(args[0], "ceiling", 1) }
+
+func floor(args []Value) (Value, error) { return integral(args[0], "floor", -1) }
+
+// integral rounds toward positive (dir 1) or negative (dir -1) infinity.
+// DECIMAL results have scale 0; other types are unchanged.
+func integral(arg Value, name string, dir int) (Value, error) {
+	v, err := numericArg(arg, 1, name)
+	if err != nil || v.Null {
+		return v, err
+	}
+	k := v.Type.Kind
+	switch {
+	case k.isInteger():
+		return v, nil
+	case k.isFloat():
+		if dir > 0 {
+			v.data = math.Ceil(v.Float64())
+		} else {
+			v.data = math.Floor(v.Float64())
+		}
+		return v, nil
+	}
+	d := v.Decimal()
+	r := d.Truncate(0)
+	if r.Cmp(d) != 0 && d.Sign() == dir {
+		r = r.Add(MakeDecimal(int64(dir), 0))
+	}
+	if k.isMoney() {
+		v.data = r.Rescale(4)
+		return v, nil
+	}
+	return Value{Type: Type{Kind: KindDecimal, Precision: v.Type.Precision}, data: r}, nil
+}
+
+func round(args []Value) (Value, error) {
+	v, err := numericArg(args[0], 1, "round")
+	if err != nil || anyNull(args) {
+		if err == nil {
+			v = Null(v.Type)
+		}
+		return v, err
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	truncate := false
+	if len(args) == 3 {
+		f, err := intArg(args[2])
+		if err != nil {
+			return Value{}, err
+		}
+		truncate = f != 0
+	}
+
+	k := v.Type.Kind
+	switch {
+	case k.isFloat():
+		scale := math.Pow10(int(n))
+		x := v.Float64() * scale
+		if truncate {
+			x = math.Trunc(x)
+		} else {
+			x = math.Round(x)
+		}
+		return makeFloat(x/scale, k)
+	case k.isInteger():
+		if n >= 0 {
+			return v, nil
+		}
+		d := roundDecimal(decimalFromInt(v.Int64()), int(n), truncate)
+		i, ok := d.Int64()
+		if lo, hi := intRange(k); !ok || i < lo || i > hi {
+			return Value{}, overflow(k)
+		}
+		v.data = i
+		return v, nil
+	}
+	d := roundDecimal(v.Decimal(), int(n), truncate)
+	if !d.fitsPrecision(v.Type.Precision) || !inMoneyRange(d, k) {
+		return Value{}, overflow(k)
+	}
+	v.data = d
+	return v, nil
+}
+
+// roundDecimal rounds d to length digits after the decimal point, or to a
+// power of ten when length is negative, keeping d's scale.
+func roundDecimal(d Decimal, length int, truncate bool) Decimal {
+	scale := d.Scale()
+	if length >= scale {
+		return d
+	}
+	if length >= 0 {
+		if truncate {
+			return d.Truncate(length).Rescale(scale)
+		}
+		return d.Rescale(length).Rescale(scale)
+	}
+	if -length > 38 {
+		return MakeDecimal(0, scale)
+	}
+	unit := Decimal{unscaled: pow10(-length)}
+	q := d.Quo(unit, 1)
+	if truncate {
+		q = q.Truncate(0)
+	} else {
+		q = q.Rescale(0)
+	}
+	return q.Mul(unit).Rescale(scale)
+}
+
+// power returns x to the power y in the type of x: INT for the smaller
+// integer types, DECIMAL(38, s) for DECIMAL(p, s).
+func power(args []Value) (Value, error) {
+	x, err := numericArg(args[0], 1, "power")
+	if err != nil {
+		return Value{}, err
+	}
+	k := x.Type.Kind
+	t := x.Type
+	switch {
+	case k == KindBit || k == KindTinyInt || k == KindSmallInt:
+		t = typeInt
+	case k == KindDecimal:
+		t = Type{Kind: KindDecimal, Precision: 38, Scale: x.Type.Scale}
+	case k == KindReal:
+		t = typeFloat
+	}
+	if anyNull(args) {
+		return Null(t), nil
+	}
+	y, err := floatArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+
+	if k == KindDecimal || k.isMoney() {
+		if y >= 0 && y == math.Trunc(y) && y <= 1000 {
+			r := MakeDecimal(1, 0)
+			for i := 0; i < int(y); i++ {
+				r = r.Mul(x.Decimal()).Rescale(t.Scale + 8)
+			}
+			return implicit(Numeric(r), t)
+		}
+	}
+	base, err := floatArg(x)
+	if err != nil {
+		return Value{}, err
+	}
+	f := math.Pow(base, y)
+	if math.IsNaN(f) {
+		return Value{}, invalidFloat()
+	}
+	if !isFinite(f) {
+		return Value{}, overflow(t.Kind)
+	}
+	switch {
+	case t.Kind.isInteger():
+		f = math.Trunc(f)
+		if lo, hi := intRange(t.Kind); f < float64(lo) || f > float64(hi) {
+			return Value{}, overflow(t.Kind)
+		}
+		return Value{Type: t, data: int64(f)}, nil
+	case t.Kind.isFloat():
+		return Float(f), nil
+	}
+	return implicit(Float(f), t)
+}
+
+func invalidFloat() *Error {
+	return sqlError(3623, "An invalid floating point operation occurred.")
+}
+
+// floatFunc wraps a math function of one FLOAT argument, reporting domain
+// errors as SQL Server does.
+func floatFunc(f func(float64) float64) Func {
+	return func(args []Value) (Value, error) {
+		if args[0].Null {
+			return Null(typeFloat), nil
+		}
+		x, err := floatArg(args[0])
+		if err != nil {
+			return Value{}, err
+		}
+		r := f(x)
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return Value{}, invalidFloat()
+		}
+		return Float(r), nil
+	}
+}
+
+func logarithm(args []Value) (Value, error) {
+	if anyNull(args) {
+		return Null(typeFloat), nil
+	}
+	x, err := floatArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if x <= 0 {
+		return Value{}, invalidFloat()
+	}
+	r := math.Log(x)
+	if len(args) == 2 {
+		b, err := floatArg(args[1])
+		if err != nil {
+			return Value{}, err
+		}
+		if b <= 0 || b == 1 {
+			return Value{}, invalidFloat()
+		}
+		r /= math.Log(b)
+	}
+	return Float(r), nil
+}
+
+func atn2(args []Value) (Value, error) {
+	if anyNull(args) {
+		return Null(typeFloat), nil
+	}
+	y, err := floatArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	x, err := floatArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	return Float(math.Atan2(y, x)), nil
+}
+
+// angle converts between degrees and radians. The result keeps the type
+// of the argument, so integer arguments are truncated; DECIMAL results
+// have 18 decimal places.
+func angle(name string, factor float64) Func {
+	return func(args []Value) (Value, error) {
+		v, err := numericArg(args[0], 1, name)
+		if err != nil || v.Null {
+			return v, err
+		}
+		f, err := floatArg(v)
+		if err != nil {
+			return Value{}, err
+		}
+		r := f * factor
+		k := v.Type.Kind
+		switch {
+		case k.isFloat():
+			return makeFloat(r, k)
+		case k == KindDecimal:
+			return implicit(Float(r), Type{Kind: KindDecimal, Precision: 38, Scale: max(v.Type.Scale, 18)})
+		}
+		return implicit(Float(math.Trunc(r)), v.Type)
+	}
+}
+
+// trimFunc returns TRIM, LTRIM or RTRIM, which take the characters to
+// remove as an optional second argument.
+func trimFunc(spec string) Func {
+	return func(args []Value) (Value, error) {
+		var chars *Value
+		if len(args) == 2 {
+			chars = &args[1]
+		}
+		return trim(spec, args[0], chars)
+	}
+}
@@ -0,0 +1,212 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFunctions(t *testing.T) {
+	e := New()
+	e.Set("@dt", DateTime(time.Date(2024, 6, 15, 14, 30, 45, 123000000, time.UTC)))
+	e.Set("@dt2", DateTime2(time.Date(2024, 6, 15, 14, 30, 45, 123456700, time.UTC)))
+
+	tests := []struct {
+		input    string
+		expected string
+		typ      string
+	}{
+		// Strings
+		{"LEN('abc  ')", "3", "INT"},
+		{"DATALENGTH(N'abc')", "6", "INT"},
+		{"SUBSTRING('hello', 0, 3)", "he", "VARCHAR(2)"},
+		{"TRIM(LEADING 'x' FROM 'xxaxx')", "axx", "VARCHAR(3)"},
+		{"CHARINDEX('L', 'hello', 4)", "4", "INT"},
+		{"PATINDEX('%[0-9]%', 'ab12')", "3", "INT"},
+		{"REPLACE('Hello', 'L', 'x')", "Hexxo", "VARCHAR(5)"},
+		{"TRANSLATE('2*[3+4]', '[]', '()')", "2*(3+4)", "VARCHAR(7)"},
+		{"STUFF('abcdef', 2, 3, 'XYZ')", "aXYZef", "VARCHAR(6)"},
+		{"CONCAT('a', NULL, 1)", "a1", "VARCHAR(2)"},
+		{"CONCAT_WS('-', 'a', NULL, 'b')", "a-b", "VARCHAR(3)"},
+		{"STR(123.456, 10, 2)", "    123.46", "VARCHAR(10)"},
+		{"STR(12345678, 5)", "*****", "VARCHAR(5)"},
+		{"QUOTENAME('a]b')", "[a]]b]", "NVARCHAR(258)"},
+		{"SOUNDEX('Ashcraft')", "A261", "VARCHAR(4)"},
+		{"DIFFERENCE('Smith', 'Smyth')", "4", "INT"},
+		{"NCHAR(26085)", "日", "NCHAR(1)"},
+
+		// Math keeps the argument type where SQL Server does
+		{"ABS(-15.5)", "15.5", "DECIMAL(3, 1)"},
+		{"CEILING(15.2)", "16", "DECIMAL(3, 0)"},
+		{"ROUND(15.565, 2)", "15.570", "DECIMAL(5, 3)"},
+		{"ROUND(15.567, 2, 1)", "15.560", "DECIMAL(5, 3)"},
+		{"ROUND(155.67, -2)", "200.00", "DECIMAL(5, 2)"},
+		{"POWER(2, 10)", "1024", "INT"},
+		{"POWER(2, -1)", "0", "INT"},
+		{"POWER(2.5, 2)", "6.3", "DECIMAL(38, 1)"},
+		{"RADIANS(180)", "3", "INT"},
+		{"LOG(8, 2)", "3", "FLOAT"},
+		{"GREATEST(10, 5, 8.5)", "10.0", "DECIMAL(11, 1)"},
+		{"LEAST('b', 'a', NULL)", "a", "VARCHAR(1)"},
+		{"ISNULL(CAST(NULL AS VARCHAR(3)), 'abcdef')", "abc", "VARCHAR(3)"},
+		{"ISNUMERIC('$')", "1", "INT"},
+		{"CHOOSE(5, 'a', 'b')", "NULL", "VARCHAR(1)"},
+
+		// Dates and times
+		{"DATEADD(month, 1, CAST('2024-01-31' AS DATE))", "2024-02-29", "DATE"},
+		{"DATEADD(hour, 25, CAST('10:00' AS TIME))", "11:00:00.0000000", "TIME(7)"},
+		{"DATEADD(day, 1, '2024-01-31')", "2024-02-01 00:00:00.000", "DATETIME"},
+		{"DATEDIFF(year, '2023-12-31', '2024-01-01')", "1", "INT"},
+		{"DATEDIFF(week, '2024-06-15', '2024-06-16')", "1", "INT"},
+		{"DATEDIFF_BIG(ns, '2000-01-01', '2024-01-02')", "757468800000000000", "BIGINT"},
+		{"DATEPART(weekday, '2024-06-15')", "7", "INT"},
+		{"DATEPART(iso_week, '2024-06-15')", "24", "INT"},
+		{"DATEPART(tz, '2024-06-15 10:00 +05:30')", "330", "INT"},
+		{"DATENAME(month, '2024-06-15')", "June", "NVARCHAR(4)"},
+		{"DATETRUNC(week, CAST('2024-06-15' AS DATE))", "2024-06-09", "DATE"},
+		{"EOMONTH('2024-01-31', 1)", "2024-02-29", "DATE"},
+		{"DATETIME2FROMPARTS(2024, 6, 15, 14, 30, 45, 5, 1)", "2024-06-15 14:30:45.5", "DATETIME2(1)"},
+		{"DATETIMEOFFSETFROMPARTS(2024, 6, 15, 14, 30, 0, 0, 5, 30, 0)", "2024-06-15 14:30:00 +05:30", "DATETIMEOFFSET(0)"},
+		{"SWITCHOFFSET(CAST('2024-06-15 10:00 +00:00' AS DATETIMEOFFSET), '+05:30')", "2024-06-15 15:30:00.0000000 +05:30", "DATETIMEOFFSET(7)"},
+		{"ISDATE('nope')", "0", "INT"},
+
+		// CONVERT styles
+		{"CONVERT(VARCHAR(10), @dt, 1)", "06/15/24", "VARCHAR(10)"},
+		{"CONVERT(VARCHAR(10), @dt, 101)", "06/15/2024", "VARCHAR(10)"},
+		{"CONVERT(VARCHAR(26), @dt, 109)", "Jun 15 2024  2:30:45:123PM", "VARCHAR(26)"},
+		{"CONVERT(VARCHAR(8), @dt, 112)", "20240615", "VARCHAR(8)"},
+		{"CONVERT(VARCHAR(23), @dt2, 121)", "2024-06-15 14:30:45.123", "VARCHAR(23)"},
+		{"CONVERT(VARCHAR(30), @dt2, 126)", "2024-06-15T14:30:45.1234567", "VARCHAR(30)"},
+		{"CONVERT(VARCHAR(20), 0x48656C6C6F, 2)", "48656C6C6F", "VARCHAR(20)"},
+		{"CONVERT(VARBINARY(10), '0x4142', 1)", "0x4142", "VARBINARY(10)"},
+		{"CONVERT(VARCHAR(20), CAST(1234567.891 AS MONEY), 1)", "1,234,567.89", "VARCHAR(20)"},
+		{"CONVERT(VARCHAR(30), 1234.5e0, 2)", "1.234500000000000e+003", "VARCHAR(30)"},
+		{"CONVERT(DATE, '15.06.24', 4)", "2024-06-15", "DATE"},
+		{"TRY_CONVERT(DATE, 'invalid', 101)", "NULL", "DATE"},
+
+		// FORMAT and PARSE
+		{"FORMAT(12345678.90, 'N', 'en-US')", "12,345,678.90", "NVARCHAR(4000)"},
+		{"FORMAT(123456.789, 'C', 'de-DE')", "123.456,79 €", "NVARCHAR(4000)"},
+		{"FORMAT(-5, 'C')", "($5.00)", "NVARCHAR(4000)"},
+		{"FORMAT(0.1234, 'P')", "12.34 %", "NVARCHAR(4000)"},
+		{"FORMAT(255, 'X4')", "00FF", "NVARCHAR(4000)"},
+		{"FORMAT(123456789, '###-##-####')", "123-45-6789", "NVARCHAR(4000)"},
+		{"FORMAT(@dt, 'D', 'de-DE')", "Samstag, 15. Juni 2024", "NVARCHAR(4000)"},
+		{"FORMAT(@dt, 'ddd, MMM d, yyyy')", "Sat, Jun 15, 2024", "NVARCHAR(4000)"},
+		{"FORMAT(1, 'Q')", "NULL", "NVARCHAR(4000)"},
+		{"PARSE('15 juin 2024' AS DATE USING 'fr-FR')", "2024-06-15", "DATE"},
+		{"PARSE('1.234,56 €' AS MONEY USING 'de-DE')", "1234.5600", "MONEY"},
+		{"TRY_PARSE('abc' AS INT)", "NULL", "INT"},
+	}
+
+	for _, tt := range tests {
+		v, err := e.Eval(parseExpr(t, tt.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if v.String() != tt.expected || v.Type.String() != tt.typ {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.input, tt.expected, tt.typ, v, v.Type)
+		}
+	}
+}
+
+func TestFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		number int
+	}{
+		{"LEFT('abc', -1)", 536},
+		{"SUBSTRING('abc', 1, -1)", 537},
+		{"TRANSLATE('abc', 'ab', 'x')", 9828},
+		{"DATEADD(fortnight, 1, '2024-01-01')", 155},
+		{"DATEPART(hour, CAST('2024-06-15' AS DATE))", 9810},
+		{"DATEDIFF(ns, '2000-01-01', '2024-01-02')", 535},
+		{"DATEFROMPARTS(2023, 2, 29)", 289},
+		{"LEN('a', 'b')", 174},
+		{"FORMAT(1, 'N', 'xx-XX')", 9818},
+		{"PARSE('abc' AS INT)", 9819},
+		{"CONVERT(VARCHAR(10), 1.5e0, 7)", 281},
+		{"SQRT(-1)", 3623},
+	}
+
+	for _, tt := range tests {
+		_, err := Eval(parseExpr(t, tt.input))
+		var sqlErr *Error
+		if !errors.As(err, &sqlErr) {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, err)
+			continue
+		}
+		if sqlErr.Number != tt.number {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, sqlErr)
+		}
+	}
+}
+
+func TestCurrentTime(t *testing.T) {
+	e := New()
+	if _, err := e.Eval(parseExpr(t, "GETDATE()")); !errors.Is(err, ErrNotConstant) {
+		t.Errorf("expected ErrNotConstant without a clock, got %v", err)
+	}
+
+	e.Now = func() time.Time {
+		return time.Date(2024, 6, 15, 14, 30, 45, 123456789, time.FixedZone("", 2*60*60))
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"GETDATE()", "2024-06-15 14:30:45.123"},
+		{"CURRENT_TIMESTAMP", "2024-06-15 14:30:45.123"},
+		{"GETUTCDATE()", "2024-06-15 12:30:45.123"},
+		{"SYSDATETIMEOFFSET()", "2024-06-15 14:30:45.1234568 +02:00"},
+	}
+	for _, tt := range tests {
+		v, err := e.Eval(parseExpr(t, tt.input))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if v.String() != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, v)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	v, err := Call("upper", VarChar("abc"))
+	if err != nil || v.String() != "ABC" {
+		t.Errorf("Call(upper) = %s, %v", v, err)
+	}
+	var sqlErr *Error
+	if _, err := Call("NO_SUCH_FUNCTION"); !errors.As(err, &sqlErr) || sqlErr.Number != 195 {
+		t.Errorf("expected error 195 for an unknown function, got %v", err)
+	}
+}
+
+func TestStringSplitAndAgg(t *testing.T) {
+	rows, err := StringSplit(VarChar("a,b,,c"), VarChar(","), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+	if rows[2][0].String() != "" || rows[3][0].String() != "c" || rows[3][1].String() != "4" {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+	if rows[3][1].Type.Kind != KindBigInt {
+		t.Errorf("expected a BIGINT ordinal, got %s", rows[3][1].Type)
+	}
+	if _, err := StringSplit(VarChar("a"), VarChar(",;"), false); err == nil {
+		t.Error("expected an error for a multi-character separator")
+	}
+
+	v, err := StringAgg([]Value{VarChar("a"), Null(Type{Kind: KindVarChar, Length: 10}), VarChar("b")}, VarChar(", "))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.String() != "a, b" || v.Type.String() != "VARCHAR(8000)" {
+		t.Errorf("expected a, b VARCHAR(8000), got %s %s", v, v.Type)
+	}
+}
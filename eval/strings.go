@@ -0,0 +1,812 @@
+package eval
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// stringArg converts an argument to a character type, as passing it to a
+// VARCHAR parameter would.
+func stringArg(v Value) (Value, error) {
+	if v.Type.Kind.isString() {
+		return v, nil
+	}
+	return implicit(v, Type{Kind: KindVarChar, Length: MaxLength})
+}
+
+// stringArgs converts each argument with stringArg, reporting whether any
+// of them is Unicode.
+func stringArgs(args []Value) ([]Value, bool, error) {
+	out := make([]Value, len(args))
+	unicode := false
+	for i, a := range args {
+		v, err := stringArg(a)
+		if err != nil {
+			return nil, false, err
+		}
+		out[i] = v
+		unicode = unicode || v.Type.Kind.isUnicode()
+	}
+	return out, unicode, nil
+}
+
+// text returns s as NVARCHAR when unicode is set and VARCHAR otherwise.
+func text(s string, unicode bool) Value {
+	if unicode {
+		return NVarChar(s)
+	}
+	return VarChar(s)
+}
+
+// textLike returns s as a string of the same kind as v, keeping v's
+// collation.
+func textLike(v Value, s string) Value {
+	r := text(s, v.Type.Kind.isUnicode())
+	r.collation = v.collation
+	return r
+}
+
+// nullText returns a NULL string of the same kind as v.
+func nullText(v Value) Value {
+	return Null(textLike(v, "").Type)
+}
+
+func length(args []Value) (Value, error) {
+	v, err := stringArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	t := typeInt
+	if v.Type.Length == MaxLength {
+		t = typeBigInt
+	}
+	if v.Null {
+		return Null(t), nil
+	}
+	n := len([]rune(strings.TrimRight(v.Text(), " ")))
+	return Value{Type: t, data: int64(n)}, nil
+}
+
+func dataLength(args []Value) (Value, error) {
+	v := args[0]
+	if v.Null {
+		return Null(typeInt), nil
+	}
+	k := v.Type.Kind
+	var n int
+	switch {
+	case k.isString():
+		n = len(encodeString(v))
+	case k.isBinary():
+		n = len(v.Bytes())
+	case k.isInteger():
+		n = intSize(k)
+	case k == KindDecimal:
+		switch p := v.Type.Precision; {
+		case p <= 9:
+			n = 5
+		case p <= 19:
+			n = 9
+		case p <= 28:
+			n = 13
+		default:
+			n = 17
+		}
+	case k == KindSmallMoney, k == KindReal, k == KindSmallDateTime:
+		n = 4
+	case k == KindMoney, k == KindFloat, k == KindDateTime:
+		n = 8
+	case k == KindUniqueIdentifier:
+		n = 16
+	case k == KindDate:
+		n = 3
+	case k == KindTime, k == KindDateTime2, k == KindDateTimeOffset:
+		n = 5
+		if v.Type.Scale <= 2 {
+			n = 3
+		} else if v.Type.Scale <= 4 {
+			n = 4
+		}
+		if k == KindDateTime2 {
+			n += 3
+		} else if k == KindDateTimeOffset {
+			n += 5
+		}
+	default:
+		return Value{}, invalidArgument(v, 1, "datalength")
+	}
+	return Int(int64(n)), nil
+}
+
+// slice returns the characters or bytes of v from index from (counting
+// from zero) up to but not including to, clamped to v's length.
+func slice(v Value, from, to int64) Value {
+	if v.Type.Kind.isBinary() {
+		b := v.Bytes()
+		from, to = clamp(from, to, len(b))
+		return VarBinary(b[from:to])
+	}
+	runes := []rune(v.Text())
+	from, to = clamp(from, to, len(runes))
+	return textLike(v, string(runes[from:to]))
+}
+
+func clamp(from, to int64, n int) (int64, int64) {
+	from = max(from, 0)
+	to = min(to, int64(n))
+	if to < from {
+		to = from
+	}
+	return min(from, int64(n)), to
+}
+
+// binaryOrString passes binary arguments through and converts others with
+// stringArg.
+func binaryOrString(v Value) (Value, error) {
+	if v.Type.Kind.isBinary() {
+		return v, nil
+	}
+	return stringArg(v)
+}
+
+func left(args []Value) (Value, error) {
+	return leftOrRight(args, "left")
+}
+
+func right(args []Value) (Value, error) {
+	return leftOrRight(args, "right")
+}
+
+func leftOrRight(args []Value, name string) (Value, error) {
+	v, err := binaryOrString(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if anyNull(args) {
+		return Null(v.Type), nil
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	if n < 0 {
+		return Value{}, sqlError(536, "Invalid length parameter passed to the %s function.", name)
+	}
+	if name == "left" {
+		return slice(v, 0, n), nil
+	}
+	size := int64(len([]rune(v.Text())))
+	if v.Type.Kind.isBinary() {
+		size = int64(len(v.Bytes()))
+	}
+	return slice(v, size-n, size), nil
+}
+
+func substring(args []Value) (Value, error) {
+	v, err := binaryOrString(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if anyNull(args) {
+		return Null(v.Type), nil
+	}
+	start, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	n, err := intArg(args[2])
+	if err != nil {
+		return Value{}, err
+	}
+	if n < 0 {
+		return Value{}, sqlError(537, "Invalid length parameter passed to the substring function.")
+	}
+	return slice(v, start-1, start-1+n), nil
+}
+
+// mapText applies f to the text of a string argument.
+func mapText(f func(string) string) Func {
+	return func(args []Value) (Value, error) {
+		v, err := stringArg(args[0])
+		if err != nil || v.Null {
+			return v, err
+		}
+		return textLike(v, f(v.Text())), nil
+	}
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// trim removes the characters in chars (spaces when chars is nil) from
+// the ends of v named by spec: LEADING, TRAILING or BOTH.
+func trim(spec string, v Value, chars *Value) (Value, error) {
+	v, err := stringArg(v)
+	if err != nil {
+		return Value{}, err
+	}
+	cut := " "
+	if chars != nil {
+		c, err := stringArg(*chars)
+		if err != nil {
+			return Value{}, err
+		}
+		if c.Null {
+			return nullText(v), nil
+		}
+		cut = c.Text()
+	}
+	if v.Null {
+		return v, nil
+	}
+	s := v.Text()
+	if spec != "TRAILING" {
+		s = strings.TrimLeft(s, cut)
+	}
+	if spec != "LEADING" {
+		s = strings.TrimRight(s, cut)
+	}
+	return textLike(v, s), nil
+}
+
+// indexRunes returns the index of the first occurrence of sub in s at or
+// after from, or -1.
+func indexRunes(s, sub []rune, from int, sensitive bool) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := max(from, 0); i+len(sub) <= len(s); i++ {
+		match := true
+		for j, c := range sub {
+			if !sameRune(s[i+j], c, sensitive) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameRune(a, b rune, sensitive bool) bool {
+	if sensitive {
+		return a == b
+	}
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+func charIndex(args []Value) (Value, error) {
+	strs, _, err := stringArgs(args[:2])
+	if err != nil {
+		return Value{}, err
+	}
+	find, s := strs[0], strs[1]
+	t := typeInt
+	if s.Type.Length == MaxLength {
+		t = typeBigInt
+	}
+	if anyNull(args) {
+		return Null(t), nil
+	}
+	start := int64(1)
+	if len(args) == 3 {
+		if start, err = intArg(args[2]); err != nil {
+			return Value{}, err
+		}
+	}
+	i := indexRunes([]rune(s.Text()), []rune(find.Text()), int(start-1), caseSensitive(find.collation, s.collation))
+	return Value{Type: t, data: int64(i + 1)}, nil
+}
+
+func patIndex(args []Value) (Value, error) {
+	strs, _, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	pattern, s := strs[0], strs[1]
+	t := typeInt
+	if s.Type.Length == MaxLength {
+		t = typeBigInt
+	}
+	if anyNull(args) {
+		return Null(t), nil
+	}
+	sensitive := caseSensitive(pattern.collation, s.collation)
+	p := pattern.Text()
+	anchored := !strings.HasPrefix(p, "%")
+	p = strings.TrimLeft(p, "%")
+	runes := []rune(s.Text())
+	for i := 0; i <= len(runes); i++ {
+		if Like(string(runes[i:]), p, 0, sensitive) {
+			return Value{Type: t, data: int64(i + 1)}, nil
+		}
+		if anchored {
+			break
+		}
+	}
+	return Value{Type: t, data: int64(0)}, nil
+}
+
+func replace(args []Value) (Value, error) {
+	strs, unicode, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	if anyNull(strs) {
+		return Null(text("", unicode).Type), nil
+	}
+	s, find, repl := strs[0], []rune(strs[1].Text()), strs[2].Text()
+	if len(find) == 0 {
+		return s, nil
+	}
+	sensitive := caseSensitive(strs[0].collation, strs[1].collation)
+	runes := []rune(s.Text())
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := indexRunes(runes, find, i, sensitive)
+		if j < 0 {
+			b.WriteString(string(runes[i:]))
+			break
+		}
+		b.WriteString(string(runes[i:j]))
+		b.WriteString(repl)
+		i = j + len(find)
+	}
+	r := text(b.String(), unicode)
+	r.collation = s.collation
+	return r, nil
+}
+
+func translate(args []Value) (Value, error) {
+	strs, unicode, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	from, to := []rune(strs[1].Text()), []rune(strs[2].Text())
+	if !anyNull(strs) && len(from) != len(to) {
+		return Value{}, sqlError(9828, "The second and third arguments of the TRANSLATE built-in function must contain an equal number of characters.")
+	}
+	if anyNull(strs) {
+		return Null(text("", unicode).Type), nil
+	}
+	runes := []rune(strs[0].Text())
+	for i, c := range runes {
+		for j, f := range from {
+			if c == f {
+				runes[i] = to[j]
+				break
+			}
+		}
+	}
+	return textLike(strs[0], string(runes)), nil
+}
+
+// stuff deletes length characters from s at start and inserts ins there.
+// It returns NULL when start is outside s or length is negative.
+func stuff(args []Value) (Value, error) {
+	strs, unicode, err := stringArgs([]Value{args[0], args[3]})
+	if err != nil {
+		return Value{}, err
+	}
+	null := Null(text("", unicode).Type)
+	if anyNull(args[:3]) {
+		return null, nil
+	}
+	start, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	n, err := intArg(args[2])
+	if err != nil {
+		return Value{}, err
+	}
+	runes := []rune(strs[0].Text())
+	if start < 1 || start > int64(len(runes)) || n < 0 {
+		return null, nil
+	}
+	end := min(start-1+n, int64(len(runes)))
+	s := string(runes[:start-1]) + strs[1].Text() + string(runes[end:])
+	return text(s, unicode), nil
+}
+
+// concatFunc implements CONCAT, which treats NULL as an empty string.
+func concatFunc(args []Value) (Value, error) {
+	strs, unicode, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	var b strings.Builder
+	for _, s := range strs {
+		b.WriteString(s.Text())
+	}
+	return text(b.String(), unicode), nil
+}
+
+// concatWS implements CONCAT_WS, which skips NULL arguments.
+func concatWS(args []Value) (Value, error) {
+	strs, unicode, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	var parts []string
+	for _, s := range strs[1:] {
+		if !s.Null {
+			parts = append(parts, s.Text())
+		}
+	}
+	return text(strings.Join(parts, strs[0].Text()), unicode), nil
+}
+
+// replicate repeats a string. Results of non-MAX strings are cut at 8000
+// bytes.
+func replicate(args []Value) (Value, error) {
+	v, err := stringArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if anyNull(args) {
+		return nullText(v), nil
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return Value{}, err
+	}
+	if n < 0 {
+		return nullText(v), nil
+	}
+	runes := []rune(strings.Repeat(v.Text(), int(n)))
+	if limit := (Type{Kind: v.Type.Kind}).maxLength(); v.Type.Length != MaxLength && len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return textLike(v, string(runes)), nil
+}
+
+func space(args []Value) (Value, error) {
+	if args[0].Null {
+		return Null(Type{Kind: KindVarChar, Length: 1}), nil
+	}
+	n, err := intArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if n < 0 {
+		return Null(Type{Kind: KindVarChar, Length: 1}), nil
+	}
+	return VarChar(strings.Repeat(" ", int(min(n, 8000)))), nil
+}
+
+// firstRune returns the first character of a string argument, and false
+// if it is NULL or empty.
+func firstRune(v Value) (rune, bool, error) {
+	v, err := stringArg(v)
+	if err != nil || v.Null || v.Text() == "" {
+		return 0, false, err
+	}
+	return []rune(v.Text())[0], true, nil
+}
+
+func ascii(args []Value) (Value, error) {
+	c, ok, err := firstRune(args[0])
+	if err != nil || !ok {
+		return Null(typeInt), err
+	}
+	if c > 0xff {
+		c = '?'
+	}
+	return Int(int64(c)), nil
+}
+
+func unicodeFunc(args []Value) (Value, error) {
+	c, ok, err := firstRune(args[0])
+	if err != nil || !ok {
+		return Null(typeInt), err
+	}
+	return Int(int64(c)), nil
+}
+
+func char(args []Value) (Value, error) {
+	t := Type{Kind: KindChar, Length: 1}
+	if args[0].Null {
+		return Null(t), nil
+	}
+	n, err := intArg(args[0])
+	if err != nil || n < 0 || n > 0xff {
+		return Null(t), err
+	}
+	return Value{Type: t, data: string(rune(n))}, nil
+}
+
+func nchar(args []Value) (Value, error) {
+	t := Type{Kind: KindNChar, Length: 1}
+	if args[0].Null {
+		return Null(t), nil
+	}
+	n, err := intArg(args[0])
+	if err != nil || n < 0 || n > unicode.MaxRune || utf16.IsSurrogate(rune(n)) {
+		return Null(t), err
+	}
+	if n > 0xffff {
+		t.Length = 2
+	}
+	return Value{Type: t, data: string(rune(n))}, nil
+}
+
+// str formats a number right-aligned in length characters (10 by default)
+// with decimals digits after the point (0 by default). Decimals are
+// dropped to make the number fit; if its integer part does not fit the
+// result is all asterisks.
+func str(args []Value) (Value, error) {
+	size, decimals := int64(10), int64(0)
+	var err error
+	if len(args) > 1 && !args[1].Null {
+		if size, err = intArg(args[1]); err != nil {
+			return Value{}, err
+		}
+	}
+	if len(args) > 2 && !args[2].Null {
+		if decimals, err = intArg(args[2]); err != nil {
+			return Value{}, err
+		}
+	}
+	if anyNull(args) || size < 1 || size > 8000 || decimals < 0 {
+		return Null(Type{Kind: KindVarChar, Length: 10}), nil
+	}
+	t := Type{Kind: KindVarChar, Length: int(size)}
+	f, err := floatArg(args[0])
+	if err != nil {
+		return Value{}, err
+	}
+	decimals = min(decimals, 16)
+
+	whole := strconv.FormatFloat(math.Round(f), 'f', 0, 64)
+	if int64(len(whole)) > size {
+		return Value{Type: t, data: strings.Repeat("*", int(size))}, nil
+	}
+	if room := size - int64(len(whole)) - 1; decimals > room {
+		decimals = max(room, 0)
+	}
+	s := strconv.FormatFloat(f, 'f', int(decimals), 64)
+	if int64(len(s)) > size {
+		s = whole
+	}
+	return Value{Type: t, data: strings.Repeat(" ", int(size)-len(s)) + s}, nil
+}
+
+var quotePairs = map[rune]rune{
+	'[': ']', ']': ']', '"': '"', '\'': '\'', '(': ')', ')': ')',
+	'<': '>', '>': '>', '{': '}', '}': '}', '`': '`',
+}
+
+// quoteName delimits a name, doubling the closing delimiter inside it.
+// Names longer than 128 characters and unknown delimiters give NULL.
+func quoteName(args []Value) (Value, error) {
+	t := Type{Kind: KindNVarChar, Length: 258}
+	strs, _, err := stringArgs(args)
+	if err != nil || anyNull(strs) {
+		return Null(t), err
+	}
+	open := '['
+	if len(strs) == 2 {
+		r := []rune(strs[1].Text())
+		if len(r) != 1 {
+			return Null(t), nil
+		}
+		open = r[0]
+	}
+	closing, ok := quotePairs[open]
+	if !ok || len([]rune(strs[0].Text())) > 128 {
+		return Null(t), nil
+	}
+	if open == ']' || open == ')' || open == '>' || open == '}' {
+		open = map[rune]rune{']': '[', ')': '(', '>': '<', '}': '{'}[open]
+	}
+	s := string(open) + strings.ReplaceAll(strs[0].Text(), string(closing), string(closing)+string(closing)) + string(closing)
+	return Value{Type: t, data: s}, nil
+}
+
+var soundexCodes = map[rune]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// soundex returns the four-character code SOUNDEX gives s. Vowels separate
+// repeated codes; H and W do not.
+func soundex(s string) string {
+	var letters []rune
+	for _, c := range strings.ToUpper(s) {
+		if c >= 'A' && c <= 'Z' {
+			letters = append(letters, c)
+		} else if len(letters) > 0 {
+			break
+		}
+	}
+	if len(letters) == 0 {
+		return "0000"
+	}
+	code := []byte{byte(letters[0])}
+	last := soundexCodes[letters[0]]
+	for _, c := range letters[1:] {
+		if len(code) == 4 {
+			break
+		}
+		d, ok := soundexCodes[c]
+		switch {
+		case ok && d != last:
+			code = append(code, d)
+			last = d
+		case !ok && c != 'H' && c != 'W':
+			last = 0
+		}
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+func soundexFunc(args []Value) (Value, error) {
+	t := Type{Kind: KindVarChar, Length: 4}
+	v, err := stringArg(args[0])
+	if err != nil || v.Null {
+		return Null(t), err
+	}
+	return Value{Type: t, data: soundex(v.Text())}, nil
+}
+
+// difference counts the positions at which the SOUNDEX codes of two
+// strings agree, from 0 to 4.
+func difference(args []Value) (Value, error) {
+	strs, _, err := stringArgs(args)
+	if err != nil || anyNull(strs) {
+		return Null(typeInt), err
+	}
+	a, b := soundex(strs[0].Text()), soundex(strs[1].Text())
+	n := 0
+	for i := range 4 {
+		if a[i] == b[i] {
+			n++
+		}
+	}
+	return Int(int64(n)), nil
+}
+
+// stringEscape escapes text for inclusion in a JSON string, the only type
+// STRING_ESCAPE supports.
+func stringEscape(args []Value) (Value, error) {
+	strs, _, err := stringArgs(args)
+	if err != nil {
+		return Value{}, err
+	}
+	if !strs[1].Null && !strings.EqualFold(strs[1].Text(), "json") {
+		return Value{}, sqlError(13606, "Invalid type parameter '%s' for STRING_ESCAPE function.", strs[1].Text())
+	}
+	if anyNull(strs) {
+		return Null(Type{Kind: KindNVarChar, Length: MaxLength}), nil
+	}
+	var b strings.Builder
+	for _, c := range strs[0].Text() {
+		switch c {
+		case '"', '\\', '/':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteString(strconv.FormatInt(int64(c)+0x100, 16)[1:])
+				continue
+			}
+			b.WriteRune(c)
+		}
+	}
+	return NVarChar(b.String()), nil
+}
+
+// StringSplit returns the rows STRING_SPLIT produces: one per substring
+// of s between occurrences of separator, which must be one character.
+// Each row holds the substring and, when ordinal is set, its BIGINT
+// position. A NULL s gives no rows.
+func StringSplit(s, separator Value, ordinal bool) ([][]Value, error) {
+	strs, _, err := stringArgs([]Value{s, separator})
+	if err != nil {
+		return nil, err
+	}
+	sep := []rune(strs[1].Text())
+	if strs[1].Null || len(sep) != 1 {
+		return nil, sqlError(214, "Procedure expects parameter 'separator' of type 'nchar(1)/nvarchar(1)'.")
+	}
+	if strs[0].Null {
+		return nil, nil
+	}
+	var rows [][]Value
+	for i, part := range strings.Split(strs[0].Text(), string(sep)) {
+		v := Value{Type: strs[0].Type, data: part, collation: strs[0].collation}
+		if v.Type.Kind == KindChar || v.Type.Kind == KindNChar {
+			v.Type.Kind++
+		}
+		row := []Value{v}
+		if ordinal {
+			row = append(row, BigInt(int64(i+1)))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// StringAgg concatenates the non-NULL values with separator between them,
+// as the STRING_AGG aggregate does. The result is NULL if every value is.
+// Results of non-MAX types are limited to 8000 bytes.
+func StringAgg(values []Value, separator Value) (Value, error) {
+	t := Type{Kind: KindNVarChar, Length: 4000}
+	unicode := separator.Type.Kind.isUnicode()
+	for _, v := range values {
+		k := v.Type.Kind
+		switch {
+		case k.isString() && v.Type.Length == MaxLength:
+			t = Type{Kind: KindNVarChar, Length: MaxLength}
+		case k.isString() || k == KindNull:
+		case k.isBinary() || k == KindUniqueIdentifier:
+			return Value{}, invalidArgument(v, 1, "string_agg")
+		default:
+			unicode = true
+		}
+		unicode = unicode || k.isUnicode()
+	}
+	if !unicode {
+		t.Kind = KindVarChar
+		if t.Length != MaxLength {
+			t.Length = 8000
+		}
+	}
+
+	sep := ""
+	if !separator.Null {
+		s, err := stringArg(separator)
+		if err != nil {
+			return Value{}, err
+		}
+		sep = s.Text()
+	}
+	var parts []string
+	for _, v := range values {
+		if v.Null {
+			continue
+		}
+		s, err := stringArg(v)
+		if err != nil {
+			return Value{}, err
+		}
+		parts = append(parts, s.Text())
+	}
+	if parts == nil {
+		return Null(t), nil
+	}
+	r := Value{Type: t, data: strings.Join(parts, sep)}
+	if t.Length != MaxLength && len(encodeString(r)) > 8000 {
+		return Value{}, sqlError(9829, "STRING_AGG aggregation result exceeded the limit of 8000 bytes. Use LOB types to avoid result truncation.")
+	}
+	return r, nil
+}
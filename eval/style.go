@@ -0,0 +1,281 @@
+package eval
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConvertStyle performs CONVERT with a style code, which selects the text
+// format of dates and times, money, floats and binary values. Style 0 is
+// the same as Convert.
+func ConvertStyle(v Value, t Type, style int) (Value, error) {
+	from := v.Type.Kind
+	if style == 0 || v.Null || from == KindBoolean {
+		return Convert(v, t)
+	}
+	switch {
+	case t.Kind.isString() && from.isTemporal():
+		s, err := formatDateStyle(v, style)
+		if err != nil {
+			return Value{}, err
+		}
+		return Convert(VarChar(s), t)
+	case t.Kind.isString() && from.isMoney():
+		s, ok := formatMoneyStyle(v.Decimal(), style)
+		if !ok {
+			return Value{}, invalidStyle(style, from)
+		}
+		return Convert(VarChar(s), t)
+	case t.Kind.isString() && from.isFloat():
+		s, ok := formatFloatStyle(v.Float64(), style)
+		if !ok {
+			return Value{}, invalidStyle(style, from)
+		}
+		return Convert(VarChar(s), t)
+	case t.Kind.isString() && from.isBinary():
+		h := strings.ToUpper(hex.EncodeToString(v.Bytes()))
+		switch style {
+		case 1:
+			return Convert(VarChar("0x"+h), t)
+		case 2:
+			return Convert(VarChar(h), t)
+		}
+		return Value{}, invalidStyle(style, from)
+	case t.Kind.isBinary() && from.isString():
+		if style != 1 && style != 2 {
+			return Value{}, unsupportedStyle(style, from, t.Kind)
+		}
+		s := strings.TrimSpace(v.Text())
+		if style == 1 {
+			if !strings.HasPrefix(strings.ToLower(s), "0x") {
+				return Value{}, sqlError(8114, "Error converting data type %s to %s.", from.messageName(), t.Kind.messageName())
+			}
+			s = s[2:]
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return Value{}, sqlError(8114, "Error converting data type %s to %s.", from.messageName(), t.Kind.messageName())
+		}
+		return Convert(VarBinary(b), t)
+	case t.Kind.isTemporal() && from.isString():
+		text, ok := reorderDate(v.Text(), style)
+		if !ok {
+			return Value{}, unsupportedStyle(style, from, t.Kind)
+		}
+		return Convert(VarChar(text), t)
+	}
+	return Convert(v, t)
+}
+
+func invalidStyle(style int, from Kind) *Error {
+	return sqlError(281, "%d is not a valid style number when converting from %s to a character string.",
+		style, from.messageName())
+}
+
+func unsupportedStyle(style int, from, to Kind) *Error {
+	return sqlError(9809, "The style %d is not supported for conversions from %s to %s.",
+		style, from.messageName(), to.messageName())
+}
+
+// formatDateStyle formats a date or time value with a CONVERT style.
+// Styles below 100 write two-digit years where the century styles write
+// four.
+func formatDateStyle(v Value, style int) (string, error) {
+	t := v.Time()
+	k := v.Type.Kind
+	digits := v.Type.Scale
+	if k.isLegacyDateTime() || k == KindDate {
+		digits = 3
+	}
+	frac := fmt.Sprintf("%09d", t.Nanosecond())[:digits]
+	hour12 := t.Hour() % 12
+	if hour12 == 0 {
+		hour12 = 12
+	}
+	yy := fmt.Sprintf("%02d", t.Year()%100)
+	yyyy := fmt.Sprintf("%04d", t.Year())
+	year := yy
+	if style >= 100 {
+		year = yyyy
+	}
+	mm, dd := fmt.Sprintf("%02d", t.Month()), fmt.Sprintf("%02d", t.Day())
+	mon := t.Format("Jan")
+	hms := t.Format("15:04:05")
+	offset := ""
+	if k == KindDateTimeOffset {
+		offset = t.Format("-07:00")
+	}
+
+	switch style {
+	case 100:
+		return format(v), nil
+	case 1, 101:
+		return mm + "/" + dd + "/" + year, nil
+	case 2, 102:
+		return year + "." + mm + "." + dd, nil
+	case 3, 103:
+		return dd + "/" + mm + "/" + year, nil
+	case 4, 104:
+		return dd + "." + mm + "." + year, nil
+	case 5, 105:
+		return dd + "-" + mm + "-" + year, nil
+	case 6, 106:
+		return dd + " " + mon + " " + year, nil
+	case 7, 107:
+		return mon + " " + dd + ", " + year, nil
+	case 8, 24, 108:
+		return hms, nil
+	case 9, 109:
+		return fmt.Sprintf("%s %2d %s %2d:%02d:%02d:%s%s", mon, t.Day(), yyyy, hour12, t.Minute(), t.Second(), frac, t.Format("PM")), nil
+	case 10, 110:
+		return mm + "-" + dd + "-" + year, nil
+	case 11, 111:
+		return year + "/" + mm + "/" + dd, nil
+	case 12, 112:
+		return year + mm + dd, nil
+	case 13, 113:
+		return dd + " " + mon + " " + yyyy + " " + hms + ":" + frac, nil
+	case 14, 114:
+		return hms + ":" + frac, nil
+	case 20, 120:
+		return strings.TrimSpace(yyyy + "-" + mm + "-" + dd + " " + hms + " " + offset), nil
+	case 21, 25, 121:
+		if frac != "" {
+			frac = "." + frac
+		}
+		return strings.TrimSpace(yyyy + "-" + mm + "-" + dd + " " + hms + frac + " " + offset), nil
+	case 22:
+		return fmt.Sprintf("%s/%s/%s %2d:%02d:%02d %s", mm, dd, yy, hour12, t.Minute(), t.Second(), t.Format("PM")), nil
+	case 23:
+		return yyyy + "-" + mm + "-" + dd, nil
+	case 126, 127:
+		if style == 127 && k == KindDateTimeOffset {
+			t = t.UTC()
+			offset = "Z"
+		}
+		// The fraction is left out when it is zero
+		s := t.Format("2006-01-02T15:04:05")
+		if strings.Trim(frac, "0") != "" {
+			s += "." + frac
+		}
+		return s + offset, nil
+	case 130, 131:
+		return "", fmt.Errorf("eval: CONVERT style %d (Hijri calendar) is not supported", style)
+	}
+	return "", invalidStyle(style, k)
+}
+
+// formatMoneyStyle formats money with style 1 (thousands separators, two
+// decimals) or style 2 or 126 (four decimals).
+func formatMoneyStyle(d Decimal, style int) (string, bool) {
+	switch style {
+	case 1:
+		s := d.Rescale(2).String()
+		neg := strings.HasPrefix(s, "-")
+		whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+		s = groupDigits(whole, ",") + "." + frac
+		if neg {
+			s = "-" + s
+		}
+		return s, true
+	case 2, 126:
+		return d.Rescale(4).String(), true
+	}
+	return "", false
+}
+
+// groupDigits inserts sep between groups of three digits.
+func groupDigits(digits, sep string) string {
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// formatFloatStyle formats a float with style 1 (8 digits), 2 or 126 (16
+// digits) or 3 (17 digits), always in scientific notation except style 3.
+func formatFloatStyle(f float64, style int) (string, bool) {
+	var s string
+	switch style {
+	case 1:
+		s = strconv.FormatFloat(f, 'e', 7, 64)
+	case 2, 126:
+		s = strconv.FormatFloat(f, 'e', 15, 64)
+	case 3:
+		return strconv.FormatFloat(f, 'g', 17, 64), true
+	default:
+		return "", false
+	}
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign, digits := exp[:1], exp[1:]
+	for len(digits) < 3 {
+		digits = "0" + digits
+	}
+	return mantissa + "e" + sign + digits, true
+}
+
+var (
+	numericDate = regexp.MustCompile(`^\s*(\d{1,4})[/.\- ](\d{1,2})[/.\- ](\d{1,4})(.*)$`)
+	shortDate   = regexp.MustCompile(`^\s*(\d{2})(\d{2})(\d{2})\s*$`)
+)
+
+// reorderDate rewrites a string date written in the field order of a
+// CONVERT style as year-month-day, expanding two-digit years with the
+// default cutoff of 2049.
+func reorderDate(s string, style int) (string, bool) {
+	var order string
+	switch style {
+	case 1, 101, 10, 110, 22:
+		order = "mdy"
+	case 3, 103, 4, 104, 5, 105:
+		order = "dmy"
+	case 2, 102, 11, 111, 20, 120, 21, 121, 23, 25, 126, 127:
+		order = "ymd"
+	case 12, 112:
+		if m := shortDate.FindStringSubmatch(s); m != nil {
+			return expandYear(m[1]) + "-" + m[2] + "-" + m[3], true
+		}
+		return s, true
+	case 100, 6, 106, 7, 107, 8, 24, 108, 9, 109, 13, 113, 14, 114:
+		return s, true
+	default:
+		return "", false
+	}
+	m := numericDate.FindStringSubmatch(s)
+	if m == nil {
+		return s, true
+	}
+	var y, mo, d string
+	switch order {
+	case "mdy":
+		mo, d, y = m[1], m[2], m[3]
+	case "dmy":
+		d, mo, y = m[1], m[2], m[3]
+	default:
+		y, mo, d = m[1], m[2], m[3]
+	}
+	if len(y) <= 2 {
+		y = expandYear(y)
+	}
+	rest := m[4]
+	if strings.HasPrefix(rest, "T") {
+		rest = " " + rest[1:]
+	}
+	pad := func(s string) string { return strings.Repeat("0", max(2-len(s), 0)) + s }
+	return y + "-" + pad(mo) + "-" + pad(d) + rest, true
+}
+
+func expandYear(yy string) string {
+	n, _ := strconv.Atoi(yy)
+	if n < 50 {
+		return strconv.Itoa(2000 + n)
+	}
+	return strconv.Itoa(1900 + n)
+}
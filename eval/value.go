@@ -0,0 +1,531 @@
+// Package eval evaluates T-SQL scalar expressions with SQL Server semantics.
+//
+// Values carry their SQL data type, and operators follow the server's
+// rules: NULL propagates through three-valued logic, integer arithmetic
+// raises overflow errors instead of wrapping, mixed-type operands are
+// converted by data type precedence, and string comparison ignores case
+// and trailing spaces unless a case-sensitive collation is applied.
+//
+//	e := eval.New()
+//	e.Set("@qty", eval.Int(3))
+//	v, err := e.Eval(expr) // expr parsed from "@qty * 2.5"
+//
+// Expressions that refer to columns, subqueries or unknown variables are
+// not constant; Eval reports ErrNotConstant for them and Fold leaves them
+// in place.
+package eval
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// Kind identifies a SQL Server data type. Kinds are declared in order of
+// data type precedence, lowest first.
+type Kind int
+
+const (
+	KindNull    Kind = iota // Untyped NULL literal
+	KindBoolean             // Result of a predicate; not a storable type
+	KindBinary
+	KindVarBinary
+	KindChar
+	KindVarChar
+	KindNChar
+	KindNVarChar
+	KindUniqueIdentifier
+	KindBit
+	KindTinyInt
+	KindSmallInt
+	KindInt
+	KindBigInt
+	KindSmallMoney
+	KindMoney
+	KindDecimal
+	KindReal
+	KindFloat
+	KindTime
+	KindDate
+	KindSmallDateTime
+	KindDateTime
+	KindDateTime2
+	KindDateTimeOffset
+)
+
+var kindNames = [...]string{
+	KindNull:             "NULL",
+	KindBoolean:          "BOOLEAN",
+	KindBinary:           "BINARY",
+	KindVarBinary:        "VARBINARY",
+	KindChar:             "CHAR",
+	KindVarChar:          "VARCHAR",
+	KindNChar:            "NCHAR",
+	KindNVarChar:         "NVARCHAR",
+	KindUniqueIdentifier: "UNIQUEIDENTIFIER",
+	KindBit:              "BIT",
+	KindTinyInt:          "TINYINT",
+	KindSmallInt:         "SMALLINT",
+	KindInt:              "INT",
+	KindBigInt:           "BIGINT",
+	KindSmallMoney:       "SMALLMONEY",
+	KindMoney:            "MONEY",
+	KindDecimal:          "DECIMAL",
+	KindReal:             "REAL",
+	KindFloat:            "FLOAT",
+	KindTime:             "TIME",
+	KindDate:             "DATE",
+	KindSmallDateTime:    "SMALLDATETIME",
+	KindDateTime:         "DATETIME",
+	KindDateTime2:        "DATETIME2",
+	KindDateTimeOffset:   "DATETIMEOFFSET",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "UNKNOWN"
+}
+
+// messageName is the type name SQL Server uses in error messages.
+func (k Kind) messageName() string {
+	if k == KindDecimal {
+		return "numeric"
+	}
+	return strings.ToLower(k.String())
+}
+
+func (k Kind) isString() bool  { return k >= KindChar && k <= KindNVarChar }
+func (k Kind) isUnicode() bool { return k == KindNChar || k == KindNVarChar }
+func (k Kind) isBinary() bool  { return k == KindBinary || k == KindVarBinary }
+func (k Kind) isInteger() bool { return k >= KindBit && k <= KindBigInt }
+func (k Kind) isMoney() bool   { return k == KindSmallMoney || k == KindMoney }
+func (k Kind) isExact() bool   { return k >= KindBit && k <= KindDecimal }
+func (k Kind) isNumeric() bool { return k >= KindBit && k <= KindFloat }
+func (k Kind) isFloat() bool   { return k == KindReal || k == KindFloat }
+func (k Kind) isTemporal() bool {
+	return k >= KindTime && k <= KindDateTimeOffset
+}
+
+// isLegacyDateTime reports whether k is DATETIME or SMALLDATETIME, the
+// only date types that take part in arithmetic and convert to numbers.
+func (k Kind) isLegacyDateTime() bool {
+	return k == KindDateTime || k == KindSmallDateTime
+}
+
+// Type is a data type with its length, precision and scale.
+type Type struct {
+	Kind      Kind
+	Length    int // Characters or bytes for string and binary types; -1 for MAX
+	Precision int // DECIMAL precision
+	Scale     int // DECIMAL scale; fractional second digits for TIME, DATETIME2 and DATETIMEOFFSET
+}
+
+// MaxLength is the Length of a (MAX) string or binary type.
+const MaxLength = -1
+
+var (
+	typeBoolean  = Type{Kind: KindBoolean}
+	typeBit      = Type{Kind: KindBit}
+	typeInt      = Type{Kind: KindInt}
+	typeBigInt   = Type{Kind: KindBigInt}
+	typeFloat    = Type{Kind: KindFloat}
+	typeMoney    = Type{Kind: KindMoney, Precision: 19, Scale: 4}
+	typeDateTime = Type{Kind: KindDateTime}
+)
+
+func (t Type) String() string {
+	name := t.Kind.String()
+	switch {
+	case t.Kind.isString() || t.Kind.isBinary():
+		if t.Length == MaxLength {
+			return name + "(MAX)"
+		}
+		return name + "(" + strconv.Itoa(t.Length) + ")"
+	case t.Kind == KindDecimal:
+		return name + "(" + strconv.Itoa(t.Precision) + ", " + strconv.Itoa(t.Scale) + ")"
+	case t.Kind == KindTime || t.Kind == KindDateTime2 || t.Kind == KindDateTimeOffset:
+		return name + "(" + strconv.Itoa(t.Scale) + ")"
+	}
+	return name
+}
+
+// DataType returns the AST form of t.
+func (t Type) DataType() *ast.DataType {
+	dt := &ast.DataType{Name: t.Kind.String()}
+	switch {
+	case t.Kind.isString() || t.Kind.isBinary():
+		if t.Length == MaxLength {
+			dt.Max = true
+		} else {
+			length := t.Length
+			dt.Precision = &length
+		}
+	case t.Kind == KindDecimal:
+		precision, scale := t.Precision, t.Scale
+		dt.Precision, dt.Scale = &precision, &scale
+	case t.Kind == KindTime || t.Kind == KindDateTime2 || t.Kind == KindDateTimeOffset:
+		scale := t.Scale
+		dt.Precision = &scale
+	}
+	return dt
+}
+
+// maxLength is the longest non-MAX length of a string or binary kind.
+func (t Type) maxLength() int {
+	if t.Kind.isUnicode() {
+		return 4000
+	}
+	return 8000
+}
+
+// TypeOf resolves a declared data type. Lengths default as they do in a
+// DECLARE: CHAR and VARCHAR without a length hold one character.
+func TypeOf(dt *ast.DataType) (Type, error) {
+	return typeOf(dt, 1)
+}
+
+// typeOf resolves dt, giving string and binary types without a length
+// defaultLength; CAST and CONVERT use 30.
+func typeOf(dt *ast.DataType, defaultLength int) (Type, error) {
+	var t Type
+	length := func() int {
+		if dt.Max {
+			return MaxLength
+		}
+		if dt.Precision != nil {
+			return *dt.Precision
+		}
+		if dt.Length != nil {
+			return *dt.Length
+		}
+		return defaultLength
+	}
+	fraction := func() int {
+		if dt.Precision != nil {
+			return *dt.Precision
+		}
+		return 7
+	}
+
+	switch dt.Name {
+	case "BIT":
+		t.Kind = KindBit
+	case "TINYINT":
+		t.Kind = KindTinyInt
+	case "SMALLINT":
+		t.Kind = KindSmallInt
+	case "INT", "INTEGER":
+		t.Kind = KindInt
+	case "BIGINT":
+		t.Kind = KindBigInt
+	case "DECIMAL", "DEC", "NUMERIC":
+		t = Type{Kind: KindDecimal, Precision: 18}
+		if dt.Precision != nil {
+			t.Precision = *dt.Precision
+		}
+		if dt.Scale != nil {
+			t.Scale = *dt.Scale
+		}
+		if t.Precision < 1 || t.Precision > 38 || t.Scale < 0 || t.Scale > t.Precision {
+			return Type{}, fmt.Errorf("eval: invalid precision or scale in %s", dt)
+		}
+	case "MONEY":
+		t = typeMoney
+	case "SMALLMONEY":
+		t = Type{Kind: KindSmallMoney, Precision: 10, Scale: 4}
+	case "FLOAT":
+		t.Kind = KindFloat
+		if dt.Precision != nil && *dt.Precision <= 24 {
+			t.Kind = KindReal
+		}
+	case "REAL":
+		t.Kind = KindReal
+	case "CHAR", "CHARACTER":
+		t = Type{Kind: KindChar, Length: length()}
+	case "VARCHAR":
+		t = Type{Kind: KindVarChar, Length: length()}
+	case "NCHAR":
+		t = Type{Kind: KindNChar, Length: length()}
+	case "NVARCHAR":
+		t = Type{Kind: KindNVarChar, Length: length()}
+	case "SYSNAME":
+		t = Type{Kind: KindNVarChar, Length: 128}
+	case "TEXT":
+		t = Type{Kind: KindVarChar, Length: MaxLength}
+	case "NTEXT":
+		t = Type{Kind: KindNVarChar, Length: MaxLength}
+	case "BINARY":
+		t = Type{Kind: KindBinary, Length: length()}
+	case "VARBINARY":
+		t = Type{Kind: KindVarBinary, Length: length()}
+	case "IMAGE":
+		t = Type{Kind: KindVarBinary, Length: MaxLength}
+	case "ROWVERSION", "TIMESTAMP":
+		t = Type{Kind: KindBinary, Length: 8}
+	case "UNIQUEIDENTIFIER":
+		t.Kind = KindUniqueIdentifier
+	case "DATE":
+		t.Kind = KindDate
+	case "TIME":
+		t = Type{Kind: KindTime, Scale: fraction()}
+	case "DATETIME":
+		t.Kind = KindDateTime
+	case "SMALLDATETIME":
+		t.Kind = KindSmallDateTime
+	case "DATETIME2":
+		t = Type{Kind: KindDateTime2, Scale: fraction()}
+	case "DATETIMEOFFSET":
+		t = Type{Kind: KindDateTimeOffset, Scale: fraction()}
+	default:
+		return Type{}, fmt.Errorf("eval: unsupported data type %s", dt)
+	}
+	if (t.Kind.isString() || t.Kind.isBinary()) && t.Length != MaxLength {
+		if t.Length < 1 || t.Length > t.maxLength() {
+			return Type{}, fmt.Errorf("eval: invalid length in %s", dt)
+		}
+	}
+	if t.Scale > 7 && t.Kind.isTemporal() {
+		return Type{}, fmt.Errorf("eval: invalid fractional seconds precision in %s", dt)
+	}
+	return t, nil
+}
+
+// Value is a typed SQL value.
+type Value struct {
+	Type Type
+	Null bool
+
+	// data holds bool for BOOLEAN, int64 for BIT and the integer types,
+	// Decimal for DECIMAL and MONEY, float64 for FLOAT and REAL, string
+	// for character types and UNIQUEIDENTIFIER, []byte for binary types
+	// and time.Time for the date and time types.
+	data      any
+	collation string
+	constant  bool // An integer literal, which takes part in DECIMAL arithmetic at its own precision
+}
+
+// Null returns a NULL of type t.
+func Null(t Type) Value { return Value{Type: t, Null: true} }
+
+// Bool returns the result of a predicate.
+func Bool(b bool) Value { return Value{Type: typeBoolean, data: b} }
+
+// Unknown returns the UNKNOWN result of a predicate.
+func Unknown() Value { return Null(typeBoolean) }
+
+// Bit returns a BIT value.
+func Bit(b bool) Value {
+	if b {
+		return Value{Type: typeBit, data: int64(1)}
+	}
+	return Value{Type: typeBit, data: int64(0)}
+}
+
+// Int returns an INT value, or a BIGINT if i does not fit in an INT.
+func Int(i int64) Value {
+	if i < -1<<31 || i > 1<<31-1 {
+		return BigInt(i)
+	}
+	return Value{Type: typeInt, data: i}
+}
+
+// BigInt returns a BIGINT value.
+func BigInt(i int64) Value { return Value{Type: typeBigInt, data: i} }
+
+// Float returns a FLOAT value.
+func Float(f float64) Value { return Value{Type: typeFloat, data: f} }
+
+// Numeric returns a DECIMAL value just wide enough to hold d.
+func Numeric(d Decimal) Value {
+	return Value{Type: Type{Kind: KindDecimal, Precision: d.Precision(), Scale: d.Scale()}, data: d}
+}
+
+// Money returns a MONEY value, rounding d to four decimal places.
+func Money(d Decimal) Value {
+	return Value{Type: typeMoney, data: d.Rescale(4)}
+}
+
+// VarChar returns a VARCHAR value as long as s.
+func VarChar(s string) Value {
+	return Value{Type: Type{Kind: KindVarChar, Length: stringLength(len([]rune(s)), 8000)}, data: s}
+}
+
+// NVarChar returns an NVARCHAR value as long as s.
+func NVarChar(s string) Value {
+	return Value{Type: Type{Kind: KindNVarChar, Length: stringLength(len([]rune(s)), 4000)}, data: s}
+}
+
+// VarBinary returns a VARBINARY value as long as b.
+func VarBinary(b []byte) Value {
+	return Value{Type: Type{Kind: KindVarBinary, Length: stringLength(len(b), 8000)}, data: b}
+}
+
+func stringLength(n, limit int) int {
+	switch {
+	case n == 0:
+		return 1
+	case n > limit:
+		return MaxLength
+	}
+	return n
+}
+
+// Date returns a DATE value.
+func Date(t time.Time) Value {
+	return Value{Type: Type{Kind: KindDate}, data: roundTime(t, KindDate, 0)}
+}
+
+// DateTime returns a DATETIME value, rounded to the type's 1/300 second
+// accuracy.
+func DateTime(t time.Time) Value {
+	return Value{Type: typeDateTime, data: roundTime(t, KindDateTime, 0)}
+}
+
+// DateTime2 returns a DATETIME2(7) value.
+func DateTime2(t time.Time) Value {
+	return Value{Type: Type{Kind: KindDateTime2, Scale: 7}, data: roundTime(t, KindDateTime2, 7)}
+}
+
+// Bool reports whether a predicate is TRUE, or a BIT is 1.
+func (v Value) Bool() bool {
+	switch d := v.data.(type) {
+	case bool:
+		return !v.Null && d
+	case int64:
+		return !v.Null && d != 0
+	}
+	return false
+}
+
+// Int64 returns the value of a BIT or integer.
+func (v Value) Int64() int64 {
+	i, _ := v.data.(int64)
+	return i
+}
+
+// Float64 returns the value of a FLOAT or REAL.
+func (v Value) Float64() float64 {
+	f, _ := v.data.(float64)
+	return f
+}
+
+// Decimal returns the value of a DECIMAL or MONEY.
+func (v Value) Decimal() Decimal {
+	d, _ := v.data.(Decimal)
+	return d
+}
+
+// Text returns the value of a character type or UNIQUEIDENTIFIER.
+func (v Value) Text() string {
+	s, _ := v.data.(string)
+	return s
+}
+
+// Bytes returns the value of a binary type.
+func (v Value) Bytes() []byte {
+	b, _ := v.data.([]byte)
+	return b
+}
+
+// Time returns the value of a date or time type.
+func (v Value) Time() time.Time {
+	t, _ := v.data.(time.Time)
+	return t
+}
+
+// Collation returns the collation applied with COLLATE, if any.
+func (v Value) Collation() string { return v.collation }
+
+// String formats v the way query tools display it.
+func (v Value) String() string {
+	if v.Null {
+		return "NULL"
+	}
+	switch v.Type.Kind {
+	case KindBoolean:
+		if v.Bool() {
+			return "TRUE"
+		}
+		return "FALSE"
+	case KindBit, KindTinyInt, KindSmallInt, KindInt, KindBigInt:
+		return strconv.FormatInt(v.Int64(), 10)
+	case KindDecimal, KindMoney, KindSmallMoney:
+		return v.Decimal().String()
+	case KindReal:
+		return strconv.FormatFloat(v.Float64(), 'g', -1, 32)
+	case KindFloat:
+		return strconv.FormatFloat(v.Float64(), 'g', -1, 64)
+	case KindBinary, KindVarBinary:
+		return "0x" + strings.ToUpper(hex.EncodeToString(v.Bytes()))
+	case KindDate:
+		return v.Time().Format("2006-01-02")
+	case KindTime:
+		return v.Time().Format("15:04:05") + fraction(v.Time(), v.Type.Scale)
+	case KindSmallDateTime:
+		return v.Time().Format("2006-01-02 15:04:05")
+	case KindDateTime:
+		return v.Time().Format("2006-01-02 15:04:05.000")
+	case KindDateTime2:
+		return v.Time().Format("2006-01-02 15:04:05") + fraction(v.Time(), v.Type.Scale)
+	case KindDateTimeOffset:
+		return v.Time().Format("2006-01-02 15:04:05") + fraction(v.Time(), v.Type.Scale) + v.Time().Format(" -07:00")
+	}
+	return v.Text()
+}
+
+// fraction formats the fractional seconds of t to scale digits.
+func fraction(t time.Time, scale int) string {
+	if scale == 0 {
+		return ""
+	}
+	return "." + fmt.Sprintf("%09d", t.Nanosecond())[:scale]
+}
+
+// ErrNotConstant is reported for expressions whose value depends on
+// something other than literals and known variables.
+var ErrNotConstant = errors.New("eval: expression is not constant")
+
+func notConstant(expr ast.Expression) error {
+	return fmt.Errorf("%w: %s", ErrNotConstant, expr)
+}
+
+// Error is a run-time error raised by an expression, carrying the number
+// and message SQL Server would report.
+type Error struct {
+	Number   int
+	Severity int
+	State    int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Msg %d, Level %d: %s", e.Number, e.Severity, e.Message)
+}
+
+func sqlError(number int, format string, args ...any) *Error {
+	return &Error{Number: number, Severity: 16, State: 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func overflow(k Kind) *Error {
+	return sqlError(8115, "Arithmetic overflow error converting expression to data type %s.", k.messageName())
+}
+
+func divideByZero() *Error {
+	return sqlError(8134, "Divide by zero error encountered.")
+}
+
+func incompatible(a, b Kind, op string) *Error {
+	return sqlError(402, "The data types %s and %s are incompatible in the %s operator.",
+		a.messageName(), b.messageName(), op)
+}
+
+func clash(a, b Kind) *Error {
+	return sqlError(206, "Operand type clash: %s is incompatible with %s", a.messageName(), b.messageName())
+}
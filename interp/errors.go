@@ -0,0 +1,262 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+)
+
+func sqlError(number int, format string, args ...any) *eval.Error {
+	return &eval.Error{Number: number, Severity: 16, State: 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (in *Interpreter) throw(f *frame, x *ast.ThrowStatement) error {
+	if x.ErrorNum == nil {
+		if len(f.caught) == 0 {
+			return sqlError(10704, "To rethrow an error, a THROW statement must be used inside a CATCH block.")
+		}
+		return f.caught[len(f.caught)-1]
+	}
+	number, err := in.intValue(f, x.ErrorNum)
+	if err != nil {
+		return err
+	}
+	if number < 50000 {
+		return sqlError(35100, "Error number %d in the THROW statement is outside the valid range. Specify an error number in the valid range of 50000 to 2147483647.", number)
+	}
+	message, err := in.value(f, x.Message)
+	if err != nil {
+		return err
+	}
+	text, err := messageText(message)
+	if err != nil {
+		return err
+	}
+	state, err := in.intValue(f, x.State)
+	if err != nil {
+		return err
+	}
+	if state < 0 || state > 255 {
+		return sqlError(35100, "State %d in the THROW statement is outside the valid range. Specify a state in the valid range of 0 to 255.", state)
+	}
+	return &eval.Error{Number: number, Severity: 16, State: state, Message: text}
+}
+
+// raiserror raises an error of severity 11 or more. Lower severities only
+// add a message.
+func (in *Interpreter) raiserror(f *frame, x *ast.RaiserrorStatement) error {
+	severity, err := in.intValue(f, x.Severity)
+	if err != nil {
+		return err
+	}
+	state, err := in.intValue(f, x.State)
+	if err != nil {
+		return err
+	}
+	severity, state = min(max(severity, 0), 25), min(max(state, 0), 255)
+
+	msg, err := in.value(f, x.Message)
+	if err != nil {
+		return err
+	}
+	switch msg.Type.Kind {
+	case eval.KindTinyInt, eval.KindSmallInt, eval.KindInt, eval.KindBigInt:
+		// Message numbers refer to sys.messages, which a script cannot see
+		return sqlError(18054, "Error %d, severity %d, state %d was raised, but no message with that error number was found in sys.messages. If error is larger than 50000, make sure the user-defined message is added using sp_addmessage.",
+			msg.Int64(), severity, state)
+	}
+	format, err := messageText(msg)
+	if err != nil {
+		return err
+	}
+	args := make([]eval.Value, len(x.Args))
+	for i, arg := range x.Args {
+		if args[i], err = in.value(f, arg); err != nil {
+			return err
+		}
+	}
+	text, err := formatMessage(format, args)
+	if err != nil {
+		return err
+	}
+
+	setError := false
+	for _, opt := range x.Options {
+		setError = setError || strings.EqualFold(opt, "SETERROR")
+	}
+	if severity <= 10 {
+		in.out.Messages = append(in.out.Messages, text)
+		if setError {
+			in.lastError = 50000
+		}
+		return nil
+	}
+	return &eval.Error{Number: 50000, Severity: severity, State: state, Message: text}
+}
+
+func (in *Interpreter) intValue(f *frame, expr ast.Expression) (int, error) {
+	v, err := in.value(f, expr)
+	if err != nil {
+		return 0, err
+	}
+	if v, err = eval.Convert(v, eval.Type{Kind: eval.KindInt}); err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// formatMessage substitutes RAISERROR arguments into a message written
+// with printf-style specifications:
+// %[flags][width][.precision][h|l]type, where type is d, i, o, s, u, x or
+// X and an asterisk takes a width or precision from the arguments.
+func formatMessage(format string, args []eval.Value) (string, error) {
+	var b strings.Builder
+	arg := func() (eval.Value, bool) {
+		if len(args) == 0 {
+			return eval.Value{}, false
+		}
+		v := args[0]
+		args = args[1:]
+		return v, true
+	}
+	number := func() (string, error) {
+		v, ok := arg()
+		if !ok || v.Null {
+			return "", nil
+		}
+		n, err := eval.Convert(v, eval.Type{Kind: eval.KindInt})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(n.Int64()), nil
+	}
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+
+		start := i
+		spec := "%"
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+0# ", format[j]) >= 0 {
+			spec += format[j : j+1]
+			j++
+		}
+		for _, part := range []string{"", "."} {
+			if part != "" {
+				if j >= len(format) || format[j] != '.' {
+					break
+				}
+				spec += "."
+				j++
+			}
+			if j < len(format) && format[j] == '*' {
+				n, err := number()
+				if err != nil {
+					return "", err
+				}
+				spec += n
+				j++
+				continue
+			}
+			for j < len(format) && format[j] >= '0' && format[j] <= '9' {
+				spec += format[j : j+1]
+				j++
+			}
+		}
+		if j < len(format) && (format[j] == 'h' || format[j] == 'l') {
+			j++
+		}
+		if j >= len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+
+		verb := format[j]
+		i = j
+		v, ok := arg()
+		if !ok || v.Null {
+			b.WriteString("(null)")
+			continue
+		}
+		switch verb {
+		case 's':
+			text, err := messageText(v)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, spec+"s", text)
+		case 'd', 'i', 'u', 'o', 'x', 'X':
+			n, err := eval.Convert(v, eval.Type{Kind: eval.KindBigInt})
+			if err != nil {
+				return "", err
+			}
+			x := n.Int64()
+			switch verb {
+			case 'd', 'i':
+				fmt.Fprintf(&b, spec+"d", int32(x))
+			case 'u':
+				fmt.Fprintf(&b, spec+"d", uint32(x))
+			default:
+				fmt.Fprintf(&b, spec+string(verb), uint32(x))
+			}
+		default:
+			return "", sqlError(2787, "Invalid format specification: '%s'.", format[start:j+1])
+		}
+	}
+	return b.String(), nil
+}
+
+// statementLine returns the line a statement starts on, or 0 for
+// statements the interpreter does not report errors for.
+func statementLine(stmt ast.Statement) int {
+	switch x := stmt.(type) {
+	case *ast.SelectStatement:
+		return x.Token.Line
+	case *ast.InsertStatement:
+		return x.Token.Line
+	case *ast.UpdateStatement:
+		return x.Token.Line
+	case *ast.DeleteStatement:
+		return x.Token.Line
+	case *ast.MergeStatement:
+		return x.Token.Line
+	case *ast.WithStatement:
+		return x.Token.Line
+	case *ast.DeclareStatement:
+		return x.Token.Line
+	case *ast.SetStatement:
+		return x.Token.Line
+	case *ast.IfStatement:
+		return x.Token.Line
+	case *ast.WhileStatement:
+		return x.Token.Line
+	case *ast.ReturnStatement:
+		return x.Token.Line
+	case *ast.PrintStatement:
+		return x.Token.Line
+	case *ast.ExecStatement:
+		return x.Token.Line
+	case *ast.ThrowStatement:
+		return x.Token.Line
+	case *ast.RaiserrorStatement:
+		return x.Token.Line
+	case *ast.BeginTransactionStatement:
+		return x.Token.Line
+	case *ast.CommitTransactionStatement:
+		return x.Token.Line
+	case *ast.RollbackTransactionStatement:
+		return x.Token.Line
+	}
+	return 0
+}
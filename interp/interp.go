@@ -0,0 +1,826 @@
+// Package interp runs T-SQL batches: variables, control flow, error
+// handling and calls to procedures created by the script. Queries and
+// data modification are handed to a QueryExecutor that the host supplies,
+// so procedure logic can be exercised without a SQL Server.
+//
+//	in := interp.New(executor)
+//	if _, err := in.Run(program); err != nil { ... } // creates the procedures
+//	out, err := in.Exec("dbo.TransferFunds", map[string]eval.Value{
+//	    "@Amount": eval.Int(100),
+//	})
+//	fmt.Println(out.ReturnCode, out.Messages)
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+// QueryExecutor runs the statements the interpreter does not run itself:
+// SELECT, INSERT, UPDATE, DELETE and MERGE, table variable declarations,
+// and anything else that needs a database. scope holds the variables the
+// statement can read and assign.
+//
+// Errors of type *eval.Error are raised in the script and can be caught
+// by TRY...CATCH; any other error stops the run.
+type QueryExecutor interface {
+	Execute(stmt ast.Statement, scope *Scope) (*Result, error)
+}
+
+// Result is what a statement run by a QueryExecutor produced. A Result
+// with Columns is a result set; RowsAffected becomes @@ROWCOUNT.
+type Result struct {
+	Columns      []string
+	Rows         [][]eval.Value
+	RowsAffected int64
+}
+
+// Outcome collects the output of Run or Exec.
+type Outcome struct {
+	ReturnCode int
+	Results    []*Result             // Result sets in the order they were produced
+	Messages   []string              // PRINT output and RAISERROR messages of severity 10 or less
+	Output     map[string]eval.Value // OUTPUT parameters after Exec, by parameter name
+}
+
+// Error is an error raised by a script and not caught, with where it was
+// raised.
+type Error struct {
+	Number    int
+	Severity  int
+	State     int
+	Message   string
+	Procedure string // Empty for errors raised by the batch itself
+	Line      int
+}
+
+func (e *Error) Error() string {
+	if e.Procedure != "" {
+		return fmt.Sprintf("Msg %d, Level %d, State %d, Procedure %s, Line %d: %s",
+			e.Number, e.Severity, e.State, e.Procedure, e.Line, e.Message)
+	}
+	return fmt.Sprintf("Msg %d, Level %d, State %d, Line %d: %s", e.Number, e.Severity, e.State, e.Line, e.Message)
+}
+
+// maxNesting is SQL Server's limit on nested procedure calls.
+const maxNesting = 32
+
+// Interpreter runs batches and keeps the procedures they create. It is
+// not safe for concurrent use.
+type Interpreter struct {
+	// Queries runs data statements. When it is nil, a script that
+	// reaches one fails.
+	Queries QueryExecutor
+
+	// Now is the clock GETDATE and the other date functions read.
+	Now func() time.Time
+
+	procs map[string]*procedure
+	out   *Outcome
+
+	// Session state behind @@ROWCOUNT, @@ERROR, @@TRANCOUNT and
+	// @@NESTLEVEL
+	rowCount  int64
+	lastError int
+	tranCount int
+	nesting   int
+}
+
+type procedure struct {
+	name   string
+	params []*ast.ParameterDef
+	body   []ast.Statement
+}
+
+// New returns an Interpreter that hands data statements to q.
+func New(q QueryExecutor) *Interpreter {
+	return &Interpreter{
+		Queries: q,
+		Now:     time.Now,
+		procs:   make(map[string]*procedure),
+	}
+}
+
+// Run runs the batches of a script, which GO separates. Procedures the
+// script creates are kept for later calls to Exec. An error that is not
+// caught ends the run, as it would with SET XACT_ABORT ON; the Outcome
+// holds what was produced before it.
+func (in *Interpreter) Run(program *ast.Program) (*Outcome, error) {
+	in.out = &Outcome{}
+	var batch []ast.Statement
+	for _, stmt := range program.Statements {
+		g, ok := stmt.(*ast.GoStatement)
+		if !ok {
+			batch = append(batch, stmt)
+			continue
+		}
+		count := 1
+		if g.Count != nil {
+			count = *g.Count
+		}
+		for i := 0; i < count; i++ {
+			if err := in.batch(batch); err != nil {
+				return in.out, err
+			}
+		}
+		batch = nil
+	}
+	return in.out, in.batch(batch)
+}
+
+func (in *Interpreter) batch(stmts []ast.Statement) error {
+	if len(stmts) == 0 {
+		return nil
+	}
+	f := in.newFrame("")
+	_, err := in.body(f, stmts)
+	return err
+}
+
+// Exec calls a procedure created by an earlier Run. Arguments are passed
+// by parameter name, with the leading @; parameters left out take their
+// defaults.
+func (in *Interpreter) Exec(name string, args map[string]eval.Value) (*Outcome, error) {
+	in.out = &Outcome{}
+	p, ok := in.procs[procedureKey(name)]
+	if !ok {
+		return in.out, in.raised(nil, notFound(name))
+	}
+	supplied := make(map[int]eval.Value)
+	for argName, v := range args {
+		i := p.param(argName)
+		if i < 0 {
+			return in.out, in.raised(nil, sqlError(8145, "%s is not a parameter for procedure %s.", argName, p.name))
+		}
+		supplied[i] = v
+	}
+	f, err := in.invoke(p, supplied)
+	if err != nil {
+		return in.out, err
+	}
+	in.out.ReturnCode = f.code
+	in.out.Output = make(map[string]eval.Value)
+	for _, param := range p.params {
+		if param.Output {
+			in.out.Output[param.Name], _ = f.scope.Get(param.Name)
+		}
+	}
+	return in.out, nil
+}
+
+// procedureKey returns the name a procedure is known by: its lower-case
+// schema and name, with the schema defaulting to dbo. Database and server
+// names are left out.
+func procedureKey(name string) string {
+	parts := strings.Split(name, ".")
+	schema := "dbo"
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		schema = parts[len(parts)-2]
+	}
+	return strings.ToLower(strings.Trim(schema, "[]") + "." + strings.Trim(parts[len(parts)-1], "[]"))
+}
+
+func (p *procedure) param(name string) int {
+	for i, param := range p.params {
+		if strings.EqualFold(param.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func notFound(name string) *eval.Error {
+	return sqlError(2812, "Could not find stored procedure '%s'.", name)
+}
+
+// frame is a running batch or procedure.
+type frame struct {
+	scope  *Scope
+	proc   string
+	code   int      // RETURN value
+	label  string   // Target of a GOTO being resolved
+	line   int      // Line of the statement running
+	caught []*Error // Errors being handled, innermost last
+
+	// The DECLARE each variable comes from, so that one run again by a
+	// loop assigns its variables instead of declaring them twice
+	declares map[string]*ast.VariableDef
+}
+
+func (in *Interpreter) newFrame(proc string) *frame {
+	f := &frame{scope: newScope(), proc: proc, declares: make(map[string]*ast.VariableDef)}
+	e := f.scope.eval
+	e.Now = in.Now
+	handled := func(name string, get func(*Error) eval.Value, null eval.Value) {
+		e.Define(name, func(args []eval.Value) (eval.Value, error) {
+			if len(args) != 0 {
+				return eval.Value{}, sqlError(174, "The %s function requires 0 argument(s).", strings.ToLower(name))
+			}
+			if len(f.caught) == 0 {
+				return null, nil
+			}
+			return get(f.caught[len(f.caught)-1]), nil
+		})
+	}
+	nullInt := eval.Null(eval.Type{Kind: eval.KindInt})
+	nullText := eval.Null(eval.Type{Kind: eval.KindNVarChar, Length: 4000})
+	handled("ERROR_NUMBER", func(err *Error) eval.Value { return eval.Int(int64(err.Number)) }, nullInt)
+	handled("ERROR_SEVERITY", func(err *Error) eval.Value { return eval.Int(int64(err.Severity)) }, nullInt)
+	handled("ERROR_STATE", func(err *Error) eval.Value { return eval.Int(int64(err.State)) }, nullInt)
+	handled("ERROR_LINE", func(err *Error) eval.Value { return eval.Int(int64(err.Line)) }, nullInt)
+	handled("ERROR_MESSAGE", func(err *Error) eval.Value { return eval.NVarChar(err.Message) }, nullText)
+	handled("ERROR_PROCEDURE", func(err *Error) eval.Value {
+		if err.Procedure == "" {
+			return nullText
+		}
+		return eval.NVarChar(err.Procedure)
+	}, nullText)
+	e.Define("XACT_STATE", func(args []eval.Value) (eval.Value, error) {
+		if in.tranCount > 0 {
+			return eval.Int(1), nil
+		}
+		return eval.Int(0), nil
+	})
+	return f
+}
+
+// sync publishes the session state to the frame's @@ variables.
+func (in *Interpreter) sync(f *frame) {
+	e := f.scope.eval
+	e.Set("@@ROWCOUNT", eval.Int(in.rowCount))
+	e.Set("@@ERROR", eval.Int(int64(in.lastError)))
+	e.Set("@@TRANCOUNT", eval.Int(int64(in.tranCount)))
+	e.Set("@@NESTLEVEL", eval.Int(int64(in.nesting)))
+}
+
+// raised records where err was raised. SQL errors become *Error; other
+// errors are returned as they are.
+func (in *Interpreter) raised(f *frame, err error) error {
+	var done *Error
+	if errors.As(err, &done) {
+		return err
+	}
+	var sqlErr *eval.Error
+	if !errors.As(err, &sqlErr) {
+		return err
+	}
+	raised := &Error{Number: sqlErr.Number, Severity: sqlErr.Severity, State: sqlErr.State, Message: sqlErr.Message}
+	if f != nil {
+		raised.Procedure, raised.Line = f.proc, f.line
+	}
+	return raised
+}
+
+// signal tells enclosing statements how a statement ended.
+type signal int
+
+const (
+	next signal = iota
+	breakLoop
+	continueLoop
+	returned
+	jump // GOTO; the frame holds the label
+)
+
+// body runs the statements of a batch or procedure.
+func (in *Interpreter) body(f *frame, stmts []ast.Statement) (signal, error) {
+	sig, err := in.block(f, stmts)
+	if err != nil {
+		return sig, err
+	}
+	switch sig {
+	case breakLoop, continueLoop:
+		return sig, in.raised(f, sqlError(135, "Cannot use a BREAK or CONTINUE statement outside the scope of a WHILE statement."))
+	case jump:
+		return sig, in.raised(f, sqlError(133, "A GOTO statement references the label '%s' but the label has not been declared.", f.label))
+	}
+	return sig, nil
+}
+
+// block runs a list of statements. A GOTO is resolved against the labels
+// of the list; a label that is not in the list is left to the enclosing
+// block.
+func (in *Interpreter) block(f *frame, stmts []ast.Statement) (signal, error) {
+	for i := 0; i < len(stmts); i++ {
+		sig, err := in.exec(f, stmts[i])
+		if err != nil {
+			return sig, err
+		}
+		if sig == jump {
+			if j := labelIndex(stmts, f.label); j >= 0 {
+				i = j
+				continue
+			}
+		}
+		if sig != next {
+			return sig, nil
+		}
+	}
+	return next, nil
+}
+
+func labelIndex(stmts []ast.Statement, label string) int {
+	for i, stmt := range stmts {
+		if l, ok := stmt.(*ast.LabelStatement); ok && strings.EqualFold(l.Name.Value, label) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (in *Interpreter) exec(f *frame, stmt ast.Statement) (signal, error) {
+	if line := statementLine(stmt); line > 0 {
+		f.line = line
+	}
+	in.sync(f)
+
+	switch x := stmt.(type) {
+	case *ast.BeginEndBlock:
+		return in.block(f, x.Statements)
+	case *ast.IfStatement:
+		ok, err := in.condition(f, x.Condition)
+		if err != nil {
+			return next, in.raised(f, err)
+		}
+		in.lastError = 0
+		if ok {
+			return in.exec(f, x.Consequence)
+		}
+		if x.Alternative != nil {
+			return in.exec(f, x.Alternative)
+		}
+		return next, nil
+	case *ast.WhileStatement:
+		for {
+			in.sync(f)
+			ok, err := in.condition(f, x.Condition)
+			if err != nil {
+				return next, in.raised(f, err)
+			}
+			in.lastError = 0
+			if !ok {
+				return next, nil
+			}
+			sig, err := in.exec(f, x.Body)
+			if err != nil {
+				return sig, err
+			}
+			switch sig {
+			case breakLoop:
+				return next, nil
+			case returned, jump:
+				return sig, nil
+			}
+		}
+	case *ast.BreakStatement:
+		return breakLoop, nil
+	case *ast.ContinueStatement:
+		return continueLoop, nil
+	case *ast.GotoStatement:
+		f.label = x.Label.Value
+		return jump, nil
+	case *ast.LabelStatement:
+		return next, nil
+	case *ast.ReturnStatement:
+		if x.Value != nil && f.proc != "" {
+			v, err := in.value(f, x.Value)
+			if err != nil {
+				return next, in.raised(f, err)
+			}
+			if v, err = eval.Convert(v, eval.Type{Kind: eval.KindInt}); err != nil {
+				return next, in.raised(f, err)
+			}
+			f.code = 0
+			if !v.Null {
+				f.code = int(v.Int64())
+			}
+		}
+		return returned, nil
+	case *ast.TryCatchStatement:
+		return in.tryCatch(f, x)
+	}
+
+	if err := in.statement(f, stmt); err != nil {
+		return next, in.raised(f, err)
+	}
+	in.lastError = 0
+	return next, nil
+}
+
+func (in *Interpreter) tryCatch(f *frame, x *ast.TryCatchStatement) (signal, error) {
+	sig, err := in.block(f, x.TryBlock.Statements)
+	var caught *Error
+	if err == nil || !errors.As(err, &caught) || caught.Severity >= 20 {
+		return sig, err
+	}
+	f.caught = append(f.caught, caught)
+	defer func() { f.caught = f.caught[:len(f.caught)-1] }()
+	in.lastError = caught.Number
+	return in.block(f, x.CatchBlock.Statements)
+}
+
+// statement runs a statement that does not change the flow of control.
+func (in *Interpreter) statement(f *frame, stmt ast.Statement) error {
+	switch x := stmt.(type) {
+	case *ast.DeclareStatement:
+		return in.declare(f, x)
+	case *ast.SetStatement:
+		if x.Option != "" {
+			return nil
+		}
+		return in.set(f, x)
+	case *ast.SelectStatement:
+		return in.selectStatement(f, x)
+	case *ast.PrintStatement:
+		v, err := in.value(f, x.Expression)
+		if err != nil {
+			return err
+		}
+		text, err := messageText(v)
+		if err != nil {
+			return err
+		}
+		in.out.Messages = append(in.out.Messages, text)
+		return nil
+	case *ast.ThrowStatement:
+		return in.throw(f, x)
+	case *ast.RaiserrorStatement:
+		return in.raiserror(f, x)
+	case *ast.ExecStatement:
+		return in.execStatement(f, x)
+	case *ast.CreateProcedureStatement:
+		in.define(x.Name.String(), x.Parameters, x.Body)
+		return nil
+	case *ast.AlterProcedureStatement:
+		in.define(x.Name.String(), x.Parameters, x.Body)
+		return nil
+	case *ast.BeginTransactionStatement:
+		in.tranCount++
+		return nil
+	case *ast.CommitTransactionStatement:
+		if in.tranCount == 0 {
+			return sqlError(3902, "The COMMIT TRANSACTION request has no corresponding BEGIN TRANSACTION.")
+		}
+		in.tranCount--
+		return nil
+	case *ast.RollbackTransactionStatement:
+		if in.tranCount == 0 {
+			return sqlError(3903, "The ROLLBACK TRANSACTION request has no corresponding BEGIN TRANSACTION.")
+		}
+		in.tranCount = 0
+		return nil
+	case *ast.SaveTransactionStatement, *ast.SetOptionStatement, *ast.SetTransactionIsolationStatement:
+		return nil
+	}
+	_, err := in.query(f, stmt)
+	return err
+}
+
+func (in *Interpreter) define(name string, params []*ast.ParameterDef, body *ast.BeginEndBlock) {
+	p := &procedure{name: name, params: params}
+	if body != nil {
+		p.body = body.Statements
+	}
+	in.procs[procedureKey(name)] = p
+}
+
+// query hands stmt to the QueryExecutor, keeping any result set.
+func (in *Interpreter) query(f *frame, stmt ast.Statement) (*Result, error) {
+	res, err := in.execute(f, stmt)
+	if err != nil {
+		return nil, err
+	}
+	if res != nil {
+		in.rowCount = res.RowsAffected
+		if res.Columns != nil {
+			in.out.Results = append(in.out.Results, res)
+		}
+	}
+	return res, nil
+}
+
+func (in *Interpreter) execute(f *frame, stmt ast.Statement) (*Result, error) {
+	if in.Queries == nil {
+		return nil, fmt.Errorf("interp: no QueryExecutor to run %s", strings.Fields(stmt.String())[0])
+	}
+	return in.Queries.Execute(stmt, f.scope)
+}
+
+func (in *Interpreter) declare(f *frame, x *ast.DeclareStatement) error {
+	for _, v := range x.Variables {
+		key := strings.ToLower(v.Name)
+		if f.scope.Declared(v.Name) {
+			if f.declares[key] != v {
+				return sqlError(134, "The variable name '%s' has already been declared. Variable names must be unique within a query batch or stored procedure.", v.Name)
+			}
+			if v.TableType != nil {
+				continue
+			}
+			if err := in.initialize(f, v); err != nil {
+				return err
+			}
+			continue
+		}
+		f.declares[key] = v
+		if v.TableType != nil {
+			if _, err := in.query(f, &ast.DeclareStatement{Token: x.Token, Variables: []*ast.VariableDef{v}}); err != nil {
+				return err
+			}
+			f.scope.declareTable(v.Name)
+			continue
+		}
+		t, err := eval.TypeOf(v.DataType)
+		if err != nil {
+			return err
+		}
+		f.scope.declare(v.Name, t)
+		if err := in.initialize(f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initialize assigns a declared variable its initial value, or NULL.
+func (in *Interpreter) initialize(f *frame, v *ast.VariableDef) error {
+	if v.Value == nil {
+		t, _ := f.scope.Type(v.Name)
+		f.scope.eval.Set(v.Name, eval.Null(t))
+		return nil
+	}
+	value, err := in.value(f, v.Value)
+	if err != nil {
+		return err
+	}
+	return f.scope.Set(v.Name, value)
+}
+
+func (in *Interpreter) set(f *frame, x *ast.SetStatement) error {
+	target, ok := x.Variable.(*ast.Variable)
+	if !ok || x.Value == nil {
+		// Method calls such as SET @doc.modify(...)
+		_, err := in.query(f, x)
+		return err
+	}
+	v, err := in.value(f, x.Value)
+	if err != nil {
+		return err
+	}
+	if op := strings.TrimSuffix(x.Operator, "="); op != "" {
+		current, ok := f.scope.Get(target.Name)
+		if !ok {
+			return undeclared(target.Name)
+		}
+		if v, err = eval.Arithmetic(op, current, v); err != nil {
+			return err
+		}
+	}
+	if err := f.scope.Set(target.Name, v); err != nil {
+		return err
+	}
+	in.rowCount = 1
+	return nil
+}
+
+// selectStatement runs a SELECT without a FROM clause itself when it can,
+// assigning variables or producing a one-row result set.
+func (in *Interpreter) selectStatement(f *frame, x *ast.SelectStatement) error {
+	if x.From != nil || x.Where != nil || x.Into != nil || x.Union != nil || x.GroupBy != nil || x.Having != nil {
+		_, err := in.query(f, x)
+		return err
+	}
+	assigns := 0
+	for _, c := range x.Columns {
+		if c.Variable != nil {
+			assigns++
+		}
+	}
+	if assigns > 0 && assigns < len(x.Columns) {
+		return sqlError(141, "A SELECT statement that assigns a value to a variable must not be combined with data-retrieval operations.")
+	}
+
+	row := make([]eval.Value, len(x.Columns))
+	for i, c := range x.Columns {
+		if c.AllColumns {
+			_, err := in.query(f, x)
+			return err
+		}
+		v, err := f.scope.eval.Eval(c.Expression)
+		if errors.Is(err, eval.ErrNotConstant) {
+			_, err := in.query(f, x)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		row[i] = v
+	}
+	in.rowCount = 1
+	if assigns > 0 {
+		for i, c := range x.Columns {
+			if err := f.scope.Set(c.Variable.Name, row[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	res := &Result{Columns: make([]string, len(x.Columns)), Rows: [][]eval.Value{row}, RowsAffected: 1}
+	for i, c := range x.Columns {
+		if c.Alias != nil {
+			res.Columns[i] = c.Alias.Value
+		}
+	}
+	in.out.Results = append(in.out.Results, res)
+	return nil
+}
+
+// value evaluates an expression, asking the QueryExecutor for the value
+// of expressions that need a database, such as scalar subqueries.
+func (in *Interpreter) value(f *frame, expr ast.Expression) (eval.Value, error) {
+	v, err := f.scope.eval.Eval(expr)
+	if !errors.Is(err, eval.ErrNotConstant) {
+		return v, err
+	}
+	if name := f.scope.undeclared(expr); name != "" {
+		return eval.Value{}, undeclared(name)
+	}
+	res, err := in.execute(f, &ast.SelectStatement{Columns: []ast.SelectColumn{{Expression: expr}}})
+	if err != nil {
+		return eval.Value{}, err
+	}
+	if res == nil || len(res.Rows) == 0 || len(res.Rows[0]) == 0 {
+		return eval.Value{Null: true}, nil
+	}
+	return res.Rows[0][0], nil
+}
+
+// condition evaluates the condition of an IF or WHILE. UNKNOWN is false.
+func (in *Interpreter) condition(f *frame, expr ast.Expression) (bool, error) {
+	v, err := f.scope.eval.Eval(expr)
+	if errors.Is(err, eval.ErrNotConstant) {
+		// IIF(condition, 1, 0) turns the predicate into a value a query
+		// can return
+		v, err = in.value(f, &ast.FunctionCall{
+			Function:  &ast.Identifier{Value: "IIF"},
+			Arguments: []ast.Expression{expr, eval.Int(1).Literal(), eval.Int(0).Literal()},
+		})
+		if err != nil {
+			return false, err
+		}
+		return !v.Null && v.Int64() == 1, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if v.Type.Kind != eval.KindBoolean && !v.Null {
+		return false, sqlError(4145, "An expression of non-boolean type specified in a context where a condition is expected, near '%s'.", expr)
+	}
+	return !v.Null && v.Bool(), nil
+}
+
+// messageText converts a PRINT or RAISERROR argument to text. NULL prints
+// as an empty string.
+func messageText(v eval.Value) (string, error) {
+	if v.Null {
+		return "", nil
+	}
+	s, err := eval.Convert(v, eval.Type{Kind: eval.KindNVarChar, Length: eval.MaxLength})
+	if err != nil {
+		return "", err
+	}
+	return s.Text(), nil
+}
+
+func (in *Interpreter) execStatement(f *frame, x *ast.ExecStatement) error {
+	if x.DynamicSQL != nil {
+		if x.AtServer != nil {
+			_, err := in.query(f, x)
+			return err
+		}
+		return in.dynamic(f, x.DynamicSQL)
+	}
+	p, ok := in.procs[procedureKey(x.Procedure.String())]
+	if !ok {
+		if in.Queries == nil {
+			return notFound(x.Procedure.String())
+		}
+		_, err := in.query(f, x)
+		return err
+	}
+
+	supplied := make(map[int]eval.Value)
+	outputs := make(map[int]*ast.Variable)
+	named := false
+	for i, arg := range x.Parameters {
+		index := i
+		if arg.Name != "" {
+			named = true
+			if index = p.param(arg.Name); index < 0 {
+				return sqlError(8145, "%s is not a parameter for procedure %s.", arg.Name, p.name)
+			}
+		} else if named {
+			return sqlError(119, "Must pass parameter number %d and subsequent parameters as '@name = value'. After the form '@name = value' has been used, all subsequent parameters must be passed in the form '@name = value'.", i+1)
+		}
+		if index >= len(p.params) {
+			return sqlError(8144, "Procedure or function %s has too many arguments specified.", p.name)
+		}
+		if arg.Output {
+			v, ok := arg.Value.(*ast.Variable)
+			if !ok {
+				return sqlError(179, "Cannot use the OUTPUT option when passing a constant to a stored procedure.")
+			}
+			if !p.params[index].Output {
+				return sqlError(8162, "The formal parameter \"%s\" was not declared as an OUTPUT parameter, but the actual parameter passed in requested output.", p.params[index].Name)
+			}
+			outputs[index] = v
+		}
+		if id, ok := arg.Value.(*ast.Identifier); ok && strings.EqualFold(id.Value, "DEFAULT") {
+			continue
+		}
+		v, err := in.value(f, arg.Value)
+		if err != nil {
+			return err
+		}
+		supplied[index] = v
+	}
+
+	callee, err := in.invoke(p, supplied)
+	if err != nil {
+		return err
+	}
+	for index, v := range outputs {
+		value, _ := callee.scope.Get(p.params[index].Name)
+		if err := f.scope.Set(v.Name, value); err != nil {
+			return err
+		}
+	}
+	if x.ReturnVariable != nil {
+		return f.scope.Set(x.ReturnVariable.Value, eval.Int(int64(callee.code)))
+	}
+	return nil
+}
+
+// invoke runs a procedure in a new frame, binding the supplied argument
+// values and the defaults of the other parameters.
+func (in *Interpreter) invoke(p *procedure, supplied map[int]eval.Value) (*frame, error) {
+	if in.nesting >= maxNesting {
+		return nil, sqlError(217, "Maximum stored procedure, function, trigger, or view nesting level exceeded (limit %d).", maxNesting)
+	}
+	f := in.newFrame(p.name)
+	for i, param := range p.params {
+		t, err := eval.TypeOf(param.DataType)
+		if err != nil {
+			return nil, err
+		}
+		f.scope.declare(param.Name, t)
+		v, ok := supplied[i]
+		if !ok {
+			if param.Default == nil {
+				return nil, sqlError(201, "Procedure or function '%s' expects parameter '%s', which was not supplied.", p.name, param.Name)
+			}
+			if v, err = f.scope.eval.Eval(param.Default); err != nil {
+				return nil, err
+			}
+		}
+		if err := f.scope.Set(param.Name, v); err != nil {
+			return nil, err
+		}
+	}
+
+	in.nesting++
+	defer func() { in.nesting-- }()
+	_, err := in.body(f, p.body)
+	return f, err
+}
+
+// dynamic runs the batch an EXEC('...') statement builds, which has
+// variables of its own.
+func (in *Interpreter) dynamic(f *frame, expr ast.Expression) error {
+	v, err := in.value(f, expr)
+	if err != nil {
+		return err
+	}
+	text, err := messageText(v)
+	if err != nil {
+		return err
+	}
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return sqlError(102, "Incorrect syntax: %s", errs[0])
+	}
+	if in.nesting >= maxNesting {
+		return sqlError(217, "Maximum stored procedure, function, trigger, or view nesting level exceeded (limit %d).", maxNesting)
+	}
+	in.nesting++
+	defer func() { in.nesting-- }()
+	_, err = in.body(in.newFrame(""), program.Statements)
+	return err
+}
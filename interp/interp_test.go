@@ -0,0 +1,428 @@
+package interp
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func run(t *testing.T, in *Interpreter, input string) *Outcome {
+	t.Helper()
+	out, err := in.Run(parse(t, input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out
+}
+
+// recorder answers every query with its canned result and records the
+// statements it was given.
+type recorder struct {
+	statements []string
+	result     func(stmt ast.Statement, scope *Scope) (*Result, error)
+}
+
+func (r *recorder) Execute(stmt ast.Statement, scope *Scope) (*Result, error) {
+	r.statements = append(r.statements, stmt.String())
+	if r.result == nil {
+		return &Result{RowsAffected: 3}, nil
+	}
+	return r.result(stmt, scope)
+}
+
+func TestControlFlow(t *testing.T) {
+	out := run(t, New(nil), `
+DECLARE @i INT = 0, @s VARCHAR(20) = ''
+WHILE @i < 10
+BEGIN
+    SET @i += 1
+    IF @i % 2 = 0 CONTINUE
+    IF @i > 7 BREAK
+    SET @s = @s + CAST(@i AS VARCHAR(2))
+END
+PRINT @s
+PRINT @i
+IF @s = '1357' PRINT 'odd' ELSE PRINT 'even'`)
+
+	expected := []string{"1357", "9", "odd"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+}
+
+func TestDeclareInLoop(t *testing.T) {
+	out := run(t, New(nil), `
+DECLARE @i INT = 0
+WHILE @i < 3
+BEGIN
+    DECLARE @x INT = @i * 10, @y INT
+    PRINT @x
+    PRINT ISNULL(@y, -1)
+    SET @y = 1
+    SET @i += 1
+END`)
+	expected := []string{"0", "-1", "10", "-1", "20", "-1"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+}
+
+func TestGoto(t *testing.T) {
+	out := run(t, New(nil), `
+DECLARE @n INT = 0
+again:
+SET @n = @n + 1
+IF @n < 3 GOTO again
+PRINT @n
+GOTO done
+PRINT 'skipped'
+done:
+PRINT 'done'`)
+
+	expected := []string{"3", "done"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	out := run(t, New(nil), `
+BEGIN TRY
+    DECLARE @x INT = 1 / 0
+    PRINT 'not reached'
+END TRY
+BEGIN CATCH
+    PRINT @@ERROR
+    PRINT CONCAT(ERROR_NUMBER(), ': ', ERROR_MESSAGE())
+END CATCH
+PRINT ISNULL(ERROR_MESSAGE(), 'cleared')
+BEGIN TRY
+    RAISERROR('Order %d for %s failed', 16, 2, 42, 'acme')
+END TRY
+BEGIN CATCH
+    PRINT CONCAT(ERROR_NUMBER(), ' ', ERROR_SEVERITY(), ' ', ERROR_STATE(), ' ', ERROR_MESSAGE())
+END CATCH
+BEGIN TRY
+    BEGIN TRY
+        THROW 50001, 'inner', 3;
+    END TRY
+    BEGIN CATCH
+        THROW;
+    END CATCH
+END TRY
+BEGIN CATCH
+    PRINT CONCAT(ERROR_NUMBER(), ' ', ERROR_MESSAGE(), ' ', ERROR_STATE())
+END CATCH
+RAISERROR('just information', 10, 1)`)
+
+	expected := []string{
+		"8134",
+		"8134: Divide by zero error encountered.",
+		"cleared",
+		"50000 16 2 Order 42 for acme failed",
+		"50001 inner 3",
+		"just information",
+	}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages:\n%q\ngot:\n%q", expected, out.Messages)
+	}
+}
+
+func TestUncaughtError(t *testing.T) {
+	in := New(nil)
+	out, err := in.Run(parse(t, "PRINT 'before'\nCREATE PROCEDURE Fail AS\nBEGIN\n    PRINT 'in proc'\n    THROW 50010, 'failed', 1\nEND\nGO\nEXEC Fail;\nPRINT 'after'"))
+
+	var sqlErr *Error
+	if !errors.As(err, &sqlErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if sqlErr.Number != 50010 || sqlErr.Procedure != "Fail" || sqlErr.Line != 5 {
+		t.Errorf("unexpected error %+v", sqlErr)
+	}
+	expected := []string{"before", "in proc"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+}
+
+func TestProcedures(t *testing.T) {
+	in := New(nil)
+	run(t, in, `
+CREATE PROCEDURE dbo.Divide
+    @a INT,
+    @b INT = 2,
+    @q INT OUTPUT,
+    @r INT = NULL OUTPUT
+AS
+BEGIN
+    IF @b = 0 RETURN -1
+    SET @q = @a / @b
+    SET @r = @a % @b
+    RETURN 0
+END
+GO
+CREATE PROCEDURE Caller AS
+BEGIN
+    DECLARE @rc INT, @q INT, @r INT
+    EXEC @rc = dbo.Divide 17, 5, @q OUTPUT, @r = @r OUTPUT
+    PRINT CONCAT(@rc, ' ', @q, ' ', @r)
+    EXEC @rc = Divide @a = 9, @q = @q OUTPUT
+    PRINT CONCAT(@rc, ' ', @q)
+    EXEC @rc = Divide 1, 0, @q OUTPUT
+    PRINT @rc
+END`)
+
+	out, err := in.Exec("Caller", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"0 3 2", "0 4", "-1"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+
+	out, err = in.Exec("dbo.divide", map[string]eval.Value{
+		"@a": eval.Int(7),
+		"@B": eval.Int(4),
+		"@q": eval.Null(eval.Type{Kind: eval.KindInt}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ReturnCode != 0 || out.Output["@q"].String() != "1" || out.Output["@r"].String() != "3" {
+		t.Errorf("unexpected outcome: %d %v", out.ReturnCode, out.Output)
+	}
+
+	tests := []struct {
+		input  string
+		number int
+	}{
+		{"EXEC Divide", 201},
+		{"EXEC Divide 1, 2, 3, 4, 5", 8144},
+		{"EXEC Divide @a = 1, @c = 2", 8145},
+		{"EXEC Divide @a = 1, 2", 119},
+		{"DECLARE @x INT\nEXEC Divide @x OUTPUT, 1, 2", 8162},
+		{"EXEC Missing", 2812},
+	}
+	for _, tt := range tests {
+		_, err := in.Run(parse(t, tt.input))
+		var sqlErr *Error
+		if !errors.As(err, &sqlErr) || sqlErr.Number != tt.number {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, err)
+		}
+	}
+}
+
+func TestProcedureSchemas(t *testing.T) {
+	in := New(nil)
+	run(t, in, `
+CREATE PROCEDURE dbo.p AS RETURN 1
+GO
+CREATE PROCEDURE sales.p AS RETURN 2
+GO
+CREATE PROCEDURE Caller AS
+BEGIN
+    DECLARE @rc INT
+    EXEC @rc = dbo.p;
+    PRINT @rc
+    EXEC @rc = sales.p;
+    PRINT @rc
+    EXEC @rc = p;
+    PRINT @rc
+END`)
+
+	out, err := in.Exec("Caller", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"1", "2", "1"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+	if out, err = in.Exec("[Sales].[P]", nil); err != nil || out.ReturnCode != 2 {
+		t.Errorf("expected return code 2, got %d (%v)", out.ReturnCode, err)
+	}
+}
+
+func TestRecursionLimit(t *testing.T) {
+	in := New(nil)
+	_, err := in.Run(parse(t, "CREATE PROCEDURE Down @n INT AS\nBEGIN\n    SET @n = @n + 1\n    EXEC Down @n\nEND\nGO\nEXEC Down 0"))
+	var sqlErr *Error
+	if !errors.As(err, &sqlErr) || sqlErr.Number != 217 {
+		t.Errorf("expected error 217, got %v", err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	out := run(t, New(nil), `
+DECLARE @d DECIMAL(5, 2) = 1.005, @s VARCHAR(3) = 'abcdef', @n INT
+SELECT @n = 40, @d = @d * 2
+SET @n += 2
+PRINT @d
+PRINT @s
+PRINT @n
+SELECT @n AS answer, 'x'`)
+
+	expected := []string{"2.02", "abc", "42"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+	if len(out.Results) != 1 {
+		t.Fatalf("expected 1 result set, got %d", len(out.Results))
+	}
+	if res := out.Results[0]; res.Columns[0] != "answer" || res.Rows[0][0].String() != "42" {
+		t.Errorf("unexpected result %v %v", res.Columns, res.Rows)
+	}
+
+	tests := []struct {
+		input  string
+		number int
+	}{
+		{"SET @missing = 1", 137},
+		{"DECLARE @a INT\nDECLARE @a INT", 134},
+		{"DECLARE @a INT, @a INT", 134},
+		{"DECLARE @i INT = 0\nWHILE @i < 2 BEGIN SET @i += 1 DECLARE @i INT END", 134},
+		{"DECLARE @a INT\nSELECT @a = 1, 2", 141},
+		{"DECLARE @a TINYINT = 300", 220},
+		{"IF 1 PRINT 'x'", 4145},
+		{"COMMIT", 3902},
+		{"BREAK", 135},
+		{"GOTO nowhere", 133},
+	}
+	for _, tt := range tests {
+		_, err := New(nil).Run(parse(t, tt.input))
+		var sqlErr *Error
+		if !errors.As(err, &sqlErr) || sqlErr.Number != tt.number {
+			t.Errorf("%s: expected error %d, got %v", tt.input, tt.number, err)
+		}
+	}
+}
+
+func TestBatches(t *testing.T) {
+	in := New(nil)
+	_, err := in.Run(parse(t, "DECLARE @x INT = 1\nGO\nPRINT @x"))
+	var sqlErr *Error
+	if !errors.As(err, &sqlErr) || sqlErr.Number != 137 {
+		t.Errorf("variables should not survive GO, got %v", err)
+	}
+
+	out := run(t, in, "DECLARE @x INT = 1\nPRINT @x\nGO 2")
+	if !reflect.DeepEqual(out.Messages, []string{"1", "1"}) {
+		t.Errorf("expected the batch to run twice, got %v", out.Messages)
+	}
+
+	out = run(t, in, "DECLARE @x INT = 5\nEXEC('DECLARE @x INT = 6 PRINT @x')\nPRINT @x")
+	if !reflect.DeepEqual(out.Messages, []string{"6", "5"}) {
+		t.Errorf("dynamic SQL should have its own variables, got %v", out.Messages)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	out := run(t, New(nil), `
+PRINT XACT_STATE()
+BEGIN TRAN
+BEGIN TRAN
+PRINT @@TRANCOUNT
+COMMIT
+PRINT XACT_STATE()
+ROLLBACK
+PRINT @@TRANCOUNT`)
+
+	expected := []string{"0", "2", "1", "0"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+}
+
+func TestQueryExecutor(t *testing.T) {
+	rec := &recorder{}
+	rec.result = func(stmt ast.Statement, scope *Scope) (*Result, error) {
+		s := stmt.String()
+		switch {
+		case strings.Contains(s, "EXISTS"):
+			return &Result{Columns: []string{""}, Rows: [][]eval.Value{{eval.Int(1)}}, RowsAffected: 1}, nil
+		case strings.Contains(s, "COUNT"):
+			return &Result{Columns: []string{""}, Rows: [][]eval.Value{{eval.Int(7)}}, RowsAffected: 1}, nil
+		case strings.HasPrefix(s, "SELECT @name"):
+			return &Result{RowsAffected: 1}, scope.Set("@name", eval.VarChar("Widget"))
+		case strings.HasPrefix(s, "INSERT"):
+			if v, _ := scope.Get("@id"); v.String() != "10" {
+				t.Errorf("executor should see @id = 10, got %s", v)
+			}
+			return nil, &eval.Error{Number: 2627, Severity: 14, State: 1, Message: "Violation of PRIMARY KEY constraint."}
+		}
+		return &Result{Columns: []string{"Id"}, Rows: [][]eval.Value{{eval.Int(1)}, {eval.Int(2)}}, RowsAffected: 2}, nil
+	}
+
+	out := run(t, New(rec), `
+DECLARE @id INT = 10, @name VARCHAR(20), @count INT
+IF EXISTS (SELECT 1 FROM Products WHERE Id = @id) PRINT 'exists'
+SET @count = (SELECT COUNT(*) FROM Products)
+SELECT @name = Name FROM Products WHERE Id = @id
+SELECT Id FROM Products
+PRINT CONCAT(@count, ' ', @name, ' ', @@ROWCOUNT)
+BEGIN TRY
+    INSERT INTO Products (Id) VALUES (@id)
+END TRY
+BEGIN CATCH
+    PRINT ERROR_NUMBER()
+END CATCH`)
+
+	expected := []string{"exists", "7 Widget 2", "2627"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+	if len(out.Results) != 1 || len(out.Results[0].Rows) != 2 {
+		t.Errorf("expected one result set of 2 rows, got %v", out.Results)
+	}
+	if len(rec.statements) != 5 {
+		t.Errorf("expected 5 statements for the executor, got %q", rec.statements)
+	}
+
+	if _, err := New(nil).Run(parse(t, "SELECT * FROM Products")); err == nil {
+		t.Error("expected an error without a QueryExecutor")
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	tests := []struct {
+		format   string
+		args     []eval.Value
+		expected string
+	}{
+		{"%d rows", []eval.Value{eval.Int(5)}, "5 rows"},
+		{"[%5d] [%-5s] [%05i]", []eval.Value{eval.Int(42), eval.VarChar("ab"), eval.Int(7)}, "[   42] [ab   ] [00007]"},
+		{"%x %X %o", []eval.Value{eval.Int(255), eval.Int(255), eval.Int(8)}, "ff FF 10"},
+		{"%u", []eval.Value{eval.Int(-1)}, "4294967295"},
+		{"%.3s", []eval.Value{eval.VarChar("abcdef")}, "abc"},
+		{"%*d", []eval.Value{eval.Int(4), eval.Int(1)}, "   1"},
+		{"100%% %s", []eval.Value{eval.Null(eval.Type{Kind: eval.KindVarChar, Length: 1})}, "100% (null)"},
+		{"%s and %s", []eval.Value{eval.VarChar("one")}, "one and (null)"},
+	}
+	for _, tt := range tests {
+		got, err := formatMessage(tt.format, tt.args)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.format, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("formatMessage(%q) = %q, want %q", tt.format, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package interp
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+)
+
+// Scope holds the variables of a running batch or procedure. A
+// QueryExecutor reads variables from it and assigns the results of
+// SELECT @v = ... statements to it.
+type Scope struct {
+	eval   *eval.Evaluator
+	types  map[string]eval.Type
+	tables map[string]bool
+}
+
+func newScope() *Scope {
+	return &Scope{
+		eval:   eval.New(),
+		types:  make(map[string]eval.Type),
+		tables: make(map[string]bool),
+	}
+}
+
+// Evaluator returns the evaluator holding the scope's variable values,
+// for evaluating or folding the expressions of a statement.
+func (s *Scope) Evaluator() *eval.Evaluator {
+	return s.eval
+}
+
+// Get returns the value of a scalar variable or of @@ROWCOUNT and the
+// other session variables.
+func (s *Scope) Get(name string) (eval.Value, bool) {
+	return s.eval.Get(name)
+}
+
+// Set assigns a value to a declared scalar variable, converting it to the
+// variable's type.
+func (s *Scope) Set(name string, v eval.Value) error {
+	t, ok := s.types[strings.ToLower(name)]
+	if !ok {
+		return undeclared(name)
+	}
+	v, err := eval.Convert(v, t)
+	if err != nil {
+		return err
+	}
+	s.eval.Set(name, v)
+	return nil
+}
+
+// Type returns the declared type of a scalar variable.
+func (s *Scope) Type(name string) (eval.Type, bool) {
+	t, ok := s.types[strings.ToLower(name)]
+	return t, ok
+}
+
+// Declared reports whether a scalar or table variable has been declared.
+func (s *Scope) Declared(name string) bool {
+	key := strings.ToLower(name)
+	_, ok := s.types[key]
+	return ok || s.tables[key]
+}
+
+// IsTable reports whether name is a table variable.
+func (s *Scope) IsTable(name string) bool {
+	return s.tables[strings.ToLower(name)]
+}
+
+func (s *Scope) declare(name string, t eval.Type) {
+	s.types[strings.ToLower(name)] = t
+	s.eval.Set(name, eval.Null(t))
+}
+
+func (s *Scope) declareTable(name string) {
+	s.tables[strings.ToLower(name)] = true
+}
+
+// undeclared returns the first variable expr reads that has not been
+// declared, or "".
+func (s *Scope) undeclared(expr ast.Expression) string {
+	finder := &variableFinder{scope: s}
+	tsqlparser.Walk(finder, expr)
+	return finder.name
+}
+
+type variableFinder struct {
+	scope *Scope
+	name  string
+}
+
+func (v *variableFinder) Visit(node ast.Node) tsqlparser.Visitor {
+	if v.name != "" {
+		return nil
+	}
+	if x, ok := node.(*ast.Variable); ok && !strings.HasPrefix(x.Name, "@@") && !v.scope.Declared(x.Name) {
+		v.name = x.Name
+	}
+	return v
+}
+
+func undeclared(name string) *eval.Error {
+	return sqlError(137, "Must declare the scalar variable \"%s\".", name)
+}
@@ -118,7 +118,15 @@ func (l *Lexer) NextToken() token.Token {
 			tok = l.newToken(token.AMPERSAND, string(l.ch))
 		}
 	case '|':
-		if l.peekChar() == '=' {
+		if l.peekChar() == '|' {
+			l.readChar()
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = l.newToken(token.DPIPEEQ, "||=")
+			} else {
+				tok = l.newToken(token.DPIPE, "||")
+			}
+		} else if l.peekChar() == '=' {
 			l.readChar()
 			tok = l.newToken(token.OREQ, "|=")
 		} else {
@@ -186,6 +194,10 @@ func (l *Lexer) NextToken() token.Token {
 		return tok
 	case ']':
 		tok = l.newToken(token.RBRACKET, string(l.ch))
+	case '{':
+		tok = l.newToken(token.LBRACE, string(l.ch))
+	case '}':
+		tok = l.newToken(token.RBRACE, string(l.ch))
 	case '.':
 		// Check if this is a floating-point number starting with dot (e.g., .5)
 		if isDigit(l.peekChar()) {
@@ -318,14 +330,17 @@ func (l *Lexer) readIdentifier() string {
 func (l *Lexer) readBracketedIdentifier() string {
 	l.readChar() // consume opening [
 	position := l.position
-	for l.ch != ']' && l.ch != 0 {
-		// Handle escaped brackets ]]
-		if l.ch == ']' && l.peekChar() == ']' {
+	for l.ch != 0 {
+		if l.ch == ']' {
+			// Handle escaped brackets ]]
+			if l.peekChar() != ']' {
+				break
+			}
 			l.readChar()
 		}
 		l.readChar()
 	}
-	ident := l.input[position:l.position]
+	ident := strings.ReplaceAll(l.input[position:l.position], "]]", "]")
 	if l.ch == ']' {
 		l.readChar() // consume closing ]
 	}
@@ -335,14 +350,17 @@ func (l *Lexer) readBracketedIdentifier() string {
 func (l *Lexer) readQuotedIdentifier() string {
 	l.readChar() // consume opening "
 	position := l.position
-	for l.ch != '"' && l.ch != 0 {
-		// Handle escaped quotes ""
-		if l.ch == '"' && l.peekChar() == '"' {
+	for l.ch != 0 {
+		if l.ch == '"' {
+			// Handle escaped quotes ""
+			if l.peekChar() != '"' {
+				break
+			}
 			l.readChar()
 		}
 		l.readChar()
 	}
-	ident := l.input[position:l.position]
+	ident := strings.ReplaceAll(l.input[position:l.position], `""`, `"`)
 	if l.ch == '"' {
 		l.readChar() // consume closing "
 	}
@@ -319,6 +319,7 @@ func TestBracketedIdentifiers(t *testing.T) {
 		{"[My Table!@#]", "My Table!@#"}, // Special characters
 		{"[123Start]", "123Start"},       // Starts with number
 		{"[]", ""},                       // Empty (edge case)
+		{"[a]]b]", "a]b"},                // Escaped bracket
 	}
 
 	for _, tt := range tests {
@@ -341,6 +342,7 @@ func TestQuotedIdentifiers(t *testing.T) {
 		{`"TableName"`, "TableName"},
 		{`"Column Name"`, "Column Name"},
 		{`"SELECT"`, "SELECT"},
+		{`"a""b"`, `a"b`}, // Escaped quote
 	}
 
 	for _, tt := range tests {
@@ -453,6 +455,8 @@ func TestCompoundOperators(t *testing.T) {
 		{"&=", token.ANDEQ},
 		{"|=", token.OREQ},
 		{"^=", token.XOREQ},
+		{"||", token.DPIPE},
+		{"||=", token.DPIPEEQ},
 		{"<>", token.NEQ},
 		{"!=", token.NEQ},
 		{"<=", token.LTE},
@@ -470,13 +474,14 @@ func TestCompoundOperators(t *testing.T) {
 }
 
 func TestPunctuation(t *testing.T) {
-	input := "( ) , ; . : ="
+	input := "( ) , ; . : = { }"
 	l := New(input)
 
 	expected := []token.Type{
 		token.LPAREN, token.RPAREN,
 		token.COMMA, token.SEMICOLON,
 		token.DOT, token.COLON, token.EQ,
+		token.LBRACE, token.RBRACE,
 	}
 
 	for i, e := range expected {
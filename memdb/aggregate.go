@@ -0,0 +1,189 @@
+package memdb
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+)
+
+// aggregate computes an aggregate over the rows of a group. e is the
+// group's env, which the separator of STRING_AGG is evaluated in.
+func aggregate(call *ast.FunctionCall, rows []*env, e *env) (eval.Value, error) {
+	if len(call.WithinGroup) > 0 {
+		keys := make([][]eval.Value, len(rows))
+		for i, r := range rows {
+			keys[i] = make([]eval.Value, len(call.WithinGroup))
+			for j, item := range call.WithinGroup {
+				v, err := r.eval(item.Expression)
+				if err != nil {
+					return eval.Value{}, err
+				}
+				keys[i][j] = v
+			}
+		}
+		perm, err := sorted(keys, call.WithinGroup)
+		if err != nil {
+			return eval.Value{}, err
+		}
+		ordered := make([]*env, len(rows))
+		for i, p := range perm {
+			ordered[i] = rows[p]
+		}
+		rows = ordered
+	}
+	values, err := argument(call, rows)
+	if err != nil {
+		return eval.Value{}, err
+	}
+	return reduce(call, values, e)
+}
+
+// argument evaluates the argument of an aggregate for each row. For
+// COUNT(*) every row gives a non-NULL value.
+func argument(call *ast.FunctionCall, rows []*env) ([]eval.Value, error) {
+	name := functionName(call)
+	want := 1
+	if name == "STRING_AGG" {
+		want = 2
+	}
+	if len(call.Arguments) != want {
+		return nil, sqlError(174, "The %s function requires %d argument(s).", strings.ToLower(name), want)
+	}
+	values := make([]eval.Value, len(rows))
+	arg := call.Arguments[0]
+	if id, ok := arg.(*ast.Identifier); ok && id.Value == "*" {
+		for i := range values {
+			values[i] = eval.Int(1)
+		}
+		return values, nil
+	}
+	for i, r := range rows {
+		v, err := r.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// reduce computes an aggregate from the values of its argument.
+func reduce(call *ast.FunctionCall, values []eval.Value, e *env) (eval.Value, error) {
+	name := functionName(call)
+	var present []eval.Value
+	for _, v := range values {
+		if v.Null {
+			continue
+		}
+		if call.Distinct {
+			dup := false
+			for _, p := range present {
+				if dup = same(p, v); dup {
+					break
+				}
+			}
+			if dup {
+				continue
+			}
+		}
+		present = append(present, v)
+	}
+
+	switch name {
+	case "COUNT":
+		return eval.Int(int64(len(present))), nil
+	case "COUNT_BIG":
+		return eval.BigInt(int64(len(present))), nil
+	case "SUM":
+		return sum(name, values, present)
+	case "AVG":
+		total, err := sum(name, values, present)
+		if err != nil || total.Null {
+			return total, err
+		}
+		avg, err := eval.Arithmetic("/", total, eval.BigInt(int64(len(present))))
+		if err != nil {
+			return eval.Value{}, err
+		}
+		t := total.Type
+		if t.Kind == eval.KindDecimal {
+			t.Scale = max(t.Scale, 6)
+		}
+		return eval.Convert(avg, t)
+	case "MIN", "MAX":
+		if len(present) == 0 {
+			return nullOf(values), nil
+		}
+		best := present[0]
+		for _, v := range present[1:] {
+			c, err := eval.Compare(v, best)
+			if err != nil {
+				return eval.Value{}, err
+			}
+			if (name == "MIN" && c < 0) || (name == "MAX" && c > 0) {
+				best = v
+			}
+		}
+		return best, nil
+	case "STRING_AGG":
+		sep, err := e.eval(call.Arguments[1])
+		if err != nil {
+			return eval.Value{}, err
+		}
+		return eval.StringAgg(values, sep)
+	}
+	return eval.Value{}, sqlError(195, "'%s' is not a recognized built-in function name.", strings.ToLower(name))
+}
+
+// sum adds the non-NULL values in the type SUM returns for them: INT for
+// the smaller integer types, DECIMAL(38, s) for decimals, MONEY for money
+// and FLOAT for floating point.
+func sum(name string, values, present []eval.Value) (eval.Value, error) {
+	t := eval.Type{Kind: eval.KindInt}
+	for _, v := range values {
+		if v.Type.Kind != eval.KindNull {
+			t = v.Type
+			break
+		}
+	}
+	switch t.Kind {
+	case eval.KindTinyInt, eval.KindSmallInt, eval.KindInt:
+		t = eval.Type{Kind: eval.KindInt}
+	case eval.KindBigInt:
+	case eval.KindDecimal:
+		t = eval.Type{Kind: eval.KindDecimal, Precision: 38, Scale: t.Scale}
+	case eval.KindSmallMoney, eval.KindMoney:
+		t = eval.Type{Kind: eval.KindMoney}
+	case eval.KindReal, eval.KindFloat:
+		t = eval.Type{Kind: eval.KindFloat}
+	default:
+		return eval.Value{}, sqlError(8117, "Operand data type %s is invalid for %s operator.", strings.ToLower(t.Kind.String()), strings.ToLower(name))
+	}
+	if len(present) == 0 {
+		return eval.Null(t), nil
+	}
+	total, err := eval.Convert(present[0], t)
+	if err != nil {
+		return eval.Value{}, err
+	}
+	for _, v := range present[1:] {
+		if v, err = eval.Convert(v, t); err != nil {
+			return eval.Value{}, err
+		}
+		if total, err = eval.Arithmetic("+", total, v); err != nil {
+			return eval.Value{}, err
+		}
+	}
+	return eval.Convert(total, t)
+}
+
+// nullOf is a NULL of the type of values.
+func nullOf(values []eval.Value) eval.Value {
+	for _, v := range values {
+		if v.Type.Kind != eval.KindNull {
+			return eval.Null(v.Type)
+		}
+	}
+	return eval.Value{Null: true}
+}
@@ -0,0 +1,527 @@
+package memdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/interp"
+)
+
+func (s *session) insert(x *ast.InsertStatement, outer *env) (*interp.Result, error) {
+	t, err := s.table(x.Table)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := insertColumns(t, x.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]eval.Value
+	switch {
+	case x.Select != nil:
+		rel, err := s.query(x.Select, outer)
+		if err != nil {
+			return nil, err
+		}
+		if len(rel.cols) != len(targets) {
+			if len(rel.cols) < len(targets) {
+				return nil, sqlError(120, "The select list for the INSERT statement contains fewer items than the insert list. The number of SELECT values must match the number of INSERT columns.")
+			}
+			return nil, sqlError(121, "The select list for the INSERT statement contains more items than the insert list. The number of SELECT values must match the number of INSERT columns.")
+		}
+		for _, r := range rel.rows {
+			rows = append(rows, r.values)
+		}
+	case x.DefaultValues:
+		rows = [][]eval.Value{nil}
+		targets = nil
+	default:
+		for _, exprs := range x.Values {
+			if len(exprs) != len(targets) {
+				if len(exprs) < len(targets) {
+					return nil, sqlError(109, "There are more columns in the INSERT statement than values specified in the VALUES clause. The number of values in the VALUES clause must match the number of columns specified in the INSERT statement.")
+				}
+				return nil, sqlError(110, "There are fewer columns in the INSERT statement than values specified in the VALUES clause. The number of values in the VALUES clause must match the number of columns specified in the INSERT statement.")
+			}
+			values := make([]eval.Value, len(exprs))
+			for i, expr := range exprs {
+				var v eval.Value
+				var err error
+				if isDefault(expr) {
+					v, err = outer.defaultValue(t.columns[targets[i]])
+				} else {
+					v, err = outer.eval(expr)
+				}
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			rows = append(rows, values)
+		}
+	}
+	if x.Top != nil {
+		n, err := count(x.Top, outer)
+		if err != nil {
+			return nil, err
+		}
+		if x.TopPercent {
+			n = (int64(len(rows))*n + 99) / 100
+		}
+		rows = rows[:min(max(n, 0), int64(len(rows)))]
+	}
+
+	recs, err := s.insertRows(t, targets, rows, outer)
+	if err != nil {
+		return nil, err
+	}
+	inserted := make([][]eval.Value, len(recs))
+	for i, r := range recs {
+		inserted[i] = r.values
+	}
+	return s.output(x.Output, t, nil, inserted, outer)
+}
+
+// insertColumns returns the indexes of the columns an INSERT names, or of
+// the columns it fills in order when it names none.
+func insertColumns(t *table, names []*ast.Identifier) ([]int, error) {
+	var targets []int
+	if names == nil {
+		for i, c := range t.columns {
+			if c.identity == nil && c.computed == nil {
+				targets = append(targets, i)
+			}
+		}
+		return targets, nil
+	}
+	for _, n := range names {
+		i := t.column(n.Value)
+		if i < 0 {
+			return nil, sqlError(207, "Invalid column name '%s'.", n.Value)
+		}
+		c := t.columns[i]
+		if c.identity != nil {
+			return nil, sqlError(544, "Cannot insert explicit value for identity column in table '%s' when IDENTITY_INSERT is set to OFF.", t.name)
+		}
+		if c.computed != nil {
+			return nil, computedColumn(c.name)
+		}
+		targets = append(targets, i)
+	}
+	return targets, nil
+}
+
+func computedColumn(name string) error {
+	return sqlError(271, "The column \"%s\" cannot be modified because it is either a computed column or is the result of a UNION operator.", name)
+}
+
+func isDefault(expr ast.Expression) bool {
+	id, ok := expr.(*ast.Identifier)
+	return ok && strings.EqualFold(id.Value, "DEFAULT")
+}
+
+func (e *env) defaultValue(c column) (eval.Value, error) {
+	if c.def == nil {
+		return eval.Null(c.typ), nil
+	}
+	return e.eval(c.def)
+}
+
+// insertRows adds rows to a table, with values for the target columns and
+// defaults, IDENTITY values and computed values for the rest. Nothing is
+// added unless every row satisfies the table's constraints.
+func (s *session) insertRows(t *table, targets []int, rows [][]eval.Value, outer *env) ([]*record, error) {
+	var recs []*record
+	var identity eval.Value
+	for _, r := range rows {
+		values := make([]eval.Value, len(t.columns))
+		supplied := make([]bool, len(t.columns))
+		for j, i := range targets {
+			values[i], supplied[i] = r[j], true
+		}
+		for i, c := range t.columns {
+			var err error
+			switch {
+			case c.computed != nil:
+				continue
+			case c.identity != nil:
+				if t.seeded {
+					t.identity += c.identity.Increment
+				} else {
+					t.identity, t.seeded = c.identity.Seed, true
+				}
+				values[i] = eval.BigInt(t.identity)
+				identity = values[i]
+			case !supplied[i]:
+				if values[i], err = outer.defaultValue(c); err != nil {
+					return nil, err
+				}
+			}
+			if values[i], err = store(t, c, values[i]); err != nil {
+				return nil, err
+			}
+		}
+		if err := compute(t, values, outer); err != nil {
+			return nil, err
+		}
+		recs = append(recs, &record{values: values})
+	}
+	if err := s.validate(t, "INSERT", recs, nil, outer); err != nil {
+		return nil, err
+	}
+	t.rows = append(t.rows, recs...)
+	if !identity.Null && identity.Type.Kind != eval.KindNull {
+		v, err := eval.Convert(identity, typeIdentity)
+		if err != nil {
+			return nil, err
+		}
+		s.db.identity = v
+		s.db.scopeIdentity[s.scope] = v
+	}
+	return recs, nil
+}
+
+// store converts a value to the type of the column it is stored in.
+// Strings too long for the column are an error rather than truncated.
+func store(t *table, c column, v eval.Value) (eval.Value, error) {
+	if v.Null {
+		return eval.Null(c.typ), nil
+	}
+	if isString(v.Type.Kind) && isString(c.typ.Kind) && c.typ.Length != eval.MaxLength {
+		text := strings.TrimRight(v.Text(), " ")
+		if n := len([]rune(text)); n > max(c.typ.Length, 1) {
+			return eval.Value{}, sqlError(2628, "String or binary data would be truncated in table '%s', column '%s'. Truncated value: '%s'.",
+				t.name, c.name, string([]rune(text)[:max(c.typ.Length, 1)]))
+		}
+	}
+	return eval.Convert(v, c.typ)
+}
+
+func isString(k eval.Kind) bool {
+	return k >= eval.KindChar && k <= eval.KindNVarChar
+}
+
+// compute fills in the computed columns of a row.
+func compute(t *table, values []eval.Value, outer *env) error {
+	e := outer.with(t.refs(""), values)
+	for i, c := range t.columns {
+		if c.computed == nil {
+			continue
+		}
+		v, err := e.eval(c.computed)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	return nil
+}
+
+// validate checks new or changed rows against the table's NOT NULL,
+// CHECK, PRIMARY KEY and UNIQUE constraints. replaced holds the records
+// an UPDATE is about to change, whose old values do not count.
+func (s *session) validate(t *table, verb string, recs []*record, replaced map[*record]bool, outer *env) error {
+	for _, r := range recs {
+		for i, c := range t.columns {
+			if !c.nullable && r.values[i].Null {
+				return sqlError(515, "Cannot insert the value NULL into column '%s', table '%s'; column does not allow nulls. %s fails.", c.name, t.name, verb)
+			}
+		}
+		e := outer.with(t.refs(""), r.values)
+		for i, ck := range t.checks {
+			v, err := e.eval(ck.expr)
+			if err != nil {
+				return err
+			}
+			if v.Type.Kind == eval.KindBoolean && !v.Null && !v.Bool() {
+				name := ck.name
+				if name == "" {
+					name = fmt.Sprintf("CK__%s__%d", strings.Trim(t.name, "#@"), i+1)
+				}
+				return sqlError(547, "The %s statement conflicted with the CHECK constraint \"%s\". The conflict occurred in table \"%s\".", verb, name, t.name)
+			}
+		}
+	}
+
+	keyOf := func(r *record, k key) []eval.Value {
+		values := make([]eval.Value, len(k.columns))
+		for i, c := range k.columns {
+			values[i] = r.values[c]
+		}
+		return values
+	}
+	for _, k := range t.keys {
+		var existing [][]eval.Value
+		for _, r := range t.rows {
+			if !replaced[r] {
+				existing = append(existing, keyOf(r, k))
+			}
+		}
+		for _, r := range recs {
+			v := keyOf(r, k)
+			for _, other := range existing {
+				if !sameRow(v, other) {
+					continue
+				}
+				kind := "UNIQUE KEY"
+				if k.primary {
+					kind = "PRIMARY KEY"
+				}
+				text := make([]string, len(v))
+				for i, x := range v {
+					text[i] = x.String()
+				}
+				return sqlError(2627, "Violation of %s constraint '%s'. Cannot insert duplicate key in object '%s'. The duplicate key value is (%s).",
+					kind, k.name, t.name, strings.Join(text, ", "))
+			}
+			existing = append(existing, v)
+		}
+	}
+	return nil
+}
+
+// target finds the rows of the table an UPDATE or DELETE changes: those
+// of its FROM clause, when it has one, that meet its WHERE clause.
+func (s *session) target(name *ast.QualifiedIdentifier, alias *ast.Identifier, from *ast.FromClause, where ast.Expression, top *ast.TopClause, outer *env) (*table, []row, []*env, int, error) {
+	var rel *relation
+	src := 0
+	if from == nil {
+		t, err := s.table(name)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		qual := nameOf(name)
+		if alias != nil {
+			qual = alias.Value
+		}
+		rel = tableRelation(t, qual)
+	} else {
+		var err error
+		if rel, err = s.from(from.Tables, outer); err != nil {
+			return nil, nil, nil, 0, err
+		}
+		if src = rel.source(nameOf(name)); src < 0 {
+			t, err := s.table(name)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			rel = product(tableRelation(t, nameOf(name)), rel)
+			src = 0
+		}
+	}
+
+	var rows []row
+	var envs []*env
+	seen := make(map[*record]bool)
+	for _, r := range rel.rows {
+		rec := r.recs[src]
+		if rec == nil || seen[rec] {
+			continue
+		}
+		e := outer.with(rel.cols, r.values)
+		if where != nil {
+			ok, err := e.test(where)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		seen[rec] = true
+		rows, envs = append(rows, r), append(envs, e)
+	}
+	if top != nil {
+		n, err := count(top.Count, outer)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		if top.Percent {
+			n = (int64(len(rows))*n + 99) / 100
+		}
+		n = min(max(n, 0), int64(len(rows)))
+		rows, envs = rows[:n], envs[:n]
+	}
+	return rel.sources[src].t, rows, envs, src, nil
+}
+
+func (s *session) update(x *ast.UpdateStatement, outer *env) (*interp.Result, error) {
+	if x.Table == nil {
+		return nil, fmt.Errorf("memdb: %s is not supported", x)
+	}
+	t, rows, envs, src, err := s.target(x.Table, x.Alias, x.From, x.Where, x.Top, outer)
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]*record, len(rows))
+	replaced := make(map[*record]bool)
+	for n, r := range rows {
+		old := r.recs[src]
+		values := append([]eval.Value(nil), old.values...)
+		for _, sc := range x.SetClauses {
+			if sc.IsMethodCall {
+				return nil, fmt.Errorf("memdb: %s is not supported", x)
+			}
+			name := nameOf(sc.Column)
+			op := strings.TrimSuffix(sc.Operator, "=")
+			if strings.HasPrefix(name, "@") {
+				// SET @v = expression assigns the variable for each row
+				v, err := envs[n].eval(sc.Value)
+				if err != nil {
+					return nil, err
+				}
+				if op != "" {
+					current, err := s.variable(name)
+					if err != nil {
+						return nil, err
+					}
+					if v, err = eval.Arithmetic(op, current, v); err != nil {
+						return nil, err
+					}
+				}
+				if err := s.scope.Set(name, v); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			i := t.column(name)
+			if i < 0 {
+				return nil, sqlError(207, "Invalid column name '%s'.", name)
+			}
+			c := t.columns[i]
+			switch {
+			case c.identity != nil:
+				return nil, sqlError(8102, "Cannot update identity column '%s'.", c.name)
+			case c.computed != nil:
+				return nil, computedColumn(c.name)
+			}
+			var v eval.Value
+			if isDefault(sc.Value) {
+				v, err = outer.defaultValue(c)
+			} else {
+				v, err = envs[n].eval(sc.Value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if op != "" {
+				if v, err = eval.Arithmetic(op, old.values[i], v); err != nil {
+					return nil, err
+				}
+			}
+			if values[i], err = store(t, c, v); err != nil {
+				return nil, err
+			}
+		}
+		if err := compute(t, values, outer); err != nil {
+			return nil, err
+		}
+		recs[n] = &record{values: values}
+		replaced[old] = true
+	}
+	if err := s.validate(t, "UPDATE", recs, replaced, outer); err != nil {
+		return nil, err
+	}
+
+	deleted := make([][]eval.Value, len(rows))
+	inserted := make([][]eval.Value, len(rows))
+	for n, r := range rows {
+		old := r.recs[src]
+		deleted[n], inserted[n] = old.values, recs[n].values
+		old.values = recs[n].values
+	}
+	return s.output(x.Output, t, deleted, inserted, outer)
+}
+
+func (s *session) delete(x *ast.DeleteStatement, outer *env) (*interp.Result, error) {
+	name, alias := x.Table, x.Alias
+	if name == nil && alias != nil {
+		// The parser reads the target of DELETE alias FROM ... and of
+		// DELETE t OUTPUT ... as an alias
+		name, alias = &ast.QualifiedIdentifier{Parts: []*ast.Identifier{alias}}, nil
+	}
+	if name == nil {
+		return nil, fmt.Errorf("memdb: %s is not supported", x)
+	}
+	t, rows, _, src, err := s.target(name, alias, x.From, x.Where, x.Top, outer)
+	if err != nil {
+		return nil, err
+	}
+	gone := make(map[*record]bool)
+	deleted := make([][]eval.Value, len(rows))
+	for n, r := range rows {
+		gone[r.recs[src]] = true
+		deleted[n] = r.recs[src].values
+	}
+	var kept []*record
+	for _, r := range t.rows {
+		if !gone[r] {
+			kept = append(kept, r)
+		}
+	}
+	t.rows = kept
+	return s.output(x.Output, t, deleted, nil, outer)
+}
+
+// output produces the result of a data modification statement: the
+// number of rows it changed and the rows of its OUTPUT clause, which
+// refer to the old and new values as deleted and inserted.
+func (s *session) output(o *ast.OutputClause, t *table, deleted, inserted [][]eval.Value, outer *env) (*interp.Result, error) {
+	n := max(len(deleted), len(inserted))
+	res := &interp.Result{RowsAffected: int64(n)}
+	if o == nil {
+		return res, nil
+	}
+	cols := append(t.refs("inserted"), t.refs("deleted")...)
+	none := (&relation{cols: t.refs("")}).nulls().values
+	names, err := outputColumns(o.Columns, cols)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]eval.Value
+	for i := 0; i < n; i++ {
+		ins, del := none, none
+		if inserted != nil {
+			ins = inserted[i]
+		}
+		if deleted != nil {
+			del = deleted[i]
+		}
+		values, err := project(o.Columns, outer.with(cols, append(append([]eval.Value(nil), ins...), del...)))
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, values)
+	}
+
+	if o.Into == nil && o.IntoVariable == nil {
+		res.Columns = make([]string, len(names))
+		for i, c := range names {
+			res.Columns[i] = c.name
+		}
+		res.Rows = rows
+		return res, nil
+	}
+	into := o.Into
+	if o.IntoVariable != nil {
+		into = &ast.QualifiedIdentifier{Parts: []*ast.Identifier{{Value: o.IntoVariable.Name}}}
+	}
+	dest, err := s.table(into)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := insertColumns(dest, o.IntoColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) != len(names) {
+		return nil, sqlError(213, "Column name or number of supplied values does not match table definition.")
+	}
+	if _, err := s.insertRows(dest, targets, rows, outer); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
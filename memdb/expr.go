@@ -0,0 +1,336 @@
+package memdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+)
+
+// env is what an expression is evaluated against: the current row of a
+// query, the values of aggregates and window functions for that row, the
+// CTEs in scope, and the row of the enclosing query for correlated
+// subqueries.
+type env struct {
+	s      *session
+	cols   []colRef
+	row    []eval.Value
+	values map[*ast.FunctionCall]eval.Value
+	ctes   map[string]*relation
+	outer  *env
+}
+
+// colRef names a column of a relation: the table name or alias it is
+// qualified by, and the column name.
+type colRef struct {
+	qual string
+	name string
+}
+
+// with returns an env for a row of cols, with e as its enclosing env.
+func (e *env) with(cols []colRef, row []eval.Value) *env {
+	return &env{s: e.s, cols: cols, row: row, outer: e}
+}
+
+func (e *env) eval(expr ast.Expression) (eval.Value, error) {
+	ev := eval.New()
+	ev.Now = e.s.scope.Evaluator().Now
+	ev.Resolve = e.resolve
+	return ev.Eval(expr)
+}
+
+// test evaluates a search condition. UNKNOWN is false.
+func (e *env) test(expr ast.Expression) (bool, error) {
+	v, err := e.eval(expr)
+	if err != nil {
+		return false, err
+	}
+	if v.Type.Kind != eval.KindBoolean {
+		return false, sqlError(4145, "An expression of non-boolean type specified in a context where a condition is expected, near '%s'.", expr)
+	}
+	return !v.Null && v.Bool(), nil
+}
+
+func (e *env) resolve(expr ast.Expression) (eval.Value, error) {
+	switch x := expr.(type) {
+	case *ast.Identifier:
+		return e.column("", x.Value, x.Value)
+	case *ast.QualifiedIdentifier:
+		n := len(x.Parts)
+		if n == 1 {
+			return e.column("", x.Parts[0].Value, x.Parts[0].Value)
+		}
+		return e.column(x.Parts[n-2].Value, x.Parts[n-1].Value, x.String())
+	case *ast.Variable:
+		return e.s.variable(x.Name)
+	case *ast.FunctionCall:
+		for o := e; o != nil; o = o.outer {
+			if v, ok := o.values[x]; ok {
+				return v, nil
+			}
+		}
+		return e.function(x)
+	case *ast.SubqueryExpression:
+		return e.scalar(x.Subquery)
+	case *ast.ExistsExpression:
+		rel, err := e.s.query(x.Subquery, e)
+		if err != nil {
+			return eval.Value{}, err
+		}
+		return eval.Bool(len(rel.rows) > 0), nil
+	case *ast.InExpression:
+		if x.Subquery != nil {
+			return e.in(x)
+		}
+	}
+	return eval.Value{}, fmt.Errorf("memdb: cannot evaluate %s", expr)
+}
+
+// column finds a column in the current row or, for outer references, in
+// the rows of the enclosing queries.
+func (e *env) column(qual, name, text string) (eval.Value, error) {
+	for o := e; o != nil; o = o.outer {
+		found := -1
+		for i, c := range o.cols {
+			if !strings.EqualFold(c.name, name) || (qual != "" && !strings.EqualFold(c.qual, qual)) {
+				continue
+			}
+			if found >= 0 {
+				return eval.Value{}, sqlError(209, "Ambiguous column name '%s'.", name)
+			}
+			found = i
+		}
+		if found >= 0 {
+			return o.row[found], nil
+		}
+	}
+	if qual != "" {
+		return eval.Value{}, sqlError(4104, "The multi-part identifier \"%s\" could not be bound.", text)
+	}
+	return eval.Value{}, sqlError(207, "Invalid column name '%s'.", name)
+}
+
+func (s *session) variable(name string) (eval.Value, error) {
+	if strings.EqualFold(name, "@@IDENTITY") {
+		return s.db.identity, nil
+	}
+	if v, ok := s.scope.Get(name); ok {
+		return v, nil
+	}
+	return eval.Value{}, sqlError(137, "Must declare the scalar variable \"%s\".", name)
+}
+
+// function calls a function the evaluator does not know. Functions the
+// host defined on the scope, such as ERROR_MESSAGE, are evaluated there
+// with the arguments evaluated against the current row.
+func (e *env) function(x *ast.FunctionCall) (eval.Value, error) {
+	name := functionName(x)
+	if x.Over != nil || isAggregate(name) {
+		return eval.Value{}, sqlError(4109, "Windowed functions and aggregates cannot be used in this context: %s.", x)
+	}
+	switch name {
+	case "SCOPE_IDENTITY":
+		if v, ok := e.s.db.scopeIdentity[e.s.scope]; ok {
+			return v, nil
+		}
+		return eval.Null(typeIdentity), nil
+	case "OBJECT_ID":
+		if len(x.Arguments) == 0 {
+			break
+		}
+		v, err := e.eval(x.Arguments[0])
+		if err != nil || v.Null {
+			return eval.Null(eval.Type{Kind: eval.KindInt}), err
+		}
+		name := strings.TrimPrefix(strings.ToLower(v.Text()), "tempdb..")
+		if t, ok := e.s.db.tables[tableKey(name)]; ok {
+			return eval.Int(t.id), nil
+		}
+		return eval.Null(eval.Type{Kind: eval.KindInt}), nil
+	}
+
+	c := *x
+	c.Arguments = make([]ast.Expression, len(x.Arguments))
+	for i, arg := range x.Arguments {
+		v, err := e.eval(arg)
+		if err != nil {
+			return eval.Value{}, err
+		}
+		c.Arguments[i] = v.Literal()
+	}
+	v, err := e.s.scope.Evaluator().Eval(&c)
+	if errors.Is(err, eval.ErrNotConstant) {
+		return eval.Value{}, sqlError(195, "'%s' is not a recognized built-in function name.", strings.ToLower(name))
+	}
+	return v, err
+}
+
+// scalar runs a subquery used as an expression.
+func (e *env) scalar(sub *ast.SelectStatement) (eval.Value, error) {
+	rel, err := e.s.query(sub, e)
+	if err != nil {
+		return eval.Value{}, err
+	}
+	if len(rel.cols) != 1 {
+		return eval.Value{}, sqlError(116, "Only one expression can be specified in the select list when the subquery is not introduced with EXISTS.")
+	}
+	switch len(rel.rows) {
+	case 0:
+		return eval.Value{Null: true}, nil
+	case 1:
+		return rel.rows[0].values[0], nil
+	}
+	return eval.Value{}, sqlError(512, "Subquery returned more than 1 value. This is not permitted when the subquery follows =, !=, <, <= , >, >= or when the subquery is used as an expression.")
+}
+
+// in evaluates x IN (subquery): true when any row matches, otherwise
+// UNKNOWN when x or any row is NULL.
+func (e *env) in(x *ast.InExpression) (eval.Value, error) {
+	v, err := e.eval(x.Expr)
+	if err != nil {
+		return eval.Value{}, err
+	}
+	rel, err := e.s.query(x.Subquery, e)
+	if err != nil {
+		return eval.Value{}, err
+	}
+	if len(rel.cols) != 1 {
+		return eval.Value{}, sqlError(116, "Only one expression can be specified in the select list when the subquery is not introduced with EXISTS.")
+	}
+	r := eval.Bool(false)
+	for _, row := range rel.rows {
+		candidate := row.values[0]
+		if v.Null || candidate.Null {
+			r = eval.Unknown()
+			continue
+		}
+		c, err := eval.Compare(v, candidate)
+		if err != nil {
+			return eval.Value{}, err
+		}
+		if c == 0 {
+			r = eval.Bool(true)
+			break
+		}
+	}
+	if x.Not && !r.Null {
+		return eval.Bool(!r.Bool()), nil
+	}
+	return r, nil
+}
+
+func functionName(x *ast.FunctionCall) string {
+	switch f := x.Function.(type) {
+	case *ast.Identifier:
+		return strings.ToUpper(f.Value)
+	case *ast.QualifiedIdentifier:
+		return strings.ToUpper(nameOf(f))
+	}
+	return ""
+}
+
+// same reports whether two values are equal for grouping, DISTINCT and
+// keys, where NULL equals NULL.
+func same(a, b eval.Value) bool {
+	c, err := eval.Compare(a, b)
+	return err == nil && c == 0
+}
+
+func sameRow(a, b []eval.Value) bool {
+	for i := range a {
+		if !same(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// calls returns the aggregate or, with windowed set, the window function
+// calls in exprs. Subqueries are not searched; their aggregates are their
+// own.
+func calls(windowed bool, exprs ...ast.Expression) []*ast.FunctionCall {
+	var found []*ast.FunctionCall
+	var walk func(ast.Expression)
+	walk = func(expr ast.Expression) {
+		switch x := expr.(type) {
+		case *ast.FunctionCall:
+			if x.Over != nil {
+				if windowed {
+					found = append(found, x)
+					return
+				}
+				for _, p := range x.Over.PartitionBy {
+					walk(p)
+				}
+				for _, o := range x.Over.OrderBy {
+					walk(o.Expression)
+				}
+			} else if !windowed && isAggregate(functionName(x)) {
+				found = append(found, x)
+				return
+			}
+			for _, arg := range x.Arguments {
+				walk(arg)
+			}
+		case *ast.PrefixExpression:
+			walk(x.Right)
+		case *ast.InfixExpression:
+			walk(x.Left)
+			walk(x.Right)
+		case *ast.CollateExpression:
+			walk(x.Expr)
+		case *ast.BetweenExpression:
+			walk(x.Expr)
+			walk(x.Low)
+			walk(x.High)
+		case *ast.InExpression:
+			walk(x.Expr)
+			for _, v := range x.Values {
+				walk(v)
+			}
+		case *ast.LikeExpression:
+			walk(x.Expr)
+			walk(x.Pattern)
+		case *ast.IsNullExpression:
+			walk(x.Expr)
+		case *ast.IsDistinctFromExpression:
+			walk(x.Left)
+			walk(x.Right)
+		case *ast.CaseExpression:
+			walk(x.Operand)
+			for _, w := range x.WhenClauses {
+				walk(w.Condition)
+				walk(w.Result)
+			}
+			walk(x.ElseClause)
+		case *ast.CastExpression:
+			walk(x.Expression)
+		case *ast.ConvertExpression:
+			walk(x.Expression)
+			walk(x.Style)
+		case *ast.TrimExpression:
+			walk(x.Characters)
+			walk(x.Expression)
+		case *ast.GreatestLeastExpression:
+			for _, arg := range x.Arguments {
+				walk(arg)
+			}
+		case *ast.DateTruncExpression:
+			walk(x.Date)
+		}
+	}
+	for _, expr := range exprs {
+		walk(expr)
+	}
+	return found
+}
+
+func isAggregate(name string) bool {
+	switch name {
+	case "COUNT", "COUNT_BIG", "SUM", "AVG", "MIN", "MAX", "STRING_AGG":
+		return true
+	}
+	return false
+}
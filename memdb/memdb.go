@@ -0,0 +1,357 @@
+// Package memdb is an in-memory database for the interp package: a
+// QueryExecutor that runs queries and data modification against tables
+// held in memory, so procedure logic can be tested without a SQL Server.
+//
+//	db := memdb.New()
+//	in := interp.New(db)
+//	out, err := in.Run(program)
+//
+// Tables are created by the script being run: CREATE TABLE for #temp and
+// other tables, DECLARE @t TABLE (...) for table variables, and SELECT
+// ... INTO. Queries support joins and APPLY, WHERE, GROUP BY and HAVING,
+// aggregates, window functions, DISTINCT, TOP and OFFSET/FETCH, UNION,
+// INTERSECT and EXCEPT, CTEs including recursive ones, and subqueries.
+// INSERT, UPDATE and DELETE enforce NOT NULL, PRIMARY KEY, UNIQUE and
+// CHECK constraints, fill in defaults and IDENTITY values, and support
+// the OUTPUT clause.
+//
+// Every table lives for the life of the DB, #temp tables included; table
+// variables belong to the scope that declared them.
+package memdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/interp"
+)
+
+// DB holds the tables. It is not safe for concurrent use.
+type DB struct {
+	tables   map[string]*table
+	vars     map[*interp.Scope]map[string]*table
+	objectID int64
+
+	// Last IDENTITY values inserted, for @@IDENTITY and SCOPE_IDENTITY
+	identity      eval.Value
+	scopeIdentity map[*interp.Scope]eval.Value
+}
+
+// New returns an empty DB.
+func New() *DB {
+	return &DB{
+		tables:        make(map[string]*table),
+		vars:          make(map[*interp.Scope]map[string]*table),
+		identity:      eval.Null(typeIdentity),
+		scopeIdentity: make(map[*interp.Scope]eval.Value),
+	}
+}
+
+// typeIdentity is the type of @@IDENTITY and SCOPE_IDENTITY().
+var typeIdentity = eval.Type{Kind: eval.KindDecimal, Precision: 38}
+
+type table struct {
+	id      int64
+	name    string
+	columns []column
+	keys    []key
+	checks  []check
+	rows    []*record
+
+	identity int64 // Last IDENTITY value, when seeded is set
+	seeded   bool
+}
+
+type column struct {
+	name     string
+	typ      eval.Type
+	nullable bool
+	def      ast.Expression
+	identity *ast.IdentitySpec
+	computed ast.Expression
+}
+
+// key is a PRIMARY KEY or UNIQUE constraint.
+type key struct {
+	name    string
+	primary bool
+	columns []int
+}
+
+type check struct {
+	name string
+	expr ast.Expression
+}
+
+type record struct {
+	values []eval.Value
+}
+
+// Rows returns the columns and rows of a table created by CREATE TABLE or
+// SELECT ... INTO, for inspecting what a script left behind.
+func (db *DB) Rows(name string) (*interp.Result, bool) {
+	t, ok := db.tables[tableKey(name)]
+	if !ok {
+		return nil, false
+	}
+	res := &interp.Result{Columns: make([]string, len(t.columns)), RowsAffected: int64(len(t.rows))}
+	for i, c := range t.columns {
+		res.Columns[i] = c.name
+	}
+	for _, r := range t.rows {
+		res.Rows = append(res.Rows, append([]eval.Value(nil), r.values...))
+	}
+	return res, true
+}
+
+// tableKey is the name a table is stored under: the last part of its
+// name, lower-cased, so dbo.Orders and [orders] are the same table.
+func tableKey(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "[]"))
+}
+
+func nameOf(q *ast.QualifiedIdentifier) string {
+	return q.Parts[len(q.Parts)-1].Value
+}
+
+// Execute runs a statement the interpreter hands over.
+func (db *DB) Execute(stmt ast.Statement, scope *interp.Scope) (*interp.Result, error) {
+	s := &session{db: db, scope: scope}
+	return s.statement(stmt, &env{s: s})
+}
+
+// session is the DB and the scope of the statement being run.
+type session struct {
+	db    *DB
+	scope *interp.Scope
+}
+
+func (s *session) statement(stmt ast.Statement, outer *env) (*interp.Result, error) {
+	switch x := stmt.(type) {
+	case *ast.SelectStatement:
+		return s.selectStatement(x, outer)
+	case *ast.InsertStatement:
+		return s.insert(x, outer)
+	case *ast.UpdateStatement:
+		return s.update(x, outer)
+	case *ast.DeleteStatement:
+		return s.delete(x, outer)
+	case *ast.WithStatement:
+		e, err := s.with(x, outer)
+		if err != nil {
+			return nil, err
+		}
+		return s.statement(x.Query, e)
+	case *ast.DeclareStatement:
+		for _, v := range x.Variables {
+			if v.TableType == nil {
+				return nil, fmt.Errorf("memdb: unexpected scalar variable %s", v.Name)
+			}
+			t, err := s.db.newTable(v.Name, v.TableType.Columns, v.TableType.Constraints)
+			if err != nil {
+				return nil, err
+			}
+			if s.db.vars[s.scope] == nil {
+				s.db.vars[s.scope] = make(map[string]*table)
+			}
+			s.db.vars[s.scope][strings.ToLower(v.Name)] = t
+		}
+		return &interp.Result{}, nil
+	case *ast.CreateTableStatement:
+		return s.createTable(x, outer)
+	case *ast.DropTableStatement:
+		for _, name := range x.Tables {
+			k := tableKey(nameOf(name))
+			if _, ok := s.db.tables[k]; !ok {
+				if x.IfExists {
+					continue
+				}
+				return nil, sqlError(3701, "Cannot drop the table '%s', because it does not exist or you do not have permission.", name)
+			}
+			delete(s.db.tables, k)
+		}
+		return &interp.Result{}, nil
+	case *ast.TruncateTableStatement:
+		t, err := s.table(x.Table)
+		if err != nil {
+			return nil, err
+		}
+		t.rows, t.seeded = nil, false
+		return &interp.Result{}, nil
+	}
+	return nil, fmt.Errorf("memdb: %s statements are not supported", strings.Fields(stmt.String())[0])
+}
+
+// table looks up a table or table variable.
+func (s *session) table(name *ast.QualifiedIdentifier) (*table, error) {
+	n := nameOf(name)
+	if strings.HasPrefix(n, "@") {
+		if t, ok := s.db.vars[s.scope][strings.ToLower(n)]; ok {
+			return t, nil
+		}
+		return nil, sqlError(1087, "Must declare the table variable \"%s\".", n)
+	}
+	if t, ok := s.db.tables[tableKey(n)]; ok {
+		return t, nil
+	}
+	return nil, sqlError(208, "Invalid object name '%s'.", name)
+}
+
+func (s *session) createTable(x *ast.CreateTableStatement, outer *env) (*interp.Result, error) {
+	name := nameOf(x.Name)
+	if _, ok := s.db.tables[tableKey(name)]; ok {
+		return nil, sqlError(2714, "There is already an object named '%s' in the database.", name)
+	}
+	if x.AsSelect != nil {
+		query, e := x.AsSelect, outer
+		if w, ok := query.(*ast.WithStatement); ok {
+			var err error
+			if e, err = s.with(w, outer); err != nil {
+				return nil, err
+			}
+			query = w.Query
+		}
+		sel, ok := query.(*ast.SelectStatement)
+		if !ok {
+			return nil, fmt.Errorf("memdb: CREATE TABLE AS %s is not supported", query.TokenLiteral())
+		}
+		rel, err := s.query(sel, e)
+		if err != nil {
+			return nil, err
+		}
+		return s.into(name, rel)
+	}
+	t, err := s.db.newTable(name, x.Columns, x.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	s.db.tables[tableKey(name)] = t
+	return &interp.Result{}, nil
+}
+
+// newTable builds a table from column definitions and table constraints.
+func (db *DB) newTable(name string, defs []*ast.ColumnDefinition, constraints []*ast.TableConstraint) (*table, error) {
+	db.objectID++
+	t := &table{id: db.objectID, name: name}
+	for _, d := range defs {
+		c := column{name: d.Name.Value, nullable: true, def: d.Default, identity: d.Identity, computed: d.Computed}
+		if d.DataType != nil {
+			typ, err := eval.TypeOf(d.DataType)
+			if err != nil {
+				return nil, err
+			}
+			c.typ = typ
+		}
+		if d.Nullable != nil {
+			c.nullable = *d.Nullable
+		}
+		if c.identity != nil {
+			c.nullable = false
+		}
+		t.columns = append(t.columns, c)
+		i := len(t.columns) - 1
+		for _, cc := range d.Constraints {
+			switch cc.Type {
+			case ast.ConstraintPrimaryKey, ast.ConstraintUnique:
+				t.keys = append(t.keys, key{name: cc.Name, primary: cc.Type == ast.ConstraintPrimaryKey, columns: []int{i}})
+			case ast.ConstraintCheck:
+				t.checks = append(t.checks, check{name: cc.Name, expr: cc.CheckExpression})
+			}
+		}
+	}
+	for _, tc := range constraints {
+		switch tc.Type {
+		case ast.ConstraintPrimaryKey, ast.ConstraintUnique:
+			k := key{name: tc.Name, primary: tc.Type == ast.ConstraintPrimaryKey}
+			for _, ic := range tc.Columns {
+				i := t.column(ic.Name.Value)
+				if i < 0 {
+					return nil, sqlError(1911, "Column name '%s' does not exist in the target table or view.", ic.Name.Value)
+				}
+				k.columns = append(k.columns, i)
+			}
+			t.keys = append(t.keys, k)
+		case ast.ConstraintCheck:
+			t.checks = append(t.checks, check{name: tc.Name, expr: tc.CheckExpression})
+		case ast.ConstraintDefault:
+			if tc.ForColumn != nil {
+				if i := t.column(tc.ForColumn.Value); i >= 0 {
+					t.columns[i].def = tc.DefaultExpression
+				}
+			}
+		}
+	}
+	for i, k := range t.keys {
+		if k.name == "" {
+			prefix := "UQ"
+			if k.primary {
+				prefix = "PK"
+			}
+			t.keys[i].name = fmt.Sprintf("%s__%s__%d", prefix, strings.Trim(name, "#@"), i+1)
+		}
+		if k.primary {
+			for _, c := range k.columns {
+				t.columns[c].nullable = false
+			}
+		}
+	}
+	return t, nil
+}
+
+// column returns the index of the named column, or -1.
+func (t *table) column(name string) int {
+	for i, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// into creates a table from the result of SELECT ... INTO, typing each
+// column by the values it holds.
+func (s *session) into(name string, rel *relation) (*interp.Result, error) {
+	if _, ok := s.db.tables[tableKey(name)]; ok {
+		return nil, sqlError(2714, "There is already an object named '%s' in the database.", name)
+	}
+	s.db.objectID++
+	t := &table{id: s.db.objectID, name: name}
+	for i, c := range rel.cols {
+		if c.name == "" {
+			return nil, sqlError(1038, "An object or column name is missing or empty. For SELECT INTO statements, verify each column has a name.")
+		}
+		typ := eval.Type{Kind: eval.KindInt}
+		for _, r := range rel.rows {
+			if v := r.values[i]; v.Type.Kind != eval.KindNull {
+				typ = v.Type
+				break
+			}
+		}
+		if typ.Kind == eval.KindBoolean {
+			typ = eval.Type{Kind: eval.KindBit}
+		}
+		t.columns = append(t.columns, column{name: c.name, typ: typ, nullable: true})
+	}
+	for _, r := range rel.rows {
+		values := make([]eval.Value, len(r.values))
+		for i, v := range r.values {
+			v, err := eval.Convert(v, t.columns[i].typ)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		t.rows = append(t.rows, &record{values: values})
+	}
+	s.db.tables[tableKey(name)] = t
+	return &interp.Result{RowsAffected: int64(len(t.rows))}, nil
+}
+
+func sqlError(number int, format string, args ...any) *eval.Error {
+	return &eval.Error{Number: number, Severity: 16, State: 1, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,301 @@
+package memdb
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/interp"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func run(t *testing.T, db *DB, input string) *interp.Outcome {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	out, err := interp.New(db).Run(program)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out
+}
+
+// rows formats a result set one row per string, values separated by |.
+func rows(res *interp.Result) []string {
+	var out []string
+	for _, r := range res.Rows {
+		text := make([]string, len(r))
+		for i, v := range r {
+			text[i] = v.String()
+		}
+		out = append(out, strings.Join(text, "|"))
+	}
+	return out
+}
+
+const fixture = `
+CREATE TABLE #dept (id INT PRIMARY KEY, name VARCHAR(20) NOT NULL)
+CREATE TABLE #emp (
+    id INT IDENTITY(1, 1) PRIMARY KEY,
+    name VARCHAR(20) NOT NULL,
+    dept INT NULL,
+    salary DECIMAL(10, 2) NOT NULL DEFAULT 1000,
+    manager INT NULL
+)
+INSERT INTO #dept VALUES (1, 'Sales'), (2, 'Ops'), (3, 'Legal')
+INSERT INTO #emp (name, dept, salary, manager) VALUES
+    ('Ann', 1, 5000, NULL),
+    ('Bob', 1, 3000, 1),
+    ('Cid', 2, 4000, 1),
+    ('Dee', 2, 4000, 3),
+    ('Eve', NULL, 2000, 4)
+`
+
+func TestQueries(t *testing.T) {
+	tests := []struct {
+		query    string
+		columns  []string
+		expected []string
+	}{
+		{"SELECT name FROM #emp WHERE salary > 3000 ORDER BY name DESC", []string{"name"}, []string{"Dee", "Cid", "Ann"}},
+		{"SELECT e.name, d.name AS dept FROM #emp e JOIN #dept d ON d.id = e.dept ORDER BY e.id",
+			[]string{"name", "dept"}, []string{"Ann|Sales", "Bob|Sales", "Cid|Ops", "Dee|Ops"}},
+		{"SELECT e.name, d.name FROM #emp e LEFT JOIN #dept d ON d.id = e.dept WHERE d.id IS NULL", nil, []string{"Eve|NULL"}},
+		{"SELECT d.name, e.name FROM #emp e RIGHT JOIN #dept d ON d.id = e.dept WHERE e.id IS NULL", nil, []string{"Legal|NULL"}},
+		{"SELECT COUNT(*) FROM #emp e FULL JOIN #dept d ON d.id = e.dept", nil, []string{"6"}},
+		{"SELECT TOP 2 name FROM #emp ORDER BY salary DESC, name", nil, []string{"Ann", "Cid"}},
+		{"SELECT TOP 2 WITH TIES name FROM #emp ORDER BY salary DESC", nil, []string{"Ann", "Cid", "Dee"}},
+		{"SELECT TOP 50 PERCENT name FROM #emp ORDER BY id", nil, []string{"Ann", "Bob", "Cid"}},
+		{"SELECT name FROM #emp ORDER BY id OFFSET 1 ROWS FETCH NEXT 2 ROWS ONLY", nil, []string{"Bob", "Cid"}},
+		{"SELECT DISTINCT salary FROM #emp ORDER BY 1", nil, []string{"2000.00", "3000.00", "4000.00", "5000.00"}},
+		{"SELECT d.* FROM #dept d WHERE d.name LIKE 'S%'", []string{"id", "name"}, []string{"1|Sales"}},
+		{"SELECT name, salary * 2 AS doubled FROM #emp WHERE id = 5", []string{"name", "doubled"}, []string{"Eve|4000.00"}},
+		{"SELECT x, y FROM (VALUES (1, 'a'), (2, 'b')) AS v(x, y) ORDER BY x DESC", nil, []string{"2|b", "1|a"}},
+		{"SELECT n FROM (SELECT name AS n, dept FROM #emp) AS t WHERE dept = 2", nil, []string{"Cid", "Dee"}},
+
+		// Grouping and aggregates
+		{"SELECT dept, COUNT(*) AS n, SUM(salary), AVG(salary), MIN(name), MAX(name) FROM #emp GROUP BY dept ORDER BY dept",
+			[]string{"dept", "n", "", "", "", ""},
+			[]string{"NULL|1|2000.00|2000.000000|Eve|Eve", "1|2|8000.00|4000.000000|Ann|Bob", "2|2|8000.00|4000.000000|Cid|Dee"}},
+		{"SELECT dept FROM #emp GROUP BY dept HAVING COUNT(*) > 1 AND SUM(salary) > 7000 ORDER BY dept", nil, []string{"1", "2"}},
+		{"SELECT COUNT(DISTINCT salary), COUNT(dept), COUNT(*) FROM #emp", nil, []string{"4|4|5"}},
+		{"SELECT COUNT(*), SUM(salary) FROM #emp WHERE 1 = 0", nil, []string{"0|NULL"}},
+		{"SELECT dept, GREATEST(MAX(salary), 4500) FROM #emp WHERE dept IS NOT NULL GROUP BY dept ORDER BY dept", nil, []string{"1|5000.00", "2|4500.00"}},
+		{"SELECT STRING_AGG(name, ',') WITHIN GROUP (ORDER BY name DESC) FROM #emp WHERE dept = 1", nil, []string{"Bob,Ann"}},
+		{"SELECT d.name, COUNT(e.id) FROM #dept d LEFT JOIN #emp e ON e.dept = d.id GROUP BY d.name ORDER BY COUNT(e.id), d.name",
+			nil, []string{"Legal|0", "Ops|2", "Sales|2"}},
+
+		// Set operations
+		{"SELECT dept FROM #emp UNION SELECT id FROM #dept ORDER BY 1", nil, []string{"NULL", "1", "2", "3"}},
+		{"SELECT dept FROM #emp WHERE dept = 1 UNION ALL SELECT id FROM #dept WHERE id = 1", nil, []string{"1", "1", "1"}},
+		{"SELECT id FROM #dept INTERSECT SELECT dept FROM #emp", nil, []string{"1", "2"}},
+		{"SELECT id FROM #dept EXCEPT SELECT dept FROM #emp", nil, []string{"3"}},
+
+		// Subqueries
+		{"SELECT name FROM #dept d WHERE EXISTS (SELECT 1 FROM #emp e WHERE e.dept = d.id AND e.salary > 4500)", nil, []string{"Sales"}},
+		{"SELECT name FROM #dept WHERE id NOT IN (SELECT dept FROM #emp WHERE dept IS NOT NULL)", nil, []string{"Legal"}},
+		{"SELECT name, (SELECT COUNT(*) FROM #emp e WHERE e.dept = d.id) FROM #dept d ORDER BY id", nil, []string{"Sales|2", "Ops|2", "Legal|0"}},
+		{"SELECT e.name, m.name FROM #emp e JOIN #emp m ON m.id = e.manager WHERE e.salary = (SELECT MAX(salary) FROM #emp x WHERE x.manager IS NOT NULL)",
+			nil, []string{"Cid|Ann", "Dee|Cid"}},
+
+		// APPLY
+		{"SELECT d.name, s.value FROM #dept d CROSS APPLY STRING_SPLIT(d.name, 'a') AS s WHERE d.id = 1", nil, []string{"Sales|S", "Sales|les"}},
+		{"SELECT d.name, t.name FROM #dept d OUTER APPLY (SELECT TOP 1 name FROM #emp e WHERE e.dept = d.id ORDER BY salary) AS t ORDER BY d.id",
+			nil, []string{"Sales|Bob", "Ops|Cid", "Legal|NULL"}},
+
+		// Window functions
+		{"SELECT name, ROW_NUMBER() OVER (ORDER BY salary DESC, name), RANK() OVER (ORDER BY salary DESC), DENSE_RANK() OVER (ORDER BY salary DESC) FROM #emp ORDER BY id",
+			nil, []string{"Ann|1|1|1", "Bob|4|4|3", "Cid|2|2|2", "Dee|3|2|2", "Eve|5|5|4"}},
+		{"SELECT name, SUM(salary) OVER (PARTITION BY dept), SUM(salary) OVER (ORDER BY id) FROM #emp WHERE dept IS NOT NULL ORDER BY id",
+			nil, []string{"Ann|8000.00|5000.00", "Bob|8000.00|8000.00", "Cid|8000.00|12000.00", "Dee|8000.00|16000.00"}},
+		{"SELECT name, LAG(name) OVER (ORDER BY id), LEAD(name, 2, '-') OVER (ORDER BY id) FROM #emp ORDER BY id",
+			nil, []string{"Ann|NULL|Cid", "Bob|Ann|Dee", "Cid|Bob|Eve", "Dee|Cid|-", "Eve|Dee|-"}},
+		{"SELECT id, AVG(id) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING), FIRST_VALUE(name) OVER (PARTITION BY dept ORDER BY id) FROM #emp ORDER BY id",
+			nil, []string{"1|1|Ann", "2|2|Ann", "3|3|Cid", "4|4|Cid", "5|4|Eve"}},
+		{"SELECT id, NTILE(2) OVER (ORDER BY id), COUNT(*) OVER () FROM #emp ORDER BY id", nil, []string{"1|1|5", "2|1|5", "3|1|5", "4|2|5", "5|2|5"}},
+		{"SELECT dept, SUM(salary), RANK() OVER (ORDER BY SUM(salary) DESC) FROM #emp WHERE dept IS NOT NULL GROUP BY dept ORDER BY dept",
+			nil, []string{"1|8000.00|1", "2|8000.00|1"}},
+
+		// CTEs
+		{"WITH big AS (SELECT * FROM #emp WHERE salary >= 4000) SELECT COUNT(*) FROM big", nil, []string{"3"}},
+		{"WITH n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT SUM(i), COUNT(*) FROM n", nil, []string{"15|5"}},
+		{`WITH chain AS (
+		    SELECT id, name, 0 AS depth FROM #emp WHERE manager IS NULL
+		    UNION ALL
+		    SELECT e.id, e.name, c.depth + 1 FROM #emp e JOIN chain c ON e.manager = c.id
+		) SELECT name, depth FROM chain ORDER BY depth, name`,
+			nil, []string{"Ann|0", "Bob|1", "Cid|1", "Dee|2", "Eve|3"}},
+	}
+
+	for _, tt := range tests {
+		db := New()
+		out := run(t, db, fixture+"\n"+tt.query)
+		if len(out.Results) != 1 {
+			t.Errorf("%s: expected 1 result set, got %d", tt.query, len(out.Results))
+			continue
+		}
+		res := out.Results[0]
+		if tt.columns != nil && !reflect.DeepEqual(res.Columns, tt.columns) {
+			t.Errorf("%s: expected columns %v, got %v", tt.query, tt.columns, res.Columns)
+		}
+		if got := rows(res); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s:\nexpected %v\ngot      %v", tt.query, tt.expected, got)
+		}
+	}
+}
+
+func TestModification(t *testing.T) {
+	db := New()
+	out := run(t, db, fixture+`
+DECLARE @ids TABLE (id INT, old DECIMAL(10, 2), new DECIMAL(10, 2))
+INSERT INTO #emp (name) VALUES ('Fay')
+PRINT SCOPE_IDENTITY()
+PRINT @@ROWCOUNT
+
+UPDATE e SET salary += 100
+OUTPUT inserted.id, deleted.salary, inserted.salary INTO @ids
+FROM #emp e JOIN #dept d ON d.id = e.dept
+WHERE d.name = 'Ops'
+PRINT @@ROWCOUNT
+SELECT * FROM @ids ORDER BY id
+
+DELETE e FROM #emp e WHERE e.dept IS NULL AND e.salary > 1000
+PRINT @@ROWCOUNT
+DELETE FROM #dept OUTPUT deleted.name WHERE id = 3
+
+SELECT dept, SUM(salary) AS total INTO #totals FROM #emp GROUP BY dept
+TRUNCATE TABLE #dept
+INSERT INTO #dept (id, name) SELECT 10 + id, UPPER(name) FROM #emp WHERE id <= 2`)
+
+	expected := []string{"6", "1", "2", "1"}
+	if !reflect.DeepEqual(out.Messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, out.Messages)
+	}
+	if len(out.Results) != 2 {
+		t.Fatalf("expected 2 result sets, got %d", len(out.Results))
+	}
+	if got, want := rows(out.Results[0]), []string{"3|4000.00|4100.00", "4|4000.00|4100.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected OUTPUT rows %v, got %v", want, got)
+	}
+	if got, want := rows(out.Results[1]), []string{"Legal"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected deleted rows %v, got %v", want, got)
+	}
+
+	totals, ok := db.Rows("#totals")
+	if !ok {
+		t.Fatal("SELECT INTO did not create #totals")
+	}
+	if got, want := rows(totals), []string{"1|8000.00", "2|8200.00", "NULL|1000.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected totals %v, got %v", want, got)
+	}
+	dept, _ := db.Rows("#dept")
+	if got, want := rows(dept), []string{"11|ANN", "12|BOB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected departments %v, got %v", want, got)
+	}
+	emp, _ := db.Rows("#emp")
+	if got, want := rows(emp)[4], "6|Fay|NULL|1000.00|NULL"; got != want {
+		t.Errorf("expected defaulted row %q, got %q", want, got)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		statement string
+		number    int
+	}{
+		{"INSERT INTO #dept VALUES (1, 'Again')", 2627},
+		{"INSERT INTO #dept VALUES (4, NULL)", 515},
+		{"INSERT INTO #dept VALUES (4, 'A name far too long for the column')", 2628},
+		{"INSERT INTO #emp (id, name) VALUES (9, 'Gus')", 544},
+		{"INSERT INTO #dept (id) VALUES (4, 'x')", 110},
+		{"UPDATE #dept SET id = 2 WHERE id = 1", 2627},
+		{"UPDATE #emp SET id = 2", 8102},
+		{"INSERT INTO #limits VALUES (-1)", 547},
+		{"INSERT INTO #missing VALUES (1)", 208},
+		{"SELECT nope FROM #dept", 207},
+		{"SELECT id FROM #dept d JOIN #emp e ON e.dept = d.id", 209},
+		{"SELECT (SELECT id FROM #dept)", 512},
+		{"CREATE TABLE #dept (id INT)", 2714},
+		{"WITH n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n OPTION (MAXRECURSION 10)", 530},
+	}
+	for _, tt := range tests {
+		db := New()
+		p := parser.New(lexer.New(fixture + "\nCREATE TABLE #limits (n INT CHECK (n >= 0));\n" + tt.statement))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.statement, p.Errors())
+		}
+		_, err := interp.New(db).Run(program)
+		var ie *interp.Error
+		if !errors.As(err, &ie) || ie.Number != tt.number {
+			t.Errorf("%s: expected error %d, got %v", tt.statement, tt.number, err)
+		}
+	}
+
+	// A failed statement changes nothing
+	db := New()
+	run(t, db, fixture+`
+BEGIN TRY
+    INSERT INTO #dept VALUES (4, 'Dev'), (1, 'Dup')
+END TRY
+BEGIN CATCH
+    PRINT ERROR_NUMBER()
+END CATCH`)
+	dept, _ := db.Rows("#dept")
+	if len(dept.Rows) != 3 {
+		t.Errorf("expected the failed INSERT to add no rows, got %v", rows(dept))
+	}
+}
+
+func TestProcedure(t *testing.T) {
+	db := New()
+	in := interp.New(db)
+	p := parser.New(lexer.New(fixture + `
+GO
+CREATE PROCEDURE dbo.Raise @dept INT, @pct INT, @names VARCHAR(100) OUTPUT
+AS
+BEGIN
+    DECLARE @changed TABLE (name VARCHAR(20))
+    UPDATE #emp SET salary = salary * (100 + @pct) / 100
+    OUTPUT inserted.name INTO @changed
+    WHERE dept = @dept
+    IF @@ROWCOUNT = 0
+        RETURN 1
+    SET @names = ''
+    SELECT @names = @names + name + ';' FROM @changed ORDER BY name DESC
+    RETURN 0
+END`))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	if _, err := in.Run(program); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := in.Exec("dbo.Raise", map[string]eval.Value{"@dept": eval.Int(2), "@pct": eval.Int(10), "@names": eval.VarChar("")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ReturnCode != 0 || out.Output["@names"].Text() != "Dee;Cid;" {
+		t.Errorf("expected return 0 and names Dee;Cid;, got %d and %v", out.ReturnCode, out.Output["@names"])
+	}
+	emp, _ := db.Rows("#emp")
+	if got := emp.Rows[2][3].String(); got != "4400.00" {
+		t.Errorf("expected the raise to be stored, got %s", got)
+	}
+
+	out, err = in.Exec("dbo.Raise", map[string]eval.Value{"@dept": eval.Int(9), "@pct": eval.Int(10), "@names": eval.VarChar("")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.ReturnCode != 1 {
+		t.Errorf("expected return 1 for an empty department, got %d", out.ReturnCode)
+	}
+}
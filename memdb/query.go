@@ -0,0 +1,1010 @@
+package memdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+	"github.com/ha1tch/tsqlparser/interp"
+)
+
+// relation is a set of rows with named columns: a table, the result of a
+// join, or the output of a query.
+type relation struct {
+	cols    []colRef
+	sources []source
+	rows    []row
+}
+
+// source is a table whose records a relation's rows were built from, so
+// UPDATE and DELETE can find the records a FROM clause matched.
+type source struct {
+	qual string
+	t    *table
+}
+
+type row struct {
+	values []eval.Value
+	recs   []*record // The record of each source; nil for outer join padding
+}
+
+func tableRelation(t *table, qual string) *relation {
+	rel := &relation{cols: t.refs(qual), sources: []source{{qual, t}}}
+	for _, r := range t.rows {
+		rel.rows = append(rel.rows, row{values: r.values, recs: []*record{r}})
+	}
+	return rel
+}
+
+func (t *table) refs(qual string) []colRef {
+	cols := make([]colRef, len(t.columns))
+	for i, c := range t.columns {
+		cols[i] = colRef{qual, c.name}
+	}
+	return cols
+}
+
+// as renames a relation for use in a FROM clause: its columns are
+// qualified by alias and, when names are given, renamed.
+func (rel *relation) as(alias string, names []*ast.Identifier) (*relation, error) {
+	if names != nil && len(names) != len(rel.cols) {
+		if len(names) < len(rel.cols) {
+			return nil, sqlError(8158, "'%s' has more columns than were specified in the column list.", alias)
+		}
+		return nil, sqlError(8159, "'%s' has fewer columns than were specified in the column list.", alias)
+	}
+	cols := make([]colRef, len(rel.cols))
+	for i, c := range rel.cols {
+		cols[i] = colRef{alias, c.name}
+		if names != nil {
+			cols[i].name = names[i].Value
+		}
+	}
+	return &relation{cols: cols, rows: rel.rows}, nil
+}
+
+// source returns the index of the source a DML statement names as its
+// target, by alias or table name, or -1.
+func (rel *relation) source(name string) int {
+	for i, src := range rel.sources {
+		if strings.EqualFold(src.qual, name) {
+			return i
+		}
+	}
+	for i, src := range rel.sources {
+		if tableKey(src.t.name) == tableKey(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (rel *relation) nulls() row {
+	r := row{values: make([]eval.Value, len(rel.cols)), recs: make([]*record, len(rel.sources))}
+	for i := range r.values {
+		r.values[i] = eval.Value{Null: true}
+	}
+	return r
+}
+
+func joined(l, r *relation) *relation {
+	return &relation{
+		cols:    append(append([]colRef(nil), l.cols...), r.cols...),
+		sources: append(append([]source(nil), l.sources...), r.sources...),
+	}
+}
+
+func concat(a, b row) row {
+	return row{
+		values: append(append([]eval.Value(nil), a.values...), b.values...),
+		recs:   append(append([]*record(nil), a.recs...), b.recs...),
+	}
+}
+
+// product is the cross join of two relations.
+func product(l, r *relation) *relation {
+	out := joined(l, r)
+	for _, lr := range l.rows {
+		for _, rr := range r.rows {
+			out.rows = append(out.rows, concat(lr, rr))
+		}
+	}
+	return out
+}
+
+func (s *session) selectStatement(x *ast.SelectStatement, outer *env) (*interp.Result, error) {
+	rel, err := s.query(x, outer)
+	if err != nil {
+		return nil, err
+	}
+	if x.Into != nil {
+		return s.into(nameOf(x.Into), rel)
+	}
+	if assigns(x) {
+		return &interp.Result{RowsAffected: int64(len(rel.rows))}, nil
+	}
+	res := &interp.Result{Columns: make([]string, len(rel.cols)), RowsAffected: int64(len(rel.rows))}
+	for i, c := range rel.cols {
+		res.Columns[i] = c.name
+	}
+	for _, r := range rel.rows {
+		res.Rows = append(res.Rows, r.values)
+	}
+	return res, nil
+}
+
+func assigns(x *ast.SelectStatement) bool {
+	return len(x.Columns) > 0 && x.Columns[0].Variable != nil
+}
+
+// query runs a SELECT, returning its output columns and rows. outer is
+// the env of the enclosing query, for correlated subqueries, or of the
+// statement.
+func (s *session) query(sel *ast.SelectStatement, outer *env) (*relation, error) {
+	if sel.Union == nil {
+		return s.core(sel, outer, true)
+	}
+	members, ops := flatten(sel)
+	rel, err := s.combine(members, ops, outer)
+	if err != nil {
+		return nil, err
+	}
+
+	// ORDER BY and OFFSET/FETCH after the last query apply to the whole
+	last := members[len(members)-1]
+	outs := make([]output, len(rel.rows))
+	for i, r := range rel.rows {
+		outs[i] = output{env: outer.with(rel.cols, r.values), values: r.values}
+	}
+	outs, keys, err := order(outs, last.OrderBy, rel.cols)
+	if err != nil {
+		return nil, err
+	}
+	if outs, err = limit(outs, keys, nil, last.Offset, last.Fetch, outer); err != nil {
+		return nil, err
+	}
+	out := &relation{cols: rel.cols}
+	for _, o := range outs {
+		out.rows = append(out.rows, row{values: o.values})
+	}
+	return out, nil
+}
+
+// flatten lists the queries a UNION, INTERSECT or EXCEPT chain combines
+// and the operators between them.
+func flatten(sel *ast.SelectStatement) ([]*ast.SelectStatement, []*ast.UnionClause) {
+	members := []*ast.SelectStatement{sel}
+	var ops []*ast.UnionClause
+	for m := sel; m.Union != nil; m = m.Union.Right {
+		ops = append(ops, m.Union)
+		members = append(members, m.Union.Right)
+	}
+	return members, ops
+}
+
+// combine runs the queries of a set operation and combines their rows.
+// INTERSECT binds more tightly than UNION and EXCEPT.
+func (s *session) combine(members []*ast.SelectStatement, ops []*ast.UnionClause, outer *env) (*relation, error) {
+	rels := make([]*relation, len(members))
+	for i, m := range members {
+		rel, err := s.core(m, outer, false)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && len(rel.cols) != len(rels[0].cols) {
+			return nil, sqlError(205, "All queries combined using a UNION, INTERSECT or EXCEPT operator must have an equal number of expressions in their target lists.")
+		}
+		rels[i] = rel
+	}
+	ops = append([]*ast.UnionClause(nil), ops...)
+	for i := 0; i < len(ops); {
+		if !strings.EqualFold(ops[i].Type, "INTERSECT") {
+			i++
+			continue
+		}
+		rels[i] = setOperation(rels[i], rels[i+1], ops[i])
+		rels = append(rels[:i+1], rels[i+2:]...)
+		ops = append(ops[:i], ops[i+1:]...)
+	}
+	rel := rels[0]
+	for i, op := range ops {
+		rel = setOperation(rel, rels[i+1], op)
+	}
+	return rel, nil
+}
+
+func setOperation(l, r *relation, op *ast.UnionClause) *relation {
+	out := &relation{cols: l.cols}
+	switch strings.ToUpper(op.Type) {
+	case "UNION":
+		out.rows = append(append(out.rows, l.rows...), r.rows...)
+		if !op.All {
+			out.rows = distinct(out.rows)
+		}
+	case "INTERSECT", "EXCEPT":
+		keep := strings.EqualFold(op.Type, "INTERSECT")
+		for _, lr := range distinct(l.rows) {
+			if contains(r.rows, lr) == keep {
+				out.rows = append(out.rows, lr)
+			}
+		}
+	}
+	return out
+}
+
+func distinct(rows []row) []row {
+	var out []row
+	for _, r := range rows {
+		if !contains(out, r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func contains(rows []row, r row) bool {
+	for _, x := range rows {
+		if sameRow(x.values, r.values) {
+			return true
+		}
+	}
+	return false
+}
+
+// output is a row of a query's result and the env it was computed in,
+// which ORDER BY can refer back to.
+type output struct {
+	env    *env
+	values []eval.Value
+}
+
+// core runs a SELECT without regard to any set operation it is part of.
+// ORDER BY and OFFSET/FETCH are applied only when ordered is set.
+func (s *session) core(sel *ast.SelectStatement, outer *env, ordered bool) (*relation, error) {
+	src := &relation{rows: []row{{}}}
+	if sel.From != nil {
+		var err error
+		if src, err = s.from(sel.From.Tables, outer); err != nil {
+			return nil, err
+		}
+	}
+	var envs []*env
+	for _, r := range src.rows {
+		e := outer.with(src.cols, r.values)
+		if sel.Where != nil {
+			ok, err := e.test(sel.Where)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		envs = append(envs, e)
+	}
+
+	exprs := []ast.Expression{sel.Having}
+	for _, c := range sel.Columns {
+		exprs = append(exprs, c.Expression)
+	}
+	for _, o := range sel.OrderBy {
+		exprs = append(exprs, o.Expression)
+	}
+	if aggs := calls(false, exprs...); len(sel.GroupBy) > 0 || len(aggs) > 0 || sel.Having != nil {
+		var err error
+		if envs, err = group(sel, src.cols, envs, aggs, outer); err != nil {
+			return nil, err
+		}
+	}
+	if windows := calls(true, exprs...); len(windows) > 0 {
+		if err := window(windows, envs); err != nil {
+			return nil, err
+		}
+	}
+
+	cols, err := outputColumns(sel.Columns, src.cols)
+	if err != nil {
+		return nil, err
+	}
+	assign := assigns(sel)
+	outs := make([]output, len(envs))
+	for i, e := range envs {
+		outs[i].env = e
+		if assign {
+			continue
+		}
+		if outs[i].values, err = project(sel.Columns, e); err != nil {
+			return nil, err
+		}
+	}
+	if sel.Distinct && !assign {
+		var unique []output
+		for _, o := range outs {
+			dup := false
+			for _, u := range unique {
+				if dup = sameRow(u.values, o.values); dup {
+					break
+				}
+			}
+			if !dup {
+				unique = append(unique, o)
+			}
+		}
+		outs = unique
+	}
+
+	var keys [][]eval.Value
+	offset, fetch := sel.Offset, sel.Fetch
+	if ordered {
+		if outs, keys, err = order(outs, sel.OrderBy, cols); err != nil {
+			return nil, err
+		}
+	} else {
+		offset, fetch = nil, nil
+	}
+	if outs, err = limit(outs, keys, sel.Top, offset, fetch, outer); err != nil {
+		return nil, err
+	}
+
+	rel := &relation{cols: cols}
+	for _, o := range outs {
+		if assign {
+			// Assignments see the values earlier rows assigned, as in
+			// SELECT @list = @list + name + ',' FROM ...
+			for _, c := range sel.Columns {
+				v, err := o.env.eval(c.Expression)
+				if err != nil {
+					return nil, err
+				}
+				if err := s.scope.Set(c.Variable.Name, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		rel.rows = append(rel.rows, row{values: o.values})
+	}
+	return rel, nil
+}
+
+// group forms the groups of a query with GROUP BY or aggregates, returning
+// an env for each group that HAVING keeps. A group's env holds the values
+// of its aggregates and the first of its rows.
+func group(sel *ast.SelectStatement, cols []colRef, envs []*env, aggs []*ast.FunctionCall, outer *env) ([]*env, error) {
+	type grouping struct {
+		key  []eval.Value
+		envs []*env
+	}
+	var groups []*grouping
+	for _, e := range envs {
+		k := make([]eval.Value, len(sel.GroupBy))
+		for i, expr := range sel.GroupBy {
+			switch expr.(type) {
+			case *ast.GroupingSetsExpression, *ast.RollupExpression, *ast.CubeExpression:
+				return nil, fmt.Errorf("memdb: %s is not supported", expr)
+			}
+			v, err := e.eval(expr)
+			if err != nil {
+				return nil, err
+			}
+			k[i] = v
+		}
+		var g *grouping
+		for _, candidate := range groups {
+			if sameRow(candidate.key, k) {
+				g = candidate
+				break
+			}
+		}
+		if g == nil {
+			g = &grouping{key: k}
+			groups = append(groups, g)
+		}
+		g.envs = append(g.envs, e)
+	}
+	if len(groups) == 0 && len(sel.GroupBy) == 0 {
+		// An aggregate over no rows still produces a row
+		groups = append(groups, &grouping{})
+	}
+
+	var out []*env
+	for _, g := range groups {
+		r := (&relation{cols: cols}).nulls().values
+		if len(g.envs) > 0 {
+			r = g.envs[0].row
+		}
+		e := outer.with(cols, r)
+		e.values = make(map[*ast.FunctionCall]eval.Value)
+		for _, call := range aggs {
+			v, err := aggregate(call, g.envs, e)
+			if err != nil {
+				return nil, err
+			}
+			e.values[call] = v
+		}
+		if sel.Having != nil {
+			ok, err := e.test(sel.Having)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// outputColumns names the columns of a select list, expanding * and t.*.
+func outputColumns(list []ast.SelectColumn, src []colRef) ([]colRef, error) {
+	var cols []colRef
+	for _, c := range list {
+		if c.AllColumns {
+			if len(src) == 0 {
+				return nil, sqlError(263, "Must specify table to select from.")
+			}
+			for _, s := range src {
+				cols = append(cols, colRef{name: s.name})
+			}
+			continue
+		}
+		if qual, ok := star(c.Expression); ok {
+			n := len(cols)
+			for _, s := range src {
+				if strings.EqualFold(s.qual, qual) {
+					cols = append(cols, colRef{name: s.name})
+				}
+			}
+			if len(cols) == n {
+				return nil, sqlError(107, "The column prefix '%s' does not match with a table name or alias name used in the query.", qual)
+			}
+			continue
+		}
+		var name string
+		switch x := c.Expression.(type) {
+		case *ast.Identifier:
+			name = x.Value
+		case *ast.QualifiedIdentifier:
+			name = nameOf(x)
+		}
+		if c.Alias != nil {
+			name = c.Alias.Value
+		}
+		cols = append(cols, colRef{name: name})
+	}
+	return cols, nil
+}
+
+// star returns the qualifier of t.*.
+func star(expr ast.Expression) (string, bool) {
+	q, ok := expr.(*ast.QualifiedIdentifier)
+	if !ok || len(q.Parts) < 2 || nameOf(q) != "*" {
+		return "", false
+	}
+	return q.Parts[len(q.Parts)-2].Value, true
+}
+
+// project evaluates a select list against e.
+func project(list []ast.SelectColumn, e *env) ([]eval.Value, error) {
+	var values []eval.Value
+	for _, c := range list {
+		if c.AllColumns {
+			values = append(values, e.row...)
+			continue
+		}
+		if qual, ok := star(c.Expression); ok {
+			for i, col := range e.cols {
+				if strings.EqualFold(col.qual, qual) {
+					values = append(values, e.row[i])
+				}
+			}
+			continue
+		}
+		v, err := e.eval(c.Expression)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// order sorts the rows of a query by its ORDER BY items, which may name
+// output columns by alias or position, and returns their sort keys.
+func order(outs []output, items []*ast.OrderByItem, cols []colRef) ([]output, [][]eval.Value, error) {
+	if len(items) == 0 {
+		return outs, nil, nil
+	}
+	keys := make([][]eval.Value, len(outs))
+	for i, o := range outs {
+		keys[i] = make([]eval.Value, len(items))
+		for j, item := range items {
+			v, err := o.key(item.Expression, cols)
+			if err != nil {
+				return nil, nil, err
+			}
+			keys[i][j] = v
+		}
+	}
+	perm, err := sorted(keys, items)
+	if err != nil {
+		return nil, nil, err
+	}
+	sortedOuts := make([]output, len(outs))
+	sortedKeys := make([][]eval.Value, len(keys))
+	for i, p := range perm {
+		sortedOuts[i], sortedKeys[i] = outs[p], keys[p]
+	}
+	return sortedOuts, sortedKeys, nil
+}
+
+func (o output) key(expr ast.Expression, cols []colRef) (eval.Value, error) {
+	switch x := expr.(type) {
+	case *ast.IntegerLiteral:
+		if x.Value < 1 || int(x.Value) > len(cols) {
+			return eval.Value{}, sqlError(108, "The ORDER BY position number %d is out of range of the number of items in the select list.", x.Value)
+		}
+		if o.values != nil {
+			return o.values[x.Value-1], nil
+		}
+	case *ast.Identifier:
+		found := -1
+		for i, c := range cols {
+			if strings.EqualFold(c.name, x.Value) {
+				if found >= 0 {
+					return eval.Value{}, sqlError(209, "Ambiguous column name '%s'.", x.Value)
+				}
+				found = i
+			}
+		}
+		if found >= 0 && o.values != nil {
+			return o.values[found], nil
+		}
+	}
+	return o.env.eval(expr)
+}
+
+// sorted returns the order of the rows with the given ORDER BY keys.
+// NULL sorts first in ascending order unless the item says otherwise.
+func sorted(keys [][]eval.Value, items []*ast.OrderByItem) ([]int, error) {
+	perm := make([]int, len(keys))
+	for i := range perm {
+		perm[i] = i
+	}
+	var err error
+	sort.SliceStable(perm, func(i, j int) bool {
+		c, cmpErr := compareKeys(keys[perm[i]], keys[perm[j]], items)
+		if cmpErr != nil && err == nil {
+			err = cmpErr
+		}
+		return c < 0
+	})
+	return perm, err
+}
+
+func compareKeys(a, b []eval.Value, items []*ast.OrderByItem) (int, error) {
+	for i, item := range items {
+		x, y := a[i], b[i]
+		if item.NullsFirst != nil && x.Null != y.Null {
+			if x.Null == *item.NullsFirst {
+				return -1, nil
+			}
+			return 1, nil
+		}
+		c, err := eval.Compare(x, y)
+		if err != nil {
+			return 0, err
+		}
+		if item.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+	return 0, nil
+}
+
+// limit applies OFFSET/FETCH and TOP to sorted rows. keys are the sort
+// keys, which TOP ... WITH TIES compares.
+func limit(outs []output, keys [][]eval.Value, top *ast.TopClause, offset, fetch ast.Expression, outer *env) ([]output, error) {
+	if offset != nil {
+		n, err := count(offset, outer)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, sqlError(10742, "The offset specified in a OFFSET clause may not be negative.")
+		}
+		n = min(n, int64(len(outs)))
+		outs = outs[n:]
+		if keys != nil {
+			keys = keys[n:]
+		}
+	}
+	if fetch != nil {
+		n, err := count(fetch, outer)
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 {
+			return nil, sqlError(10744, "The number of rows provided for a FETCH clause must be greater then zero.")
+		}
+		outs = outs[:min(n, int64(len(outs)))]
+	}
+	if top == nil {
+		return outs, nil
+	}
+
+	var n int64
+	if top.Percent {
+		v, err := outer.eval(top.Count)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = eval.Convert(v, eval.Type{Kind: eval.KindFloat}); err != nil {
+			return nil, err
+		}
+		p := v.Float64()
+		if v.Null || p < 0 || p > 100 {
+			return nil, sqlError(1031, "Percent values must be between 0 and 100.")
+		}
+		n = int64(math.Ceil(float64(len(outs)) * p / 100))
+	} else {
+		var err error
+		if n, err = count(top.Count, outer); err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, sqlError(1060, "The number of rows in the TOP clause must be an integer.")
+		}
+	}
+	if n >= int64(len(outs)) {
+		return outs, nil
+	}
+	if top.WithTies && keys != nil && n > 0 {
+		for n < int64(len(outs)) && sameRow(keys[n], keys[n-1]) {
+			n++
+		}
+	}
+	return outs[:n], nil
+}
+
+func count(expr ast.Expression, e *env) (int64, error) {
+	v, err := e.eval(expr)
+	if err != nil {
+		return 0, err
+	}
+	if v, err = eval.Convert(v, eval.Type{Kind: eval.KindBigInt}); err != nil {
+		return 0, err
+	}
+	return v.Int64(), nil
+}
+
+func (s *session) from(refs []ast.TableReference, outer *env) (*relation, error) {
+	var rel *relation
+	for _, ref := range refs {
+		r, err := s.tableRef(ref, outer)
+		if err != nil {
+			return nil, err
+		}
+		if rel == nil {
+			rel = r
+		} else {
+			rel = product(rel, r)
+		}
+	}
+	return rel, nil
+}
+
+func (s *session) tableRef(ref ast.TableReference, outer *env) (*relation, error) {
+	switch x := ref.(type) {
+	case *ast.TableName:
+		name := nameOf(x.Name)
+		qual := name
+		if x.Alias != nil {
+			qual = x.Alias.Value
+		}
+		if len(x.Name.Parts) == 1 {
+			if cte := outer.cte(name); cte != nil {
+				return cte.as(qual, nil)
+			}
+		}
+		t, err := s.table(x.Name)
+		if err != nil {
+			return nil, err
+		}
+		return tableRelation(t, qual), nil
+	case *ast.DerivedTable:
+		rel, err := s.query(x.Subquery, outer)
+		if err != nil {
+			return nil, err
+		}
+		alias := ""
+		if x.Alias != nil {
+			alias = x.Alias.Value
+		}
+		if x.ColumnAliases == nil {
+			for i, c := range rel.cols {
+				if c.name == "" {
+					return nil, sqlError(8155, "No column name was specified for column %d of '%s'.", i+1, alias)
+				}
+			}
+		}
+		return rel.as(alias, x.ColumnAliases)
+	case *ast.ValuesTable:
+		alias := ""
+		if x.Alias != nil {
+			alias = x.Alias.Value
+		}
+		rel := &relation{}
+		for _, r := range x.Rows {
+			if len(r) != len(x.Columns) {
+				return nil, sqlError(8158, "'%s' has more columns than were specified in the column list.", alias)
+			}
+			values := make([]eval.Value, len(r))
+			for i, expr := range r {
+				v, err := outer.eval(expr)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			rel.rows = append(rel.rows, row{values: values})
+		}
+		for _, c := range x.Columns {
+			rel.cols = append(rel.cols, colRef{alias, c.Value})
+		}
+		return rel, nil
+	case *ast.TableValuedFunction:
+		return s.tableFunction(x, outer)
+	case *ast.ParenthesizedTableRef:
+		return s.tableRef(x.Inner, outer)
+	case *ast.JoinClause:
+		return s.join(x, outer)
+	}
+	return nil, fmt.Errorf("memdb: %s is not supported", ref)
+}
+
+// cte finds a CTE by name in e or the envs enclosing it.
+func (e *env) cte(name string) *relation {
+	for o := e; o != nil; o = o.outer {
+		if rel, ok := o.ctes[strings.ToLower(name)]; ok {
+			return rel
+		}
+	}
+	return nil
+}
+
+func (s *session) tableFunction(x *ast.TableValuedFunction, outer *env) (*relation, error) {
+	if !strings.EqualFold(nameOf(x.Function), "STRING_SPLIT") {
+		return nil, sqlError(208, "Invalid object name '%s'.", x.Function)
+	}
+	if len(x.Arguments) < 2 || len(x.Arguments) > 3 {
+		return nil, sqlError(174, "The string_split function requires 2 or 3 argument(s).")
+	}
+	args := make([]eval.Value, len(x.Arguments))
+	for i, arg := range x.Arguments {
+		v, err := outer.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	ordinal := len(args) == 3 && !args[2].Null && args[2].Int64() != 0
+	rows, err := eval.StringSplit(args[0], args[1], ordinal)
+	if err != nil {
+		return nil, err
+	}
+	rel := &relation{cols: []colRef{{name: "value"}}}
+	if ordinal {
+		rel.cols = append(rel.cols, colRef{name: "ordinal"})
+	}
+	for _, r := range rows {
+		rel.rows = append(rel.rows, row{values: r})
+	}
+	alias := ""
+	if x.Alias != nil {
+		alias = x.Alias.Value
+	}
+	return rel.as(alias, x.ColumnAliases)
+}
+
+func (s *session) join(x *ast.JoinClause, outer *env) (*relation, error) {
+	kind := strings.ToUpper(x.Type)
+	if kind == "CROSS APPLY" || kind == "OUTER APPLY" {
+		return s.apply(x, outer, kind == "OUTER APPLY")
+	}
+	l, err := s.tableRef(x.Left, outer)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.tableRef(x.Right, outer)
+	if err != nil {
+		return nil, err
+	}
+	out := joined(l, r)
+	matchedRight := make([]bool, len(r.rows))
+	for _, lr := range l.rows {
+		matched := false
+		for j, rr := range r.rows {
+			c := concat(lr, rr)
+			if x.Condition != nil {
+				ok, err := outer.with(out.cols, c.values).test(x.Condition)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+			}
+			matched, matchedRight[j] = true, true
+			out.rows = append(out.rows, c)
+		}
+		if !matched && (kind == "LEFT" || kind == "FULL") {
+			out.rows = append(out.rows, concat(lr, r.nulls()))
+		}
+	}
+	if kind == "RIGHT" || kind == "FULL" {
+		for j, rr := range r.rows {
+			if !matchedRight[j] {
+				out.rows = append(out.rows, concat(l.nulls(), rr))
+			}
+		}
+	}
+	return out, nil
+}
+
+// apply evaluates the right side of CROSS APPLY or OUTER APPLY once for
+// each row on the left, which it can refer to. With no rows on the left,
+// the right side is evaluated against a row of NULLs for its columns.
+func (s *session) apply(x *ast.JoinClause, outer *env, outerApply bool) (*relation, error) {
+	l, err := s.tableRef(x.Left, outer)
+	if err != nil {
+		return nil, err
+	}
+	if len(l.rows) == 0 {
+		r, err := s.tableRef(x.Right, outer.with(l.cols, l.nulls().values))
+		if err != nil {
+			return nil, err
+		}
+		return joined(l, r), nil
+	}
+	var out *relation
+	for _, lr := range l.rows {
+		r, err := s.tableRef(x.Right, outer.with(l.cols, lr.values))
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = joined(l, r)
+		}
+		if len(r.rows) == 0 && outerApply {
+			out.rows = append(out.rows, concat(lr, r.nulls()))
+		}
+		for _, rr := range r.rows {
+			out.rows = append(out.rows, concat(lr, rr))
+		}
+	}
+	return out, nil
+}
+
+// with binds the CTEs of a WITH statement, returning the env the
+// statement runs in.
+func (s *session) with(x *ast.WithStatement, outer *env) (*env, error) {
+	e := &env{s: s, ctes: make(map[string]*relation), outer: outer}
+	maxRecursion := int64(100)
+	if sel, ok := x.Query.(*ast.SelectStatement); ok {
+		for _, opt := range sel.Options {
+			if strings.EqualFold(opt.Name, "MAXRECURSION") && opt.Value != nil {
+				n, err := count(opt.Value, outer)
+				if err != nil {
+					return nil, err
+				}
+				maxRecursion = n
+			}
+		}
+	}
+	for _, def := range x.CTEs {
+		rel, err := s.cte(def, e, maxRecursion)
+		if err != nil {
+			return nil, err
+		}
+		e.ctes[strings.ToLower(def.Name.Value)] = rel
+	}
+	return e, nil
+}
+
+// cte runs the query of a CTE. A recursive CTE's anchor members run
+// first; its recursive members then run against the rows the previous
+// level produced until a level produces none.
+func (s *session) cte(def *ast.CTEDef, e *env, maxRecursion int64) (*relation, error) {
+	name := def.Name.Value
+	members, ops := flatten(def.Query)
+	anchors := 0
+	for anchors < len(members) && !references(members[anchors], name) {
+		anchors++
+	}
+	if anchors == len(members) {
+		rel, err := s.query(def.Query, e)
+		if err != nil {
+			return nil, err
+		}
+		return rel.named(name, def.Columns)
+	}
+	if anchors == 0 {
+		return nil, sqlError(246, "No anchor member was specified for recursive query \"%s\".", name)
+	}
+
+	anchor, err := s.combine(members[:anchors], ops[:anchors-1], e)
+	if err != nil {
+		return nil, err
+	}
+	if anchor, err = anchor.named(name, def.Columns); err != nil {
+		return nil, err
+	}
+	result := &relation{cols: anchor.cols, rows: anchor.rows}
+	level := anchor
+	for depth := int64(0); len(level.rows) > 0; depth++ {
+		if maxRecursion > 0 && depth >= maxRecursion {
+			return nil, sqlError(530, "The statement terminated. The maximum recursion %d has been exhausted before statement completion.", maxRecursion)
+		}
+		e.ctes[strings.ToLower(name)] = level
+		next := &relation{cols: anchor.cols}
+		for _, m := range members[anchors:] {
+			rel, err := s.core(m, e, false)
+			if err != nil {
+				return nil, err
+			}
+			if len(rel.cols) != len(anchor.cols) {
+				return nil, sqlError(205, "All queries combined using a UNION, INTERSECT or EXCEPT operator must have an equal number of expressions in their target lists.")
+			}
+			next.rows = append(next.rows, rel.rows...)
+		}
+		result.rows = append(result.rows, next.rows...)
+		level = next
+	}
+	return result, nil
+}
+
+// named gives the columns of a CTE their names.
+func (rel *relation) named(cte string, names []*ast.Identifier) (*relation, error) {
+	if names == nil {
+		for i, c := range rel.cols {
+			if c.name == "" {
+				return nil, sqlError(8155, "No column name was specified for column %d of '%s'.", i+1, cte)
+			}
+		}
+	}
+	return rel.as("", names)
+}
+
+// references reports whether a query reads the named CTE in its FROM
+// clause.
+func references(sel *ast.SelectStatement, name string) bool {
+	var ref func(ast.TableReference) bool
+	ref = func(t ast.TableReference) bool {
+		switch x := t.(type) {
+		case *ast.TableName:
+			return len(x.Name.Parts) == 1 && strings.EqualFold(nameOf(x.Name), name)
+		case *ast.JoinClause:
+			return ref(x.Left) || ref(x.Right)
+		case *ast.ParenthesizedTableRef:
+			return ref(x.Inner)
+		case *ast.DerivedTable:
+			return references(x.Subquery, name)
+		}
+		return false
+	}
+	if sel.From != nil {
+		for _, t := range sel.From.Tables {
+			if ref(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
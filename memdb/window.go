@@ -0,0 +1,303 @@
+package memdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/eval"
+)
+
+// window computes the window functions of a query for each of its rows,
+// storing the results in the rows' envs.
+func window(calls []*ast.FunctionCall, envs []*env) error {
+	for _, e := range envs {
+		if e.values == nil {
+			e.values = make(map[*ast.FunctionCall]eval.Value)
+		}
+	}
+	for _, call := range calls {
+		if call.Over.WindowRef != "" {
+			return fmt.Errorf("memdb: named window %s is not supported", call.Over.WindowRef)
+		}
+		partitions, err := partition(call.Over.PartitionBy, envs)
+		if err != nil {
+			return err
+		}
+		for _, p := range partitions {
+			if err := windowFunction(call, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func partition(exprs []ast.Expression, envs []*env) ([][]*env, error) {
+	var keys [][]eval.Value
+	var parts [][]*env
+	for _, e := range envs {
+		k := make([]eval.Value, len(exprs))
+		for i, expr := range exprs {
+			v, err := e.eval(expr)
+			if err != nil {
+				return nil, err
+			}
+			k[i] = v
+		}
+		found := false
+		for i := range keys {
+			if sameRow(keys[i], k) {
+				parts[i] = append(parts[i], e)
+				found = true
+				break
+			}
+		}
+		if !found {
+			keys = append(keys, k)
+			parts = append(parts, []*env{e})
+		}
+	}
+	return parts, nil
+}
+
+// windowFunction computes a window function over one partition.
+func windowFunction(call *ast.FunctionCall, rows []*env) error {
+	items := call.Over.OrderBy
+	keys := make([][]eval.Value, len(rows))
+	for i, r := range rows {
+		keys[i] = make([]eval.Value, len(items))
+		for j, item := range items {
+			v, err := r.eval(item.Expression)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = v
+		}
+	}
+	perm, err := sorted(keys, items)
+	if err != nil {
+		return err
+	}
+	ordered := make([]*env, len(rows))
+	orderedKeys := make([][]eval.Value, len(rows))
+	for i, p := range perm {
+		ordered[i], orderedKeys[i] = rows[p], keys[p]
+	}
+	rows, keys = ordered, orderedKeys
+	n := len(rows)
+
+	// Rows with equal ORDER BY keys are peers
+	first, last := make([]int, n), make([]int, n)
+	for i := range rows {
+		first[i] = i
+		if i > 0 && sameRow(keys[i], keys[i-1]) {
+			first[i] = first[i-1]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		last[i] = i
+		if i < n-1 && sameRow(keys[i], keys[i+1]) {
+			last[i] = last[i+1]
+		}
+	}
+
+	name := functionName(call)
+	set := func(i int, v eval.Value) {
+		rows[i].values[call] = v
+	}
+	switch name {
+	case "ROW_NUMBER":
+		for i := range rows {
+			set(i, eval.BigInt(int64(i+1)))
+		}
+	case "RANK":
+		for i := range rows {
+			set(i, eval.BigInt(int64(first[i]+1)))
+		}
+	case "DENSE_RANK":
+		rank := int64(0)
+		for i := range rows {
+			if first[i] == i {
+				rank++
+			}
+			set(i, eval.BigInt(rank))
+		}
+	case "PERCENT_RANK":
+		for i := range rows {
+			r := 0.0
+			if n > 1 {
+				r = float64(first[i]) / float64(n-1)
+			}
+			set(i, eval.Float(r))
+		}
+	case "CUME_DIST":
+		for i := range rows {
+			set(i, eval.Float(float64(last[i]+1)/float64(n)))
+		}
+	case "NTILE":
+		if len(call.Arguments) != 1 {
+			return sqlError(174, "The ntile function requires 1 argument(s).")
+		}
+		buckets, err := count(call.Arguments[0], rows[0])
+		if err != nil {
+			return err
+		}
+		if buckets < 1 {
+			return sqlError(4112, "The function 'ntile' must have a positive integer argument.")
+		}
+		size, extra := int64(n)/buckets, int64(n)%buckets
+		bucket, left := int64(1), size
+		if extra > 0 {
+			left++
+		}
+		for i := range rows {
+			if left == 0 {
+				bucket++
+				left = size
+				if bucket <= extra {
+					left++
+				}
+			}
+			set(i, eval.BigInt(bucket))
+			left--
+		}
+	case "LAG", "LEAD":
+		if len(call.Arguments) < 1 || len(call.Arguments) > 3 {
+			return sqlError(174, "The %s function requires 1 to 3 argument(s).", strings.ToLower(name))
+		}
+		for i, r := range rows {
+			offset := int64(1)
+			if len(call.Arguments) > 1 {
+				if offset, err = count(call.Arguments[1], r); err != nil {
+					return err
+				}
+				if offset < 0 {
+					return sqlError(8730, "Offset parameter for Lag and Lead functions cannot be a negative value.")
+				}
+			}
+			j := i - int(offset)
+			if name == "LEAD" {
+				j = i + int(offset)
+			}
+			var v eval.Value
+			switch {
+			case j >= 0 && j < n:
+				v, err = rows[j].eval(call.Arguments[0])
+			case len(call.Arguments) == 3:
+				v, err = r.eval(call.Arguments[2])
+			default:
+				v = eval.Value{Null: true}
+			}
+			if err != nil {
+				return err
+			}
+			set(i, v)
+		}
+	case "FIRST_VALUE", "LAST_VALUE":
+		if len(call.Arguments) != 1 {
+			return sqlError(174, "The %s function requires 1 argument(s).", strings.ToLower(name))
+		}
+		for i := range rows {
+			start, end, err := frame(call.Over, rows, i, first[i], last[i])
+			if err != nil {
+				return err
+			}
+			v := eval.Value{Null: true}
+			if start <= end {
+				pick := start
+				if name == "LAST_VALUE" {
+					pick = end
+				}
+				if v, err = rows[pick].eval(call.Arguments[0]); err != nil {
+					return err
+				}
+			}
+			set(i, v)
+		}
+	default:
+		if !isAggregate(name) || name == "STRING_AGG" {
+			return fmt.Errorf("memdb: window function %s is not supported", name)
+		}
+		values, err := argument(call, rows)
+		if err != nil {
+			return err
+		}
+		for i, r := range rows {
+			start, end, err := frame(call.Over, rows, i, first[i], last[i])
+			if err != nil {
+				return err
+			}
+			var framed []eval.Value
+			if start <= end {
+				framed = values[start : end+1]
+			}
+			v, err := reduce(call, framed, r)
+			if err != nil {
+				return err
+			}
+			set(i, v)
+		}
+	}
+	return nil
+}
+
+// frame returns the first and last row of the window frame of row i,
+// whose peers run from first to last. Without a frame clause the frame
+// is the whole partition, or with ORDER BY runs from the start of the
+// partition to the last peer of the row.
+func frame(over *ast.OverClause, rows []*env, i, first, last int) (int, int, error) {
+	n := len(rows)
+	f := over.Frame
+	if f == nil {
+		if len(over.OrderBy) == 0 {
+			return 0, n - 1, nil
+		}
+		return 0, last, nil
+	}
+	rangeFrame := strings.EqualFold(f.Type, "RANGE")
+	bound := func(b *ast.FrameBound, start bool) (int, error) {
+		switch b.Type {
+		case "UNBOUNDED PRECEDING":
+			return 0, nil
+		case "UNBOUNDED FOLLOWING":
+			return n - 1, nil
+		case "CURRENT ROW":
+			if rangeFrame && start {
+				return first, nil
+			}
+			if rangeFrame {
+				return last, nil
+			}
+			return i, nil
+		}
+		if rangeFrame {
+			return 0, fmt.Errorf("memdb: RANGE frames with an offset are not supported")
+		}
+		v, err := eval.Eval(b.Offset)
+		if err != nil {
+			return 0, err
+		}
+		if v, err = eval.Convert(v, eval.Type{Kind: eval.KindBigInt}); err != nil {
+			return 0, err
+		}
+		if b.Type == "PRECEDING" {
+			return i - int(v.Int64()), nil
+		}
+		return i + int(v.Int64()), nil
+	}
+	start, err := bound(f.Start, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	end := i
+	if rangeFrame {
+		end = last
+	}
+	if f.End != nil {
+		if end, err = bound(f.End, false); err != nil {
+			return 0, 0, err
+		}
+	}
+	return max(start, 0), min(end, n-1), nil
+}
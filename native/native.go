@@ -0,0 +1,301 @@
+// Package native checks natively compiled modules for the constructs that
+// In-Memory OLTP does not allow in them.
+//
+// A procedure, scalar function or trigger created WITH NATIVE_COMPILATION
+// is compiled to machine code when it is created, and SQL Server rejects
+// it if it uses anything outside the surface area of natively compiled
+// T-SQL. Check finds those uses without a server: cursors, temporary
+// tables, inline table variables, MERGE, SELECT INTO, TRUNCATE TABLE,
+// common table expressions, dynamic SQL, cross-database names, sequences,
+// full-text search and the built-in functions listed in
+// unsupportedFunctions. It also checks the module itself: SCHEMABINDING,
+// a BEGIN ATOMIC body with TRANSACTION ISOLATION LEVEL and LANGUAGE, and
+// that the tables and table types the script defines and the module uses
+// are memory-optimized.
+//
+// Example usage:
+//
+//	program, _ := tsqlparser.Parse(script)
+//	for _, issue := range native.Check(program) {
+//	    fmt.Println(issue)
+//	}
+package native
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Issue is a construct that is not allowed in a natively compiled module.
+type Issue struct {
+	Line    int
+	Column  int
+	Module  string // The name of the module
+	Message string
+}
+
+func (i *Issue) String() string {
+	return fmt.Sprintf("line %d, col %d: %s: %s", i.Line, i.Column, i.Module, i.Message)
+}
+
+// unsupportedFunctions are the built-in functions that natively compiled
+// modules cannot call, by upper-case name.
+var unsupportedFunctions = map[string]bool{
+	"CHECKSUM": true, "BINARY_CHECKSUM": true, "CHECKSUM_AGG": true,
+	"FORMAT": true, "PARSE": true, "TRY_PARSE": true,
+	"OPENROWSET": true, "OPENQUERY": true, "OPENDATASOURCE": true, "OPENXML": true,
+	"CONTAINSTABLE": true, "FREETEXTTABLE": true, "SEMANTICKEYPHRASETABLE": true,
+}
+
+// Check checks the natively compiled procedures, functions and triggers
+// of a script. The CREATE TABLE and CREATE TYPE statements of the script
+// tell it which tables and table types are memory-optimized; objects it
+// does not define are assumed to be.
+func Check(program *ast.Program) []*Issue {
+	c := &checker{diskBased: make(map[string]*ast.QualifiedIdentifier)}
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.CreateTableStatement:
+			if !s.MemoryOptimized {
+				c.diskBased[key(s.Name.String())] = s.Name
+			}
+		case *ast.CreateTypeStatement:
+			if s.IsTableType && !s.MemoryOptimized {
+				c.diskBased[key(s.Name.String())] = s.Name
+			}
+		}
+	}
+	for _, stmt := range program.Statements {
+		c.module(stmt)
+	}
+	return c.issues
+}
+
+// CheckModule checks a single module, which is reported on only if it is
+// natively compiled.
+func CheckModule(stmt ast.Statement) []*Issue {
+	c := &checker{}
+	c.module(stmt)
+	return c.issues
+}
+
+type checker struct {
+	diskBased map[string]*ast.QualifiedIdentifier // tables and table types, by key
+	name      string                              // the module being checked
+	issues    []*Issue
+}
+
+func (c *checker) add(tok token.Token, format string, args ...any) {
+	c.issues = append(c.issues, &Issue{Line: tok.Line, Column: tok.Column, Module: c.name, Message: fmt.Sprintf(format, args...)})
+}
+
+// module checks stmt if it creates or alters a natively compiled module.
+func (c *checker) module(stmt ast.Statement) {
+	var (
+		tok     token.Token
+		name    *ast.QualifiedIdentifier
+		options []string
+		body    *ast.BeginEndBlock
+	)
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		tok, name, options, body = s.Token, s.Name, s.Options, s.Body
+	case *ast.AlterProcedureStatement:
+		tok, name, options, body = s.Token, s.Name, s.Options, s.Body
+	case *ast.CreateFunctionStatement:
+		tok, name, options, body = s.Token, s.Name, s.Options, s.Body
+	case *ast.AlterFunctionStatement:
+		tok, name, options, body = s.Token, s.Name, s.Options, s.Body
+	case *ast.CreateTriggerStatement:
+		tok, name, options, body = s.Token, s.Name, s.Options, s.Body
+	default:
+		return
+	}
+	if !hasOption(options, "NATIVE_COMPILATION") {
+		return
+	}
+	c.name = name.String()
+	if !hasOption(options, "SCHEMABINDING") {
+		c.add(tok, "natively compiled modules must be created WITH SCHEMABINDING")
+	}
+	if body == nil {
+		c.add(tok, "natively compiled modules must have a BEGIN ATOMIC body")
+		return
+	}
+	if !body.IsAtomic {
+		c.add(body.Token, "natively compiled modules must have a BEGIN ATOMIC body")
+	} else {
+		atomic := body.Atomic
+		if atomic == nil {
+			atomic = &ast.AtomicBlockOptions{}
+		}
+		if atomic.IsolationLevel == "" {
+			c.add(body.Token, "BEGIN ATOMIC requires TRANSACTION ISOLATION LEVEL")
+		}
+		if atomic.Language == "" {
+			c.add(body.Token, "BEGIN ATOMIC requires LANGUAGE")
+		}
+	}
+	for _, s := range body.Statements {
+		visit(reflect.ValueOf(s), c.node)
+	}
+}
+
+// node checks a node of a module body.
+func (c *checker) node(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.DeclareCursorStatement:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.OpenCursorStatement:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.FetchStatement:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.CloseCursorStatement:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.DeallocateCursorStatement:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.CursorExpression:
+		c.add(n.Token, "cursors are not supported")
+	case *ast.DeclareStatement:
+		for _, v := range n.Variables {
+			switch {
+			case v.TableType != nil:
+				c.add(n.Token, "table variable %s must use a memory-optimized table type", v.Name)
+			case v.DataType != nil && strings.EqualFold(v.DataType.Name, "CURSOR"):
+				c.add(n.Token, "cursors are not supported")
+			case v.DataType != nil:
+				c.tableType(n.Token, v.DataType.Name)
+			}
+		}
+	case *ast.MergeStatement:
+		c.add(n.Token, "MERGE is not supported")
+	case *ast.TruncateTableStatement:
+		c.add(n.Token, "TRUNCATE TABLE is not supported")
+	case *ast.WithStatement:
+		c.add(n.Token, "common table expressions are not supported")
+	case *ast.SelectStatement:
+		if n.Into != nil {
+			c.add(n.Token, "SELECT INTO is not supported")
+		}
+	case *ast.ExecStatement:
+		if n.DynamicSQL != nil || n.Procedure != nil && strings.EqualFold(n.Procedure.String(), "sp_executesql") {
+			c.add(n.Token, "dynamic SQL is not supported")
+		}
+	case *ast.NextValueForExpression:
+		c.add(n.Token, "sequences are not supported")
+	case *ast.ContainsExpression:
+		c.add(n.Token, "full-text search is not supported")
+	case *ast.FreetextExpression:
+		c.add(n.Token, "full-text search is not supported")
+	case *ast.ContainsTableExpression:
+		c.add(n.Token, "full-text search is not supported")
+	case *ast.BulkRowset:
+		c.add(n.Token, "OPENROWSET is not supported")
+	case *ast.FunctionCall:
+		if name := functionName(n.Function); unsupportedFunctions[name] {
+			c.add(n.Token, "function %s is not supported", name)
+		}
+	case *ast.TableValuedFunction:
+		if name := functionName(n.Function); unsupportedFunctions[name] {
+			c.add(n.Token, "function %s is not supported", name)
+		}
+	case *ast.QualifiedIdentifier:
+		if len(n.Parts) > 0 && strings.HasPrefix(n.Parts[0].Value, "#") {
+			c.add(n.Parts[0].Token, "temporary table %s is not supported", n.Parts[0].Value)
+		}
+	case *ast.TableName:
+		c.table(n)
+	}
+}
+
+// table checks a table referred to by a module body.
+func (c *checker) table(t *ast.TableName) {
+	if t.Name == nil || len(t.Name.Parts) == 0 {
+		return
+	}
+	tok := t.Name.Parts[0].Token
+	if len(t.Name.Parts) > 2 {
+		c.add(tok, "cross-database reference %s is not supported", t.Name)
+		return
+	}
+	if name, ok := c.diskBased[key(t.Name.String())]; ok {
+		c.add(tok, "table %s is not memory-optimized", name)
+	}
+}
+
+// tableType checks the type of a variable, which may be a table type the
+// script defines.
+func (c *checker) tableType(tok token.Token, name string) {
+	if def, ok := c.diskBased[key(name)]; ok {
+		c.add(tok, "table type %s is not memory-optimized", def)
+	}
+}
+
+// hasOption reports whether a module was created WITH the option.
+func hasOption(options []string, name string) bool {
+	for _, o := range options {
+		if strings.EqualFold(o, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the name a table is known by: lower-case, with the default
+// schema dbo left out.
+func key(name string) string {
+	name = strings.ToLower(name)
+	return strings.TrimPrefix(name, "dbo.")
+}
+
+// functionName returns the upper-case name of a built-in function, or ""
+// for a schema-qualified one.
+func functionName(fn ast.Expression) string {
+	switch f := fn.(type) {
+	case *ast.Identifier:
+		return strings.ToUpper(f.Value)
+	case *ast.QualifiedIdentifier:
+		if f != nil && len(f.Parts) == 1 {
+			return strings.ToUpper(f.Parts[0].Value)
+		}
+	}
+	return ""
+}
+
+var (
+	nodeType  = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(token.Token{})
+)
+
+// visit calls f for every AST node in v, parents before their children.
+func visit(v reflect.Value, f func(ast.Node)) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			visit(v.Elem(), f)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if v.Type().Implements(nodeType) {
+			f(v.Interface().(ast.Node))
+		}
+		visit(v.Elem(), f)
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			visit(v.Field(i), f)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			visit(v.Index(i), f)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package native
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// messages checks input and returns the messages of its issues.
+func messages(t *testing.T, input string) []string {
+	t.Helper()
+	var out []string
+	for _, issue := range Check(parse(t, input)) {
+		out = append(out, issue.Message)
+	}
+	return out
+}
+
+const header = "CREATE PROCEDURE dbo.P WITH NATIVE_COMPILATION, SCHEMABINDING AS BEGIN ATOMIC WITH (TRANSACTION ISOLATION LEVEL = SNAPSHOT, LANGUAGE = N'us_english')\n"
+
+func TestValidModule(t *testing.T) {
+	input := `CREATE TABLE dbo.Orders (Id INT NOT NULL PRIMARY KEY NONCLUSTERED HASH WITH (BUCKET_COUNT = 1024), Total MONEY)
+WITH (MEMORY_OPTIMIZED = ON, DURABILITY = SCHEMA_AND_DATA);
+GO
+CREATE TYPE dbo.Ids AS TABLE (Id INT NOT NULL PRIMARY KEY NONCLUSTERED HASH WITH (BUCKET_COUNT = 64)) WITH (MEMORY_OPTIMIZED = ON);
+GO
+` + header + `    DECLARE @ids dbo.Ids;
+    DECLARE @n INT = 0;
+    INSERT INTO @ids (Id) SELECT Id FROM dbo.Orders WHERE Total > 0;
+    WHILE @n < 10 SET @n = @n + 1;
+    UPDATE dbo.Orders SET Total = ISNULL(Total, 0) WHERE Id = @n;
+    SELECT Id, DATEADD(day, 1, SYSDATETIME()) FROM dbo.Orders;
+END`
+	if got := messages(t, input); len(got) != 0 {
+		t.Errorf("unexpected issues: %q", got)
+	}
+}
+
+func TestUnsupportedConstructs(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"DECLARE c CURSOR FOR SELECT Id FROM dbo.Orders; OPEN c;", "cursors are not supported"},
+		{"CREATE TABLE #t (Id INT);", "temporary table #t is not supported"},
+		{"SELECT Id FROM #work;", "temporary table #work is not supported"},
+		{"DECLARE @t TABLE (Id INT);", "table variable @t must use a memory-optimized table type"},
+		{"MERGE dbo.Orders AS t USING dbo.Staging AS s ON t.Id = s.Id WHEN MATCHED THEN DELETE;", "MERGE is not supported"},
+		{"SELECT Id INTO dbo.Copy FROM dbo.Orders;", "SELECT INTO is not supported"},
+		{"TRUNCATE TABLE dbo.Orders;", "TRUNCATE TABLE is not supported"},
+		{"WITH x AS (SELECT Id FROM dbo.Orders) SELECT Id FROM x;", "common table expressions are not supported"},
+		{"EXEC ('SELECT 1');", "dynamic SQL is not supported"},
+		{"EXEC sp_executesql N'SELECT 1';", "dynamic SQL is not supported"},
+		{"SELECT Id FROM Sales.dbo.Orders;", "cross-database reference Sales.dbo.Orders is not supported"},
+		{"SELECT NEXT VALUE FOR dbo.Seq;", "sequences are not supported"},
+		{"SELECT CHECKSUM(Id), FORMAT(Total, 'C') FROM dbo.Orders;", "function CHECKSUM is not supported"},
+	}
+	for _, tt := range tests {
+		got := messages(t, header+tt.body+"\nEND")
+		found := false
+		for _, m := range got {
+			if m == tt.want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s\ngot %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestModuleRequirements(t *testing.T) {
+	input := `CREATE TABLE dbo.Disk (Id INT);
+GO
+CREATE TYPE dbo.DiskIds AS TABLE (Id INT);
+GO
+CREATE PROCEDURE dbo.P WITH NATIVE_COMPILATION AS BEGIN ATOMIC WITH (TRANSACTION ISOLATION LEVEL = SNAPSHOT)
+    DECLARE @ids dbo.DiskIds;
+    SELECT Id FROM dbo.Disk;
+END`
+	got := strings.Join(messages(t, input), "\n")
+	want := strings.Join([]string{
+		"natively compiled modules must be created WITH SCHEMABINDING",
+		"BEGIN ATOMIC requires LANGUAGE",
+		"table type dbo.DiskIds is not memory-optimized",
+		"table dbo.Disk is not memory-optimized",
+	}, "\n")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInterpretedModulesAreSkipped(t *testing.T) {
+	input := `CREATE PROCEDURE dbo.P AS
+BEGIN
+    DECLARE c CURSOR FOR SELECT Id FROM #t;
+    MERGE dbo.Orders AS t USING dbo.Staging AS s ON t.Id = s.Id WHEN MATCHED THEN DELETE;
+END`
+	if got := messages(t, input); len(got) != 0 {
+		t.Errorf("unexpected issues: %q", got)
+	}
+}
+
+func TestIssuePosition(t *testing.T) {
+	issues := CheckModule(parse(t, header+"    SELECT Id FROM dbo.Orders;\n    MERGE dbo.Orders AS t USING dbo.Staging AS s ON t.Id = s.Id WHEN MATCHED THEN DELETE;\nEND").Statements[0])
+	if len(issues) != 1 {
+		t.Fatalf("got %d issues, want 1", len(issues))
+	}
+	if got, want := issues[0].String(), "line 3, col 5: dbo.P: MERGE is not supported"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package parser
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -58,6 +59,7 @@ var precedences = map[token.Type]int{
 	token.RSHIFT:               SHIFT,
 	token.PLUS:                 SUM,
 	token.MINUS:                SUM,
+	token.DPIPE:                SUM,
 	token.ASTERISK:             PRODUCT,
 	token.SLASH:                PRODUCT,
 	token.PERCENT:              PRODUCT,
@@ -71,10 +73,144 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// statementStarts are the tokens that can begin a statement.
+var statementStarts = []token.Type{
+	token.SELECT, token.INSERT, token.UPDATE, token.DELETE, token.MERGE, token.WITH,
+	token.DECLARE, token.SET, token.IF, token.WHILE, token.BEGIN, token.RETURN,
+	token.BREAK, token.CONTINUE, token.PRINT, token.EXEC, token.EXECUTE, token.CREATE,
+	token.ALTER, token.DROP, token.TRUNCATE, token.THROW, token.RAISERROR, token.COMMIT,
+	token.ROLLBACK, token.SAVE, token.GO, token.ENABLE, token.DISABLE, token.OPEN,
+	token.CLOSE, token.FETCH_KW, token.DEALLOCATE, token.USE, token.WAITFOR, token.GOTO,
+	token.BULK, token.REVERT, token.RECONFIGURE, token.DBCC, token.GRANT, token.REVOKE,
+	token.DENY, token.BACKUP, token.RESTORE, token.SEND, token.RECEIVE, token.MOVE,
+	token.GET,
+}
+
+// keywordIdentifiers are the keywords that can be used as column names and
+// aliases. They are treated as identifiers when used in expression context.
+var keywordIdentifiers = []token.Type{
+	token.DATE, token.TIME, token.DATETIME, token.DATETIME2,
+	token.TEXT, token.NTEXT, token.IMAGE,
+	token.MONEY, token.SMALLMONEY,
+	token.REAL,
+	token.XML,
+	token.KEY, token.LEFT, token.RIGHT,
+	// Window frame keywords that can also be identifiers
+	token.ROWS, token.RANGE, token.ROW,
+	token.CURRENT, token.UNBOUNDED, token.PRECEDING, token.FOLLOWING,
+	// DEFAULT can appear in VALUES clauses
+	token.DEFAULT_KW,
+	// SETS and RESULT can be identifiers (GROUPING SETS, RESULT column names)
+	token.SETS, token.RESULT,
+	// GROUPING, CUBE, ROLLUP can be used as functions (GROUPING(col), etc.)
+	token.GROUPING, token.CUBE, token.ROLLUP,
+	// MERGE keywords that are commonly used as table aliases
+	token.TARGET, token.SOURCE, token.MATCHED,
+	// Common column names that are also keywords
+	token.VALUE, token.LEVEL, token.TYPE_WARNING,
+	token.ACTION, token.PATH, token.LOG,
+	// Data types used as column names
+	token.CHAR, token.VARCHAR, token.NCHAR, token.NVARCHAR, token.INT_TYPE,
+	// JOIN keywords sometimes used as aliases
+	token.INNER, token.OUTER, token.CROSS, token.APPLY,
+	// Misc keywords used as identifiers
+	token.PERCENT_KW, token.MESSAGE, token.CONVERSATION,
+	// EXECUTE AS keywords
+	token.OWNER_KW, token.CALLER,
+	// SET options and query hints
+	token.NOCOUNT, token.OPTION,
+	// Index keywords and data types often used as column names
+	token.CLUSTERED, token.NONCLUSTERED, token.TIMESTAMP,
+	token.CHECKSUM, token.COLLATE,
+	// More common identifier conflicts
+	token.INDEX, token.STATS, token.MEMBER, token.SUBJECT,
+	token.RESOURCE, token.PRIMARY,
+	// Spatial data types
+	token.GEOGRAPHY, token.GEOMETRY, token.HIERARCHYID,
+	// Additional keywords commonly used as column names or aliases
+	token.CYCLE, token.ROLE, token.ADD, token.BULK, token.RECOVERY,
+	token.PARTITION, token.ALGORITHM, token.AFTER, token.SNAPSHOT,
+	token.OUTPUT, token.LANGUAGE, token.ISOLATION, token.AUTO,
+	token.GET, token.INCREMENT, token.SELF, token.IDENTITY,
+	token.INIT, token.COMPRESSION, token.PERIOD, token.ENCRYPTION,
+	token.AUTHORIZATION, token.LOCAL, token.GLOBAL,
+	token.DATABASE, token.NONE_KW, token.DISK, token.FUNCTION,
+	token.CONSTRAINT, token.CHECK, token.FOREIGN, token.REFERENCES,
+	// Sequence keywords often used as column names (MaxValue, MinValue)
+	token.MAXVALUE, token.MINVALUE,
+	token.CERTIFICATE, token.QUEUE, token.RECEIVE, token.UPDATE,
+	token.TRANSACTION, token.DELETE, token.INSERT,
+	// Hint keywords as identifiers
+	token.HASH, token.LOOP, token.REMOTE, token.MERGE,
+}
+
+// The kinds of object that CREATE, ALTER and DROP can start with.
+var (
+	createObjects = []token.Type{
+		token.PROCEDURE, token.PROC, token.TABLE, token.VIEW, token.INDEX, token.UNIQUE,
+		token.CLUSTERED, token.NONCLUSTERED, token.FUNCTION, token.DEFAULT_KW, token.PRIMARY,
+		token.XML, token.TRIGGER, token.TYPE_WARNING, token.SYNONYM, token.SEQUENCE,
+		token.STATISTICS, token.LOGIN, token.USER, token.ROLE, token.MASTER, token.CERTIFICATE,
+		token.SYMMETRIC, token.ASYMMETRIC, token.ASSEMBLY, token.PARTITION, token.FULLTEXT,
+		token.RESOURCE, token.WORKLOAD, token.AVAILABILITY, token.MESSAGE, token.CONTRACT,
+		token.QUEUE, token.SERVICE, token.SCHEMA, token.SERVER, token.DATABASE,
+	}
+	alterObjects = []token.Type{
+		token.TABLE, token.VIEW, token.FUNCTION, token.TRIGGER, token.PROCEDURE, token.PROC,
+		token.INDEX, token.SEQUENCE, token.LOGIN, token.USER, token.ROLE, token.ASSEMBLY,
+		token.PARTITION, token.FULLTEXT, token.RESOURCE, token.WORKLOAD, token.AVAILABILITY,
+		token.QUEUE, token.DATABASE, token.SERVER,
+	}
+	dropObjects = []token.Type{
+		token.TABLE, token.VIEW, token.FUNCTION, token.PROCEDURE, token.PROC, token.TRIGGER,
+		token.SYNONYM, token.LOGIN, token.USER, token.ROLE, token.ASSEMBLY, token.CERTIFICATE,
+		token.SCHEMA, token.TYPE_WARNING, token.DEFAULT_KW, token.INDEX, token.SEQUENCE,
+		token.STATISTICS, token.SYMMETRIC, token.ASYMMETRIC, token.MASTER, token.FULLTEXT,
+		token.RESOURCE, token.WORKLOAD, token.AVAILABILITY, token.MESSAGE, token.CONTRACT,
+		token.QUEUE, token.SERVICE, token.SERVER, token.DATABASE,
+	}
+)
+
+// SyntaxError is a parse error with the tokens that were valid in place
+// of the one found.
+type SyntaxError struct {
+	Line     int
+	Column   int
+	Message  string
+	Got      token.Token
+	Expected []token.Type // Only recorded after Parser.TrackExpected
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, col %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Dialect selects the statements the parser accepts beyond those of SQL
+// Server.
+type Dialect int
+
+const (
+	// SQLServer is SQL Server and Azure SQL Database, the default.
+	SQLServer Dialect = iota
+	// Synapse is Azure Synapse dedicated SQL pools and Fabric warehouses,
+	// which add CREATE TABLE AS SELECT, COPY INTO, RENAME OBJECT and
+	// CREATE REMOTE TABLE.
+	Synapse
+)
+
 // Parser represents a T-SQL parser.
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l            *lexer.Lexer
+	errors       []string
+	syntaxErrors []*SyntaxError
+	dialect      Dialect
+
+	// The furthest token the parser has tested, and the token types it
+	// tested it for, when tracked
+	trackExpected bool
+	expectedAt    token.Token
+	expected      []token.Type
+	expectedSet   []uint64 // expected as a bit set
 
 	curToken      token.Token
 	peekToken     token.Token
@@ -84,6 +220,12 @@ type Parser struct {
 	infixParseFns  map[token.Type]infixParseFn
 }
 
+// SetDialect selects the dialect to parse. It must be called before
+// ParseProgram.
+func (p *Parser) SetDialect(d Dialect) {
+	p.dialect = d
+}
+
 // New creates a new Parser.
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
@@ -144,62 +286,7 @@ func New(l *lexer.Lexer) *Parser {
 	}
 
 	// Register data type keywords that can be used as column names
-	// These are treated as identifiers when used in expression context
-	for _, dt := range []token.Type{
-		token.DATE, token.TIME, token.DATETIME, token.DATETIME2,
-		token.TEXT, token.NTEXT, token.IMAGE,
-		token.MONEY, token.SMALLMONEY,
-		token.REAL,
-		token.XML,
-		token.KEY, token.LEFT, token.RIGHT,
-		// Window frame keywords that can also be identifiers
-		token.ROWS, token.RANGE, token.ROW,
-		token.CURRENT, token.UNBOUNDED, token.PRECEDING, token.FOLLOWING,
-		// DEFAULT can appear in VALUES clauses
-		token.DEFAULT_KW,
-		// SETS and RESULT can be identifiers (GROUPING SETS, RESULT column names)
-		token.SETS, token.RESULT,
-		// GROUPING, CUBE, ROLLUP can be used as functions (GROUPING(col), etc.)
-		token.GROUPING, token.CUBE, token.ROLLUP,
-		// MERGE keywords that are commonly used as table aliases
-		token.TARGET, token.SOURCE, token.MATCHED,
-		// Common column names that are also keywords
-		token.VALUE, token.LEVEL, token.TYPE_WARNING,
-		token.ACTION, token.PATH, token.LOG,
-		// Data types used as column names
-		token.CHAR, token.VARCHAR, token.NCHAR, token.NVARCHAR, token.INT_TYPE,
-		// JOIN keywords sometimes used as aliases
-		token.INNER, token.OUTER, token.CROSS, token.APPLY,
-		// Misc keywords used as identifiers
-		token.PERCENT_KW, token.MESSAGE, token.CONVERSATION,
-		// EXECUTE AS keywords
-		token.OWNER_KW, token.CALLER,
-		// SET options and query hints
-		token.NOCOUNT, token.OPTION,
-		// Index keywords and data types often used as column names
-		token.CLUSTERED, token.NONCLUSTERED, token.TIMESTAMP,
-		token.CHECKSUM, token.COLLATE,
-		// More common identifier conflicts
-		token.INDEX, token.STATS, token.MEMBER, token.SUBJECT,
-		token.RESOURCE, token.PRIMARY,
-		// Spatial data types
-		token.GEOGRAPHY, token.GEOMETRY, token.HIERARCHYID,
-		// Additional keywords commonly used as column names or aliases
-		token.CYCLE, token.ROLE, token.ADD, token.BULK, token.RECOVERY,
-		token.PARTITION, token.ALGORITHM, token.AFTER, token.SNAPSHOT,
-		token.OUTPUT, token.LANGUAGE, token.ISOLATION, token.AUTO,
-		token.GET, token.INCREMENT, token.SELF, token.IDENTITY,
-		token.INIT, token.COMPRESSION, token.PERIOD, token.ENCRYPTION,
-		token.AUTHORIZATION, token.LOCAL, token.GLOBAL,
-		token.DATABASE, token.NONE_KW, token.DISK, token.FUNCTION,
-		token.CONSTRAINT, token.CHECK, token.FOREIGN, token.REFERENCES,
-		// Sequence keywords often used as column names (MaxValue, MinValue)
-		token.MAXVALUE, token.MINVALUE,
-		token.CERTIFICATE, token.QUEUE, token.RECEIVE, token.UPDATE,
-		token.TRANSACTION, token.DELETE, token.INSERT,
-		// Hint keywords as identifiers
-		token.HASH, token.LOOP, token.REMOTE, token.MERGE,
-	} {
+	for _, dt := range keywordIdentifiers {
 		p.registerPrefix(dt, p.parseKeywordAsIdentifier)
 	}
 
@@ -221,6 +308,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
 	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.DPIPE, p.parseInfixExpression)
 	p.registerInfix(token.CARET, p.parseInfixExpression)
 	p.registerInfix(token.LSHIFT, p.parseInfixExpression)
 	p.registerInfix(token.RSHIFT, p.parseInfixExpression)
@@ -258,10 +346,94 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// SyntaxErrors returns the errors that have a position, with the tokens
+// that were expected there.
+func (p *Parser) SyntaxErrors() []*SyntaxError {
+	return p.syntaxErrors
+}
+
+// TrackExpected makes the parser record the token types it tests each
+// token for, which Expected and SyntaxError.Expected report. It costs
+// time on every token, so it is off unless called before ParseProgram.
+func (p *Parser) TrackExpected() {
+	p.trackExpected = true
+}
+
+// Expected returns the furthest token the parser reached and the token
+// types that would have been valid in its place. After parsing a prefix of
+// a statement, it is the end of input and the tokens that can come next.
+// It is only recorded after TrackExpected.
+func (p *Parser) Expected() (token.Token, []token.Type) {
+	return p.expectedAt, append([]token.Type(nil), p.expected...)
+}
+
+// expect records that tok was tested for t, if expectations are tracked.
+func (p *Parser) expect(tok token.Token, t token.Type) {
+	if p.trackExpected {
+		p.record(tok, t)
+	}
+}
+
+func (p *Parser) record(tok token.Token, t token.Type) {
+	if !samePlace(tok, p.expectedAt) {
+		if before(tok, p.expectedAt) {
+			return
+		}
+		for _, e := range p.expected {
+			p.expectedSet[e/64] = 0
+		}
+		p.expectedAt = tok
+		p.expected = p.expected[:0]
+	}
+	word, bit := int(t/64), uint64(1)<<(t%64)
+	for len(p.expectedSet) <= word {
+		p.expectedSet = append(p.expectedSet, 0)
+	}
+	if p.expectedSet[word]&bit == 0 {
+		p.expectedSet[word] |= bit
+		p.expected = append(p.expected, t)
+	}
+}
+
+// samePlace reports whether a and b are the same token of the input. The
+// lexer moves the column of EOF each time it is read, so every EOF token
+// is the same.
+func samePlace(a, b token.Token) bool {
+	if a.Type == token.EOF || b.Type == token.EOF {
+		return a.Type == b.Type
+	}
+	return a.Line == b.Line && a.Column == b.Column && a.Type == b.Type
+}
+
+// before reports whether a comes before b in the input.
+func before(a, b token.Token) bool {
+	switch {
+	case a.Type == token.EOF:
+		return false
+	case b.Type == token.EOF:
+		return true
+	}
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// syntaxError records an error at tok.
+func (p *Parser) syntaxError(tok token.Token, format string, args ...any) {
+	err := &SyntaxError{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, args...),
+		Got:     tok,
+	}
+	if samePlace(tok, p.expectedAt) {
+		err.Expected = append([]token.Type(nil), p.expected...)
+	}
+	p.syntaxErrors = append(p.syntaxErrors, err)
+	p.errors = append(p.errors, err.Error())
+}
+
 func (p *Parser) peekError(t token.Type) {
-	msg := fmt.Sprintf("line %d, col %d: expected %s, got %s",
-		p.peekToken.Line, p.peekToken.Column, t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.expect(p.peekToken, t)
+	p.syntaxError(p.peekToken, "expected %s, got %s", t, p.peekToken.Type)
 }
 
 func (p *Parser) nextToken() {
@@ -275,10 +447,12 @@ func (p *Parser) nextToken() {
 }
 
 func (p *Parser) curTokenIs(t token.Type) bool {
+	p.expect(p.curToken, t)
 	return p.curToken.Type == t
 }
 
 func (p *Parser) peekTokenIs(t token.Type) bool {
+	p.expect(p.peekToken, t)
 	return p.peekToken.Type == t
 }
 
@@ -314,22 +488,40 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
+	p.expectStatement()
 	for !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 		p.nextToken()
+		p.expectStatement()
 	}
 
 	return program
 }
 
+// expectStatement records that a statement can start at the current token.
+func (p *Parser) expectStatement() {
+	p.expectAll(statementStarts)
+}
+
+// expectAll records that the current token was tested for each of types.
+func (p *Parser) expectAll(types []token.Type) {
+	if !p.trackExpected {
+		return
+	}
+	for _, t := range types {
+		p.record(p.curToken, t)
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	// Skip semicolons
 	for p.curTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
+	p.expectStatement()
 
 	switch p.curToken.Type {
 	case token.SELECT:
@@ -454,6 +646,14 @@ func (p *Parser) parseStatement() ast.Statement {
 		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
 			return p.parseLabelStatement()
 		}
+		if p.dialect == Synapse {
+			if strings.EqualFold(p.curToken.Literal, "COPY") && p.peekTokenIs(token.INTO) {
+				return p.parseCopyIntoStatement()
+			}
+			if p.curTokenIs(token.RENAME) {
+				return p.parseRenameStatement()
+			}
+		}
 		return p.parseExpressionStatement()
 	}
 }
@@ -484,12 +684,48 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Type) {
-	msg := fmt.Sprintf("line %d, col %d: no prefix parse function for %s found",
-		p.curToken.Line, p.curToken.Column, t)
-	p.errors = append(p.errors, msg)
+	// Anything that starts an expression was valid here. The keywords
+	// that stand for names are covered by IDENT.
+	var types []token.Type
+	for typ := range p.prefixParseFns {
+		if !slices.Contains(keywordIdentifiers, typ) {
+			types = append(types, typ)
+		}
+	}
+	slices.Sort(types)
+	p.expectAll(types)
+	p.syntaxError(p.curToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
+	// These names are not reserved, but a user-defined function of the same
+	// name would have to be schema-qualified
+	if p.peekTokenIs(token.LPAREN) {
+		switch strings.ToUpper(p.curToken.Literal) {
+		case "MATCH":
+			return p.parseMatchExpression()
+		case "GREATEST", "LEAST":
+			return p.parseGreatestLeast()
+		case "DATETRUNC":
+			return p.parseDateTrunc()
+		case "DATE_BUCKET":
+			return p.parseDateBucket()
+		case "APPROX_PERCENTILE_CONT", "APPROX_PERCENTILE_DISC":
+			return p.parseApproxPercentile()
+		case "JSON_OBJECT":
+			return p.parseJsonObject()
+		case "JSON_ARRAY":
+			return p.parseJsonArray()
+		case "JSON_OBJECTAGG":
+			return p.parseJsonObjectAgg()
+		case "JSON_ARRAYAGG":
+			return p.parseJsonArrayAgg()
+		case "REGEXP_LIKE", "REGEXP_REPLACE", "REGEXP_SUBSTR", "REGEXP_INSTR", "REGEXP_COUNT":
+			return p.parseRegexp()
+		case "VECTOR_DISTANCE":
+			return p.parseVectorDistance()
+		}
+	}
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
@@ -573,8 +809,13 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		Token:    p.curToken,
 		Operator: p.curToken.Literal,
 	}
+	precedence := PREFIX
+	if p.curTokenIs(token.NOT) {
+		// NOT binds looser than comparisons: NOT a = b is NOT (a = b)
+		precedence = NOT_PREC
+	}
 	p.nextToken()
-	expression.Right = p.parseExpression(PREFIX)
+	expression.Right = p.parseExpression(precedence)
 	return expression
 }
 
@@ -695,6 +936,7 @@ func (p *Parser) parseScopeExpression(left ast.Expression) ast.Expression {
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.FunctionCall{Token: p.curToken, Function: function}
+	exp.Distinct = p.peekTokenIs(token.DISTINCT)
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
 
 	// Check for WITHIN GROUP clause (for ordered-set aggregate functions)
@@ -706,14 +948,23 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		if !p.expectPeek(token.LPAREN) {
 			return nil
 		}
-		if !p.expectPeek(token.ORDER) {
-			return nil
-		}
-		if !p.expectPeek(token.BY) {
-			return nil
+		if p.peekToken.Type == token.IDENT && strings.EqualFold(p.peekToken.Literal, "GRAPH") {
+			// WITHIN GROUP (GRAPH PATH) aggregates over the nodes of a SHORTEST_PATH
+			p.nextToken() // consume GRAPH
+			if !p.expectPeek(token.PATH) {
+				return nil
+			}
+			exp.GraphPath = true
+		} else {
+			if !p.expectPeek(token.ORDER) {
+				return nil
+			}
+			if !p.expectPeek(token.BY) {
+				return nil
+			}
+			p.nextToken()
+			exp.WithinGroup = p.parseOrderByItems()
 		}
-		p.nextToken()
-		exp.WithinGroup = p.parseOrderByItems()
 		if !p.expectPeek(token.RPAREN) {
 			return nil
 		}
@@ -786,6 +1037,279 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	return list
 }
 
+// parseFunctionArguments parses the arguments of a built-in function that
+// takes from min to max of them, or at least min when max is negative.
+// The current token is the name of the function; on return it is the
+// closing parenthesis.
+func (p *Parser) parseFunctionArguments(min, max int) []ast.Expression {
+	name := p.curToken
+	p.nextToken() // move to (
+	args := p.parseExpressionList(token.RPAREN)
+	if args == nil {
+		return nil
+	}
+	if len(args) < min || max >= 0 && len(args) > max {
+		switch {
+		case min == max:
+			p.syntaxError(name, "%s takes %d argument(s), got %d", strings.ToUpper(name.Literal), min, len(args))
+		case max < 0:
+			p.syntaxError(name, "%s takes at least %d argument(s), got %d", strings.ToUpper(name.Literal), min, len(args))
+		default:
+			p.syntaxError(name, "%s takes %d to %d arguments, got %d", strings.ToUpper(name.Literal), min, max, len(args))
+		}
+		return nil
+	}
+	return args
+}
+
+// datePartArgument returns the date part a function is given as its first
+// argument, which is written as a name such as day or mi.
+func (p *Parser) datePartArgument(name token.Token, arg ast.Expression) *ast.Identifier {
+	part, ok := arg.(*ast.Identifier)
+	if !ok {
+		p.syntaxError(name, "invalid datepart %s in %s", arg, strings.ToUpper(name.Literal))
+		return nil
+	}
+	return part
+}
+
+func (p *Parser) parseGreatestLeast() ast.Expression {
+	expr := &ast.GreatestLeastExpression{Token: p.curToken, Least: strings.EqualFold(p.curToken.Literal, "LEAST")}
+	expr.Arguments = p.parseFunctionArguments(1, -1)
+	if expr.Arguments == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseDateTrunc() ast.Expression {
+	expr := &ast.DateTruncExpression{Token: p.curToken}
+	args := p.parseFunctionArguments(2, 2)
+	if args == nil {
+		return nil
+	}
+	if expr.DatePart = p.datePartArgument(expr.Token, args[0]); expr.DatePart == nil {
+		return nil
+	}
+	expr.Date = args[1]
+	return expr
+}
+
+func (p *Parser) parseDateBucket() ast.Expression {
+	expr := &ast.DateBucketExpression{Token: p.curToken}
+	args := p.parseFunctionArguments(3, 4)
+	if args == nil {
+		return nil
+	}
+	if expr.DatePart = p.datePartArgument(expr.Token, args[0]); expr.DatePart == nil {
+		return nil
+	}
+	expr.Number, expr.Date = args[1], args[2]
+	if len(args) == 4 {
+		expr.Origin = args[3]
+	}
+	return expr
+}
+
+// parseApproxPercentile parses APPROX_PERCENTILE_CONT(p) or
+// APPROX_PERCENTILE_DISC(p) WITHIN GROUP (ORDER BY expr).
+func (p *Parser) parseApproxPercentile() ast.Expression {
+	expr := &ast.ApproxPercentileExpression{
+		Token:    p.curToken,
+		Discrete: strings.EqualFold(p.curToken.Literal, "APPROX_PERCENTILE_DISC"),
+	}
+	args := p.parseFunctionArguments(1, 1)
+	if args == nil {
+		return nil
+	}
+	expr.Percentile = args[0]
+	if !p.expectPeek(token.WITHIN) || !p.expectPeek(token.GROUP) || !p.expectPeek(token.LPAREN) ||
+		!p.expectPeek(token.ORDER) || !p.expectPeek(token.BY) {
+		return nil
+	}
+	p.nextToken()
+	expr.OrderBy = p.parseOrderByItems()[0]
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return expr
+}
+
+// peekJsonClause reports whether the next token starts the null clause or
+// the RETURNING clause of a JSON constructor.
+func (p *Parser) peekJsonClause() bool {
+	switch {
+	case p.peekTokenIs(token.NULL) || p.peekToken.Type == token.IDENT && strings.EqualFold(p.peekToken.Literal, "ABSENT"):
+		return p.peekPeekTokenIs(token.ON)
+	case p.peekToken.Type == token.IDENT && strings.EqualFold(p.peekToken.Literal, "RETURNING"):
+		return true
+	}
+	return false
+}
+
+// parseJsonClauses parses the NULL ON NULL or ABSENT ON NULL clause and
+// the RETURNING json clause that may end the arguments of a JSON
+// constructor. On return the current token is the last token of the
+// clauses, if there are any.
+func (p *Parser) parseJsonClauses() (ast.JsonNullHandling, *ast.DataType, bool) {
+	onNull := ast.JsonNullDefault
+	if p.peekJsonClause() && !strings.EqualFold(p.peekToken.Literal, "RETURNING") {
+		p.nextToken() // consume NULL or ABSENT
+		onNull = ast.JsonNullOnNull
+		if strings.EqualFold(p.curToken.Literal, "ABSENT") {
+			onNull = ast.JsonAbsentOnNull
+		}
+		if !p.expectPeek(token.ON) || !p.expectPeek(token.NULL) {
+			return onNull, nil, false
+		}
+	}
+	var returning *ast.DataType
+	if p.peekToken.Type == token.IDENT && strings.EqualFold(p.peekToken.Literal, "RETURNING") {
+		p.nextToken() // consume RETURNING
+		p.nextToken()
+		returning = p.parseDataType()
+	}
+	return onNull, returning, true
+}
+
+// parseJsonKeyValue parses 'key':value in JSON_OBJECT or JSON_OBJECTAGG.
+// The current token is the first token of the key.
+func (p *Parser) parseJsonKeyValue() *ast.JsonKeyValuePair {
+	pair := &ast.JsonKeyValuePair{Token: p.curToken, Key: p.parseExpression(LOWEST)}
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	pair.Value = p.parseExpression(LOWEST)
+	return pair
+}
+
+func (p *Parser) parseJsonObject() ast.Expression {
+	expr := &ast.JsonObjectExpression{Token: p.curToken}
+	p.nextToken() // move to (
+	for !p.peekTokenIs(token.RPAREN) && !p.peekJsonClause() {
+		p.nextToken()
+		pair := p.parseJsonKeyValue()
+		if pair == nil {
+			return nil
+		}
+		expr.Pairs = append(expr.Pairs, pair)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+	var ok bool
+	if expr.OnNull, expr.Returning, ok = p.parseJsonClauses(); !ok || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseJsonArray() ast.Expression {
+	expr := &ast.JsonArrayExpression{Token: p.curToken}
+	p.nextToken() // move to (
+	for !p.peekTokenIs(token.RPAREN) && !p.peekJsonClause() {
+		p.nextToken()
+		expr.Elements = append(expr.Elements, p.parseExpression(LOWEST))
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+	var ok bool
+	if expr.OnNull, expr.Returning, ok = p.parseJsonClauses(); !ok || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseJsonObjectAgg() ast.Expression {
+	expr := &ast.JsonObjectAggExpression{Token: p.curToken}
+	p.nextToken() // move to (
+	p.nextToken()
+	pair := p.parseJsonKeyValue()
+	if pair == nil {
+		return nil
+	}
+	expr.Key, expr.Value = pair.Key, pair.Value
+	var ok bool
+	if expr.OnNull, expr.Returning, ok = p.parseJsonClauses(); !ok || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if p.peekTokenIs(token.OVER) {
+		p.nextToken()
+		expr.Over = p.parseOverClause()
+	}
+	return expr
+}
+
+func (p *Parser) parseJsonArrayAgg() ast.Expression {
+	expr := &ast.JsonArrayAggExpression{Token: p.curToken}
+	p.nextToken() // move to (
+	p.nextToken()
+	expr.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.ORDER) {
+		p.nextToken() // consume ORDER
+		if !p.expectPeek(token.BY) {
+			return nil
+		}
+		p.nextToken()
+		expr.OrderBy = p.parseOrderByItems()
+	}
+	var ok bool
+	if expr.OnNull, expr.Returning, ok = p.parseJsonClauses(); !ok || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if p.peekTokenIs(token.OVER) {
+		p.nextToken()
+		expr.Over = p.parseOverClause()
+	}
+	return expr
+}
+
+// parseRegexp parses a call of one of the regular expression functions,
+// whose arguments are assigned by position.
+func (p *Parser) parseRegexp() ast.Expression {
+	expr := &ast.RegexpExpression{Token: p.curToken}
+	var params []*ast.Expression
+	switch strings.ToUpper(p.curToken.Literal) {
+	case "REGEXP_LIKE":
+		expr.Function = ast.RegexpLike
+		params = []*ast.Expression{&expr.Source, &expr.Pattern, &expr.Flags}
+	case "REGEXP_REPLACE":
+		expr.Function = ast.RegexpReplace
+		params = []*ast.Expression{&expr.Source, &expr.Pattern, &expr.Replacement, &expr.Start, &expr.Occurrence, &expr.Flags}
+	case "REGEXP_SUBSTR":
+		expr.Function = ast.RegexpSubstr
+		params = []*ast.Expression{&expr.Source, &expr.Pattern, &expr.Start, &expr.Occurrence, &expr.Flags, &expr.Group}
+	case "REGEXP_INSTR":
+		expr.Function = ast.RegexpInstr
+		params = []*ast.Expression{&expr.Source, &expr.Pattern, &expr.Start, &expr.Occurrence, &expr.ReturnOption, &expr.Flags, &expr.Group}
+	case "REGEXP_COUNT":
+		expr.Function = ast.RegexpCount
+		params = []*ast.Expression{&expr.Source, &expr.Pattern, &expr.Start, &expr.Flags}
+	}
+	args := p.parseFunctionArguments(2, len(params))
+	if args == nil {
+		return nil
+	}
+	for i, arg := range args {
+		*params[i] = arg
+	}
+	return expr
+}
+
+func (p *Parser) parseVectorDistance() ast.Expression {
+	expr := &ast.VectorDistanceExpression{Token: p.curToken}
+	args := p.parseFunctionArguments(3, 3)
+	if args == nil {
+		return nil
+	}
+	expr.Metric, expr.Left, expr.Right = args[0], args[1], args[2]
+	return expr
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	// If not followed by (, treat as identifier (e.g., RANK used as column alias)
@@ -1875,6 +2399,7 @@ func (p *Parser) parseOptionClause() []*ast.QueryOption {
 			p.nextToken() // consume =
 			p.nextToken() // move to value
 			opt.Value = p.parseExpression(LOWEST)
+			opt.Equals = true
 		}
 
 		options = append(options, opt)
@@ -2242,56 +2767,23 @@ func (p *Parser) parseTableReference() ast.TableReference {
 		// But NOT if it's legacy hint syntax (NOLOCK) etc.
 		if p.peekTokenIs(token.LPAREN) && !p.isLegacyTableHint() {
 			p.nextToken() // consume (
-			tvf := &ast.TableValuedFunction{
-				Token:    startToken,
-				Function: name,
-			}
-
 			// Check function name for special handling
 			funcName := ""
 			if len(name.Parts) > 0 {
 				funcName = strings.ToUpper(name.Parts[len(name.Parts)-1].Value)
 			}
 
-			// Special handling for OPENROWSET BULK syntax
+			// OPENROWSET(BULK ...) reads a file and has options rather than arguments
 			if funcName == "OPENROWSET" && p.peekTokenIs(token.BULK) {
-				p.nextToken() // consume BULK
-				// Create a special argument to represent BULK mode
-				bulkIdent := &ast.Identifier{Token: p.curToken, Value: "BULK"}
-				tvf.Arguments = append(tvf.Arguments, bulkIdent)
-
-				// Parse file path
-				if p.peekTokenIs(token.STRING) || p.peekTokenIs(token.NSTRING) {
-					p.nextToken()
-					tvf.Arguments = append(tvf.Arguments, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+				tableRef = p.parseBulkRowset(startToken)
+			} else if funcName == "GENERATE_SERIES" && len(name.Parts) == 1 {
+				tableRef = p.parseGenerateSeries(startToken)
+			} else {
+				tvf := &ast.TableValuedFunction{
+					Token:    startToken,
+					Function: name,
 				}
 
-				// Parse options (comma-separated): FORMATFILE = 'x', FIRSTROW = 2, SINGLE_CLOB, etc.
-				for p.peekTokenIs(token.COMMA) {
-					p.nextToken() // consume comma
-					p.nextToken() // move to option
-
-					// Check for keyword options (SINGLE_CLOB, SINGLE_BLOB, SINGLE_NCLOB) or assignment options
-					optToken := p.curToken
-					optName := strings.ToUpper(optToken.Literal)
-
-					if p.peekTokenIs(token.EQ) {
-						// Assignment option: FORMATFILE = 'path', FIRSTROW = 2
-						p.nextToken() // consume =
-						p.nextToken() // move to value
-						// Create key-value pair as identifier = expression
-						keyIdent := &ast.Identifier{Token: optToken, Value: optName}
-						value := p.parseExpression(LOWEST)
-						// Store as a special expression or just append both
-						tvf.Arguments = append(tvf.Arguments, keyIdent)
-						tvf.Arguments = append(tvf.Arguments, value)
-					} else {
-						// Standalone keyword option like SINGLE_CLOB
-						tvf.Arguments = append(tvf.Arguments, &ast.Identifier{Token: optToken, Value: optName})
-					}
-				}
-				p.expectPeek(token.RPAREN)
-			} else {
 				// Normal argument parsing
 				if !p.peekTokenIs(token.RPAREN) {
 					p.nextToken()
@@ -2303,39 +2795,19 @@ func (p *Parser) parseTableReference() ast.TableReference {
 					}
 				}
 				p.expectPeek(token.RPAREN)
-			}
 
-			// Check for OPENJSON/OPENXML WITH schema clause
-			isOpenJsonOrXml := funcName == "OPENJSON" || funcName == "OPENXML"
-			if isOpenJsonOrXml && p.peekTokenIs(token.WITH) {
-				p.nextToken() // consume WITH
-				if p.expectPeek(token.LPAREN) {
-					tvf.OpenJsonColumns = p.parseOpenJsonColumns()
+				// Check for OPENJSON/OPENXML WITH schema clause
+				isOpenJsonOrXml := funcName == "OPENJSON" || funcName == "OPENXML"
+				if isOpenJsonOrXml && p.peekTokenIs(token.WITH) {
+					p.nextToken() // consume WITH
+					if p.expectPeek(token.LPAREN) {
+						tvf.OpenJsonColumns = p.parseOpenJsonColumns()
+					}
 				}
-			}
 
-			// Parse alias
-			if p.peekTokenIs(token.AS) {
-				p.nextToken()
-				p.nextToken()
-				tvf.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-				// Parse column aliases: AS T(c1, c2)
-				if p.peekTokenIs(token.LPAREN) {
-					p.nextToken() // consume (
-					tvf.ColumnAliases = p.parseColumnNameList()
-				}
-			} else if p.canPeekBeAlias() && !p.isJoinKeyword() && !p.peekTokenIs(token.WHERE) &&
-				!p.peekTokenIs(token.ORDER) && !p.peekTokenIs(token.GROUP) && !p.peekTokenIs(token.PIVOT) && !p.peekTokenIs(token.UNPIVOT) {
-				p.nextToken()
-				tvf.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-				// Parse column aliases: AS T(c1, c2)
-				if p.peekTokenIs(token.LPAREN) {
-					p.nextToken() // consume (
-					tvf.ColumnAliases = p.parseColumnNameList()
-				}
+				tvf.Alias, tvf.ColumnAliases = p.parseRowsetAlias()
+				tableRef = tvf
 			}
-
-			tableRef = tvf
 		} else {
 			table := &ast.TableName{Token: startToken}
 			table.Name = name
@@ -2371,6 +2843,13 @@ func (p *Parser) parseTableReference() ast.TableReference {
 				table.TemporalClause = p.parseTemporalClause()
 			}
 
+			// Check for FOR PATH (graph tables matched by SHORTEST_PATH)
+			if p.peekTokenIs(token.FOR) && p.peekPeekTokenIs(token.PATH) {
+				p.nextToken() // consume FOR
+				p.nextToken() // consume PATH
+				table.ForPath = true
+			}
+
 			// Parse alias (but not if PIVOT/UNPIVOT follows)
 			if p.peekTokenIs(token.AS) {
 				p.nextToken()
@@ -2803,21 +3282,137 @@ func (p *Parser) parseTableSample() *ast.TableSampleClause {
 // parseOpenJsonColumns parses the column definitions in OPENJSON/OPENXML WITH clause
 // OPENJSON syntax: col_name datatype ['$.path'] [AS JSON], ...
 // OPENXML syntax: col_name datatype ['@attr' or 'element'], ...
-func (p *Parser) parseOpenJsonColumns() []*ast.OpenJsonColumn {
-	var columns []*ast.OpenJsonColumn
-
-	p.nextToken() // move to first column name
-	for !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.EOF) {
-		col := &ast.OpenJsonColumn{}
-
-		// Column name
-		col.Name = p.curToken.Literal
+// parseRowsetAlias parses the optional alias of a rowset function and the
+// names it gives the columns: AS t(c1, c2).
+func (p *Parser) parseRowsetAlias() (*ast.Identifier, []*ast.Identifier) {
+	if p.peekTokenIs(token.AS) {
 		p.nextToken()
-
-		// Data type
-		col.DataType = p.parseDataType()
-
-		// Optional JSON path (string literal starting with $)
+	} else if !p.canPeekBeAlias() || p.isJoinKeyword() || p.peekTokenIs(token.WHERE) ||
+		p.peekTokenIs(token.ORDER) || p.peekTokenIs(token.GROUP) || p.peekTokenIs(token.PIVOT) || p.peekTokenIs(token.UNPIVOT) {
+		return nil, nil
+	}
+	p.nextToken()
+	alias := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	var columns []*ast.Identifier
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken() // consume (
+		columns = p.parseColumnNameList()
+	}
+	return alias, columns
+}
+
+// parseGenerateSeries parses GENERATE_SERIES(start, stop [, step]) [AS
+// alias]. The current token is the opening parenthesis.
+func (p *Parser) parseGenerateSeries(startToken token.Token) ast.TableReference {
+	series := &ast.GenerateSeries{Token: startToken}
+	args := p.parseExpressionList(token.RPAREN)
+	if args == nil {
+		return nil
+	}
+	if len(args) < 2 || len(args) > 3 {
+		p.syntaxError(startToken, "GENERATE_SERIES takes 2 to 3 arguments, got %d", len(args))
+		return nil
+	}
+	series.Start, series.Stop = args[0], args[1]
+	if len(args) == 3 {
+		series.Step = args[2]
+	}
+	series.Alias, series.ColumnAliases = p.parseRowsetAlias()
+	return series
+}
+
+// parseBulkRowset parses OPENROWSET(BULK 'path', option, ...) [WITH
+// (columns)] [AS alias]. The current token is the opening parenthesis.
+func (p *Parser) parseBulkRowset(startToken token.Token) ast.TableReference {
+	rowset := &ast.BulkRowset{Token: startToken}
+	p.nextToken() // move to BULK
+
+	// BULK 'path' or BULK ('path', 'path', ...)
+	p.nextToken()
+	if p.curTokenIs(token.LPAREN) {
+		for {
+			p.nextToken()
+			rowset.Paths = append(rowset.Paths, p.parseExpression(LOWEST))
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // consume ,
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	} else {
+		rowset.Paths = append(rowset.Paths, p.parseExpression(LOWEST))
+	}
+
+	// FORMATFILE = 'x', FIRSTROW = 2, SINGLE_CLOB, FORMAT = 'PARQUET', ...
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken() // consume ,
+		p.nextToken() // move to the first option
+		rowset.Options = p.parseOptions()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		rowset.Columns = p.parseRowsetColumns()
+	}
+
+	rowset.Alias, rowset.ColumnAliases = p.parseRowsetAlias()
+	return rowset
+}
+
+// parseRowsetColumns parses the WITH schema of OPENROWSET(BULK ...):
+// name type [COLLATE collation] ['path' | ordinal], ... The current token
+// is the opening parenthesis; on return it is the closing one.
+func (p *Parser) parseRowsetColumns() []*ast.RowsetColumn {
+	var columns []*ast.RowsetColumn
+	for {
+		p.nextToken() // move to the column name
+		col := &ast.RowsetColumn{Name: p.curToken.Literal}
+		p.nextToken()
+		col.DataType = p.parseDataType()
+		if p.peekTokenIs(token.COLLATE) {
+			p.nextToken() // consume COLLATE
+			p.nextToken()
+			col.Collation = p.curToken.Literal
+		}
+		switch p.peekToken.Type {
+		case token.STRING, token.NSTRING:
+			p.nextToken()
+			col.Path = p.curToken.Literal
+		case token.INT:
+			p.nextToken()
+			col.Ordinal = int(p.parseIntLiteral())
+		}
+		columns = append(columns, col)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+	p.expectPeek(token.RPAREN)
+	return columns
+}
+
+func (p *Parser) parseOpenJsonColumns() []*ast.OpenJsonColumn {
+	var columns []*ast.OpenJsonColumn
+
+	p.nextToken() // move to first column name
+	for !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.EOF) {
+		col := &ast.OpenJsonColumn{}
+
+		// Column name
+		col.Name = p.curToken.Literal
+		p.nextToken()
+
+		// Data type
+		col.DataType = p.parseDataType()
+
+		// Optional JSON path (string literal starting with $)
 		if p.peekTokenIs(token.STRING) || p.peekTokenIs(token.NSTRING) {
 			p.nextToken()
 			col.Path = p.curToken.Literal
@@ -3387,6 +3982,12 @@ func (p *Parser) parseUpdateStatement() ast.Statement {
 		}
 	}
 
+	// Parse OPTION clause
+	if p.peekTokenIs(token.OPTION) {
+		p.nextToken() // consume OPTION
+		stmt.Options = p.parseOptionClause()
+	}
+
 	return stmt
 }
 
@@ -3455,7 +4056,8 @@ func (p *Parser) parseSetClause() *ast.SetClause {
 		p.peekTokenIs(token.PLUSEQ) || p.peekTokenIs(token.MINUSEQ) ||
 		p.peekTokenIs(token.MULEQ) || p.peekTokenIs(token.DIVEQ) ||
 		p.peekTokenIs(token.MODEQ) || p.peekTokenIs(token.ANDEQ) ||
-		p.peekTokenIs(token.OREQ) || p.peekTokenIs(token.XOREQ) {
+		p.peekTokenIs(token.OREQ) || p.peekTokenIs(token.XOREQ) ||
+		p.peekTokenIs(token.DPIPEEQ) {
 		p.nextToken()
 	} else {
 		p.peekError(token.EQ)
@@ -3507,8 +4109,12 @@ func (p *Parser) parseDeleteStatement() ast.Statement {
 
 		// Check for OUTPUT before determining if firstIdent is alias or table
 		if p.peekTokenIs(token.OUTPUT) {
-			// DELETE alias OUTPUT ... FROM table
-			stmt.Alias = &ast.Identifier{Token: firstIdent.Parts[0].Token, Value: firstIdent.Parts[0].Value}
+			// DELETE alias OUTPUT ... FROM table, or DELETE schema.table OUTPUT ...
+			if len(firstIdent.Parts) > 1 {
+				stmt.Table = firstIdent
+			} else {
+				stmt.Alias = &ast.Identifier{Token: firstIdent.Parts[0].Token, Value: firstIdent.Parts[0].Value}
+			}
 			p.nextToken()
 			stmt.Output = p.parseOutputClause()
 			// After OUTPUT, expect FROM
@@ -3564,6 +4170,12 @@ func (p *Parser) parseDeleteStatement() ast.Statement {
 		}
 	}
 
+	// Parse OPTION clause
+	if p.peekTokenIs(token.OPTION) {
+		p.nextToken() // consume OPTION
+		stmt.Options = p.parseOptionClause()
+	}
+
 	return stmt
 }
 
@@ -3694,6 +4306,12 @@ func (p *Parser) parseMergeStatement() ast.Statement {
 		stmt.Output = p.parseOutputClause()
 	}
 
+	// Parse OPTION clause
+	if p.peekTokenIs(token.OPTION) {
+		p.nextToken() // consume OPTION
+		stmt.Options = p.parseOptionClause()
+	}
+
 	return stmt
 }
 
@@ -3973,8 +4591,13 @@ func (p *Parser) parseDataType() *ast.DataType {
 			if p.peekTokenIs(token.COMMA) {
 				p.nextToken()
 				p.nextToken()
-				scale, _ := strconv.Atoi(p.curToken.Literal)
-				dt.Scale = &scale
+				if dt.Name == "VECTOR" {
+					// VECTOR(dimensions, float16) gives the type of the elements
+					dt.ElementType = p.curToken.Literal
+				} else {
+					scale, _ := strconv.Atoi(p.curToken.Literal)
+					dt.Scale = &scale
+				}
 			}
 
 			p.expectPeek(token.RPAREN)
@@ -4083,8 +4706,9 @@ func (p *Parser) parseSetStatement() ast.Statement {
 	// Check for compound assignment operators
 	switch p.peekToken.Type {
 	case token.PLUSEQ, token.MINUSEQ, token.MULEQ, token.DIVEQ, token.MODEQ,
-		token.ANDEQ, token.OREQ, token.XOREQ:
+		token.ANDEQ, token.OREQ, token.XOREQ, token.DPIPEEQ:
 		p.nextToken() // consume operator
+		stmt.Operator = p.curToken.Literal
 		p.nextToken() // move to value
 		stmt.Value = p.parseExpression(LOWEST)
 		return stmt
@@ -4093,6 +4717,7 @@ func (p *Parser) parseSetStatement() ast.Statement {
 	if !p.expectPeek(token.EQ) {
 		return nil
 	}
+	stmt.Operator = "="
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 
@@ -4265,26 +4890,15 @@ func (p *Parser) parseBeginEndBlock() *ast.BeginEndBlock {
 
 // parseBeginAtomicBlock handles BEGIN ATOMIC WITH (...) blocks for natively compiled procs
 func (p *Parser) parseBeginAtomicBlock() *ast.BeginEndBlock {
-	block := &ast.BeginEndBlock{Token: p.curToken}
+	block := &ast.BeginEndBlock{Token: p.curToken, IsAtomic: true}
 	p.nextToken() // move past BEGIN ATOMIC
 
 	// Handle WITH clause: WITH (TRANSACTION ISOLATION LEVEL = ..., LANGUAGE = ...)
 	if p.curTokenIs(token.WITH) {
 		p.nextToken() // move past WITH
 		if p.curTokenIs(token.LPAREN) {
-			// Skip the entire WITH (...) clause by matching parens
-			depth := 1
-			p.nextToken()
-			for depth > 0 && !p.curTokenIs(token.EOF) {
-				if p.curTokenIs(token.LPAREN) {
-					depth++
-				} else if p.curTokenIs(token.RPAREN) {
-					depth--
-				}
-				if depth > 0 {
-					p.nextToken()
-				}
-			}
+			block.AtomicOptions = p.parseOptionList()
+			block.Atomic = atomicBlockOptions(block.AtomicOptions)
 			p.nextToken() // move past final )
 		}
 	}
@@ -4307,8 +4921,10 @@ func (p *Parser) parseBeginAtomicBlock() *ast.BeginEndBlock {
 	return block
 }
 
-// isBlockEndingEnd returns true if current END token ends a BEGIN block
+// isBlockEndingEnd returns true if current END token ends a BEGIN block.
+// Otherwise a statement of the block starts there.
 func (p *Parser) isBlockEndingEnd() bool {
+	p.expectStatement()
 	return p.curTokenIs(token.END)
 }
 
@@ -4700,11 +5316,17 @@ func (p *Parser) parseCreateStatement() ast.Statement {
 	createToken := p.curToken
 	p.nextToken()
 
+	p.expectAll(createObjects)
 	switch p.curToken.Type {
 	case token.PROCEDURE, token.PROC:
 		return p.parseCreateProcedureStatement()
 	case token.TABLE:
 		return p.parseCreateTableStatement()
+	case token.REMOTE:
+		if p.dialect == Synapse && p.peekTokenIs(token.TABLE) {
+			return p.parseCreateRemoteTableStatement(createToken)
+		}
+		return nil
 	case token.VIEW:
 		return p.parseCreateViewStatement(createToken)
 	case token.INDEX:
@@ -4775,6 +5397,13 @@ func (p *Parser) parseCreateStatement() ast.Statement {
 			return p.parseCreateXmlIndexStatement(createToken, false)
 		}
 		return nil
+	case token.JSON:
+		// CREATE JSON INDEX
+		if p.peekTokenIs(token.INDEX) {
+			p.nextToken() // consume INDEX
+			return p.parseCreateJsonIndexStatement(createToken)
+		}
+		return nil
 	case token.XML_SCHEMA_COLLECTION:
 		// CREATE XML SCHEMA COLLECTION name AS N'...'
 		return p.parseCreateXmlSchemaCollectionStatement(createToken)
@@ -4830,8 +5459,21 @@ func (p *Parser) parseCreateStatement() ast.Statement {
 			p.nextToken() // move to ROLE
 			return p.parseCreateServerRoleStatement(createToken)
 		}
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") {
+			p.nextToken() // move to AUDIT
+			if strings.EqualFold(p.peekToken.Literal, "SPECIFICATION") {
+				p.nextToken() // move to SPECIFICATION
+				return p.parseCreateAuditSpecificationStatement(createToken, "SERVER")
+			}
+			return p.parseCreateServerAuditStatement(createToken)
+		}
 		return nil
 	case token.DATABASE:
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") && strings.EqualFold(p.peekPeekToken.Literal, "SPECIFICATION") {
+			p.nextToken() // move to AUDIT
+			p.nextToken() // move to SPECIFICATION
+			return p.parseCreateAuditSpecificationStatement(createToken, "DATABASE")
+		}
 		// CREATE DATABASE or CREATE DATABASE SCOPED CREDENTIAL
 		return p.parseCreateDatabaseOrScopedCredential(createToken)
 	case token.IDENT:
@@ -4844,12 +5486,26 @@ func (p *Parser) parseCreateStatement() ast.Statement {
 		if upper == "CREDENTIAL" {
 			return p.parseCreateCredentialStatement(createToken)
 		}
+		if upper == "EXTERNAL" {
+			return p.parseCreateExternalStatement(createToken)
+		}
+		if upper == "SECURITY" && strings.EqualFold(p.peekToken.Literal, "POLICY") {
+			p.nextToken() // move to POLICY
+			return p.parseCreateSecurityPolicyStatement(createToken)
+		}
+		if upper == "EVENT" && strings.EqualFold(p.peekToken.Literal, "SESSION") {
+			p.nextToken() // move to SESSION
+			return p.parseCreateEventSessionStatement(createToken)
+		}
 		if upper == "SPATIAL" && p.peekTokenIs(token.INDEX) {
 			p.nextToken() // move to INDEX
 			return p.parseCreateIndexStatement(createToken, false, nil)
 		}
 		return nil
 	default:
+		if p.curToken.Type == token.EOF {
+			p.syntaxError(p.curToken, "expected object type after CREATE, got EOF")
+		}
 		// Skip other CREATE statements for now
 		return nil
 	}
@@ -4869,36 +5525,53 @@ func (p *Parser) parseCreateTableStatement() ast.Statement {
 		}
 	}
 
+	// CREATE TABLE name [(names)] [WITH (options)] AS SELECT ...
+	if p.dialect == Synapse {
+		if p.peekTokenIs(token.WITH) || (p.peekTokenIs(token.AS) && (p.peekPeekTokenIs(token.SELECT) || p.peekPeekTokenIs(token.WITH))) {
+			return p.parseCreateTableAsSelect(stmt)
+		}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken() // consume (
+			if p.peekPeekTokenIs(token.COMMA) || p.peekPeekTokenIs(token.RPAREN) {
+				stmt.ColumnNames = p.parseColumnNameList()
+				return p.parseCreateTableAsSelect(stmt)
+			}
+			return p.parseCreateTableElements(stmt)
+		}
+	}
+
 	// Check for CREATE TABLE name AS FILETABLE
 	if p.peekTokenIs(token.AS) {
 		p.nextToken() // consume AS
 		p.nextToken() // move to FILETABLE or other
 		if strings.ToUpper(p.curToken.Literal) == "FILETABLE" {
+			stmt.IsFileTable = true
 			// FILETABLE doesn't have column definitions, just WITH options
 			if p.peekTokenIs(token.WITH) {
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
-					}
+					stmt.Options = p.parseOptionList()
 				}
 			}
 			return stmt
 		}
-		// Handle other AS cases (NODE, EDGE) - but those come after column defs
+		// An edge table may have no columns of its own: CREATE TABLE name AS EDGE
+		if kind := graphTableKind(p.curToken); kind != ast.GraphTableNone {
+			stmt.GraphKind = kind
+			return stmt
+		}
 	}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
+	return p.parseCreateTableElements(stmt)
+}
+
+// parseCreateTableElements parses the columns and constraints of CREATE
+// TABLE, from the opening parenthesis, and the options that follow them.
+func (p *Parser) parseCreateTableElements(stmt *ast.CreateTableStatement) ast.Statement {
 	p.nextToken()
 
 	// Parse column definitions and table constraints
@@ -4934,20 +5607,7 @@ func (p *Parser) parseCreateTableStatement() ast.Statement {
 		p.nextToken() // consume WITH
 		if p.peekTokenIs(token.LPAREN) {
 			p.nextToken() // consume (
-			p.nextToken() // move to first option
-			// Parse options until matching ) - handle nested parentheses
-			depth := 1
-			for depth > 0 && !p.curTokenIs(token.EOF) {
-				if p.curTokenIs(token.LPAREN) {
-					depth++
-				} else if p.curTokenIs(token.RPAREN) {
-					depth--
-					if depth == 0 {
-						break
-					}
-				}
-				p.nextToken()
-			}
+			stmt.Options = p.parseOptionList()
 		}
 	}
 
@@ -4975,66 +5635,469 @@ func (p *Parser) parseCreateTableStatement() ast.Statement {
 		stmt.TextImageOn = p.curToken.Literal
 	}
 
+	// Scripting tools write the WITH clause after the filegroup
+	if stmt.Options == nil && p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		stmt.Options = p.parseOptionList()
+	}
+
 	// Check for AS NODE or AS EDGE (graph tables)
 	if p.peekTokenIs(token.AS) {
 		p.nextToken() // consume AS
 		p.nextToken() // move to NODE/EDGE
-		// Just consume it - we're not storing it for now
+		stmt.GraphKind = graphTableKind(p.curToken)
 	}
 
+	readTableOptions(stmt)
 	return stmt
 }
 
-func (p *Parser) isTableConstraintStart() bool {
-	// PERIOD is only a constraint if followed by FOR (PERIOD FOR SYSTEM_TIME)
-	if p.curTokenIs(token.PERIOD) {
-		return p.peekTokenIs(token.FOR)
+// parseCreateTableAsSelect parses the rest of CREATE TABLE name [(names)]
+// [WITH (options)] AS SELECT ... after the name or the column names.
+func (p *Parser) parseCreateTableAsSelect(stmt *ast.CreateTableStatement) ast.Statement {
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
 	}
-	return p.curTokenIs(token.CONSTRAINT) ||
-		p.curTokenIs(token.PRIMARY) ||
-		p.curTokenIs(token.FOREIGN) ||
-		p.curTokenIs(token.UNIQUE) ||
-		p.curTokenIs(token.CHECK) ||
-		p.curTokenIs(token.INDEX)
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if stmt.AsSelect = p.parseAsSelect("CREATE TABLE"); stmt.AsSelect == nil {
+		return nil
+	}
+	readTableOptions(stmt)
+	return stmt
 }
 
-func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
-	col := &ast.ColumnDefinition{Token: p.curToken}
-	col.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+// parseAsSelect parses the SELECT or WITH ... SELECT after AS, which is
+// the current token.
+func (p *Parser) parseAsSelect(what string) ast.Statement {
 	p.nextToken()
+	switch {
+	case p.curTokenIs(token.SELECT):
+		if sel := p.parseSelectStatement(); sel != nil {
+			return sel
+		}
+	case p.curTokenIs(token.WITH):
+		return p.parseWithStatement()
+	default:
+		p.syntaxError(p.curToken, "expected SELECT after %s ... AS, got %s", what, p.curToken.Literal)
+	}
+	return nil
+}
 
-	// Check for computed column (AS expression)
-	if p.curTokenIs(token.AS) {
+// readTableOptions sets the typed table options from the WITH options of
+// CREATE TABLE.
+func readTableOptions(stmt *ast.CreateTableStatement) {
+	stmt.SystemVersioning = systemVersioning(stmt.Options.Get("SYSTEM_VERSIONING"))
+	stmt.Ledger = ledgerOption(stmt.Options.Get("LEDGER"))
+	stmt.Distribution = distribution(stmt.Options.Get("DISTRIBUTION"))
+	stmt.Index = tableIndex(stmt.Options)
+	stmt.Partition = tablePartition(stmt.Options.Get("PARTITION"))
+	if opt := stmt.Options.Get("MEMORY_OPTIMIZED"); opt != nil {
+		stmt.MemoryOptimized = optionIsOn(opt)
+	}
+	if opt := stmt.Options.Get("DURABILITY"); opt != nil && strings.EqualFold(optionText(opt), "SCHEMA_ONLY") {
+		stmt.Durability = ast.DurabilitySchemaOnly
+	}
+}
+
+// parseOptionList parses a parenthesized option list such as
+// (FILLFACTOR = 80, ONLINE = ON (MAXDOP = 4)). The current token is the
+// opening parenthesis; on return it is the closing one.
+func (p *Parser) parseOptionList() ast.OptionList {
+	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		if p.curTokenIs(token.LPAREN) {
-			p.nextToken()
-			col.Computed = p.parseExpression(LOWEST)
-			p.expectPeek(token.RPAREN)
-		} else {
-			col.Computed = p.parseExpression(LOWEST)
+		return ast.OptionList{}
+	}
+	p.nextToken() // move past (
+	list := p.parseOptions()
+	p.expectPeek(token.RPAREN)
+	return list
+}
+
+// parseOptions parses options separated by commas, from the current token
+// to the last token of the last option.
+func (p *Parser) parseOptions() ast.OptionList {
+	list := ast.OptionList{p.parseOption()}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken() // consume ,
+		p.nextToken() // move to the next option
+		list = append(list, p.parseOption())
+	}
+	return list
+}
+
+// parseOption parses a single option: a name of one or more words, an
+// optional = value and an optional nested list.
+func (p *Parser) parseOption() *ast.Option {
+	opt := &ast.Option{Token: p.curToken}
+	switch p.curToken.Type {
+	case token.LPAREN:
+		opt.List = p.parseOptionList()
+		return opt
+	case token.STRING, token.NSTRING, token.INT, token.FLOAT, token.MINUS, token.PLUS, token.BINARY:
+		// A bare value, as in PARTITION (c RANGE RIGHT FOR VALUES ('2024', ...))
+		opt.Value = p.parseOptionValue()
+		return opt
+	}
+	opt.Name = p.parseOptionWords()
+	if p.peekTokenIs(token.EQ) {
+		p.nextToken() // consume =
+		opt.Equals = true
+		if !p.peekTokenIs(token.LPAREN) {
+			p.nextToken() // move to value
+			opt.Value = p.parseOptionValue()
 		}
-		// Check for PERSISTED
-		if p.peekTokenIs(token.PERSISTED) {
-			p.nextToken()
-			col.IsPersisted = true
+	}
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken() // consume (
+		opt.List = p.parseOptionList()
+	}
+	return opt
+}
+
+// parseOptionValue parses the value of an option. Literals, variables and
+// qualified names are expressions; keywords and other words, such as ON,
+// SNAPSHOT or 6 MONTHS, become an identifier holding the words.
+func (p *Parser) parseOptionValue() ast.Expression {
+	switch p.curToken.Type {
+	case token.STRING, token.NSTRING, token.VARIABLE, token.MINUS, token.PLUS, token.BINARY:
+		return p.parseExpression(LOWEST)
+	case token.INT, token.FLOAT:
+		if isOptionEnd(p.peekToken) {
+			return p.parseExpression(LOWEST)
+		}
+	case token.IDENT:
+		// A qualified name may be followed by its own options, as in
+		// LEDGER_VIEW = dbo.v (TRANSACTION_ID_COLUMN_NAME = ...)
+		if p.peekTokenIs(token.DOT) {
+			return p.parseQualifiedIdentifier()
 		}
-		return col
 	}
+	tok := p.curToken
+	return &ast.Identifier{Token: tok, Value: p.parseOptionWords()}
+}
 
-	// Parse data type
-	col.DataType = p.parseDataType()
+// parseOptionWords joins the words from the current token up to the next
+// =, comma, parenthesis or end of statement.
+func (p *Parser) parseOptionWords() string {
+	words := []string{p.curToken.Literal}
+	for !isOptionEnd(p.peekToken) {
+		p.nextToken()
+		words = append(words, p.curToken.Literal)
+	}
+	return strings.Join(words, " ")
+}
 
-	// Parse column options
-	for {
-		if p.peekTokenIs(token.COLLATE) {
-			p.nextToken()
-			p.nextToken()
-			col.Collation = p.curToken.Literal
-		} else if p.peekTokenIs(token.NULL) {
-			p.nextToken()
-			nullable := true
-			col.Nullable = &nullable
-		} else if p.peekTokenIs(token.NOT) {
+// systemVersioning reads a SYSTEM_VERSIONING option.
+func systemVersioning(opt *ast.Option) *ast.SystemVersioning {
+	if opt == nil {
+		return nil
+	}
+	sv := &ast.SystemVersioning{On: optionIsOn(opt)}
+	for _, o := range opt.List {
+		switch strings.ToUpper(o.Name) {
+		case "HISTORY_TABLE":
+			sv.HistoryTable = optionName(o)
+		case "DATA_CONSISTENCY_CHECK":
+			on := optionIsOn(o)
+			sv.DataConsistencyCheck = &on
+		case "HISTORY_RETENTION_PERIOD":
+			sv.HistoryRetention = retentionPeriod(o)
+		}
+	}
+	return sv
+}
+
+// retentionPeriod reads INFINITE or a count and unit such as 6 MONTHS.
+func retentionPeriod(opt *ast.Option) *ast.RetentionPeriod {
+	if opt.Value == nil {
+		return nil
+	}
+	words := strings.Fields(strings.ToUpper(opt.Value.String()))
+	if len(words) == 1 && words[0] == "INFINITE" {
+		return &ast.RetentionPeriod{Infinite: true}
+	}
+	if len(words) != 2 {
+		return nil
+	}
+	count, err := strconv.ParseInt(words[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &ast.RetentionPeriod{Count: count, Unit: strings.TrimSuffix(words[1], "S")}
+}
+
+// ledgerOption reads a LEDGER option.
+func ledgerOption(opt *ast.Option) *ast.LedgerOption {
+	if opt == nil {
+		return nil
+	}
+	lo := &ast.LedgerOption{On: optionIsOn(opt)}
+	for _, o := range opt.List {
+		switch strings.ToUpper(o.Name) {
+		case "APPEND_ONLY":
+			lo.AppendOnly = optionIsOn(o)
+		case "LEDGER_VIEW":
+			lo.View = optionName(o)
+			for _, col := range o.List {
+				name := ""
+				if col.Value != nil {
+					name = col.Value.String()
+				}
+				switch strings.ToUpper(col.Name) {
+				case "TRANSACTION_ID_COLUMN_NAME":
+					lo.TransactionIDColumn = name
+				case "SEQUENCE_NUMBER_COLUMN_NAME":
+					lo.SequenceNumberColumn = name
+				case "OPERATION_TYPE_COLUMN_NAME":
+					lo.OperationTypeColumn = name
+				case "OPERATION_TYPE_DESC_COLUMN_NAME":
+					lo.OperationTypeDescColumn = name
+				}
+			}
+		}
+	}
+	return lo
+}
+
+// optionIsOn reports whether an option is set to ON.
+func optionIsOn(opt *ast.Option) bool {
+	return opt.Value != nil && strings.EqualFold(opt.Value.String(), "ON")
+}
+
+// optionName returns the value of an option that names an object.
+func optionName(opt *ast.Option) *ast.QualifiedIdentifier {
+	switch v := opt.Value.(type) {
+	case *ast.QualifiedIdentifier:
+		return v
+	case *ast.Identifier:
+		return &ast.QualifiedIdentifier{Parts: []*ast.Identifier{v}}
+	}
+	return nil
+}
+
+// distribution reads the DISTRIBUTION option of a dedicated pool table.
+func distribution(opt *ast.Option) *ast.Distribution {
+	if opt == nil || opt.Value == nil {
+		return nil
+	}
+	switch strings.ToUpper(opt.Value.String()) {
+	case "HASH":
+		d := &ast.Distribution{Kind: ast.DistributionHash}
+		for _, o := range opt.List {
+			d.Columns = append(d.Columns, &ast.Identifier{Token: o.Token, Value: o.Name})
+		}
+		return d
+	case "ROUND_ROBIN":
+		return &ast.Distribution{Kind: ast.DistributionRoundRobin}
+	case "REPLICATE":
+		return &ast.Distribution{Kind: ast.DistributionReplicate}
+	}
+	return nil
+}
+
+// tableIndex reads the HEAP, CLUSTERED INDEX (...) or CLUSTERED
+// COLUMNSTORE INDEX [ORDER (...)] option of a dedicated pool table.
+func tableIndex(options ast.OptionList) *ast.TableIndex {
+	for _, o := range options {
+		var index *ast.TableIndex
+		switch strings.ToUpper(strings.Join(strings.Fields(o.Name), " ")) {
+		case "HEAP":
+			return &ast.TableIndex{Kind: ast.TableIndexHeap}
+		case "CLUSTERED INDEX":
+			index = &ast.TableIndex{Kind: ast.TableIndexClustered}
+		case "CLUSTERED COLUMNSTORE INDEX", "CLUSTERED COLUMNSTORE INDEX ORDER":
+			index = &ast.TableIndex{Kind: ast.TableIndexClusteredColumnstore}
+		default:
+			continue
+		}
+		for _, c := range o.List {
+			words := strings.Fields(c.Name)
+			if len(words) == 0 {
+				continue
+			}
+			index.Columns = append(index.Columns, &ast.IndexColumn{
+				Name:       &ast.Identifier{Token: c.Token, Value: words[0]},
+				Descending: len(words) > 1 && strings.EqualFold(words[1], "DESC"),
+			})
+		}
+		return index
+	}
+	return nil
+}
+
+// tablePartition reads PARTITION (column RANGE LEFT|RIGHT FOR VALUES
+// (...)). The range is LEFT when it is not given.
+func tablePartition(opt *ast.Option) *ast.TablePartition {
+	if opt == nil || len(opt.List) != 1 {
+		return nil
+	}
+	spec := opt.List[0]
+	words := strings.Fields(spec.Name)
+	if len(words) == 0 {
+		return nil
+	}
+	part := &ast.TablePartition{Column: &ast.Identifier{Token: spec.Token, Value: words[0]}, Range: "LEFT"}
+	if len(words) > 2 && strings.EqualFold(words[1], "RANGE") {
+		part.Range = strings.ToUpper(words[2])
+	}
+	for _, v := range spec.List {
+		if v.Value != nil {
+			part.Values = append(part.Values, v.Value)
+		} else {
+			part.Values = append(part.Values, &ast.Identifier{Token: v.Token, Value: v.Name})
+		}
+	}
+	return part
+}
+
+// bucketCount reads the BUCKET_COUNT of a HASH index.
+func bucketCount(options ast.OptionList) int64 {
+	opt := options.Get("BUCKET_COUNT")
+	if opt == nil {
+		return 0
+	}
+	if n, ok := opt.Value.(*ast.IntegerLiteral); ok {
+		return n.Value
+	}
+	return 0
+}
+
+// atomicBlockOptions reads the options of BEGIN ATOMIC WITH (...).
+func atomicBlockOptions(options ast.OptionList) *ast.AtomicBlockOptions {
+	atomic := &ast.AtomicBlockOptions{}
+	for _, o := range options {
+		switch strings.ToUpper(o.Name) {
+		case "TRANSACTION ISOLATION LEVEL":
+			atomic.IsolationLevel = strings.ToUpper(optionText(o))
+		case "LANGUAGE":
+			atomic.Language = optionText(o)
+		case "DATEFIRST":
+			if n, ok := o.Value.(*ast.IntegerLiteral); ok {
+				atomic.DateFirst = int(n.Value)
+			}
+		case "DATEFORMAT":
+			atomic.DateFormat = optionText(o)
+		case "DELAYED_DURABILITY":
+			atomic.DelayedDurability = optionBool(o)
+		}
+	}
+	return atomic
+}
+
+// optionText returns the value of an option that is a word or a string,
+// without quotes.
+func optionText(opt *ast.Option) string {
+	switch v := opt.Value.(type) {
+	case nil:
+		return ""
+	case *ast.StringLiteral:
+		return v.Value
+	default:
+		return v.String()
+	}
+}
+
+func isOptionEnd(tok token.Token) bool {
+	switch tok.Type {
+	case token.EQ, token.COMMA, token.LPAREN, token.RPAREN, token.SEMICOLON, token.GO, token.EOF:
+		return true
+	}
+	return false
+}
+
+// generatedAlwaysKind returns the kind of a GENERATED ALWAYS AS column
+// from the word before START or END.
+func generatedAlwaysKind(what string, start bool) ast.GeneratedAlwaysKind {
+	var kind ast.GeneratedAlwaysKind
+	switch what {
+	case "ROW":
+		kind = ast.GeneratedRowStart
+	case "TRANSACTION_ID":
+		kind = ast.GeneratedTransactionIDStart
+	case "SEQUENCE_NUMBER":
+		kind = ast.GeneratedSequenceNumberStart
+	default:
+		return ast.GeneratedNone
+	}
+	if !start {
+		kind++ // each END follows its START
+	}
+	return kind
+}
+
+// graphTableKind returns the kind named by NODE or EDGE after CREATE TABLE
+// ... AS.
+func graphTableKind(tok token.Token) ast.GraphTableKind {
+	switch strings.ToUpper(tok.Literal) {
+	case "NODE":
+		return ast.GraphTableNode
+	case "EDGE":
+		return ast.GraphTableEdge
+	}
+	return ast.GraphTableNone
+}
+
+func (p *Parser) isTableConstraintStart() bool {
+	// PERIOD is only a constraint if followed by FOR (PERIOD FOR SYSTEM_TIME)
+	if p.curTokenIs(token.PERIOD) {
+		return p.peekTokenIs(token.FOR)
+	}
+	// CONNECTION is not a keyword; a column of that name is followed by its type
+	if p.curToken.Type == token.IDENT && strings.EqualFold(p.curToken.Literal, "CONNECTION") {
+		return p.peekTokenIs(token.LPAREN)
+	}
+	return p.curTokenIs(token.CONSTRAINT) ||
+		p.curTokenIs(token.PRIMARY) ||
+		p.curTokenIs(token.FOREIGN) ||
+		p.curTokenIs(token.UNIQUE) ||
+		p.curTokenIs(token.CHECK) ||
+		p.curTokenIs(token.INDEX)
+}
+
+func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
+	col := &ast.ColumnDefinition{Token: p.curToken}
+	col.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.nextToken()
+
+	// Check for computed column (AS expression)
+	if p.curTokenIs(token.AS) {
+		p.nextToken()
+		if p.curTokenIs(token.LPAREN) {
+			p.nextToken()
+			col.Computed = p.parseExpression(LOWEST)
+			p.expectPeek(token.RPAREN)
+		} else {
+			col.Computed = p.parseExpression(LOWEST)
+		}
+		// Check for PERSISTED
+		if p.peekTokenIs(token.PERSISTED) {
+			p.nextToken()
+			col.IsPersisted = true
+		}
+		return col
+	}
+
+	// Parse data type
+	col.DataType = p.parseDataType()
+
+	// Parse column options
+	for {
+		if p.peekTokenIs(token.COLLATE) {
+			p.nextToken()
+			p.nextToken()
+			col.Collation = p.curToken.Literal
+		} else if p.peekTokenIs(token.NULL) {
+			p.nextToken()
+			nullable := true
+			col.Nullable = &nullable
+		} else if p.peekTokenIs(token.NOT) {
 			p.nextToken()
 			if p.expectPeek(token.NULL) {
 				nullable := false
@@ -5100,15 +6163,17 @@ func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
 			if p.peekTokenIs(token.AS) {
 				p.nextToken() // consume AS
 			}
-			if p.peekTokenIs(token.ROW) {
-				p.nextToken() // consume ROW
-				if p.peekTokenIs(token.START) {
-					p.nextToken()
-					col.GeneratedAlways = "ROW START"
-				} else if p.peekTokenIs(token.END) {
-					p.nextToken()
-					col.GeneratedAlways = "ROW END"
-				}
+			// ROW, TRANSACTION_ID or SEQUENCE_NUMBER, then START or END
+			p.nextToken()
+			what := strings.ToUpper(p.curToken.Literal)
+			p.nextToken()
+			col.GeneratedAlways = generatedAlwaysKind(what, p.curToken.Type == token.START)
+			if col.GeneratedAlways == ast.GeneratedNone {
+				p.syntaxError(p.curToken, "expected START or END after GENERATED ALWAYS AS %s", what)
+			}
+			if p.peekToken.Type == token.IDENT && strings.EqualFold(p.peekToken.Literal, "HIDDEN") {
+				p.nextToken() // consume HIDDEN
+				col.IsHidden = true
 			}
 		} else if p.peekTokenIs(token.CONSTRAINT) {
 			p.nextToken()
@@ -5136,6 +6201,7 @@ func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
 			} else if p.curTokenIs(token.DEFAULT_KW) {
 				p.nextToken()
 				col.Default = p.parseExpression(LOWEST)
+				col.DefaultName = constraintName
 				continue
 			}
 			if constraint != nil {
@@ -5160,22 +6226,15 @@ func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
 			// Check for HASH (memory-optimized)
 			if p.peekTokenIs(token.HASH) {
 				p.nextToken() // consume HASH
+				col.InlineIndex.IsHash = true
 			}
 			// Check for WITH (options)
 			if p.peekTokenIs(token.WITH) {
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
-					}
+					col.InlineIndex.Options = p.parseOptionList()
+					col.InlineIndex.BucketCount = bucketCount(col.InlineIndex.Options)
 				}
 			}
 		} else if p.peekTokenIs(token.IDENT) && strings.ToUpper(p.peekToken.Literal) == "MASKED" {
@@ -5185,16 +6244,7 @@ func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
-					}
+					col.Masked = p.parseOptionList()
 				}
 			}
 		} else if p.peekTokenIs(token.IDENT) && strings.ToUpper(p.peekToken.Literal) == "ENCRYPTED" {
@@ -5204,16 +6254,7 @@ func (p *Parser) parseColumnDefinition() *ast.ColumnDefinition {
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
-					}
+					col.Encrypted = p.parseOptionList()
 				}
 			}
 		} else {
@@ -5289,21 +6330,14 @@ func (p *Parser) parseInlineConstraint(ctype ast.ConstraintType) *ast.ColumnCons
 		// Check for HASH (memory-optimized tables)
 		if p.peekTokenIs(token.HASH) {
 			p.nextToken() // consume HASH
+			constraint.IsHash = true
 			// Parse WITH (BUCKET_COUNT = n)
 			if p.peekTokenIs(token.WITH) {
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
-					}
+					constraint.IndexOptions = p.parseOptionList()
+					constraint.BucketCount = bucketCount(constraint.IndexOptions)
 				}
 			}
 		}
@@ -5389,32 +6423,7 @@ func (p *Parser) parseTableConstraint() *ast.TableConstraint {
 			clustered := false
 			constraint.IsClustered = &clustered
 		}
-		p.expectPeek(token.LPAREN)
-		constraint.Columns = p.parseIndexColumns()
-		// Parse optional WITH (index options)
-		if p.peekTokenIs(token.WITH) {
-			p.nextToken() // consume WITH
-			if p.peekTokenIs(token.LPAREN) {
-				p.nextToken() // consume (
-				var opts []string
-				depth := 1
-				for depth > 0 && !p.curTokenIs(token.EOF) {
-					p.nextToken()
-					if p.curTokenIs(token.LPAREN) {
-						depth++
-						opts = append(opts, "(")
-					} else if p.curTokenIs(token.RPAREN) {
-						depth--
-						if depth > 0 {
-							opts = append(opts, ")")
-						}
-					} else {
-						opts = append(opts, p.curToken.Literal)
-					}
-				}
-				constraint.IndexOptions = strings.Join(opts, " ")
-			}
-		}
+		p.parseTableConstraintIndex(constraint)
 
 	case token.UNIQUE:
 		constraint.Type = ast.ConstraintUnique
@@ -5427,32 +6436,7 @@ func (p *Parser) parseTableConstraint() *ast.TableConstraint {
 			clustered := false
 			constraint.IsClustered = &clustered
 		}
-		p.expectPeek(token.LPAREN)
-		constraint.Columns = p.parseIndexColumns()
-		// Parse optional WITH (index options)
-		if p.peekTokenIs(token.WITH) {
-			p.nextToken() // consume WITH
-			if p.peekTokenIs(token.LPAREN) {
-				p.nextToken() // consume (
-				var opts []string
-				depth := 1
-				for depth > 0 && !p.curTokenIs(token.EOF) {
-					p.nextToken()
-					if p.curTokenIs(token.LPAREN) {
-						depth++
-						opts = append(opts, "(")
-					} else if p.curTokenIs(token.RPAREN) {
-						depth--
-						if depth > 0 {
-							opts = append(opts, ")")
-						}
-					} else {
-						opts = append(opts, p.curToken.Literal)
-					}
-				}
-				constraint.IndexOptions = strings.Join(opts, " ")
-			}
-		}
+		p.parseTableConstraintIndex(constraint)
 
 	case token.FOREIGN:
 		constraint.Type = ast.ConstraintForeignKey
@@ -5466,35 +6450,7 @@ func (p *Parser) parseTableConstraint() *ast.TableConstraint {
 		constraint.ReferencesColumns = p.parseIdentifierList()
 
 		// Parse ON DELETE / ON UPDATE
-		for p.peekTokenIs(token.ON) {
-			p.nextToken()
-			p.nextToken()
-			action := strings.ToUpper(p.curToken.Literal)
-			p.nextToken()
-
-			var actionValue string
-			if p.curTokenIs(token.CASCADE) {
-				actionValue = "CASCADE"
-			} else if p.curTokenIs(token.RESTRICT) {
-				actionValue = "RESTRICT"
-			} else if strings.ToUpper(p.curToken.Literal) == "NO" {
-				p.nextToken()
-				actionValue = "NO ACTION"
-			} else if p.curTokenIs(token.SET) {
-				p.nextToken()
-				if p.curTokenIs(token.NULL) {
-					actionValue = "SET NULL"
-				} else if p.curTokenIs(token.DEFAULT_KW) {
-					actionValue = "SET DEFAULT"
-				}
-			}
-
-			if action == "DELETE" {
-				constraint.OnDelete = actionValue
-			} else if action == "UPDATE" {
-				constraint.OnUpdate = actionValue
-			}
-		}
+		constraint.OnDelete, constraint.OnUpdate = p.parseReferentialActions()
 
 	case token.CHECK:
 		constraint.Type = ast.ConstraintCheck
@@ -5534,8 +6490,36 @@ func (p *Parser) parseTableConstraint() *ast.TableConstraint {
 		p.expectPeek(token.LPAREN)
 		constraint.Columns = p.parseIndexColumns()
 
+	case token.IDENT:
+		// CONNECTION (node TO node, ...) [ON DELETE {NO ACTION | CASCADE}]
+		if !strings.EqualFold(p.curToken.Literal, "CONNECTION") {
+			break
+		}
+		constraint.Type = ast.ConstraintConnection
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for {
+			p.nextToken()
+			conn := &ast.EdgeConnection{From: p.parseQualifiedIdentifier()}
+			if !p.expectPeek(token.TO) {
+				return nil
+			}
+			p.nextToken()
+			conn.To = p.parseQualifiedIdentifier()
+			constraint.Connections = append(constraint.Connections, conn)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // consume ,
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		constraint.OnDelete, constraint.OnUpdate = p.parseReferentialActions()
+
 	case token.INDEX:
-		// INDEX ix_name [CLUSTERED|NONCLUSTERED] (columns)
+		// INDEX ix_name [CLUSTERED|NONCLUSTERED] [HASH] (columns) [WITH (...)]
 		constraint.Type = ast.ConstraintIndex
 		p.nextToken() // move to index name
 		constraint.Name = p.curToken.Literal
@@ -5549,27 +6533,81 @@ func (p *Parser) parseTableConstraint() *ast.TableConstraint {
 			clustered := false
 			constraint.IsClustered = &clustered
 		}
-		p.expectPeek(token.LPAREN)
-		constraint.Columns = p.parseIndexColumns()
+		p.parseTableConstraintIndex(constraint)
 	}
 
 	return constraint
 }
 
-func (p *Parser) parseIndexColumns() []*ast.IndexColumn {
-	var columns []*ast.IndexColumn
-	p.nextToken()
-
-	col := &ast.IndexColumn{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
-	if p.peekTokenIs(token.ASC) {
-		p.nextToken()
-	} else if p.peekTokenIs(token.DESC) {
-		p.nextToken()
-		col.Descending = true
+// parseTableConstraintIndex parses the index of a PRIMARY KEY, UNIQUE or
+// INDEX table constraint after CLUSTERED or NONCLUSTERED: an optional HASH,
+// the columns and optional WITH (index options).
+func (p *Parser) parseTableConstraintIndex(constraint *ast.TableConstraint) {
+	if p.peekTokenIs(token.HASH) {
+		p.nextToken() // consume HASH
+		constraint.IsHash = true
 	}
-	columns = append(columns, col)
+	p.expectPeek(token.LPAREN)
+	constraint.Columns = p.parseIndexColumns()
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken() // consume (
+			constraint.IndexOptions = p.parseOptionList()
+			constraint.BucketCount = bucketCount(constraint.IndexOptions)
+		}
+	}
+}
 
-	for p.peekTokenIs(token.COMMA) {
+// parseReferentialActions parses the ON DELETE and ON UPDATE actions of a
+// foreign key or edge constraint.
+func (p *Parser) parseReferentialActions() (onDelete, onUpdate string) {
+	for p.peekTokenIs(token.ON) {
+		p.nextToken()
+		p.nextToken()
+		action := strings.ToUpper(p.curToken.Literal)
+		p.nextToken()
+
+		var actionValue string
+		if p.curTokenIs(token.CASCADE) {
+			actionValue = "CASCADE"
+		} else if p.curTokenIs(token.RESTRICT) {
+			actionValue = "RESTRICT"
+		} else if strings.ToUpper(p.curToken.Literal) == "NO" {
+			p.nextToken()
+			actionValue = "NO ACTION"
+		} else if p.curTokenIs(token.SET) {
+			p.nextToken()
+			if p.curTokenIs(token.NULL) {
+				actionValue = "SET NULL"
+			} else if p.curTokenIs(token.DEFAULT_KW) {
+				actionValue = "SET DEFAULT"
+			}
+		}
+
+		if action == "DELETE" {
+			onDelete = actionValue
+		} else if action == "UPDATE" {
+			onUpdate = actionValue
+		}
+	}
+	return onDelete, onUpdate
+}
+
+func (p *Parser) parseIndexColumns() []*ast.IndexColumn {
+	var columns []*ast.IndexColumn
+	p.nextToken()
+
+	col := &ast.IndexColumn{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	if p.peekTokenIs(token.ASC) {
+		p.nextToken()
+	} else if p.peekTokenIs(token.DESC) {
+		p.nextToken()
+		col.Descending = true
+	}
+	columns = append(columns, col)
+
+	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 		col = &ast.IndexColumn{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
@@ -5590,6 +6628,7 @@ func (p *Parser) parseDropStatement() ast.Statement {
 	dropToken := p.curToken
 	p.nextToken()
 
+	p.expectAll(dropObjects)
 	switch p.curToken.Type {
 	case token.TABLE:
 		return p.parseDropTableStatement()
@@ -5626,8 +6665,14 @@ func (p *Parser) parseDropStatement() ast.Statement {
 			p.nextToken() // move to ROLE
 			return p.parseDropObjectStatement(dropToken)
 		}
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") {
+			return p.parseDropAuditStatement(dropToken)
+		}
 		return nil
 	case token.DATABASE:
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") && strings.EqualFold(p.peekPeekToken.Literal, "SPECIFICATION") {
+			return p.parseDropAuditStatement(dropToken)
+		}
 		// DROP DATABASE [IF EXISTS] name [, name, ...] or DROP DATABASE SCOPED CREDENTIAL
 		// Check for SCOPED first
 		if p.peekTokenIs(token.IDENT) && strings.ToUpper(p.peekToken.Literal) == "SCOPED" {
@@ -5649,8 +6694,32 @@ func (p *Parser) parseDropStatement() ast.Statement {
 		if upper == "CREDENTIAL" || upper == "RULE" {
 			return p.parseDropObjectStatement(dropToken)
 		}
+		if upper == "EXTERNAL" {
+			kind := p.parseExternalObjectType()
+			if kind == "" {
+				return nil
+			}
+			stmt := p.parseDropObjectStatement(dropToken).(*ast.DropObjectStatement)
+			stmt.ObjectType = "EXTERNAL " + kind
+			return stmt
+		}
+		if upper == "SECURITY" && strings.EqualFold(p.peekToken.Literal, "POLICY") {
+			p.nextToken() // move to POLICY
+			stmt := p.parseDropObjectStatement(dropToken).(*ast.DropObjectStatement)
+			stmt.ObjectType = "SECURITY POLICY"
+			return stmt
+		}
+		if upper == "EVENT" && strings.EqualFold(p.peekToken.Literal, "SESSION") {
+			p.nextToken() // move to SESSION
+			stmt := p.parseDropObjectStatement(dropToken).(*ast.DropObjectStatement)
+			stmt.ObjectType = "EVENT SESSION"
+			return stmt
+		}
 		return nil
 	default:
+		if p.curToken.Type == token.EOF {
+			p.syntaxError(p.curToken, "expected object type after DROP, got EOF")
+		}
 		return nil
 	}
 }
@@ -5678,13 +6747,17 @@ func (p *Parser) parseDropObjectStatement(dropToken token.Token) ast.Statement {
 		stmt.Names = append(stmt.Names, p.parseQualifiedIdentifier())
 	}
 
-	// For DROP TRIGGER: check for ON DATABASE or ON ALL SERVER
-	if strings.ToUpper(stmt.ObjectType) == "TRIGGER" {
+	// For DROP TRIGGER: check for ON DATABASE or ON ALL SERVER, and for
+	// DROP EVENT SESSION: ON SERVER or ON DATABASE
+	if upper := strings.ToUpper(stmt.ObjectType); upper == "TRIGGER" || upper == "SESSION" {
 		if p.peekTokenIs(token.ON) {
 			p.nextToken() // consume ON
 			if p.peekTokenIs(token.DATABASE) {
 				p.nextToken()
 				stmt.OnDatabase = true
+			} else if p.peekTokenIs(token.SERVER) {
+				p.nextToken()
+				stmt.OnServer = true
 			} else if p.peekTokenIs(token.ALL) {
 				p.nextToken() // consume ALL
 				if p.peekTokenIs(token.SERVER) {
@@ -5854,6 +6927,7 @@ func (p *Parser) parseAlterStatement() ast.Statement {
 	alterToken := p.curToken
 	p.nextToken()
 
+	p.expectAll(alterObjects)
 	switch p.curToken.Type {
 	case token.TABLE:
 		return p.parseAlterTableStatement()
@@ -5890,6 +6964,11 @@ func (p *Parser) parseAlterStatement() ast.Statement {
 	case token.QUEUE:
 		return p.parseAlterQueueStatement(alterToken)
 	case token.DATABASE:
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") && strings.EqualFold(p.peekPeekToken.Literal, "SPECIFICATION") {
+			p.nextToken() // move to AUDIT
+			p.nextToken() // move to SPECIFICATION
+			return p.parseAlterAuditSpecificationStatement(alterToken, "DATABASE")
+		}
 		return p.parseAlterDatabaseStatement(alterToken)
 	case token.SERVER:
 		// ALTER SERVER ROLE
@@ -5897,6 +6976,14 @@ func (p *Parser) parseAlterStatement() ast.Statement {
 			p.nextToken() // move to ROLE
 			return p.parseAlterServerRoleStatement(alterToken)
 		}
+		if strings.EqualFold(p.peekToken.Literal, "AUDIT") {
+			p.nextToken() // move to AUDIT
+			if strings.EqualFold(p.peekToken.Literal, "SPECIFICATION") {
+				p.nextToken() // move to SPECIFICATION
+				return p.parseAlterAuditSpecificationStatement(alterToken, "SERVER")
+			}
+			return p.parseAlterServerAuditStatement(alterToken)
+		}
 		return nil
 	case token.IDENT:
 		// Handle ALTER APPLICATION ROLE
@@ -5905,8 +6992,22 @@ func (p *Parser) parseAlterStatement() ast.Statement {
 			p.nextToken() // move to ROLE
 			return p.parseAlterApplicationRoleStatement(alterToken)
 		}
+		if upper == "EXTERNAL" {
+			return p.parseAlterExternalStatement(alterToken)
+		}
+		if upper == "SECURITY" && strings.EqualFold(p.peekToken.Literal, "POLICY") {
+			p.nextToken() // move to POLICY
+			return p.parseAlterSecurityPolicyStatement(alterToken)
+		}
+		if upper == "EVENT" && strings.EqualFold(p.peekToken.Literal, "SESSION") {
+			p.nextToken() // move to SESSION
+			return p.parseAlterEventSessionStatement(alterToken)
+		}
 		return nil
 	default:
+		if p.curToken.Type == token.EOF {
+			p.syntaxError(p.curToken, "expected object type after ALTER, got EOF")
+		}
 		return nil
 	}
 }
@@ -5938,11 +7039,14 @@ func (p *Parser) parseAlterViewStatement(alterToken token.Token) ast.Statement {
 	// Optional WITH SCHEMABINDING, ENCRYPTION, VIEW_METADATA
 	if p.peekTokenIs(token.WITH) {
 		p.nextToken() // consume WITH
-		// Skip options until AS
-		for !p.peekTokenIs(token.AS) && !p.peekTokenIs(token.EOF) {
-			p.nextToken()
+		p.nextToken()
+		for {
+			stmt.Options = append(stmt.Options, p.curToken.Literal)
 			if p.peekTokenIs(token.COMMA) {
 				p.nextToken()
+				p.nextToken()
+			} else {
+				break
 			}
 		}
 	}
@@ -6079,6 +7183,8 @@ func (p *Parser) parseAlterProcedureStatement(alterToken token.Token) ast.Statem
 		stmt.Parameters = p.parseParameterDefs()
 	}
 
+	stmt.Options = p.parseModuleOptions()
+
 	// Skip to AS
 	for !p.curTokenIs(token.AS) && !p.curTokenIs(token.EOF) {
 		p.nextToken()
@@ -6088,6 +7194,8 @@ func (p *Parser) parseAlterProcedureStatement(alterToken token.Token) ast.Statem
 	// Parse body
 	if p.curTokenIs(token.BEGIN) {
 		stmt.Body = p.parseBeginEndBlock()
+	} else if p.curTokenIs(token.BEGIN_ATOMIC) {
+		stmt.Body = p.parseBeginAtomicBlock()
 	} else {
 		// Single or multiple statements without BEGIN/END
 		block := &ast.BeginEndBlock{Token: p.curToken}
@@ -6175,42 +7283,46 @@ func (p *Parser) parseAlterTableStatement() ast.Statement {
 	return stmt
 }
 
+func isAlterTableActionStart(tok token.Token) bool {
+	switch tok.Type {
+	case token.ADD, token.DROP, token.ALTER, token.ENABLE, token.DISABLE, token.SET:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) parseAlterTableAction() *ast.AlterTableAction {
 	action := &ast.AlterTableAction{}
 
 	switch p.curToken.Type {
 	case token.ADD:
-		p.nextToken()
-		// Could be ADD column or ADD CONSTRAINT
-		if p.curTokenIs(token.CONSTRAINT) || p.curTokenIs(token.PRIMARY) ||
-			p.curTokenIs(token.FOREIGN) || p.curTokenIs(token.UNIQUE) || p.curTokenIs(token.CHECK) {
-			action.Type = ast.AlterAddConstraint
-			action.Constraint = p.parseTableConstraint()
-		} else {
-			action.Type = ast.AlterAddColumn
-			// Parse first column
-			col := p.parseColumnDefinition()
-			action.Columns = append(action.Columns, col)
-
-			// Check for additional columns (comma-separated)
-			for p.peekTokenIs(token.COMMA) {
-				p.nextToken() // consume comma
-				p.nextToken() // move to next column name
-				// Make sure we're not hitting a new ALTER TABLE action keyword
-				if p.curTokenIs(token.ADD) || p.curTokenIs(token.DROP) || p.curTokenIs(token.ALTER) ||
-					p.curTokenIs(token.ENABLE) || p.curTokenIs(token.DISABLE) || p.curTokenIs(token.SET) ||
-					p.curTokenIs(token.CONSTRAINT) || p.curTokenIs(token.PRIMARY) ||
-					p.curTokenIs(token.FOREIGN) || p.curTokenIs(token.UNIQUE) || p.curTokenIs(token.CHECK) {
-					// This comma was separating actions, not columns - back up
-					break
+		// ADD takes a list of columns and table constraints, such as
+		// ADD ValidFrom ..., ValidTo ..., PERIOD FOR SYSTEM_TIME (...)
+		for {
+			p.nextToken()
+			if p.isTableConstraintStart() {
+				if constraint := p.parseTableConstraint(); constraint != nil {
+					action.Constraints = append(action.Constraints, constraint)
 				}
-				col := p.parseColumnDefinition()
-				action.Columns = append(action.Columns, col)
+			} else {
+				action.Columns = append(action.Columns, p.parseColumnDefinition())
 			}
-			// For backwards compatibility, set Column to first column
-			if len(action.Columns) > 0 {
-				action.Column = action.Columns[0]
+			// A comma may also separate actions
+			if !p.peekTokenIs(token.COMMA) || isAlterTableActionStart(p.peekPeekToken) {
+				break
 			}
+			p.nextToken() // consume ,
+		}
+		action.Type = ast.AlterAddColumn
+		if len(action.Columns) == 0 {
+			action.Type = ast.AlterAddConstraint
+		}
+		// For backwards compatibility, Column and Constraint hold the first of each
+		if len(action.Columns) > 0 {
+			action.Column = action.Columns[0]
+		}
+		if len(action.Constraints) > 0 {
+			action.Constraint = action.Constraints[0]
 		}
 
 	case token.DROP:
@@ -6223,6 +7335,12 @@ func (p *Parser) parseAlterTableAction() *ast.AlterTableAction {
 			action.Type = ast.AlterDropConstraint
 			p.nextToken()
 			action.ConstraintName = p.curToken.Literal
+		} else if p.curTokenIs(token.PERIOD) {
+			// DROP PERIOD FOR SYSTEM_TIME
+			action.Type = ast.AlterDropPeriod
+			if !p.expectPeek(token.FOR) || !p.expectPeek(token.SYSTEM_TIME) {
+				return nil
+			}
 		}
 
 	case token.ALTER:
@@ -6233,6 +7351,22 @@ func (p *Parser) parseAlterTableAction() *ast.AlterTableAction {
 			action.ColumnName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 			p.nextToken()
 			action.NewDataType = p.parseDataType()
+			if p.peekTokenIs(token.COLLATE) {
+				p.nextToken()
+				p.nextToken()
+				action.Collation = p.curToken.Literal
+			}
+			if p.peekTokenIs(token.NULL) {
+				p.nextToken()
+				nullable := true
+				action.Nullable = &nullable
+			} else if p.peekTokenIs(token.NOT) {
+				p.nextToken()
+				if p.expectPeek(token.NULL) {
+					nullable := false
+					action.Nullable = &nullable
+				}
+			}
 		}
 
 	case token.ENABLE:
@@ -6261,25 +7395,11 @@ func (p *Parser) parseAlterTableAction() *ast.AlterTableAction {
 
 	case token.SET:
 		action.Type = ast.AlterSetOption
-		action.Options = make(map[string]string)
-		p.nextToken() // move past SET
-		if p.curTokenIs(token.LPAREN) {
-			p.nextToken() // move past (
-			// Parse option = value pairs
-			for !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.EOF) {
-				optionName := p.curToken.Literal
-				p.nextToken() // move past option name
-				if p.curTokenIs(token.EQ) || (p.curToken.Type == token.IDENT && p.curToken.Literal == "=") {
-					p.nextToken() // move past =
-				}
-				optionValue := p.curToken.Literal
-				action.Options[optionName] = optionValue
-				p.nextToken()
-				if p.curTokenIs(token.COMMA) {
-					p.nextToken()
-				}
-			}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
 		}
+		action.Options = p.parseOptionList()
+		action.SystemVersioning = systemVersioning(action.Options.Get("SYSTEM_VERSIONING"))
 
 	case token.NOCHECK:
 		action.Type = ast.AlterNoCheckConstraint
@@ -6481,16 +7601,7 @@ func (p *Parser) parseCreateIndexStatement(createToken token.Token, isUnique boo
 		p.nextToken() // consume WITH
 		if p.peekTokenIs(token.LPAREN) {
 			p.nextToken() // consume (
-			// Skip options until matching )
-			depth := 1
-			for depth > 0 && !p.curTokenIs(token.EOF) {
-				p.nextToken()
-				if p.curTokenIs(token.LPAREN) {
-					depth++
-				} else if p.curTokenIs(token.RPAREN) {
-					depth--
-				}
-			}
+			stmt.Options = p.parseOptionList()
 		}
 	}
 
@@ -6528,16 +7639,7 @@ func (p *Parser) parseCreateIndexStatement(createToken token.Token, isUnique boo
 			p.nextToken() // consume WITH
 			if p.peekTokenIs(token.LPAREN) {
 				p.nextToken() // consume (
-				// Skip options until matching )
-				depth := 1
-				for depth > 0 && !p.curTokenIs(token.EOF) {
-					p.nextToken()
-					if p.curTokenIs(token.LPAREN) {
-						depth++
-					} else if p.curTokenIs(token.RPAREN) {
-						depth--
-					}
-				}
+				stmt.Options = p.parseOptionList()
 			}
 		}
 	}
@@ -6578,6 +7680,67 @@ func (p *Parser) parseCreateXmlIndexStatement(createToken token.Token, isPrimary
 	return stmt
 }
 
+// parseCreateJsonIndexStatement parses CREATE JSON INDEX name ON table
+// (column) [FOR ('path', ...)] [WITH (options)] [ON filegroup].
+func (p *Parser) parseCreateJsonIndexStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateJsonIndexStatement{Token: createToken}
+	p.nextToken() // move past INDEX
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ON) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Table = p.parseQualifiedIdentifier()
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Column = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	// FOR ('$.a', '$.b.c')
+	if p.peekTokenIs(token.FOR) {
+		p.nextToken() // consume FOR
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for {
+			p.nextToken()
+			if !p.curTokenIs(token.STRING) && !p.curTokenIs(token.NSTRING) {
+				p.syntaxError(p.curToken, "expected a JSON path, got %s", p.curToken.Literal)
+				return nil
+			}
+			stmt.Paths = append(stmt.Paths, p.curToken.Literal)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // consume ,
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
+	}
+
+	if p.peekTokenIs(token.ON) {
+		p.nextToken() // consume ON
+		p.nextToken()
+		stmt.Filegroup = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	return stmt
+}
+
 func (p *Parser) parseCreateDefaultStatement(createToken token.Token) ast.Statement {
 	stmt := &ast.CreateDefaultStatement{Token: createToken}
 	p.nextToken() // move past DEFAULT
@@ -6642,17 +7805,7 @@ func (p *Parser) parseCreateFunctionStatement(createToken token.Token) ast.State
 	if p.curTokenIs(token.TABLE) {
 		stmt.ReturnsTable = true
 		// Check for WITH clause (e.g., WITH EXECUTE AS CALLER)
-		if p.peekTokenIs(token.WITH) {
-			p.nextToken() // consume WITH
-			// Skip WITH options - handle EXECUTE AS specially
-			for !p.curTokenIs(token.EOF) {
-				// If we see AS next and it's NOT after EXECUTE, we're at body start
-				if p.peekTokenIs(token.AS) && !p.curTokenIs(token.EXECUTE) {
-					break
-				}
-				p.nextToken()
-			}
-		}
+		stmt.Options = p.parseModuleOptions()
 		// Check for inline vs multi-statement TVF
 		if p.peekTokenIs(token.AS) {
 			// Inline TVF: RETURNS TABLE AS RETURN (SELECT...) or RETURNS TABLE AS RETURN SELECT...
@@ -6690,21 +7843,7 @@ func (p *Parser) parseCreateFunctionStatement(createToken token.Token) ast.State
 	}
 
 	// WITH options (may include EXECUTE AS ...)
-	if p.peekTokenIs(token.WITH) {
-		p.nextToken() // consume WITH
-		// Skip WITH options - handle EXECUTE AS specially
-		for !p.curTokenIs(token.EOF) {
-			// If we see AS next and it's NOT after EXECUTE, we're at body start
-			if p.peekTokenIs(token.AS) && !p.curTokenIs(token.EXECUTE) {
-				break
-			}
-			// Store non-EXECUTE options
-			if !p.curTokenIs(token.EXECUTE) && !p.curTokenIs(token.AS) && !p.curTokenIs(token.WITH) {
-				stmt.Options = append(stmt.Options, p.curToken.Literal)
-			}
-			p.nextToken()
-		}
-	}
+	stmt.Options = p.parseModuleOptions()
 
 	// AS BEGIN...END or AS BEGIN ATOMIC...END
 	if p.peekTokenIs(token.AS) {
@@ -6761,6 +7900,9 @@ func (p *Parser) parseCreateTriggerStatement(createToken token.Token) ast.Statem
 					p.nextToken()
 				}
 				break
+			} else {
+				// NATIVE_COMPILATION, SCHEMABINDING
+				stmt.Options = append(stmt.Options, strings.ToUpper(p.curToken.Literal))
 			}
 			if p.peekTokenIs(token.COMMA) {
 				p.nextToken()
@@ -6843,15 +7985,9 @@ func (p *Parser) parseCreateTypeStatement(createToken token.Token) ast.Statement
 				p.nextToken() // consume WITH
 				if p.peekTokenIs(token.LPAREN) {
 					p.nextToken() // consume (
-					// Skip to matching )
-					depth := 1
-					for depth > 0 && !p.curTokenIs(token.EOF) {
-						p.nextToken()
-						if p.curTokenIs(token.LPAREN) {
-							depth++
-						} else if p.curTokenIs(token.RPAREN) {
-							depth--
-						}
+					stmt.Options = p.parseOptionList()
+					if opt := stmt.Options.Get("MEMORY_OPTIMIZED"); opt != nil {
+						stmt.MemoryOptimized = optionIsOn(opt)
 					}
 				}
 			}
@@ -7743,68 +8879,874 @@ func (p *Parser) parseCreateApplicationRoleStatement(createToken token.Token) as
 			}
 		}
 	}
-
+
+	return stmt
+}
+
+// parseCreateServerRoleStatement parses CREATE SERVER ROLE name [AUTHORIZATION owner]
+func (p *Parser) parseCreateServerRoleStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateServerRoleStatement{Token: createToken}
+	p.nextToken() // move past ROLE
+
+	stmt.Name = p.curToken.Literal
+	p.nextToken()
+
+	if p.curTokenIs(token.AUTHORIZATION) {
+		p.nextToken() // move past AUTHORIZATION
+		stmt.Authorization = p.curToken.Literal
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseCreateCredentialStatement parses CREATE CREDENTIAL name WITH IDENTITY = '...', SECRET = '...'
+func (p *Parser) parseCreateCredentialStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateCredentialStatement{Token: createToken}
+	p.nextToken() // move past CREDENTIAL (which is the name in this context)
+
+	// Wait, the current token is CREDENTIAL as IDENT, and we need the name after it
+	// Actually, when we get here, curToken is "CREDENTIAL" identifier, so the name follows
+	stmt.Name = p.curToken.Literal
+	p.nextToken()
+
+	// Parse WITH clause
+	if p.curTokenIs(token.WITH) {
+		p.nextToken() // move past WITH
+		for !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.GO) {
+			optName := strings.ToUpper(p.curToken.Literal)
+			if p.peekTokenIs(token.EQ) {
+				p.nextToken() // move to =
+				p.nextToken() // move to value
+				switch optName {
+				case "IDENTITY":
+					if p.curTokenIs(token.STRING) || p.curTokenIs(token.NSTRING) {
+						stmt.Identity = p.curToken.Literal
+					}
+				case "SECRET":
+					if p.curTokenIs(token.STRING) || p.curTokenIs(token.NSTRING) {
+						stmt.Secret = p.curToken.Literal
+					}
+				}
+				p.nextToken()
+			} else {
+				p.nextToken()
+			}
+			if p.curTokenIs(token.COMMA) {
+				p.nextToken()
+			} else {
+				break
+			}
+		}
+	}
+
+	return stmt
+}
+
+// parseExternalObjectType reads the kind of object after EXTERNAL: DATA
+// SOURCE, FILE FORMAT or TABLE. The current token is EXTERNAL; on return
+// it is the last word of the kind. Other kinds, such as EXTERNAL LIBRARY,
+// give "".
+func (p *Parser) parseExternalObjectType() string {
+	switch strings.ToUpper(p.peekToken.Literal) {
+	case "TABLE":
+		p.nextToken()
+		return "TABLE"
+	case "DATA":
+		if p.peekPeekTokenIs(token.SOURCE) {
+			p.nextToken()
+			p.nextToken()
+			return "DATA SOURCE"
+		}
+	case "FILE":
+		if strings.EqualFold(p.peekPeekToken.Literal, "FORMAT") {
+			p.nextToken()
+			p.nextToken()
+			return "FILE FORMAT"
+		}
+	}
+	return ""
+}
+
+// parseCreateExternalStatement parses CREATE EXTERNAL DATA SOURCE, FILE
+// FORMAT and TABLE.
+func (p *Parser) parseCreateExternalStatement(createToken token.Token) ast.Statement {
+	switch p.parseExternalObjectType() {
+	case "DATA SOURCE":
+		stmt := &ast.CreateExternalDataSourceStatement{Token: createToken}
+		stmt.Name, stmt.Options = p.parseExternalObjectOptions()
+		return stmt
+	case "FILE FORMAT":
+		stmt := &ast.CreateExternalFileFormatStatement{Token: createToken}
+		stmt.Name, stmt.Options = p.parseExternalObjectOptions()
+		return stmt
+	case "TABLE":
+		return p.parseCreateExternalTableStatement(createToken)
+	}
+	return nil
+}
+
+// parseExternalObjectOptions parses name WITH (option, ...) after CREATE
+// EXTERNAL DATA SOURCE or FILE FORMAT.
+func (p *Parser) parseExternalObjectOptions() (*ast.Identifier, ast.OptionList) {
+	p.nextToken()
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.WITH) || !p.expectPeek(token.LPAREN) {
+		return name, nil
+	}
+	return name, p.parseOptionList()
+}
+
+// parseCreateExternalTableStatement parses CREATE EXTERNAL TABLE name
+// (columns) WITH (options) and CREATE EXTERNAL TABLE name [(names)] WITH
+// (options) AS SELECT ...
+func (p *Parser) parseCreateExternalTableStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateExternalTableStatement{Token: createToken}
+	p.nextToken()
+	stmt.Name = p.parseQualifiedIdentifier()
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken() // consume (
+		if p.peekPeekTokenIs(token.COMMA) || p.peekPeekTokenIs(token.RPAREN) {
+			// Only the names of the columns of AS SELECT
+			stmt.ColumnNames = p.parseColumnNameList()
+		} else {
+			for {
+				p.nextToken()
+				stmt.Columns = append(stmt.Columns, p.parseColumnDefinition())
+				if !p.peekTokenIs(token.COMMA) {
+					break
+				}
+				p.nextToken() // consume ,
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+	}
+
+	if p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		stmt.Options = p.parseOptionList()
+	}
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken() // consume AS
+		p.nextToken()
+		switch {
+		case p.curTokenIs(token.SELECT):
+			stmt.AsSelect = p.parseSelectStatement()
+		case p.curTokenIs(token.WITH):
+			stmt.AsSelect = p.parseWithStatement()
+		default:
+			p.syntaxError(p.curToken, "expected SELECT after CREATE EXTERNAL TABLE ... AS, got %s", p.curToken.Literal)
+			return nil
+		}
+	}
+	return stmt
+}
+
+// parseAlterExternalStatement parses ALTER EXTERNAL DATA SOURCE name SET
+// option = value, ...
+func (p *Parser) parseAlterExternalStatement(alterToken token.Token) ast.Statement {
+	if p.parseExternalObjectType() != "DATA SOURCE" {
+		return nil
+	}
+	stmt := &ast.AlterExternalDataSourceStatement{Token: alterToken}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.SET) {
+		return nil
+	}
+	p.nextToken() // move to the first option
+	stmt.Options = p.parseOptions()
+	return stmt
+}
+
+// -----------------------------------------------------------------------------
+// Synapse and Fabric warehouse statements
+// -----------------------------------------------------------------------------
+
+// parseCopyIntoStatement parses COPY INTO table [(column [DEFAULT value]
+// [field_number], ...)] FROM 'url', ... [WITH (options)].
+func (p *Parser) parseCopyIntoStatement() ast.Statement {
+	stmt := &ast.CopyIntoStatement{Token: p.curToken}
+	p.nextToken() // move to INTO
+	p.nextToken()
+	stmt.Table = p.parseQualifiedIdentifier()
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken() // consume (
+		for {
+			p.nextToken()
+			col := &ast.CopyColumn{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+			if p.peekTokenIs(token.DEFAULT_KW) {
+				p.nextToken() // consume DEFAULT
+				p.nextToken()
+				col.Default = p.parseExpression(LOWEST)
+			}
+			if p.peekTokenIs(token.INT) {
+				p.nextToken()
+				col.FieldNumber = p.parseExpression(LOWEST)
+			}
+			stmt.Columns = append(stmt.Columns, col)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // consume ,
+		}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.FROM) {
+		return nil
+	}
+	for {
+		p.nextToken()
+		stmt.From = append(stmt.From, p.parseExpression(LOWEST))
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
+	}
+	return stmt
+}
+
+// parseRenameStatement parses RENAME OBJECT [::] name [COLUMN column] TO
+// new_name and RENAME DATABASE [::] name TO new_name.
+func (p *Parser) parseRenameStatement() ast.Statement {
+	stmt := &ast.RenameStatement{Token: p.curToken}
+	p.nextToken()
+	switch {
+	case strings.EqualFold(p.curToken.Literal, "OBJECT"), p.curTokenIs(token.DATABASE):
+		stmt.ObjectType = strings.ToUpper(p.curToken.Literal)
+	default:
+		p.syntaxError(p.curToken, "expected OBJECT or DATABASE after RENAME, got %s", p.curToken.Literal)
+		return nil
+	}
+	if p.peekTokenIs(token.SCOPE) {
+		p.nextToken() // consume ::
+	}
+	p.nextToken()
+	stmt.Name = p.parseQualifiedIdentifier()
+
+	if stmt.ObjectType == "OBJECT" && p.peekTokenIs(token.COLUMN) {
+		p.nextToken() // consume COLUMN
+		p.nextToken()
+		stmt.Column = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.TO) {
+		return nil
+	}
+	p.nextToken()
+	stmt.NewName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return stmt
+}
+
+// parseCreateRemoteTableStatement parses CREATE REMOTE TABLE name AT
+// ('connection string') [WITH (options)] AS SELECT ...
+func (p *Parser) parseCreateRemoteTableStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateRemoteTableStatement{Token: createToken}
+	p.nextToken() // move to TABLE
+	p.nextToken()
+	stmt.Name = p.parseQualifiedIdentifier()
+
+	if !p.expectPeek(token.AT) || !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.ConnectionString = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
+	}
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if stmt.AsSelect = p.parseAsSelect("CREATE REMOTE TABLE"); stmt.AsSelect == nil {
+		return nil
+	}
+	return stmt
+}
+
+// -----------------------------------------------------------------------------
+// Row-level security
+// -----------------------------------------------------------------------------
+
+// parseCreateSecurityPolicyStatement parses CREATE SECURITY POLICY name
+// ADD FILTER|BLOCK PREDICATE ..., ... [WITH (options)] [NOT FOR
+// REPLICATION]. The current token is POLICY.
+func (p *Parser) parseCreateSecurityPolicyStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateSecurityPolicyStatement{Token: createToken}
+	p.nextToken()
+	stmt.Name = p.parseQualifiedIdentifier()
+	stmt.Predicates = p.parseSecurityPredicates()
+	stmt.Options, stmt.NotForReplication = p.parseSecurityPolicyOptions()
+	stmt.State = optionBool(stmt.Options.Get("STATE"))
+	stmt.SchemaBinding = optionBool(stmt.Options.Get("SCHEMABINDING"))
+	return stmt
+}
+
+// parseAlterSecurityPolicyStatement parses ALTER SECURITY POLICY name
+// followed by predicates to add, alter or drop and the options. The
+// current token is POLICY.
+func (p *Parser) parseAlterSecurityPolicyStatement(alterToken token.Token) ast.Statement {
+	stmt := &ast.AlterSecurityPolicyStatement{Token: alterToken}
+	p.nextToken()
+	stmt.Name = p.parseQualifiedIdentifier()
+	stmt.Predicates = p.parseSecurityPredicates()
+	stmt.Options, stmt.NotForReplication = p.parseSecurityPolicyOptions()
+	stmt.State = optionBool(stmt.Options.Get("STATE"))
+	return stmt
+}
+
+// parseSecurityPredicates parses the comma-separated predicates that
+// follow the name of a security policy.
+func (p *Parser) parseSecurityPredicates() []*ast.SecurityPredicate {
+	var preds []*ast.SecurityPredicate
+	for isSecurityPredicateStart(p.peekToken, p.peekPeekToken) {
+		p.nextToken() // move to ADD, ALTER or DROP
+		pred := p.parseSecurityPredicate()
+		if pred == nil {
+			break
+		}
+		preds = append(preds, pred)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+	return preds
+}
+
+// isSecurityPredicateStart reports whether tok and next start ADD, ALTER or
+// DROP FILTER|BLOCK PREDICATE.
+func isSecurityPredicateStart(tok, next token.Token) bool {
+	switch tok.Type {
+	case token.ADD, token.ALTER, token.DROP:
+		return strings.EqualFold(next.Literal, "FILTER") || strings.EqualFold(next.Literal, "BLOCK")
+	}
+	return false
+}
+
+// parseSecurityPredicate parses ADD|ALTER FILTER|BLOCK PREDICATE
+// function(args) ON table [AFTER|BEFORE operation] or DROP FILTER|BLOCK
+// PREDICATE ON table.
+func (p *Parser) parseSecurityPredicate() *ast.SecurityPredicate {
+	pred := &ast.SecurityPredicate{Token: p.curToken, Action: strings.ToUpper(p.curToken.Literal)}
+	p.nextToken()
+	if strings.EqualFold(p.curToken.Literal, "BLOCK") {
+		pred.Type = ast.BlockPredicate
+	}
+	p.nextToken()
+	if !strings.EqualFold(p.curToken.Literal, "PREDICATE") {
+		p.syntaxError(p.curToken, "expected PREDICATE, got %s", p.curToken.Literal)
+		return nil
+	}
+
+	if pred.Action != "DROP" {
+		p.nextToken()
+		pred.Function = p.parseQualifiedIdentifier()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		pred.Arguments = p.parseExpressionList(token.RPAREN)
+	}
+	if !p.expectPeek(token.ON) {
+		return nil
+	}
+	p.nextToken()
+	pred.Table = p.parseQualifiedIdentifier()
+
+	// Block predicates may be limited to one operation
+	if p.peekTokenIs(token.AFTER) || strings.EqualFold(p.peekToken.Literal, "BEFORE") {
+		p.nextToken()
+		when := strings.ToUpper(p.curToken.Literal)
+		p.nextToken() // move to INSERT, UPDATE or DELETE
+		pred.Operation = when + " " + strings.ToUpper(p.curToken.Literal)
+	}
+	return pred
+}
+
+// parseSecurityPolicyOptions parses [WITH (options)] [NOT FOR REPLICATION]
+// at the end of CREATE or ALTER SECURITY POLICY.
+func (p *Parser) parseSecurityPolicyOptions() (ast.OptionList, bool) {
+	var options ast.OptionList
+	if p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		options = p.parseOptionList()
+	}
+	if p.peekTokenIs(token.NOT) && p.peekPeekTokenIs(token.FOR) {
+		p.nextToken() // consume NOT
+		p.nextToken() // consume FOR
+		return options, p.expectPeek(token.REPLICATION)
+	}
+	return options, false
+}
+
+// optionBool reads an ON or OFF option, or returns nil when it is not
+// given.
+func optionBool(opt *ast.Option) *bool {
+	if opt == nil {
+		return nil
+	}
+	on := optionIsOn(opt)
+	return &on
+}
+
+// -----------------------------------------------------------------------------
+// Extended Events and auditing
+// -----------------------------------------------------------------------------
+
+// parseCreateEventSessionStatement parses CREATE EVENT SESSION name ON
+// SERVER|DATABASE ADD EVENT ..., ADD TARGET ... [WITH (options)]. The
+// current token is SESSION.
+func (p *Parser) parseCreateEventSessionStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateEventSessionStatement{Token: createToken}
+	var ok bool
+	if stmt.Name, stmt.Scope, ok = p.parseEventSessionName(); !ok {
+		return nil
+	}
+	if !p.parseEventSessionChanges(&stmt.Events, &stmt.Targets, nil, nil) {
+		return nil
+	}
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
+		stmt.Settings = eventSessionOptions(stmt.Options)
+	}
+	return stmt
+}
+
+// parseAlterEventSessionStatement parses ALTER EVENT SESSION name ON
+// SERVER|DATABASE followed by events and targets to add or drop and WITH
+// (options), or by STATE = START|STOP. The current token is SESSION.
+func (p *Parser) parseAlterEventSessionStatement(alterToken token.Token) ast.Statement {
+	stmt := &ast.AlterEventSessionStatement{Token: alterToken}
+	var ok bool
+	if stmt.Name, stmt.Scope, ok = p.parseEventSessionName(); !ok {
+		return nil
+	}
+	if strings.EqualFold(p.peekToken.Literal, "STATE") {
+		p.nextToken() // consume STATE
+		if !p.expectPeek(token.EQ) {
+			return nil
+		}
+		p.nextToken()
+		stmt.State = strings.ToUpper(p.curToken.Literal)
+		return stmt
+	}
+	if !p.parseEventSessionChanges(&stmt.AddEvents, &stmt.AddTargets, &stmt.DropEvents, &stmt.DropTargets) {
+		return nil
+	}
+	if p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Options = p.parseOptionList()
+		stmt.Settings = eventSessionOptions(stmt.Options)
+	}
+	return stmt
+}
+
+// parseEventSessionName parses name ON SERVER|DATABASE after EVENT
+// SESSION.
+func (p *Parser) parseEventSessionName() (*ast.Identifier, string, bool) {
+	p.nextToken()
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.ON) {
+		return name, "", false
+	}
+	p.nextToken()
+	if !p.curTokenIs(token.SERVER) && !p.curTokenIs(token.DATABASE) {
+		p.syntaxError(p.curToken, "expected SERVER or DATABASE after EVENT SESSION %s ON, got %s", name.Value, p.curToken.Literal)
+		return name, "", false
+	}
+	return name, strings.ToUpper(p.curToken.Literal), true
+}
+
+// parseEventSessionChanges parses the ADD EVENT and ADD TARGET entries of
+// an event session and, when dropEvents is not nil, DROP EVENT and DROP
+// TARGET.
+func (p *Parser) parseEventSessionChanges(events *[]*ast.EventSessionEvent, targets *[]*ast.EventSessionTarget, dropEvents, dropTargets *[]*ast.QualifiedIdentifier) bool {
+	for p.peekTokenIs(token.ADD) || (dropEvents != nil && p.peekTokenIs(token.DROP)) {
+		kind := strings.ToUpper(p.peekPeekToken.Literal)
+		if kind != "EVENT" && kind != "TARGET" {
+			return true
+		}
+		drop := p.peekTokenIs(token.DROP)
+		p.nextToken() // move to ADD or DROP
+		p.nextToken() // move to EVENT or TARGET
+		tok := p.curToken
+		p.nextToken()
+		name := p.parseQualifiedIdentifier()
+
+		switch {
+		case drop && kind == "EVENT":
+			*dropEvents = append(*dropEvents, name)
+		case drop:
+			*dropTargets = append(*dropTargets, name)
+		case kind == "EVENT":
+			event := p.parseEventSessionEvent(tok, name)
+			if event == nil {
+				return false
+			}
+			*events = append(*events, event)
+		default:
+			target := &ast.EventSessionTarget{Token: tok, Name: name}
+			if p.peekTokenIs(token.LPAREN) {
+				p.nextToken() // consume (
+				if !p.expectPeek(token.SET) {
+					return false
+				}
+				p.nextToken()
+				target.Set = p.parseEventFields()
+				if !p.expectPeek(token.RPAREN) {
+					return false
+				}
+			}
+			*targets = append(*targets, target)
+		}
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken() // consume ,
+		}
+	}
+	return true
+}
+
+// parseEventSessionEvent parses the optional ([SET ...] [ACTION (...)]
+// [WHERE ...]) after the name of an event.
+func (p *Parser) parseEventSessionEvent(tok token.Token, name *ast.QualifiedIdentifier) *ast.EventSessionEvent {
+	event := &ast.EventSessionEvent{Token: tok, Name: name}
+	if !p.peekTokenIs(token.LPAREN) {
+		return event
+	}
+	p.nextToken() // consume (
+	for !p.peekTokenIs(token.RPAREN) {
+		switch {
+		case p.peekTokenIs(token.SET):
+			p.nextToken() // consume SET
+			p.nextToken()
+			event.Set = p.parseEventFields()
+		case p.peekTokenIs(token.ACTION):
+			p.nextToken() // consume ACTION
+			if !p.expectPeek(token.LPAREN) {
+				return nil
+			}
+			for {
+				p.nextToken()
+				event.Actions = append(event.Actions, p.parseQualifiedIdentifier())
+				if !p.peekTokenIs(token.COMMA) {
+					break
+				}
+				p.nextToken() // consume ,
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		case p.peekTokenIs(token.WHERE):
+			p.nextToken() // consume WHERE
+			p.nextToken()
+			event.Predicate = p.parseExpression(LOWEST)
+		default:
+			p.nextToken()
+			p.syntaxError(p.curToken, "expected SET, ACTION or WHERE in event %s, got %s", name, p.curToken.Literal)
+			return nil
+		}
+	}
+	p.nextToken() // consume )
+	return event
+}
+
+// parseEventFields parses the field = value list after SET in an event or
+// target. Values are expressions, so that SET a = 1 ACTION (...) ends at
+// ACTION.
+func (p *Parser) parseEventFields() ast.OptionList {
+	var fields ast.OptionList
+	for {
+		field := &ast.Option{Token: p.curToken, Name: p.curToken.Literal, Equals: true}
+		if !p.expectPeek(token.EQ) {
+			return fields
+		}
+		p.nextToken()
+		field.Value = p.parseExpression(LOWEST)
+		fields = append(fields, field)
+		if !p.peekTokenIs(token.COMMA) {
+			return fields
+		}
+		p.nextToken() // consume ,
+		p.nextToken()
+	}
+}
+
+// eventSessionOptions reads the WITH options of an event session.
+func eventSessionOptions(options ast.OptionList) *ast.EventSessionOptions {
+	settings := &ast.EventSessionOptions{}
+	for _, o := range options {
+		value := ""
+		if o.Value != nil {
+			value = strings.ToUpper(o.Value.String())
+		}
+		switch strings.ToUpper(o.Name) {
+		case "MAX_MEMORY":
+			settings.MaxMemory = value
+		case "EVENT_RETENTION_MODE":
+			settings.EventRetentionMode = value
+		case "MAX_DISPATCH_LATENCY":
+			settings.MaxDispatchLatency = value
+		case "MAX_EVENT_SIZE":
+			settings.MaxEventSize = value
+		case "MEMORY_PARTITION_MODE":
+			settings.MemoryPartitionMode = value
+		case "TRACK_CAUSALITY":
+			settings.TrackCausality = optionBool(o)
+		case "STARTUP_STATE":
+			settings.StartupState = optionBool(o)
+		}
+	}
+	return settings
+}
+
+// parseDropAuditStatement parses DROP SERVER AUDIT name and DROP
+// SERVER|DATABASE AUDIT SPECIFICATION name. The current token is SERVER or
+// DATABASE.
+func (p *Parser) parseDropAuditStatement(dropToken token.Token) ast.Statement {
+	objectType := strings.ToUpper(p.curToken.Literal) + " AUDIT"
+	p.nextToken() // move to AUDIT
+	if strings.EqualFold(p.peekToken.Literal, "SPECIFICATION") {
+		p.nextToken() // move to SPECIFICATION
+		objectType += " SPECIFICATION"
+	}
+	stmt := p.parseDropObjectStatement(dropToken).(*ast.DropObjectStatement)
+	stmt.ObjectType = objectType
+	return stmt
+}
+
+// parseCreateServerAuditStatement parses CREATE SERVER AUDIT name TO
+// target [WITH (options)] [WHERE predicate]. The current token is AUDIT.
+func (p *Parser) parseCreateServerAuditStatement(createToken token.Token) ast.Statement {
+	stmt := &ast.CreateServerAuditStatement{Token: createToken}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.TO) {
+		return nil
+	}
+	stmt.Target = p.parseAuditTarget()
+	stmt.Options, stmt.Predicate = p.parseServerAuditOptions()
+	return stmt
+}
+
+// parseAlterServerAuditStatement parses ALTER SERVER AUDIT name [TO
+// target] [WITH (options)] [WHERE predicate], ALTER SERVER AUDIT name
+// REMOVE WHERE and ALTER SERVER AUDIT name MODIFY NAME = new_name. The
+// current token is AUDIT.
+func (p *Parser) parseAlterServerAuditStatement(alterToken token.Token) ast.Statement {
+	stmt := &ast.AlterServerAuditStatement{Token: alterToken}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	switch {
+	case strings.EqualFold(p.peekToken.Literal, "REMOVE") && p.peekPeekTokenIs(token.WHERE):
+		p.nextToken() // consume REMOVE
+		p.nextToken() // consume WHERE
+		stmt.RemoveWhere = true
+		return stmt
+	case strings.EqualFold(p.peekToken.Literal, "MODIFY"):
+		p.nextToken() // consume MODIFY
+		p.nextToken()
+		if !strings.EqualFold(p.curToken.Literal, "NAME") {
+			p.syntaxError(p.curToken, "expected NAME after MODIFY, got %s", p.curToken.Literal)
+			return nil
+		}
+		if !p.expectPeek(token.EQ) {
+			return nil
+		}
+		p.nextToken()
+		stmt.NewName = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return stmt
+	}
+	if p.peekTokenIs(token.TO) {
+		p.nextToken() // consume TO
+		stmt.Target = p.parseAuditTarget()
+	}
+	stmt.Options, stmt.Predicate = p.parseServerAuditOptions()
 	return stmt
 }
 
-// parseCreateServerRoleStatement parses CREATE SERVER ROLE name [AUTHORIZATION owner]
-func (p *Parser) parseCreateServerRoleStatement(createToken token.Token) ast.Statement {
-	stmt := &ast.CreateServerRoleStatement{Token: createToken}
-	p.nextToken() // move past ROLE
-
-	stmt.Name = p.curToken.Literal
+// parseAuditTarget parses FILE (options), APPLICATION_LOG, SECURITY_LOG,
+// URL or EXTERNAL_MONITOR after TO.
+func (p *Parser) parseAuditTarget() *ast.AuditTarget {
 	p.nextToken()
+	target := &ast.AuditTarget{Kind: strings.ToUpper(p.curToken.Literal)}
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken() // consume (
+		target.Options = p.parseOptionList()
+	}
+	return target
+}
 
-	if p.curTokenIs(token.AUTHORIZATION) {
-		p.nextToken() // move past AUTHORIZATION
-		stmt.Authorization = p.curToken.Literal
+// parseServerAuditOptions parses [WITH (options)] [WHERE predicate] at the
+// end of CREATE or ALTER SERVER AUDIT.
+func (p *Parser) parseServerAuditOptions() (ast.OptionList, ast.Expression) {
+	var options ast.OptionList
+	var predicate ast.Expression
+	if p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		options = p.parseOptionList()
+	}
+	if p.peekTokenIs(token.WHERE) {
+		p.nextToken() // consume WHERE
 		p.nextToken()
+		predicate = p.parseExpression(LOWEST)
 	}
+	return options, predicate
+}
 
+// parseCreateAuditSpecificationStatement parses CREATE SERVER|DATABASE
+// AUDIT SPECIFICATION name FOR SERVER AUDIT audit ADD (...), ... [WITH
+// (STATE = ON|OFF)]. The current token is SPECIFICATION.
+func (p *Parser) parseCreateAuditSpecificationStatement(createToken token.Token, scope string) ast.Statement {
+	stmt := &ast.CreateAuditSpecificationStatement{Token: createToken, Scope: scope}
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	var ok bool
+	if stmt.Audit, stmt.Actions, stmt.Options, ok = p.parseAuditSpecificationBody(); !ok {
+		return nil
+	}
+	stmt.State = optionBool(stmt.Options.Get("STATE"))
 	return stmt
 }
 
-// parseCreateCredentialStatement parses CREATE CREDENTIAL name WITH IDENTITY = '...', SECRET = '...'
-func (p *Parser) parseCreateCredentialStatement(createToken token.Token) ast.Statement {
-	stmt := &ast.CreateCredentialStatement{Token: createToken}
-	p.nextToken() // move past CREDENTIAL (which is the name in this context)
-
-	// Wait, the current token is CREDENTIAL as IDENT, and we need the name after it
-	// Actually, when we get here, curToken is "CREDENTIAL" identifier, so the name follows
-	stmt.Name = p.curToken.Literal
+// parseAlterAuditSpecificationStatement parses ALTER SERVER|DATABASE
+// AUDIT SPECIFICATION name [FOR SERVER AUDIT audit] ADD|DROP (...), ...
+// [WITH (STATE = ON|OFF)]. The current token is SPECIFICATION.
+func (p *Parser) parseAlterAuditSpecificationStatement(alterToken token.Token, scope string) ast.Statement {
+	stmt := &ast.AlterAuditSpecificationStatement{Token: alterToken, Scope: scope}
 	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	var ok bool
+	if stmt.Audit, stmt.Actions, stmt.Options, ok = p.parseAuditSpecificationBody(); !ok {
+		return nil
+	}
+	stmt.State = optionBool(stmt.Options.Get("STATE"))
+	return stmt
+}
 
-	// Parse WITH clause
-	if p.curTokenIs(token.WITH) {
-		p.nextToken() // move past WITH
-		for !p.curTokenIs(token.EOF) && !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.GO) {
-			optName := strings.ToUpper(p.curToken.Literal)
-			if p.peekTokenIs(token.EQ) {
-				p.nextToken() // move to =
-				p.nextToken() // move to value
-				switch optName {
-				case "IDENTITY":
-					if p.curTokenIs(token.STRING) || p.curTokenIs(token.NSTRING) {
-						stmt.Identity = p.curToken.Literal
-					}
-				case "SECRET":
-					if p.curTokenIs(token.STRING) || p.curTokenIs(token.NSTRING) {
-						stmt.Secret = p.curToken.Literal
-					}
-				}
-				p.nextToken()
-			} else {
-				p.nextToken()
-			}
-			if p.curTokenIs(token.COMMA) {
-				p.nextToken()
-			} else {
-				break
-			}
+// parseAuditSpecificationBody parses the [FOR SERVER AUDIT audit], the
+// ADD and DROP entries and the WITH options of an audit specification.
+func (p *Parser) parseAuditSpecificationBody() (*ast.Identifier, []*ast.AuditSpecificationAction, ast.OptionList, bool) {
+	var audit *ast.Identifier
+	var actions []*ast.AuditSpecificationAction
+	var options ast.OptionList
+	if p.peekTokenIs(token.FOR) {
+		p.nextToken() // consume FOR
+		if !p.expectPeek(token.SERVER) {
+			return nil, nil, nil, false
+		}
+		p.nextToken()
+		if !strings.EqualFold(p.curToken.Literal, "AUDIT") {
+			p.syntaxError(p.curToken, "expected AUDIT after FOR SERVER, got %s", p.curToken.Literal)
+			return nil, nil, nil, false
+		}
+		p.nextToken()
+		audit = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	for (p.peekTokenIs(token.ADD) || p.peekTokenIs(token.DROP)) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // move to ADD or DROP
+		action := p.parseAuditSpecificationAction()
+		if action == nil {
+			return nil, nil, nil, false
+		}
+		actions = append(actions, action)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken() // consume ,
 		}
 	}
+	if p.peekTokenIs(token.WITH) && p.peekPeekTokenIs(token.LPAREN) {
+		p.nextToken() // consume WITH
+		p.nextToken() // consume (
+		options = p.parseOptionList()
+	}
+	return audit, actions, options, true
+}
 
-	return stmt
+// parseAuditSpecificationAction parses ADD|DROP (group) or ADD|DROP
+// (action, ... ON [class::]securable BY principal, ...).
+func (p *Parser) parseAuditSpecificationAction() *ast.AuditSpecificationAction {
+	action := &ast.AuditSpecificationAction{Token: p.curToken, Action: strings.ToUpper(p.curToken.Literal)}
+	p.nextToken() // consume (
+	var names []string
+	for {
+		p.nextToken()
+		names = append(names, p.curToken.Literal)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+
+	if !p.peekTokenIs(token.ON) {
+		if len(names) != 1 {
+			p.syntaxError(p.peekToken, "expected ON after audit actions, got %s", p.peekToken.Literal)
+			return nil
+		}
+		action.Group = names[0]
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return action
+	}
+
+	for _, name := range names {
+		action.Actions = append(action.Actions, strings.ToUpper(name))
+	}
+	p.nextToken() // consume ON
+	p.nextToken()
+	if p.peekTokenIs(token.SCOPE) {
+		action.Class = strings.ToUpper(p.curToken.Literal)
+		p.nextToken() // consume ::
+		p.nextToken()
+	}
+	action.Securable = p.parseQualifiedIdentifier()
+	if !p.expectPeek(token.BY) {
+		return nil
+	}
+	for {
+		p.nextToken()
+		action.Principals = append(action.Principals, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() // consume ,
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return action
 }
 
 // parseCreateDatabaseOrScopedCredential handles CREATE DATABASE and CREATE DATABASE SCOPED CREDENTIAL
@@ -8974,6 +10916,179 @@ func (p *Parser) parseFreetextTableExpression() ast.Expression {
 	return expr
 }
 
+// parseMatchExpression parses MATCH(pattern [AND pattern ...]), the graph
+// predicate of a WHERE clause.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expr := &ast.MatchExpression{Token: p.curToken}
+	p.nextToken() // move to (
+
+	for {
+		p.nextToken() // move to the pattern
+		pattern := p.parseGraphPattern()
+		if pattern == nil {
+			return nil
+		}
+		expr.Patterns = append(expr.Patterns, pattern)
+		if !p.peekTokenIs(token.AND) {
+			break
+		}
+		p.nextToken() // consume AND
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return expr
+}
+
+// parseGraphPattern parses a path such as a-(e)->b<-(f)-c, or a
+// SHORTEST_PATH. On return the current token is the last token of the
+// pattern.
+func (p *Parser) parseGraphPattern() ast.GraphPattern {
+	if strings.EqualFold(p.curToken.Literal, "SHORTEST_PATH") && p.peekTokenIs(token.LPAREN) {
+		return p.parseShortestPath()
+	}
+
+	path := &ast.GraphPath{Token: p.curToken}
+	node := p.parseGraphNode()
+	if node == nil {
+		return nil
+	}
+	path.Elements = append(path.Elements, node)
+	for p.peekTokenIs(token.MINUS) || p.peekTokenIs(token.LT) {
+		p.nextToken()
+		edge := p.parseGraphEdge()
+		if edge == nil {
+			return nil
+		}
+		p.nextToken()
+		node := p.parseGraphNode()
+		if node == nil {
+			return nil
+		}
+		path.Elements = append(path.Elements, edge, node)
+	}
+	return path
+}
+
+// parseGraphNode parses a node alias or LAST_NODE(alias).
+func (p *Parser) parseGraphNode() *ast.GraphNode {
+	node := &ast.GraphNode{Token: p.curToken}
+	if strings.EqualFold(p.curToken.Literal, "LAST_NODE") && p.peekTokenIs(token.LPAREN) {
+		node.LastNode = true
+		p.nextToken() // move to (
+		p.nextToken() // move to the alias
+	}
+	if !p.curTokenIs(token.IDENT) && !slices.Contains(keywordIdentifiers, p.curToken.Type) {
+		p.syntaxError(p.curToken, "expected a node name, got %s", p.curToken.Type)
+		return nil
+	}
+	node.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if node.LastNode && !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return node
+}
+
+// parseGraphEdge parses -(edge)-> or <-(edge)-. The current token is the
+// first - or <; on return it is the final > or -.
+func (p *Parser) parseGraphEdge() *ast.GraphEdge {
+	edge := &ast.GraphEdge{Token: p.curToken, Direction: ast.GraphDirectionRight}
+	if p.curTokenIs(token.LT) {
+		edge.Direction = ast.GraphDirectionLeft
+		if !p.expectPeek(token.MINUS) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	if !p.curTokenIs(token.IDENT) && !slices.Contains(keywordIdentifiers, p.curToken.Type) {
+		p.syntaxError(p.curToken, "expected an edge name, got %s", p.curToken.Type)
+		return nil
+	}
+	edge.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.MINUS) {
+		return nil
+	}
+	if edge.Direction == ast.GraphDirectionRight && !p.expectPeek(token.GT) {
+		return nil
+	}
+	return edge
+}
+
+// parseShortestPath parses SHORTEST_PATH(anchor(group)+),
+// SHORTEST_PATH(anchor(group){min,max}) or SHORTEST_PATH((group)+anchor).
+func (p *Parser) parseShortestPath() ast.GraphPattern {
+	sp := &ast.ShortestPath{Token: p.curToken}
+	p.nextToken() // move to (
+	p.nextToken()
+
+	if !p.curTokenIs(token.LPAREN) {
+		sp.Anchor = p.parseGraphNode()
+		if sp.Anchor == nil || !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+	}
+	if !p.parseShortestPathGroup(sp) {
+		return nil
+	}
+	if sp.Anchor == nil {
+		p.nextToken()
+		sp.Anchor = p.parseGraphNode()
+		if sp.Anchor == nil {
+			return nil
+		}
+		sp.AnchorAtEnd = true
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return sp
+}
+
+// parseShortestPathGroup parses the repeated group of a SHORTEST_PATH and
+// its quantifier. The current token is the opening parenthesis; on return
+// it is the + or the closing brace.
+func (p *Parser) parseShortestPathGroup(sp *ast.ShortestPath) bool {
+	for !p.peekTokenIs(token.RPAREN) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		if p.curTokenIs(token.MINUS) || p.curTokenIs(token.LT) {
+			edge := p.parseGraphEdge()
+			if edge == nil {
+				return false
+			}
+			sp.Group = append(sp.Group, edge)
+		} else {
+			node := p.parseGraphNode()
+			if node == nil {
+				return false
+			}
+			sp.Group = append(sp.Group, node)
+		}
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return false
+	}
+
+	if p.peekTokenIs(token.PLUS) {
+		p.nextToken()
+		sp.Unbounded = true
+		return true
+	}
+	if !p.expectPeek(token.LBRACE) || !p.expectPeek(token.INT) {
+		return false
+	}
+	sp.MinHops, _ = strconv.Atoi(p.curToken.Literal)
+	if !p.expectPeek(token.COMMA) || !p.expectPeek(token.INT) {
+		return false
+	}
+	sp.MaxHops, _ = strconv.Atoi(p.curToken.Literal)
+	return p.expectPeek(token.RBRACE)
+}
+
 // parseCreateFulltextStatement handles CREATE FULLTEXT CATALOG/INDEX
 func (p *Parser) parseCreateFulltextStatement(createToken token.Token) ast.Statement {
 	p.nextToken() // move past FULLTEXT
@@ -9091,16 +11206,7 @@ func (p *Parser) parseCreateFulltextIndexStatement(createToken token.Token) ast.
 	if p.curTokenIs(token.WITH) {
 		p.nextToken() // move past WITH
 		if p.curTokenIs(token.LPAREN) {
-			// Skip to matching )
-			depth := 1
-			for depth > 0 && !p.curTokenIs(token.EOF) {
-				p.nextToken()
-				if p.curTokenIs(token.LPAREN) {
-					depth++
-				} else if p.curTokenIs(token.RPAREN) {
-					depth--
-				}
-			}
+			stmt.WithOptions = p.parseOptionList()
 			p.nextToken() // move past final )
 		}
 	}
@@ -9770,18 +11876,19 @@ func (p *Parser) parseAlterQueueStatement(alterToken token.Token) ast.Statement
 
 	stmt.Name = p.parseQualifiedIdentifier()
 
-	// Parse WITH options - skip until end of statement
+	// Parse WITH options: STATUS = ON, ACTIVATION (STATUS = ON, ...), ...
 	if p.peekTokenIs(token.WITH) {
 		p.nextToken() // move to WITH
-		// Skip everything until semicolon, GO, or EOF
-		depth := 0
-		for !p.peekTokenIs(token.SEMICOLON) && !p.peekTokenIs(token.GO) && !p.peekTokenIs(token.EOF) {
-			p.nextToken()
-			if p.curTokenIs(token.LPAREN) {
-				depth++
-			} else if p.curTokenIs(token.RPAREN) {
-				depth--
+		p.nextToken() // move past WITH
+		for _, opt := range p.parseOptions() {
+			value := ""
+			if opt.Value != nil {
+				value = opt.Value.String()
+			}
+			if opt.List != nil {
+				value = strings.TrimSpace(value + " " + opt.List.String())
 			}
+			stmt.Options[strings.ToUpper(opt.Name)] = value
 		}
 	}
 
@@ -10150,29 +12257,7 @@ func (p *Parser) parseCreateProcedureStatement() ast.Statement {
 		stmt.Parameters = p.parseParameterDefs()
 	}
 
-	// Handle WITH clause options (RECOMPILE, ENCRYPTION, EXECUTE AS)
-	for p.peekTokenIs(token.WITH) {
-		p.nextToken() // consume WITH
-		p.nextToken() // move to option
-		for {
-			optionName := strings.ToUpper(p.curToken.Literal)
-			if optionName == "EXECUTE" {
-				// EXECUTE AS CALLER|OWNER|SELF|'user_name'
-				p.nextToken() // move past EXECUTE
-				if p.curTokenIs(token.AS) {
-					p.nextToken() // move past AS
-					p.nextToken() // move past CALLER/OWNER/SELF/user
-				}
-			} else if optionName == "RECOMPILE" || optionName == "ENCRYPTION" || optionName == "SCHEMABINDING" {
-				// Single keyword options
-			}
-			if !p.peekTokenIs(token.COMMA) {
-				break
-			}
-			p.nextToken() // consume comma
-			p.nextToken() // move to next option
-		}
-	}
+	stmt.Options = p.parseModuleOptions()
 
 	// Skip to AS (the one that starts the body)
 	for !p.curTokenIs(token.AS) && !p.curTokenIs(token.EOF) {
@@ -10206,6 +12291,39 @@ func (p *Parser) parseCreateProcedureStatement() ast.Statement {
 	return stmt
 }
 
+// parseModuleOptions parses the WITH options of a procedure or function,
+// such as RECOMPILE, NATIVE_COMPILATION or EXECUTE AS OWNER, if there are
+// any.
+func (p *Parser) parseModuleOptions() []string {
+	var options []string
+	for p.peekTokenIs(token.WITH) {
+		p.nextToken() // consume WITH
+		p.nextToken() // move to option
+		for {
+			option := strings.ToUpper(p.curToken.Literal)
+			if option == "EXECUTE" && p.peekTokenIs(token.AS) {
+				// EXECUTE AS CALLER|OWNER|SELF|'user_name'
+				p.nextToken() // move to AS
+				p.nextToken() // move to CALLER/OWNER/SELF/user
+				option = "EXECUTE AS " + p.curToken.Literal
+			} else {
+				// RETURNS NULL ON NULL INPUT, INLINE = OFF
+				for !p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.AS) && !p.peekTokenIs(token.EOF) {
+					p.nextToken()
+					option += " " + strings.ToUpper(p.curToken.Literal)
+				}
+			}
+			options = append(options, option)
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken() // consume comma
+			p.nextToken() // move to next option
+		}
+	}
+	return options
+}
+
 func (p *Parser) parseParameterDefs() []*ast.ParameterDef {
 	params := []*ast.ParameterDef{}
 
@@ -1,11 +1,13 @@
 package parser
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/ha1tch/tsqlparser/ast"
 	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/token"
 )
 
 func TestSelectStatement(t *testing.T) {
@@ -434,6 +436,29 @@ func TestBetweenWithAndCondition(t *testing.T) {
 	}
 }
 
+func TestNotPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SELECT * FROM t WHERE NOT a = 1", "(NOT (a = 1))"},
+		{"SELECT * FROM t WHERE NOT a = 1 AND b = 2", "((NOT (a = 1)) AND (b = 2))"},
+		{"SELECT * FROM t WHERE a = 1 OR NOT b > 2", "((a = 1) OR (NOT (b > 2)))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.SelectStatement)
+		if got := stmt.Where.String(); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestTryCatchStatement(t *testing.T) {
 	input := `
 BEGIN TRY
@@ -796,6 +821,30 @@ func TestCreateTableWithIdentity(t *testing.T) {
 	}
 }
 
+func TestCreateTableNamedDefault(t *testing.T) {
+	input := `CREATE TABLE Orders (Status INT NOT NULL CONSTRAINT DF_Orders_Status DEFAULT 0, Created DATETIME DEFAULT GETDATE())`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.CreateTableStatement)
+
+	if stmt.Columns[0].DefaultName != "DF_Orders_Status" {
+		t.Errorf("expected default name 'DF_Orders_Status', got %q", stmt.Columns[0].DefaultName)
+	}
+	if stmt.Columns[0].Default == nil {
+		t.Error("expected default on Status")
+	}
+	if stmt.Columns[1].DefaultName != "" {
+		t.Errorf("expected unnamed default on Created, got %q", stmt.Columns[1].DefaultName)
+	}
+	if got := stmt.Columns[0].String(); got != "Status INT NOT NULL CONSTRAINT DF_Orders_Status DEFAULT 0" {
+		t.Errorf("unexpected String(): %s", got)
+	}
+}
+
 func TestCreateTableWithConstraints(t *testing.T) {
 	input := `
 CREATE TABLE OrderItems (
@@ -955,6 +1004,33 @@ func TestAlterTableDropColumn(t *testing.T) {
 	}
 }
 
+func TestAlterTableAlterColumnNullability(t *testing.T) {
+	input := `ALTER TABLE Users ALTER COLUMN Email NVARCHAR(320) COLLATE Latin1_General_CI_AS NOT NULL`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+	}
+	action := program.Statements[0].(*ast.AlterTableStatement).Actions[0]
+
+	if action.Type != ast.AlterAlterColumn {
+		t.Fatalf("expected AlterAlterColumn, got %v", action.Type)
+	}
+	if action.Nullable == nil || *action.Nullable {
+		t.Error("expected NOT NULL")
+	}
+	if action.Collation != "Latin1_General_CI_AS" {
+		t.Errorf("expected collation 'Latin1_General_CI_AS', got %q", action.Collation)
+	}
+	if got := action.String(); got != "ALTER COLUMN Email NVARCHAR(320) COLLATE Latin1_General_CI_AS NOT NULL" {
+		t.Errorf("unexpected String(): %s", got)
+	}
+}
+
 func TestAlterTableAddConstraint(t *testing.T) {
 	input := `ALTER TABLE Orders ADD CONSTRAINT FK_Customer FOREIGN KEY (CustomerID) REFERENCES Customers(ID)`
 
@@ -2720,6 +2796,36 @@ func TestCompoundAssignmentOperators(t *testing.T) {
 	}
 }
 
+func TestSetCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+	}{
+		{`SET @i = 1`, "="},
+		{`SET @i += 1`, "+="},
+		{`SET @s -= @x`, "-="},
+		{`SET @flags |= 4`, "|="},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.SetStatement)
+		if !ok {
+			t.Fatalf("expected SetStatement, got %T", program.Statements[0])
+		}
+		if stmt.Operator != tt.operator {
+			t.Errorf("%s: expected operator %q, got %q", tt.input, tt.operator, stmt.Operator)
+		}
+		if stmt.String() != tt.input {
+			t.Errorf("expected %q, got %q", tt.input, stmt.String())
+		}
+	}
+}
+
 func TestIndexHint(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -5283,6 +5389,91 @@ func TestOpenrowsetBulk(t *testing.T) {
 			t.Errorf("unexpected error for %q: %s", input, p.Errors()[0])
 		}
 	}
+
+	input := `SELECT r.Id FROM OPENROWSET(BULK 'sales/*.parquet', DATA_SOURCE = 'Lake', FORMAT = 'PARQUET')
+WITH (Id INT, Region VARCHAR(50) COLLATE Latin1_General_100_BIN2_UTF8 '$.region', Amount DECIMAL(10, 2) 3) AS r`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	rowset, ok := program.Statements[0].(*ast.SelectStatement).From.Tables[0].(*ast.BulkRowset)
+	if !ok {
+		t.Fatalf("expected BulkRowset, got %T", program.Statements[0].(*ast.SelectStatement).From.Tables[0])
+	}
+	if len(rowset.Paths) != 1 || rowset.Paths[0].String() != "'sales/*.parquet'" {
+		t.Errorf("Paths = %v", rowset.Paths)
+	}
+	if format := rowset.Options.Get("FORMAT"); format == nil || format.Value.String() != "'PARQUET'" {
+		t.Errorf("FORMAT = %v", format)
+	}
+	if len(rowset.Columns) != 3 || rowset.Columns[1].Path != "$.region" || rowset.Columns[1].Collation != "Latin1_General_100_BIN2_UTF8" || rowset.Columns[2].Ordinal != 3 {
+		t.Errorf("Columns = %v", rowset.Columns)
+	}
+	if rowset.Alias == nil || rowset.Alias.Value != "r" {
+		t.Errorf("Alias = %v", rowset.Alias)
+	}
+	want := "OPENROWSET(BULK 'sales/*.parquet', DATA_SOURCE = 'Lake', FORMAT = 'PARQUET') WITH (Id INT, Region VARCHAR(50) COLLATE Latin1_General_100_BIN2_UTF8 '$.region', Amount DECIMAL(10, 2) 3) AS r"
+	if got := rowset.String(); got != want {
+		t.Errorf("String() = %s\nwant %s", got, want)
+	}
+}
+
+func TestExternalData(t *testing.T) {
+	input := `CREATE EXTERNAL DATA SOURCE Lake WITH (LOCATION = 'abfss://data@acct.dfs.core.windows.net', CREDENTIAL = LakeCredential);
+ALTER EXTERNAL DATA SOURCE Lake SET LOCATION = 'abfss://archive@acct.dfs.core.windows.net';
+CREATE EXTERNAL FILE FORMAT Csv WITH (FORMAT_TYPE = DELIMITEDTEXT, FORMAT_OPTIONS (FIELD_TERMINATOR = ',', FIRST_ROW = 2));
+CREATE EXTERNAL TABLE ext.Sales (Id INT NOT NULL, Amount DECIMAL(10, 2)) WITH (LOCATION = '/sales/', DATA_SOURCE = Lake, FILE_FORMAT = Csv);
+CREATE EXTERNAL TABLE ext.Totals (Region, Total) WITH (LOCATION = '/totals/', DATA_SOURCE = Lake, FILE_FORMAT = Parquet)
+AS SELECT Region, SUM(Amount) FROM ext.Sales GROUP BY Region;
+DROP EXTERNAL TABLE ext.Sales;
+DROP EXTERNAL FILE FORMAT Csv;
+DROP EXTERNAL DATA SOURCE Lake;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 8 {
+		t.Fatalf("expected 8 statements, got %d", len(program.Statements))
+	}
+
+	source := program.Statements[0].(*ast.CreateExternalDataSourceStatement)
+	if source.Name.Value != "Lake" || source.Options.Get("CREDENTIAL").Value.String() != "LakeCredential" {
+		t.Errorf("data source = %s", source)
+	}
+	alter := program.Statements[1].(*ast.AlterExternalDataSourceStatement)
+	if len(alter.Options) != 1 || alter.Options.Get("LOCATION") == nil {
+		t.Errorf("ALTER options = %v", alter.Options)
+	}
+	format := program.Statements[2].(*ast.CreateExternalFileFormatStatement)
+	if opts := format.Options.Get("FORMAT_OPTIONS"); opts == nil || opts.List.Get("FIRST_ROW").Value.String() != "2" {
+		t.Errorf("file format = %s", format)
+	}
+	table := program.Statements[3].(*ast.CreateExternalTableStatement)
+	if table.Name.String() != "ext.Sales" || len(table.Columns) != 2 || table.Options.Get("DATA_SOURCE").Value.String() != "Lake" {
+		t.Errorf("external table = %s", table)
+	}
+	cetas := program.Statements[4].(*ast.CreateExternalTableStatement)
+	if len(cetas.ColumnNames) != 2 || len(cetas.Columns) != 0 {
+		t.Errorf("CETAS columns = %v", cetas.ColumnNames)
+	}
+	if _, ok := cetas.AsSelect.(*ast.SelectStatement); !ok {
+		t.Errorf("CETAS AsSelect = %T", cetas.AsSelect)
+	}
+	for i, kind := range []string{"EXTERNAL TABLE", "EXTERNAL FILE FORMAT", "EXTERNAL DATA SOURCE"} {
+		drop := program.Statements[5+i].(*ast.DropObjectStatement)
+		if drop.ObjectType != kind || len(drop.Names) != 1 {
+			t.Errorf("DROP = %s", drop)
+		}
+	}
+
+	for i, want := range map[int]string{
+		0: "CREATE EXTERNAL DATA SOURCE Lake WITH (LOCATION = 'abfss://data@acct.dfs.core.windows.net', CREDENTIAL = LakeCredential)",
+		1: "ALTER EXTERNAL DATA SOURCE Lake SET LOCATION = 'abfss://archive@acct.dfs.core.windows.net'",
+		4: "CREATE EXTERNAL TABLE ext.Totals (Region, Total) WITH (LOCATION = '/totals/', DATA_SOURCE = Lake, FILE_FORMAT = Parquet) AS SELECT Region, SUM(Amount) FROM ext.Sales GROUP BY Region",
+		7: "DROP EXTERNAL DATA SOURCE Lake",
+	} {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("String() = %s\nwant %s", got, want)
+		}
+	}
 }
 
 func TestComposableDml(t *testing.T) {
@@ -7101,3 +7292,857 @@ func TestOpenXmlWithClause(t *testing.T) {
 		}
 	}
 }
+
+func TestExpectedTokens(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []token.Type
+		notWant []token.Type
+	}{
+		{"SELECT a FROM t ", []token.Type{token.WHERE, token.JOIN, token.ORDER, token.SELECT, token.EOF}, nil},
+		{"SELECT a FROM t WHERE ", []token.Type{token.IDENT, token.VARIABLE, token.NOT, token.EXISTS, token.CASE}, []token.Type{token.LEFT}},
+		{"CREATE ", []token.Type{token.TABLE, token.PROCEDURE, token.VIEW, token.INDEX}, nil},
+		{"BEGIN SELECT 1; ", []token.Type{token.END, token.SELECT, token.IF}, nil},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.TrackExpected()
+		p.ParseProgram()
+		at, expected := p.Expected()
+		if at.Type != token.EOF {
+			t.Errorf("%q: expected tokens at %s, want EOF", tt.input, at.Type)
+		}
+		// An error at the end reports the tokens expected when it occurred
+		for _, err := range p.SyntaxErrors() {
+			if err.Got.Type == token.EOF && err.Expected != nil {
+				expected = err.Expected
+			}
+		}
+		for _, typ := range tt.want {
+			if !slices.Contains(expected, typ) {
+				t.Errorf("%q: %s not in expected %v", tt.input, typ, expected)
+			}
+		}
+		for _, typ := range tt.notWant {
+			if slices.Contains(expected, typ) {
+				t.Errorf("%q: %s should not be expected", tt.input, typ)
+			}
+		}
+	}
+}
+
+func TestExpectedNotTrackedByDefault(t *testing.T) {
+	p := New(lexer.New("SELECT a FROM t WHERE a IN (1, 2"))
+	p.ParseProgram()
+	if _, expected := p.Expected(); len(expected) != 0 {
+		t.Errorf("expected %v without TrackExpected", expected)
+	}
+	if errs := p.SyntaxErrors(); len(errs) == 0 || len(errs[0].Expected) != 0 {
+		t.Errorf("syntax errors %v should not record expected tokens", errs)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	p := New(lexer.New("SELECT a FROM t WHERE a IN (1, 2"))
+	p.TrackExpected()
+	p.ParseProgram()
+	errs := p.SyntaxErrors()
+	if len(errs) == 0 {
+		t.Fatalf("expected a syntax error")
+	}
+	err := errs[0]
+	if err.Error() != p.Errors()[0] {
+		t.Errorf("Error() = %q, Errors()[0] = %q", err.Error(), p.Errors()[0])
+	}
+	if err.Line != 1 || err.Got.Type != token.EOF {
+		t.Errorf("error at line %d, got %s", err.Line, err.Got.Type)
+	}
+	if !slices.Contains(err.Expected, token.RPAREN) || !slices.Contains(err.Expected, token.COMMA) {
+		t.Errorf("expected %v, want ) and ,", err.Expected)
+	}
+}
+
+func TestOptionLists(t *testing.T) {
+	input := `CREATE TABLE dbo.Customers (
+    Id INT NOT NULL INDEX IX_Id NONCLUSTERED HASH WITH (BUCKET_COUNT = 1024),
+    Email NVARCHAR(100) MASKED WITH (FUNCTION = 'email()'),
+    SSN CHAR(11) ENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = CEK1, ENCRYPTION_TYPE = DETERMINISTIC, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256'),
+    CONSTRAINT PK_Customers PRIMARY KEY (Id) WITH (FILLFACTOR = 90)
+) WITH (SYSTEM_VERSIONING = ON (HISTORY_TABLE = dbo.CustomersHistory, HISTORY_RETENTION_PERIOD = 6 MONTHS))`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.CreateTableStatement)
+	if !ok {
+		t.Fatalf("expected CreateTableStatement, got %T", program.Statements[0])
+	}
+	tests := []struct {
+		name string
+		list ast.OptionList
+		want string
+	}{
+		{"inline index", stmt.Columns[0].InlineIndex.Options, "(BUCKET_COUNT = 1024)"},
+		{"masked", stmt.Columns[1].Masked, "(FUNCTION = 'email()')"},
+		{"encrypted", stmt.Columns[2].Encrypted, "(COLUMN_ENCRYPTION_KEY = CEK1, ENCRYPTION_TYPE = DETERMINISTIC, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256')"},
+		{"constraint", stmt.Constraints[0].IndexOptions, "(FILLFACTOR = 90)"},
+		{"table", stmt.Options, "(SYSTEM_VERSIONING = ON (HISTORY_TABLE = dbo.CustomersHistory, HISTORY_RETENTION_PERIOD = 6 MONTHS))"},
+	}
+	for _, tt := range tests {
+		if got := tt.list.String(); got != tt.want {
+			t.Errorf("%s: options = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if !stmt.Columns[0].InlineIndex.IsHash {
+		t.Errorf("expected a HASH inline index")
+	}
+
+	versioning := stmt.Options.Get("system_versioning")
+	if versioning == nil {
+		t.Fatalf("expected SYSTEM_VERSIONING option")
+	}
+	if id, ok := versioning.Value.(*ast.Identifier); !ok || id.Value != "ON" {
+		t.Errorf("SYSTEM_VERSIONING value = %v", versioning.Value)
+	}
+	if _, ok := versioning.List.Get("HISTORY_TABLE").Value.(*ast.QualifiedIdentifier); !ok {
+		t.Errorf("HISTORY_TABLE value is %T", versioning.List.Get("HISTORY_TABLE").Value)
+	}
+	if _, ok := stmt.Columns[1].Masked.Get("FUNCTION").Value.(*ast.StringLiteral); !ok {
+		t.Errorf("FUNCTION value is %T", stmt.Columns[1].Masked.Get("FUNCTION").Value)
+	}
+}
+
+func TestOptionListsOnModulesAndIndexes(t *testing.T) {
+	tests := []struct {
+		input string
+		get   func(ast.Statement) ast.OptionList
+		want  string
+	}{
+		{
+			`CREATE PROCEDURE dbo.P WITH NATIVE_COMPILATION, SCHEMABINDING AS BEGIN ATOMIC WITH (TRANSACTION ISOLATION LEVEL = SNAPSHOT, LANGUAGE = N'us_english') SELECT 1 END`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateProcedureStatement).Body.AtomicOptions },
+			"(TRANSACTION ISOLATION LEVEL = SNAPSHOT, LANGUAGE = N'us_english')",
+		},
+		{
+			`CREATE NONCLUSTERED INDEX IX_A ON dbo.T (A) WITH (ONLINE = ON (WAIT_AT_LOW_PRIORITY (MAX_DURATION = 1 MINUTES, ABORT_AFTER_WAIT = SELF)), MAXDOP = 4)`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateIndexStatement).Options },
+			"(ONLINE = ON (WAIT_AT_LOW_PRIORITY (MAX_DURATION = 1 MINUTES, ABORT_AFTER_WAIT = SELF)), MAXDOP = 4)",
+		},
+		{
+			`CREATE SPATIAL INDEX IX_G ON dbo.T (G) USING GEOMETRY_AUTO_GRID WITH (BOUNDING_BOX = (0, 0, 100, 100))`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateIndexStatement).Options },
+			"(BOUNDING_BOX = (0, 0, 100, 100))",
+		},
+		{
+			`CREATE TYPE dbo.Ids AS TABLE (Id INT NOT NULL PRIMARY KEY NONCLUSTERED HASH WITH (BUCKET_COUNT = 64)) WITH (MEMORY_OPTIMIZED = ON)`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateTypeStatement).Options },
+			"(MEMORY_OPTIMIZED = ON)",
+		},
+		{
+			`CREATE TABLE dbo.Docs AS FILETABLE WITH (FILETABLE_DIRECTORY = 'Docs')`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateTableStatement).Options },
+			"(FILETABLE_DIRECTORY = 'Docs')",
+		},
+		{
+			`CREATE FULLTEXT INDEX ON dbo.Docs (Body) KEY INDEX PK_Docs WITH (CHANGE_TRACKING = AUTO, STOPLIST = SYSTEM)`,
+			func(s ast.Statement) ast.OptionList { return s.(*ast.CreateFulltextIndexStatement).WithOptions },
+			"(CHANGE_TRACKING = AUTO, STOPLIST = SYSTEM)",
+		},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := tt.get(program.Statements[0]).String(); got != tt.want {
+			t.Errorf("%s\noptions = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	p := New(lexer.New(`ALTER QUEUE dbo.Q WITH STATUS = ON, ACTIVATION (STATUS = ON, MAX_QUEUE_READERS = 5)`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	queue := program.Statements[0].(*ast.AlterQueueStatement)
+	if queue.Options["STATUS"] != "ON" || queue.Options["ACTIVATION"] != "(STATUS = ON, MAX_QUEUE_READERS = 5)" {
+		t.Errorf("queue options = %v", queue.Options)
+	}
+}
+
+func TestGraphTables(t *testing.T) {
+	for input, want := range map[string]ast.GraphTableKind{
+		`CREATE TABLE dbo.Person (Id INT PRIMARY KEY, Name NVARCHAR(100)) AS NODE`: ast.GraphTableNode,
+		`CREATE TABLE dbo.FriendOf (Since DATE) AS EDGE`:                           ast.GraphTableEdge,
+		`CREATE TABLE dbo.Likes AS EDGE`:                                           ast.GraphTableEdge,
+		`CREATE TABLE dbo.Plain (Id INT)`:                                          ast.GraphTableNone,
+	} {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.Statements[0].(*ast.CreateTableStatement).GraphKind; got != want {
+			t.Errorf("%s: GraphKind = %v, want %v", input, got, want)
+		}
+	}
+
+	input := `CREATE TABLE dbo.LocatedIn (Since DATE, CONSTRAINT EC_LocatedIn CONNECTION (dbo.Restaurant TO dbo.City, Person TO City) ON DELETE CASCADE) AS EDGE`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.CreateTableStatement)
+	if len(stmt.Columns) != 1 || len(stmt.Constraints) != 1 {
+		t.Fatalf("expected 1 column and 1 constraint, got %d and %d", len(stmt.Columns), len(stmt.Constraints))
+	}
+	con := stmt.Constraints[0]
+	if con.Type != ast.ConstraintConnection || con.Name != "EC_LocatedIn" || con.OnDelete != "CASCADE" {
+		t.Errorf("constraint = %s", con)
+	}
+	if len(con.Connections) != 2 || con.Connections[0].From.String() != "dbo.Restaurant" || con.Connections[1].To.String() != "City" {
+		t.Errorf("connections = %v", con.Connections)
+	}
+	if got := stmt.String(); !strings.Contains(got, "CONNECTION (dbo.Restaurant TO dbo.City, Person TO City) ON DELETE CASCADE") || !strings.HasSuffix(got, ") AS EDGE") {
+		t.Errorf("String() = %s", got)
+	}
+
+	input = `ALTER TABLE dbo.LocatedIn ADD CONSTRAINT EC_2 CONNECTION (Person TO City)`
+	p = New(lexer.New(input))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `SELECT p1.Name, r.Name FROM Person p1, FriendOf f, Person p2, Likes l, Restaurant r
+WHERE MATCH(p1-(f)->p2-(l)->r AND p2<-(f2)-p1) AND p1.Name = 'Alice'`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	where := program.Statements[0].(*ast.SelectStatement).Where.(*ast.InfixExpression)
+	match, ok := where.Left.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("expected MatchExpression, got %T", where.Left)
+	}
+	if len(match.Patterns) != 2 {
+		t.Fatalf("expected 2 patterns, got %d", len(match.Patterns))
+	}
+	path := match.Patterns[0].(*ast.GraphPath)
+	if len(path.Elements) != 5 {
+		t.Fatalf("expected 5 elements, got %d", len(path.Elements))
+	}
+	if node := path.Elements[0].(*ast.GraphNode); node.Name.Value != "p1" {
+		t.Errorf("first node = %s", node)
+	}
+	if edge := path.Elements[3].(*ast.GraphEdge); edge.Name.Value != "l" || edge.Direction != ast.GraphDirectionRight {
+		t.Errorf("second edge = %s", edge)
+	}
+	back := match.Patterns[1].(*ast.GraphPath)
+	if edge := back.Elements[1].(*ast.GraphEdge); edge.Direction != ast.GraphDirectionLeft {
+		t.Errorf("expected a left edge, got %s", edge)
+	}
+	if got := match.String(); got != "MATCH(p1-(f)->p2-(l)->r AND p2<-(f2)-p1)" {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	input := `SELECT P1.Name, STRING_AGG(P2.Name, '->') WITHIN GROUP (GRAPH PATH) AS Friends
+FROM Person AS P1, FriendOf FOR PATH AS fo, Person FOR PATH AS P2, Likes l, Restaurant r
+WHERE MATCH(SHORTEST_PATH(P1(-(fo)->P2){1,3}) AND LAST_NODE(P2)-(l)->r AND SHORTEST_PATH((P2<-(fo)-)+P1))`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.SelectStatement)
+
+	agg := stmt.Columns[1].Expression.(*ast.FunctionCall)
+	if !agg.GraphPath {
+		t.Errorf("expected WITHIN GROUP (GRAPH PATH)")
+	}
+	if !stmt.From.Tables[1].(*ast.TableName).ForPath || stmt.From.Tables[3].(*ast.TableName).ForPath {
+		t.Errorf("FOR PATH not recorded on the right tables")
+	}
+
+	match := stmt.Where.(*ast.MatchExpression)
+	sp := match.Patterns[0].(*ast.ShortestPath)
+	if sp.Anchor.Name.Value != "P1" || sp.AnchorAtEnd || sp.Unbounded || sp.MinHops != 1 || sp.MaxHops != 3 || len(sp.Group) != 2 {
+		t.Errorf("shortest path = %+v", sp)
+	}
+	if node := match.Patterns[1].(*ast.GraphPath).Elements[0].(*ast.GraphNode); !node.LastNode || node.Name.Value != "P2" {
+		t.Errorf("expected LAST_NODE(P2), got %s", node)
+	}
+	reversed := match.Patterns[2].(*ast.ShortestPath)
+	if !reversed.AnchorAtEnd || !reversed.Unbounded || reversed.Anchor.Name.Value != "P1" {
+		t.Errorf("shortest path = %+v", reversed)
+	}
+	want := "MATCH(SHORTEST_PATH(P1(-(fo)->P2){1,3}) AND LAST_NODE(P2)-(l)->r AND SHORTEST_PATH((P2<-(fo)-)+P1))"
+	if got := match.String(); got != want {
+		t.Errorf("String() = %s\nwant %s", got, want)
+	}
+}
+
+func TestTemporalTable(t *testing.T) {
+	input := `CREATE TABLE dbo.Employee (
+    Id INT PRIMARY KEY,
+    ValidFrom DATETIME2 GENERATED ALWAYS AS ROW START HIDDEN NOT NULL,
+    ValidTo DATETIME2 GENERATED ALWAYS AS ROW END NOT NULL,
+    PERIOD FOR SYSTEM_TIME (ValidFrom, ValidTo)
+) ON [PRIMARY] WITH (SYSTEM_VERSIONING = ON (HISTORY_TABLE = dbo.EmployeeHistory, DATA_CONSISTENCY_CHECK = ON, HISTORY_RETENTION_PERIOD = 6 MONTHS))`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.CreateTableStatement)
+
+	if from := stmt.Columns[1]; from.GeneratedAlways != ast.GeneratedRowStart || !from.IsHidden {
+		t.Errorf("ValidFrom = %s", from)
+	}
+	if to := stmt.Columns[2]; to.GeneratedAlways != ast.GeneratedRowEnd || to.IsHidden {
+		t.Errorf("ValidTo = %s", to)
+	}
+	sv := stmt.SystemVersioning
+	if sv == nil || !sv.On {
+		t.Fatalf("SystemVersioning = %v", sv)
+	}
+	if sv.HistoryTable.String() != "dbo.EmployeeHistory" {
+		t.Errorf("HistoryTable = %s", sv.HistoryTable)
+	}
+	if sv.DataConsistencyCheck == nil || !*sv.DataConsistencyCheck {
+		t.Errorf("DataConsistencyCheck = %v", sv.DataConsistencyCheck)
+	}
+	if r := sv.HistoryRetention; r == nil || r.Infinite || r.Count != 6 || r.Unit != "MONTH" {
+		t.Errorf("HistoryRetention = %+v", r)
+	}
+	if stmt.FileGroup != "PRIMARY" {
+		t.Errorf("FileGroup = %q", stmt.FileGroup)
+	}
+	if got := stmt.String(); !strings.Contains(got, "ValidFrom DATETIME2 GENERATED ALWAYS AS ROW START HIDDEN NOT NULL") {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestLedgerTable(t *testing.T) {
+	input := `CREATE TABLE dbo.Audit (
+    Id INT,
+    TxId BIGINT GENERATED ALWAYS AS TRANSACTION_ID START HIDDEN NOT NULL,
+    Seq BIGINT GENERATED ALWAYS AS SEQUENCE_NUMBER START HIDDEN NOT NULL
+) WITH (LEDGER = ON (APPEND_ONLY = ON, LEDGER_VIEW = dbo.AuditLedger (TRANSACTION_ID_COLUMN_NAME = TxId, SEQUENCE_NUMBER_COLUMN_NAME = Seq)))`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.CreateTableStatement)
+
+	if stmt.Columns[1].GeneratedAlways != ast.GeneratedTransactionIDStart || stmt.Columns[2].GeneratedAlways != ast.GeneratedSequenceNumberStart {
+		t.Errorf("GeneratedAlways = %v, %v", stmt.Columns[1].GeneratedAlways, stmt.Columns[2].GeneratedAlways)
+	}
+	ledger := stmt.Ledger
+	if ledger == nil || !ledger.On || !ledger.AppendOnly {
+		t.Fatalf("Ledger = %v", ledger)
+	}
+	if ledger.View.String() != "dbo.AuditLedger" || ledger.TransactionIDColumn != "TxId" || ledger.SequenceNumberColumn != "Seq" {
+		t.Errorf("Ledger = %+v", ledger)
+	}
+	if stmt.SystemVersioning != nil {
+		t.Errorf("SystemVersioning = %v", stmt.SystemVersioning)
+	}
+}
+
+func TestAlterTemporalTable(t *testing.T) {
+	input := `ALTER TABLE dbo.Employee SET (SYSTEM_VERSIONING = OFF);
+ALTER TABLE dbo.Employee ADD
+    ValidFrom DATETIME2 GENERATED ALWAYS AS ROW START HIDDEN CONSTRAINT DF_ValidFrom DEFAULT SYSUTCDATETIME(),
+    ValidTo DATETIME2 GENERATED ALWAYS AS ROW END HIDDEN CONSTRAINT DF_ValidTo DEFAULT '9999-12-31',
+    PERIOD FOR SYSTEM_TIME (ValidFrom, ValidTo);
+ALTER TABLE dbo.Employee SET (SYSTEM_VERSIONING = ON (HISTORY_TABLE = dbo.EmployeeHistory, HISTORY_RETENTION_PERIOD = INFINITE));
+ALTER TABLE dbo.Employee DROP PERIOD FOR SYSTEM_TIME;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 4 {
+		t.Fatalf("expected 4 statements, got %d", len(program.Statements))
+	}
+	action := func(i int) *ast.AlterTableAction {
+		return program.Statements[i].(*ast.AlterTableStatement).Actions[0]
+	}
+
+	off := action(0)
+	if off.Type != ast.AlterSetOption || off.SystemVersioning == nil || off.SystemVersioning.On {
+		t.Errorf("SET (SYSTEM_VERSIONING = OFF): %+v", off)
+	}
+	add := action(1)
+	if add.Type != ast.AlterAddColumn || len(add.Columns) != 2 || len(add.Constraints) != 1 {
+		t.Fatalf("ADD: %d columns and %d constraints", len(add.Columns), len(add.Constraints))
+	}
+	if add.Constraints[0].Type != ast.ConstraintPeriod || add.Columns[1].GeneratedAlways != ast.GeneratedRowEnd {
+		t.Errorf("ADD = %s", add)
+	}
+	on := action(2).SystemVersioning
+	if on == nil || !on.On || on.HistoryTable.String() != "dbo.EmployeeHistory" || !on.HistoryRetention.Infinite {
+		t.Errorf("SET (SYSTEM_VERSIONING = ON ...): %v", on)
+	}
+	if drop := action(3); drop.Type != ast.AlterDropPeriod {
+		t.Errorf("expected AlterDropPeriod, got %v", drop.Type)
+	}
+
+	for i, want := range map[int]string{
+		0: "ALTER TABLE dbo.Employee SET (SYSTEM_VERSIONING = OFF)",
+		1: "ALTER TABLE dbo.Employee ADD ValidFrom DATETIME2 GENERATED ALWAYS AS ROW START HIDDEN CONSTRAINT DF_ValidFrom DEFAULT SYSUTCDATETIME(), ValidTo DATETIME2 GENERATED ALWAYS AS ROW END HIDDEN CONSTRAINT DF_ValidTo DEFAULT '9999-12-31', PERIOD FOR SYSTEM_TIME (ValidFrom, ValidTo)",
+		3: "ALTER TABLE dbo.Employee DROP PERIOD FOR SYSTEM_TIME",
+	} {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("String() = %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestQueryOptionLabel(t *testing.T) {
+	tests := []string{
+		"SELECT * FROM T OPTION (LABEL = 'load', MAX_GRANT_PERCENT = 25)",
+		"UPDATE T SET a = 1 WHERE (b = 2) OPTION (LABEL = 'update')",
+		"DELETE FROM T WHERE (b = 2) OPTION (MAXDOP 1)",
+		"MERGE INTO T USING S ON (T.a = S.a) WHEN MATCHED THEN DELETE OPTION (LABEL = 'merge')",
+	}
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.Statements[0].String(); got != input {
+			t.Errorf("String() = %s\nwant %s", got, input)
+		}
+	}
+}
+
+func TestSynapseTableOptions(t *testing.T) {
+	input := `CREATE TABLE dbo.Sales (Id INT, Region INT, SoldOn DATE)
+WITH (DISTRIBUTION = HASH(Id), CLUSTERED COLUMNSTORE INDEX ORDER (SoldOn),
+      PARTITION (SoldOn RANGE RIGHT FOR VALUES ('2024-01-01', '2025-01-01')))`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.CreateTableStatement)
+	if d := stmt.Distribution; d == nil || d.Kind != ast.DistributionHash || len(d.Columns) != 1 || d.Columns[0].Value != "Id" {
+		t.Errorf("Distribution = %+v", d)
+	}
+	if ix := stmt.Index; ix == nil || ix.Kind != ast.TableIndexClusteredColumnstore || len(ix.Columns) != 1 || ix.Columns[0].Name.Value != "SoldOn" {
+		t.Errorf("Index = %+v", ix)
+	}
+	part := stmt.Partition
+	if part == nil || part.Column.Value != "SoldOn" || part.Range != "RIGHT" || len(part.Values) != 2 {
+		t.Fatalf("Partition = %+v", part)
+	}
+	if part.Values[0].String() != "'2024-01-01'" {
+		t.Errorf("Partition value = %s", part.Values[0])
+	}
+
+	tests := []struct {
+		input string
+		kind  ast.TableIndexKind
+	}{
+		{"CREATE TABLE t (a INT) WITH (DISTRIBUTION = REPLICATE, HEAP)", ast.TableIndexHeap},
+		{"CREATE TABLE t (a INT, b INT) WITH (CLUSTERED INDEX (a, b DESC))", ast.TableIndexClustered},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt := program.Statements[0].(*ast.CreateTableStatement)
+		if stmt.Index == nil || stmt.Index.Kind != tt.kind {
+			t.Errorf("%s: Index = %+v", tt.input, stmt.Index)
+		}
+	}
+}
+
+func TestSynapseDialect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			"CREATE TABLE dbo.SalesCopy WITH (DISTRIBUTION = ROUND_ROBIN, HEAP) AS SELECT * FROM dbo.Sales OPTION (LABEL = 'ctas')",
+			"CREATE TABLE dbo.SalesCopy WITH (DISTRIBUTION = ROUND_ROBIN, HEAP) AS SELECT * FROM dbo.Sales OPTION (LABEL = 'ctas')",
+		},
+		{
+			"CREATE TABLE t (x, y) WITH (DISTRIBUTION = HASH(x)) AS SELECT a, b FROM s",
+			"CREATE TABLE t (x, y) WITH (DISTRIBUTION = HASH (x)) AS SELECT a, b FROM s",
+		},
+		{
+			"CREATE TABLE t AS SELECT 1 AS a",
+			"CREATE TABLE t AS SELECT 1 AS a",
+		},
+		{
+			"COPY INTO dbo.Sales (Id DEFAULT 0 1, Region 2) FROM 'https://acct.blob.core.windows.net/c/a.csv', 'https://acct.blob.core.windows.net/c/b.csv' WITH (FILE_TYPE = 'CSV', CREDENTIAL = (IDENTITY = 'Managed Identity'), FIRSTROW = 2)",
+			"COPY INTO dbo.Sales (Id DEFAULT 0 1, Region 2) FROM 'https://acct.blob.core.windows.net/c/a.csv', 'https://acct.blob.core.windows.net/c/b.csv' WITH (FILE_TYPE = 'CSV', CREDENTIAL = (IDENTITY = 'Managed Identity'), FIRSTROW = 2)",
+		},
+		{"RENAME OBJECT::dbo.Sales TO SalesOld", "RENAME OBJECT dbo.Sales TO SalesOld"},
+		{"RENAME OBJECT dbo.Sales COLUMN Region TO RegionId", "RENAME OBJECT dbo.Sales COLUMN Region TO RegionId"},
+		{"RENAME DATABASE::Staging TO StagingOld", "RENAME DATABASE Staging TO StagingOld"},
+		{
+			"CREATE REMOTE TABLE db.dbo.Sales AT ('Data Source = host, 1433; User ID = u; Password = p;') WITH (BATCH_SIZE = 1000) AS SELECT * FROM dbo.Sales",
+			"CREATE REMOTE TABLE db.dbo.Sales AT ('Data Source = host, 1433; User ID = u; Password = p;') WITH (BATCH_SIZE = 1000) AS SELECT * FROM dbo.Sales",
+		},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.SetDialect(Synapse)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("%s: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.want {
+			t.Errorf("String() = %s\nwant %s", got, tt.want)
+		}
+	}
+
+	// The statements are not SQL Server's
+	for _, input := range []string{"COPY INTO t FROM 'x'", "RENAME OBJECT t TO u", "CREATE TABLE t AS SELECT 1 AS a"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected errors without the Synapse dialect", input)
+		}
+	}
+}
+
+func TestSecurityPolicy(t *testing.T) {
+	input := `CREATE SECURITY POLICY Security.SalesFilter
+ADD FILTER PREDICATE Security.fn_securitypredicate(SalesRep) ON dbo.Sales,
+ADD BLOCK PREDICATE Security.fn_securitypredicate(SalesRep) ON dbo.Sales AFTER INSERT
+WITH (STATE = ON, SCHEMABINDING = ON);
+ALTER SECURITY POLICY Security.SalesFilter
+ALTER FILTER PREDICATE Security.fn_region(Region, 1) ON dbo.Sales,
+DROP BLOCK PREDICATE ON dbo.Sales AFTER INSERT
+WITH (STATE = OFF) NOT FOR REPLICATION;
+DROP SECURITY POLICY IF EXISTS Security.SalesFilter;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(program.Statements))
+	}
+
+	create, ok := program.Statements[0].(*ast.CreateSecurityPolicyStatement)
+	if !ok {
+		t.Fatalf("expected CreateSecurityPolicyStatement, got %T", program.Statements[0])
+	}
+	if len(create.Predicates) != 2 {
+		t.Fatalf("expected 2 predicates, got %d", len(create.Predicates))
+	}
+	block := create.Predicates[1]
+	if block.Type != ast.BlockPredicate || block.Function.String() != "Security.fn_securitypredicate" ||
+		block.Table.String() != "dbo.Sales" || block.Operation != "AFTER INSERT" || len(block.Arguments) != 1 {
+		t.Errorf("block predicate = %s", block)
+	}
+	if create.State == nil || !*create.State || create.SchemaBinding == nil || !*create.SchemaBinding {
+		t.Errorf("State = %v, SchemaBinding = %v", create.State, create.SchemaBinding)
+	}
+
+	alter, ok := program.Statements[1].(*ast.AlterSecurityPolicyStatement)
+	if !ok {
+		t.Fatalf("expected AlterSecurityPolicyStatement, got %T", program.Statements[1])
+	}
+	if len(alter.Predicates) != 2 || alter.Predicates[0].Action != "ALTER" || alter.Predicates[1].Function != nil {
+		t.Errorf("predicates = %v", alter.Predicates)
+	}
+	if alter.State == nil || *alter.State || !alter.NotForReplication {
+		t.Errorf("State = %v, NotForReplication = %v", alter.State, alter.NotForReplication)
+	}
+
+	drop := program.Statements[2].(*ast.DropObjectStatement)
+	if drop.ObjectType != "SECURITY POLICY" || !drop.IfExists {
+		t.Errorf("DROP = %s", drop)
+	}
+
+	for i, want := range []string{
+		"CREATE SECURITY POLICY Security.SalesFilter ADD FILTER PREDICATE Security.fn_securitypredicate(SalesRep) ON dbo.Sales, ADD BLOCK PREDICATE Security.fn_securitypredicate(SalesRep) ON dbo.Sales AFTER INSERT WITH (STATE = ON, SCHEMABINDING = ON)",
+		"ALTER SECURITY POLICY Security.SalesFilter ALTER FILTER PREDICATE Security.fn_region(Region, 1) ON dbo.Sales, DROP BLOCK PREDICATE ON dbo.Sales AFTER INSERT WITH (STATE = OFF) NOT FOR REPLICATION",
+	} {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("String() = %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestEventSession(t *testing.T) {
+	input := `CREATE EVENT SESSION [LongQueries] ON SERVER
+ADD EVENT sqlserver.sql_statement_completed (
+    SET collect_statement = (1)
+    ACTION (sqlserver.client_app_name, sqlserver.sql_text)
+    WHERE duration >= 1000000 AND [sqlserver].[database_name] = N'Sales'
+),
+ADD EVENT sqlserver.xml_deadlock_report
+ADD TARGET package0.event_file (SET filename = N'LongQueries.xel', max_file_size = 5)
+WITH (MAX_MEMORY = 4096 KB, EVENT_RETENTION_MODE = ALLOW_SINGLE_EVENT_LOSS, MAX_DISPATCH_LATENCY = 30 SECONDS, STARTUP_STATE = OFF);
+ALTER EVENT SESSION LongQueries ON SERVER STATE = START;
+ALTER EVENT SESSION LongQueries ON SERVER DROP EVENT sqlserver.xml_deadlock_report, ADD TARGET package0.ring_buffer;
+DROP EVENT SESSION LongQueries ON SERVER;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 4 {
+		t.Fatalf("expected 4 statements, got %d", len(program.Statements))
+	}
+
+	create, ok := program.Statements[0].(*ast.CreateEventSessionStatement)
+	if !ok {
+		t.Fatalf("expected CreateEventSessionStatement, got %T", program.Statements[0])
+	}
+	if create.Name.Value != "LongQueries" || create.Scope != "SERVER" || len(create.Events) != 2 || len(create.Targets) != 1 {
+		t.Fatalf("CREATE EVENT SESSION = %s", create)
+	}
+	event := create.Events[0]
+	if event.Name.String() != "sqlserver.sql_statement_completed" || len(event.Actions) != 2 || event.Actions[1].String() != "sqlserver.sql_text" {
+		t.Errorf("event = %s", event)
+	}
+	if event.Set.Get("collect_statement") == nil {
+		t.Errorf("SET = %s", event.Set)
+	}
+	if _, ok := event.Predicate.(*ast.InfixExpression); !ok {
+		t.Errorf("expected the predicate to be an expression, got %T", event.Predicate)
+	}
+	target := create.Targets[0]
+	if target.Name.String() != "package0.event_file" || target.Set.Get("filename").Value.String() != "N'LongQueries.xel'" {
+		t.Errorf("target = %s", target)
+	}
+	settings := create.Settings
+	if settings.MaxMemory != "4096 KB" || settings.EventRetentionMode != "ALLOW_SINGLE_EVENT_LOSS" ||
+		settings.MaxDispatchLatency != "30 SECONDS" || settings.StartupState == nil || *settings.StartupState {
+		t.Errorf("Settings = %+v", settings)
+	}
+
+	if start := program.Statements[1].(*ast.AlterEventSessionStatement); start.State != "START" {
+		t.Errorf("State = %q", start.State)
+	}
+	alter := program.Statements[2].(*ast.AlterEventSessionStatement)
+	if len(alter.DropEvents) != 1 || len(alter.AddTargets) != 1 {
+		t.Errorf("ALTER EVENT SESSION = %s", alter)
+	}
+	if drop := program.Statements[3].(*ast.DropObjectStatement); drop.ObjectType != "EVENT SESSION" || !drop.OnServer {
+		t.Errorf("DROP = %s", drop)
+	}
+
+	for i, want := range map[int]string{
+		1: "ALTER EVENT SESSION LongQueries ON SERVER STATE = START",
+		2: "ALTER EVENT SESSION LongQueries ON SERVER DROP EVENT sqlserver.xml_deadlock_report, ADD TARGET package0.ring_buffer",
+		3: "DROP EVENT SESSION LongQueries ON SERVER",
+	} {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("String() = %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestServerAudit(t *testing.T) {
+	input := `CREATE SERVER AUDIT HIPAA_Audit
+TO FILE (FILEPATH = 'D:\Audit\', MAXSIZE = 100 MB, MAX_ROLLOVER_FILES = UNLIMITED)
+WITH (QUEUE_DELAY = 1000, ON_FAILURE = SHUTDOWN)
+WHERE object_name = 'Patients';
+ALTER SERVER AUDIT HIPAA_Audit WITH (STATE = ON);
+ALTER SERVER AUDIT HIPAA_Audit REMOVE WHERE;
+CREATE SERVER AUDIT SPECIFICATION LoginSpec FOR SERVER AUDIT HIPAA_Audit
+ADD (FAILED_LOGIN_GROUP), ADD (SUCCESSFUL_LOGIN_GROUP) WITH (STATE = ON);
+CREATE DATABASE AUDIT SPECIFICATION PatientSpec FOR SERVER AUDIT HIPAA_Audit
+ADD (SELECT, INSERT ON OBJECT::dbo.Patients BY public, dbo),
+ADD (DATABASE_ROLE_MEMBER_CHANGE_GROUP);
+ALTER DATABASE AUDIT SPECIFICATION PatientSpec DROP (SELECT ON dbo.Patients BY public) WITH (STATE = OFF);
+DROP DATABASE AUDIT SPECIFICATION PatientSpec;
+DROP SERVER AUDIT HIPAA_Audit;`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 8 {
+		t.Fatalf("expected 8 statements, got %d", len(program.Statements))
+	}
+
+	audit, ok := program.Statements[0].(*ast.CreateServerAuditStatement)
+	if !ok {
+		t.Fatalf("expected CreateServerAuditStatement, got %T", program.Statements[0])
+	}
+	if audit.Target.Kind != "FILE" || audit.Target.Options.Get("MAXSIZE").Value.String() != "100 MB" {
+		t.Errorf("Target = %s", audit.Target)
+	}
+	if audit.Options.Get("ON_FAILURE") == nil || audit.Predicate == nil {
+		t.Errorf("CREATE SERVER AUDIT = %s", audit)
+	}
+	if remove := program.Statements[2].(*ast.AlterServerAuditStatement); !remove.RemoveWhere {
+		t.Errorf("expected REMOVE WHERE")
+	}
+
+	spec := program.Statements[3].(*ast.CreateAuditSpecificationStatement)
+	if spec.Scope != "SERVER" || spec.Audit.Value != "HIPAA_Audit" || len(spec.Actions) != 2 || spec.Actions[0].Group != "FAILED_LOGIN_GROUP" {
+		t.Errorf("server audit specification = %s", spec)
+	}
+	if spec.State == nil || !*spec.State {
+		t.Errorf("State = %v", spec.State)
+	}
+
+	db := program.Statements[4].(*ast.CreateAuditSpecificationStatement)
+	if db.Scope != "DATABASE" || len(db.Actions) != 2 {
+		t.Fatalf("database audit specification = %s", db)
+	}
+	action := db.Actions[0]
+	if len(action.Actions) != 2 || action.Class != "OBJECT" || action.Securable.String() != "dbo.Patients" || len(action.Principals) != 2 {
+		t.Errorf("action = %s", action)
+	}
+
+	alter := program.Statements[5].(*ast.AlterAuditSpecificationStatement)
+	if alter.Actions[0].Action != "DROP" || alter.State == nil || *alter.State {
+		t.Errorf("ALTER = %s", alter)
+	}
+
+	for i, want := range map[int]string{
+		4: "CREATE DATABASE AUDIT SPECIFICATION PatientSpec FOR SERVER AUDIT HIPAA_Audit ADD (SELECT, INSERT ON OBJECT::dbo.Patients BY public, dbo), ADD (DATABASE_ROLE_MEMBER_CHANGE_GROUP)",
+		6: "DROP DATABASE AUDIT SPECIFICATION PatientSpec",
+		7: "DROP SERVER AUDIT HIPAA_Audit",
+	} {
+		if got := program.Statements[i].String(); got != want {
+			t.Errorf("String() = %s\nwant %s", got, want)
+		}
+	}
+}
+
+func TestInMemoryOLTP(t *testing.T) {
+	input := `CREATE TABLE dbo.Sessions (
+    Id INT NOT NULL PRIMARY KEY NONCLUSTERED HASH WITH (BUCKET_COUNT = 1048576),
+    UserId INT NOT NULL INDEX ix_UserId HASH WITH (BUCKET_COUNT = 65536),
+    Created DATETIME2 NOT NULL,
+    INDEX ix_Created NONCLUSTERED (Created),
+    CONSTRAINT uq_User UNIQUE NONCLUSTERED HASH (UserId, Created) WITH (BUCKET_COUNT = 1024)
+) WITH (MEMORY_OPTIMIZED = ON, DURABILITY = SCHEMA_ONLY);
+GO
+CREATE PROCEDURE dbo.GetSession @Id INT
+WITH NATIVE_COMPILATION, SCHEMABINDING, EXECUTE AS OWNER
+AS BEGIN ATOMIC WITH (TRANSACTION ISOLATION LEVEL = REPEATABLE READ, LANGUAGE = N'us_english', DATEFIRST = 1, DATEFORMAT = dmy, DELAYED_DURABILITY = ON)
+    SELECT UserId FROM dbo.Sessions WHERE Id = @Id;
+END`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	table := program.Statements[0].(*ast.CreateTableStatement)
+	if !table.MemoryOptimized || table.Durability != ast.DurabilitySchemaOnly {
+		t.Errorf("MemoryOptimized = %v, Durability = %s", table.MemoryOptimized, table.Durability)
+	}
+	if c := table.Columns[0].Constraints[0]; !c.IsHash || c.BucketCount != 1048576 {
+		t.Errorf("primary key = %s, BucketCount = %d", c, c.BucketCount)
+	}
+	if ix := table.Columns[1].InlineIndex; ix == nil || !ix.IsHash || ix.BucketCount != 65536 {
+		t.Errorf("InlineIndex = %+v", ix)
+	}
+	if c := table.Constraints[0]; c.Type != ast.ConstraintIndex || c.IsHash || c.String() != "INDEX ix_Created NONCLUSTERED (Created)" {
+		t.Errorf("index = %s", c)
+	}
+	if c := table.Constraints[1]; !c.IsHash || c.BucketCount != 1024 || len(c.Columns) != 2 {
+		t.Errorf("unique = %s, BucketCount = %d", c, c.BucketCount)
+	}
+
+	proc := program.Statements[2].(*ast.CreateProcedureStatement)
+	if got := strings.Join(proc.Options, ", "); got != "NATIVE_COMPILATION, SCHEMABINDING, EXECUTE AS OWNER" {
+		t.Errorf("Options = %s", got)
+	}
+	atomic := proc.Body.Atomic
+	if atomic == nil || atomic.IsolationLevel != "REPEATABLE READ" || atomic.Language != "us_english" ||
+		atomic.DateFirst != 1 || atomic.DateFormat != "dmy" || atomic.DelayedDurability == nil || !*atomic.DelayedDurability {
+		t.Errorf("Atomic = %+v", atomic)
+	}
+	if len(proc.Body.Statements) != 1 {
+		t.Errorf("got %d statements, want 1", len(proc.Body.Statements))
+	}
+
+	input = "CREATE TABLE t (a INT NOT NULL, CONSTRAINT pk PRIMARY KEY NONCLUSTERED HASH (a) WITH (BUCKET_COUNT = 8), INDEX ix HASH (a) WITH (BUCKET_COUNT = 16)) WITH (MEMORY_OPTIMIZED = ON)"
+	p = New(lexer.New(input))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	got := strings.Join(strings.Fields(program.Statements[0].String()), " ")
+	want := "CREATE TABLE t ( a INT NOT NULL, CONSTRAINT pk PRIMARY KEY NONCLUSTERED HASH (a) WITH (BUCKET_COUNT = 8), INDEX ix HASH (a) WITH (BUCKET_COUNT = 16) ) WITH (MEMORY_OPTIMIZED = ON)"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSQLServer2025Functions(t *testing.T) {
+	input := `SELECT GREATEST(a, b, 3), LEAST(1, @x), DATETRUNC(month, d), DATE_BUCKET(week, 2, d, @origin),
+    APPROX_PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY Latency DESC),
+    JSON_OBJECT('id':Id, 'note':Note ABSENT ON NULL), JSON_ARRAY(1, NULL NULL ON NULL RETURNING json),
+    JSON_OBJECTAGG(k:v), JSON_ARRAYAGG(v ORDER BY v) OVER (PARTITION BY g),
+    REGEXP_REPLACE(Name, '[0-9]+', '#', 1, 2, 'i'), VECTOR_DISTANCE('cosine', @v, Embedding),
+    FirstName || ' ' || LastName
+FROM GENERATE_SERIES(1, 10, 2) AS s(n)
+WHERE REGEXP_LIKE(Name, '^a', 'i')`
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.SelectStatement)
+	columns := make([]ast.Expression, len(stmt.Columns))
+	for i, col := range stmt.Columns {
+		columns[i] = col.Expression
+	}
+	if e, ok := columns[0].(*ast.GreatestLeastExpression); !ok || e.Least || len(e.Arguments) != 3 {
+		t.Errorf("GREATEST = %#v", columns[0])
+	}
+	if e, ok := columns[1].(*ast.GreatestLeastExpression); !ok || !e.Least {
+		t.Errorf("LEAST = %#v", columns[1])
+	}
+	if e, ok := columns[2].(*ast.DateTruncExpression); !ok || e.DatePart.Value != "month" {
+		t.Errorf("DATETRUNC = %#v", columns[2])
+	}
+	if e, ok := columns[3].(*ast.DateBucketExpression); !ok || e.DatePart.Value != "week" || e.Origin == nil {
+		t.Errorf("DATE_BUCKET = %#v", columns[3])
+	}
+	if e, ok := columns[4].(*ast.ApproxPercentileExpression); !ok || e.Discrete || !e.OrderBy.Descending {
+		t.Errorf("APPROX_PERCENTILE_CONT = %#v", columns[4])
+	}
+	if e, ok := columns[5].(*ast.JsonObjectExpression); !ok || len(e.Pairs) != 2 || e.OnNull != ast.JsonAbsentOnNull {
+		t.Errorf("JSON_OBJECT = %#v", columns[5])
+	}
+	if e, ok := columns[6].(*ast.JsonArrayExpression); !ok || len(e.Elements) != 2 || e.OnNull != ast.JsonNullOnNull || e.Returning.Name != "JSON" {
+		t.Errorf("JSON_ARRAY = %#v", columns[6])
+	}
+	if e, ok := columns[7].(*ast.JsonObjectAggExpression); !ok || e.Key.String() != "k" {
+		t.Errorf("JSON_OBJECTAGG = %#v", columns[7])
+	}
+	if e, ok := columns[8].(*ast.JsonArrayAggExpression); !ok || len(e.OrderBy) != 1 || e.Over == nil {
+		t.Errorf("JSON_ARRAYAGG = %#v", columns[8])
+	}
+	if e, ok := columns[9].(*ast.RegexpExpression); !ok || e.Function != ast.RegexpReplace || e.Occurrence.String() != "2" || e.Flags.String() != "'i'" {
+		t.Errorf("REGEXP_REPLACE = %#v", columns[9])
+	}
+	if e, ok := columns[10].(*ast.VectorDistanceExpression); !ok || e.Metric.String() != "'cosine'" {
+		t.Errorf("VECTOR_DISTANCE = %#v", columns[10])
+	}
+	if e, ok := columns[11].(*ast.InfixExpression); !ok || e.Operator != "||" {
+		t.Errorf("|| = %#v", columns[11])
+	}
+	if series, ok := stmt.From.Tables[0].(*ast.GenerateSeries); !ok || series.Step == nil || series.Alias.Value != "s" {
+		t.Errorf("FROM = %#v", stmt.From.Tables[0])
+	}
+	if e, ok := stmt.Where.(*ast.RegexpExpression); !ok || e.Function != ast.RegexpLike || e.Flags == nil {
+		t.Errorf("WHERE = %#v", stmt.Where)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"SELECT JSON_OBJECT(), JSON_ARRAY(ABSENT ON NULL)", "SELECT JSON_OBJECT(), JSON_ARRAY(ABSENT ON NULL)"},
+		{"SELECT APPROX_PERCENTILE_DISC(0.9) WITHIN GROUP (ORDER BY x) FROM t", "SELECT APPROX_PERCENTILE_DISC(0.9) WITHIN GROUP (ORDER BY x ASC) FROM t"},
+		{"SELECT dbo.Greatest(1, 2)", "SELECT dbo.Greatest(1, 2)"},
+		{"SET @s ||= 'x'", "SET @s ||= 'x'"},
+		{"UPDATE t SET s ||= 'x'", "UPDATE t SET s ||= 'x'"},
+		{"DECLARE @v VECTOR(1536, float16), @e VECTOR(3), @j JSON", "DECLARE @v VECTOR(1536, float16), @e VECTOR(3), @j JSON"},
+		{"CREATE JSON INDEX ix_doc ON dbo.Docs (Doc) FOR ('$.a', '$.b.c') WITH (FILLFACTOR = 80) ON [PRIMARY]",
+			"CREATE JSON INDEX ix_doc ON dbo.Docs (Doc) FOR ('$.a', '$.b.c') WITH (FILLFACTOR = 80) ON PRIMARY"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.Statements[0].String(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"SELECT GREATEST()", "SELECT DATETRUNC('month', d)", "SELECT REGEXP_LIKE(a)", "SELECT * FROM GENERATE_SERIES(1)"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
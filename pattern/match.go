@@ -0,0 +1,223 @@
+package pattern
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// matcher matches a pattern tree against a node, collecting bindings.
+type matcher struct {
+	names    []string // Metavariable names, by number
+	bindings map[string]ast.Node
+}
+
+// metavariable returns what follows the prefix of the metavariable v
+// stands for, its number or _, or "..." for an ellipsis. Metavariables
+// are identifiers, one-part names, strings or data types without a
+// length; the parser may have changed their case.
+func metavariable(v reflect.Value) (string, bool) {
+	var name string
+	switch x := v.Interface().(type) {
+	case string:
+		name = x
+	case *ast.Identifier:
+		if x == nil {
+			return "", false
+		}
+		name = x.Value
+	case *ast.QualifiedIdentifier:
+		if x == nil || len(x.Parts) != 1 {
+			return "", false
+		}
+		name = x.Parts[0].Value
+	case *ast.DataType:
+		if x == nil || x.Length != nil || x.Precision != nil || x.Scale != nil || x.Max || x.XmlSchema != "" || x.ElementType != "" {
+			return "", false
+		}
+		name = x.Name
+	default:
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			return metavariable(v.Elem())
+		}
+		return "", false
+	}
+	switch {
+	case strings.EqualFold(name, ellipsis):
+		return "...", true
+	case len(name) > len(metaPrefix) && strings.EqualFold(name[:len(metaPrefix)], metaPrefix):
+		return name[len(metaPrefix):], true
+	}
+	return "", false
+}
+
+func (m *matcher) match(p, t reflect.Value) bool {
+	if p.CanInterface() {
+		if name, ok := metavariable(p); ok {
+			return m.bind(name, t)
+		}
+	}
+	switch p.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if p.IsNil() || t.IsNil() {
+			return p.IsNil() && t.IsNil()
+		}
+		if p.Kind() == reflect.Pointer && p.Type() != t.Type() {
+			return false
+		}
+		return m.match(p.Elem(), t.Elem())
+	case reflect.Struct:
+		if p.Type() != t.Type() {
+			return false
+		}
+		if p.Type() == tokenType {
+			// Positions and spelling do not matter
+			return true
+		}
+		for i := 0; i < p.NumField(); i++ {
+			if p.Type() == stringLiteralType && p.Type().Field(i).Name == "Value" {
+				// The case of a string is data
+				if p.Field(i).String() != t.Field(i).String() {
+					return false
+				}
+				continue
+			}
+			if !m.match(p.Field(i), t.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		return m.list(p, t, 0, 0)
+	case reflect.String:
+		return strings.EqualFold(p.String(), t.String())
+	}
+	if !p.CanInterface() {
+		return true
+	}
+	return reflect.DeepEqual(p.Interface(), t.Interface())
+}
+
+// bind matches a metavariable against t.
+func (m *matcher) bind(name string, t reflect.Value) bool {
+	if name == "..." {
+		return true
+	}
+	if absent(t) {
+		return false
+	}
+	if name == "_" {
+		return true
+	}
+	if n, err := strconv.Atoi(name); err == nil && n < len(m.names) {
+		name = m.names[n]
+	}
+	node := asNode(t)
+	if prev, ok := m.bindings[name]; ok {
+		return sameText(prev.String(), node.String())
+	}
+	m.bindings[name] = node
+	return true
+}
+
+// sameText reports whether two nodes written out are the same but for the
+// case of what is outside string literals.
+func sameText(a, b string) bool {
+	// Splitting at quotes leaves literals at odd indexes; a doubled quote
+	// inside one splits it around an empty text outside
+	as, bs := strings.Split(a, "'"), strings.Split(b, "'")
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if i%2 == 1 && as[i] != bs[i] || !strings.EqualFold(as[i], bs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// list matches the elements of two slices. An element of the pattern that
+// is or directly holds an ellipsis matches any run of elements.
+func (m *matcher) list(p, t reflect.Value, i, j int) bool {
+	if i == p.Len() {
+		return j == t.Len()
+	}
+	if isEllipsis(p.Index(i)) {
+		for k := j; k <= t.Len(); k++ {
+			saved := maps.Clone(m.bindings)
+			if m.list(p, t, i+1, k) {
+				return true
+			}
+			m.bindings = saved
+		}
+		return false
+	}
+	if j == t.Len() {
+		return false
+	}
+	saved := maps.Clone(m.bindings)
+	if m.match(p.Index(i), t.Index(j)) && m.list(p, t, i+1, j+1) {
+		return true
+	}
+	m.bindings = saved
+	return false
+}
+
+// isEllipsis reports whether a list element of a pattern is an ellipsis,
+// such as the column of SELECT $... or the clause of SET $... = $....
+func isEllipsis(v reflect.Value) bool {
+	if name, ok := metavariable(v); ok {
+		return name == "..."
+	}
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.CanInterface() {
+			if name, ok := metavariable(f); ok && name == "..." {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// absent reports whether an optional part of a node is missing.
+func absent(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+		return v.IsNil() || v.Kind() == reflect.Slice && v.Len() == 0
+	case reflect.String:
+		return v.String() == ""
+	}
+	return false
+}
+
+// asNode returns what a metavariable is bound to as a node. Parts of the
+// tree that are not nodes, such as names held as strings, become
+// identifiers.
+func asNode(v reflect.Value) ast.Node {
+	if n, ok := v.Interface().(ast.Node); ok {
+		return n
+	}
+	if v.Kind() == reflect.String {
+		return &ast.Identifier{Value: v.String()}
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return &ast.Identifier{Value: s.String()}
+	}
+	return &ast.Identifier{Value: fmt.Sprint(v.Interface())}
+}
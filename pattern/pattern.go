@@ -0,0 +1,274 @@
+// Package pattern finds T-SQL by its structure.
+//
+// A pattern is a statement or expression written in T-SQL, in which
+// metavariables stand for the parts that may vary:
+//
+//	UPDATE $T SET $X = $Y
+//	CONVERT(VARCHAR, $e)
+//	SELECT $... FROM $T WHERE $X = NULL
+//
+// The pattern is parsed by the same parser as the code it searches, and
+// matches any node with the same tree, ignoring layout, comments and the
+// case of keywords and names. A metavariable such as $T matches anything
+// in its place and is bound to what it matched; if it appears more than
+// once, every occurrence must match the same text. $_ matches anything
+// without binding. $... matches any number of elements of a list, such as
+// columns or arguments, or an optional part whether it is present or not.
+//
+// Everything else must match exactly, so parts left out of a pattern must
+// be absent: the UPDATE above only matches updates without a WHERE clause,
+// and the CONVERT only conversions to VARCHAR without a length.
+package pattern
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// The identifiers metavariables are rewritten to before parsing. A named
+// metavariable becomes the prefix followed by its number, which survives
+// the parser upper-casing names such as those of data types.
+const (
+	metaPrefix = "__pattern_var_"
+	ellipsis   = "__pattern_ellipsis"
+)
+
+// pseudoColumns are the $ names T-SQL itself uses, which are not
+// metavariables.
+var pseudoColumns = map[string]bool{
+	"ACTION": true, "IDENTITY": true, "ROWGUID": true, "PARTITION": true,
+	"NODE_ID": true, "EDGE_ID": true, "FROM_ID": true, "TO_ID": true,
+}
+
+// Pattern is a compiled pattern.
+type Pattern struct {
+	src   string
+	root  ast.Node
+	names []string // Metavariable names, by number
+}
+
+// Match is a node that matches a pattern.
+type Match struct {
+	Node     ast.Node
+	Line     int // position of the first token of the node
+	Column   int
+	Bindings map[string]ast.Node // by metavariable name, without the $
+}
+
+// Compile parses a pattern. A pattern that is not a statement is parsed as
+// an expression.
+func Compile(src string) (*Pattern, error) {
+	text, names := rewrite(src)
+	root, errs := parse(text)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("pattern %q: %s", src, errs[0])
+	}
+	if _, ok := metavariable(reflect.ValueOf(root)); ok {
+		return nil, fmt.Errorf("pattern %q: a pattern cannot be only a metavariable", src)
+	}
+	return &Pattern{src: src, root: root, names: names}, nil
+}
+
+// MustCompile is like Compile but panics if the pattern does not parse.
+func MustCompile(src string) *Pattern {
+	p, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Pattern) String() string { return p.src }
+
+// parse parses a rewritten pattern as a single statement, or failing that
+// as an expression.
+func parse(text string) (ast.Node, []string) {
+	program, errs := tsqlparser.Parse(text)
+	if len(errs) == 0 && len(program.Statements) == 1 {
+		// The parser takes a lone expression for an expression statement
+		if stmt, ok := program.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression, nil
+		}
+		return program.Statements[0], nil
+	}
+	program, exprErrs := tsqlparser.Parse("SELECT " + text)
+	if len(exprErrs) == 0 && len(program.Statements) == 1 {
+		if sel, ok := program.Statements[0].(*ast.SelectStatement); ok && len(sel.Columns) == 1 &&
+			sel.Columns[0].Alias == nil && sel.Columns[0].Expression != nil && sel.From == nil {
+			return sel.Columns[0].Expression, nil
+		}
+	}
+	if len(errs) == 0 {
+		return nil, []string{fmt.Sprintf("expected one statement or expression, got %d statements", len(program.Statements))}
+	}
+	return nil, errs
+}
+
+// rewrite replaces the metavariables of a pattern with identifiers the
+// parser accepts, leaving strings, quoted names and comments alone.
+func rewrite(src string) (string, []string) {
+	var out strings.Builder
+	var names []string
+	numbers := make(map[string]int)
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\'' || c == '"' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(src) && src[j] != end {
+				j++
+			}
+			j = min(j+1, len(src))
+			out.WriteString(src[i:j])
+			i = j - 1
+		case strings.HasPrefix(src[i:], "--"):
+			j := strings.IndexByte(src[i:], '\n')
+			if j < 0 {
+				j = len(src) - i
+			}
+			out.WriteString(src[i : i+j])
+			i += j - 1
+		case strings.HasPrefix(src[i:], "$..."):
+			out.WriteString(ellipsis)
+			i += len("$...") - 1
+		case c == '$' && i+1 < len(src) && isNameStart(src[i+1]):
+			j := i + 1
+			for j < len(src) && isNameByte(src[j]) {
+				j++
+			}
+			name := src[i+1 : j]
+			if pseudoColumns[strings.ToUpper(name)] {
+				out.WriteByte(c)
+				continue
+			}
+			if name == "_" {
+				out.WriteString(metaPrefix + name)
+			} else {
+				n, ok := numbers[name]
+				if !ok {
+					n = len(names)
+					numbers[name] = n
+					names = append(names, name)
+				}
+				out.WriteString(metaPrefix + strconv.Itoa(n))
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), names
+}
+
+// isNameStart reports whether c can start a metavariable name, which a
+// money literal such as $5.00 cannot.
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Find returns the nodes under node, node included, that match the
+// pattern, in depth-first order.
+func (p *Pattern) Find(node ast.Node) []Match {
+	var matches []Match
+	want := reflect.TypeOf(p.root)
+	visit(reflect.ValueOf(node), func(v reflect.Value) {
+		if v.Type() != want {
+			return
+		}
+		n := v.Interface().(ast.Node)
+		if bindings, ok := p.Match(n); ok {
+			line, col := position(v)
+			matches = append(matches, Match{Node: n, Line: line, Column: col, Bindings: bindings})
+		}
+	})
+	return matches
+}
+
+// Match reports whether node matches the pattern, and if so what the
+// metavariables are bound to.
+func (p *Pattern) Match(node ast.Node) (map[string]ast.Node, bool) {
+	m := &matcher{names: p.names, bindings: make(map[string]ast.Node)}
+	if !m.match(reflect.ValueOf(p.root), reflect.ValueOf(node)) {
+		return nil, false
+	}
+	return m.bindings, true
+}
+
+var (
+	nodeType          = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	tokenType         = reflect.TypeOf(token.Token{})
+	stringLiteralType = reflect.TypeOf(ast.StringLiteral{})
+)
+
+// visit calls f for every AST node in v, parents before their children.
+func visit(v reflect.Value, f func(reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			visit(v.Elem(), f)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if v.Type().Implements(nodeType) {
+			f(v)
+		}
+		visit(v.Elem(), f)
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			visit(v.Field(i), f)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			visit(v.Index(i), f)
+		}
+	}
+}
+
+// position returns the earliest position of the tokens in v.
+func position(v reflect.Value) (line, col int) {
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			if v.Type() == tokenType {
+				tok := v.Interface().(token.Token)
+				if tok.Line > 0 && (line == 0 || tok.Line < line || tok.Line == line && tok.Column < col) {
+					line, col = tok.Line, tok.Column
+				}
+				return
+			}
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	walk(v)
+	return line, col
+}
@@ -0,0 +1,170 @@
+package pattern
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func program(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	program, errs := tsqlparser.Parse(input)
+	if len(errs) > 0 {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	return program
+}
+
+// find returns the matches of a pattern in src as "line:column text".
+func find(t *testing.T, pat, src string) []string {
+	t.Helper()
+	p, err := Compile(pat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, m := range p.Find(program(t, src)) {
+		out = append(out, fmt.Sprintf("%d:%d %s", m.Line, m.Column, m.Node))
+	}
+	return out
+}
+
+func check(t *testing.T, pat, src string, want ...string) {
+	t.Helper()
+	got := find(t, pat, src)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s:\ngot  %q\nwant %q", pat, got, want)
+	}
+}
+
+func TestUpdateWithoutWhere(t *testing.T) {
+	src := `UPDATE dbo.Orders SET Amount = 0;
+update dbo.Orders set Amount = 1 where Id = 3;
+IF @reset = 1
+    UPDATE Totals SET Sum = NULL, Count = 0;`
+	check(t, "UPDATE $T SET $X = $Y", src,
+		"1:1 UPDATE dbo.Orders SET Amount = 0")
+	check(t, "UPDATE $T SET $... = $...", src,
+		"1:1 UPDATE dbo.Orders SET Amount = 0",
+		"4:5 UPDATE Totals SET Sum = NULL, Count = 0")
+	check(t, "UPDATE $T SET $... = $... WHERE $...", src,
+		"1:1 UPDATE dbo.Orders SET Amount = 0",
+		"2:1 UPDATE dbo.Orders SET Amount = 1 WHERE (Id = 3)",
+		"4:5 UPDATE Totals SET Sum = NULL, Count = 0")
+}
+
+func TestConvertWithoutLength(t *testing.T) {
+	src := "SELECT CONVERT(VARCHAR, Amount), CONVERT(VARCHAR(10), Amount),\n       convert(varchar, o.Placed, 120) FROM Orders o"
+	check(t, "CONVERT(VARCHAR, $e)", src,
+		"1:8 CONVERT(VARCHAR, Amount)")
+	check(t, "CONVERT(VARCHAR, $e, $...)", src,
+		"1:8 CONVERT(VARCHAR, Amount)",
+		"2:8 CONVERT(VARCHAR, o.Placed, 120)")
+}
+
+func TestBindings(t *testing.T) {
+	p := MustCompile("UPDATE $T SET $X = $Y")
+	bindings, ok := p.Match(program(t, "UPDATE dbo.Orders SET Amount = Amount * 2").Statements[0])
+	if !ok {
+		t.Fatal("no match")
+	}
+	for name, want := range map[string]string{"T": "dbo.Orders", "X": "Amount", "Y": "(Amount * 2)"} {
+		if got := bindings[name]; got == nil || got.String() != want {
+			t.Errorf("$%s = %v, want %s", name, got, want)
+		}
+	}
+	if len(bindings) != 3 {
+		t.Errorf("bindings = %v", bindings)
+	}
+}
+
+func TestRepeatedMetavariable(t *testing.T) {
+	src := "SELECT a FROM t WHERE a = a AND b = c AND t.x = T.X"
+	check(t, "$A = $A", src,
+		"1:23 (a = a)",
+		"1:43 (t.x = T.X)")
+	check(t, "$_ = $_", src,
+		"1:23 (a = a)",
+		"1:33 (b = c)",
+		"1:43 (t.x = T.X)")
+}
+
+func TestEllipsis(t *testing.T) {
+	src := "SELECT COALESCE(a, b, c), COALESCE(c), COALESCE(a, b) FROM t"
+	check(t, "COALESCE($..., c)", src,
+		"1:8 COALESCE(a, b, c)",
+		"1:27 COALESCE(c)")
+	check(t, "COALESCE($x, $...)", src,
+		"1:8 COALESCE(a, b, c)",
+		"1:27 COALESCE(c)",
+		"1:40 COALESCE(a, b)")
+
+	src = "SELECT * FROM Orders WHERE Amount = NULL;\nSELECT Id, Amount FROM Orders o WHERE o.Placed = NULL"
+	check(t, "SELECT $... FROM $T $... WHERE $X = NULL", src,
+		"1:1 SELECT * FROM Orders WHERE (Amount = NULL)",
+		"2:1 SELECT Id, Amount FROM Orders AS o WHERE (o.Placed = NULL)")
+}
+
+func TestNestedMatches(t *testing.T) {
+	src := `CREATE PROCEDURE dbo.Stamp AS
+BEGIN
+    SELECT GETDATE();
+    IF 1 = 1
+    BEGIN
+        INSERT INTO Log (At) VALUES (GETDATE());
+    END
+END`
+	check(t, "GETDATE()", src,
+		"3:12 GETDATE()",
+		"6:38 GETDATE()")
+}
+
+func TestDataTypes(t *testing.T) {
+	src := "DECLARE @n INT, @s NVARCHAR(20);\nSELECT CAST(a AS varchar(10)), CONVERT(VARCHAR, b), CONVERT(int, c, 1) FROM t"
+	check(t, "CAST($e AS $t)", src,
+		"2:8 CAST(a AS VARCHAR(10))")
+	check(t, "CONVERT($t, $e)", src,
+		"2:32 CONVERT(VARCHAR, b)")
+	check(t, "DECLARE $v $t, $...", src,
+		"1:1 DECLARE @n INT, @s NVARCHAR(20)")
+
+	p := MustCompile("CAST($e AS $Type)")
+	bindings, ok := p.Match(program(t, "SELECT CAST(a AS DECIMAL(10, 2))").Statements[0].(*ast.SelectStatement).Columns[0].Expression)
+	if !ok {
+		t.Fatal("no match")
+	}
+	if got := bindings["Type"]; got == nil || got.String() != "DECIMAL(10, 2)" {
+		t.Errorf("$Type = %v, want DECIMAL(10, 2)", got)
+	}
+}
+
+func TestMoneyLiterals(t *testing.T) {
+	check(t, "$x = $5.00", "SELECT a FROM t WHERE a = $5.00 OR b = $6.00",
+		"1:23 (a = $5.00)")
+}
+
+func TestStringsAreLiteral(t *testing.T) {
+	check(t, "PRINT '$X'", "PRINT '$X'; PRINT 'other'",
+		"1:1 PRINT '$X'")
+	check(t, "SELECT $ACTION", "SELECT $ACTION",
+		"1:1 SELECT $ACTION")
+}
+
+func TestLiteralCase(t *testing.T) {
+	src := "SELECT a FROM t WHERE Status = 'Open' OR status = 'open'"
+	check(t, "$X = 'Open'", src,
+		"1:23 (Status = 'Open')")
+	check(t, "$X = $X", "SELECT a FROM t WHERE f(a, 'X') = F(A, 'X') OR f(a, 'X') = f(a, 'x')",
+		"1:23 (f(a, 'X') = F(A, 'X'))")
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{"$X", "UPDATE SET", "SELECT 1; SELECT 2"} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("%q compiled", src)
+		}
+	}
+}
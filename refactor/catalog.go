@@ -0,0 +1,150 @@
+package refactor
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// catalog is what the scripts declare: the columns of tables and table
+// variables, and the objects they create.
+type catalog struct {
+	columns map[string][]column // by tableKey
+	known   map[string]bool     // tables whose columns are all declared
+	objects []object
+}
+
+// column is the declaration of a column.
+type column struct {
+	name *ast.Identifier
+	file string
+}
+
+// object is an object a script creates.
+type object struct {
+	kind string // TABLE, VIEW, PROCEDURE...
+	name []string
+	src  *source
+	tok  int // the last part of the name
+}
+
+func tableKey(name []string) string {
+	return strings.ToLower(schemaOf(name) + "." + name[len(name)-1])
+}
+
+func newCatalog(sources []*source) *catalog {
+	c := &catalog{columns: make(map[string][]column), known: make(map[string]bool)}
+	for _, s := range sources {
+		c.declarations(reflect.ValueOf(s.program), s.file.Name)
+		c.creations(s)
+	}
+	return c
+}
+
+// declarations collects the columns of CREATE TABLE, ALTER TABLE ... ADD
+// and DECLARE @t TABLE anywhere in the tree.
+func (c *catalog) declarations(v reflect.Value, file string) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			c.declarations(v.Elem(), file)
+		}
+		return
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		switch n := v.Interface().(type) {
+		case *ast.CreateTableStatement:
+			if n.Name != nil {
+				c.add(identifierValues(n.Name), n.Columns, file, true)
+			}
+		case *ast.AlterTableStatement:
+			if n.Table != nil {
+				for _, action := range n.Actions {
+					if action.Column != nil {
+						c.add(identifierValues(n.Table), []*ast.ColumnDefinition{action.Column}, file, false)
+					}
+					c.add(identifierValues(n.Table), action.Columns, file, false)
+				}
+			}
+		case *ast.DeclareStatement:
+			for _, def := range n.Variables {
+				if def.TableType != nil {
+					c.add([]string{def.Name}, def.TableType.Columns, file, true)
+				}
+			}
+		}
+		c.declarations(v.Elem(), file)
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			c.declarations(v.Field(i), file)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.declarations(v.Index(i), file)
+		}
+	}
+}
+
+// add adds columns to a table. Only a definition of the table makes all
+// its columns known; ALTER TABLE ... ADD only adds to them.
+func (c *catalog) add(table []string, cols []*ast.ColumnDefinition, file string, defines bool) {
+	key := tableKey(table)
+	if defines {
+		c.known[key] = true
+	}
+	for _, col := range cols {
+		if col != nil && col.Name != nil {
+			c.columns[key] = append(c.columns[key], column{name: col.Name, file: file})
+		}
+	}
+}
+
+// column returns the declaration of a column, and whether the columns of
+// the table are known at all.
+func (c *catalog) column(table []string, name string) (*column, bool) {
+	key := tableKey(table)
+	cols := c.columns[key]
+	for i := range cols {
+		if strings.EqualFold(cols[i].name.Value, name) {
+			return &cols[i], true
+		}
+	}
+	return nil, c.known[key]
+}
+
+// creatable are the kinds of object CREATE makes that share the namespace
+// of tables and procedures.
+var creatable = map[string]bool{
+	"TABLE": true, "VIEW": true, "PROCEDURE": true, "PROC": true, "FUNCTION": true,
+	"TRIGGER": true, "SYNONYM": true, "SEQUENCE": true,
+}
+
+// creations collects the objects created by CREATE [OR ALTER] kind name.
+func (c *catalog) creations(s *source) {
+	for i, tok := range s.tokens {
+		if tok.Type != token.CREATE {
+			continue
+		}
+		j := i + 1
+		if s.is(j, token.OR) && s.is(j+1, token.ALTER) {
+			j += 2
+		}
+		if j >= len(s.tokens) || !creatable[strings.ToUpper(s.tokens[j].Literal)] {
+			continue
+		}
+		if parts := s.name(j + 1); parts != nil {
+			kind := strings.ToUpper(s.tokens[j].Literal)
+			if kind == "PROC" {
+				kind = "PROCEDURE"
+			}
+			c.objects = append(c.objects, object{kind: kind, name: s.literals(parts), src: s, tok: parts[len(parts)-1]})
+		}
+	}
+}
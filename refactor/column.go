@@ -0,0 +1,250 @@
+package refactor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// RenameColumn renames a column of a table in the scripts: in the
+// definition of the table, in ALTER TABLE and CREATE INDEX, in the column
+// lists of INSERT, MERGE and REFERENCES, in SET clauses, in OUTPUT and
+// trigger references through inserted and deleted, in expressions
+// qualified by the table or an alias of it, and in COL_LENGTH().
+//
+// An unqualified column is renamed when the statement names the table and
+// the scripts declare the other tables it names without such a column.
+// Otherwise the reference is ambiguous and RenameColumn returns an error,
+// as it does when the column is selected by a derived table or common
+// table expression that would then change the name of its own column.
+//
+// It returns a *CollisionError if the table already has a column of the
+// new name, or if a statement that uses the column names another table
+// with a column of the new name.
+func RenameColumn(files []File, table, column, to string) ([]Edit, error) {
+	tbl, err := objectName(table)
+	if err != nil {
+		return nil, err
+	}
+	col, err := newName(column)
+	if err != nil {
+		return nil, err
+	}
+	to, err = newName(to)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	cat := newCatalog(sources)
+
+	if def, known := cat.column(tbl, col); known && def == nil {
+		return nil, fmt.Errorf("refactor: %s has no column %s", table, col)
+	}
+	if !strings.EqualFold(col, to) {
+		if def, _ := cat.column(tbl, to); def != nil {
+			return nil, &CollisionError{Name: to, File: def.file, Line: def.name.Token.Line, Column: def.name.Token.Column,
+				What: "the table has a column of that name"}
+		}
+		for _, s := range sources {
+			// Where the new name is already resolved to the table, the
+			// table has the column whether or not the scripts declare it
+			if used, err := s.columns(cat, tbl, to, to); err == nil && len(used) > 0 {
+				return nil, &CollisionError{Name: to, File: used[0].File, Line: used[0].Line, Column: used[0].Column,
+					What: "a column of that name is used here"}
+			}
+		}
+	}
+
+	var edits []Edit
+	for _, s := range sources {
+		found, err := s.columns(cat, tbl, col, to)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, found...)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("refactor: no references to column %s of %s", col, table)
+	}
+	return edits, nil
+}
+
+// dateparts are the functions whose first argument is a date part, which
+// the parser reads as a name.
+var dateparts = map[string]bool{
+	"DATEADD": true, "DATEDIFF": true, "DATEDIFF_BIG": true, "DATENAME": true,
+	"DATEPART": true, "DATETRUNC": true, "DATE_BUCKET": true,
+}
+
+// columns finds the references to a column of a table.
+func (s *source) columns(cat *catalog, table []string, col, to string) ([]Edit, error) {
+	var edits []Edit
+	for _, sc := range s.scopes {
+		named := make(map[int]bool)
+		for _, ref := range sc.refs {
+			for _, p := range ref.parts {
+				named[p] = true
+			}
+		}
+		for i := sc.first; i < sc.end; i++ {
+			tok := s.tokens[i]
+			if !strings.EqualFold(tok.Literal, col) || tok.Type == token.VARIABLE || !s.isName(i) || named[i] ||
+				s.aliases[s.pos(i)] || s.is(i+1, token.DOT) || s.is(i+1, token.LPAREN) {
+				continue
+			}
+			var owner []string
+			if ref, ok := sc.owners[i]; ok {
+				if ref == nil || ref.opaque {
+					continue
+				}
+				owner = ref.name
+			} else if s.is(i-1, token.DOT) {
+				j := i - 2
+				for s.is(j-1, token.DOT) && s.isName(j-2) {
+					j -= 2
+				}
+				owner = sc.resolve(s.literals(s.name(j)[:len(s.name(j))-1]))
+			} else {
+				if !s.idents[s.pos(i)] || s.is(i-1, token.LPAREN) && i >= 2 && dateparts[strings.ToUpper(s.tokens[i-2].Literal)] {
+					continue
+				}
+				if sc.trigger != nil && s.is(i-1, token.LPAREN) && s.is(i-2, token.UPDATE) && s.is(i+1, token.RPAREN) {
+					// IF UPDATE(column) in a trigger
+					owner = sc.trigger
+				} else {
+					var err error
+					if owner, err = s.unqualified(sc, cat, i, table, col, to); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if owner == nil || !sameObject(owner, table) {
+				continue
+			}
+			if err := s.derived(sc, i); err != nil {
+				return nil, err
+			}
+			edits = append(edits, s.edit(i, to))
+		}
+	}
+
+	// COL_LENGTH('dbo.Orders', 'Amount')
+	for i := range s.tokens {
+		if strings.EqualFold(s.tokens[i].Literal, "COL_LENGTH") && s.is(i+1, token.LPAREN) && s.isString(i+2) &&
+			s.is(i+3, token.COMMA) && s.isString(i+4) {
+			if _, ok := s.stringEdit(i+2, table, to, true); !ok {
+				continue
+			}
+			if e, ok := s.stringEdit(i+4, []string{col}, to, false); ok {
+				edits = append(edits, e)
+			}
+		}
+	}
+	return sortEdits(edits), nil
+}
+
+// unqualified resolves an unqualified column at token i. The tables of the
+// innermost query come first; the column belongs to an outer query only
+// if they are all declared without it.
+func (s *source) unqualified(sc *scope, cat *catalog, i int, table []string, col, to string) ([]string, error) {
+	tok := s.tokens[i]
+	for q := sc.queryAt(i); ; q = q.parent {
+		var hit *tableRef
+		var others []*tableRef
+		for _, ref := range sc.refs {
+			if ref.query != q || ref.listed {
+				continue
+			}
+			if hit == nil && !ref.opaque && sameObject(sc.table(ref), table) {
+				hit = ref
+			} else {
+				others = append(others, ref)
+			}
+		}
+		belongs := false
+		for _, ref := range others {
+			has, known := false, false
+			if !ref.opaque {
+				def, k := cat.column(sc.table(ref), col)
+				has, known = def != nil, k
+			}
+			if has || !known {
+				if hit == nil {
+					// Another table's column, or perhaps one
+					belongs = true
+					break
+				}
+				return nil, fmt.Errorf("refactor: %s:%d:%d: cannot tell whether %s is a column of %s or of %s; qualify it",
+					s.file.Name, tok.Line, tok.Column, tok.Literal, strings.Join(table, "."), describe(ref))
+			}
+			if hit != nil {
+				if def, _ := cat.column(sc.table(ref), to); def != nil {
+					return nil, &CollisionError{Name: to, File: s.file.Name, Line: tok.Line, Column: tok.Column,
+						What: fmt.Sprintf("it would name the column of %s here", describe(ref))}
+				}
+			}
+		}
+		switch {
+		case hit != nil:
+			return sc.table(hit), nil
+		case belongs, q == nil:
+			return nil, nil
+		}
+	}
+}
+
+func describe(ref *tableRef) string {
+	switch {
+	case ref.parts != nil:
+		return strings.Join(ref.name, ".")
+	case ref.alias != "":
+		return ref.alias
+	}
+	return "a derived table"
+}
+
+// derived returns an error if the column at token i is selected without
+// an alias by a derived table or common table expression without a
+// column list, whose column would be renamed with it.
+func (s *source) derived(sc *scope, i int) error {
+	q := sc.queryAt(i)
+	if q == nil {
+		return nil
+	}
+	switch {
+	case s.is(q.start-1, token.AS) && !s.is(q.start-2, token.RPAREN):
+	case s.is(q.start-1, token.FROM), s.is(q.start-1, token.JOIN), s.is(q.start-1, token.APPLY):
+	default:
+		return nil
+	}
+	// The start of the select item, and its depth in the query
+	j := i
+	for s.is(j-1, token.DOT) && s.isName(j-2) {
+		j -= 2
+	}
+	depth := 0
+	for k := q.start + 1; k < j; k++ {
+		switch s.tokens[k].Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+		}
+	}
+	if depth != 0 || !s.is(j-1, token.SELECT) && !s.is(j-1, token.COMMA) && !s.is(j-1, token.DISTINCT) {
+		return nil
+	}
+	if !s.is(i+1, token.COMMA) && !s.is(i+1, token.FROM) && i+1 != q.end {
+		return nil
+	}
+	if s.seen(q.start, j, token.FROM) {
+		return nil
+	}
+	tok := s.tokens[i]
+	return fmt.Errorf("refactor: %s:%d:%d: %s is a column of a derived table or common table expression, which would be renamed too; give it an alias",
+		s.file.Name, tok.Line, tok.Column, tok.Literal)
+}
@@ -0,0 +1,238 @@
+package refactor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// RenameTable renames a table, which may be qualified with its schema, in
+// the scripts: where it is created, altered and dropped, in the FROM,
+// JOIN, INTO, UPDATE, DELETE, MERGE and REFERENCES clauses of statements,
+// where it qualifies a column, and in the names given to OBJECT_ID() and
+// COL_LENGTH() as strings. An unqualified name is taken to be in dbo.
+//
+// It returns a *CollisionError if the scripts create or use an object of
+// the new name in the same schema.
+func RenameTable(files []File, name, to string) ([]Edit, error) {
+	return renameObject(files, name, to, "table", (*source).tables)
+}
+
+// RenameProcedure renames a stored procedure in the scripts: where it is
+// created, altered and dropped, where it is executed, and in names given
+// to OBJECT_ID() as strings.
+//
+// It returns a *CollisionError if the scripts create or use an object of
+// the new name in the same schema.
+func RenameProcedure(files []File, name, to string) ([]Edit, error) {
+	return renameObject(files, name, to, "procedure", (*source).procedures)
+}
+
+// renameObject renames the object found by find.
+func renameObject(files []File, name, to, what string, find func(*source, []string, string) []Edit) ([]Edit, error) {
+	old, err := objectName(name)
+	if err != nil {
+		return nil, err
+	}
+	to, err = newName(to)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := append(append([]string(nil), old[:len(old)-1]...), to)
+	if !strings.EqualFold(old[len(old)-1], to) {
+		for _, obj := range newCatalog(sources).objects {
+			if sameObject(obj.name, renamed) {
+				tok := obj.src.tokens[obj.tok]
+				return nil, &CollisionError{Name: to, File: obj.src.file.Name, Line: tok.Line, Column: tok.Column,
+					What: fmt.Sprintf("a %s of that name is created here", strings.ToLower(obj.kind))}
+			}
+		}
+		for _, s := range sources {
+			if used := find(s, renamed, to); len(used) > 0 {
+				return nil, &CollisionError{Name: to, File: used[0].File, Line: used[0].Line, Column: used[0].Column,
+					What: fmt.Sprintf("a %s of that name is used here", what)}
+			}
+		}
+	}
+
+	var edits []Edit
+	for _, s := range sources {
+		edits = append(edits, find(s, old, to)...)
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("refactor: no references to %s %s", what, name)
+	}
+	return edits, nil
+}
+
+// tables finds the references to a table.
+func (s *source) tables(table []string, to string) []Edit {
+	var edits []Edit
+	for _, sc := range s.scopes {
+		named := make(map[int]bool)
+		for _, ref := range sc.refs {
+			for _, p := range ref.parts {
+				named[p] = true
+			}
+			if ref.parts != nil && !ref.opaque && sameObject(ref.name, table) {
+				edits = append(edits, s.edit(ref.parts[len(ref.parts)-1], to))
+			}
+		}
+		// Qualified columns: Orders.Id, dbo.Orders.Id
+		for i := sc.first; i < sc.end; i++ {
+			if named[i] || s.is(i-1, token.DOT) || !s.is(i+1, token.DOT) {
+				continue
+			}
+			parts := s.name(i)
+			if len(parts) < 2 || named[parts[len(parts)-1]] || s.is(parts[len(parts)-1]+1, token.LPAREN) {
+				continue
+			}
+			qualifier := parts[:len(parts)-1]
+			if s.qualifies(sc, s.literals(qualifier), table) {
+				edits = append(edits, s.edit(qualifier[len(qualifier)-1], to))
+			}
+			i = parts[len(parts)-1]
+		}
+	}
+	edits = append(edits, s.stringNames(table, to, "OBJECT_ID", "COL_LENGTH")...)
+	return sortEdits(edits)
+}
+
+// qualifies reports whether a column qualifier names the table itself
+// rather than an alias of it.
+func (s *source) qualifies(sc *scope, qualifier, table []string) bool {
+	if len(qualifier) > 1 {
+		return sameObject(qualifier, table)
+	}
+	for _, ref := range sc.refs {
+		if strings.EqualFold(ref.alias, qualifier[0]) {
+			return false
+		}
+	}
+	for _, ref := range sc.refs {
+		if ref.alias == "" && ref.parts != nil && !ref.opaque && strings.EqualFold(ref.name[len(ref.name)-1], qualifier[0]) {
+			return sameObject(ref.name, table)
+		}
+	}
+	return false
+}
+
+// procedures finds the references to a procedure.
+func (s *source) procedures(proc []string, to string) []Edit {
+	var edits []Edit
+	for i, tok := range s.tokens {
+		j := i + 1
+		switch tok.Type {
+		case token.EXEC, token.EXECUTE:
+			// EXEC @status = name
+			if s.is(j, token.VARIABLE) && s.is(j+1, token.EQ) {
+				j += 2
+			}
+		case token.PROCEDURE, token.PROC:
+			if !s.is(i-1, token.CREATE) && !s.is(i-1, token.ALTER) && !s.is(i-1, token.DROP) {
+				continue
+			}
+			if s.is(j, token.IF) && s.is(j+1, token.EXISTS) {
+				j += 2
+			}
+		default:
+			continue
+		}
+		for {
+			parts := s.name(j)
+			if parts == nil || s.is(j, token.VARIABLE) {
+				break
+			}
+			if sameObject(s.literals(parts), proc) {
+				edits = append(edits, s.edit(parts[len(parts)-1], to))
+			}
+			// DROP PROCEDURE a, b
+			j = parts[len(parts)-1] + 1
+			if !s.is(i-1, token.DROP) || !s.is(j, token.COMMA) {
+				break
+			}
+			j++
+		}
+	}
+	edits = append(edits, s.stringNames(proc, to, "OBJECT_ID")...)
+	return sortEdits(edits)
+}
+
+// stringNames finds an object named in a string passed as the first argument
+// of one of the functions, as in OBJECT_ID(N'dbo.Orders').
+func (s *source) stringNames(object []string, to string, functions ...string) []Edit {
+	var edits []Edit
+	for i := range s.tokens {
+		if !s.is(i+1, token.LPAREN) || !s.isString(i+2) {
+			continue
+		}
+		called := false
+		for _, f := range functions {
+			called = called || strings.EqualFold(s.tokens[i].Literal, f)
+		}
+		if !called {
+			continue
+		}
+		if e, ok := s.stringEdit(i+2, object, to, true); ok {
+			edits = append(edits, e)
+		}
+	}
+	return edits
+}
+
+func (s *source) isString(i int) bool {
+	return s.is(i, token.STRING) || s.is(i, token.NSTRING)
+}
+
+// stringEdit renames the last part of the name in string token i if the
+// name is object. An object name is quoted if it needs to be; a column
+// name, as COL_LENGTH() takes it, never is.
+func (s *source) stringEdit(i int, object []string, to string, quoted bool) (Edit, bool) {
+	text := s.text(i)
+	open := strings.IndexByte(text, '\'')
+	if open < 0 || len(text) < open+2 || strings.Contains(text[open+1:len(text)-1], "'") {
+		return Edit{}, false
+	}
+	content := text[open+1 : len(text)-1]
+	parts, starts, ends := splitName(content)
+	if !sameObject(parts, object) {
+		return Edit{}, false
+	}
+	last := len(parts) - 1
+	written := strings.TrimSpace(content[starts[last]:ends[last]])
+	start := s.starts[i] + open + 1 + starts[last] + strings.Index(content[starts[last]:ends[last]], written)
+	tok := s.tokens[i]
+	return Edit{
+		File:   s.file.Name,
+		Start:  start,
+		End:    start + len(written),
+		Line:   tok.Line,
+		Column: tok.Column + len([]rune(s.file.Source[s.starts[i]:start])),
+		New:    strings.ReplaceAll(nameInString(to, written, quoted), "'", "''"),
+	}, true
+}
+
+func sortEdits(edits []Edit) []Edit {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	return edits
+}
+
+func nameInString(name, written string, quoted bool) string {
+	if !quoted {
+		return name
+	}
+	switch {
+	case strings.HasPrefix(written, "[") || strings.HasPrefix(written, `"`):
+		return quote(name, written)
+	case strings.ContainsAny(name, " .[]\"'"):
+		return quote(name, "[")
+	}
+	return name
+}
@@ -0,0 +1,185 @@
+// Package refactor renames variables, tables, columns and procedures in
+// T-SQL scripts.
+//
+// A rename produces the text edits that make it, each replacing one name
+// and nothing around it, so the layout, comments and quoting of the
+// scripts are kept:
+//
+//	edits, err := refactor.RenameTable(files, "dbo.Orders", "SalesOrders")
+//	if err != nil {
+//	    return err // a collision, or a reference that cannot be resolved
+//	}
+//	for _, f := range files {
+//	    os.WriteFile(f.Name, []byte(refactor.Apply(f, edits)), 0o644)
+//	}
+//
+// References are resolved statement by statement against the tables the
+// statement names and the columns the scripts declare for them. A rename
+// that would make a name mean something else, or that cannot be proved to
+// leave other names alone, is refused with an error rather than done
+// halfway.
+package refactor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// File is a script and the name it is reported under.
+type File struct {
+	Name   string
+	Source string
+}
+
+// Edit replaces the bytes Start to End of a file with New.
+type Edit struct {
+	File   string
+	Start  int
+	End    int
+	Line   int // position of Start; the column counts runes from 1
+	Column int
+	New    string
+}
+
+// CollisionError reports a rename to a name that is already taken.
+type CollisionError struct {
+	Name   string // the new name
+	File   string
+	Line   int // where the name is declared or used
+	Column int
+	What   string // what already has the name
+}
+
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: cannot rename to %s: %s", e.File, e.Line, e.Column, e.Name, e.What)
+}
+
+// Apply returns the source of file with the edits to it made.
+func Apply(file File, edits []Edit) string {
+	var mine []Edit
+	for _, e := range edits {
+		if e.File == file.Name {
+			mine = append(mine, e)
+		}
+	}
+	sort.Slice(mine, func(i, j int) bool { return mine[i].Start < mine[j].Start })
+	var out strings.Builder
+	at := 0
+	for _, e := range mine {
+		out.WriteString(file.Source[at:e.Start])
+		out.WriteString(e.New)
+		at = e.End
+	}
+	out.WriteString(file.Source[at:])
+	return out.String()
+}
+
+// splitName splits a possibly qualified and quoted name into its parts,
+// returning the offset and length of each part as written.
+func splitName(name string) (parts []string, starts, ends []int) {
+	for i := 0; i <= len(name); {
+		start := i
+		var part strings.Builder
+		for i < len(name) && name[i] != '.' {
+			if c := name[i]; c == '[' || c == '"' {
+				close := byte(']')
+				if c == '"' {
+					close = '"'
+				}
+				for i++; i < len(name); i++ {
+					if name[i] == close {
+						if i+1 < len(name) && name[i+1] == close {
+							i++
+						} else {
+							break
+						}
+					}
+					part.WriteByte(name[i])
+				}
+			} else {
+				part.WriteByte(c)
+			}
+			i++
+		}
+		parts = append(parts, strings.TrimSpace(part.String()))
+		starts = append(starts, start)
+		ends = append(ends, min(i, len(name)))
+		i++
+	}
+	return parts, starts, ends
+}
+
+// objectName parses the name of a table or procedure given to a rename.
+func objectName(name string) ([]string, error) {
+	parts, _, _ := splitName(name)
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("refactor: invalid name %q", name)
+		}
+	}
+	return parts, nil
+}
+
+// newName checks the new name given to a rename, which is a single name
+// that may be quoted.
+func newName(name string) (string, error) {
+	parts, _, _ := splitName(name)
+	if len(parts) != 1 || parts[0] == "" {
+		return "", fmt.Errorf("refactor: new name %q must be a single name; objects are not moved between schemas", name)
+	}
+	return parts[0], nil
+}
+
+// sameObject reports whether two names, each qualified or not, name the
+// same object. An unqualified name is taken to be in dbo; the database
+// and server parts are ignored.
+func sameObject(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 || !strings.EqualFold(a[len(a)-1], b[len(b)-1]) {
+		return false
+	}
+	return strings.EqualFold(schemaOf(a), schemaOf(b))
+}
+
+func schemaOf(parts []string) string {
+	if len(parts) < 2 || parts[len(parts)-2] == "" {
+		if strings.HasPrefix(parts[len(parts)-1], "#") {
+			return ""
+		}
+		return "dbo"
+	}
+	return parts[len(parts)-2]
+}
+
+// quote writes name the way written, the text it replaces, is quoted:
+// in brackets or double quotes if written so, otherwise plain unless the
+// name needs brackets.
+func quote(name, written string) string {
+	switch {
+	case strings.HasPrefix(written, "["):
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	case strings.HasPrefix(written, `"`):
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	case !isRegular(name):
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	}
+	return name
+}
+
+// isRegular reports whether name can be written without quotes.
+func isRegular(name string) bool {
+	if name == "" || token.LookupIdent(strings.ToUpper(name)) != token.IDENT {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '#' || r == '@' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f:
+		case i > 0 && (r == '$' || r >= '0' && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
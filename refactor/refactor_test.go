@@ -0,0 +1,285 @@
+package refactor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var schema = File{Name: "schema.sql", Source: `CREATE TABLE dbo.Orders (
+    Id INT NOT NULL PRIMARY KEY,
+    CustomerId INT NOT NULL,
+    Amount DECIMAL(10,2),
+    CONSTRAINT FK_Orders_Customers FOREIGN KEY (CustomerId) REFERENCES dbo.Customers (Id)
+);
+CREATE TABLE dbo.Customers (Id INT NOT NULL, Name NVARCHAR(100), Total MONEY);
+CREATE INDEX IX_Orders_Customer ON dbo.Orders (CustomerId) INCLUDE (Amount);
+`}
+
+var procs = File{Name: "procs.sql", Source: `CREATE PROCEDURE dbo.PlaceOrder @id INT, @customer INT, @amount DECIMAL(10,2)
+AS
+BEGIN
+    IF OBJECT_ID(N'dbo.Orders') IS NULL RETURN;
+    INSERT INTO dbo.Orders (Id, CustomerId, Amount)
+    OUTPUT inserted.Amount
+    VALUES (@id, @customer, @amount);
+    UPDATE [Orders] SET Amount = Amount * 2 WHERE Id = @id;
+    SELECT o.Amount, c.Name, Orders.Id
+    FROM Orders JOIN dbo.Customers c ON c.Id = Orders.CustomerId
+    JOIN Orders o ON o.Id = Orders.Id;
+END
+GO
+EXEC dbo.PlaceOrder @id = 1, @customer = 2, @amount = 10;
+`}
+
+var more = File{Name: "more.sql", Source: `CREATE TRIGGER dbo.trg_Orders ON dbo.Orders AFTER INSERT, UPDATE AS
+BEGIN
+    IF UPDATE(Amount)
+        INSERT INTO dbo.Audit (OrderId, Amount) SELECT i.Id, i.Amount FROM inserted i;
+    SELECT Amount FROM deleted;
+END
+GO
+MERGE INTO dbo.Orders AS t
+USING (SELECT Id, Amount FROM dbo.Staging) AS s ON t.Id = s.Id
+WHEN MATCHED THEN UPDATE SET Amount = s.Amount
+WHEN NOT MATCHED THEN INSERT (Id, CustomerId, Amount) VALUES (s.Id, 0, s.Amount)
+OUTPUT deleted.Amount, inserted.Amount;
+WITH big AS (SELECT Id, Amount AS Value FROM dbo.Orders WHERE Amount > 100)
+SELECT Value FROM big;
+SELECT DATEADD(day, 1, GETDATE()), COL_LENGTH('dbo.Orders', 'Amount');
+`}
+
+func apply(files []File, edits []Edit) map[string]string {
+	out := make(map[string]string)
+	for _, f := range files {
+		out[f.Name] = Apply(f, edits)
+	}
+	return out
+}
+
+func check(t *testing.T, got, want string) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func collision(t *testing.T, err error) *CollisionError {
+	t.Helper()
+	var c *CollisionError
+	if !errors.As(err, &c) {
+		t.Fatalf("got %v, want a collision", err)
+	}
+	return c
+}
+
+func TestRenameTable(t *testing.T) {
+	files := []File{schema, procs}
+	edits, err := RenameTable(files, "dbo.Orders", "SalesOrders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := apply(files, edits)
+	check(t, out["schema.sql"], strings.NewReplacer(
+		"CREATE TABLE dbo.Orders", "CREATE TABLE dbo.SalesOrders",
+		"ON dbo.Orders", "ON dbo.SalesOrders",
+	).Replace(schema.Source))
+	check(t, out["procs.sql"], `CREATE PROCEDURE dbo.PlaceOrder @id INT, @customer INT, @amount DECIMAL(10,2)
+AS
+BEGIN
+    IF OBJECT_ID(N'dbo.SalesOrders') IS NULL RETURN;
+    INSERT INTO dbo.SalesOrders (Id, CustomerId, Amount)
+    OUTPUT inserted.Amount
+    VALUES (@id, @customer, @amount);
+    UPDATE [SalesOrders] SET Amount = Amount * 2 WHERE Id = @id;
+    SELECT o.Amount, c.Name, SalesOrders.Id
+    FROM SalesOrders JOIN dbo.Customers c ON c.Id = SalesOrders.CustomerId
+    JOIN SalesOrders o ON o.Id = SalesOrders.Id;
+END
+GO
+EXEC dbo.PlaceOrder @id = 1, @customer = 2, @amount = 10;
+`)
+}
+
+func TestRenameTableKeepsOtherNames(t *testing.T) {
+	src := File{Name: "q.sql", Source: `WITH Orders AS (SELECT Id FROM sales.Orders)
+SELECT Orders.Id FROM Orders;
+SELECT Orders FROM dbo.Customers;
+DROP TABLE IF EXISTS #Orders, Orders;
+`}
+	edits, err := RenameTable([]File{src}, "Orders", "Sales Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, Apply(src, edits), `WITH Orders AS (SELECT Id FROM sales.Orders)
+SELECT Orders.Id FROM Orders;
+SELECT Orders FROM dbo.Customers;
+DROP TABLE IF EXISTS #Orders, [Sales Orders];
+`)
+
+	_, err = RenameTable([]File{schema, procs}, "dbo.Orders", "CUSTOMERS")
+	if c := collision(t, err); c.File != "schema.sql" || c.Line != 7 {
+		t.Errorf("collision at %v", err)
+	}
+	_, err = RenameTable([]File{src}, "sales.Orders", "Customers")
+	if err != nil {
+		t.Errorf("dbo.Customers is in another schema: %v", err)
+	}
+	if _, err := RenameTable([]File{schema}, "dbo.Missing", "Other"); err == nil {
+		t.Error("renamed a table that is not there")
+	}
+}
+
+func TestRenameColumn(t *testing.T) {
+	files := []File{schema, procs, more}
+	edits, err := RenameColumn(files, "dbo.Orders", "Amount", "Price")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := apply(files, edits)
+	check(t, out["schema.sql"], strings.NewReplacer(
+		"Amount DECIMAL", "Price DECIMAL",
+		"INCLUDE (Amount)", "INCLUDE (Price)",
+	).Replace(schema.Source))
+	check(t, out["procs.sql"], strings.NewReplacer(
+		"(Id, CustomerId, Amount)", "(Id, CustomerId, Price)",
+		"inserted.Amount", "inserted.Price",
+		"SET Amount = Amount * 2", "SET Price = Price * 2",
+		"o.Amount", "o.Price",
+	).Replace(procs.Source))
+	check(t, out["more.sql"], `CREATE TRIGGER dbo.trg_Orders ON dbo.Orders AFTER INSERT, UPDATE AS
+BEGIN
+    IF UPDATE(Price)
+        INSERT INTO dbo.Audit (OrderId, Amount) SELECT i.Id, i.Price FROM inserted i;
+    SELECT Price FROM deleted;
+END
+GO
+MERGE INTO dbo.Orders AS t
+USING (SELECT Id, Amount FROM dbo.Staging) AS s ON t.Id = s.Id
+WHEN MATCHED THEN UPDATE SET Price = s.Amount
+WHEN NOT MATCHED THEN INSERT (Id, CustomerId, Price) VALUES (s.Id, 0, s.Amount)
+OUTPUT deleted.Price, inserted.Price;
+WITH big AS (SELECT Id, Price AS Value FROM dbo.Orders WHERE Price > 100)
+SELECT Value FROM big;
+SELECT DATEADD(day, 1, GETDATE()), COL_LENGTH('dbo.Orders', 'Price');
+`)
+}
+
+func TestRenameColumnScopes(t *testing.T) {
+	src := File{Name: "q.sql", Source: `SELECT Name FROM dbo.Customers c
+WHERE EXISTS (SELECT 1 FROM dbo.Orders WHERE CustomerId = c.Id AND Amount > 0)
+  AND Id IN (SELECT CustomerId FROM dbo.Orders);
+SELECT CustomerId FROM #work;
+`}
+	edits, err := RenameColumn([]File{schema, src}, "Orders", "CustomerId", "BuyerId")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, Apply(src, edits), `SELECT Name FROM dbo.Customers c
+WHERE EXISTS (SELECT 1 FROM dbo.Orders WHERE BuyerId = c.Id AND Amount > 0)
+  AND Id IN (SELECT BuyerId FROM dbo.Orders);
+SELECT CustomerId FROM #work;
+`)
+}
+
+func TestRenameColumnRefusals(t *testing.T) {
+	for _, tt := range []struct {
+		src, want string
+	}{
+		{"SELECT Amount FROM dbo.Orders o JOIN dbo.Elsewhere e ON e.Id = o.Id", "qualify it"},
+		{"SELECT x.Amount FROM (SELECT Amount FROM dbo.Orders) x", "give it an alias"},
+		{"WITH t AS (SELECT Id, Amount FROM dbo.Orders) SELECT Amount FROM t", "give it an alias"},
+	} {
+		src := File{Name: "q.sql", Source: tt.src}
+		_, err := RenameColumn([]File{schema, src}, "dbo.Orders", "Amount", "Price")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.src, err, tt.want)
+		}
+	}
+
+	// A column of the same name in the table, or in a table joined to it
+	if c := collision(t, func() error {
+		_, err := RenameColumn([]File{schema}, "dbo.Orders", "Amount", "customerid")
+		return err
+	}()); c.Line != 3 {
+		t.Errorf("collision at %v", c)
+	}
+	src := File{Name: "q.sql", Source: "SELECT Amount, c.Id FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId"}
+	_, err := RenameColumn([]File{schema, src}, "dbo.Orders", "Amount", "Total")
+	if c := collision(t, err); c.File != "q.sql" || c.Column != 8 {
+		t.Errorf("collision at %v", c)
+	}
+	if _, err := RenameColumn([]File{schema}, "dbo.Orders", "Missing", "Other"); err == nil {
+		t.Error("renamed a column that is not declared")
+	}
+}
+
+func TestRenameProcedure(t *testing.T) {
+	src := File{Name: "deploy.sql", Source: `IF OBJECT_ID('dbo.PlaceOrder', 'P') IS NOT NULL DROP PROCEDURE dbo.PlaceOrder;
+GO
+DECLARE @rc INT;
+EXEC @rc = PlaceOrder 1, 2, 3;
+EXECUTE [dbo].[PlaceOrder] @id = 4, @customer = 5, @amount = 6;
+`}
+	files := []File{procs, src}
+	edits, err := RenameProcedure(files, "dbo.PlaceOrder", "SubmitOrder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := apply(files, edits)
+	check(t, out["procs.sql"], strings.ReplaceAll(procs.Source, "PlaceOrder", "SubmitOrder"))
+	check(t, out["deploy.sql"], `IF OBJECT_ID('dbo.SubmitOrder', 'P') IS NOT NULL DROP PROCEDURE dbo.SubmitOrder;
+GO
+DECLARE @rc INT;
+EXEC @rc = SubmitOrder 1, 2, 3;
+EXECUTE [dbo].[SubmitOrder] @id = 4, @customer = 5, @amount = 6;
+`)
+
+	other := File{Name: "other.sql", Source: "EXEC dbo.SubmitOrder 1, 2, 3"}
+	_, err = RenameProcedure([]File{procs, other}, "dbo.PlaceOrder", "SubmitOrder")
+	if c := collision(t, err); c.File != "other.sql" {
+		t.Errorf("collision at %v", c)
+	}
+}
+
+func TestRenameVariable(t *testing.T) {
+	src := File{Name: "batch.sql", Source: `DECLARE @total MONEY = 0;
+GO
+DECLARE @Total MONEY, @n INT;
+SELECT @total = SUM(Amount) FROM dbo.Orders;
+EXEC dbo.Report @total = @total, @count = @n;
+GO
+PRINT @total;
+`}
+	offset := strings.Index(src.Source, "SELECT @total") + len("SELECT @to")
+	edits, err := RenameVariable(src, offset, "@sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, Apply(src, edits), `DECLARE @total MONEY = 0;
+GO
+DECLARE @sum MONEY, @n INT;
+SELECT @sum = SUM(Amount) FROM dbo.Orders;
+EXEC dbo.Report @total = @sum, @count = @n;
+GO
+PRINT @total;
+`)
+
+	_, err = RenameVariable(src, offset, "@N")
+	if c := collision(t, err); c.Line != 3 {
+		t.Errorf("collision at %v", c)
+	}
+	if _, err := RenameVariable(src, offset, "@count"); err != nil {
+		t.Errorf("@count is only a parameter of the procedure called: %v", err)
+	}
+	if _, err := RenameVariable(src, 0, "@x"); err == nil {
+		t.Error("renamed a keyword")
+	}
+}
+
+func TestUnparsedScript(t *testing.T) {
+	src := File{Name: "broken.sql", Source: "SELECT FROM WHERE ((("}
+	if _, err := RenameTable([]File{schema, src}, "dbo.Orders", "Sales"); err == nil || !strings.Contains(err.Error(), "broken.sql") {
+		t.Errorf("got %v", err)
+	}
+}
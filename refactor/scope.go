@@ -0,0 +1,476 @@
+package refactor
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// scope is a statement: the names in it resolve against the tables it
+// names. The statements of blocks, procedures, functions and triggers are
+// scopes of their own; subqueries belong to their statement.
+type scope struct {
+	first, end int        // its tokens
+	kind       token.Type // SELECT, INSERT, UPDATE, DELETE, MERGE, EXEC, CREATE...
+	refs       []*tableRef
+	ctes       map[string]bool
+	target     *tableRef         // the table an INSERT, UPDATE, DELETE or MERGE writes
+	trigger    []string          // the table of the trigger the statement is in
+	owners     map[int]*tableRef // columns named in column lists and SET, by token
+	queries    []*query          // subqueries, outermost first
+}
+
+// query is a parenthesized SELECT within a statement. Its tables hide
+// those of the queries around it.
+type query struct {
+	start, end int // its parentheses
+	parent     *query
+}
+
+// tableRef is a table a statement names.
+type tableRef struct {
+	parts   []int // tokens of the name; nil for a derived table
+	name    []string
+	alias   string
+	opaque  bool // a derived table, function or CTE, whose columns are unknown
+	listed  bool // INSERT INTO t (...) and REFERENCES t (...): its columns are not in scope
+	context token.Type
+	query   *query // nil for the statement itself
+}
+
+// statement is where a statement starts, and the trigger it is in.
+type statement struct {
+	offset  int
+	trigger []string
+}
+
+// statements divides the tokens into scopes, one for each statement of
+// the tree.
+func (s *source) statements() {
+	var stmts []statement
+	var walk func(stmt ast.Statement, trigger []string)
+	walk = func(stmt ast.Statement, trigger []string) {
+		v := reflect.ValueOf(stmt)
+		if !v.IsValid() || v.Kind() == reflect.Pointer && v.IsNil() {
+			return
+		}
+		if f := v.Elem().FieldByName("Token"); f.IsValid() && f.Type() == tokenType {
+			if tok := f.Interface().(token.Token); tok.Line > 0 {
+				i := sort.Search(len(s.tokens), func(i int) bool {
+					return s.tokens[i].Line > tok.Line || s.tokens[i].Line == tok.Line && s.tokens[i].Column >= tok.Column
+				})
+				// Some statements start at the kind of object: DROP TABLE
+				for i > 0 && i < len(s.tokens) && (s.is(i-1, token.CREATE) || s.is(i-1, token.ALTER) || s.is(i-1, token.DROP) || s.is(i-1, token.OR)) {
+					i--
+				}
+				if i < len(s.tokens) {
+					stmts = append(stmts, statement{offset: s.starts[i], trigger: trigger})
+				}
+			}
+		}
+		block := func(b *ast.BeginEndBlock) {
+			if b != nil {
+				walk(b, trigger)
+			}
+		}
+		switch n := stmt.(type) {
+		case *ast.BeginEndBlock:
+			for _, child := range n.Statements {
+				walk(child, trigger)
+			}
+		case *ast.IfStatement:
+			walk(n.Consequence, trigger)
+			walk(n.Alternative, trigger)
+		case *ast.WhileStatement:
+			walk(n.Body, trigger)
+		case *ast.TryCatchStatement:
+			block(n.TryBlock)
+			block(n.CatchBlock)
+		case *ast.CreateProcedureStatement:
+			block(n.Body)
+		case *ast.AlterProcedureStatement:
+			block(n.Body)
+		case *ast.CreateFunctionStatement:
+			block(n.Body)
+		case *ast.AlterFunctionStatement:
+			block(n.Body)
+		case *ast.CreateTriggerStatement:
+			if n.Body != nil && n.Table != nil {
+				walk(n.Body, identifierValues(n.Table))
+			}
+		case *ast.AlterTriggerStatement:
+			if n.Body != nil && n.Table != nil {
+				walk(n.Body, identifierValues(n.Table))
+			}
+		}
+	}
+	for _, stmt := range s.program.Statements {
+		walk(stmt, nil)
+	}
+	sort.SliceStable(stmts, func(i, j int) bool { return stmts[i].offset < stmts[j].offset })
+
+	first := 0
+	var trigger []string
+	for _, stmt := range stmts {
+		end := s.tokenAt(stmt.offset)
+		if end > first {
+			s.scopes = append(s.scopes, s.scope(first, end, trigger))
+		}
+		first, trigger = end, stmt.trigger
+	}
+	if first < len(s.tokens) {
+		s.scopes = append(s.scopes, s.scope(first, len(s.tokens), trigger))
+	}
+}
+
+func identifierValues(q *ast.QualifiedIdentifier) []string {
+	var out []string
+	for _, p := range q.Parts {
+		out = append(out, p.Value)
+	}
+	return out
+}
+
+// scope finds the tables named by the tokens first to end.
+func (s *source) scope(first, end int, trigger []string) *scope {
+	sc := &scope{
+		first:   first,
+		end:     end,
+		kind:    s.tokens[first].Type,
+		ctes:    make(map[string]bool),
+		trigger: trigger,
+		owners:  make(map[int]*tableRef),
+	}
+	var parens []token.Type // the token before each open parenthesis
+	var index *tableRef     // the table of CREATE INDEX
+	sawOn, inSet := false, false
+	setDepth := 0
+	var current *query
+	for i := first; i < end; i++ {
+		tok := s.tokens[i]
+		for current != nil && i > current.end {
+			current = current.parent
+		}
+		prev := token.Type(token.ILLEGAL)
+		if i > first {
+			prev = s.tokens[i-1].Type
+		}
+		switch tok.Type {
+		case token.LPAREN:
+			parens = append(parens, prev)
+			if s.is(i+1, token.SELECT) {
+				current = &query{start: i, end: s.closing(i), parent: current}
+				sc.queries = append(sc.queries, current)
+			}
+			continue
+		case token.RPAREN:
+			if len(parens) > 0 {
+				parens = parens[:len(parens)-1]
+			}
+			continue
+		case token.SELECT, token.INSERT, token.UPDATE, token.DELETE, token.MERGE:
+			if sc.kind == token.WITH && len(parens) == 0 {
+				sc.kind = tok.Type
+			}
+		case token.SET:
+			inSet, setDepth = true, len(parens)
+		case token.FROM, token.WHERE, token.OUTPUT, token.WHEN, token.OPTION, token.SEMICOLON:
+			if len(parens) == setDepth {
+				inSet = false
+			}
+		case token.COMMA:
+		case token.IDENT:
+			if inSet && len(parens) == setDepth && (prev == token.SET || prev == token.COMMA) && isAssignment(s.tokens[min(i+1, end-1)].Type) {
+				// The column assigned belongs to the table written; resolved
+				// once the target is known
+				sc.owners[i] = nil
+			}
+		}
+
+		var context token.Type
+		switch tok.Type {
+		case token.FROM:
+			if prev == token.DISTINCT || len(parens) > 0 && strings.EqualFold(s.tokens[s.openingBefore(i)].Literal, "TRIM") {
+				continue
+			}
+			context = token.FROM
+		case token.JOIN, token.INTO, token.USING, token.REFERENCES, token.APPLY:
+			context = tok.Type
+		case token.TABLE:
+			if s.is(i+1, token.IF) && s.is(i+2, token.EXISTS) {
+				i += 2
+			}
+			context = token.TABLE
+		case token.UPDATE, token.DELETE, token.INSERT, token.MERGE:
+			if prev == token.THEN && tok.Type == token.INSERT && s.is(i+1, token.LPAREN) {
+				// MERGE ... WHEN NOT MATCHED THEN INSERT (columns)
+				s.columnList(sc, i+1, nil)
+				continue
+			}
+			if tok.Type != sc.kind || len(parens) > 0 || i != first && s.tokens[first].Type != token.WITH {
+				// The events of a trigger, UPDATE(column), ON DELETE CASCADE
+				continue
+			}
+			context = tok.Type
+		case token.ON:
+			if sawOn || (sc.kind != token.CREATE && sc.kind != token.ALTER && sc.kind != token.DROP) || !s.seen(first, i, token.INDEX, token.TRIGGER) {
+				continue
+			}
+			sawOn = true
+			context = token.ON
+		case token.INCLUDE:
+			if index != nil && s.is(i+1, token.LPAREN) {
+				s.columnList(sc, i+1, index)
+			}
+			continue
+		default:
+			continue
+		}
+
+		for {
+			ref, next := s.tableRef(sc, i+1, end, context)
+			if ref == nil {
+				break
+			}
+			ref.query = current
+			sc.refs = append(sc.refs, ref)
+			if context == token.ON {
+				index = ref
+			}
+			i = next
+			if context != token.FROM && !(context == token.TABLE && sc.kind == token.DROP) || !s.is(i+1, token.COMMA) {
+				break
+			}
+			i++
+		}
+	}
+	s.ctes(sc)
+	s.target(sc)
+	for i, owner := range sc.owners {
+		if owner == nil {
+			sc.owners[i] = sc.target
+		}
+	}
+	return sc
+}
+
+// openingBefore returns the index of the token before the innermost open
+// parenthesis around token i.
+func (s *source) openingBefore(i int) int {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch s.tokens[j].Type {
+		case token.RPAREN:
+			depth++
+		case token.LPAREN:
+			if depth == 0 {
+				return max(j-1, 0)
+			}
+			depth--
+		}
+	}
+	return 0
+}
+
+// seen reports whether a token of one of the types occurs from first to i.
+func (s *source) seen(first, i int, types ...token.Type) bool {
+	for j := first; j < i; j++ {
+		for _, t := range types {
+			if s.tokens[j].Type == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isAssignment(t token.Type) bool {
+	switch t {
+	case token.EQ, token.PLUSEQ, token.MINUSEQ, token.MULEQ, token.DIVEQ, token.MODEQ, token.ANDEQ, token.OREQ, token.XOREQ:
+		return true
+	}
+	return false
+}
+
+// tableRef reads a table reference at token i: a name, a function call or
+// a derived table, and an alias. It returns nil if there is none, and the
+// index of the last token read.
+func (s *source) tableRef(sc *scope, i, end int, context token.Type) (*tableRef, int) {
+	if i >= end {
+		return nil, i - 1
+	}
+	ref := &tableRef{context: context}
+	switch {
+	case s.is(i, token.LPAREN):
+		if context != token.FROM && context != token.JOIN && context != token.APPLY && context != token.USING {
+			return nil, i - 1
+		}
+		// A derived table: its alias is read here, and its query is
+		// scanned with the rest of the statement
+		ref.opaque = true
+		if j := s.closing(i); s.is(j+1, token.AS) && s.aliases[s.pos(min(j+2, len(s.tokens)-1))] {
+			ref.alias = s.tokens[j+2].Literal
+		} else if j+1 < end && s.aliases[s.pos(j+1)] {
+			ref.alias = s.tokens[j+1].Literal
+		}
+		return ref, i - 1
+	case s.isName(i):
+		ref.parts = s.name(i)
+		ref.name = s.literals(ref.parts)
+		i = ref.parts[len(ref.parts)-1]
+		if s.is(i+1, token.LPAREN) {
+			switch context {
+			case token.INTO, token.INSERT, token.REFERENCES, token.ON:
+				ref.listed = context != token.ON
+				s.columnList(sc, i+1, ref)
+				return ref, i
+			case token.TABLE:
+				return ref, i
+			}
+			ref.opaque = true
+			i = s.closing(i + 1)
+		}
+		if context == token.INTO || context == token.INSERT {
+			ref.listed = true
+		}
+	default:
+		return nil, i - 1
+	}
+	if s.is(i+1, token.AS) && i+2 < end && s.aliases[s.pos(i+2)] {
+		i++
+	}
+	if i+1 < end && s.aliases[s.pos(i+1)] {
+		i++
+		ref.alias = s.tokens[i].Literal
+	}
+	return ref, i
+}
+
+// columnList records the names in the parenthesis at token i as columns
+// of ref, or of the statement's target if ref is nil.
+func (s *source) columnList(sc *scope, i int, ref *tableRef) {
+	end := s.closing(i)
+	for j := i + 1; j < end; j++ {
+		if s.isName(j) && (s.is(j-1, token.LPAREN) || s.is(j-1, token.COMMA)) {
+			sc.owners[j] = ref
+		}
+	}
+}
+
+// ctes finds the common table expressions a statement defines. Tables of
+// the same name are the CTE within the statement.
+func (s *source) ctes(sc *scope) {
+	for i := sc.first; i < sc.end; i++ {
+		if !s.is(i, token.WITH) && !(s.is(i, token.COMMA) && len(sc.ctes) > 0 && s.is(i-1, token.RPAREN)) {
+			continue
+		}
+		j := i + 1
+		if !s.is(j, token.IDENT) {
+			continue
+		}
+		k := j + 1
+		if s.is(k, token.LPAREN) {
+			k = s.closing(k) + 1
+		}
+		if s.is(k, token.AS) && s.is(k+1, token.LPAREN) {
+			sc.ctes[strings.ToLower(s.tokens[j].Literal)] = true
+		}
+	}
+	for _, ref := range sc.refs {
+		if len(ref.name) == 1 && sc.ctes[strings.ToLower(ref.name[0])] {
+			ref.opaque = true
+		}
+	}
+}
+
+// target finds the table an INSERT, UPDATE, DELETE or MERGE writes. UPDATE
+// and DELETE may name it by an alias defined in their FROM clause.
+func (s *source) target(sc *scope) {
+	switch sc.kind {
+	case token.INSERT, token.UPDATE, token.DELETE, token.MERGE:
+	default:
+		return
+	}
+	for _, ref := range sc.refs {
+		if ref.context == sc.kind || ref.context == token.INTO || sc.kind == token.DELETE && ref.context == token.FROM {
+			sc.target = ref
+			break
+		}
+	}
+	if sc.target == nil || len(sc.target.name) != 1 {
+		return
+	}
+	for _, ref := range sc.refs {
+		if ref != sc.target && strings.EqualFold(ref.alias, sc.target.name[0]) {
+			// UPDATE o SET ... FROM Orders o: o is not a table of its own
+			for j, r := range sc.refs {
+				if r == sc.target {
+					sc.refs = append(sc.refs[:j], sc.refs[j+1:]...)
+					break
+				}
+			}
+			sc.target = ref
+			return
+		}
+	}
+}
+
+// resolve returns the table a qualifier names in the statement: an
+// alias, a table named without one, or inserted or deleted. It returns
+// nil for a derived table or a qualifier the statement does not define.
+func (sc *scope) resolve(qualifier []string) []string {
+	if len(qualifier) > 1 {
+		return qualifier
+	}
+	q := qualifier[0]
+	for _, ref := range sc.refs {
+		if strings.EqualFold(ref.alias, q) {
+			if ref.opaque {
+				return nil
+			}
+			return sc.table(ref)
+		}
+	}
+	if isPseudoTable(q) {
+		if sc.trigger != nil {
+			return sc.trigger
+		}
+		if sc.target != nil && !sc.target.opaque {
+			return sc.target.name
+		}
+		return nil
+	}
+	for _, ref := range sc.refs {
+		if ref.alias == "" && !ref.opaque && strings.EqualFold(ref.name[len(ref.name)-1], q) {
+			return sc.table(ref)
+		}
+	}
+	return nil
+}
+
+// table returns the name of the table ref names. In a trigger, inserted
+// and deleted are the trigger's table.
+func (sc *scope) table(ref *tableRef) []string {
+	if len(ref.name) == 1 && sc.trigger != nil && isPseudoTable(ref.name[0]) {
+		return sc.trigger
+	}
+	return ref.name
+}
+
+func isPseudoTable(name string) bool {
+	return strings.EqualFold(name, "inserted") || strings.EqualFold(name, "deleted")
+}
+
+// queryAt returns the innermost query around token i, or nil.
+func (sc *scope) queryAt(i int) *query {
+	var in *query
+	for _, q := range sc.queries {
+		if q.start < i && i < q.end {
+			in = q
+		}
+	}
+	return in
+}
@@ -0,0 +1,237 @@
+package refactor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// source is a parsed script: its tokens, what the parser made of them and
+// the statements they belong to.
+type source struct {
+	file    File
+	program *ast.Program
+	tokens  []token.Token // without comments and EOF
+	starts  []int         // byte offset of each token
+	ends    []int
+
+	idents  map[position]bool // tokens the parser read as identifiers
+	aliases map[position]bool // identifiers that are aliases
+	scopes  []*scope
+}
+
+type position struct{ line, col int }
+
+// load parses the files. A file that does not parse cannot be renamed in
+// safely, so it is an error.
+func load(files []File) ([]*source, error) {
+	var sources []*source
+	for _, f := range files {
+		program, errs := tsqlparser.Parse(f.Source)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("refactor: %s: %s", f.Name, errs[0])
+		}
+		s := &source{
+			file:    f,
+			program: program,
+			idents:  make(map[position]bool),
+			aliases: make(map[position]bool),
+		}
+		for _, tok := range tsqlparser.Tokenize(f.Source) {
+			if tok.Type != token.COMMENT && tok.Type != token.EOF {
+				s.tokens = append(s.tokens, tok)
+			}
+		}
+		s.spans()
+		s.identifiers(reflect.ValueOf(program), false)
+		s.statements()
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+func (s *source) pos(i int) position {
+	return position{s.tokens[i].Line, s.tokens[i].Column}
+}
+
+// text returns the source text of token i.
+func (s *source) text(i int) string {
+	return s.file.Source[s.starts[i]:s.ends[i]]
+}
+
+// is reports whether token i has type t; out of range tokens have none.
+func (s *source) is(i int, t token.Type) bool {
+	return i >= 0 && i < len(s.tokens) && s.tokens[i].Type == t
+}
+
+// isName reports whether token i can be part of a name: an identifier, a
+// variable naming a table, or a keyword the parser took for a name.
+func (s *source) isName(i int) bool {
+	if i < 0 || i >= len(s.tokens) {
+		return false
+	}
+	switch s.tokens[i].Type {
+	case token.IDENT, token.VARIABLE:
+		return true
+	}
+	return s.idents[s.pos(i)]
+}
+
+// name reads a dotted name starting at token i and returns the indexes of
+// its parts.
+func (s *source) name(i int) []int {
+	if !s.isName(i) {
+		return nil
+	}
+	parts := []int{i}
+	for s.is(i+1, token.DOT) && s.isName(i+2) {
+		i += 2
+		parts = append(parts, i)
+	}
+	return parts
+}
+
+func (s *source) literals(parts []int) []string {
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		out[i] = s.tokens[p].Literal
+	}
+	return out
+}
+
+// closing returns the index of the parenthesis that closes token i.
+func (s *source) closing(i int) int {
+	depth := 0
+	for ; i < len(s.tokens); i++ {
+		switch s.tokens[i].Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+// edit replaces the name written as token i.
+func (s *source) edit(i int, name string) Edit {
+	tok := s.tokens[i]
+	return Edit{
+		File:   s.file.Name,
+		Start:  s.starts[i],
+		End:    s.ends[i],
+		Line:   tok.Line,
+		Column: tok.Column,
+		New:    quote(name, s.text(i)),
+	}
+}
+
+// spans finds the bytes of each token. The lexer gives the line and the
+// column in runes; the end follows from the quoting of the token.
+func (s *source) spans() {
+	src := s.file.Source
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	s.starts = make([]int, len(s.tokens))
+	s.ends = make([]int, len(s.tokens))
+	for i, tok := range s.tokens {
+		off := lines[min(max(tok.Line, 1), len(lines))-1]
+		for n := 1; n < tok.Column && off < len(src); n++ {
+			_, size := utf8.DecodeRuneInString(src[off:])
+			off += size
+		}
+		s.starts[i] = off
+		s.ends[i] = min(off+tokenLength(src[off:], tok), len(src))
+	}
+}
+
+// tokenLength returns the length in bytes of tok at the start of src.
+func tokenLength(src string, tok token.Token) int {
+	switch {
+	case src == "":
+		return 0
+	case src[0] == '[':
+		return quoted(src, ']')
+	case src[0] == '"' && tok.Type == token.IDENT:
+		return quoted(src, '"')
+	case tok.Type == token.STRING || tok.Type == token.NSTRING:
+		if src[0] == 'N' || src[0] == 'n' {
+			return 1 + quoted(src[1:], '\'')
+		}
+		return quoted(src, '\'')
+	}
+	return len(tok.Literal)
+}
+
+// quoted returns the length of the quoted text at the start of src, which
+// ends with close; a doubled close is part of the text.
+func quoted(src string, close byte) int {
+	for i := 1; i < len(src); i++ {
+		if src[i] == close {
+			if i+1 < len(src) && src[i+1] == close {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+var (
+	identifierType = reflect.TypeOf((*ast.Identifier)(nil))
+	tokenType      = reflect.TypeOf(token.Token{})
+)
+
+// identifiers records the positions of the identifiers of the tree, and
+// which of them are aliases.
+func (s *source) identifiers(v reflect.Value, alias bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			s.identifiers(v.Elem(), alias)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if v.Type() == identifierType {
+			tok := v.Interface().(*ast.Identifier).Token
+			s.idents[position{tok.Line, tok.Column}] = true
+			if alias {
+				s.aliases[position{tok.Line, tok.Column}] = true
+			}
+			return
+		}
+		s.identifiers(v.Elem(), false)
+	case reflect.Struct:
+		if v.Type() == tokenType {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			s.identifiers(v.Field(i), strings.HasSuffix(v.Type().Field(i).Name, "Alias"))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.identifiers(v.Index(i), false)
+		}
+	}
+}
+
+// tokenAt returns the index of the first token at or after byte offset.
+func (s *source) tokenAt(offset int) int {
+	return sort.SearchInts(s.starts, offset)
+}
@@ -0,0 +1,87 @@
+package refactor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// RenameVariable renames the variable or parameter at offset, a byte
+// offset into the file, throughout its batch. The names of the parameters
+// of a procedure called with EXEC @name = value are not the variable and
+// are left alone; so are callers in other batches that pass a renamed
+// parameter of the batch's procedure by name.
+//
+// It returns a *CollisionError if the batch already uses the new name.
+func RenameVariable(file File, offset int, to string) ([]Edit, error) {
+	if !strings.HasPrefix(to, "@") || strings.HasPrefix(to, "@@") || !isRegular(to) {
+		return nil, fmt.Errorf("refactor: invalid variable name %q", to)
+	}
+	sources, err := load([]File{file})
+	if err != nil {
+		return nil, err
+	}
+	s := sources[0]
+
+	at := -1
+	for i := max(s.tokenAt(offset)-1, 0); i < len(s.tokens) && s.starts[i] <= offset; i++ {
+		if s.tokens[i].Type == token.VARIABLE && offset <= s.ends[i] {
+			at = i
+		}
+	}
+	if at < 0 {
+		return nil, fmt.Errorf("refactor: %s: no variable at offset %d", file.Name, offset)
+	}
+	old := s.tokens[at].Literal
+
+	first, end := 0, len(s.tokens)
+	for i, tok := range s.tokens {
+		if tok.Type != token.GO {
+			continue
+		}
+		if i < at {
+			first = i + 1
+		} else {
+			end = i
+			break
+		}
+	}
+
+	var edits []Edit
+	for i := first; i < end; i++ {
+		tok := s.tokens[i]
+		if tok.Type != token.VARIABLE || s.parameterName(i) {
+			continue
+		}
+		switch {
+		case strings.EqualFold(tok.Literal, to) && !strings.EqualFold(old, to):
+			return nil, &CollisionError{Name: to, File: file.Name, Line: tok.Line, Column: tok.Column,
+				What: "the batch uses a variable of that name"}
+		case strings.EqualFold(tok.Literal, old):
+			edits = append(edits, Edit{
+				File:   file.Name,
+				Start:  s.starts[i],
+				End:    s.ends[i],
+				Line:   tok.Line,
+				Column: tok.Column,
+				New:    to,
+			})
+		}
+	}
+	return edits, nil
+}
+
+// parameterName reports whether variable token i names a parameter of a
+// procedure being executed, as @id in EXEC p @id = @value.
+func (s *source) parameterName(i int) bool {
+	if !s.is(i+1, token.EQ) || !s.is(i-1, token.COMMA) && !s.isName(i-1) {
+		return false
+	}
+	for _, sc := range s.scopes {
+		if sc.first <= i && i < sc.end {
+			return s.seen(sc.first, i, token.EXEC, token.EXECUTE)
+		}
+	}
+	return false
+}
@@ -0,0 +1,567 @@
+package schemadiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Action is the kind of change made to an object.
+type Action int
+
+const (
+	ActionCreate Action = iota
+	ActionDrop
+	ActionAlter
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionCreate:
+		return "CREATE"
+	case ActionDrop:
+		return "DROP"
+	case ActionAlter:
+		return "ALTER"
+	}
+	return "UNKNOWN"
+}
+
+// ObjectType identifies the kind of object a Change applies to.
+type ObjectType int
+
+const (
+	ObjectTable ObjectType = iota
+	ObjectColumn
+	ObjectConstraint
+	ObjectIndex
+	ObjectView
+	ObjectFunction
+	ObjectProcedure
+)
+
+func (o ObjectType) String() string {
+	switch o {
+	case ObjectTable:
+		return "TABLE"
+	case ObjectColumn:
+		return "COLUMN"
+	case ObjectConstraint:
+		return "CONSTRAINT"
+	case ObjectIndex:
+		return "INDEX"
+	case ObjectView:
+		return "VIEW"
+	case ObjectFunction:
+		return "FUNCTION"
+	case ObjectProcedure:
+		return "PROCEDURE"
+	}
+	return "UNKNOWN"
+}
+
+// Change is a single difference between two schemas.
+type Change struct {
+	Action  Action
+	Object  ObjectType
+	Table   string   // Owning table for columns, constraints and indexes
+	Name    string   // Object name; for tables and modules the qualified name
+	Details []string // What changed, e.g. "type INT -> BIGINT"
+	Warning string   // Set when the change can lose data or fail on existing data
+
+	table                        *Table
+	oldColumn, newColumn         *ast.ColumnDefinition
+	oldConstraint, newConstraint *ast.TableConstraint
+	oldIndex, newIndex           *ast.CreateIndexStatement
+	oldModule, newModule         *Module
+}
+
+func (c *Change) String() string {
+	var out strings.Builder
+	out.WriteString(c.Action.String())
+	out.WriteString(" ")
+	out.WriteString(c.Object.String())
+	out.WriteString(" ")
+	if c.Table != "" {
+		out.WriteString(c.Table)
+		out.WriteString(".")
+	}
+	out.WriteString(c.Name)
+	if len(c.Details) > 0 {
+		out.WriteString(": ")
+		out.WriteString(strings.Join(c.Details, "; "))
+	}
+	return out.String()
+}
+
+// Diff is the ordered list of changes that turn one schema into another.
+type Diff struct {
+	Changes []*Change
+}
+
+// Empty reports whether the two schemas are equivalent.
+func (d *Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Warnings returns a description of every change that can lose data.
+func (d *Diff) Warnings() []string {
+	var warnings []string
+	for _, c := range d.Changes {
+		if c.Warning != "" {
+			warnings = append(warnings, c.Warning)
+		}
+	}
+	return warnings
+}
+
+func (d *Diff) String() string {
+	var out strings.Builder
+	for _, c := range d.Changes {
+		out.WriteString(c.String())
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// Compare computes the changes needed to turn before into after.
+func Compare(before, after *Schema) *Diff {
+	d := &Diff{}
+	d.compareTables(before.Tables, after.Tables)
+	d.compareModules(ObjectFunction, before.Functions, after.Functions)
+	d.compareModules(ObjectView, before.Views, after.Views)
+	d.compareModules(ObjectProcedure, before.Procedures, after.Procedures)
+	return d
+}
+
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *Diff) add(c *Change) {
+	d.Changes = append(d.Changes, c)
+}
+
+func (d *Diff) compareTables(before, after map[string]*Table) {
+	retyped := make(map[string]map[string]bool)
+	for _, k := range sortedKeys(before, after) {
+		old, new := before[k], after[k]
+		switch {
+		case new == nil:
+			name := old.Name.String()
+			d.add(&Change{
+				Action:  ActionDrop,
+				Object:  ObjectTable,
+				Name:    name,
+				Warning: "dropping table " + name + " deletes all of its data",
+				table:   old,
+			})
+		case old == nil:
+			d.add(&Change{Action: ActionCreate, Object: ObjectTable, Name: new.Name.String(), table: new})
+			for _, c := range new.Constraints {
+				if c.Type == ast.ConstraintForeignKey {
+					d.add(&Change{Action: ActionCreate, Object: ObjectConstraint, Table: new.Name.String(),
+						Name: constraintName(c), table: new, newConstraint: c})
+				}
+			}
+			for _, ix := range new.Indexes {
+				d.add(&Change{Action: ActionCreate, Object: ObjectIndex, Table: new.Name.String(),
+					Name: ix.Name.Value, table: new, newIndex: ix})
+			}
+		default:
+			retyped[k] = d.compareColumns(old, new)
+			d.compareConstraints(old, new)
+			d.compareIndexes(old, new)
+		}
+	}
+	d.rebuildDependents(after, retyped)
+}
+
+// rebuildDependents recreates unchanged constraints and indexes that use
+// a column whose type changes, since SQL Server refuses to alter a column
+// that a key or index depends on.
+func (d *Diff) rebuildDependents(tables map[string]*Table, retyped map[string]map[string]bool) {
+	changed := make(map[any]bool)
+	for _, c := range d.Changes {
+		if c.oldConstraint != nil {
+			changed[c.oldConstraint] = true
+		}
+		if c.oldIndex != nil {
+			changed[c.oldIndex] = true
+		}
+	}
+	uses := func(table string, cols []*ast.IndexColumn) bool {
+		for _, col := range cols {
+			if retyped[table][strings.ToLower(col.Name.Value)] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, k := range sortedKeys(retyped) {
+		t := tables[k]
+		name := t.Name.String()
+		for _, con := range t.Constraints {
+			dependent := uses(k, con.Columns)
+			if con.Type == ast.ConstraintForeignKey {
+				for _, ref := range con.ReferencesColumns {
+					if retyped[key(con.ReferencesTable)][strings.ToLower(ref.Value)] {
+						dependent = true
+					}
+				}
+			}
+			if dependent && !changed[con] {
+				d.add(&Change{Action: ActionAlter, Object: ObjectConstraint, Table: name, Name: constraintName(con),
+					Details: []string{"recreated for column type change"},
+					table:   t, oldConstraint: con, newConstraint: con})
+			}
+		}
+		for _, ix := range t.Indexes {
+			dependent := uses(k, ix.Columns)
+			for _, inc := range ix.IncludeColumns {
+				if retyped[k][strings.ToLower(inc.Value)] {
+					dependent = true
+				}
+			}
+			if dependent && !changed[ix] {
+				d.add(&Change{Action: ActionAlter, Object: ObjectIndex, Table: name, Name: ix.Name.Value,
+					Details: []string{"recreated for column type change"},
+					table:   t, oldIndex: ix, newIndex: ix})
+			}
+		}
+	}
+}
+
+// compareColumns records column changes and returns the (lowercased)
+// names of the columns whose type or nullability changes.
+func (d *Diff) compareColumns(old, new *Table) map[string]bool {
+	retyped := make(map[string]bool)
+	table := new.Name.String()
+	for _, col := range old.Columns {
+		if new.Column(col.Name.Value) == nil {
+			d.add(&Change{
+				Action:    ActionDrop,
+				Object:    ObjectColumn,
+				Table:     table,
+				Name:      col.Name.Value,
+				Warning:   "dropping column " + table + "." + col.Name.Value + " deletes its data",
+				table:     new,
+				oldColumn: col,
+			})
+		}
+	}
+	for _, col := range new.Columns {
+		before := old.Column(col.Name.Value)
+		if before == nil {
+			c := &Change{Action: ActionCreate, Object: ObjectColumn, Table: table, Name: col.Name.Value, table: new, newColumn: col}
+			if !nullable(col) && col.Default == nil && col.Identity == nil && col.Computed == nil {
+				c.Warning = "adding NOT NULL column " + table + "." + col.Name.Value + " without a default fails if the table has rows"
+			}
+			d.add(c)
+			continue
+		}
+		if c := compareColumn(table, before, col); c != nil {
+			c.table = new
+			d.add(c)
+			if canonicalType(before.DataType) != canonicalType(col.DataType) || nullable(before) != nullable(col) {
+				retyped[strings.ToLower(col.Name.Value)] = true
+			}
+		}
+	}
+	return retyped
+}
+
+func compareColumn(table string, old, new *ast.ColumnDefinition) *Change {
+	c := &Change{Action: ActionAlter, Object: ObjectColumn, Table: table, Name: new.Name.Value, oldColumn: old, newColumn: new}
+	var warnings []string
+
+	if (old.Computed == nil) != (new.Computed == nil) ||
+		old.Computed != nil && normalizeSQL(old.Computed.String()) != normalizeSQL(new.Computed.String()) {
+		c.Details = append(c.Details, "computed expression changed")
+	}
+	if old.Computed == nil && new.Computed == nil {
+		if oldType, newType := canonicalType(old.DataType), canonicalType(new.DataType); oldType != newType {
+			c.Details = append(c.Details, "type "+oldType+" -> "+newType)
+			if reason := narrowing(old.DataType, new.DataType); reason != "" {
+				warnings = append(warnings, reason)
+			}
+		}
+		if nullable(old) != nullable(new) {
+			c.Details = append(c.Details, "nullability "+nullString(nullable(old))+" -> "+nullString(nullable(new)))
+			if !nullable(new) {
+				warnings = append(warnings, "making it NOT NULL fails if it contains NULLs")
+			}
+		}
+		if !strings.EqualFold(old.Collation, new.Collation) {
+			c.Details = append(c.Details, "collation "+orNone(old.Collation)+" -> "+orNone(new.Collation))
+		}
+	}
+	if defaultText(old) != defaultText(new) || !strings.EqualFold(old.DefaultName, new.DefaultName) {
+		c.Details = append(c.Details, "default "+orNone(defaultText(old))+" -> "+orNone(defaultText(new)))
+	}
+	if identityText(old) != identityText(new) {
+		c.Details = append(c.Details, "identity "+orNone(identityText(old))+" -> "+orNone(identityText(new)))
+		warnings = append(warnings, "IDENTITY cannot be altered in place; the table must be rebuilt")
+	}
+	if !sameOptions(old.Masked, new.Masked) {
+		c.Details = append(c.Details, "masking "+orNone(optionsText(old.Masked))+" -> "+orNone(optionsText(new.Masked)))
+	}
+	if !sameOptions(old.Encrypted, new.Encrypted) {
+		c.Details = append(c.Details, "encryption "+orNone(optionsText(old.Encrypted))+" -> "+orNone(optionsText(new.Encrypted)))
+		warnings = append(warnings, "Always Encrypted settings cannot be altered in place; the column must be re-encrypted by a client")
+	}
+
+	if len(c.Details) == 0 {
+		return nil
+	}
+	if len(warnings) > 0 {
+		c.Warning = "column " + table + "." + new.Name.Value + ": " + strings.Join(warnings, "; ")
+	}
+	return c
+}
+
+func (d *Diff) compareConstraints(old, new *Table) {
+	table := new.Name.String()
+	oldByKey := make(map[string]*ast.TableConstraint)
+	for _, c := range old.Constraints {
+		oldByKey[constraintKey(c)] = c
+	}
+	newByKey := make(map[string]*ast.TableConstraint)
+	for _, c := range new.Constraints {
+		newByKey[constraintKey(c)] = c
+	}
+	for _, k := range sortedKeys(oldByKey, newByKey) {
+		before, after := oldByKey[k], newByKey[k]
+		switch {
+		case after == nil:
+			d.add(&Change{Action: ActionDrop, Object: ObjectConstraint, Table: table, Name: constraintName(before),
+				table: new, oldConstraint: before})
+		case before == nil:
+			d.add(&Change{Action: ActionCreate, Object: ObjectConstraint, Table: table, Name: constraintName(after),
+				table: new, newConstraint: after})
+		case normalizeSQL(before.String()) != normalizeSQL(after.String()):
+			d.add(&Change{Action: ActionAlter, Object: ObjectConstraint, Table: table, Name: constraintName(after),
+				Details: []string{before.String() + " -> " + after.String()},
+				table:   new, oldConstraint: before, newConstraint: after})
+		}
+	}
+}
+
+func (d *Diff) compareIndexes(old, new *Table) {
+	table := new.Name.String()
+	oldByName := make(map[string]*ast.CreateIndexStatement)
+	for _, ix := range old.Indexes {
+		oldByName[strings.ToLower(ix.Name.Value)] = ix
+	}
+	newByName := make(map[string]*ast.CreateIndexStatement)
+	for _, ix := range new.Indexes {
+		newByName[strings.ToLower(ix.Name.Value)] = ix
+	}
+	for _, k := range sortedKeys(oldByName, newByName) {
+		before, after := oldByName[k], newByName[k]
+		switch {
+		case after == nil:
+			d.add(&Change{Action: ActionDrop, Object: ObjectIndex, Table: table, Name: before.Name.Value,
+				table: new, oldIndex: before})
+		case before == nil:
+			d.add(&Change{Action: ActionCreate, Object: ObjectIndex, Table: table, Name: after.Name.Value,
+				table: new, newIndex: after})
+		case indexSignature(before) != indexSignature(after):
+			d.add(&Change{Action: ActionAlter, Object: ObjectIndex, Table: table, Name: after.Name.Value,
+				Details: []string{"definition changed"},
+				table:   new, oldIndex: before, newIndex: after})
+		}
+	}
+}
+
+func (d *Diff) compareModules(object ObjectType, before, after map[string]*Module) {
+	for _, k := range sortedKeys(before, after) {
+		old, new := before[k], after[k]
+		switch {
+		case new == nil:
+			d.add(&Change{Action: ActionDrop, Object: object, Name: old.Name.String(), oldModule: old})
+		case old == nil:
+			d.add(&Change{Action: ActionCreate, Object: object, Name: new.Name.String(), newModule: new})
+		case moduleBody(old.Definition) != moduleBody(new.Definition):
+			d.add(&Change{Action: ActionAlter, Object: object, Name: new.Name.String(),
+				Details: []string{"definition changed"}, oldModule: old, newModule: new})
+		}
+	}
+}
+
+func nullable(col *ast.ColumnDefinition) bool {
+	return col.Nullable == nil || *col.Nullable
+}
+
+func nullString(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func defaultText(col *ast.ColumnDefinition) string {
+	if col.Default == nil {
+		return ""
+	}
+	return normalizeSQL(col.Default.String())
+}
+
+func identityText(col *ast.ColumnDefinition) string {
+	if col.Identity == nil {
+		return ""
+	}
+	return col.Identity.String()
+}
+
+func optionsText(options ast.OptionList) string {
+	if options == nil {
+		return ""
+	}
+	return options.String()
+}
+
+func sameOptions(a, b ast.OptionList) bool {
+	return normalizeSQL(optionsText(a)) == normalizeSQL(optionsText(b))
+}
+
+// constraintKey identifies a constraint across schema versions: by name
+// when it has one, otherwise by its definition.
+func constraintKey(c *ast.TableConstraint) string {
+	if c.Name != "" {
+		return "name:" + strings.ToLower(c.Name)
+	}
+	if c.Type == ast.ConstraintPrimaryKey {
+		return "pk"
+	}
+	return normalizeSQL(c.String())
+}
+
+func constraintName(c *ast.TableConstraint) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "(unnamed " + constraintTypeName(c.Type) + ")"
+}
+
+func constraintTypeName(t ast.ConstraintType) string {
+	switch t {
+	case ast.ConstraintPrimaryKey:
+		return "PRIMARY KEY"
+	case ast.ConstraintForeignKey:
+		return "FOREIGN KEY"
+	case ast.ConstraintUnique:
+		return "UNIQUE"
+	case ast.ConstraintCheck:
+		return "CHECK"
+	case ast.ConstraintDefault:
+		return "DEFAULT"
+	case ast.ConstraintConnection:
+		return "CONNECTION"
+	}
+	return "constraint"
+}
+
+// indexSignature returns an index definition independent of how its
+// table name was written.
+func indexSignature(ix *ast.CreateIndexStatement) string {
+	copied := *ix
+	copied.Table = &ast.QualifiedIdentifier{Parts: []*ast.Identifier{{Value: "t"}}}
+	return normalizeSQL(copied.String())
+}
+
+// moduleBody normalizes a module definition, ignoring whether it was
+// written as CREATE, ALTER or CREATE OR ALTER.
+func moduleBody(def string) string {
+	norm := normalizeSQL(def)
+	for _, kw := range []string{"procedure ", "proc ", "function ", "view "} {
+		if i := strings.Index(norm, kw); i >= 0 {
+			norm = norm[i+len(kw):]
+			break
+		}
+	}
+	return norm
+}
+
+// canonicalType renders a data type with defaults and synonyms resolved.
+func canonicalType(dt *ast.DataType) string {
+	if dt == nil {
+		return ""
+	}
+	c := *dt
+	c.Name = strings.ToUpper(c.Name)
+	switch c.Name {
+	case "INTEGER":
+		c.Name = "INT"
+	case "DEC":
+		c.Name = "DECIMAL"
+	case "ROWVERSION":
+		c.Name = "TIMESTAMP"
+	}
+	switch c.Name {
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "BINARY", "VARBINARY":
+		if c.Precision == nil && !c.Max {
+			c.Precision = intPtr(1)
+		}
+	case "DECIMAL", "NUMERIC":
+		if c.Precision == nil {
+			c.Precision = intPtr(18)
+		}
+		if c.Scale == nil {
+			c.Scale = intPtr(0)
+		}
+	case "DATETIME2", "DATETIMEOFFSET", "TIME":
+		if c.Precision == nil {
+			c.Precision = intPtr(7)
+		}
+	case "FLOAT":
+		if c.Precision != nil && *c.Precision <= 24 {
+			c.Name, c.Precision = "REAL", nil
+		} else {
+			c.Precision = nil
+		}
+	}
+	return c.String()
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// tokenOffset returns the byte offset of tok in src.
+func tokenOffset(src string, tok token.Token) int {
+	line, col := 1, 1
+	for i, r := range src {
+		if line == tok.Line && col == tok.Column {
+			return i
+		}
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return len(src)
+}
@@ -0,0 +1,445 @@
+// Package schemadiff compares two versions of a database schema described
+// by T-SQL DDL and generates a migration script between them.
+//
+// A Schema is built by replaying DDL scripts: CREATE TABLE, ALTER TABLE,
+// CREATE/DROP INDEX, DROP TABLE and the CREATE/ALTER/DROP statements for
+// views, functions and procedures. Compare produces a structured Diff,
+// and Diff.Script renders it as an ordered migration, flagging changes
+// that can lose data.
+//
+// Example usage:
+//
+//	before, _ := schemadiff.LoadDir("schema/v1")
+//	after, _ := schemadiff.LoadDir("schema/v2")
+//	diff := schemadiff.Compare(before, after)
+//	for _, w := range diff.Warnings() {
+//	    fmt.Println("warning:", w)
+//	}
+//	fmt.Print(diff.Script())
+package schemadiff
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Schema is the set of objects defined by a series of DDL scripts.
+type Schema struct {
+	Tables     map[string]*Table  // Keyed by normalized name, e.g. "dbo.orders"
+	Views      map[string]*Module // Keyed by normalized name
+	Functions  map[string]*Module // Keyed by normalized name
+	Procedures map[string]*Module // Keyed by normalized name
+	Errors     []string           // Parse errors, prefixed with the script name
+}
+
+// Table is a table definition with every ALTER TABLE applied to it.
+// Inline column constraints are hoisted into Constraints; DEFAULT
+// constraints are kept on their column. IDENTITY and PRIMARY KEY columns
+// are NOT NULL whether or not they say so.
+type Table struct {
+	Name        *ast.QualifiedIdentifier
+	Columns     []*ast.ColumnDefinition
+	Constraints []*ast.TableConstraint
+	Indexes     []*ast.CreateIndexStatement
+	FileGroup   string
+}
+
+// Column returns the named column, or nil.
+func (t *Table) Column(name string) *ast.ColumnDefinition {
+	for _, col := range t.Columns {
+		if strings.EqualFold(col.Name.Value, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// TableNames returns the names of the tables as they were declared,
+// sorted.
+func (s *Schema) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for _, t := range s.Tables {
+		names = append(names, t.Name.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Columns returns the columns of the named table, or nil. The schema
+// defaults to dbo.
+func (s *Schema) Columns(table string) []*ast.ColumnDefinition {
+	name := &ast.QualifiedIdentifier{}
+	for _, part := range strings.Split(table, ".") {
+		name.Parts = append(name.Parts, &ast.Identifier{Value: part})
+	}
+	if t, ok := s.Tables[key(name)]; ok {
+		return t.Columns
+	}
+	return nil
+}
+
+// Module is a view, function or procedure.
+type Module struct {
+	Name       *ast.QualifiedIdentifier
+	Type       string // VIEW, FUNCTION or PROCEDURE
+	Definition string // Source text of the CREATE statement
+}
+
+// NewSchema creates an empty Schema.
+func NewSchema() *Schema {
+	return &Schema{
+		Tables:     make(map[string]*Table),
+		Views:      make(map[string]*Module),
+		Functions:  make(map[string]*Module),
+		Procedures: make(map[string]*Module),
+	}
+}
+
+// LoadDir builds a Schema from the .sql files in dir, applied in file
+// name order. Parse errors are collected in Schema.Errors.
+func LoadDir(dir string) (*Schema, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	s := NewSchema()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s.AddScript(filepath.Base(path), string(data))
+	}
+	return s, nil
+}
+
+// AddScript parses a DDL script batch by batch and applies it to the
+// schema. Module definitions keep the source text of their batch.
+func (s *Schema) AddScript(name, src string) {
+	for _, batch := range splitBatches(src) {
+		program, errors := tsqlparser.Parse(batch)
+		for _, e := range errors {
+			s.Errors = append(s.Errors, name+": "+e)
+		}
+		s.apply(program, strings.TrimSpace(batch))
+	}
+}
+
+// AddProgram applies an already parsed program to the schema. Module
+// definitions are taken from the AST's String form.
+func (s *Schema) AddProgram(program *ast.Program) {
+	s.apply(program, "")
+}
+
+// splitBatches splits a script on GO separator lines.
+func splitBatches(src string) []string {
+	var batches []string
+	var current strings.Builder
+	for _, line := range strings.SplitAfter(src, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && len(fields) <= 2 && strings.EqualFold(fields[0], "GO") {
+			batches = append(batches, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteString(line)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		batches = append(batches, current.String())
+	}
+	return batches
+}
+
+func (s *Schema) apply(program *ast.Program, source string) {
+	for _, stmt := range program.Statements {
+		text := source
+		if text == "" || len(program.Statements) > 1 {
+			text = stmt.String()
+		}
+		switch st := stmt.(type) {
+		case *ast.CreateTableStatement:
+			if !st.IsTemporary {
+				s.Tables[key(st.Name)] = newTable(st)
+			}
+		case *ast.AlterTableStatement:
+			if t, ok := s.Tables[key(st.Table)]; ok {
+				t.alter(st)
+			}
+		case *ast.DropTableStatement:
+			for _, name := range st.Tables {
+				delete(s.Tables, key(name))
+			}
+		case *ast.CreateIndexStatement:
+			if t, ok := s.Tables[key(st.Table)]; ok {
+				t.dropIndex(st.Name.Value)
+				t.Indexes = append(t.Indexes, st)
+			}
+		case *ast.DropIndexStatement:
+			if t, ok := s.Tables[key(st.Table)]; ok {
+				t.dropIndex(st.Name.Value)
+			}
+		case *ast.CreateViewStatement:
+			s.Views[key(st.Name)] = &Module{Name: st.Name, Type: "VIEW", Definition: text}
+		case *ast.AlterViewStatement:
+			s.Views[key(st.Name)] = &Module{Name: st.Name, Type: "VIEW", Definition: text}
+		case *ast.CreateFunctionStatement:
+			s.Functions[key(st.Name)] = &Module{Name: st.Name, Type: "FUNCTION", Definition: text}
+		case *ast.AlterFunctionStatement:
+			s.Functions[key(st.Name)] = &Module{Name: st.Name, Type: "FUNCTION", Definition: text}
+		case *ast.CreateProcedureStatement:
+			s.Procedures[key(st.Name)] = &Module{Name: st.Name, Type: "PROCEDURE", Definition: text}
+		case *ast.AlterProcedureStatement:
+			s.Procedures[key(st.Name)] = &Module{Name: st.Name, Type: "PROCEDURE", Definition: text}
+		case *ast.DropObjectStatement:
+			s.drop(st)
+		}
+	}
+}
+
+func (s *Schema) drop(st *ast.DropObjectStatement) {
+	var objects map[string]*Module
+	switch strings.ToUpper(st.ObjectType) {
+	case "VIEW":
+		objects = s.Views
+	case "FUNCTION":
+		objects = s.Functions
+	case "PROCEDURE", "PROC":
+		objects = s.Procedures
+	case "INDEX":
+		if t, ok := s.Tables[key(st.TableName)]; ok && st.IndexName != nil {
+			t.dropIndex(st.IndexName.Value)
+		}
+		return
+	default:
+		return
+	}
+	for _, name := range st.Names {
+		delete(objects, key(name))
+	}
+}
+
+// key normalizes an object name, defaulting the schema to dbo.
+func key(name *ast.QualifiedIdentifier) string {
+	if name == nil || len(name.Parts) == 0 {
+		return ""
+	}
+	n := len(name.Parts)
+	schema := "dbo"
+	if n > 1 && name.Parts[n-2].Value != "" {
+		schema = name.Parts[n-2].Value
+	}
+	return strings.ToLower(schema + "." + name.Parts[n-1].Value)
+}
+
+func newTable(st *ast.CreateTableStatement) *Table {
+	t := &Table{Name: st.Name, FileGroup: st.FileGroup}
+	for _, col := range st.Columns {
+		t.addColumn(col)
+	}
+	for _, c := range st.Constraints {
+		t.addConstraint(c)
+	}
+	return t
+}
+
+// addColumn adds a copy of col, moving its inline constraints and index
+// to the table.
+func (t *Table) addColumn(def *ast.ColumnDefinition) {
+	col := *def
+	col.Constraints = nil
+	col.InlineIndex = nil
+	t.Columns = append(t.Columns, &col)
+	if col.Identity != nil {
+		t.notNull(col.Name.Value)
+	}
+
+	cols := []*ast.IndexColumn{{Name: col.Name}}
+	for _, cc := range def.Constraints {
+		t.Constraints = append(t.Constraints, &ast.TableConstraint{
+			Name:              cc.Name,
+			Type:              cc.Type,
+			Columns:           cols,
+			IsClustered:       cc.IsClustered,
+			ReferencesTable:   cc.ReferencesTable,
+			ReferencesColumns: cc.ReferencesColumns,
+			CheckExpression:   cc.CheckExpression,
+			OnDelete:          cc.OnDelete,
+			OnUpdate:          cc.OnUpdate,
+		})
+		if cc.Type == ast.ConstraintPrimaryKey {
+			t.notNull(col.Name.Value)
+		}
+	}
+	if def.InlineIndex != nil {
+		t.Indexes = append(t.Indexes, &ast.CreateIndexStatement{
+			Name:        &ast.Identifier{Value: def.InlineIndex.Name},
+			IsClustered: def.InlineIndex.Clustered,
+			Table:       t.Name,
+			Columns:     cols,
+		})
+	}
+}
+
+func (t *Table) addConstraint(c *ast.TableConstraint) {
+	switch c.Type {
+	case ast.ConstraintDefault:
+		if c.ForColumn == nil {
+			return
+		}
+		if col := t.Column(c.ForColumn.Value); col != nil {
+			copied := *col
+			copied.Default = c.DefaultExpression
+			copied.DefaultName = c.Name
+			t.replaceColumn(&copied)
+		}
+	case ast.ConstraintIndex:
+		t.Indexes = append(t.Indexes, &ast.CreateIndexStatement{
+			Name:        &ast.Identifier{Value: c.Name},
+			IsClustered: c.IsClustered,
+			Table:       t.Name,
+			Columns:     c.Columns,
+		})
+	default:
+		t.Constraints = append(t.Constraints, c)
+		if c.Type == ast.ConstraintPrimaryKey {
+			for _, col := range c.Columns {
+				t.notNull(col.Name.Value)
+			}
+		}
+	}
+}
+
+// notNull makes a column NOT NULL, as a primary key or IDENTITY makes it
+// without saying so. Dropping the key later leaves it NOT NULL.
+func (t *Table) notNull(name string) {
+	col := t.Column(name)
+	if col == nil || col.Nullable != nil && !*col.Nullable {
+		return
+	}
+	copied := *col
+	nullable := false
+	copied.Nullable = &nullable
+	t.replaceColumn(&copied)
+}
+
+func (t *Table) replaceColumn(col *ast.ColumnDefinition) {
+	for i, existing := range t.Columns {
+		if strings.EqualFold(existing.Name.Value, col.Name.Value) {
+			t.Columns[i] = col
+			return
+		}
+	}
+}
+
+func (t *Table) alter(st *ast.AlterTableStatement) {
+	for _, action := range st.Actions {
+		switch action.Type {
+		case ast.AlterAddColumn:
+			cols := action.Columns
+			if len(cols) == 0 && action.Column != nil {
+				cols = []*ast.ColumnDefinition{action.Column}
+			}
+			for _, col := range cols {
+				t.addColumn(col)
+			}
+			for _, c := range action.Constraints {
+				t.addConstraint(c)
+			}
+		case ast.AlterDropColumn:
+			t.dropColumn(action.ColumnName.Value)
+		case ast.AlterAlterColumn:
+			if col := t.Column(action.ColumnName.Value); col != nil {
+				copied := *col
+				copied.DataType = action.NewDataType
+				copied.Nullable = action.Nullable
+				copied.Collation = action.Collation
+				t.replaceColumn(&copied)
+			}
+		case ast.AlterRenameColumn:
+			for i, col := range t.Columns {
+				if strings.EqualFold(col.Name.Value, action.ColumnName.Value) {
+					copied := *col
+					copied.Name = action.NewColumnName
+					t.Columns[i] = &copied
+				}
+			}
+		case ast.AlterAddConstraint:
+			constraints := action.Constraints
+			if len(constraints) == 0 && action.Constraint != nil {
+				constraints = []*ast.TableConstraint{action.Constraint}
+			}
+			for _, c := range constraints {
+				t.addConstraint(c)
+			}
+		case ast.AlterDropConstraint:
+			t.dropConstraint(action.ConstraintName)
+		case ast.AlterDropPeriod:
+			t.Constraints = slices.DeleteFunc(t.Constraints, func(c *ast.TableConstraint) bool {
+				return c.Type == ast.ConstraintPeriod
+			})
+		}
+	}
+}
+
+func (t *Table) dropColumn(name string) {
+	for i, col := range t.Columns {
+		if strings.EqualFold(col.Name.Value, name) {
+			t.Columns = append(t.Columns[:i:i], t.Columns[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *Table) dropConstraint(name string) {
+	for i, c := range t.Constraints {
+		if strings.EqualFold(c.Name, name) {
+			t.Constraints = append(t.Constraints[:i:i], t.Constraints[i+1:]...)
+			return
+		}
+	}
+	for i, col := range t.Columns {
+		if strings.EqualFold(col.DefaultName, name) {
+			copied := *col
+			copied.Default = nil
+			copied.DefaultName = ""
+			t.Columns[i] = &copied
+			return
+		}
+	}
+}
+
+func (t *Table) dropIndex(name string) {
+	for i, ix := range t.Indexes {
+		if strings.EqualFold(ix.Name.Value, name) {
+			t.Indexes = append(t.Indexes[:i:i], t.Indexes[i+1:]...)
+			return
+		}
+	}
+}
+
+// normalizeSQL reduces SQL text to a canonical token string so that
+// definitions differing only in whitespace, comments, semicolons or
+// keyword case compare equal.
+func normalizeSQL(sql string) string {
+	var parts []string
+	for _, tok := range tsqlparser.Tokenize(sql) {
+		switch tok.Type {
+		case token.COMMENT, token.SEMICOLON, token.EOF:
+			continue
+		case token.STRING, token.NSTRING:
+			parts = append(parts, tok.Literal)
+		default:
+			parts = append(parts, strings.ToLower(tok.Literal))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,300 @@
+package schemadiff
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func schema(t *testing.T, script string) *Schema {
+	t.Helper()
+	s := NewSchema()
+	s.AddScript("test.sql", script)
+	if len(s.Errors) > 0 {
+		t.Fatalf("parse errors: %v", s.Errors)
+	}
+	return s
+}
+
+func changes(d *Diff) []string {
+	var out []string
+	for _, c := range d.Changes {
+		out = append(out, c.Action.String()+" "+c.Object.String()+" "+c.Name)
+	}
+	return out
+}
+
+func TestSchemaReplaysAlterTable(t *testing.T) {
+	s := schema(t, `
+CREATE TABLE dbo.Orders (
+    Id INT NOT NULL PRIMARY KEY,
+    Amount DECIMAL(10,2) NULL,
+    Legacy INT NULL,
+    Code CHAR(3) NOT NULL INDEX IX_Orders_Code
+);
+GO
+ALTER TABLE dbo.Orders ADD Created DATETIME2 NOT NULL CONSTRAINT DF_Orders_Created DEFAULT SYSDATETIME();
+ALTER TABLE dbo.Orders DROP COLUMN Legacy;
+ALTER TABLE dbo.Orders ALTER COLUMN Amount DECIMAL(12,2) NOT NULL;
+ALTER TABLE dbo.Orders ADD CONSTRAINT CK_Orders_Amount CHECK (Amount >= 0);
+GO
+CREATE INDEX IX_Orders_Created ON Orders (Created);
+GO
+CREATE VIEW dbo.V AS SELECT 1 AS One;
+GO
+DROP VIEW dbo.V;
+`)
+
+	table := s.Tables["dbo.orders"]
+	if table == nil {
+		t.Fatal("expected table dbo.orders")
+	}
+	var names []string
+	for _, col := range table.Columns {
+		names = append(names, col.Name.Value)
+	}
+	if got := strings.Join(names, ","); got != "Id,Amount,Code,Created" {
+		t.Errorf("columns = %s", got)
+	}
+	if amount := table.Column("amount"); amount.DataType.String() != "DECIMAL(12, 2)" || nullable(amount) {
+		t.Errorf("Amount = %s nullable=%v", amount.DataType, nullable(amount))
+	}
+	if created := table.Column("Created"); created.DefaultName != "DF_Orders_Created" {
+		t.Errorf("Created default name = %q", created.DefaultName)
+	}
+	if len(table.Constraints) != 2 {
+		t.Errorf("expected 2 constraints (PK, CHECK), got %d", len(table.Constraints))
+	}
+	if len(table.Indexes) != 2 {
+		t.Errorf("expected 2 indexes, got %d", len(table.Indexes))
+	}
+	if len(s.Views) != 0 {
+		t.Errorf("expected dropped view to be removed")
+	}
+	if got := s.TableNames(); len(got) != 1 || got[0] != "dbo.Orders" {
+		t.Errorf("TableNames() = %v", got)
+	}
+	if cols := s.Columns("orders"); len(cols) != 4 {
+		t.Errorf("Columns(orders) = %d columns, want 4", len(cols))
+	}
+}
+
+func TestCompareTables(t *testing.T) {
+	before := schema(t, `
+CREATE TABLE dbo.Customers (
+    Id INT IDENTITY(1,1) PRIMARY KEY,
+    Name NVARCHAR(100) NOT NULL,
+    Notes NVARCHAR(MAX) NULL,
+    Status INT NOT NULL DEFAULT 0
+);
+GO
+CREATE TABLE dbo.Legacy (Id INT);
+`)
+	after := schema(t, `
+CREATE TABLE Customers (
+    Id INT IDENTITY(1,1) PRIMARY KEY,
+    Name NVARCHAR(50) NOT NULL,
+    Status INT NOT NULL CONSTRAINT DF_Customers_Status DEFAULT 1,
+    Email VARCHAR(200) NULL
+);
+GO
+CREATE TABLE dbo.Orders (
+    Id INT NOT NULL PRIMARY KEY,
+    CustomerId INT NOT NULL REFERENCES dbo.Customers (Id)
+);
+`)
+	d := Compare(before, after)
+
+	expected := []string{
+		"DROP COLUMN Notes",
+		"ALTER COLUMN Name",
+		"ALTER COLUMN Status",
+		"CREATE COLUMN Email",
+		"DROP TABLE dbo.Legacy",
+		"CREATE TABLE dbo.Orders",
+		"CREATE CONSTRAINT (unnamed FOREIGN KEY)",
+	}
+	if got := changes(d); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	warnings := d.Warnings()
+	if len(warnings) != 3 {
+		t.Fatalf("expected 3 warnings, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[1], "NVARCHAR(100) to NVARCHAR(50)") {
+		t.Errorf("unexpected narrowing warning: %s", warnings[1])
+	}
+}
+
+func TestMaskingAndEncryption(t *testing.T) {
+	before := schema(t, `
+CREATE TABLE dbo.People (
+    Id INT NOT NULL,
+    Email NVARCHAR(100) NULL,
+    Phone VARCHAR(20) MASKED WITH (FUNCTION = 'default()') NULL,
+    SSN CHAR(11) NULL
+);
+`)
+	after := schema(t, `
+CREATE TABLE dbo.People (
+    Id INT NOT NULL,
+    Email NVARCHAR(100) MASKED WITH (FUNCTION = 'email()') NULL,
+    Phone VARCHAR(20) NULL,
+    SSN CHAR(11) ENCRYPTED WITH (COLUMN_ENCRYPTION_KEY = CEK1, ENCRYPTION_TYPE = DETERMINISTIC, ALGORITHM = 'AEAD_AES_256_CBC_HMAC_SHA_256') NULL
+);
+`)
+	d := Compare(before, after)
+	expected := []string{"ALTER COLUMN Email", "ALTER COLUMN Phone", "ALTER COLUMN SSN"}
+	if got := changes(d); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	if got := d.Changes[0].Details; len(got) != 1 || got[0] != "masking none -> (FUNCTION = 'email()')" {
+		t.Errorf("Email details = %q", got)
+	}
+	if len(d.Warnings()) != 1 || !strings.Contains(d.Warnings()[0], "SSN") {
+		t.Errorf("expected an encryption warning, got %v", d.Warnings())
+	}
+
+	script := d.Script()
+	for _, want := range []string{
+		"ALTER TABLE dbo.People ALTER COLUMN Email ADD MASKED WITH (FUNCTION = 'email()')",
+		"ALTER TABLE dbo.People ALTER COLUMN Phone DROP MASKED",
+		"-- dbo.People.SSN: change encryption with a client tool",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q\n%s", want, script)
+		}
+	}
+}
+
+func TestScriptOrdering(t *testing.T) {
+	before := schema(t, `
+CREATE TABLE dbo.Parent (Id INT NOT NULL CONSTRAINT PK_Parent PRIMARY KEY);
+GO
+CREATE TABLE dbo.Child (Id INT NOT NULL, ParentId INT NOT NULL,
+    CONSTRAINT FK_Child_Parent FOREIGN KEY (ParentId) REFERENCES dbo.Parent (Id));
+GO
+CREATE PROCEDURE dbo.ListChildren AS SELECT Id FROM dbo.Child;
+`)
+	after := schema(t, `
+CREATE TABLE dbo.Parent (Id BIGINT NOT NULL CONSTRAINT PK_Parent PRIMARY KEY);
+GO
+CREATE TABLE dbo.Child (Id INT NOT NULL, ParentId BIGINT NOT NULL,
+    CONSTRAINT FK_Child_Parent FOREIGN KEY (ParentId) REFERENCES dbo.Parent (Id));
+GO
+CREATE FUNCTION dbo.Double (@x INT) RETURNS INT AS BEGIN RETURN @x * 2 END;
+GO
+CREATE PROCEDURE dbo.ListChildren AS SELECT Id, ParentId FROM dbo.Child;
+`)
+	script := Compare(before, after).Script()
+
+	order := []string{
+		"ALTER TABLE dbo.Child DROP CONSTRAINT FK_Child_Parent",
+		"ALTER TABLE dbo.Child ALTER COLUMN ParentId BIGINT NOT NULL",
+		"ALTER TABLE dbo.Parent ALTER COLUMN Id BIGINT NOT NULL",
+		"ALTER TABLE dbo.Child ADD CONSTRAINT FK_Child_Parent FOREIGN KEY",
+		"CREATE FUNCTION dbo.Double",
+		"CREATE OR ALTER PROCEDURE dbo.ListChildren",
+	}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(script, want)
+		if i < 0 {
+			t.Fatalf("script missing %q\n%s", want, script)
+		}
+		if i < last {
+			t.Errorf("%q is out of order\n%s", want, script)
+		}
+		last = i
+	}
+	if strings.Contains(script, "WARNING") {
+		t.Errorf("widening INT to BIGINT should not warn\n%s", script)
+	}
+}
+
+func TestImplicitNotNull(t *testing.T) {
+	before := schema(t, `
+CREATE TABLE dbo.Orders (Id INT PRIMARY KEY, Seq INT IDENTITY(1,1));
+GO
+CREATE TABLE dbo.Lines (OrderId INT, Line INT, CONSTRAINT PK_Lines PRIMARY KEY (OrderId, Line));
+`)
+	after := schema(t, `
+CREATE TABLE dbo.Orders (Id INT NOT NULL PRIMARY KEY, Seq INT NOT NULL IDENTITY(1,1));
+GO
+CREATE TABLE dbo.Lines (OrderId INT NOT NULL, Line INT NOT NULL);
+GO
+ALTER TABLE dbo.Lines ADD CONSTRAINT PK_Lines PRIMARY KEY (OrderId, Line);
+`)
+	if d := Compare(before, after); !d.Empty() {
+		t.Errorf("expected no changes, got:\n%s\n%s", d, d.Script())
+	}
+}
+
+func TestModulesIgnoreFormatting(t *testing.T) {
+	before := schema(t, "CREATE VIEW dbo.V AS SELECT 1 AS One;")
+	after := schema(t, "-- reformatted\ncreate or alter view dbo.V\nas\n    select 1 as One\n")
+	if d := Compare(before, after); !d.Empty() {
+		t.Errorf("expected no changes, got:\n%s", d)
+	}
+}
+
+func TestNarrowing(t *testing.T) {
+	dt := func(name string, sizes ...int) *ast.DataType {
+		d := &ast.DataType{Name: name}
+		if len(sizes) > 0 {
+			d.Precision = &sizes[0]
+		}
+		if len(sizes) > 1 {
+			d.Scale = &sizes[1]
+		}
+		return d
+	}
+	tests := []struct {
+		old, new *ast.DataType
+		lossy    bool
+	}{
+		{dt("INT"), dt("BIGINT"), false},
+		{dt("BIGINT"), dt("INT"), true},
+		{dt("INT"), dt("DECIMAL", 10, 0), false},
+		{dt("INT"), dt("DECIMAL", 10, 2), true},
+		{dt("DECIMAL", 10, 2), dt("DECIMAL", 12, 2), false},
+		{dt("DECIMAL", 10, 4), dt("DECIMAL", 10, 2), true},
+		{dt("VARCHAR", 50), dt("NVARCHAR", 50), false},
+		{dt("NVARCHAR", 50), dt("VARCHAR", 50), true},
+		{dt("VARCHAR", 50), dt("VARCHAR", 20), true},
+		{dt("VARCHAR", 50), &ast.DataType{Name: "VARCHAR", Max: true}, false},
+		{dt("DATETIME"), dt("DATE"), true},
+		{dt("DATE"), dt("DATETIME2"), false},
+		{dt("INT"), dt("VARCHAR", 10), true},
+	}
+	for _, tt := range tests {
+		if got := narrowing(tt.old, tt.new) != ""; got != tt.lossy {
+			t.Errorf("narrowing(%s, %s) = %v, want %v", tt.old, tt.new, got, tt.lossy)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"01_tables.sql": "CREATE TABLE dbo.T (Id INT NOT NULL);\nGO\n",
+		"02_alter.sql":  "ALTER TABLE dbo.T ADD Name NVARCHAR(10) NULL;\n",
+		"README.txt":    "not sql",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table := s.Tables["dbo.t"]; table == nil || len(table.Columns) != 2 {
+		t.Errorf("expected dbo.T with 2 columns")
+	}
+}
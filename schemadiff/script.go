@@ -0,0 +1,411 @@
+package schemadiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Script phases. Objects are dropped before the objects they depend on
+// and created after them.
+const (
+	phaseDropModule = iota
+	phaseDropForeignKey
+	phaseDropIndex
+	phaseDropConstraint
+	phaseDropColumn
+	phaseDropTable
+	phaseCreateTable
+	phaseAddColumn
+	phaseAlterColumn
+	phaseAddConstraint
+	phaseCreateIndex
+	phaseAddForeignKey
+	phaseCreateModule
+)
+
+type step struct {
+	phase   int
+	order   int
+	sql     string
+	warning string
+}
+
+// Script renders the diff as a migration script. Each statement is its
+// own batch, and changes that can lose data are preceded by a WARNING
+// comment.
+func (d *Diff) Script() string {
+	var steps []step
+	for _, c := range d.Changes {
+		steps = append(steps, c.steps()...)
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].phase != steps[j].phase {
+			return steps[i].phase < steps[j].phase
+		}
+		return steps[i].order < steps[j].order
+	})
+
+	var out strings.Builder
+	for _, s := range steps {
+		if s.warning != "" {
+			out.WriteString("-- WARNING: ")
+			out.WriteString(s.warning)
+			out.WriteString("\n")
+		}
+		out.WriteString(s.sql)
+		out.WriteString("\nGO\n\n")
+	}
+	return out.String()
+}
+
+func (c *Change) steps() []step {
+	switch c.Object {
+	case ObjectTable:
+		return c.tableSteps()
+	case ObjectColumn:
+		return c.columnSteps()
+	case ObjectConstraint:
+		var steps []step
+		if c.oldConstraint != nil {
+			steps = append(steps, dropConstraint(c.table, c.oldConstraint))
+		}
+		if c.newConstraint != nil {
+			phase := phaseAddConstraint
+			if c.newConstraint.Type == ast.ConstraintForeignKey {
+				phase = phaseAddForeignKey
+			}
+			steps = append(steps, step{phase: phase, sql: "ALTER TABLE " + c.table.Name.String() + " ADD " + c.newConstraint.String()})
+		}
+		return steps
+	case ObjectIndex:
+		var steps []step
+		if c.oldIndex != nil {
+			steps = append(steps, step{phase: phaseDropIndex, sql: "DROP INDEX " + c.oldIndex.Name.Value + " ON " + c.table.Name.String()})
+		}
+		if c.newIndex != nil {
+			ix := *c.newIndex
+			ix.Table = c.table.Name
+			steps = append(steps, step{phase: phaseCreateIndex, sql: ix.String()})
+		}
+		return steps
+	default:
+		return c.moduleSteps()
+	}
+}
+
+func (c *Change) tableSteps() []step {
+	name := c.table.Name.String()
+	if c.Action == ActionDrop {
+		return []step{{phase: phaseDropTable, sql: "DROP TABLE " + name, warning: c.Warning}}
+	}
+	create := &ast.CreateTableStatement{Name: c.table.Name, Columns: c.table.Columns, FileGroup: c.table.FileGroup}
+	for _, con := range c.table.Constraints {
+		if con.Type != ast.ConstraintForeignKey {
+			create.Constraints = append(create.Constraints, con)
+		}
+	}
+	return []step{{phase: phaseCreateTable, sql: create.String()}}
+}
+
+func (c *Change) columnSteps() []step {
+	table := c.table.Name.String()
+	switch c.Action {
+	case ActionCreate:
+		return []step{{phase: phaseAddColumn, sql: "ALTER TABLE " + table + " ADD " + c.newColumn.String(), warning: c.Warning}}
+	case ActionDrop:
+		var steps []step
+		if c.oldColumn.Default != nil {
+			steps = append(steps, dropDefault(c.table, c.oldColumn))
+		}
+		return append(steps, step{phase: phaseDropColumn, sql: "ALTER TABLE " + table + " DROP COLUMN " + c.oldColumn.Name.Value, warning: c.Warning})
+	}
+
+	old, new := c.oldColumn, c.newColumn
+	if identityText(old) != identityText(new) {
+		return []step{{
+			phase:   phaseAlterColumn,
+			sql:     "-- " + table + "." + new.Name.Value + ": change IDENTITY by rebuilding the table",
+			warning: c.Warning,
+		}}
+	}
+	if !sameOptions(old.Encrypted, new.Encrypted) {
+		return []step{{
+			phase:   phaseAlterColumn,
+			sql:     "-- " + table + "." + new.Name.Value + ": change encryption with a client tool",
+			warning: c.Warning,
+		}}
+	}
+
+	var steps []step
+	if old.Computed != nil || new.Computed != nil {
+		if normalizeSQL(exprString(old.Computed)) != normalizeSQL(exprString(new.Computed)) {
+			if old.Default != nil {
+				steps = append(steps, dropDefault(c.table, old))
+			}
+			steps = append(steps,
+				step{phase: phaseDropColumn, sql: "ALTER TABLE " + table + " DROP COLUMN " + old.Name.Value, warning: c.Warning},
+				step{phase: phaseAddColumn, sql: "ALTER TABLE " + table + " ADD " + new.String()})
+			return steps
+		}
+	}
+
+	typeChanged := canonicalType(old.DataType) != canonicalType(new.DataType) ||
+		nullable(old) != nullable(new) || !strings.EqualFold(old.Collation, new.Collation)
+	defaultChanged := defaultText(old) != defaultText(new) || !strings.EqualFold(old.DefaultName, new.DefaultName)
+
+	// A default constraint blocks ALTER COLUMN, so it is recreated around it.
+	if old.Default != nil && (defaultChanged || typeChanged) {
+		steps = append(steps, dropDefault(c.table, old))
+	}
+	if typeChanged && new.Computed == nil {
+		nullable := nullable(new)
+		action := &ast.AlterTableAction{
+			Type:        ast.AlterAlterColumn,
+			ColumnName:  new.Name,
+			NewDataType: new.DataType,
+			Collation:   new.Collation,
+			Nullable:    &nullable,
+		}
+		steps = append(steps, step{phase: phaseAlterColumn, sql: "ALTER TABLE " + table + " " + action.String(), warning: c.Warning})
+	}
+	if !sameOptions(old.Masked, new.Masked) {
+		sql := "ALTER TABLE " + table + " ALTER COLUMN " + new.Name.Value + " DROP MASKED"
+		if new.Masked != nil {
+			sql = "ALTER TABLE " + table + " ALTER COLUMN " + new.Name.Value + " ADD MASKED WITH " + new.Masked.String()
+		}
+		steps = append(steps, step{phase: phaseAlterColumn, sql: sql})
+	}
+	if new.Default != nil && (defaultChanged || typeChanged) {
+		con := &ast.TableConstraint{
+			Name:              new.DefaultName,
+			Type:              ast.ConstraintDefault,
+			DefaultExpression: new.Default,
+			ForColumn:         new.Name,
+		}
+		steps = append(steps, step{phase: phaseAddConstraint, sql: "ALTER TABLE " + table + " ADD " + con.String()})
+	}
+	return steps
+}
+
+func exprString(expr ast.Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// dropDefault drops the default constraint on col, looking up its
+// system-generated name when the DDL did not name it.
+func dropDefault(t *Table, col *ast.ColumnDefinition) step {
+	table := t.Name.String()
+	if col.DefaultName != "" {
+		return step{phase: phaseDropConstraint, sql: "ALTER TABLE " + table + " DROP CONSTRAINT " + col.DefaultName}
+	}
+	return step{phase: phaseDropConstraint, sql: fmt.Sprintf(`DECLARE @name sysname;
+SELECT @name = dc.name FROM sys.default_constraints dc
+JOIN sys.columns c ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+WHERE dc.parent_object_id = OBJECT_ID(N'%s') AND c.name = N'%s';
+IF @name IS NOT NULL EXEC(N'ALTER TABLE %s DROP CONSTRAINT ' + QUOTENAME(@name));`,
+		quote(table), quote(col.Name.Value), quote(table))}
+}
+
+// dropConstraint drops a constraint, looking up the name of an unnamed
+// primary key. Other unnamed constraints cannot be identified reliably
+// and are left as a comment.
+func dropConstraint(t *Table, con *ast.TableConstraint) step {
+	table := t.Name.String()
+	phase := phaseDropConstraint
+	if con.Type == ast.ConstraintForeignKey {
+		phase = phaseDropForeignKey
+	}
+	switch {
+	case con.Name != "":
+		return step{phase: phase, sql: "ALTER TABLE " + table + " DROP CONSTRAINT " + con.Name}
+	case con.Type == ast.ConstraintPrimaryKey:
+		return step{phase: phase, sql: fmt.Sprintf(`DECLARE @name sysname;
+SELECT @name = name FROM sys.key_constraints WHERE parent_object_id = OBJECT_ID(N'%s') AND type = 'PK';
+IF @name IS NOT NULL EXEC(N'ALTER TABLE %s DROP CONSTRAINT ' + QUOTENAME(@name));`,
+			quote(table), quote(table))}
+	}
+	return step{
+		phase:   phase,
+		sql:     "-- ALTER TABLE " + table + " DROP CONSTRAINT <name of: " + con.String() + ">",
+		warning: "unnamed " + constraintTypeName(con.Type) + " constraint on " + table + " must be dropped by hand",
+	}
+}
+
+func quote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+func (c *Change) moduleSteps() []step {
+	rank := map[ObjectType]int{ObjectFunction: 0, ObjectView: 1, ObjectProcedure: 2}[c.Object]
+	switch c.Action {
+	case ActionDrop:
+		return []step{{phase: phaseDropModule, order: -rank, sql: "DROP " + c.Object.String() + " " + c.Name}}
+	case ActionCreate:
+		return []step{{phase: phaseCreateModule, order: rank, sql: rewriteHeader(c.newModule.Definition, "CREATE")}}
+	}
+	return []step{{phase: phaseCreateModule, order: rank, sql: rewriteHeader(c.newModule.Definition, "CREATE OR ALTER")}}
+}
+
+// rewriteHeader replaces the CREATE / ALTER / CREATE OR ALTER that
+// introduces a module definition with verb.
+func rewriteHeader(def, verb string) string {
+	var start, end *token.Token
+	for _, tok := range tsqlparser.Tokenize(def) {
+		tok := tok
+		switch tok.Type {
+		case token.CREATE, token.ALTER:
+			if start == nil {
+				start = &tok
+			}
+		case token.VIEW, token.PROCEDURE, token.PROC, token.FUNCTION:
+			end = &tok
+		case token.COMMENT, token.OR:
+		default:
+			if start != nil {
+				end = &tok
+			}
+		}
+		if end != nil {
+			break
+		}
+	}
+	if start == nil || end == nil {
+		return def
+	}
+	return def[:tokenOffset(def, *start)] + verb + " " + def[tokenOffset(def, *end):]
+}
+
+// narrowing describes why changing a column from old to new can lose
+// data, or returns "" for a widening change.
+func narrowing(old, new *ast.DataType) string {
+	if old == nil || new == nil {
+		return ""
+	}
+	from, to := strings.ToUpper(canonicalName(old)), strings.ToUpper(canonicalName(new))
+	reason := "changing type from " + canonicalType(old) + " to " + canonicalType(new) + " can lose data"
+
+	intRank := map[string]int{"BIT": 0, "TINYINT": 1, "SMALLINT": 2, "INT": 3, "BIGINT": 4}
+	intDigits := map[string]int{"BIT": 1, "TINYINT": 3, "SMALLINT": 5, "INT": 10, "BIGINT": 19}
+	decimal := map[string]bool{"DECIMAL": true, "NUMERIC": true}
+	stringKind := map[string]int{"CHAR": 1, "VARCHAR": 1, "TEXT": 1, "NCHAR": 2, "NVARCHAR": 2, "NTEXT": 2}
+	binary := map[string]bool{"BINARY": true, "VARBINARY": true, "IMAGE": true}
+	temporal := map[string]int{"DATE": 1, "SMALLDATETIME": 2, "DATETIME": 3, "DATETIME2": 4, "DATETIMEOFFSET": 5}
+
+	switch {
+	case intRank[from] > 0 || from == "BIT":
+		if r, ok := intRank[to]; ok {
+			if r < intRank[from] {
+				return reason
+			}
+			return ""
+		}
+		if decimal[to] {
+			if precision(new)-scale(new) < intDigits[from] {
+				return reason
+			}
+			return ""
+		}
+		if to == "FLOAT" || to == "REAL" || to == "MONEY" && intRank[from] < 4 {
+			return ""
+		}
+	case decimal[from]:
+		if decimal[to] {
+			if scale(new) < scale(old) || precision(new)-scale(new) < precision(old)-scale(old) {
+				return reason
+			}
+			return ""
+		}
+		if to == "FLOAT" {
+			return ""
+		}
+	case from == "REAL":
+		if to == "FLOAT" {
+			return ""
+		}
+	case from == "SMALLMONEY":
+		if to == "MONEY" {
+			return ""
+		}
+	case stringKind[from] > 0:
+		if k := stringKind[to]; k > 0 {
+			if k < stringKind[from] || length(new) < length(old) {
+				return reason
+			}
+			return ""
+		}
+	case binary[from]:
+		if binary[to] {
+			if length(new) < length(old) {
+				return reason
+			}
+			return ""
+		}
+	case temporal[from] > 0:
+		if r := temporal[to]; r > 0 {
+			if from == "DATETIMEOFFSET" || r < temporal[from] || from == to && precision(new) < precision(old) {
+				return reason
+			}
+			return ""
+		}
+	}
+	if from == to {
+		return ""
+	}
+	return reason
+}
+
+func canonicalName(dt *ast.DataType) string {
+	name := canonicalType(dt)
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func precision(dt *ast.DataType) int {
+	switch strings.ToUpper(canonicalName(dt)) {
+	case "DECIMAL", "NUMERIC":
+		if dt.Precision == nil {
+			return 18
+		}
+	case "DATETIME2", "DATETIMEOFFSET", "TIME":
+		if dt.Precision == nil {
+			return 7
+		}
+	}
+	if dt.Precision == nil {
+		return 0
+	}
+	return *dt.Precision
+}
+
+func scale(dt *ast.DataType) int {
+	if dt.Scale == nil {
+		return 0
+	}
+	return *dt.Scale
+}
+
+// length returns the declared length of a string or binary type, with
+// MAX and the legacy large types treated as unbounded.
+func length(dt *ast.DataType) int {
+	switch strings.ToUpper(dt.Name) {
+	case "TEXT", "NTEXT", "IMAGE":
+		return 1 << 31
+	}
+	if dt.Max {
+		return 1 << 31
+	}
+	if dt.Precision == nil {
+		return 1
+	}
+	return *dt.Precision
+}
@@ -0,0 +1,502 @@
+package semdiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// routine is what a CREATE or ALTER of a procedure, function, trigger or
+// view defines.
+type routine struct {
+	verb    string // CREATE or ALTER
+	kind    string // PROCEDURE, FUNCTION, TRIGGER or VIEW
+	name    string
+	params  []*ast.ParameterDef
+	header  []fmt.Stringer // return type, trigger table and events, view columns...
+	options []string
+	body    []ast.Statement
+}
+
+func routineOf(s ast.Statement) *routine {
+	switch s := s.(type) {
+	case *ast.CreateProcedureStatement:
+		return &routine{verb: "CREATE", kind: "PROCEDURE", name: s.Name.String(), params: s.Parameters,
+			options: s.Options, body: block(s.Body)}
+	case *ast.AlterProcedureStatement:
+		return &routine{verb: "ALTER", kind: "PROCEDURE", name: s.Name.String(), params: s.Parameters,
+			options: s.Options, body: block(s.Body)}
+	case *ast.CreateFunctionStatement:
+		return &routine{verb: "CREATE", kind: "FUNCTION", name: s.Name.String(), params: s.Parameters,
+			header: returns(s.ReturnType, s.ReturnsTable, s.TableDef, s.TableVar), options: s.Options,
+			body: append(returnStatement(s.AsReturn), block(s.Body)...)}
+	case *ast.AlterFunctionStatement:
+		return &routine{verb: "ALTER", kind: "FUNCTION", name: s.Name.String(), params: s.Parameters,
+			header: returns(s.ReturnType, s.ReturnsTable, s.TableDef, s.TableVar), options: s.Options,
+			body: append(returnStatement(s.AsReturn), block(s.Body)...)}
+	case *ast.CreateTriggerStatement:
+		return &routine{verb: "CREATE", kind: "TRIGGER", name: s.Name.String(),
+			header: []fmt.Stringer{words(trigger(s.Table, s.Timing, s.Events))}, options: s.Options, body: block(s.Body)}
+	case *ast.AlterTriggerStatement:
+		return &routine{verb: "ALTER", kind: "TRIGGER", name: s.Name.String(),
+			header: []fmt.Stringer{words(trigger(s.Table, s.Timing, s.Events))}, body: block(s.Body)}
+	case *ast.CreateViewStatement:
+		return &routine{verb: "CREATE", kind: "VIEW", name: s.Name.String(),
+			header: []fmt.Stringer{identifiers(s.Columns)}, options: s.Options, body: block(s.AsSelect)}
+	case *ast.AlterViewStatement:
+		return &routine{verb: "ALTER", kind: "VIEW", name: s.Name.String(),
+			header: []fmt.Stringer{identifiers(s.Columns)}, options: s.Options, body: block(s.AsSelect)}
+	}
+	return nil
+}
+
+// words is text compared as a node.
+type words string
+
+func (w words) String() string { return string(w) }
+
+func identifiers(ids []*ast.Identifier) words {
+	var names []string
+	for _, id := range ids {
+		names = append(names, id.Value)
+	}
+	return words(strings.Join(names, ", "))
+}
+
+func trigger(table *ast.QualifiedIdentifier, timing ast.TriggerTiming, events []string) string {
+	on := ""
+	if table != nil {
+		on = "ON " + table.String() + " "
+	}
+	when := map[ast.TriggerTiming]string{ast.TriggerAfter: "AFTER", ast.TriggerInsteadOf: "INSTEAD OF", ast.TriggerFor: "FOR"}[timing]
+	return on + when + " " + strings.Join(events, ", ")
+}
+
+func returns(dt *ast.DataType, table bool, def *ast.TableTypeDefinition, v string) []fmt.Stringer {
+	switch {
+	case dt != nil:
+		return []fmt.Stringer{dt}
+	case def != nil:
+		return []fmt.Stringer{words(v), def}
+	case table:
+		return []fmt.Stringer{words("TABLE")}
+	}
+	return nil
+}
+
+// returnStatement is the query of an inline table-valued function.
+func returnStatement(e ast.Expression) []ast.Statement {
+	if s, ok := e.(ast.Statement); ok && !isNil(s) {
+		return []ast.Statement{s}
+	}
+	return nil
+}
+
+func (d *Diff) routine(path []string, a, b *routine, lines [2]int) {
+	if a.verb != b.verb {
+		d.add(&Change{Kind: Changed, Path: path, What: "statement", Before: a.verb + " " + a.kind + " " + a.name,
+			After: b.verb + " " + b.kind + " " + b.name, BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+	path = extend(path, b.kind+" "+b.name)
+	d.parameters(path, a.params, b.params, lines)
+	if !equal(a.header, b.header) {
+		d.add(&Change{Kind: Changed, Path: path, What: "definition", Before: nodes(a.header), After: nodes(b.header),
+			BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+	if !equal(a.options, b.options) {
+		d.add(&Change{Kind: Changed, Path: path, What: "options", Before: strings.Join(a.options, ", "),
+			After: strings.Join(b.options, ", "), BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+	d.statements(path, a.body, b.body)
+}
+
+func nodes(ns []fmt.Stringer) string {
+	var texts []string
+	for _, n := range ns {
+		texts = append(texts, text(n))
+	}
+	return strings.Join(texts, " ")
+}
+
+// parameters compares parameter lists by name.
+func (d *Diff) parameters(path []string, a, b []*ast.ParameterDef, lines [2]int) {
+	find := func(params []*ast.ParameterDef, name string) *ast.ParameterDef {
+		for _, p := range params {
+			if strings.EqualFold(p.Name, name) {
+				return p
+			}
+		}
+		return nil
+	}
+	var oldOrder, newOrder []string
+	for _, p := range a {
+		if find(b, p.Name) == nil {
+			d.add(&Change{Kind: Removed, Path: path, What: "parameter " + p.Name, Before: text(p),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		} else {
+			oldOrder = append(oldOrder, p.Name)
+		}
+	}
+	for _, p := range b {
+		old := find(a, p.Name)
+		if old == nil {
+			d.add(&Change{Kind: Added, Path: path, What: "parameter " + p.Name, After: text(p),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+			continue
+		}
+		newOrder = append(newOrder, p.Name)
+		d.clause(path, "type of "+p.Name, old.DataType, p.DataType, lines)
+		d.clause(path, "default of "+p.Name, old.Default, p.Default, lines)
+		for _, flag := range []struct {
+			what     string
+			old, new bool
+		}{{"OUTPUT", old.Output, p.Output}, {"READONLY", old.ReadOnly, p.ReadOnly}} {
+			switch {
+			case !flag.old && flag.new:
+				d.add(&Change{Kind: Added, Path: path, What: flag.what + " of " + p.Name, BeforeLine: lines[0], AfterLine: lines[1]})
+			case flag.old && !flag.new:
+				d.add(&Change{Kind: Removed, Path: path, What: flag.what + " of " + p.Name, BeforeLine: lines[0], AfterLine: lines[1]})
+			}
+		}
+	}
+	if !equal(oldOrder, newOrder) {
+		// Callers passing parameters by position see a different procedure
+		d.add(&Change{Kind: Changed, Path: path, What: "parameter order", Before: strings.Join(oldOrder, ", "),
+			After: strings.Join(newOrder, ", "), BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+}
+
+// query compares two SELECT statements clause by clause.
+func (d *Diff) query(path []string, a, b *ast.SelectStatement, lines [2]int) {
+	if a.Distinct != b.Distinct {
+		kind := Added
+		if a.Distinct {
+			kind = Removed
+		}
+		d.add(&Change{Kind: kind, Path: path, What: "DISTINCT", BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+	d.clause(path, "TOP", a.Top, b.Top, lines)
+	d.columns(path, a.Columns, b.Columns, lines)
+	d.clause(path, "INTO", a.Into, b.Into, lines)
+	d.from(path, a.From, b.From, lines)
+	d.clause(path, "WHERE predicate", a.Where, b.Where, lines)
+	list(d, path, "GROUP BY", a.GroupBy, b.GroupBy, lines)
+	d.clause(path, "HAVING predicate", a.Having, b.Having, lines)
+	list(d, path, "ORDER BY", a.OrderBy, b.OrderBy, lines)
+	switch {
+	case a.Union != nil && b.Union != nil && a.Union.Type == b.Union.Type && a.Union.All == b.Union.All &&
+		a.Union.Right != nil && b.Union.Right != nil:
+		d.query(extend(path, b.Union.Type), a.Union.Right, b.Union.Right, [2]int{line(a.Union.Right, lines[0]), line(b.Union.Right, lines[1])})
+	default:
+		d.clause(path, "UNION", a.Union, b.Union, lines)
+	}
+
+	rest := func(s ast.SelectStatement) *ast.SelectStatement {
+		s.Distinct, s.Top, s.Columns, s.Into, s.From, s.Where = false, nil, nil, nil, nil, nil
+		s.GroupBy, s.Having, s.OrderBy, s.Union = nil, nil, nil, nil
+		return &s
+	}
+	if ra, rb := rest(*a), rest(*b); !equal(ra, rb) {
+		d.changed(path, "statement", a, b, lines)
+	}
+}
+
+// columns compares SELECT lists. A column of the same name on both sides
+// is reported as changed.
+func (d *Diff) columns(path []string, a, b []ast.SelectColumn, lines [2]int) {
+	pairs := align(len(a), len(b), func(i, j int) int {
+		if equal(a[i], b[j]) {
+			return 1
+		}
+		return 0
+	})
+	oldMatched := make([]bool, len(a))
+	newMatched := make([]bool, len(b))
+	for _, p := range pairs {
+		oldMatched[p[0]], newMatched[p[1]] = true, true
+	}
+	for j := range b {
+		if newMatched[j] {
+			continue
+		}
+		name := columnName(b[j])
+		old := -1
+		for i := range a {
+			if !oldMatched[i] && name != "" && strings.EqualFold(columnName(a[i]), name) {
+				old = i
+				break
+			}
+		}
+		if old < 0 {
+			d.add(&Change{Kind: Added, Path: path, What: "column", After: text(b[j]),
+				BeforeLine: lines[0], AfterLine: line(b[j].Expression, lines[1])})
+			continue
+		}
+		oldMatched[old] = true
+		d.add(&Change{Kind: Changed, Path: path, What: "column " + name, Before: text(a[old]), After: text(b[j]),
+			BeforeLine: line(a[old].Expression, lines[0]), AfterLine: line(b[j].Expression, lines[1])})
+	}
+	for i := range a {
+		if !oldMatched[i] {
+			d.add(&Change{Kind: Removed, Path: path, What: "column", Before: text(a[i]),
+				BeforeLine: line(a[i].Expression, lines[0]), AfterLine: lines[1]})
+		}
+	}
+}
+
+// columnName is the name of the column a select item produces, if it is
+// easily told.
+func columnName(c ast.SelectColumn) string {
+	switch {
+	case c.Alias != nil:
+		return c.Alias.Value
+	case c.Variable != nil:
+		return c.Variable.Name
+	}
+	switch e := c.Expression.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.QualifiedIdentifier:
+		if len(e.Parts) > 0 {
+			return e.Parts[len(e.Parts)-1].Value
+		}
+	}
+	return ""
+}
+
+// list compares a list clause as a whole.
+func list[T fmt.Stringer](d *Diff, path []string, what string, a, b []T, lines [2]int) {
+	if equal(a, b) {
+		return
+	}
+	join := func(items []T) string {
+		var texts []string
+		for _, item := range items {
+			texts = append(texts, text(item))
+		}
+		return strings.Join(texts, ", ")
+	}
+	switch {
+	case len(a) == 0:
+		d.add(&Change{Kind: Added, Path: path, What: what, After: join(b), BeforeLine: lines[0], AfterLine: lines[1]})
+	case len(b) == 0:
+		d.add(&Change{Kind: Removed, Path: path, What: what, Before: join(a), BeforeLine: lines[0], AfterLine: lines[1]})
+	default:
+		d.add(&Change{Kind: Changed, Path: path, What: what, Before: join(a), After: join(b), BeforeLine: lines[0], AfterLine: lines[1]})
+	}
+}
+
+// from compares FROM clauses. When they join the same tables the same way
+// only the join conditions that changed are reported.
+func (d *Diff) from(path []string, a, b *ast.FromClause, lines [2]int) {
+	if a == nil || b == nil || equal(a, b) {
+		d.clause(path, "FROM", a, b, lines)
+		return
+	}
+	ja, jb := joins(a), joins(b)
+	if len(ja) == 0 || len(ja) != len(jb) {
+		d.changed(path, "FROM", a, b, lines)
+		return
+	}
+	for i := range ja {
+		// The left side of a join is the joins before it, but for the first
+		if ja[i].Type != jb[i].Type || i == 0 && !equal(ja[i].Left, jb[i].Left) || !equal(ja[i].Right, jb[i].Right) {
+			d.changed(path, "FROM", a, b, lines)
+			return
+		}
+	}
+	for i := range ja {
+		d.clause(path, "join condition of "+text(jb[i].Right), ja[i].Condition, jb[i].Condition,
+			[2]int{line(ja[i], lines[0]), line(jb[i], lines[1])})
+	}
+}
+
+// joins returns the joins of a FROM clause of a single table expression,
+// innermost first.
+func joins(from *ast.FromClause) []*ast.JoinClause {
+	if len(from.Tables) != 1 {
+		return nil
+	}
+	var out []*ast.JoinClause
+	for t := from.Tables[0]; ; {
+		j, ok := t.(*ast.JoinClause)
+		if !ok {
+			break
+		}
+		out = append([]*ast.JoinClause{j}, out...)
+		t = j.Left
+	}
+	return out
+}
+
+func (d *Diff) with(path []string, a, b *ast.WithStatement, lines [2]int) {
+	for _, old := range a.CTEs {
+		if cte(b.CTEs, old.Name.Value) == nil {
+			d.add(&Change{Kind: Removed, Path: path, What: "CTE " + old.Name.Value, Before: text(old.Query),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+	}
+	for _, c := range b.CTEs {
+		old := cte(a.CTEs, c.Name.Value)
+		switch {
+		case old == nil:
+			d.add(&Change{Kind: Added, Path: path, What: "CTE " + c.Name.Value, After: text(c.Query),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		case !equal(old.Columns, c.Columns):
+			d.add(&Change{Kind: Changed, Path: path, What: "columns of CTE " + c.Name.Value,
+				Before: string(identifiers(old.Columns)), After: string(identifiers(c.Columns)),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+		if old != nil && old.Query != nil && c.Query != nil {
+			d.query(extend(path, "WITH "+c.Name.Value), old.Query, c.Query,
+				[2]int{line(old.Query, lines[0]), line(c.Query, lines[1])})
+		}
+	}
+	if counterparts(a.Query, b.Query) {
+		d.statement(path, a.Query, b.Query)
+	} else {
+		d.changed(path, "statement", a.Query, b.Query, lines)
+	}
+}
+
+func cte(ctes []*ast.CTEDef, name string) *ast.CTEDef {
+	for _, c := range ctes {
+		if strings.EqualFold(c.Name.Value, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (d *Diff) insert(path []string, a, b *ast.InsertStatement, lines [2]int) {
+	var oldCols, newCols []string
+	for _, c := range a.Columns {
+		oldCols = append(oldCols, c.Value)
+	}
+	for _, c := range b.Columns {
+		newCols = append(newCols, c.Value)
+	}
+	d.names(path, "column", oldCols, newCols, lines)
+	switch {
+	case a.Select != nil && b.Select != nil:
+		d.query(path, a.Select, b.Select, [2]int{line(a.Select, lines[0]), line(b.Select, lines[1])})
+	case !equal(a.Values, b.Values) || !equal(a.Select, b.Select):
+		d.changed(path, "source", source(a), source(b), lines)
+	}
+	d.clause(path, "OUTPUT", a.Output, b.Output, lines)
+
+	rest := func(s ast.InsertStatement) *ast.InsertStatement {
+		s.Columns, s.Values, s.Select, s.Output = nil, nil, nil, nil
+		return &s
+	}
+	if ra, rb := rest(*a), rest(*b); !equal(ra, rb) {
+		d.changed(path, "statement", a, b, lines)
+	}
+}
+
+// source is the rows an INSERT inserts.
+func source(s *ast.InsertStatement) fmt.Stringer {
+	switch {
+	case s.Select != nil:
+		return s.Select
+	case s.DefaultValues:
+		return words("DEFAULT VALUES")
+	}
+	var rows []string
+	for _, row := range s.Values {
+		var values []string
+		for _, v := range row {
+			values = append(values, text(v))
+		}
+		rows = append(rows, "("+strings.Join(values, ", ")+")")
+	}
+	return words("VALUES " + strings.Join(rows, ", "))
+}
+
+// names compares lists of names, ignoring their order.
+func (d *Diff) names(path []string, what string, a, b []string, lines [2]int) {
+	has := func(names []string, name string) bool {
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range a {
+		if !has(b, n) {
+			d.add(&Change{Kind: Removed, Path: path, What: what, Before: n, BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+	}
+	for _, n := range b {
+		if !has(a, n) {
+			d.add(&Change{Kind: Added, Path: path, What: what, After: n, BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+	}
+}
+
+func (d *Diff) update(path []string, a, b *ast.UpdateStatement, lines [2]int) {
+	d.clause(path, "TOP", a.Top, b.Top, lines)
+	set := func(clauses []*ast.SetClause, column string) *ast.SetClause {
+		for _, c := range clauses {
+			if c.Column != nil && strings.EqualFold(c.Column.String(), column) {
+				return c
+			}
+		}
+		return nil
+	}
+	for _, c := range a.SetClauses {
+		if c.Column != nil && set(b.SetClauses, c.Column.String()) == nil {
+			d.add(&Change{Kind: Removed, Path: path, What: "SET " + c.Column.String(), Before: setText(c),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+	}
+	for _, c := range b.SetClauses {
+		if c.Column == nil {
+			continue
+		}
+		old := set(a.SetClauses, c.Column.String())
+		switch {
+		case old == nil:
+			d.add(&Change{Kind: Added, Path: path, What: "SET " + c.Column.String(), After: setText(c),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		case !equal(old, c):
+			d.add(&Change{Kind: Changed, Path: path, What: "SET " + c.Column.String(), Before: setText(old), After: setText(c),
+				BeforeLine: lines[0], AfterLine: lines[1]})
+		}
+	}
+	d.from(path, a.From, b.From, lines)
+	d.clause(path, "WHERE predicate", a.Where, b.Where, lines)
+	d.clause(path, "OUTPUT", a.Output, b.Output, lines)
+
+	rest := func(s ast.UpdateStatement) *ast.UpdateStatement {
+		s.Top, s.SetClauses, s.From, s.Where, s.Output = nil, nil, nil, nil, nil
+		return &s
+	}
+	if ra, rb := rest(*a), rest(*b); !equal(ra, rb) {
+		d.changed(path, "statement", a, b, lines)
+	}
+}
+
+func setText(c *ast.SetClause) string {
+	if c.Value == nil {
+		return c.Column.String()
+	}
+	return c.Column.String() + " " + c.Operator + " " + text(c.Value)
+}
+
+func (d *Diff) delete(path []string, a, b *ast.DeleteStatement, lines [2]int) {
+	d.clause(path, "TOP", a.Top, b.Top, lines)
+	d.from(path, a.From, b.From, lines)
+	d.clause(path, "WHERE predicate", a.Where, b.Where, lines)
+	d.clause(path, "OUTPUT", a.Output, b.Output, lines)
+
+	rest := func(s ast.DeleteStatement) *ast.DeleteStatement {
+		s.Top, s.From, s.Where, s.Output = nil, nil, nil, nil
+		return &s
+	}
+	if ra, rb := rest(*a), rest(*b); !equal(ra, rb) {
+		d.changed(path, "statement", a, b, lines)
+	}
+}
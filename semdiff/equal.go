@@ -0,0 +1,11 @@
+package semdiff
+
+import "github.com/ha1tch/tsqlparser/ast"
+
+// equal reports whether two nodes are the same but for their tokens,
+// which hold positions and keywords as written, case and BEGIN...END
+// around a single statement. The values of string literals are compared
+// exactly.
+func equal(a, b any) bool {
+	return ast.Equal(a, b, ast.EqualOptions{IgnoreTokens: true, IgnoreCase: true, IgnoreQuoting: true, IgnoreBlocks: true})
+}
@@ -0,0 +1,164 @@
+// Package semdiff compares two versions of a script by their syntax trees
+// rather than their text.
+//
+// Whitespace, comments, keyword and identifier case, semicolons and
+// BEGIN...END around a single statement are ignored, so a procedure that
+// has only been reformatted compares equal. What is left is reported
+// statement by statement: statements added, removed or moved, conditions
+// and predicates that changed, columns added to a SELECT list, parameters
+// whose type or default changed:
+//
+//	before, _ := tsqlparser.Parse(oldSource)
+//	after, _ := tsqlparser.Parse(newSource)
+//	diff := semdiff.Compare(before, after)
+//	fmt.Print(diff)
+//
+// A Diff marshals to JSON with encoding/json.
+package semdiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// Kind is the kind of a Change.
+type Kind int
+
+const (
+	Added Kind = iota
+	Removed
+	Moved
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Moved:
+		return "moved"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// MarshalText makes a Kind marshal to JSON as its name.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Change is a single difference between two versions of a script.
+type Change struct {
+	Kind       Kind     `json:"kind"`
+	Path       []string `json:"path,omitempty"` // Enclosing statements, outermost first
+	What       string   `json:"what"`           // What changed, e.g. "statement", "WHERE predicate", "default of @Status"
+	Before     string   `json:"before,omitempty"`
+	After      string   `json:"after,omitempty"`
+	BeforeLine int      `json:"beforeLine,omitempty"`
+	AfterLine  int      `json:"afterLine,omitempty"`
+}
+
+// String describes the change on one line, with the line it is at in the
+// new version, or in the old one for a removed statement. Long texts are
+// shortened.
+func (c *Change) String() string {
+	var out strings.Builder
+	line := c.AfterLine
+	if line == 0 {
+		line = c.BeforeLine
+	}
+	if line > 0 {
+		fmt.Fprintf(&out, "line %d: ", line)
+	}
+	if len(c.Path) > 0 {
+		out.WriteString(strings.Join(c.Path, " > "))
+		out.WriteString(": ")
+	}
+	out.WriteString(c.What)
+	out.WriteString(" ")
+	out.WriteString(c.Kind.String())
+	switch {
+	case c.Kind == Moved && c.BeforeLine > 0:
+		fmt.Fprintf(&out, " from line %d: %s", c.BeforeLine, summary(c.After))
+	case c.Before != "" && c.After != "":
+		out.WriteString(": " + summary(c.Before) + " -> " + summary(c.After))
+	case c.Before != "":
+		out.WriteString(": " + summary(c.Before))
+	case c.After != "":
+		out.WriteString(": " + summary(c.After))
+	}
+	return out.String()
+}
+
+// Diff is the list of changes between two versions of a script, in the
+// order of the new version.
+type Diff struct {
+	Changes []*Change `json:"changes"`
+}
+
+// Empty reports whether the two versions are equivalent.
+func (d *Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *Diff) String() string {
+	var out strings.Builder
+	for _, c := range d.Changes {
+		out.WriteString(c.String())
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// Compare computes the changes that turn before into after.
+func Compare(before, after *ast.Program) *Diff {
+	d := &Diff{Changes: []*Change{}}
+	d.statements(nil, before.Statements, after.Statements)
+	return d
+}
+
+func (d *Diff) add(c *Change) {
+	d.Changes = append(d.Changes, c)
+}
+
+// text renders a node on one line, collapsing whitespace outside string
+// literals.
+func text(n fmt.Stringer) string {
+	var out strings.Builder
+	quoted, space := false, false
+	for _, r := range n.String() {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if space && out.Len() > 0 {
+			out.WriteByte(' ')
+		}
+		space = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// summary shortens a text for String.
+func summary(s string) string {
+	const limit = 72
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit-3]) + "..."
+	}
+	return s
+}
+
+// extend returns path with another element, leaving path alone.
+func extend(path []string, elem string) []string {
+	return append(path[:len(path):len(path)], elem)
+}
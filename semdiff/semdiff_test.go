@@ -0,0 +1,192 @@
+package semdiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+func program(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	p, errs := tsqlparser.Parse(src)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return p
+}
+
+func compare(t *testing.T, before, after string) []string {
+	t.Helper()
+	d := Compare(program(t, before), program(t, after))
+	return strings.Split(strings.TrimSuffix(d.String(), "\n"), "\n")
+}
+
+func check(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+const getOrders = `CREATE PROCEDURE dbo.GetOrders @Status VARCHAR(10) = 'open', @Top INT = 10
+AS
+BEGIN
+    SET NOCOUNT ON;
+    -- the orders
+    SELECT o.Id, o.Total
+    FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId
+    WHERE o.Status = @Status;
+    IF @Top > 100 SET @Top = 100;
+    DELETE FROM dbo.Log;
+END`
+
+func TestReformattingIsNotAChange(t *testing.T) {
+	reformatted := `create   procedure [dbo].[GetOrders]
+    @status varchar(10) = 'open',
+    @top int = 10
+as
+begin
+    set nocount on
+    select o.id, o.total
+      from dbo.orders as o
+      inner join dbo.customers as c
+        on c.id = o.customerid
+     where o.status = @status
+    if @top > 100
+    begin
+        set @top = 100 /* capped */
+    end
+    delete from dbo.log
+end`
+	d := Compare(program(t, getOrders), program(t, reformatted))
+	if !d.Empty() {
+		t.Errorf("reformatting changed:\n%s", d)
+	}
+
+	// The contents of strings are data
+	d = Compare(program(t, getOrders), program(t, strings.Replace(getOrders, "'open'", "'Open'", 1)))
+	if d.Empty() {
+		t.Error("a change to a string was ignored")
+	}
+}
+
+func TestChangesInProcedure(t *testing.T) {
+	got := compare(t, getOrders, `CREATE PROCEDURE dbo.GetOrders @Status VARCHAR(10) = 'all', @Top INT = 10
+AS
+BEGIN
+    SET NOCOUNT ON;
+    DELETE FROM dbo.Log;
+    SELECT o.Id, o.Total, o.CreatedAt
+    FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId AND c.Active = 1
+    WHERE o.Status IN (@Status, 'held');
+    IF @Top > 100 SET @Top = 100;
+    EXEC dbo.Audit;
+END`)
+	check(t, got, []string{
+		"line 1: PROCEDURE dbo.GetOrders: default of @Status changed: 'open' -> 'all'",
+		"line 5: PROCEDURE dbo.GetOrders: statement moved from line 10: DELETE FROM dbo.Log",
+		"line 6: PROCEDURE dbo.GetOrders: column added: o.CreatedAt",
+		"line 7: PROCEDURE dbo.GetOrders: join condition of dbo.Customers AS c changed: (c.Id = o.CustomerId) -> ((c.Id = o.CustomerId) AND (c.Active = 1))",
+		"line 8: PROCEDURE dbo.GetOrders: WHERE predicate changed: (o.Status = @Status) -> o.Status IN (@Status, 'held')",
+		"line 10: PROCEDURE dbo.GetOrders: statement added: EXEC dbo.Audit",
+	})
+}
+
+func TestParameters(t *testing.T) {
+	got := compare(t, getOrders, strings.Replace(getOrders,
+		"@Status VARCHAR(10) = 'open', @Top INT = 10",
+		"@Top BIGINT = 10, @Status VARCHAR(10) = 'open', @Count INT OUTPUT", 1))
+	check(t, got, []string{
+		"line 1: PROCEDURE dbo.GetOrders: type of @Top changed: INT -> BIGINT",
+		"line 1: PROCEDURE dbo.GetOrders: parameter @Count added: @Count INT OUTPUT",
+		"line 1: PROCEDURE dbo.GetOrders: parameter order changed: @Status, @Top -> @Top, @Status",
+	})
+}
+
+func TestNestedStatements(t *testing.T) {
+	got := compare(t, `
+IF @Mode = 1
+BEGIN
+    UPDATE dbo.Stats SET Hits = Hits + 1, Touched = GETDATE() WHERE Id = @Id;
+    PRINT 'one';
+END
+ELSE
+    PRINT 'other';
+`, `
+IF @Mode = 1
+BEGIN
+    UPDATE dbo.Stats SET Hits = Hits + 2 WHERE Id = @Id;
+END
+ELSE
+BEGIN
+    PRINT 'other';
+    RETURN;
+END
+`)
+	check(t, got, []string{
+		"line 4: IF (@Mode = 1): SET Touched removed: Touched = GETDATE()",
+		"line 4: IF (@Mode = 1): SET Hits changed: Hits = (Hits + 1) -> Hits = (Hits + 2)",
+		"line 5: IF (@Mode = 1): statement removed: PRINT 'one'",
+		"line 9: IF (@Mode = 1) > ELSE: statement added: RETURN",
+	})
+
+	got = compare(t, "WHILE @i < 10 SET @i = @i + 1", "WHILE @i <= 10 SET @i = @i + 1")
+	check(t, got, []string{"line 1: condition changed: (@i < 10) -> (@i <= 10)"})
+}
+
+func TestStatementsAddedAndRemoved(t *testing.T) {
+	got := compare(t, `
+CREATE TABLE dbo.A (Id INT);
+GO
+CREATE VIEW dbo.V AS SELECT Id FROM dbo.A;
+`, `
+CREATE TABLE dbo.A (Id INT);
+GO
+CREATE VIEW dbo.W AS SELECT Id FROM dbo.A;
+`)
+	check(t, got, []string{
+		"line 4: statement added: CREATE VIEW dbo.W AS SELECT Id FROM dbo.A",
+		"line 4: statement removed: CREATE VIEW dbo.V AS SELECT Id FROM dbo.A",
+	})
+
+	got = compare(t, "CREATE VIEW dbo.V AS SELECT Id, Name FROM dbo.A", "ALTER VIEW dbo.V AS SELECT Id, Name AS FullName FROM dbo.A")
+	check(t, got, []string{
+		"line 1: statement changed: CREATE VIEW dbo.V -> ALTER VIEW dbo.V",
+		"line 1: VIEW dbo.V: column added: Name AS FullName",
+		"line 1: VIEW dbo.V: column removed: Name",
+	})
+}
+
+func TestRotatedStatements(t *testing.T) {
+	got := compare(t, "PRINT 'a';\nPRINT 'b';\nPRINT 'c';", "PRINT 'c';\nPRINT 'a';\nPRINT 'b';")
+	check(t, got, []string{
+		"line 1: statement moved from line 3: PRINT 'c'",
+	})
+}
+
+func TestWhitespaceInLiterals(t *testing.T) {
+	got := compare(t, "PRINT N'Ab  c'", "PRINT N'Ab c'")
+	check(t, got, []string{
+		"line 1: statement changed: PRINT N'Ab  c' -> PRINT N'Ab c'",
+	})
+}
+
+func TestJSON(t *testing.T) {
+	d := Compare(program(t, "SELECT a FROM t"), program(t, "SELECT a FROM t WHERE b = 1"))
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"changes":[{"kind":"added","what":"WHERE predicate","after":"(b = 1)","beforeLine":1,"afterLine":1}]}`
+	if string(data) != want {
+		t.Errorf("JSON = %s\nwant %s", data, want)
+	}
+
+	data, _ = json.Marshal(Compare(program(t, "SELECT 1"), program(t, "select 1")))
+	if string(data) != `{"changes":[]}` {
+		t.Errorf("JSON = %s", data)
+	}
+}
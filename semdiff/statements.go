@@ -0,0 +1,261 @@
+package semdiff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// statements compares two lists of statements. Versions of the same
+// statement, of the same kind on the same object, are matched keeping
+// their order and preferring equal ones; the rest are moves if an equal
+// statement is elsewhere, and otherwise additions and removals.
+func (d *Diff) statements(path []string, before, after []ast.Statement) {
+	before, after = flatten(before), flatten(after)
+	// An equal pair outweighs any number of changed ones
+	weight := min(len(before), len(after)) + 1
+	pairs := align(len(before), len(after), func(i, j int) int {
+		switch {
+		case !counterparts(before[i], after[j]):
+			return 0
+		case equal(before[i], after[j]):
+			return weight + 1
+		}
+		return 1
+	})
+
+	oldPair := make(map[int]int) // old index -> new index
+	newPair := make(map[int]int)
+	for _, p := range pairs {
+		oldPair[p[0]], newPair[p[1]] = p[1], p[0]
+	}
+	movedFrom := make(map[int]int) // new index -> old index
+	moved := make(map[int]bool)
+	for j := range after {
+		if _, ok := newPair[j]; ok {
+			continue
+		}
+		for i := range before {
+			if _, ok := oldPair[i]; !ok && !moved[i] && equal(before[i], after[j]) {
+				movedFrom[j], moved[i] = i, true
+				break
+			}
+		}
+	}
+
+	next := 0 // the first old statement not yet accounted for
+	removed := func(end int) {
+		for ; next < end; next++ {
+			if _, ok := oldPair[next]; !ok && !moved[next] {
+				d.add(&Change{Kind: Removed, Path: path, What: "statement", Before: text(before[next]),
+					BeforeLine: line(before[next], 0)})
+			}
+		}
+	}
+	for j, s := range after {
+		if i, ok := newPair[j]; ok {
+			removed(i)
+			next = i + 1
+			d.statement(path, before[i], s)
+		} else if i, ok := movedFrom[j]; ok {
+			d.add(&Change{Kind: Moved, Path: path, What: "statement", Before: text(before[i]), After: text(s),
+				BeforeLine: line(before[i], 0), AfterLine: line(s, 0)})
+		} else {
+			d.add(&Change{Kind: Added, Path: path, What: "statement", After: text(s), AfterLine: line(s, 0)})
+		}
+	}
+	removed(len(before))
+}
+
+// align returns the pairs of indexes of two lists that match with the
+// greatest total score, keeping their order. A score of 0 is no match.
+func align(n, m int, score func(i, j int) int) [][2]int {
+	scores := make([][]int, n+1)
+	for i := range scores {
+		scores[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			scores[i][j] = max(scores[i+1][j], scores[i][j+1])
+			if s := score(i, j); s > 0 {
+				scores[i][j] = max(scores[i][j], scores[i+1][j+1]+s)
+			}
+		}
+	}
+	var pairs [][2]int
+	for i, j := 0, 0; i < n && j < m; {
+		switch s := score(i, j); {
+		case s > 0 && scores[i][j] == scores[i+1][j+1]+s:
+			pairs = append(pairs, [2]int{i, j})
+			i++
+			j++
+		case scores[i+1][j] >= scores[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// flatten drops the statements that are only punctuation and unwraps
+// BEGIN...END around a single statement.
+func flatten(stmts []ast.Statement) []ast.Statement {
+	var out []ast.Statement
+	for _, s := range stmts {
+		if s == nil {
+			continue
+		}
+		for {
+			block, ok := s.(*ast.BeginEndBlock)
+			if !ok || len(block.Statements) != 1 {
+				break
+			}
+			s = block.Statements[0]
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// block returns the statements of a statement that may be a BEGIN...END
+// block.
+func block(s ast.Statement) []ast.Statement {
+	if b, ok := s.(*ast.BeginEndBlock); ok && b != nil {
+		return b.Statements
+	}
+	if s == nil || reflect.ValueOf(s).IsNil() {
+		return nil
+	}
+	return []ast.Statement{s}
+}
+
+// counterparts reports whether two statements are versions of the same
+// statement: of the same kind, on the same object.
+func counterparts(a, b ast.Statement) bool {
+	return identity(a) == identity(b)
+}
+
+func identity(s ast.Statement) string {
+	if r := routineOf(s); r != nil {
+		return r.kind + " " + strings.ToLower(r.name)
+	}
+	kind := fmt.Sprintf("%T", s)
+	switch s := s.(type) {
+	case *ast.SetStatement:
+		if s.Variable != nil {
+			return kind + " " + strings.ToLower(s.Variable.String())
+		}
+		return kind + " " + strings.ToUpper(s.Option)
+	case *ast.DeclareStatement:
+		var names []string
+		for _, v := range s.Variables {
+			names = append(names, strings.ToLower(v.Name))
+		}
+		return kind + " " + strings.Join(names, ",")
+	}
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return kind
+	}
+	for _, field := range []string{"Name", "Table", "Procedure"} {
+		f := v.Elem().FieldByName(field)
+		if !f.IsValid() || !f.CanInterface() {
+			continue
+		}
+		if name, ok := f.Interface().(*ast.QualifiedIdentifier); ok && name != nil {
+			return kind + " " + strings.ToLower(name.String())
+		}
+	}
+	return kind
+}
+
+// line returns the line of a node, or or if it has none.
+func line(n any, or int) int {
+	v := reflect.ValueOf(n)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return or
+	}
+	if f := v.Elem().FieldByName("Token"); f.IsValid() {
+		if tok, ok := f.Interface().(token.Token); ok && tok.Line > 0 {
+			return tok.Line
+		}
+	}
+	return or
+}
+
+// statement compares two versions of a statement.
+func (d *Diff) statement(path []string, a, b ast.Statement) {
+	count := len(d.Changes)
+	lines := [2]int{line(a, 0), line(b, 0)}
+	if ra, rb := routineOf(a), routineOf(b); ra != nil && rb != nil {
+		d.routine(path, ra, rb, lines)
+	} else {
+		switch a := a.(type) {
+		case *ast.BeginEndBlock:
+			d.statements(path, a.Statements, b.(*ast.BeginEndBlock).Statements)
+		case *ast.IfStatement:
+			d.ifStatement(path, a, b.(*ast.IfStatement), lines)
+		case *ast.WhileStatement:
+			b := b.(*ast.WhileStatement)
+			d.clause(path, "condition", a.Condition, b.Condition, lines)
+			d.statements(extend(path, "WHILE "+summary(text(b.Condition))), block(a.Body), block(b.Body))
+		case *ast.TryCatchStatement:
+			b := b.(*ast.TryCatchStatement)
+			d.statements(extend(path, "TRY"), block(a.TryBlock), block(b.TryBlock))
+			d.statements(extend(path, "CATCH"), block(a.CatchBlock), block(b.CatchBlock))
+		case *ast.SelectStatement:
+			d.query(path, a, b.(*ast.SelectStatement), lines)
+		case *ast.WithStatement:
+			d.with(path, a, b.(*ast.WithStatement), lines)
+		case *ast.InsertStatement:
+			d.insert(path, a, b.(*ast.InsertStatement), lines)
+		case *ast.UpdateStatement:
+			d.update(path, a, b.(*ast.UpdateStatement), lines)
+		case *ast.DeleteStatement:
+			d.delete(path, a, b.(*ast.DeleteStatement), lines)
+		}
+	}
+	if len(d.Changes) == count && !equal(a, b) {
+		d.changed(path, "statement", a, b, lines)
+	}
+}
+
+func (d *Diff) changed(path []string, what string, a, b fmt.Stringer, lines [2]int) {
+	d.add(&Change{Kind: Changed, Path: path, What: what, Before: text(a), After: text(b),
+		BeforeLine: lines[0], AfterLine: lines[1]})
+}
+
+// clause compares an optional part of two statements.
+func (d *Diff) clause(path []string, what string, a, b fmt.Stringer, lines [2]int) {
+	absentA, absentB := isNil(a), isNil(b)
+	lines = [2]int{line(a, lines[0]), line(b, lines[1])}
+	switch {
+	case absentA && absentB:
+	case absentA:
+		d.add(&Change{Kind: Added, Path: path, What: what, After: text(b), BeforeLine: lines[0], AfterLine: lines[1]})
+	case absentB:
+		d.add(&Change{Kind: Removed, Path: path, What: what, Before: text(a), BeforeLine: lines[0], AfterLine: lines[1]})
+	case !equal(a, b):
+		d.changed(path, what, a, b, lines)
+	}
+}
+
+func isNil(n any) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+func (d *Diff) ifStatement(path []string, a, b *ast.IfStatement, lines [2]int) {
+	d.clause(path, "condition", a.Condition, b.Condition, lines)
+	inner := extend(path, "IF "+summary(text(b.Condition)))
+	d.statements(inner, block(a.Consequence), block(b.Consequence))
+	// ELSE IF chains compare as nested IFs
+	d.statements(extend(inner, "ELSE"), block(a.Alternative), block(b.Alternative))
+}
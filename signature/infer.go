@@ -0,0 +1,409 @@
+package signature
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// builtinTypes lists the system data types. Any other type name used for
+// a READONLY parameter is a user-defined table type.
+var builtinTypes = map[string]bool{
+	"BIGINT": true, "INT": true, "INTEGER": true, "SMALLINT": true, "TINYINT": true, "BIT": true,
+	"DECIMAL": true, "DEC": true, "NUMERIC": true, "MONEY": true, "SMALLMONEY": true,
+	"FLOAT": true, "REAL": true,
+	"DATE": true, "TIME": true, "DATETIME": true, "DATETIME2": true, "SMALLDATETIME": true, "DATETIMEOFFSET": true,
+	"CHAR": true, "VARCHAR": true, "TEXT": true, "NCHAR": true, "NVARCHAR": true, "NTEXT": true,
+	"BINARY": true, "VARBINARY": true, "IMAGE": true,
+	"UNIQUEIDENTIFIER": true, "XML": true, "SQL_VARIANT": true, "HIERARCHYID": true,
+	"GEOGRAPHY": true, "GEOMETRY": true, "ROWVERSION": true, "TIMESTAMP": true,
+	"SYSNAME": true, "CURSOR": true, "TABLE": true, "JSON": true, "VECTOR": true,
+}
+
+func isBuiltinType(name string) bool {
+	return builtinTypes[strings.ToUpper(name)]
+}
+
+// typePrecedence follows SQL Server's data type precedence; when two
+// types meet in an expression the one with the higher value wins.
+var typePrecedence = map[string]int{
+	"DATETIMEOFFSET": 30, "DATETIME2": 29, "DATETIME": 28, "SMALLDATETIME": 27, "DATE": 26, "TIME": 25,
+	"FLOAT": 24, "REAL": 23, "DECIMAL": 22, "NUMERIC": 22, "MONEY": 21, "SMALLMONEY": 20,
+	"BIGINT": 19, "INT": 18, "SMALLINT": 17, "TINYINT": 16, "BIT": 15,
+	"NTEXT": 14, "TEXT": 13, "IMAGE": 12, "TIMESTAMP": 11, "UNIQUEIDENTIFIER": 10,
+	"NVARCHAR": 9, "NCHAR": 8, "VARCHAR": 7, "CHAR": 6, "VARBINARY": 5, "BINARY": 4,
+}
+
+// fixedFunctionTypes maps built-in functions to the type they always return.
+var fixedFunctionTypes = map[string]*ast.DataType{
+	"COUNT":             {Name: "INT"},
+	"COUNT_BIG":         {Name: "BIGINT"},
+	"ROW_NUMBER":        {Name: "BIGINT"},
+	"RANK":              {Name: "BIGINT"},
+	"DENSE_RANK":        {Name: "BIGINT"},
+	"NTILE":             {Name: "BIGINT"},
+	"GETDATE":           {Name: "DATETIME"},
+	"GETUTCDATE":        {Name: "DATETIME"},
+	"CURRENT_TIMESTAMP": {Name: "DATETIME"},
+	"SYSDATETIME":       {Name: "DATETIME2"},
+	"SYSUTCDATETIME":    {Name: "DATETIME2"},
+	"SYSDATETIMEOFFSET": {Name: "DATETIMEOFFSET"},
+	"NEWID":             {Name: "UNIQUEIDENTIFIER"},
+	"NEWSEQUENTIALID":   {Name: "UNIQUEIDENTIFIER"},
+	"LEN":               {Name: "INT"},
+	"DATALENGTH":        {Name: "INT"},
+	"CHARINDEX":         {Name: "INT"},
+	"PATINDEX":          {Name: "INT"},
+	"DATEDIFF":          {Name: "INT"},
+	"DATEDIFF_BIG":      {Name: "BIGINT"},
+	"DATEPART":          {Name: "INT"},
+	"YEAR":              {Name: "INT"},
+	"MONTH":             {Name: "INT"},
+	"DAY":               {Name: "INT"},
+	"DATENAME":          {Name: "NVARCHAR", Precision: intPtr(30)},
+	"EOMONTH":           {Name: "DATE"},
+	"DATEFROMPARTS":     {Name: "DATE"},
+	"ISNUMERIC":         {Name: "INT"},
+	"ISDATE":            {Name: "INT"},
+	"@@ROWCOUNT":        {Name: "INT"},
+	"@@ERROR":           {Name: "INT"},
+	"@@TRANCOUNT":       {Name: "INT"},
+	"@@IDENTITY":        {Name: "NUMERIC", Precision: intPtr(38), Scale: intPtr(0)},
+	"SCOPE_IDENTITY":    {Name: "NUMERIC", Precision: intPtr(38), Scale: intPtr(0)},
+	"ERROR_NUMBER":      {Name: "INT"},
+	"ERROR_SEVERITY":    {Name: "INT"},
+	"ERROR_STATE":       {Name: "INT"},
+	"ERROR_LINE":        {Name: "INT"},
+	"ERROR_MESSAGE":     {Name: "NVARCHAR", Precision: intPtr(4000)},
+	"ERROR_PROCEDURE":   {Name: "NVARCHAR", Precision: intPtr(128)},
+	"OBJECT_ID":         {Name: "INT"},
+	"OBJECT_NAME":       {Name: "SYSNAME"},
+	"DB_NAME":           {Name: "NVARCHAR", Precision: intPtr(128)},
+	"SUSER_SNAME":       {Name: "NVARCHAR", Precision: intPtr(128)},
+	"USER_NAME":         {Name: "NVARCHAR", Precision: intPtr(128)},
+	"CONCAT":            {Name: "NVARCHAR", Max: true},
+	"STRING_AGG":        {Name: "NVARCHAR", Max: true},
+	"FORMAT":            {Name: "NVARCHAR", Max: true},
+	"JSON_VALUE":        {Name: "NVARCHAR", Precision: intPtr(4000)},
+	"JSON_QUERY":        {Name: "NVARCHAR", Max: true},
+	"ISJSON":            {Name: "INT"},
+	"CHECKSUM":          {Name: "INT"},
+	"BINARY_CHECKSUM":   {Name: "INT"},
+	"HASHBYTES":         {Name: "VARBINARY", Precision: intPtr(8000)},
+}
+
+// fixedType returns a copy of the type a built-in function always
+// returns, or nil, so that callers may change it.
+func fixedType(name string) *ast.DataType {
+	dt, ok := fixedFunctionTypes[strings.ToUpper(name)]
+	if !ok {
+		return nil
+	}
+	return ast.Clone(dt)
+}
+
+// argumentTypeFunctions return the type of (one of) their arguments.
+var argumentTypeFunctions = map[string]bool{
+	"ISNULL": true, "COALESCE": true, "NULLIF": true, "MIN": true, "MAX": true,
+	"ABS": true, "ROUND": true, "FLOOR": true, "CEILING": true,
+	"UPPER": true, "LOWER": true, "LTRIM": true, "RTRIM": true, "TRIM": true,
+	"LEFT": true, "RIGHT": true, "SUBSTRING": true, "REPLACE": true, "REVERSE": true,
+	"DATEADD": true, "LAG": true, "LEAD": true, "FIRST_VALUE": true, "LAST_VALUE": true,
+}
+
+// infer returns the data type of expr, or nil if it cannot be determined.
+func (sc *scope) infer(expr ast.Expression, src sources) *ast.DataType {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		if e.Value > math.MaxInt32 || e.Value < math.MinInt32 {
+			return &ast.DataType{Name: "BIGINT"}
+		}
+		return &ast.DataType{Name: "INT"}
+	case *ast.FloatLiteral:
+		lit := e.Token.Literal
+		if strings.ContainsAny(lit, "eE") {
+			return &ast.DataType{Name: "FLOAT"}
+		}
+		digits := strings.TrimLeft(strings.Replace(lit, ".", "", 1), "0")
+		scale := 0
+		if i := strings.Index(lit, "."); i >= 0 {
+			scale = len(lit) - i - 1
+		}
+		precision := len(digits)
+		if precision < scale {
+			precision = scale
+		}
+		if precision == 0 {
+			precision = 1
+		}
+		return &ast.DataType{Name: "DECIMAL", Precision: intPtr(precision), Scale: intPtr(scale)}
+	case *ast.MoneyLiteral:
+		return &ast.DataType{Name: "MONEY"}
+	case *ast.StringLiteral:
+		n := len(e.Value)
+		if e.Unicode {
+			n = len([]rune(e.Value))
+		}
+		if n == 0 {
+			n = 1
+		}
+		if e.Unicode {
+			return &ast.DataType{Name: "NVARCHAR", Precision: intPtr(n)}
+		}
+		return &ast.DataType{Name: "VARCHAR", Precision: intPtr(n)}
+	case *ast.BinaryLiteral:
+		return &ast.DataType{Name: "VARBINARY"}
+	case *ast.Variable:
+		if strings.HasPrefix(e.Name, "@@") {
+			return fixedType(e.Name)
+		}
+		return sc.variables[strings.ToLower(e.Name)]
+	case *ast.CastExpression:
+		return e.TargetType
+	case *ast.ConvertExpression:
+		return e.TargetType
+	case *ast.ParseExpression:
+		return e.TargetType
+	case *ast.CollateExpression:
+		return sc.infer(e.Expr, src)
+	case *ast.PrefixExpression:
+		if strings.EqualFold(e.Operator, "NOT") {
+			return nil
+		}
+		return sc.infer(e.Right, src)
+	case *ast.InfixExpression:
+		return sc.inferInfix(e, src)
+	case *ast.CaseExpression:
+		for _, w := range e.WhenClauses {
+			if dt := sc.infer(w.Result, src); dt != nil {
+				return dt
+			}
+		}
+		if e.ElseClause != nil {
+			return sc.infer(e.ElseClause, src)
+		}
+	case *ast.FunctionCall:
+		return sc.inferFunction(e, src)
+	case *ast.GreatestLeastExpression:
+		var result *ast.DataType
+		for _, arg := range e.Arguments {
+			dt := sc.infer(arg, src)
+			if dt == nil {
+				return nil
+			}
+			if result == nil || typePrecedence[strings.ToUpper(dt.Name)] > typePrecedence[strings.ToUpper(result.Name)] {
+				result = dt
+			}
+		}
+		return result
+	case *ast.DateTruncExpression:
+		return sc.infer(e.Date, src)
+	case *ast.DateBucketExpression:
+		return sc.infer(e.Date, src)
+	case *ast.RegexpExpression:
+		switch e.Function {
+		case ast.RegexpReplace, ast.RegexpSubstr:
+			return sc.infer(e.Source, src)
+		case ast.RegexpInstr, ast.RegexpCount:
+			return &ast.DataType{Name: "INT"}
+		}
+	case *ast.VectorDistanceExpression:
+		return &ast.DataType{Name: "FLOAT"}
+	case *ast.JsonObjectExpression:
+		return jsonType(e.Returning)
+	case *ast.JsonArrayExpression:
+		return jsonType(e.Returning)
+	case *ast.JsonObjectAggExpression:
+		return jsonType(e.Returning)
+	case *ast.JsonArrayAggExpression:
+		return jsonType(e.Returning)
+	case *ast.SubqueryExpression:
+		cols := sc.selectColumns(e.Subquery)
+		if len(cols) == 1 {
+			return cols[0].DataType
+		}
+	case *ast.Identifier, *ast.QualifiedIdentifier:
+		if col, _ := src.resolve(e); col != nil {
+			return col.DataType
+		}
+		if id, ok := e.(*ast.Identifier); ok && strings.EqualFold(id.Value, "CURRENT_TIMESTAMP") {
+			return fixedType("CURRENT_TIMESTAMP")
+		}
+	}
+	return nil
+}
+
+func (sc *scope) inferInfix(e *ast.InfixExpression, src sources) *ast.DataType {
+	switch strings.ToUpper(e.Operator) {
+	case "+", "-", "*", "/", "%", "&", "|", "^", "||":
+	default:
+		return nil // Comparisons and logical operators are not values
+	}
+	left := sc.infer(e.Left, src)
+	right := sc.infer(e.Right, src)
+	if left == nil || right == nil {
+		return nil
+	}
+	if (e.Operator == "+" || e.Operator == "||") && isString(left) && isString(right) {
+		return concatType(left, right)
+	}
+	if typePrecedence[strings.ToUpper(right.Name)] > typePrecedence[strings.ToUpper(left.Name)] {
+		return right
+	}
+	return left
+}
+
+// jsonType returns the type of a JSON constructor, which is the json type
+// when asked for with RETURNING json.
+func jsonType(returning *ast.DataType) *ast.DataType {
+	if returning != nil {
+		return returning
+	}
+	return &ast.DataType{Name: "NVARCHAR", Max: true}
+}
+
+func (sc *scope) inferFunction(fc *ast.FunctionCall, src sources) *ast.DataType {
+	name := strings.ToUpper(functionName(fc.Function))
+	if dt := fixedType(name); dt != nil {
+		return dt
+	}
+	switch name {
+	case "IIF":
+		if len(fc.Arguments) < 2 {
+			return nil
+		}
+		for _, arg := range fc.Arguments[1:] {
+			if dt := sc.infer(arg, src); dt != nil {
+				return dt
+			}
+		}
+		return nil
+	case "SUM", "AVG":
+		if len(fc.Arguments) == 0 {
+			return nil
+		}
+		dt := sc.infer(fc.Arguments[0], src)
+		if dt == nil {
+			return nil
+		}
+		switch strings.ToUpper(dt.Name) {
+		case "TINYINT", "SMALLINT", "BIT":
+			return &ast.DataType{Name: "INT"}
+		case "DECIMAL", "NUMERIC":
+			return &ast.DataType{Name: "DECIMAL", Precision: intPtr(38), Scale: dt.Scale}
+		case "SMALLMONEY":
+			return &ast.DataType{Name: "MONEY"}
+		case "REAL":
+			return &ast.DataType{Name: "FLOAT"}
+		}
+		return dt
+	}
+	if argumentTypeFunctions[name] {
+		args := fc.Arguments
+		if name == "DATEADD" && len(args) == 3 {
+			args = args[2:]
+		}
+		for _, arg := range args {
+			if dt := sc.infer(arg, src); dt != nil {
+				return dt
+			}
+		}
+	}
+	return nil
+}
+
+func functionName(fn ast.Expression) string {
+	switch f := fn.(type) {
+	case *ast.Identifier:
+		return f.Value
+	case *ast.QualifiedIdentifier:
+		return f.String()
+	}
+	return ""
+}
+
+// nullability reports whether expr may be NULL, or nil when unknown.
+func (sc *scope) nullability(expr ast.Expression, src sources) *bool {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.MoneyLiteral, *ast.StringLiteral, *ast.BinaryLiteral:
+		return boolPtr(false)
+	case *ast.NullLiteral:
+		return boolPtr(true)
+	case *ast.Identifier, *ast.QualifiedIdentifier:
+		col, s := src.resolve(e)
+		if col == nil {
+			return nil
+		}
+		if s.nullable {
+			return boolPtr(true)
+		}
+		return col.Nullable
+	case *ast.FunctionCall:
+		switch strings.ToUpper(functionName(e.Function)) {
+		case "COUNT", "COUNT_BIG", "ROW_NUMBER", "RANK", "DENSE_RANK", "NTILE",
+			"GETDATE", "GETUTCDATE", "SYSDATETIME", "SYSUTCDATETIME", "SYSDATETIMEOFFSET", "NEWID", "CONCAT":
+			return boolPtr(false)
+		case "ISNULL":
+			if len(e.Arguments) != 2 {
+				break
+			}
+			// NULL only if both arguments are
+			check := sc.nullability(e.Arguments[0], src)
+			replacement := sc.nullability(e.Arguments[1], src)
+			switch {
+			case check != nil && !*check || replacement != nil && !*replacement:
+				return boolPtr(false)
+			case check != nil && replacement != nil:
+				return boolPtr(true)
+			}
+		case "COALESCE":
+			for _, arg := range e.Arguments {
+				if n := sc.nullability(arg, src); n != nil && !*n {
+					return boolPtr(false)
+				}
+			}
+		}
+	case *ast.InfixExpression:
+		left := sc.nullability(e.Left, src)
+		right := sc.nullability(e.Right, src)
+		if left != nil && right != nil {
+			return boolPtr(*left || *right)
+		}
+	}
+	return nil
+}
+
+func isString(dt *ast.DataType) bool {
+	switch strings.ToUpper(dt.Name) {
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR":
+		return true
+	}
+	return false
+}
+
+// concatType returns the type of left + right for two string types.
+func concatType(left, right *ast.DataType) *ast.DataType {
+	unicode := strings.HasPrefix(strings.ToUpper(left.Name), "N") || strings.HasPrefix(strings.ToUpper(right.Name), "N")
+	dt := &ast.DataType{Name: "VARCHAR"}
+	limit := 8000
+	if unicode {
+		dt.Name = "NVARCHAR"
+		limit = 4000
+	}
+	if left.Max || right.Max || left.Precision == nil || right.Precision == nil {
+		dt.Max = true
+		return dt
+	}
+	n := *left.Precision + *right.Precision
+	if n > limit {
+		dt.Max = true
+		return dt
+	}
+	dt.Precision = intPtr(n)
+	return dt
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,456 @@
+package signature
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// scope tracks the variables and local tables visible while walking a
+// routine body.
+type scope struct {
+	e         *Extractor
+	variables map[string]*ast.DataType
+	tables    map[string][]*Column // #temp tables, table variables and CTEs
+}
+
+func (e *Extractor) newScope(params []*Parameter) *scope {
+	sc := &scope{
+		e:         e,
+		variables: make(map[string]*ast.DataType),
+		tables:    make(map[string][]*Column),
+	}
+	for _, p := range params {
+		if p.TableValued {
+			if cols, ok := sc.lookupTable(typeName(p.DataType)); ok {
+				sc.tables[strings.ToLower(p.Name)] = cols
+			}
+			continue
+		}
+		sc.variables[strings.ToLower(p.Name)] = p.DataType
+	}
+	return sc
+}
+
+// typeName converts a user-defined type name such as dbo.OrderLines into
+// an identifier that can be looked up like a table.
+func typeName(dt *ast.DataType) *ast.QualifiedIdentifier {
+	name := &ast.QualifiedIdentifier{}
+	for _, part := range strings.Split(dt.Name, ".") {
+		name.Parts = append(name.Parts, &ast.Identifier{Value: part})
+	}
+	return name
+}
+
+// child returns a scope that sees everything in sc but whose new tables
+// (CTEs) do not leak back into sc.
+func (sc *scope) child() *scope {
+	c := &scope{e: sc.e, variables: sc.variables, tables: make(map[string][]*Column)}
+	for k, v := range sc.tables {
+		c.tables[k] = v
+	}
+	return c
+}
+
+func (sc *scope) lookupTable(name *ast.QualifiedIdentifier) ([]*Column, bool) {
+	if name == nil || len(name.Parts) == 0 {
+		return nil, false
+	}
+	last := strings.ToLower(name.Parts[len(name.Parts)-1].Value)
+	if len(name.Parts) == 1 {
+		if cols, ok := sc.tables[last]; ok {
+			return cols, true
+		}
+	}
+	if cols, ok := sc.e.tables[strings.ToLower(name.String())]; ok {
+		return cols, true
+	}
+	if cols, ok := sc.e.tables[last]; ok {
+		return cols, true
+	}
+	return nil, false
+}
+
+func (sc *scope) statements(stmts []ast.Statement, conditional bool) []*ResultSet {
+	var sets []*ResultSet
+	for _, stmt := range stmts {
+		sets = append(sets, sc.statement(stmt, conditional)...)
+	}
+	return sets
+}
+
+func (sc *scope) statement(stmt ast.Statement, conditional bool) []*ResultSet {
+	switch s := stmt.(type) {
+	case *ast.DeclareStatement:
+		for _, v := range s.Variables {
+			if v.TableType != nil {
+				sc.tables[strings.ToLower(v.Name)] = columnsFromDefinitions(v.TableType.Columns)
+			} else {
+				sc.variables[strings.ToLower(v.Name)] = v.DataType
+			}
+		}
+	case *ast.CreateTableStatement:
+		if s.IsTemporary {
+			sc.tables[strings.ToLower(s.Name.String())] = columnsFromDefinitions(s.Columns)
+		}
+	case *ast.SelectStatement:
+		if s.Into != nil {
+			sc.tables[strings.ToLower(s.Into.String())] = sc.selectColumns(s)
+			return nil
+		}
+		if isAssignment(s) {
+			return nil
+		}
+		return []*ResultSet{{Columns: sc.selectColumns(s), Source: s, Conditional: conditional}}
+	case *ast.WithStatement:
+		c := sc.child()
+		for _, cte := range s.CTEs {
+			c.addCTE(cte)
+		}
+		return c.statement(s.Query, conditional)
+	case *ast.InsertStatement:
+		return sc.outputResultSet(s, s.Output, s.Table, nil, conditional)
+	case *ast.UpdateStatement:
+		return sc.outputResultSet(s, s.Output, s.Table, s.From, conditional)
+	case *ast.DeleteStatement:
+		return sc.outputResultSet(s, s.Output, s.Table, s.From, conditional)
+	case *ast.MergeStatement:
+		return sc.outputResultSet(s, s.Output, s.Target, nil, conditional)
+	case *ast.ExecStatement:
+		var sets []*ResultSet
+		for _, def := range s.ResultSets {
+			rs := &ResultSet{Source: s, Declared: true, Conditional: conditional}
+			for _, col := range def.Columns {
+				c := &Column{Name: col.Name, DataType: col.DataType}
+				if col.HasNull {
+					c.Nullable = boolPtr(col.Nullable)
+				}
+				rs.Columns = append(rs.Columns, c)
+			}
+			sets = append(sets, rs)
+		}
+		return sets
+	case *ast.BeginEndBlock:
+		return sc.statements(s.Statements, conditional)
+	case *ast.IfStatement:
+		sets := sc.statement(s.Consequence, true)
+		if s.Alternative != nil {
+			sets = append(sets, sc.statement(s.Alternative, true)...)
+		}
+		return sets
+	case *ast.WhileStatement:
+		return sc.statement(s.Body, true)
+	case *ast.TryCatchStatement:
+		var sets []*ResultSet
+		if s.TryBlock != nil {
+			sets = sc.statements(s.TryBlock.Statements, conditional)
+		}
+		if s.CatchBlock != nil {
+			sets = append(sets, sc.statements(s.CatchBlock.Statements, true)...)
+		}
+		return sets
+	}
+	return nil
+}
+
+func (sc *scope) addCTE(cte *ast.CTEDef) {
+	if cte.Query == nil {
+		return
+	}
+	cols := sc.selectColumns(cte.Query)
+	for i, name := range cte.Columns {
+		if i < len(cols) {
+			renamed := *cols[i]
+			renamed.Name = name.Value
+			cols[i] = &renamed
+		} else {
+			cols = append(cols, &Column{Name: name.Value})
+		}
+	}
+	sc.tables[strings.ToLower(cte.Name.Value)] = cols
+}
+
+// outputResultSet returns the result set produced by an OUTPUT clause
+// that does not write INTO a table.
+func (sc *scope) outputResultSet(stmt ast.Statement, out *ast.OutputClause, target *ast.QualifiedIdentifier, from *ast.FromClause, conditional bool) []*ResultSet {
+	if out == nil || out.Into != nil || out.IntoVariable != nil {
+		return nil
+	}
+	var src sources
+	if cols, ok := sc.lookupTable(target); ok {
+		src = append(src, &source{name: "inserted", columns: cols}, &source{name: "deleted", columns: cols})
+	}
+	if from != nil {
+		for _, ref := range from.Tables {
+			src = sc.collectSources(src, ref, false)
+		}
+	}
+	return []*ResultSet{{Columns: sc.columns(out.Columns, src), Source: stmt, Conditional: conditional}}
+}
+
+func isAssignment(sel *ast.SelectStatement) bool {
+	for _, col := range sel.Columns {
+		if col.Variable != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// source is a table visible in a FROM clause.
+type source struct {
+	name     string    // Alias, or the unqualified table name
+	columns  []*Column // nil when the table is unknown
+	nullable bool      // On the outer side of an outer join
+}
+
+type sources []*source
+
+func (src sources) find(name string) *source {
+	for _, s := range src {
+		if strings.EqualFold(s.name, name) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (sc *scope) collectSources(src sources, ref ast.TableReference, nullable bool) sources {
+	switch t := ref.(type) {
+	case *ast.TableName:
+		s := &source{name: t.Name.Parts[len(t.Name.Parts)-1].Value, nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		s.columns, _ = sc.lookupTable(t.Name)
+		src = append(src, s)
+	case *ast.DerivedTable:
+		s := &source{nullable: nullable, columns: renameColumns(sc.selectColumns(t.Subquery), t.ColumnAliases)}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		src = append(src, s)
+	case *ast.TableValuedFunction:
+		s := &source{nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		for _, col := range t.OpenJsonColumns {
+			s.columns = append(s.columns, &Column{Name: col.Name, DataType: col.DataType})
+		}
+		if len(t.ColumnAliases) > 0 {
+			s.columns = renameColumns(s.columns, t.ColumnAliases)
+		}
+		src = append(src, s)
+	case *ast.BulkRowset:
+		s := &source{nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		for _, col := range t.Columns {
+			s.columns = append(s.columns, &Column{Name: col.Name, DataType: col.DataType})
+		}
+		if len(t.ColumnAliases) > 0 {
+			s.columns = renameColumns(s.columns, t.ColumnAliases)
+		}
+		src = append(src, s)
+	case *ast.GenerateSeries:
+		s := &source{nullable: nullable, columns: []*Column{{Name: "value", DataType: sc.infer(t.Start, nil)}}}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		if len(t.ColumnAliases) > 0 {
+			s.columns = renameColumns(s.columns, t.ColumnAliases)
+		}
+		src = append(src, s)
+	case *ast.ValuesTable:
+		s := &source{nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		for i, name := range t.Columns {
+			col := &Column{Name: name.Value}
+			if len(t.Rows) > 0 && i < len(t.Rows[0]) {
+				col.DataType = sc.infer(t.Rows[0][i], nil)
+			}
+			s.columns = append(s.columns, col)
+		}
+		src = append(src, s)
+	case *ast.JoinClause:
+		leftNullable, rightNullable := nullable, nullable
+		switch t.Type {
+		case "LEFT", "OUTER APPLY":
+			rightNullable = true
+		case "RIGHT":
+			leftNullable = true
+		case "FULL":
+			leftNullable, rightNullable = true, true
+		}
+		src = sc.collectSources(src, t.Left, leftNullable)
+		src = sc.collectSources(src, t.Right, rightNullable)
+	case *ast.ParenthesizedTableRef:
+		src = sc.collectSources(src, t.Inner, nullable)
+	case *ast.PivotTable:
+		s := &source{nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		src = append(src, s)
+	case *ast.UnpivotTable:
+		s := &source{nullable: nullable}
+		if t.Alias != nil {
+			s.name = t.Alias.Value
+		}
+		src = append(src, s)
+	}
+	return src
+}
+
+func renameColumns(cols []*Column, names []*ast.Identifier) []*Column {
+	if len(names) == 0 {
+		return cols
+	}
+	var out []*Column
+	for i, name := range names {
+		col := &Column{Name: name.Value}
+		if i < len(cols) {
+			renamed := *cols[i]
+			renamed.Name = name.Value
+			col = &renamed
+		}
+		out = append(out, col)
+	}
+	return out
+}
+
+// selectColumns infers the columns produced by a SELECT statement.
+func (sc *scope) selectColumns(sel *ast.SelectStatement) []*Column {
+	var src sources
+	if sel.From != nil {
+		for _, ref := range sel.From.Tables {
+			src = sc.collectSources(src, ref, false)
+		}
+	}
+	cols := sc.columns(sel.Columns, src)
+
+	// Fill in types the first branch of a set operation leaves unknown.
+	for u := sel.Union; u != nil && u.Right != nil; u = u.Right.Union {
+		right := sc.selectColumns(u.Right)
+		for i, col := range cols {
+			if i < len(right) && col.DataType == nil {
+				col.DataType = right[i].DataType
+			}
+		}
+	}
+	return cols
+}
+
+func (sc *scope) columns(list []ast.SelectColumn, src sources) []*Column {
+	var cols []*Column
+	for _, item := range list {
+		if item.AllColumns {
+			cols = append(cols, expandWildcard("*", src, src)...)
+			continue
+		}
+		expr := item.Expression
+		name := ""
+		if item.Alias != nil {
+			name = item.Alias.Value
+		} else if infix, ok := expr.(*ast.InfixExpression); ok && infix.Operator == "=" {
+			// SELECT Name = expr
+			if id, ok := infix.Left.(*ast.Identifier); ok {
+				name = id.Value
+				expr = infix.Right
+			}
+		}
+		if q, ok := expr.(*ast.QualifiedIdentifier); ok && len(q.Parts) > 1 && q.Parts[len(q.Parts)-1].Value == "*" {
+			qualifier := q.Parts[len(q.Parts)-2].Value
+			var only sources
+			if s := src.find(qualifier); s != nil {
+				only = sources{s}
+			}
+			cols = append(cols, expandWildcard(q.String(), only, src)...)
+			continue
+		}
+		if id, ok := expr.(*ast.Identifier); ok && id.Value == "*" {
+			cols = append(cols, expandWildcard("*", src, src)...)
+			continue
+		}
+		if name == "" {
+			name = columnName(expr)
+		}
+		cols = append(cols, &Column{
+			Name:       name,
+			DataType:   sc.infer(expr, src),
+			Nullable:   sc.nullability(expr, src),
+			Expression: expr,
+		})
+	}
+	return cols
+}
+
+// expandWildcard expands SELECT * (or alias.*) against the given sources,
+// falling back to a single wildcard column when any of them is unknown.
+func expandWildcard(text string, expand sources, all sources) []*Column {
+	if len(expand) == 0 {
+		return []*Column{{Name: text}}
+	}
+	var cols []*Column
+	for _, s := range expand {
+		if s.columns == nil {
+			return []*Column{{Name: text}}
+		}
+		for _, col := range s.columns {
+			c := *col
+			if s.nullable {
+				c.Nullable = boolPtr(true)
+			}
+			cols = append(cols, &c)
+		}
+	}
+	return cols
+}
+
+// columnName returns the name SQL Server gives an unaliased select-list expression.
+func columnName(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.QualifiedIdentifier:
+		return e.Parts[len(e.Parts)-1].Value
+	}
+	return ""
+}
+
+// resolve finds the column a (possibly qualified) identifier refers to.
+func (src sources) resolve(expr ast.Expression) (*Column, *source) {
+	var qualifier, name string
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		name = e.Value
+	case *ast.QualifiedIdentifier:
+		name = e.Parts[len(e.Parts)-1].Value
+		if len(e.Parts) > 1 {
+			qualifier = e.Parts[len(e.Parts)-2].Value
+		}
+	default:
+		return nil, nil
+	}
+
+	var found *Column
+	var foundIn *source
+	for _, s := range src {
+		if qualifier != "" && !strings.EqualFold(s.name, qualifier) {
+			continue
+		}
+		for _, col := range s.columns {
+			if strings.EqualFold(col.Name, name) {
+				if found != nil {
+					return nil, nil // ambiguous
+				}
+				found, foundIn = col, s
+			}
+		}
+	}
+	return found, foundIn
+}
@@ -0,0 +1,294 @@
+// Package signature extracts the callable signature of T-SQL stored
+// procedures and functions, and infers the shape of the result sets
+// they return.
+//
+// Everything is derived from the AST alone, so it works without a
+// database connection. Column types are resolved from literals,
+// declared variables, CAST/CONVERT targets, built-in functions and any
+// tables the Extractor has been told about (including #temp tables,
+// table variables and CTEs declared in the procedure itself). Types
+// that cannot be determined are left nil.
+//
+// Example usage:
+//
+//	program, _ := tsqlparser.Parse(script)
+//	for _, sig := range signature.FromProgram(program) {
+//	    fmt.Println(sig.Name, len(sig.Parameters), len(sig.ResultSets))
+//	}
+package signature
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// Kind identifies the kind of routine a Signature describes.
+type Kind int
+
+const (
+	KindProcedure Kind = iota
+	KindScalarFunction
+	KindInlineTableFunction
+	KindMultiStatementTableFunction
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindProcedure:
+		return "PROCEDURE"
+	case KindScalarFunction:
+		return "SCALAR FUNCTION"
+	case KindInlineTableFunction:
+		return "INLINE TABLE FUNCTION"
+	case KindMultiStatementTableFunction:
+		return "MULTI-STATEMENT TABLE FUNCTION"
+	}
+	return "UNKNOWN"
+}
+
+// Signature describes the parameters and outputs of a procedure or function.
+type Signature struct {
+	Name       *ast.QualifiedIdentifier
+	Kind       Kind
+	Parameters []*Parameter
+	ReturnType *ast.DataType // For scalar functions
+	ResultSets []*ResultSet  // Result sets (procedures) or the returned table (TVFs)
+}
+
+// UsesTableValuedParameters reports whether any parameter is a table-valued parameter.
+func (s *Signature) UsesTableValuedParameters() bool {
+	for _, p := range s.Parameters {
+		if p.TableValued {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputParameters returns the parameters declared OUTPUT.
+func (s *Signature) OutputParameters() []*Parameter {
+	var params []*Parameter
+	for _, p := range s.Parameters {
+		if p.Output {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// Parameter describes a single routine parameter.
+type Parameter struct {
+	Name        string // Including the leading @
+	DataType    *ast.DataType
+	Default     ast.Expression // nil if the parameter has no default
+	Output      bool
+	ReadOnly    bool
+	TableValued bool // READONLY parameter of a user-defined table type
+}
+
+// HasDefault reports whether the parameter may be omitted by the caller.
+func (p *Parameter) HasDefault() bool {
+	return p.Default != nil
+}
+
+// ResultSet describes the shape of one result set.
+type ResultSet struct {
+	Columns     []*Column
+	Source      ast.Statement // The SELECT, DML with OUTPUT, or EXEC that produces it
+	Declared    bool          // Shape comes from EXEC ... WITH RESULT SETS or a table definition
+	Conditional bool          // Only produced on some control-flow paths
+}
+
+// Column describes a column in a result set.
+type Column struct {
+	Name       string         // Empty when the column has no name; "*" for an unresolved wildcard
+	DataType   *ast.DataType  // nil when the type could not be inferred
+	Nullable   *bool          // nil when nullability is unknown
+	Expression ast.Expression // The select-list expression, nil for declared columns
+}
+
+// IsWildcard reports whether the column stands for an unresolved SELECT *.
+func (c *Column) IsWildcard() bool {
+	return strings.HasSuffix(c.Name, "*")
+}
+
+// Extractor extracts signatures, resolving column references against the
+// tables it has been told about.
+type Extractor struct {
+	tables map[string][]*Column
+}
+
+// NewExtractor creates an Extractor with no known tables.
+func NewExtractor() *Extractor {
+	return &Extractor{tables: make(map[string][]*Column)}
+}
+
+// AddTable registers the columns of a table so that result set columns
+// selected from it can be typed.
+func (e *Extractor) AddTable(name *ast.QualifiedIdentifier, columns []*ast.ColumnDefinition) {
+	cols := columnsFromDefinitions(columns)
+	e.tables[strings.ToLower(name.String())] = cols
+	if len(name.Parts) > 1 {
+		last := strings.ToLower(name.Parts[len(name.Parts)-1].Value)
+		if _, exists := e.tables[last]; !exists {
+			e.tables[last] = cols
+		}
+	}
+}
+
+// AddProgram registers every CREATE TABLE and CREATE TYPE ... AS TABLE in program.
+func (e *Extractor) AddProgram(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.CreateTableStatement:
+			if !s.IsTemporary {
+				e.AddTable(s.Name, s.Columns)
+			}
+		case *ast.CreateTypeStatement:
+			if s.IsTableType && s.TableDef != nil {
+				e.AddTable(s.Name, s.TableDef.Columns)
+			}
+		}
+	}
+}
+
+// Statement returns the signature of a CREATE/ALTER PROCEDURE or FUNCTION
+// statement. The second result is false for any other statement.
+func (e *Extractor) Statement(stmt ast.Statement) (*Signature, bool) {
+	switch s := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		return e.Procedure(s), true
+	case *ast.AlterProcedureStatement:
+		return e.Procedure(&ast.CreateProcedureStatement{
+			Token:      s.Token,
+			Name:       s.Name,
+			Parameters: s.Parameters,
+			Body:       s.Body,
+			Options:    s.Options,
+		}), true
+	case *ast.CreateFunctionStatement:
+		return e.Function(s), true
+	case *ast.AlterFunctionStatement:
+		return e.Function(&ast.CreateFunctionStatement{
+			Token:        s.Token,
+			Name:         s.Name,
+			Parameters:   s.Parameters,
+			ReturnType:   s.ReturnType,
+			ReturnsTable: s.ReturnsTable,
+			TableDef:     s.TableDef,
+			TableVar:     s.TableVar,
+			Options:      s.Options,
+			AsReturn:     s.AsReturn,
+			Body:         s.Body,
+		}), true
+	}
+	return nil, false
+}
+
+// Procedure returns the signature of a stored procedure, including the
+// result sets returned by its top-level SELECT statements.
+func (e *Extractor) Procedure(proc *ast.CreateProcedureStatement) *Signature {
+	sig := &Signature{
+		Name:       proc.Name,
+		Kind:       KindProcedure,
+		Parameters: parameters(proc.Parameters),
+	}
+	sc := e.newScope(sig.Parameters)
+	if proc.Body != nil {
+		sig.ResultSets = sc.statements(proc.Body.Statements, false)
+	}
+	return sig
+}
+
+// Function returns the signature of a user-defined function. Table-valued
+// functions report the returned table as their only result set.
+func (e *Extractor) Function(fn *ast.CreateFunctionStatement) *Signature {
+	sig := &Signature{
+		Name:       fn.Name,
+		Parameters: parameters(fn.Parameters),
+	}
+	sc := e.newScope(sig.Parameters)
+
+	switch {
+	case fn.ReturnsTable:
+		sig.Kind = KindInlineTableFunction
+		if sel, ok := fn.AsReturn.(*ast.SelectStatement); ok {
+			sig.ResultSets = []*ResultSet{{Columns: sc.selectColumns(sel), Source: sel}}
+		}
+	case fn.TableDef != nil:
+		sig.Kind = KindMultiStatementTableFunction
+		sig.ResultSets = []*ResultSet{{
+			Columns:  columnsFromDefinitions(fn.TableDef.Columns),
+			Declared: true,
+		}}
+	default:
+		sig.Kind = KindScalarFunction
+		sig.ReturnType = fn.ReturnType
+	}
+	return sig
+}
+
+// FromProcedure returns the signature of a stored procedure.
+func FromProcedure(proc *ast.CreateProcedureStatement) *Signature {
+	return NewExtractor().Procedure(proc)
+}
+
+// FromFunction returns the signature of a user-defined function.
+func FromFunction(fn *ast.CreateFunctionStatement) *Signature {
+	return NewExtractor().Function(fn)
+}
+
+// FromProgram returns the signatures of every procedure and function in
+// program, resolving columns against the tables the program creates.
+func FromProgram(program *ast.Program) []*Signature {
+	e := NewExtractor()
+	e.AddProgram(program)
+
+	var sigs []*Signature
+	for _, stmt := range program.Statements {
+		if sig, ok := e.Statement(stmt); ok {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+func parameters(defs []*ast.ParameterDef) []*Parameter {
+	var params []*Parameter
+	for _, def := range defs {
+		params = append(params, &Parameter{
+			Name:        def.Name,
+			DataType:    def.DataType,
+			Default:     def.Default,
+			Output:      def.Output,
+			ReadOnly:    def.ReadOnly,
+			TableValued: def.ReadOnly && def.DataType != nil && !isBuiltinType(def.DataType.Name),
+		})
+	}
+	return params
+}
+
+func columnsFromDefinitions(defs []*ast.ColumnDefinition) []*Column {
+	var cols []*Column
+	for _, def := range defs {
+		col := &Column{Name: def.Name.Value, DataType: def.DataType, Nullable: def.Nullable}
+		if def.Computed != nil {
+			col.Expression = def.Computed
+		}
+		if col.Nullable == nil && def.Identity != nil {
+			col.Nullable = boolPtr(false)
+		}
+		for _, c := range def.Constraints {
+			if c.Type == ast.ConstraintPrimaryKey {
+				col.Nullable = boolPtr(false)
+			}
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,340 @@
+package signature
+
+import (
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func only(t *testing.T, input string) *Signature {
+	t.Helper()
+	sigs := FromProgram(parse(t, input))
+	if len(sigs) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(sigs))
+	}
+	return sigs[0]
+}
+
+func typeString(dt *ast.DataType) string {
+	if dt == nil {
+		return "<nil>"
+	}
+	return dt.String()
+}
+
+func TestProcedureParameters(t *testing.T) {
+	sig := only(t, `
+CREATE TYPE dbo.IdList AS TABLE (Id INT NOT NULL);
+GO
+CREATE PROCEDURE dbo.GetOrders
+    @CustomerId INT,
+    @Since DATETIME = NULL,
+    @Total MONEY OUTPUT,
+    @Ids dbo.IdList READONLY
+AS
+BEGIN
+    SELECT 1 AS One
+END`)
+
+	if sig.Kind != KindProcedure {
+		t.Errorf("Kind = %s, want PROCEDURE", sig.Kind)
+	}
+	if sig.Name.String() != "dbo.GetOrders" {
+		t.Errorf("Name = %s", sig.Name)
+	}
+
+	tests := []struct {
+		name        string
+		dataType    string
+		hasDefault  bool
+		output      bool
+		tableValued bool
+	}{
+		{"@CustomerId", "INT", false, false, false},
+		{"@Since", "DATETIME", true, false, false},
+		{"@Total", "MONEY", false, true, false},
+		{"@Ids", "DBO.IdList", false, false, true},
+	}
+	if len(sig.Parameters) != len(tests) {
+		t.Fatalf("expected %d parameters, got %d", len(tests), len(sig.Parameters))
+	}
+	for i, tt := range tests {
+		p := sig.Parameters[i]
+		if p.Name != tt.name || typeString(p.DataType) != tt.dataType {
+			t.Errorf("param %d = %s %s, want %s %s", i, p.Name, typeString(p.DataType), tt.name, tt.dataType)
+		}
+		if p.HasDefault() != tt.hasDefault || p.Output != tt.output || p.TableValued != tt.tableValued {
+			t.Errorf("param %s: default=%v output=%v tvp=%v", p.Name, p.HasDefault(), p.Output, p.TableValued)
+		}
+	}
+	if !sig.UsesTableValuedParameters() {
+		t.Error("expected UsesTableValuedParameters to be true")
+	}
+	if out := sig.OutputParameters(); len(out) != 1 || out[0].Name != "@Total" {
+		t.Errorf("OutputParameters = %v", out)
+	}
+}
+
+func TestProcedureResultSets(t *testing.T) {
+	sig := only(t, `
+CREATE TABLE dbo.Customers (
+    Id INT IDENTITY(1,1) PRIMARY KEY,
+    Name NVARCHAR(100) NOT NULL,
+    Email VARCHAR(200) NULL
+);
+GO
+CREATE TABLE dbo.Orders (
+    OrderId INT NOT NULL,
+    CustomerId INT NOT NULL,
+    Amount DECIMAL(10,2) NOT NULL
+);
+GO
+CREATE PROCEDURE dbo.CustomerReport @Verbose BIT
+AS
+BEGIN
+    DECLARE @Count INT;
+    SELECT @Count = COUNT(*) FROM dbo.Customers;
+
+    SELECT c.Id, c.Name, o.Amount, Total = COUNT(*) OVER (), @Count AS CustomerCount
+    FROM dbo.Customers c
+    LEFT JOIN dbo.Orders o ON o.CustomerId = c.Id;
+
+    IF @Verbose = 1
+        SELECT * FROM dbo.Customers;
+END`)
+
+	if len(sig.ResultSets) != 2 {
+		t.Fatalf("expected 2 result sets, got %d", len(sig.ResultSets))
+	}
+
+	first := sig.ResultSets[0]
+	if first.Conditional {
+		t.Error("first result set should not be conditional")
+	}
+	tests := []struct {
+		name     string
+		dataType string
+		nullable string
+	}{
+		{"Id", "INT", "false"},
+		{"Name", "NVARCHAR(100)", "false"},
+		{"Amount", "DECIMAL(10, 2)", "true"},
+		{"Total", "INT", "false"},
+		{"CustomerCount", "INT", "unknown"},
+	}
+	if len(first.Columns) != len(tests) {
+		t.Fatalf("expected %d columns, got %d", len(tests), len(first.Columns))
+	}
+	for i, tt := range tests {
+		col := first.Columns[i]
+		nullable := "unknown"
+		if col.Nullable != nil {
+			nullable = map[bool]string{true: "true", false: "false"}[*col.Nullable]
+		}
+		if col.Name != tt.name || typeString(col.DataType) != tt.dataType || nullable != tt.nullable {
+			t.Errorf("column %d = %s %s nullable=%s, want %s %s nullable=%s",
+				i, col.Name, typeString(col.DataType), nullable, tt.name, tt.dataType, tt.nullable)
+		}
+	}
+
+	second := sig.ResultSets[1]
+	if !second.Conditional {
+		t.Error("second result set should be conditional")
+	}
+	if len(second.Columns) != 3 || second.Columns[2].Name != "Email" {
+		t.Errorf("SELECT * was not expanded: %d columns", len(second.Columns))
+	}
+}
+
+func TestIsNullNullability(t *testing.T) {
+	sig := only(t, `
+CREATE TABLE dbo.T (A INT NULL, B INT NULL, C INT NOT NULL);
+GO
+CREATE PROCEDURE dbo.P
+AS
+BEGIN
+    SELECT ISNULL(A, B) AS AB, ISNULL(A, C) AS AC, ISNULL(C, A) AS CA, ISNULL(A, 1) AS A1 FROM dbo.T;
+END`)
+	want := []bool{true, false, false, false}
+	for i, col := range sig.ResultSets[0].Columns {
+		if col.Nullable == nil {
+			t.Errorf("%s: nullable unknown, want %v", col.Name, want[i])
+		} else if *col.Nullable != want[i] {
+			t.Errorf("%s: nullable = %v, want %v", col.Name, *col.Nullable, want[i])
+		}
+	}
+}
+
+func TestInferredTypesAreCopies(t *testing.T) {
+	const input = "CREATE PROCEDURE dbo.P AS BEGIN SELECT COUNT(*) AS N, @@ROWCOUNT AS R END"
+	for _, col := range only(t, input).ResultSets[0].Columns {
+		col.DataType.Name = "BIT"
+	}
+	for _, col := range only(t, input).ResultSets[0].Columns {
+		if col.DataType.Name != "INT" {
+			t.Errorf("%s: type = %s, want INT", col.Name, col.DataType)
+		}
+	}
+}
+
+func TestLocalTables(t *testing.T) {
+	sig := only(t, `
+CREATE PROCEDURE dbo.Summary
+AS
+BEGIN
+    CREATE TABLE #Totals (Region VARCHAR(10), Total MONEY);
+    DECLARE @Recent TABLE (Id INT, Created DATETIME2);
+
+    WITH Counts (Region, N) AS (
+        SELECT Region, COUNT(*) FROM #Totals GROUP BY Region
+    )
+    SELECT Region, N FROM Counts;
+
+    SELECT r.Created FROM @Recent r;
+END`)
+
+	if len(sig.ResultSets) != 2 {
+		t.Fatalf("expected 2 result sets, got %d", len(sig.ResultSets))
+	}
+	cte := sig.ResultSets[0].Columns
+	if typeString(cte[0].DataType) != "VARCHAR(10)" || typeString(cte[1].DataType) != "INT" {
+		t.Errorf("CTE columns = %s, %s", typeString(cte[0].DataType), typeString(cte[1].DataType))
+	}
+	if got := typeString(sig.ResultSets[1].Columns[0].DataType); got != "DATETIME2" {
+		t.Errorf("table variable column type = %s", got)
+	}
+}
+
+func TestUnknownTableWildcard(t *testing.T) {
+	sig := only(t, `
+CREATE PROCEDURE dbo.Everything
+AS
+    SELECT * FROM dbo.Unknown`)
+
+	cols := sig.ResultSets[0].Columns
+	if len(cols) != 1 || !cols[0].IsWildcard() {
+		t.Errorf("expected a single unresolved wildcard column, got %d", len(cols))
+	}
+}
+
+func TestWithResultSets(t *testing.T) {
+	sig := only(t, `
+CREATE PROCEDURE dbo.Wrapper
+AS
+    EXEC dbo.Inner WITH RESULT SETS ((Id INT NOT NULL, Label NVARCHAR(50)))`)
+
+	if len(sig.ResultSets) != 1 {
+		t.Fatalf("expected 1 result set, got %d", len(sig.ResultSets))
+	}
+	rs := sig.ResultSets[0]
+	if !rs.Declared {
+		t.Error("expected a declared result set")
+	}
+	if len(rs.Columns) != 2 || rs.Columns[0].Nullable == nil || *rs.Columns[0].Nullable {
+		t.Errorf("unexpected columns: %+v", rs.Columns)
+	}
+}
+
+func TestOutputClause(t *testing.T) {
+	sig := only(t, `
+CREATE TABLE dbo.Items (Id INT NOT NULL, Price MONEY NOT NULL);
+GO
+CREATE PROCEDURE dbo.Reprice
+AS
+    UPDATE dbo.Items SET Price = Price * 2
+    OUTPUT inserted.Id, deleted.Price AS OldPrice`)
+
+	if len(sig.ResultSets) != 1 {
+		t.Fatalf("expected 1 result set, got %d", len(sig.ResultSets))
+	}
+	cols := sig.ResultSets[0].Columns
+	if cols[1].Name != "OldPrice" || typeString(cols[1].DataType) != "MONEY" {
+		t.Errorf("column = %s %s", cols[1].Name, typeString(cols[1].DataType))
+	}
+}
+
+func TestFunctionKinds(t *testing.T) {
+	sigs := FromProgram(parse(t, `
+CREATE FUNCTION dbo.Double (@x INT) RETURNS INT AS BEGIN RETURN @x * 2 END;
+GO
+CREATE FUNCTION dbo.Squares (@n INT) RETURNS TABLE AS RETURN (SELECT @n AS N, @n * @n AS Square);
+GO
+CREATE FUNCTION dbo.Names () RETURNS @result TABLE (Name NVARCHAR(20) NOT NULL)
+AS BEGIN
+    INSERT INTO @result VALUES (N'a');
+    RETURN
+END`))
+
+	tests := []struct {
+		kind    Kind
+		columns int
+	}{
+		{KindScalarFunction, 0},
+		{KindInlineTableFunction, 2},
+		{KindMultiStatementTableFunction, 1},
+	}
+	if len(sigs) != len(tests) {
+		t.Fatalf("expected %d signatures, got %d", len(tests), len(sigs))
+	}
+	for i, tt := range tests {
+		sig := sigs[i]
+		if sig.Kind != tt.kind {
+			t.Errorf("sig %d kind = %s, want %s", i, sig.Kind, tt.kind)
+		}
+		columns := 0
+		if len(sig.ResultSets) > 0 {
+			columns = len(sig.ResultSets[0].Columns)
+		}
+		if columns != tt.columns {
+			t.Errorf("sig %d has %d columns, want %d", i, columns, tt.columns)
+		}
+	}
+	if typeString(sigs[0].ReturnType) != "INT" {
+		t.Errorf("ReturnType = %s", typeString(sigs[0].ReturnType))
+	}
+	if typeString(sigs[1].ResultSets[0].Columns[1].DataType) != "INT" {
+		t.Error("expected inline TVF column to be typed from its parameter")
+	}
+}
+
+func TestInferLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"SELECT 1", "INT"},
+		{"SELECT 3000000000", "BIGINT"},
+		{"SELECT 12.50", "DECIMAL(4, 2)"},
+		{"SELECT 'abc'", "VARCHAR(3)"},
+		{"SELECT N'abc' + N'de'", "NVARCHAR(5)"},
+		{"SELECT CAST(1 AS BIGINT)", "BIGINT"},
+		{"SELECT GETDATE()", "DATETIME"},
+		{"SELECT ISNULL(NULL, 1)", "INT"},
+		{"SELECT 1 + 2.5", "DECIMAL(2, 1)"},
+		{"SELECT CASE WHEN 1 = 1 THEN 'x' ELSE 'yy' END", "VARCHAR(1)"},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		sc := NewExtractor().newScope(nil)
+		sets := sc.statements(program.Statements, false)
+		if len(sets) != 1 || len(sets[0].Columns) != 1 {
+			t.Fatalf("%s: unexpected result sets", tt.input)
+		}
+		if got := typeString(sets[0].Columns[0].DataType); got != tt.expected {
+			t.Errorf("%s: type = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+}
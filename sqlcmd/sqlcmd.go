@@ -0,0 +1,461 @@
+// Package sqlcmd expands the sqlcmd extensions of a script so that it can
+// be parsed as T-SQL.
+//
+// Scripts written for sqlcmd, such as SSDT publish and deployment scripts,
+// define variables with :setvar, include other files with :r and refer to
+// variables as $(name), in identifiers and strings alike. Expand replaces
+// each $(name) with its value and each :r with the file it names, and keeps
+// a source map from every line and column of the expanded text back to the
+// file, line and column it came from:
+//
+//	pp := &sqlcmd.Preprocessor{
+//	    Variables: map[string]string{"DatabaseName": "Sales"},
+//	    Files:     os.DirFS("deploy"),
+//	}
+//	script, err := pp.ExpandFile("Script.PostDeployment.sql")
+//	if err != nil {
+//	    return err // an undefined variable or a missing include
+//	}
+//	program, errs := script.Parse()
+//	for _, e := range errs {
+//	    fmt.Println(e) // Tables/Orders.sql:12:5: expected ...
+//	}
+//
+// The other commands, such as :on error exit and :connect, are not acted
+// on; they are kept in Script.Commands for whatever runs the script.
+// Variable names are not case-sensitive, and commands and variables are
+// not recognized inside comments.
+package sqlcmd
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+// Preprocessor expands sqlcmd scripts.
+type Preprocessor struct {
+	// Variables are the values of $(name), as given to sqlcmd with -v. As
+	// with sqlcmd, a :setvar in the script overrides them.
+	Variables map[string]string
+	// Files is where :r finds the files it includes. A relative path is
+	// taken from the directory of the including file, as in SSDT
+	// projects. Includes are refused when Files is nil.
+	Files fs.FS
+}
+
+// Position is a place in a file of a script.
+type Position struct {
+	File   string
+	Line   int
+	Column int // Counts runes from 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Error is an error in a script, at the place it came from.
+type Error struct {
+	Pos     Position // The zero Position if the place is not known
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.Line == 0 {
+		return e.Message
+	}
+	return e.Pos.String() + ": " + e.Message
+}
+
+// Command is a sqlcmd command that expansion leaves to whatever runs the
+// script, such as :on error exit or :connect.
+type Command struct {
+	Pos  Position
+	Line int    // The line of Script.Text the command stood at, which is left blank
+	Name string // In lower case and without the colon: "on error", "connect", "!!"
+	Args string // The rest of the line, with variables substituted
+}
+
+// commands are the sqlcmd commands other than :setvar and :r, which are
+// kept for a runner.
+var commands = map[string]bool{
+	"on error": true, "connect": true, "out": true, "error": true, "perftrace": true,
+	"reset": true, "ed": true, "quit": true, "exit": true, "list": true, "listvar": true,
+	"serverlist": true, "xml": true, "help": true, "!!": true,
+}
+
+// Script is an expanded script.
+type Script struct {
+	Text     string // The T-SQL, with every variable and include expanded
+	Commands []*Command
+	lines    [][]segment // The source map, by line of Text
+}
+
+// segment is a run of a line of Text that came from one place. A copied
+// run was copied from a file, and its columns advance with those of the
+// file; any other run is the value of a variable, all of which maps to
+// the $( that referred to it.
+type segment struct {
+	column int // Where the run starts in the line of Text
+	pos    Position
+	copied bool
+}
+
+// Position returns where the given line and column of Text came from,
+// such as the position of a token of the expanded script. It is the zero
+// Position outside Text.
+func (s *Script) Position(line, column int) Position {
+	if line < 1 || line > len(s.lines) {
+		return Position{}
+	}
+	segs := s.lines[line-1]
+	seg := segs[0]
+	for _, sg := range segs[1:] {
+		if sg.column > column {
+			break
+		}
+		seg = sg
+	}
+	pos := seg.pos
+	if seg.copied {
+		pos.Column += column - seg.column
+	}
+	return pos
+}
+
+// Parse parses the expanded script. Syntax errors are reported at the
+// file, line and column they came from.
+func (s *Script) Parse() (*ast.Program, []*Error) {
+	p := parser.New(lexer.New(s.Text))
+	program := p.ParseProgram()
+	located := p.SyntaxErrors()
+	var errs []*Error
+	for _, msg := range p.Errors() {
+		if len(located) > 0 && msg == located[0].Error() {
+			e := located[0]
+			located = located[1:]
+			errs = append(errs, &Error{Pos: s.Position(e.Line, e.Column), Message: e.Message})
+			continue
+		}
+		errs = append(errs, &Error{Message: msg})
+	}
+	return program, errs
+}
+
+// ExpandFile expands the named file of Files.
+func (pp *Preprocessor) ExpandFile(name string) (*Script, error) {
+	if pp.Files == nil {
+		return nil, &Error{Message: "no Files to read " + name + " from"}
+	}
+	name, ok := cleanPath(".", name)
+	if !ok {
+		return nil, &Error{Message: "invalid file name " + name}
+	}
+	data, err := fs.ReadFile(pp.Files, name)
+	if err != nil {
+		return nil, err
+	}
+	return pp.Expand(name, string(data))
+}
+
+// Expand expands a script. Positions in the script are reported under
+// name, and the files it includes are found relative to its directory.
+func (pp *Preprocessor) Expand(name, input string) (*Script, error) {
+	e := &expander{pp: pp, script: &Script{}, vars: make(map[string]string), column: 1}
+	for k, v := range pp.Variables {
+		e.vars[strings.ToUpper(k)] = v
+	}
+	if err := e.file(name, input); err != nil {
+		return nil, err
+	}
+	e.script.Text = e.out.String()
+	return e.script, nil
+}
+
+type expander struct {
+	pp     *Preprocessor
+	script *Script
+	vars   map[string]string // By upper-case name
+	files  []string          // The files being expanded, innermost last
+	out    strings.Builder
+	line   []segment // The source map of the line being written
+	column int       // The column of the line being written that comes next
+
+	// The lexical state at the end of a line, which carries over to the next
+	comment int  // The depth of nested /* */ comments
+	quote   rune // The character that ends the string or quoted name being read, or 0
+}
+
+// file expands a file, or the script itself, line by line.
+func (e *expander) file(name, text string) error {
+	e.files = append(e.files, name)
+	defer func() { e.files = e.files[:len(e.files)-1] }()
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		// A final newline does not start another line
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		pos := Position{File: name, Line: i + 1, Column: 1}
+		if e.comment == 0 && e.quote == 0 {
+			done, err := e.command(pos, line)
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
+		}
+		e.line = []segment{{column: 1, pos: pos, copied: true}}
+		if err := e.text(pos, line); err != nil {
+			return err
+		}
+		e.endLine()
+	}
+	return nil
+}
+
+// command handles a line that is a sqlcmd command, reporting whether it
+// was one.
+func (e *expander) command(pos Position, line string) (bool, error) {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	pos.Column += utf8.RuneCountInString(line[:len(line)-len(trimmed)])
+	var name, args string
+	switch {
+	case strings.HasPrefix(trimmed, "!!"):
+		name, args = "!!", trimmed[2:]
+	case strings.HasPrefix(trimmed, ":") && !strings.HasPrefix(trimmed, "::"):
+		rest := trimmed[1:]
+		end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+		if end < 0 {
+			end = len(rest)
+		}
+		name, args = strings.ToLower(rest[:end]), rest[end:]
+		if name == "on" {
+			// :on error exit|ignore
+			args = strings.TrimSpace(args)
+			end = strings.IndexFunc(args, unicode.IsSpace)
+			if end < 0 {
+				end = len(args)
+			}
+			name, args = "on "+strings.ToLower(args[:end]), args[end:]
+		}
+	default:
+		return false, nil
+	}
+
+	args, err := e.substitute(pos, strings.TrimSpace(args))
+	if err != nil {
+		return true, err
+	}
+	switch {
+	case name == "setvar":
+		if err := e.setvar(pos, args); err != nil {
+			return true, err
+		}
+	case name == "r":
+		return true, e.include(pos, args)
+	case commands[name]:
+		e.script.Commands = append(e.script.Commands, &Command{Pos: pos, Line: len(e.script.lines) + 1, Name: name, Args: args})
+	default:
+		return true, &Error{Pos: pos, Message: "unknown sqlcmd command :" + name}
+	}
+	// The command's line is left blank
+	e.line = []segment{{column: 1, pos: pos, copied: true}}
+	e.endLine()
+	return true, nil
+}
+
+// setvar handles :setvar name [value]. Without a value, the variable is
+// removed.
+func (e *expander) setvar(pos Position, args string) error {
+	name, value := args, ""
+	if end := strings.IndexFunc(args, unicode.IsSpace); end >= 0 {
+		name, value = args[:end], args[end:]
+	}
+	if name == "" || strings.ContainsAny(name, "$()'\"") {
+		return &Error{Pos: pos, Message: fmt.Sprintf("invalid variable name %q in :setvar", name)}
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		delete(e.vars, strings.ToUpper(name))
+		return nil
+	}
+	if strings.HasPrefix(value, `"`) {
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return &Error{Pos: pos, Message: fmt.Sprintf("unterminated value of %s in :setvar", name)}
+		}
+		value = strings.ReplaceAll(value[1:len(value)-1], `""`, `"`)
+	}
+	e.vars[strings.ToUpper(name)] = value
+	return nil
+}
+
+// include handles :r path.
+func (e *expander) include(pos Position, args string) error {
+	if e.pp.Files == nil {
+		return &Error{Pos: pos, Message: "includes are not allowed without Files"}
+	}
+	file := strings.TrimSpace(args)
+	if len(file) >= 2 && strings.HasPrefix(file, `"`) && strings.HasSuffix(file, `"`) {
+		file = file[1 : len(file)-1]
+	}
+	if file == "" {
+		return &Error{Pos: pos, Message: "missing file name in :r"}
+	}
+	name, ok := cleanPath(path.Dir(e.files[len(e.files)-1]), file)
+	if !ok {
+		return &Error{Pos: pos, Message: fmt.Sprintf("cannot include %s: outside Files", file)}
+	}
+	for _, f := range e.files {
+		if f == name {
+			return &Error{Pos: pos, Message: fmt.Sprintf("cannot include %s: it includes itself", file)}
+		}
+	}
+	data, err := fs.ReadFile(e.pp.Files, name)
+	if err != nil {
+		return &Error{Pos: pos, Message: fmt.Sprintf("cannot include %s: %v", file, err)}
+	}
+	return e.file(name, string(data))
+}
+
+// cleanPath returns the path of Files that file names, taken from the
+// directory dir. Windows separators are accepted.
+func cleanPath(dir, file string) (string, bool) {
+	file = strings.ReplaceAll(file, `\`, "/")
+	name := path.Clean(path.Join(dir, file))
+	return name, fs.ValidPath(name)
+}
+
+// text copies a line that is not a command, substituting variables
+// outside comments.
+func (e *expander) text(pos Position, line string) error {
+	start, startCol := 0, pos.Column // The run being copied
+	col := pos.Column
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		next := ""
+		if i+size < len(line) {
+			next = line[i+size : i+size+1]
+		}
+		switch {
+		case e.comment > 0:
+			if r == '*' && next == "/" {
+				e.comment--
+				size++
+			} else if r == '/' && next == "*" {
+				e.comment++
+				size++
+			}
+		case r == '$' && next == "(":
+			end := strings.IndexByte(line[i:], ')')
+			at := Position{File: pos.File, Line: pos.Line, Column: col}
+			if end < 0 {
+				return &Error{Pos: at, Message: "unterminated variable reference"}
+			}
+			name := line[i+2 : i+end]
+			value, ok := e.vars[strings.ToUpper(name)]
+			if !ok {
+				return &Error{Pos: at, Message: fmt.Sprintf("variable %s is not defined", name)}
+			}
+			e.copy(Position{File: pos.File, Line: pos.Line, Column: startCol}, line[start:i])
+			e.insert(at, value)
+			col += utf8.RuneCountInString(line[i : i+end+1])
+			i += end + 1
+			start, startCol = i, col
+			continue
+		case e.quote != 0:
+			if r == e.quote {
+				if next == string(e.quote) {
+					size++ // An escaped quote, '' or ]]
+				} else {
+					e.quote = 0
+				}
+			}
+		case r == '-' && next == "-":
+			// The rest of the line is a comment
+			i = len(line)
+			continue
+		case r == '/' && next == "*":
+			e.comment++
+			size++
+		case r == '\'' || r == '"':
+			e.quote = r
+		case r == '[':
+			e.quote = ']'
+		}
+		col += utf8.RuneCountInString(line[i : i+size])
+		i += size
+	}
+	e.copy(Position{File: pos.File, Line: pos.Line, Column: startCol}, line[start:])
+	return nil
+}
+
+// substitute substitutes the variables in the arguments of a command,
+// which begin at pos.
+func (e *expander) substitute(pos Position, args string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(args, "$(")
+		if i < 0 {
+			b.WriteString(args)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(args[i:], ')')
+		if end < 0 {
+			return "", &Error{Pos: pos, Message: "unterminated variable reference"}
+		}
+		name := args[i+2 : i+end]
+		value, ok := e.vars[strings.ToUpper(name)]
+		if !ok {
+			return "", &Error{Pos: pos, Message: fmt.Sprintf("variable %s is not defined", name)}
+		}
+		b.WriteString(args[:i])
+		b.WriteString(value)
+		args = args[i+end+1:]
+	}
+}
+
+// copy writes text copied from pos.
+func (e *expander) copy(pos Position, text string) {
+	if text == "" {
+		return
+	}
+	e.line = append(e.line, segment{column: e.column, pos: pos, copied: true})
+	e.out.WriteString(text)
+	e.column += utf8.RuneCountInString(text)
+}
+
+// insert writes the value of a variable referred to at pos.
+func (e *expander) insert(pos Position, value string) {
+	for i, part := range strings.Split(value, "\n") {
+		if i > 0 {
+			e.endLine()
+			e.line = []segment{{column: 1, pos: pos}}
+		}
+		if part == "" {
+			continue
+		}
+		e.line = append(e.line, segment{column: e.column, pos: pos})
+		e.out.WriteString(part)
+		e.column += utf8.RuneCountInString(part)
+	}
+}
+
+// endLine ends the line being written.
+func (e *expander) endLine() {
+	e.out.WriteByte('\n')
+	e.script.lines = append(e.script.lines, e.line)
+	e.line = nil
+	e.column = 1
+}
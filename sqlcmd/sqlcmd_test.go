@@ -0,0 +1,156 @@
+package sqlcmd
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func expand(t *testing.T, pp *Preprocessor, input string) *Script {
+	t.Helper()
+	script, err := pp.Expand("main.sql", input)
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	return script
+}
+
+func TestVariables(t *testing.T) {
+	pp := &Preprocessor{Variables: map[string]string{"Env": "prod", "DatabaseName": "ignored"}}
+	input := `:setvar DatabaseName Sales
+:SETVAR Owner "O""Brien"
+USE [$(DatabaseName)];
+SELECT '$(env)', N'$(Owner)' FROM $(databasename).dbo.Orders; -- $(Missing)
+/* $(Missing)
+   :r nothing.sql */
+:setvar Env
+`
+	script := expand(t, pp, input)
+	want := `
+
+USE [Sales];
+SELECT 'prod', N'O"Brien' FROM Sales.dbo.Orders; -- $(Missing)
+/* $(Missing)
+   :r nothing.sql */
+
+`
+	if script.Text != want {
+		t.Errorf("got\n%s\nwant\n%s", script.Text, want)
+	}
+	if _, errs := script.Parse(); len(errs) != 0 {
+		t.Errorf("parse errors: %v", errs)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	files := fstest.MapFS{
+		"deploy/Script.sql":        {Data: []byte(":setvar Schema sales\r\n:r .\\Tables\\Orders.sql\r\n:r \"Tables/Lines.sql\"\r\nGO\r\n")},
+		"deploy/Tables/Orders.sql": {Data: []byte("CREATE TABLE $(Schema).Orders (Id INT);\n:r Common.sql\n")},
+		"deploy/Tables/Lines.sql":  {Data: []byte("CREATE TABLE $(Schema).Lines (Id INT);")},
+		"deploy/Tables/Common.sql": {Data: []byte("GO")},
+	}
+	script, err := (&Preprocessor{Files: files}).ExpandFile("deploy/Script.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\nCREATE TABLE sales.Orders (Id INT);\nGO\nCREATE TABLE sales.Lines (Id INT);\nGO\n"
+	if script.Text != want {
+		t.Errorf("got %q, want %q", script.Text, want)
+	}
+
+	tests := []struct {
+		line, column int
+		want         string
+	}{
+		{1, 1, "deploy/Script.sql:1:1"},
+		{2, 1, "deploy/Tables/Orders.sql:1:1"},
+		{2, 14, "deploy/Tables/Orders.sql:1:14"}, // sales, from $(Schema)
+		{2, 18, "deploy/Tables/Orders.sql:1:14"},
+		{2, 19, "deploy/Tables/Orders.sql:1:23"}, // .Orders
+		{3, 2, "deploy/Tables/Common.sql:1:2"},
+		{4, 20, "deploy/Tables/Lines.sql:1:24"},
+		{5, 1, "deploy/Script.sql:4:1"},
+	}
+	for _, tt := range tests {
+		if got := script.Position(tt.line, tt.column).String(); got != tt.want {
+			t.Errorf("Position(%d, %d) = %s, want %s", tt.line, tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	input := `:on error exit
+:setvar Server db01
+  :connect $(Server) -U deploy
+SELECT 1;
+!! dir
+:: not a command
+`
+	script := expand(t, &Preprocessor{}, input)
+	if want := "\n\n\nSELECT 1;\n\n:: not a command\n"; script.Text != want {
+		t.Errorf("got %q, want %q", script.Text, want)
+	}
+	var got []string
+	for _, c := range script.Commands {
+		got = append(got, c.Pos.String()+" "+c.Name+" ["+c.Args+"]")
+	}
+	want := []string{"main.sql:1:1 on error [exit]", "main.sql:3:3 connect [db01 -U deploy]", "main.sql:5:1 !! [dir]"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if script.Commands[1].Line != 3 {
+		t.Errorf("got line %d, want 3", script.Commands[1].Line)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	files := fstest.MapFS{
+		"a.sql": {Data: []byte("SELECT 1;\n:r b.sql\n")},
+		"b.sql": {Data: []byte(":r a.sql\n")},
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"SELECT 1;\nSELECT  $(Missing);", "main.sql:2:9: variable Missing is not defined"},
+		{"SELECT '$(Name';", "main.sql:1:9: unterminated variable reference"},
+		{":setvar Path $(Root)\\x", "main.sql:1:1: variable Root is not defined"},
+		{":setvar Name \"x", "main.sql:1:1: unterminated value of Name in :setvar"},
+		{":frobnicate", "main.sql:1:1: unknown sqlcmd command :frobnicate"},
+		{":r missing.sql", "main.sql:1:1: cannot include missing.sql: open missing.sql: file does not exist"},
+		{":r ../outside.sql", "main.sql:1:1: cannot include ../outside.sql: outside Files"},
+		{":r a.sql", "b.sql:1:1: cannot include a.sql: it includes itself"},
+	}
+	for _, tt := range tests {
+		_, err := (&Preprocessor{Files: files}).Expand("main.sql", tt.input)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.input)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s\ngot  %s\nwant %s", tt.input, err, tt.want)
+		}
+	}
+
+	if _, err := (&Preprocessor{}).Expand("main.sql", ":r a.sql"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("got %v, want includes to be refused", err)
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	files := fstest.MapFS{
+		"main.sql":       {Data: []byte(":setvar Table Orders\nSELECT 1;\n:r Tables/Bad.sql\n")},
+		"Tables/Bad.sql": {Data: []byte("SELECT Id\nFROM $(Table) WHERE;\n")},
+	}
+	script, err := (&Preprocessor{Files: files}).ExpandFile("main.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errs := script.Parse()
+	if len(errs) == 0 {
+		t.Fatal("expected a syntax error")
+	}
+	if got := errs[0].Pos.String(); got != "Tables/Bad.sql:2:20" {
+		t.Errorf("got %s (%v), want Tables/Bad.sql:2:20", got, errs[0])
+	}
+}
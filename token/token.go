@@ -52,6 +52,8 @@ const (
 	OREQ      // |=
 	XOREQ     // ^=
 	CONCAT    // + (string concatenation, same as PLUS)
+	DPIPE     // || (string concatenation)
+	DPIPEEQ   // ||=
 	SCOPE     // ::
 
 	// Delimiters
@@ -61,6 +63,8 @@ const (
 	RPAREN    // )
 	LBRACKET  // [
 	RBRACKET  // ]
+	LBRACE    // {
+	RBRACE    // }
 	DOT       // .
 	COLON     // :
 
@@ -585,6 +589,8 @@ var tokenNames = map[Type]string{
 	ANDEQ:                 "&=",
 	OREQ:                  "|=",
 	XOREQ:                 "^=",
+	DPIPE:                 "||",
+	DPIPEEQ:               "||=",
 	SCOPE:                 "::",
 	COMMA:                 ",",
 	SEMICOLON:             ";",
@@ -592,6 +598,8 @@ var tokenNames = map[Type]string{
 	RPAREN:                ")",
 	LBRACKET:              "[",
 	RBRACKET:              "]",
+	LBRACE:                "{",
+	RBRACE:                "}",
 	DOT:                   ".",
 	COLON:                 ":",
 	END_CONVERSATION:      "END_CONVERSATION",
@@ -0,0 +1,356 @@
+package translate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// dataType translates a data type. cast is set for the target of CAST
+// and CONVERT, where a VARCHAR without a length means 30 characters
+// rather than 1.
+func (t *postgres) dataType(dt *ast.DataType, cast bool) string {
+	// The parser keeps the length of VARCHAR(n) in Precision
+	length := func(def int) int {
+		switch {
+		case dt.Precision != nil:
+			return *dt.Precision
+		case dt.Length != nil:
+			return *dt.Length
+		}
+		return def
+	}
+	fraction := func() string {
+		return "(" + strconv.Itoa(min(length(7), 6)) + ")"
+	}
+	switch strings.ToUpper(dt.Name) {
+	case "INT", "INTEGER":
+		return "integer"
+	case "BIGINT":
+		return "bigint"
+	case "SMALLINT", "TINYINT", "BIT":
+		return "smallint"
+	case "DECIMAL", "NUMERIC", "DEC":
+		precision, scale := 18, 0
+		if dt.Precision != nil {
+			precision = *dt.Precision
+		} else if dt.Length != nil {
+			precision = *dt.Length
+		}
+		if dt.Scale != nil {
+			scale = *dt.Scale
+		}
+		return "numeric(" + strconv.Itoa(precision) + ", " + strconv.Itoa(scale) + ")"
+	case "MONEY":
+		return "numeric(19, 4)"
+	case "SMALLMONEY":
+		return "numeric(10, 4)"
+	case "FLOAT":
+		if length(53) <= 24 {
+			return "real"
+		}
+		return "double precision"
+	case "REAL":
+		return "real"
+	case "CHAR", "NCHAR", "CHARACTER":
+		return "char(" + strconv.Itoa(length(1)) + ")"
+	case "VARCHAR", "NVARCHAR":
+		if dt.Max {
+			return "text"
+		}
+		def := 1
+		if cast {
+			def = 30
+		}
+		return "varchar(" + strconv.Itoa(length(def)) + ")"
+	case "TEXT", "NTEXT":
+		return "text"
+	case "DATE":
+		return "date"
+	case "TIME":
+		return "time" + fraction()
+	case "DATETIME":
+		return "timestamp(3)"
+	case "DATETIME2":
+		return "timestamp" + fraction()
+	case "SMALLDATETIME":
+		return "timestamp(0)"
+	case "DATETIMEOFFSET":
+		return "timestamptz" + fraction()
+	case "UNIQUEIDENTIFIER":
+		return "uuid"
+	case "BINARY", "VARBINARY", "IMAGE":
+		return "bytea"
+	case "XML":
+		return "xml"
+	case "SYSNAME":
+		return "varchar(128)"
+	case "ROWVERSION", "TIMESTAMP", "SQL_VARIANT", "HIERARCHYID", "GEOGRAPHY", "GEOMETRY", "CURSOR", "TABLE":
+		t.add(t.at, "data type %s is not supported", strings.ToUpper(dt.Name))
+		return dt.String()
+	}
+	// A user-defined type, which must be created in PostgreSQL as well
+	var parts []*ast.Identifier
+	for _, p := range strings.Split(dt.Name, ".") {
+		parts = append(parts, &ast.Identifier{Token: t.at, Value: strings.Trim(p, "[]")})
+	}
+	return t.table(&ast.QualifiedIdentifier{Parts: parts})
+}
+
+func (t *postgres) createTable(x *ast.CreateTableStatement, indent string) (string, bool) {
+	temporary := ""
+	if x.IsTemporary || isTemporary(x.Name) {
+		temporary = "TEMPORARY "
+	}
+	if x.AsSelect != nil {
+		body, ok := t.sql(x.AsSelect, indent)
+		if !ok {
+			return "", false
+		}
+		names := ""
+		if len(x.ColumnNames) > 0 {
+			names = " (" + t.idents(x.ColumnNames) + ")"
+		}
+		return "CREATE " + temporary + "TABLE " + t.table(x.Name) + names + " AS " + body, true
+	}
+	if x.GraphKind != ast.GraphTableNone {
+		t.add(x.Token, "graph %s table %s is not supported", x.GraphKind, x.Name)
+	}
+	return "CREATE " + temporary + "TABLE " + t.table(x.Name) + " " + t.tableElements(x.Columns, x.Constraints, indent), true
+}
+
+// tableElements translates the column and constraint list of a table,
+// one element per line.
+func (t *postgres) tableElements(columns []*ast.ColumnDefinition, constraints []*ast.TableConstraint, indent string) string {
+	defaults := make(map[string]*ast.TableConstraint)
+	var elements []string
+	for _, c := range constraints {
+		if c.Type == ast.ConstraintDefault && c.ForColumn != nil {
+			defaults[strings.ToLower(c.ForColumn.Value)] = c
+		}
+	}
+	for _, col := range columns {
+		elements = append(elements, t.columnDefinition(col, defaults[strings.ToLower(col.Name.Value)]))
+	}
+	for _, c := range constraints {
+		if s, ok := t.tableConstraint(c); ok {
+			elements = append(elements, s)
+		}
+	}
+	inner := indent + "    "
+	return "(\n" + inner + strings.Join(elements, ",\n"+inner) + "\n" + indent + ")"
+}
+
+func (t *postgres) columnDefinition(col *ast.ColumnDefinition, def *ast.TableConstraint) string {
+	at := col.Token
+	if at.Line == 0 {
+		at = col.Name.Token
+	}
+	s := t.ident(col.Name.Value)
+	if col.Computed != nil {
+		if col.DataType == nil {
+			t.add(at, "computed column %s needs a data type in PostgreSQL", col.Name.Value)
+			return s + " GENERATED ALWAYS AS (" + t.expr(col.Computed) + ") STORED"
+		}
+		return s + " " + t.dataType(col.DataType, false) + " GENERATED ALWAYS AS (" + t.expr(col.Computed) + ") STORED"
+	}
+	if col.DataType != nil {
+		s += " " + t.dataType(col.DataType, false)
+	}
+	switch {
+	case col.Collation != "":
+		t.add(at, "COLLATE %s is not supported", col.Collation)
+	case col.IsSparse || col.IsColumnSet:
+		t.add(at, "sparse column %s is not supported", col.Name.Value)
+	case col.IsRowGuidCol:
+		t.add(at, "ROWGUIDCOL is not supported")
+	case col.GeneratedAlways != ast.GeneratedNone:
+		t.add(at, "GENERATED ALWAYS AS %s is not supported", col.GeneratedAlways)
+	case col.InlineIndex != nil:
+		t.add(at, "inline INDEX %s is not supported", col.InlineIndex.Name)
+	case col.Masked != nil:
+		t.add(at, "MASKED WITH %s is not supported", col.Masked)
+	case col.Encrypted != nil:
+		t.add(at, "ENCRYPTED WITH %s is not supported", col.Encrypted)
+	}
+	if col.Identity != nil {
+		s += " GENERATED BY DEFAULT AS IDENTITY"
+		if col.Identity.Seed != 1 || col.Identity.Increment != 1 {
+			s += " (START WITH " + strconv.FormatInt(col.Identity.Seed, 10) +
+				" INCREMENT BY " + strconv.FormatInt(col.Identity.Increment, 10) + ")"
+		}
+	}
+	if col.Nullable != nil {
+		if *col.Nullable {
+			s += " NULL"
+		} else {
+			s += " NOT NULL"
+		}
+	}
+	switch {
+	case col.Default != nil:
+		if col.DefaultName != "" {
+			s += " CONSTRAINT " + t.ident(col.DefaultName)
+		}
+		s += " DEFAULT " + t.expr(col.Default)
+	case def != nil:
+		if def.Name != "" {
+			s += " CONSTRAINT " + t.ident(def.Name)
+		}
+		s += " DEFAULT " + t.expr(def.DefaultExpression)
+	}
+	for _, c := range col.Constraints {
+		if c.Name != "" {
+			s += " CONSTRAINT " + t.ident(c.Name)
+		}
+		switch c.Type {
+		case ast.ConstraintPrimaryKey:
+			s += " PRIMARY KEY"
+		case ast.ConstraintUnique:
+			s += " UNIQUE"
+		case ast.ConstraintCheck:
+			s += " CHECK (" + t.expr(c.CheckExpression) + ")"
+		case ast.ConstraintForeignKey:
+			s += " " + t.references(c.ReferencesTable, c.ReferencesColumns, c.OnDelete, c.OnUpdate)
+		}
+	}
+	return s
+}
+
+// tableConstraint translates a table constraint. DEFAULT ... FOR
+// constraints are written with their columns.
+func (t *postgres) tableConstraint(c *ast.TableConstraint) (string, bool) {
+	if c.IndexOptions != nil {
+		t.add(c.Token, "index options %s are not supported", c.IndexOptions)
+	}
+	s := ""
+	if c.Name != "" {
+		s = "CONSTRAINT " + t.ident(c.Name) + " "
+	}
+	columns := func() string {
+		names := make([]string, len(c.Columns))
+		for i, col := range c.Columns {
+			names[i] = t.ident(col.Name.Value)
+			if col.Descending {
+				t.add(c.Token, "descending key column %s is not supported", col.Name.Value)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	switch c.Type {
+	case ast.ConstraintPrimaryKey:
+		return s + "PRIMARY KEY (" + columns() + ")", true
+	case ast.ConstraintUnique:
+		return s + "UNIQUE (" + columns() + ")", true
+	case ast.ConstraintCheck:
+		return s + "CHECK (" + t.expr(c.CheckExpression) + ")", true
+	case ast.ConstraintForeignKey:
+		return s + "FOREIGN KEY (" + columns() + ") " + t.references(c.ReferencesTable, c.ReferencesColumns, c.OnDelete, c.OnUpdate), true
+	case ast.ConstraintDefault:
+		if c.ForColumn == nil {
+			t.add(c.Token, "DEFAULT constraint without a column is not supported")
+		}
+	case ast.ConstraintPeriod:
+		t.add(c.Token, "PERIOD FOR SYSTEM_TIME is not supported")
+	case ast.ConstraintIndex:
+		t.add(c.Token, "inline INDEX %s is not supported", c.Name)
+	case ast.ConstraintConnection:
+		t.add(c.Token, "edge constraint %s is not supported", c.Name)
+	}
+	return "", false
+}
+
+func (t *postgres) references(table *ast.QualifiedIdentifier, columns []*ast.Identifier, onDelete, onUpdate string) string {
+	s := "REFERENCES " + t.table(table)
+	if len(columns) > 0 {
+		s += " (" + t.idents(columns) + ")"
+	}
+	if onDelete != "" {
+		s += " ON DELETE " + strings.ToUpper(onDelete)
+	}
+	if onUpdate != "" {
+		s += " ON UPDATE " + strings.ToUpper(onUpdate)
+	}
+	return s
+}
+
+// view translates CREATE VIEW and ALTER VIEW. SCHEMABINDING and the
+// other view attributes have no equivalent.
+func (t *postgres) view(create string, name *ast.QualifiedIdentifier, columns []*ast.Identifier, options []string, query ast.Statement, check bool, indent string) (string, bool) {
+	for _, o := range options {
+		t.add(t.at, "view option %s is not supported", strings.ToUpper(o))
+	}
+	s := create + t.table(name)
+	if len(columns) > 0 {
+		s += " (" + t.idents(columns) + ")"
+	}
+	body, ok := t.sql(query, indent)
+	if !ok {
+		return "", false
+	}
+	s += " AS " + body
+	if check {
+		s += " WITH CHECK OPTION"
+	}
+	return s, true
+}
+
+func (t *postgres) createIndex(x *ast.CreateIndexStatement) (string, bool) {
+	for _, o := range x.Options {
+		t.add(x.Token, "index option %s is not supported", o)
+	}
+	s := "CREATE "
+	if x.IsUnique {
+		s += "UNIQUE "
+	}
+	columns := make([]string, len(x.Columns))
+	for i, col := range x.Columns {
+		columns[i] = t.ident(col.Name.Value)
+		if col.Descending {
+			columns[i] += " DESC"
+		}
+	}
+	s += "INDEX " + t.ident(x.Name.Value) + " ON " + t.table(x.Table) + " (" + strings.Join(columns, ", ") + ")"
+	if len(x.IncludeColumns) > 0 {
+		s += " INCLUDE (" + t.idents(x.IncludeColumns) + ")"
+	}
+	if x.Where != nil {
+		s += " WHERE " + t.expr(x.Where)
+	}
+	return s, true
+}
+
+// index names an index for DROP INDEX. Index names are unique per
+// schema in PostgreSQL, so the index takes the schema of its table.
+func (t *postgres) index(name *ast.Identifier, table *ast.QualifiedIdentifier) string {
+	if table == nil {
+		return t.ident(name.Value)
+	}
+	schema := t.table(table)
+	if i := strings.LastIndex(schema, "."); i >= 0 {
+		return schema[:i+1] + t.ident(name.Value)
+	}
+	return t.ident(name.Value)
+}
+
+func (t *postgres) dropObject(x *ast.DropObjectStatement) (string, bool) {
+	kind := strings.ToUpper(x.ObjectType)
+	switch kind {
+	case "PROC", "PROCEDURE":
+		kind = "FUNCTION"
+	case "INDEX":
+		if x.IndexName != nil {
+			return "DROP INDEX " + ifExists(x.IfExists) + t.index(x.IndexName, x.TableName), true
+		}
+	case "VIEW", "FUNCTION", "SEQUENCE", "TYPE", "SCHEMA":
+	default:
+		t.add(x.Token, "DROP %s is not supported", kind)
+		return "", false
+	}
+	names := make([]string, len(x.Names))
+	for i, name := range x.Names {
+		names[i] = t.table(name)
+	}
+	return "DROP " + kind + " " + ifExists(x.IfExists) + strings.Join(names, ", "), true
+}
@@ -0,0 +1,914 @@
+package translate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+)
+
+// expr translates an expression. Expressions that cannot be translated
+// are reported and written as T-SQL.
+func (t *postgres) expr(e ast.Expression) string {
+	switch x := e.(type) {
+	case nil:
+		return ""
+	case *ast.Identifier:
+		return t.identifier(x)
+	case *ast.QualifiedIdentifier:
+		return t.column(x)
+	case *ast.Variable:
+		return t.variable(x)
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(x.Value, 10)
+	case *ast.FloatLiteral:
+		return x.Token.Literal
+	case *ast.MoneyLiteral:
+		return strings.TrimPrefix(x.Value, "$")
+	case *ast.StringLiteral:
+		return quoteString(x.Value)
+	case *ast.BinaryLiteral:
+		return `'\x` + x.Value[2:] + `'::bytea`
+	case *ast.NullLiteral:
+		return "NULL"
+	case *ast.PrefixExpression:
+		op := strings.ToUpper(x.Operator)
+		if op == "NOT" {
+			return "NOT " + t.operand(x.Right, precedenceNot)
+		}
+		return op + t.operand(x.Right, precedenceUnary)
+	case *ast.InfixExpression:
+		return t.infix(x)
+	case *ast.BetweenExpression:
+		return t.operand(x.Expr, precedenceCompare+1) + not(x.Not) + " BETWEEN " +
+			t.operand(x.Low, precedenceCompare+1) + " AND " + t.operand(x.High, precedenceCompare+1)
+	case *ast.InExpression:
+		list := ""
+		if x.Subquery != nil {
+			list = t.query(x.Subquery)
+		} else {
+			list = t.list(x.Values)
+		}
+		return t.operand(x.Expr, precedenceCompare+1) + not(x.Not) + " IN (" + list + ")"
+	case *ast.LikeExpression:
+		s := t.operand(x.Expr, precedenceCompare+1) + not(x.Not) + " LIKE " + t.operand(x.Pattern, precedenceCompare+1)
+		if x.Escape != nil {
+			s += " ESCAPE " + t.expr(x.Escape)
+		}
+		return s
+	case *ast.IsNullExpression:
+		if x.Not {
+			return t.operand(x.Expr, precedenceCompare+1) + " IS NOT NULL"
+		}
+		return t.operand(x.Expr, precedenceCompare+1) + " IS NULL"
+	case *ast.IsDistinctFromExpression:
+		return t.operand(x.Left, precedenceCompare+1) + " IS" + not(x.Not) + " DISTINCT FROM " + t.operand(x.Right, precedenceCompare+1)
+	case *ast.ExistsExpression:
+		return "EXISTS (" + t.query(x.Subquery) + ")"
+	case *ast.SubqueryExpression:
+		return "(" + t.query(x.Subquery) + ")"
+	case *ast.SelectStatement:
+		return "(" + t.query(x) + ")"
+	case *ast.TupleExpression:
+		return "(" + t.list(x.Elements) + ")"
+	case *ast.CaseExpression:
+		var b strings.Builder
+		b.WriteString("CASE")
+		if x.Operand != nil {
+			b.WriteString(" " + t.expr(x.Operand))
+		}
+		for _, w := range x.WhenClauses {
+			b.WriteString(" WHEN " + t.expr(w.Condition) + " THEN " + t.expr(w.Result))
+		}
+		if x.ElseClause != nil {
+			b.WriteString(" ELSE " + t.expr(x.ElseClause))
+		}
+		b.WriteString(" END")
+		return b.String()
+	case *ast.CastExpression:
+		if x.IsTry {
+			t.add(x.Token, "TRY_CAST is not supported")
+			return x.String()
+		}
+		return "CAST(" + t.expr(x.Expression) + " AS " + t.dataType(x.TargetType, true) + ")"
+	case *ast.ConvertExpression:
+		return t.convert(x)
+	case *ast.TrimExpression:
+		s := "TRIM("
+		if x.TrimSpec != "" {
+			s += strings.ToUpper(x.TrimSpec) + " "
+		}
+		if x.Characters != nil {
+			s += t.expr(x.Characters) + " "
+		}
+		if x.TrimSpec != "" || x.Characters != nil {
+			s += "FROM "
+		}
+		return s + t.expr(x.Expression) + ")"
+	case *ast.AtTimeZoneExpression:
+		return t.operand(x.Expr, precedenceUnary) + " AT TIME ZONE " + t.operand(x.TimeZone, precedenceUnary)
+	case *ast.NextValueForExpression:
+		if x.Over != nil {
+			t.add(x.Token, "NEXT VALUE FOR ... OVER is not supported")
+			return x.String()
+		}
+		return "nextval(" + quoteString(t.table(x.SequenceName)) + ")"
+	case *ast.GroupingSetsExpression:
+		return "GROUPING SETS (" + t.list(x.Sets) + ")"
+	case *ast.CubeExpression:
+		return "CUBE (" + t.list(x.Columns) + ")"
+	case *ast.RollupExpression:
+		return "ROLLUP (" + t.list(x.Columns) + ")"
+	case *ast.FunctionCall:
+		return t.function(x)
+	case *ast.GreatestLeastExpression:
+		// PostgreSQL ignores NULL arguments in the same way
+		if x.Least {
+			return "least(" + t.list(x.Arguments) + ")"
+		}
+		return "greatest(" + t.list(x.Arguments) + ")"
+	case *ast.DateTruncExpression:
+		return t.dateTrunc(x)
+	case *ast.RegexpExpression:
+		return t.regexp(x)
+	case *ast.MethodCallExpression:
+		// dbo.f(x) parses as a method call on dbo
+		if schema, ok := x.Object.(*ast.Identifier); ok {
+			name := &ast.QualifiedIdentifier{Parts: []*ast.Identifier{schema, {Token: x.Token, Value: x.MethodName}}}
+			return t.table(name) + "(" + t.list(x.Arguments) + ")"
+		}
+		t.add(x.Token, "method %s is not supported", x.MethodName)
+		return x.String()
+	case *ast.CollateExpression:
+		t.add(x.Token, "COLLATE %s is not supported", x.Collation)
+		return x.String()
+	}
+	t.add(t.at, "%s is not supported", nodeName(e, "Expression"))
+	return e.String()
+}
+
+func not(ok bool) string {
+	if ok {
+		return " NOT"
+	}
+	return ""
+}
+
+func (t *postgres) list(exprs []ast.Expression) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = t.expr(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Operator precedence in PostgreSQL, lowest first. Operators without
+// a precedence of their own, such as || and the bitwise operators, bind
+// between comparisons and addition.
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceNot
+	precedenceCompare
+	precedenceOther
+	precedenceAdd
+	precedenceMultiply
+	precedenceUnary
+)
+
+func precedence(op string) int {
+	switch op {
+	case "OR":
+		return precedenceOr
+	case "AND":
+		return precedenceAnd
+	case "=", "<>", "!=", "<", ">", "<=", ">=":
+		return precedenceCompare
+	case "+", "-":
+		return precedenceAdd
+	case "*", "/", "%":
+		return precedenceMultiply
+	}
+	return precedenceOther
+}
+
+// operand translates e as an operand of an operator of precedence p,
+// parenthesising it when it binds less tightly.
+func (t *postgres) operand(e ast.Expression, p int) string {
+	s := t.expr(e)
+	inner := precedenceUnary
+	switch x := e.(type) {
+	case *ast.InfixExpression:
+		inner = precedence(t.operator(x))
+	case *ast.PrefixExpression:
+		if strings.EqualFold(x.Operator, "NOT") {
+			inner = precedenceNot
+		}
+	case *ast.BetweenExpression, *ast.InExpression, *ast.LikeExpression, *ast.IsNullExpression,
+		*ast.IsDistinctFromExpression:
+		inner = precedenceCompare
+	}
+	if inner < p || (inner == p && p == precedenceOther) {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// operator returns the PostgreSQL operator of an infix expression.
+func (t *postgres) operator(x *ast.InfixExpression) string {
+	op := strings.ToUpper(x.Operator)
+	switch op {
+	case "+":
+		var types map[string]*ast.DataType
+		if t.r != nil {
+			types = t.r.types
+		}
+		if isText(x.Left, types) || isText(x.Right, types) {
+			return "||"
+		}
+	case "^":
+		return "#"
+	case "!<":
+		return ">="
+	case "!>":
+		return "<="
+	}
+	return op
+}
+
+func (t *postgres) infix(x *ast.InfixExpression) string {
+	op := t.operator(x)
+	p := precedence(op)
+	right := p
+	if op != "AND" && op != "OR" && op != "+" && op != "*" && op != "||" {
+		// Not associative: a - (b - c) needs its parentheses
+		right++
+	}
+	left := t.operand(x.Left, p)
+	if l, ok := x.Left.(*ast.InfixExpression); ok && t.operator(l) == op {
+		// Left-associative: (a || b) || c is a || b || c
+		left = t.expr(l)
+	}
+	return left + " " + op + " " + t.operand(x.Right, right)
+}
+
+// isText reports whether e is known to be a string, which decides
+// whether + concatenates. types holds the types of declared variables.
+// Column types are not known, so a + between two columns is left as
+// addition.
+func isText(e ast.Expression, types map[string]*ast.DataType) bool {
+	switch x := e.(type) {
+	case *ast.StringLiteral:
+		return true
+	case *ast.Variable:
+		if dt, ok := types[strings.ToLower(x.Name)]; ok {
+			return isStringType(dt)
+		}
+	case *ast.CastExpression:
+		return isStringType(x.TargetType)
+	case *ast.ConvertExpression:
+		return isStringType(x.TargetType)
+	case *ast.InfixExpression:
+		return x.Operator == "||" || x.Operator == "+" && (isText(x.Left, types) || isText(x.Right, types))
+	case *ast.CaseExpression:
+		for _, w := range x.WhenClauses {
+			if isText(w.Result, types) {
+				return true
+			}
+		}
+		return isText(x.ElseClause, types)
+	case *ast.FunctionCall:
+		switch strings.ToUpper(functionName(x)) {
+		case "ISNULL", "COALESCE", "NULLIF", "IIF":
+			for _, a := range x.Arguments {
+				if isText(a, types) {
+					return true
+				}
+			}
+		case "UPPER", "LOWER", "LTRIM", "RTRIM", "TRIM", "SUBSTRING", "LEFT", "RIGHT", "REPLACE",
+			"REPLICATE", "REVERSE", "SPACE", "STUFF", "CHAR", "NCHAR", "CONCAT", "CONCAT_WS",
+			"STRING_AGG", "FORMAT", "DATENAME", "QUOTENAME":
+			return true
+		}
+	}
+	return false
+}
+
+func isStringType(dt *ast.DataType) bool {
+	switch strings.ToUpper(dt.Name) {
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "TEXT", "NTEXT", "SYSNAME":
+		return true
+	}
+	return false
+}
+
+func (t *postgres) identifier(x *ast.Identifier) string {
+	switch strings.ToUpper(x.Value) {
+	case "*":
+		return "*"
+	case "DEFAULT":
+		return "DEFAULT"
+	case "CURRENT_TIMESTAMP":
+		return "CURRENT_TIMESTAMP"
+	case "CURRENT_USER", "USER":
+		return "CURRENT_USER"
+	case "SESSION_USER", "SYSTEM_USER":
+		return "SESSION_USER"
+	}
+	if t.pseudo != nil {
+		if _, ok := t.pseudo[strings.ToLower(x.Value)]; ok {
+			t.add(x.Token, "%s without a column is not supported", x.Value)
+		}
+	}
+	return t.ident(x.Value)
+}
+
+// column translates a column reference. In a RETURNING clause the
+// inserted and deleted pseudo-tables become the target table.
+func (t *postgres) column(q *ast.QualifiedIdentifier) string {
+	parts := q.Parts
+	if t.pseudo != nil && len(parts) == 2 {
+		qual := strings.ToLower(parts[0].Value)
+		if qual == "inserted" || qual == "deleted" {
+			to, ok := t.pseudo[qual]
+			if !ok {
+				t.add(parts[0].Token, "%s values cannot be returned by this statement", qual)
+				return q.String()
+			}
+			if to == "" {
+				return t.ident(parts[1].Value)
+			}
+			return to + "." + t.identOrStar(parts[1].Value)
+		}
+	}
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		names[i] = t.identOrStar(p.Value)
+	}
+	return strings.Join(names, ".")
+}
+
+func (t *postgres) identOrStar(name string) string {
+	if name == "*" {
+		return name
+	}
+	return t.ident(name)
+}
+
+// ident writes a name, quoting it when PostgreSQL requires it.
+func (t *postgres) ident(name string) string {
+	if isPlainName(name) && !postgresReserved[strings.ToLower(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// postgresReserved holds the keywords PostgreSQL does not accept as
+// names.
+var postgresReserved = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "binary": true,
+	"both": true, "case": true, "cast": true, "check": true, "collate": true,
+	"collation": true, "column": true, "concurrently": true, "constraint": true,
+	"create": true, "cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true, "deferrable": true,
+	"desc": true, "distinct": true, "do": true, "else": true, "end": true, "except": true,
+	"false": true, "fetch": true, "for": true, "foreign": true, "freeze": true, "from": true,
+	"full": true, "grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true, "or": true,
+	"order": true, "outer": true, "overlaps": true, "placing": true, "primary": true,
+	"references": true, "returning": true, "right": true, "select": true,
+	"session_user": true, "similar": true, "some": true, "symmetric": true,
+	"system_user": true, "table": true, "tablesample": true, "then": true, "to": true,
+	"trailing": true, "true": true, "union": true, "unique": true, "user": true,
+	"using": true, "variadic": true, "verbose": true, "when": true, "where": true,
+	"window": true, "with": true,
+}
+
+// table translates the name of a table or other schema object.
+func (t *postgres) table(q *ast.QualifiedIdentifier) string {
+	var parts []*ast.Identifier
+	for _, p := range q.Parts {
+		if p.Value != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) > 2 {
+		t.add(parts[0].Token, "reference to another database or server in %s is not supported", q.String())
+		parts = parts[len(parts)-2:]
+	}
+	if len(parts) == 2 && strings.EqualFold(parts[0].Value, "dbo") {
+		parts = parts[1:]
+	}
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		name := p.Value
+		switch {
+		case strings.HasPrefix(name, "##"):
+			t.add(p.Token, "global temporary table %s is not supported", name)
+			name = name[2:]
+		case strings.HasPrefix(name, "#"):
+			name = name[1:]
+		case strings.HasPrefix(name, "@"):
+			names[i] = t.variableName(name)
+			continue
+		}
+		names[i] = t.ident(name)
+	}
+	return strings.Join(names, ".")
+}
+
+// isTemporary reports whether name is a #temp table.
+func isTemporary(name *ast.QualifiedIdentifier) bool {
+	return strings.HasPrefix(name.Parts[len(name.Parts)-1].Value, "#")
+}
+
+func (t *postgres) variable(x *ast.Variable) string {
+	switch strings.ToUpper(x.Name) {
+	case "@@ROWCOUNT":
+		if t.r == nil {
+			break
+		}
+		t.r.rowCount = true
+		return rowCountVariable
+	case "@@IDENTITY":
+		return "lastval()"
+	case "@@SPID":
+		return "pg_backend_pid()"
+	case "@@VERSION":
+		return "version()"
+	}
+	if strings.HasPrefix(x.Name, "@@") {
+		t.add(x.Token, "%s is not supported", strings.ToUpper(x.Name))
+		return x.Name
+	}
+	return t.variableName(x.Name)
+}
+
+// variableName returns the PL/pgSQL name of a variable.
+func (t *postgres) variableName(name string) string {
+	if t.r != nil {
+		if v, ok := t.r.vars[strings.ToLower(name)]; ok {
+			return v
+		}
+	}
+	return t.ident("v_" + strings.TrimPrefix(name, "@"))
+}
+
+// convert translates CONVERT. The date styles people use to format
+// dates as strings become to_char formats.
+func (t *postgres) convert(x *ast.ConvertExpression) string {
+	if x.IsTry {
+		t.add(x.Token, "TRY_CONVERT is not supported")
+		return x.String()
+	}
+	typ := t.dataType(x.TargetType, true)
+	if x.Style == nil {
+		return "CAST(" + t.expr(x.Expression) + " AS " + typ + ")"
+	}
+	style, ok := x.Style.(*ast.IntegerLiteral)
+	format, known := "", false
+	if ok && isStringType(x.TargetType) {
+		format, known = dateStyles[style.Value]
+	}
+	if !known {
+		t.add(x.Token, "CONVERT style %s is not supported", x.Style.String())
+		return x.String()
+	}
+	return "CAST(to_char(" + t.expr(x.Expression) + ", " + quoteString(format) + ") AS " + typ + ")"
+}
+
+// dateStyles maps CONVERT styles to to_char formats.
+var dateStyles = map[int64]string{
+	1:   "MM/DD/YY",
+	3:   "DD/MM/YY",
+	23:  "YYYY-MM-DD",
+	101: "MM/DD/YYYY",
+	103: "DD/MM/YYYY",
+	104: "DD.MM.YYYY",
+	108: "HH24:MI:SS",
+	112: "YYYYMMDD",
+	120: "YYYY-MM-DD HH24:MI:SS",
+	121: "YYYY-MM-DD HH24:MI:SS.MS",
+	126: `YYYY-MM-DD"T"HH24:MI:SS.MS`,
+}
+
+func functionName(x *ast.FunctionCall) string {
+	switch f := x.Function.(type) {
+	case *ast.Identifier:
+		return f.Value
+	case *ast.QualifiedIdentifier:
+		return f.Parts[len(f.Parts)-1].Value
+	}
+	return x.Function.String()
+}
+
+// function translates a function call.
+func (t *postgres) function(x *ast.FunctionCall) string {
+	if q, ok := x.Function.(*ast.QualifiedIdentifier); ok && len(q.Parts) > 1 {
+		// A user-defined function
+		return t.table(q) + "(" + t.list(x.Arguments) + ")" + t.over(x.Over)
+	}
+	name := strings.ToUpper(functionName(x))
+	tok := x.Token
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		tok = id.Token
+	}
+	args := x.Arguments
+	arg := func(i int) string {
+		return t.expr(args[i])
+	}
+	if n, ok := arity[name]; ok && (len(args) < n[0] || len(args) > n[1]) {
+		t.add(tok, "%s takes %d to %d arguments", name, n[0], n[1])
+		return x.String()
+	}
+
+	switch name {
+	case "ISNULL":
+		return "coalesce(" + t.list(args) + ")"
+	case "GETDATE", "SYSDATETIME", "SYSDATETIMEOFFSET":
+		return "now()"
+	case "GETUTCDATE", "SYSUTCDATETIME":
+		return "(now() AT TIME ZONE 'utc')"
+	case "LEN":
+		return "length(rtrim(" + arg(0) + "))"
+	case "DATALENGTH":
+		return "octet_length(" + arg(0) + ")"
+	case "CHARINDEX":
+		if len(args) == 3 {
+			break
+		}
+		return "strpos(" + arg(1) + ", " + arg(0) + ")"
+	case "REPLICATE":
+		return "repeat(" + t.list(args) + ")"
+	case "SPACE":
+		return "repeat(' ', " + arg(0) + ")"
+	case "STUFF":
+		return "overlay(" + arg(0) + " placing " + arg(3) + " from " + arg(1) + " for " + arg(2) + ")"
+	case "CHAR", "NCHAR":
+		return "chr(" + arg(0) + ")"
+	case "UNICODE":
+		return "ascii(" + arg(0) + ")"
+	case "CEILING":
+		return "ceil(" + arg(0) + ")"
+	case "LOG":
+		if len(args) == 2 {
+			return "log(" + arg(1) + ", " + arg(0) + ")"
+		}
+		return "ln(" + arg(0) + ")"
+	case "LOG10":
+		return "log(" + arg(0) + ")"
+	case "SQUARE":
+		return "power(" + arg(0) + ", 2)"
+	case "ATN2":
+		return "atan2(" + t.list(args) + ")"
+	case "RAND":
+		if len(args) == 0 {
+			return "random()"
+		}
+	case "ROUND":
+		if len(args) == 3 {
+			break
+		}
+		return "round(" + t.list(args) + ")"
+	case "NEWID":
+		return "gen_random_uuid()"
+	case "SCOPE_IDENTITY":
+		return "lastval()"
+	case "ERROR_MESSAGE":
+		return "SQLERRM"
+	case "DB_NAME":
+		if len(args) == 0 {
+			return "current_database()"
+		}
+	case "SUSER_SNAME", "SUSER_NAME", "USER_NAME":
+		if len(args) == 0 {
+			return "current_user"
+		}
+	case "OBJECT_ID":
+		return t.objectID(x)
+	case "IIF":
+		return "CASE WHEN " + arg(0) + " THEN " + arg(1) + " ELSE " + arg(2) + " END"
+	case "CHOOSE":
+		s := "CASE " + t.operand(args[0], precedenceUnary)
+		for i := 1; i < len(args); i++ {
+			s += " WHEN " + strconv.Itoa(i) + " THEN " + arg(i)
+		}
+		return s + " END"
+	case "COUNT_BIG":
+		name = "COUNT"
+	case "STDEV":
+		name = "STDDEV_SAMP"
+	case "STDEVP":
+		name = "STDDEV_POP"
+	case "VAR":
+		name = "VAR_SAMP"
+	case "VARP":
+		name = "VAR_POP"
+	case "STRING_AGG":
+		s := "string_agg(" + arg(0) + ", " + arg(1)
+		if len(x.WithinGroup) > 0 {
+			s += " ORDER BY " + t.orderBy(x.WithinGroup)
+		}
+		return s + ")" + t.over(x.Over)
+	case "DATEADD":
+		return t.dateAdd(x)
+	case "DATEDIFF":
+		return t.dateDiff(x)
+	case "DATEPART":
+		return t.datePart(x, args[0], args[1])
+	case "YEAR", "MONTH", "DAY":
+		return t.datePart(x, &ast.Identifier{Token: tok, Value: name}, args[0])
+	case "DATENAME":
+		part, _ := datePartName(args[0])
+		switch part {
+		case "month":
+			return "to_char(" + arg(1) + ", 'FMMonth')"
+		case "weekday":
+			return "to_char(" + arg(1) + ", 'FMDay')"
+		}
+	case "EOMONTH":
+		if len(args) == 1 {
+			return "CAST(date_trunc('month', " + arg(0) + ") + interval '1 month - 1 day' AS date)"
+		}
+	case "DATEFROMPARTS":
+		return "make_date(" + t.list(args) + ")"
+	}
+
+	if !postgresFunctions[name] {
+		t.add(tok, "function %s has no PostgreSQL translation", name)
+		return x.String()
+	}
+	s := strings.ToLower(name) + "("
+	if x.Distinct {
+		s += "DISTINCT "
+	}
+	s += t.list(args) + ")"
+	if len(x.WithinGroup) > 0 {
+		if x.Over != nil {
+			t.add(tok, "%s WITHIN GROUP with OVER is not supported", name)
+		}
+		s += " WITHIN GROUP (ORDER BY " + t.orderBy(x.WithinGroup) + ")"
+	}
+	return s + t.over(x.Over)
+}
+
+// arity gives the number of arguments of the functions whose
+// translation picks arguments out by position.
+var arity = map[string][2]int{
+	"ISNULL": {2, 2}, "LEN": {1, 1}, "DATALENGTH": {1, 1}, "CHARINDEX": {2, 3},
+	"SPACE": {1, 1}, "STUFF": {4, 4}, "CHAR": {1, 1}, "NCHAR": {1, 1}, "UNICODE": {1, 1},
+	"CEILING": {1, 1}, "LOG": {1, 2}, "LOG10": {1, 1}, "SQUARE": {1, 1}, "IIF": {3, 3},
+	"CHOOSE": {2, 255}, "STRING_AGG": {2, 2}, "DATEADD": {3, 3}, "DATEDIFF": {3, 3},
+	"DATEPART": {2, 2}, "DATENAME": {2, 2}, "YEAR": {1, 1}, "MONTH": {1, 1}, "DAY": {1, 1},
+	"EOMONTH": {1, 2}, "DATEFROMPARTS": {3, 3}, "OBJECT_ID": {1, 2}, "LEFT": {2, 2},
+	"RIGHT": {2, 2}, "SUBSTRING": {3, 3}, "REPLICATE": {2, 2}, "CONCAT": {2, 254},
+}
+
+// postgresFunctions are the T-SQL functions PostgreSQL has under the
+// same name and with the same arguments.
+var postgresFunctions = map[string]bool{
+	"COALESCE": true, "NULLIF": true, "UPPER": true, "LOWER": true, "LTRIM": true,
+	"RTRIM": true, "TRIM": true, "SUBSTRING": true, "LEFT": true, "RIGHT": true,
+	"REPLACE": true, "REVERSE": true, "ASCII": true, "CONCAT": true, "CONCAT_WS": true,
+	"ABS": true, "ROUND": true, "FLOOR": true, "POWER": true, "SQRT": true, "EXP": true,
+	"SIGN": true, "PI": true, "SIN": true, "COS": true, "TAN": true, "ASIN": true,
+	"ACOS": true, "ATAN": true, "COT": true, "DEGREES": true, "RADIANS": true,
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+	"STDDEV_SAMP": true, "STDDEV_POP": true, "VAR_SAMP": true, "VAR_POP": true,
+	"ROW_NUMBER": true, "RANK": true, "DENSE_RANK": true, "NTILE": true,
+	"PERCENT_RANK": true, "CUME_DIST": true, "LAG": true, "LEAD": true,
+	"FIRST_VALUE": true, "LAST_VALUE": true, "PERCENTILE_CONT": true,
+	"PERCENTILE_DISC": true, "GROUPING": true,
+}
+
+// objectID translates OBJECT_ID('name') to to_regclass, which is NULL
+// for missing tables in the same way.
+func (t *postgres) objectID(x *ast.FunctionCall) string {
+	lit, ok := x.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		return "to_regclass(" + t.expr(x.Arguments[0]) + ")"
+	}
+	var parts []*ast.Identifier
+	for _, p := range strings.Split(lit.Value, ".") {
+		p = strings.Trim(p, "[]")
+		if p != "" && !strings.EqualFold(p, "tempdb") {
+			parts = append(parts, &ast.Identifier{Token: lit.Token, Value: p})
+		}
+	}
+	return "to_regclass(" + quoteString(t.table(&ast.QualifiedIdentifier{Parts: parts})) + ")"
+}
+
+func (t *postgres) over(o *ast.OverClause) string {
+	if o == nil {
+		return ""
+	}
+	if o.WindowRef != "" {
+		return " OVER " + t.ident(o.WindowRef)
+	}
+	return " OVER (" + t.window(o) + ")"
+}
+
+// window translates the inside of an OVER clause or WINDOW definition.
+func (t *postgres) window(o *ast.OverClause) string {
+	var parts []string
+	if len(o.PartitionBy) > 0 {
+		parts = append(parts, "PARTITION BY "+t.list(o.PartitionBy))
+	}
+	if len(o.OrderBy) > 0 {
+		parts = append(parts, "ORDER BY "+t.orderBy(o.OrderBy))
+	}
+	if f := o.Frame; f != nil {
+		frame := strings.ToUpper(f.Type) + " "
+		if f.End != nil {
+			frame += "BETWEEN " + t.frameBound(f.Start) + " AND " + t.frameBound(f.End)
+		} else {
+			frame += t.frameBound(f.Start)
+		}
+		parts = append(parts, frame)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (t *postgres) frameBound(b *ast.FrameBound) string {
+	if b.Offset != nil {
+		return t.expr(b.Offset) + " " + strings.ToUpper(b.Type)
+	}
+	return strings.ToUpper(b.Type)
+}
+
+func (t *postgres) orderBy(items []*ast.OrderByItem) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		s := t.expr(item.Expression)
+		if item.Descending {
+			s += " DESC"
+		}
+		if item.NullsFirst != nil {
+			if *item.NullsFirst {
+				s += " NULLS FIRST"
+			} else {
+				s += " NULLS LAST"
+			}
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ", ")
+}
+
+// datePartName normalises the first argument of DATEADD, DATEDIFF and
+// DATEPART.
+func datePartName(e ast.Expression) (string, bool) {
+	var name string
+	switch x := e.(type) {
+	case *ast.Identifier:
+		name = x.Value
+	case *ast.StringLiteral:
+		name = x.Value
+	default:
+		return "", false
+	}
+	switch strings.ToLower(name) {
+	case "year", "yy", "yyyy":
+		return "year", true
+	case "quarter", "qq", "q":
+		return "quarter", true
+	case "month", "mm", "m":
+		return "month", true
+	case "dayofyear", "dy", "y":
+		return "dayofyear", true
+	case "day", "dd", "d":
+		return "day", true
+	case "week", "wk", "ww":
+		return "week", true
+	case "weekday", "dw", "w":
+		return "weekday", true
+	case "hour", "hh":
+		return "hour", true
+	case "minute", "mi", "n":
+		return "minute", true
+	case "second", "ss", "s":
+		return "second", true
+	case "millisecond", "ms":
+		return "millisecond", true
+	case "microsecond", "mcs":
+		return "microsecond", true
+	case "iso_week", "isowk", "isoww":
+		return "iso_week", true
+	}
+	return strings.ToLower(name), false
+}
+
+// dateAdd translates DATEADD to interval arithmetic.
+func (t *postgres) dateAdd(x *ast.FunctionCall) string {
+	part, _ := datePartName(x.Arguments[0])
+	unit := map[string]string{
+		"year": "1 year", "quarter": "3 months", "month": "1 month", "dayofyear": "1 day",
+		"day": "1 day", "week": "1 week", "weekday": "1 day", "hour": "1 hour",
+		"minute": "1 minute", "second": "1 second", "millisecond": "1 millisecond",
+		"microsecond": "1 microsecond",
+	}[part]
+	if unit == "" {
+		t.add(x.Token, "DATEADD by %s is not supported", x.Arguments[0].String())
+		return x.String()
+	}
+	return "(" + t.operand(x.Arguments[2], precedenceAdd) + " + " + t.operand(x.Arguments[1], precedenceMultiply) +
+		" * interval '" + unit + "')"
+}
+
+// dateDiff translates DATEDIFF, which counts the boundaries of the unit
+// crossed between the two dates.
+func (t *postgres) dateDiff(x *ast.FunctionCall) string {
+	part, _ := datePartName(x.Arguments[0])
+	a, b := t.expr(x.Arguments[1]), t.expr(x.Arguments[2])
+	field := func(f, v string) string {
+		return "extract(" + f + " FROM " + v + ")"
+	}
+	var diff string
+	switch part {
+	case "year":
+		diff = field("year", b) + " - " + field("year", a)
+	case "quarter":
+		diff = "(" + field("year", b) + " - " + field("year", a) + ") * 4 + " + field("quarter", b) + " - " + field("quarter", a)
+	case "month":
+		diff = "(" + field("year", b) + " - " + field("year", a) + ") * 12 + " + field("month", b) + " - " + field("month", a)
+	case "day", "dayofyear":
+		return "(CAST(" + b + " AS date) - CAST(" + a + " AS date))"
+	case "week":
+		// Weeks start on Sunday, as with the default DATEFIRST
+		return "((CAST(" + b + " AS date) - CAST(" + field("dow", b) + " AS integer)) - (CAST(" + a +
+			" AS date) - CAST(" + field("dow", a) + " AS integer))) / 7"
+	case "hour", "minute", "second":
+		seconds := map[string]string{"hour": "3600", "minute": "60", "second": "1"}[part]
+		diff = field("epoch", "date_trunc('"+part+"', "+b+") - date_trunc('"+part+"', "+a+")") + " / " + seconds
+	default:
+		t.add(x.Token, "DATEDIFF in %s is not supported", x.Arguments[0].String())
+		return x.String()
+	}
+	return "CAST(" + diff + " AS integer)"
+}
+
+// dateTrunc translates DATETRUNC to date_trunc for the date parts that
+// truncate the same way. A week starts on Sunday in T-SQL, with the
+// default DATEFIRST, and on Monday in PostgreSQL.
+func (t *postgres) dateTrunc(x *ast.DateTruncExpression) string {
+	part, _ := datePartName(x.DatePart)
+	switch part {
+	case "year", "quarter", "month", "day", "hour", "minute", "second", "millisecond", "microsecond":
+	case "dayofyear":
+		part = "day"
+	case "iso_week":
+		part = "week"
+	default:
+		t.add(x.Token, "DATETRUNC to %s is not supported", x.DatePart.Value)
+		return x.String()
+	}
+	return "date_trunc(" + quoteString(part) + ", " + t.expr(x.Date) + ")"
+}
+
+// regexp translates the regular expression functions, which PostgreSQL
+// has with the same arguments. REGEXP_REPLACE replaces every match unless
+// told which one, where regexp_replace replaces the first.
+func (t *postgres) regexp(x *ast.RegexpExpression) string {
+	if x.Function != ast.RegexpReplace {
+		return strings.ToLower(x.Function.String()) + "(" + t.list(x.Arguments()) + ")"
+	}
+	args := []string{t.expr(x.Source), t.expr(x.Pattern), "''", "1", "0"}
+	for i, e := range []ast.Expression{x.Replacement, x.Start, x.Occurrence} {
+		if e != nil {
+			args[i+2] = t.expr(e)
+		}
+	}
+	if x.Flags != nil {
+		args = append(args, t.expr(x.Flags))
+	}
+	return "regexp_replace(" + strings.Join(args, ", ") + ")"
+}
+
+// datePart translates DATEPART and the functions like it to extract.
+func (t *postgres) datePart(x *ast.FunctionCall, partExpr, date ast.Expression) string {
+	part, _ := datePartName(partExpr)
+	d := t.expr(date)
+	var field string
+	switch part {
+	case "year", "quarter", "month", "day", "hour", "minute":
+		field = part
+	case "dayofyear":
+		field = "doy"
+	case "iso_week":
+		field = "week"
+	case "second":
+		return "CAST(floor(extract(second FROM " + d + ")) AS integer)"
+	case "weekday":
+		// Sunday is 1, as with the default DATEFIRST
+		return "CAST(extract(dow FROM " + d + ") + 1 AS integer)"
+	default:
+		t.add(x.Token, "DATEPART of %s is not supported", partExpr.String())
+		return x.String()
+	}
+	return "CAST(extract(" + field + " FROM " + d + ") AS integer)"
+}
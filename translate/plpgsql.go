@@ -0,0 +1,719 @@
+package translate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser"
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/signature"
+)
+
+// rowCountVariable holds @@ROWCOUNT. PL/pgSQL only reports the row
+// count of the last SQL statement through GET DIAGNOSTICS, which is
+// emitted just before each statement that reads @@ROWCOUNT.
+const rowCountVariable = "v_rowcount"
+
+// routine is a PL/pgSQL block being written: a DO block, or the body of
+// a function translated from a procedure or function.
+type routine struct {
+	vars  map[string]string        // PL/pgSQL names by lower-cased T-SQL name
+	types map[string]*ast.DataType // T-SQL types by lower-cased T-SQL name
+	decls []string
+	lines []string
+
+	block   bool   // DO block, which returns nothing
+	code    bool   // Returns the RETURN code of a procedure
+	results bool   // RETURNS TABLE; result sets become RETURN QUERY
+	scalar  bool   // Scalar function
+	table   string // Temporary table a multi-statement table function returns
+	columns bool   // Has output columns, which may clash with column names
+
+	rowCount         bool // @@ROWCOUNT read since the last GET DIAGNOSTICS
+	rowCountDeclared bool
+}
+
+func newRoutine() *routine {
+	return &routine{
+		vars:  make(map[string]string),
+		types: make(map[string]*ast.DataType),
+	}
+}
+
+// line adds a statement, indented by depth.
+func (r *routine) line(depth int, s string) {
+	r.lines = append(r.lines, strings.Repeat("    ", depth)+s)
+}
+
+// diagnostics emits the GET DIAGNOSTICS a statement reading @@ROWCOUNT
+// needs.
+func (r *routine) diagnostics(depth int) {
+	if !r.rowCount {
+		return
+	}
+	r.rowCount = false
+	if !r.rowCountDeclared {
+		r.rowCountDeclared = true
+		r.decls = append(r.decls, rowCountVariable+" integer;")
+	}
+	r.line(depth, "GET DIAGNOSTICS "+rowCountVariable+" = ROW_COUNT;")
+}
+
+// declare adds a variable under its PL/pgSQL name.
+func (r *routine) declare(name, pgName string, dt *ast.DataType) {
+	key := strings.ToLower(name)
+	r.vars[key] = pgName
+	if dt != nil {
+		r.types[key] = dt
+	}
+}
+
+// body returns the block, from DECLARE to END.
+func (r *routine) body() string {
+	var b strings.Builder
+	if r.columns {
+		b.WriteString("#variable_conflict use_column\n")
+	}
+	if len(r.decls) > 0 {
+		b.WriteString("DECLARE\n")
+		for _, d := range r.decls {
+			b.WriteString("    " + d + "\n")
+		}
+	}
+	b.WriteString("BEGIN\n")
+	for _, l := range r.lines {
+		b.WriteString(l + "\n")
+	}
+	b.WriteString("END;\n")
+	return b.String()
+}
+
+// value translates an expression a statement at depth uses.
+func (t *postgres) value(e ast.Expression, depth int) string {
+	s := t.expr(e)
+	t.r.diagnostics(depth)
+	return s
+}
+
+// untranslated leaves a statement in the block as a comment.
+func (t *postgres) untranslated(stmt ast.Statement, depth int) {
+	for _, l := range strings.Split(comment(stmt.String()), "\n") {
+		t.r.line(depth, l)
+	}
+}
+
+// stmt translates a statement of a PL/pgSQL block.
+func (t *postgres) stmt(stmt ast.Statement, depth int) {
+	t.at = statementToken(stmt)
+	r := t.r
+	switch x := stmt.(type) {
+	case *ast.DeclareStatement:
+		t.declare(x, depth)
+	case *ast.SetStatement:
+		if x.Variable == nil {
+			if !t.option(x) {
+				t.untranslated(stmt, depth)
+			}
+			return
+		}
+		v, ok := x.Variable.(*ast.Variable)
+		if !ok {
+			t.add(x.Token, "SET of %s is not supported", x.Variable.String())
+			t.untranslated(stmt, depth)
+			return
+		}
+		value := x.Value
+		if x.Operator != "=" && x.Operator != "" {
+			value = &ast.InfixExpression{Token: x.Token, Left: v, Operator: strings.TrimSuffix(x.Operator, "="), Right: value}
+		}
+		r.line(depth, t.variable(v)+" := "+t.value(value, depth)+";")
+	case *ast.IfStatement:
+		t.ifStatement(x, depth)
+	case *ast.WhileStatement:
+		cond := t.expr(x.Condition)
+		rowCount := r.rowCount
+		r.diagnostics(depth)
+		r.line(depth, "WHILE "+cond+" LOOP")
+		t.block(x.Body, depth+1)
+		if rowCount {
+			// The condition is evaluated again after the body
+			r.rowCount = true
+			r.diagnostics(depth + 1)
+		}
+		r.line(depth, "END LOOP;")
+	case *ast.BeginEndBlock:
+		t.block(x, depth)
+	case *ast.TryCatchStatement:
+		r.line(depth, "BEGIN")
+		t.block(x.TryBlock, depth+1)
+		r.line(depth, "EXCEPTION WHEN OTHERS THEN")
+		t.block(x.CatchBlock, depth+1)
+		r.line(depth, "END;")
+	case *ast.PrintStatement:
+		r.line(depth, "RAISE NOTICE '%', "+t.value(x.Expression, depth)+";")
+	case *ast.ReturnStatement:
+		t.returnStatement(x, depth)
+	case *ast.BreakStatement:
+		r.line(depth, "EXIT;")
+	case *ast.ContinueStatement:
+		r.line(depth, "CONTINUE;")
+	case *ast.ThrowStatement:
+		t.throw(x, depth)
+	case *ast.RaiserrorStatement:
+		t.raiserror(x, depth)
+	case *ast.ExecStatement:
+		t.exec(x, depth)
+	case *ast.SelectStatement:
+		switch {
+		case assigns(x):
+			t.assign(x, depth)
+		case x.Into == nil:
+			t.resultSet(stmt, depth)
+		default:
+			t.sqlStatement(stmt, depth)
+		}
+	case *ast.WithStatement:
+		if sel, ok := x.Query.(*ast.SelectStatement); ok && sel.Into == nil {
+			t.resultSet(stmt, depth)
+			return
+		}
+		t.sqlStatement(stmt, depth)
+	default:
+		t.sqlStatement(stmt, depth)
+	}
+}
+
+// sqlStatement writes a query, data modification or DDL statement as it
+// is. Data modification that returns OUTPUT rows is a result set.
+func (t *postgres) sqlStatement(stmt ast.Statement, depth int) {
+	if returnsRows(stmt) {
+		t.resultSet(stmt, depth)
+		return
+	}
+	if x, ok := stmt.(*ast.CreateTableStatement); ok && (x.IsTemporary || isTemporary(x.Name)) && x.AsSelect == nil {
+		// A #temp table goes away when its procedure ends; the
+		// PostgreSQL one lasts for the session
+		t.r.line(depth, "DROP TABLE IF EXISTS "+t.table(x.Name)+";")
+	}
+	sql, ok := t.sql(stmt, strings.Repeat("    ", depth))
+	if !ok {
+		t.untranslated(stmt, depth)
+		return
+	}
+	t.r.diagnostics(depth)
+	t.r.line(depth, sql+";")
+}
+
+// resultSet writes a statement that returns rows to the caller, which
+// only a function that RETURNS TABLE can do.
+func (t *postgres) resultSet(stmt ast.Statement, depth int) {
+	if !t.r.results {
+		if t.r.block {
+			t.add(t.at, "result sets cannot be returned from a DO block")
+		} else {
+			t.add(t.at, "result sets can only be returned by procedures")
+		}
+		t.untranslated(stmt, depth)
+		return
+	}
+	sql, ok := t.sql(stmt, strings.Repeat("    ", depth))
+	if !ok {
+		t.untranslated(stmt, depth)
+		return
+	}
+	if returnsRows(stmt) {
+		sql = "WITH changed AS (" + sql + ") SELECT * FROM changed"
+	}
+	t.r.diagnostics(depth)
+	t.r.line(depth, "RETURN QUERY "+sql+";")
+}
+
+// block translates the statements of a BEGIN ... END, or a single
+// statement, at depth.
+func (t *postgres) block(stmt ast.Statement, depth int) {
+	switch x := stmt.(type) {
+	case nil:
+	case *ast.BeginEndBlock:
+		if x == nil {
+			return
+		}
+		for _, s := range x.Statements {
+			t.stmt(s, depth)
+		}
+	default:
+		t.stmt(stmt, depth)
+	}
+}
+
+// declare translates DECLARE. Table variables become temporary tables;
+// initial values are assigned where the DECLARE is, as T-SQL does.
+func (t *postgres) declare(x *ast.DeclareStatement, depth int) {
+	r := t.r
+	for _, v := range x.Variables {
+		name := t.ident("v_" + strings.TrimPrefix(v.Name, "@"))
+		if v.TableType != nil {
+			r.declare(v.Name, name, nil)
+			r.line(depth, "DROP TABLE IF EXISTS "+name+";")
+			r.line(depth, "CREATE TEMPORARY TABLE "+name+" "+
+				t.tableElements(v.TableType.Columns, v.TableType.Constraints, strings.Repeat("    ", depth))+";")
+			continue
+		}
+		r.decls = append(r.decls, name+" "+t.dataType(v.DataType, false)+";")
+		r.declare(v.Name, name, v.DataType)
+		if v.Value != nil {
+			r.line(depth, name+" := "+t.value(v.Value, depth)+";")
+		}
+	}
+}
+
+// ifStatement translates an IF and the IFs of its ELSE into a single
+// IF ... ELSIF.
+func (t *postgres) ifStatement(x *ast.IfStatement, depth int) {
+	var conds []string
+	var bodies []ast.Statement
+	var otherwise ast.Statement
+	for stmt := ast.Statement(x); ; {
+		next, ok := stmt.(*ast.IfStatement)
+		if !ok {
+			otherwise = stmt
+			break
+		}
+		conds = append(conds, t.expr(next.Condition))
+		bodies = append(bodies, next.Consequence)
+		if next.Alternative == nil {
+			break
+		}
+		stmt = next.Alternative
+	}
+	t.r.diagnostics(depth)
+	for i, cond := range conds {
+		keyword := "ELSIF "
+		if i == 0 {
+			keyword = "IF "
+		}
+		t.r.line(depth, keyword+cond+" THEN")
+		t.block(bodies[i], depth+1)
+	}
+	if otherwise != nil {
+		t.r.line(depth, "ELSE")
+		t.block(otherwise, depth+1)
+	}
+	t.r.line(depth, "END IF;")
+}
+
+func (t *postgres) returnStatement(x *ast.ReturnStatement, depth int) {
+	r := t.r
+	switch {
+	case r.table != "":
+		r.line(depth, "RETURN QUERY SELECT * FROM "+r.table+";")
+		r.line(depth, "RETURN;")
+	case r.scalar:
+		r.line(depth, "RETURN "+t.value(x.Value, depth)+";")
+	case r.code:
+		value := "0"
+		if x.Value != nil {
+			value = t.value(x.Value, depth)
+		}
+		r.line(depth, "RETURN "+value+";")
+	default:
+		if x.Value != nil {
+			t.add(x.Token, "RETURN with a value is only supported in procedures without result sets or OUTPUT parameters")
+		}
+		r.line(depth, "RETURN;")
+	}
+}
+
+// throw translates THROW. User error numbers become the SQLSTATE of
+// the exception.
+func (t *postgres) throw(x *ast.ThrowStatement, depth int) {
+	if x.ErrorNum == nil {
+		t.r.line(depth, "RAISE;")
+		return
+	}
+	s := "RAISE EXCEPTION '%', " + t.value(x.Message, depth)
+	if n, ok := x.ErrorNum.(*ast.IntegerLiteral); ok && n.Value >= 50000 && n.Value <= 99999 {
+		s += " USING ERRCODE = '" + strconv.FormatInt(n.Value, 10) + "'"
+	} else {
+		t.add(x.Token, "THROW error number %s has no SQLSTATE", x.ErrorNum.String())
+	}
+	t.r.line(depth, s+";")
+}
+
+// raiserror translates RAISERROR. Severities up to 10 are informational
+// and become notices.
+func (t *postgres) raiserror(x *ast.RaiserrorStatement, depth int) {
+	level := "EXCEPTION"
+	if n, ok := x.Severity.(*ast.IntegerLiteral); ok && n.Value <= 10 {
+		level = "NOTICE"
+	}
+	for _, o := range x.Options {
+		if !strings.EqualFold(o, "NOWAIT") {
+			t.add(x.Token, "RAISERROR option %s is not supported", strings.ToUpper(o))
+		}
+	}
+	args := make([]string, len(x.Args))
+	for i, a := range x.Args {
+		args[i] = t.expr(a)
+	}
+	var format string
+	switch m := x.Message.(type) {
+	case *ast.StringLiteral:
+		var ok bool
+		if format, ok = raiseFormat(m.Value); !ok {
+			t.add(x.Token, "RAISERROR format %s is not supported", quoteString(m.Value))
+		}
+	case *ast.IntegerLiteral:
+		t.add(x.Token, "RAISERROR with a message number is not supported")
+		format, args = "%", []string{m.Token.Literal}
+	default:
+		if len(args) > 0 {
+			t.add(x.Token, "RAISERROR with arguments to a message that is not a literal is not supported")
+		}
+		format, args = "%", []string{t.expr(x.Message)}
+	}
+	t.r.diagnostics(depth)
+	s := "RAISE " + level + " " + quoteString(format)
+	if len(args) > 0 {
+		s += ", " + strings.Join(args, ", ")
+	}
+	t.r.line(depth, s+";")
+}
+
+// raiseFormat converts a RAISERROR format to a RAISE format, which only
+// knows %. It reports false for widths, precisions and flags.
+func raiseFormat(format string) (string, bool) {
+	var b strings.Builder
+	ok := true
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		if i+1 < len(format) {
+			switch format[i+1] {
+			case '%':
+				b.WriteString("%%")
+				i++
+				continue
+			case 'd', 'i', 's', 'u':
+				b.WriteString("%")
+				i++
+				continue
+			}
+		}
+		ok = false
+		b.WriteString("%%")
+	}
+	return b.String(), ok
+}
+
+// call translates the procedure call of an EXEC. results reports
+// whether the procedure returns rows, which makes it a table function.
+func (t *postgres) call(x *ast.ExecStatement) (call string, results, ok bool) {
+	switch {
+	case x.DynamicSQL != nil:
+		t.add(x.Token, "dynamic SQL is not supported")
+		return "", false, false
+	case x.AtServer != nil:
+		t.add(x.Token, "EXEC ... AT is not supported")
+		return "", false, false
+	case x.Procedure == nil:
+		t.add(x.Token, "EXEC of a procedure named by a variable is not supported")
+		return "", false, false
+	}
+	name := x.Procedure.Parts[len(x.Procedure.Parts)-1].Value
+	if lower := strings.ToLower(name); strings.HasPrefix(lower, "sp_") || strings.HasPrefix(lower, "xp_") {
+		t.add(x.Token, "system procedure %s is not supported", name)
+		return "", false, false
+	}
+	if len(x.ResultSets) > 0 || x.ResultSetsMode != "" {
+		t.add(x.Token, "WITH RESULT SETS is not supported")
+	}
+	var args []string
+	for _, p := range x.Parameters {
+		isDefault := false
+		if id, ok := p.Value.(*ast.Identifier); ok && strings.EqualFold(id.Value, "DEFAULT") {
+			isDefault = true
+		}
+		switch {
+		case p.Name == "" && isDefault:
+			t.add(x.Token, "DEFAULT as a positional argument is not supported")
+			return "", false, false
+		case isDefault:
+		case p.Name == "":
+			args = append(args, t.expr(p.Value))
+		default:
+			args = append(args, t.ident("p_"+strings.TrimPrefix(p.Name, "@"))+" => "+t.expr(p.Value))
+		}
+	}
+	sig := t.procs[routineKey(x.Procedure)]
+	return t.table(x.Procedure) + "(" + strings.Join(args, ", ") + ")", sig != nil && len(sig.ResultSets) > 0, true
+}
+
+// exec translates EXEC in a PL/pgSQL block. OUTPUT arguments are read
+// back from the INOUT parameters of the function.
+func (t *postgres) exec(x *ast.ExecStatement, depth int) {
+	r := t.r
+	if x.DynamicSQL != nil && x.AtServer == nil {
+		t.add(x.Token, "dynamic SQL is not translated and runs as written")
+		r.line(depth, "EXECUTE "+t.value(x.DynamicSQL, depth)+";")
+		return
+	}
+	call, results, ok := t.call(x)
+	if !ok {
+		t.untranslated(x, depth)
+		return
+	}
+	sig := t.procs[routineKey(x.Procedure)]
+	var outputs, into []string
+	for i, p := range x.Parameters {
+		if !p.Output {
+			continue
+		}
+		name := p.Name
+		if name == "" && sig != nil && i < len(sig.Parameters) {
+			name = sig.Parameters[i].Name
+		}
+		if name == "" {
+			t.add(x.Token, "OUTPUT argument %d of an unknown procedure is not supported", i+1)
+			t.untranslated(x, depth)
+			return
+		}
+		outputs = append(outputs, t.ident("p_"+strings.TrimPrefix(name, "@")))
+		into = append(into, t.expr(p.Value))
+	}
+	r.diagnostics(depth)
+	switch {
+	case results:
+		if x.ReturnVariable != nil || len(outputs) > 0 {
+			t.add(x.Token, "the RETURN code and OUTPUT parameters of a procedure with result sets are not supported")
+		}
+		if !r.results {
+			t.add(x.Token, "result sets can only be returned by procedures")
+			t.untranslated(x, depth)
+			return
+		}
+		r.line(depth, "RETURN QUERY SELECT * FROM "+call+";")
+	case len(outputs) > 0:
+		if x.ReturnVariable != nil {
+			t.add(x.Token, "the RETURN code of a procedure with OUTPUT parameters is not supported")
+		}
+		r.line(depth, "SELECT "+strings.Join(outputs, ", ")+" INTO "+strings.Join(into, ", ")+" FROM "+call+";")
+	case x.ReturnVariable != nil:
+		r.line(depth, t.variableName(x.ReturnVariable.Value)+" := "+call+";")
+	default:
+		r.line(depth, "PERFORM "+call+";")
+	}
+}
+
+// assign translates a SELECT that assigns variables. Without a FROM it
+// is a list of assignments; otherwise it becomes SELECT ... INTO.
+func (t *postgres) assign(x *ast.SelectStatement, depth int) {
+	r := t.r
+	if x.From == nil && x.Where == nil && x.Union == nil {
+		for _, c := range x.Columns {
+			r.line(depth, t.variable(c.Variable)+" := "+t.value(c.Expression, depth)+";")
+		}
+		return
+	}
+	sel := *x
+	sel.Columns = make([]ast.SelectColumn, len(x.Columns))
+	vars := make([]string, len(x.Columns))
+	for i, c := range x.Columns {
+		if c.Variable == nil {
+			t.add(x.Token, "a SELECT that both assigns variables and returns rows is not supported")
+			t.untranslated(x, depth)
+			return
+		}
+		if refersTo(c.Expression, c.Variable.Name) {
+			t.add(x.Token, "accumulating %s over the rows of a SELECT is not supported", c.Variable.Name)
+			t.untranslated(x, depth)
+			return
+		}
+		sel.Columns[i] = ast.SelectColumn{Expression: c.Expression}
+		vars[i] = t.variable(c.Variable)
+	}
+	s := t.selectCore(&sel, strings.Join(vars, ", ")) + t.orderAndLimit(&sel, sel.Top)
+	r.diagnostics(depth)
+	r.line(depth, s+";")
+}
+
+// refersTo reports whether e reads the variable name.
+func refersTo(e ast.Expression, name string) bool {
+	finder := &variableFinder{name: name}
+	tsqlparser.Walk(finder, e)
+	return finder.found
+}
+
+type variableFinder struct {
+	name  string
+	found bool
+}
+
+func (v *variableFinder) Visit(node ast.Node) tsqlparser.Visitor {
+	if v.found {
+		return nil
+	}
+	if x, ok := node.(*ast.Variable); ok && strings.EqualFold(x.Name, v.name) {
+		v.found = true
+	}
+	return v
+}
+
+// parameters translates the parameters of a procedure or function and
+// declares them in the routine. OUTPUT parameters become INOUT when
+// inout is set.
+func (t *postgres) parameters(params []*ast.ParameterDef, inout bool) string {
+	defs := make([]string, len(params))
+	for i, p := range params {
+		name := t.ident("p_" + strings.TrimPrefix(p.Name, "@"))
+		t.r.declare(p.Name, name, p.DataType)
+		if p.ReadOnly {
+			t.add(t.at, "table-valued parameter %s is not supported", p.Name)
+		}
+		defs[i] = name + " " + t.dataType(p.DataType, false)
+		if p.Output && inout {
+			defs[i] = "INOUT " + defs[i]
+		}
+		if p.Default != nil {
+			defs[i] += " DEFAULT " + t.expr(p.Default)
+		}
+	}
+	return strings.Join(defs, ", ")
+}
+
+// resultColumns translates the columns of the result sets of sig for
+// RETURNS TABLE. It reports false when they are not known.
+func (t *postgres) resultColumns(sig *signature.Signature) (string, bool) {
+	first := sig.ResultSets[0]
+	for _, rs := range sig.ResultSets[1:] {
+		if len(rs.Columns) != len(first.Columns) {
+			t.add(t.at, "%s returns result sets of different shapes, which is not supported", sig.Name.String())
+			break
+		}
+	}
+	cols := make([]string, len(first.Columns))
+	for i, c := range first.Columns {
+		if c.IsWildcard() {
+			t.add(t.at, "the columns of SELECT %s in %s are not known", c.Name, sig.Name.String())
+			return "", false
+		}
+		name := c.Name
+		if name == "" {
+			name = "column" + strconv.Itoa(i+1)
+			t.add(t.at, "result column %d of %s has no name", i+1, sig.Name.String())
+		}
+		typ := "text"
+		if c.DataType != nil {
+			typ = t.dataType(c.DataType, false)
+		} else {
+			t.add(t.at, "the type of result column %s of %s is not known", name, sig.Name.String())
+		}
+		cols[i] = t.ident(name) + " " + typ
+	}
+	return strings.Join(cols, ", "), true
+}
+
+// createProcedure translates a procedure to a PL/pgSQL function.
+func (t *postgres) createProcedure(proc *ast.CreateProcedureStatement) string {
+	sig := t.sigs.Procedure(proc)
+	t.r = newRoutine()
+	defer func() { t.r = nil }()
+	r := t.r
+	for _, o := range proc.Options {
+		t.add(proc.Token, "procedure option %s is not supported", strings.ToUpper(o))
+	}
+
+	r.results = len(sig.ResultSets) > 0
+	outputs := len(sig.OutputParameters()) > 0
+	if r.results && outputs {
+		t.add(proc.Token, "OUTPUT parameters of a procedure with result sets are not supported")
+		outputs = false
+	}
+	r.code = !r.results && !outputs
+	params := t.parameters(proc.Parameters, outputs)
+
+	var returns string
+	switch {
+	case r.results:
+		cols, ok := t.resultColumns(sig)
+		if ok {
+			returns = "RETURNS TABLE (" + cols + ")\n"
+			r.columns = true
+		} else {
+			returns = "RETURNS SETOF record\n"
+		}
+	case r.code:
+		returns = "RETURNS integer\n"
+	}
+
+	var last ast.Statement
+	if proc.Body != nil {
+		for _, stmt := range proc.Body.Statements {
+			t.stmt(stmt, 1)
+			last = stmt
+		}
+	}
+	if _, ok := last.(*ast.ReturnStatement); r.code && !ok {
+		r.line(1, "RETURN 0;")
+	}
+	return "CREATE OR REPLACE FUNCTION " + t.table(proc.Name) + "(" + params + ")\n" + returns +
+		"LANGUAGE plpgsql\nAS $$\n" + r.body() + "$$;"
+}
+
+// createFunction translates a function. Inline table functions become
+// SQL functions; multi-statement ones fill a temporary table that is
+// returned at their RETURN.
+func (t *postgres) createFunction(fn *ast.CreateFunctionStatement) string {
+	t.r = newRoutine()
+	defer func() { t.r = nil }()
+	r := t.r
+	for _, o := range fn.Options {
+		t.add(fn.Token, "function option %s is not supported", strings.ToUpper(o))
+	}
+	head := "CREATE OR REPLACE FUNCTION " + t.table(fn.Name) + "(" + t.parameters(fn.Parameters, false) + ")\n"
+
+	switch {
+	case fn.ReturnsTable:
+		sig := t.sigs.Function(fn)
+		returns := "RETURNS SETOF record\n"
+		if len(sig.ResultSets) > 0 {
+			if cols, ok := t.resultColumns(sig); ok {
+				returns = "RETURNS TABLE (" + cols + ")\n"
+			}
+		}
+		var query string
+		if sel, ok := fn.AsReturn.(*ast.SelectStatement); ok {
+			if query, ok = t.sql(sel, "    "); !ok {
+				return comment(fn.String())
+			}
+		} else {
+			query = t.expr(fn.AsReturn)
+		}
+		return head + returns + "LANGUAGE sql\nAS $$\n    " + query + ";\n$$;"
+	case fn.TableDef != nil:
+		r.table = t.ident("v_" + strings.TrimPrefix(fn.TableVar, "@"))
+		r.declare(fn.TableVar, r.table, nil)
+		r.columns = true
+		cols := make([]string, len(fn.TableDef.Columns))
+		for i, col := range fn.TableDef.Columns {
+			typ := "text"
+			if col.DataType != nil {
+				typ = t.dataType(col.DataType, false)
+			}
+			cols[i] = t.ident(col.Name.Value) + " " + typ
+		}
+		r.line(1, "DROP TABLE IF EXISTS "+r.table+";")
+		r.line(1, "CREATE TEMPORARY TABLE "+r.table+" "+t.tableElements(fn.TableDef.Columns, fn.TableDef.Constraints, "    ")+";")
+		head += "RETURNS TABLE (" + strings.Join(cols, ", ") + ")\n"
+	default:
+		r.scalar = true
+		head += "RETURNS " + t.dataType(fn.ReturnType, false) + "\n"
+	}
+	if fn.Body != nil {
+		for _, stmt := range fn.Body.Statements {
+			t.stmt(stmt, 1)
+		}
+	}
+	return head + "LANGUAGE plpgsql\nAS $$\n" + r.body() + "$$;"
+}
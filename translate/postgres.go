@@ -0,0 +1,402 @@
+package translate
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/signature"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Postgres translates program to PostgreSQL and reports what it could
+// not translate.
+//
+// Batches that only hold queries and DDL become plain SQL statements;
+// batches with variables or control flow become DO blocks. Procedures
+// become PL/pgSQL functions: a procedure that produces a result set
+// RETURNS TABLE, one with OUTPUT parameters takes them as INOUT
+// parameters, and any other returns its RETURN code as an integer.
+//
+// Names are written unquoted wherever PostgreSQL allows it. The dbo
+// schema is dropped so that objects resolve through the search_path,
+// #temp tables become TEMPORARY tables, parameters are prefixed p_ and
+// local variables v_ so that they cannot clash with column names, and
+// table variables become temporary tables named like local variables.
+// BIT becomes smallint rather than boolean so that comparisons with 0
+// and 1 keep working. Physical storage options such as CLUSTERED and
+// filegroups have no equivalent and are dropped.
+func Postgres(program *ast.Program) (string, []*Issue) {
+	t := &postgres{
+		sigs:  signature.NewExtractor(),
+		procs: make(map[string]*signature.Signature),
+	}
+	t.sigs.AddProgram(program)
+	for _, stmt := range program.Statements {
+		if proc, ok := procedureOf(stmt); ok {
+			t.procs[routineKey(proc.Name)] = t.sigs.Procedure(proc)
+		}
+	}
+
+	var out strings.Builder
+	var batch []ast.Statement
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.GoStatement); ok {
+			t.batch(&out, batch)
+			batch = nil
+			continue
+		}
+		batch = append(batch, stmt)
+	}
+	t.batch(&out, batch)
+	return out.String(), t.issues
+}
+
+type postgres struct {
+	issues
+	sigs  *signature.Extractor
+	procs map[string]*signature.Signature // Procedures of the script, for EXEC
+	r     *routine                        // PL/pgSQL being written; nil for plain SQL
+	at    token.Token                     // Start of the statement being translated
+
+	cte     string // CTE whose query is being translated
+	selfRef bool   // Whether that query refers to the CTE
+	lost    bool   // Whether the statement lost something it cannot do without
+
+	// What the inserted and deleted pseudo-tables of an OUTPUT clause
+	// become in RETURNING
+	pseudo map[string]string
+}
+
+func procedureOf(stmt ast.Statement) (*ast.CreateProcedureStatement, bool) {
+	switch x := stmt.(type) {
+	case *ast.CreateProcedureStatement:
+		return x, true
+	case *ast.AlterProcedureStatement:
+		return &ast.CreateProcedureStatement{
+			Token:      x.Token,
+			Name:       x.Name,
+			Parameters: x.Parameters,
+			Body:       x.Body,
+			Options:    x.Options,
+		}, true
+	}
+	return nil, false
+}
+
+func functionOf(stmt ast.Statement) (*ast.CreateFunctionStatement, bool) {
+	switch x := stmt.(type) {
+	case *ast.CreateFunctionStatement:
+		return x, true
+	case *ast.AlterFunctionStatement:
+		return &ast.CreateFunctionStatement{
+			Token:        x.Token,
+			Name:         x.Name,
+			Parameters:   x.Parameters,
+			ReturnType:   x.ReturnType,
+			ReturnsTable: x.ReturnsTable,
+			TableDef:     x.TableDef,
+			TableVar:     x.TableVar,
+			Options:      x.Options,
+			AsReturn:     x.AsReturn,
+			Body:         x.Body,
+		}, true
+	}
+	return nil, false
+}
+
+// routineKey returns the name a procedure is known by: its lower-case
+// schema and name, with the schema defaulting to dbo.
+func routineKey(name *ast.QualifiedIdentifier) string {
+	schema := "dbo"
+	if n := len(name.Parts); n > 1 && name.Parts[n-2].Value != "" {
+		schema = name.Parts[n-2].Value
+	}
+	return strings.ToLower(schema + "." + name.Parts[len(name.Parts)-1].Value)
+}
+
+// batch translates the statements between two GOs.
+func (t *postgres) batch(out *strings.Builder, stmts []ast.Statement) {
+	procedural := false
+	for _, stmt := range stmts {
+		procedural = procedural || isProcedural(stmt)
+	}
+	if !procedural {
+		for _, stmt := range stmts {
+			if sql := t.topLevel(stmt); sql != "" {
+				out.WriteString(sql)
+				out.WriteString("\n\n")
+			}
+		}
+		return
+	}
+
+	t.r = newRoutine()
+	t.r.block = true
+	for _, stmt := range stmts {
+		t.stmt(stmt, 1)
+	}
+	out.WriteString("DO $$\n")
+	out.WriteString(t.r.body())
+	out.WriteString("$$;\n\n")
+	t.r = nil
+}
+
+// isProcedural reports whether stmt can only run in PL/pgSQL.
+func isProcedural(stmt ast.Statement) bool {
+	switch x := stmt.(type) {
+	case *ast.DeclareStatement, *ast.IfStatement, *ast.WhileStatement, *ast.BeginEndBlock,
+		*ast.TryCatchStatement, *ast.PrintStatement, *ast.ReturnStatement, *ast.BreakStatement,
+		*ast.ContinueStatement, *ast.ThrowStatement, *ast.RaiserrorStatement,
+		*ast.GotoStatement, *ast.LabelStatement:
+		return true
+	case *ast.SetStatement:
+		return x.Variable != nil
+	case *ast.SelectStatement:
+		return assigns(x)
+	case *ast.ExecStatement:
+		if x.ReturnVariable != nil || x.DynamicSQL != nil {
+			return true
+		}
+		for _, p := range x.Parameters {
+			if p.Output {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assigns reports whether sel assigns variables rather than returning
+// rows.
+func assigns(sel *ast.SelectStatement) bool {
+	return len(sel.Columns) > 0 && sel.Columns[0].Variable != nil
+}
+
+// topLevel translates a statement outside PL/pgSQL, with its
+// terminating semicolon.
+func (t *postgres) topLevel(stmt ast.Statement) string {
+	t.at = statementToken(stmt)
+	if proc, ok := procedureOf(stmt); ok {
+		return t.createProcedure(proc)
+	}
+	if fn, ok := functionOf(stmt); ok {
+		return t.createFunction(fn)
+	}
+	switch x := stmt.(type) {
+	case *ast.SetStatement:
+		if !t.option(x) {
+			return comment(stmt.String())
+		}
+		return ""
+	case *ast.ExecStatement:
+		call, results, ok := t.call(x)
+		if !ok {
+			return comment(stmt.String())
+		}
+		if results {
+			return "SELECT * FROM " + call + ";"
+		}
+		return "SELECT " + call + ";"
+	}
+	sql, ok := t.sql(stmt, "")
+	if !ok {
+		return comment(stmt.String())
+	}
+	return sql + ";"
+}
+
+// option checks a SET option. Options whose ON setting is how
+// PostgreSQL always behaves translate to nothing; it reports false for
+// any other.
+func (t *postgres) option(x *ast.SetStatement) bool {
+	switch strings.ToUpper(x.Option) {
+	case "NOCOUNT", "XACT_ABORT", "ANSI_WARNINGS", "ARITHABORT", "CONCAT_NULL_YIELDS_NULL":
+		return true
+	case "ANSI_NULLS", "QUOTED_IDENTIFIER", "ANSI_PADDING":
+		if strings.EqualFold(x.OnOff, "ON") {
+			return true
+		}
+	}
+	t.add(x.Token, "SET %s %s has no PostgreSQL equivalent", strings.ToUpper(x.Option), strings.ToUpper(x.OnOff))
+	return false
+}
+
+// sql translates a query, data modification or DDL statement, without
+// a terminating semicolon. indent is the indentation of the line the
+// statement starts on, for statements written over several lines. It
+// reports false for a statement that would lose part of its meaning,
+// such as its TOP ... PERCENT.
+func (t *postgres) sql(stmt ast.Statement, indent string) (string, bool) {
+	outer := t.lost
+	t.lost = false
+	sql, ok := t.statement(stmt, indent)
+	if t.lost {
+		sql, ok = "", false
+	}
+	t.lost = outer
+	return sql, ok
+}
+
+func (t *postgres) statement(stmt ast.Statement, indent string) (string, bool) {
+	t.at = statementToken(stmt)
+	switch x := stmt.(type) {
+	case *ast.SelectStatement:
+		if x.Into == nil {
+			return t.query(x), true
+		}
+		into := *x
+		into.Into = nil
+		temporary := ""
+		if isTemporary(x.Into) {
+			temporary = "TEMPORARY "
+		}
+		return "CREATE " + temporary + "TABLE " + t.table(x.Into) + " AS " + t.query(&into), true
+	case *ast.WithStatement:
+		return t.with(x, indent)
+	case *ast.InsertStatement:
+		return t.insert(x)
+	case *ast.UpdateStatement:
+		return t.update(x)
+	case *ast.DeleteStatement:
+		return t.delete(x)
+	case *ast.CreateTableStatement:
+		return t.createTable(x, indent)
+	case *ast.DropTableStatement:
+		names := make([]string, len(x.Tables))
+		for i, name := range x.Tables {
+			names[i] = t.table(name)
+		}
+		return "DROP TABLE " + ifExists(x.IfExists) + strings.Join(names, ", "), true
+	case *ast.TruncateTableStatement:
+		if len(x.Partitions) > 0 {
+			t.add(x.Token, "TRUNCATE TABLE WITH (PARTITIONS ...) is not supported")
+			return "", false
+		}
+		return "TRUNCATE TABLE " + t.table(x.Table), true
+	case *ast.CreateViewStatement:
+		return t.view("CREATE VIEW ", x.Name, x.Columns, x.Options, x.AsSelect, x.CheckOption, indent)
+	case *ast.AlterViewStatement:
+		return t.view("CREATE OR REPLACE VIEW ", x.Name, x.Columns, x.Options, x.AsSelect, false, indent)
+	case *ast.CreateIndexStatement:
+		return t.createIndex(x)
+	case *ast.DropIndexStatement:
+		return "DROP INDEX " + ifExists(x.IfExists) + t.index(x.Name, x.Table), true
+	case *ast.DropObjectStatement:
+		return t.dropObject(x)
+	case *ast.BeginTransactionStatement, *ast.CommitTransactionStatement, *ast.RollbackTransactionStatement,
+		*ast.SaveTransactionStatement:
+		return t.transaction(stmt)
+	}
+	t.add(t.at, "%s is not supported", nodeName(stmt, "Statement"))
+	return "", false
+}
+
+func ifExists(ok bool) string {
+	if ok {
+		return "IF EXISTS "
+	}
+	return ""
+}
+
+func (t *postgres) transaction(stmt ast.Statement) (string, bool) {
+	if t.r != nil {
+		t.add(t.at, "transaction control inside a procedure or DO block is not supported")
+		return "", false
+	}
+	switch x := stmt.(type) {
+	case *ast.BeginTransactionStatement:
+		return "BEGIN", true
+	case *ast.CommitTransactionStatement:
+		return "COMMIT", true
+	case *ast.RollbackTransactionStatement:
+		return "ROLLBACK", true
+	case *ast.SaveTransactionStatement:
+		return "SAVEPOINT " + t.ident(x.SavepointName.Value), true
+	}
+	return "", false
+}
+
+// statementToken returns the token a statement starts with.
+func statementToken(stmt ast.Statement) token.Token {
+	switch x := stmt.(type) {
+	case *ast.SelectStatement:
+		return x.Token
+	case *ast.InsertStatement:
+		return x.Token
+	case *ast.UpdateStatement:
+		return x.Token
+	case *ast.DeleteStatement:
+		return x.Token
+	case *ast.MergeStatement:
+		return x.Token
+	case *ast.WithStatement:
+		return x.Token
+	case *ast.DeclareStatement:
+		return x.Token
+	case *ast.SetStatement:
+		return x.Token
+	case *ast.IfStatement:
+		return x.Token
+	case *ast.WhileStatement:
+		return x.Token
+	case *ast.BeginEndBlock:
+		return x.Token
+	case *ast.TryCatchStatement:
+		return x.Token
+	case *ast.ReturnStatement:
+		return x.Token
+	case *ast.BreakStatement:
+		return x.Token
+	case *ast.ContinueStatement:
+		return x.Token
+	case *ast.PrintStatement:
+		return x.Token
+	case *ast.ExecStatement:
+		return x.Token
+	case *ast.ThrowStatement:
+		return x.Token
+	case *ast.RaiserrorStatement:
+		return x.Token
+	case *ast.GotoStatement:
+		return x.Token
+	case *ast.LabelStatement:
+		return x.Token
+	case *ast.BeginTransactionStatement:
+		return x.Token
+	case *ast.CommitTransactionStatement:
+		return x.Token
+	case *ast.RollbackTransactionStatement:
+		return x.Token
+	case *ast.SaveTransactionStatement:
+		return x.Token
+	case *ast.CreateTableStatement:
+		return x.Token
+	case *ast.DropTableStatement:
+		return x.Token
+	case *ast.TruncateTableStatement:
+		return x.Token
+	case *ast.CreateViewStatement:
+		return x.Token
+	case *ast.AlterViewStatement:
+		return x.Token
+	case *ast.CreateIndexStatement:
+		return x.Token
+	case *ast.DropIndexStatement:
+		return x.Token
+	case *ast.DropObjectStatement:
+		return x.Token
+	case *ast.CreateProcedureStatement:
+		return x.Token
+	case *ast.AlterProcedureStatement:
+		return x.Token
+	case *ast.CreateFunctionStatement:
+		return x.Token
+	case *ast.AlterFunctionStatement:
+		return x.Token
+	case *ast.DeclareCursorStatement:
+		return x.Token
+	case *ast.CreateTriggerStatement:
+		return x.Token
+	}
+	return token.Token{}
+}
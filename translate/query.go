@@ -0,0 +1,599 @@
+package translate
+
+import (
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// query translates a SELECT and the set operations chained to it. The
+// parser attaches the ORDER BY and OFFSET of a UNION to its last member.
+func (t *postgres) query(sel *ast.SelectStatement) string {
+	var b strings.Builder
+	b.WriteString(t.selectCore(sel, ""))
+	last := sel
+	for u := sel.Union; u != nil; u = last.Union {
+		b.WriteString(" " + strings.ToUpper(u.Type))
+		if u.All {
+			b.WriteString(" ALL")
+		}
+		b.WriteString(" " + t.selectCore(u.Right, ""))
+		if u.Right.Top != nil {
+			t.add(u.Right.Token, "TOP in a member of a %s is not supported", strings.ToUpper(u.Type))
+			t.lost = true
+		}
+		last = u.Right
+	}
+	top := sel.Top
+	if last != sel && top != nil {
+		t.add(sel.Token, "TOP in a member of a %s is not supported", strings.ToUpper(sel.Union.Type))
+		t.lost = true
+		top = nil
+	}
+	b.WriteString(t.orderAndLimit(last, top))
+	return b.String()
+}
+
+// selectCore translates a SELECT up to its ORDER BY. into holds the
+// variables a PL/pgSQL SELECT INTO assigns.
+func (t *postgres) selectCore(sel *ast.SelectStatement, into string) string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if sel.Distinct {
+		b.WriteString("DISTINCT ")
+	}
+	b.WriteString(t.selectColumns(sel.Columns))
+	if into != "" {
+		b.WriteString(" INTO " + into)
+	}
+	if sel.From != nil {
+		b.WriteString(" FROM " + t.from(sel.From.Tables))
+	}
+	if sel.Where != nil {
+		b.WriteString(" WHERE " + t.expr(sel.Where))
+	}
+	if len(sel.GroupBy) > 0 {
+		b.WriteString(" GROUP BY " + t.list(sel.GroupBy))
+	}
+	if sel.Having != nil {
+		b.WriteString(" HAVING " + t.expr(sel.Having))
+	}
+	if len(sel.WindowDefs) > 0 {
+		defs := make([]string, len(sel.WindowDefs))
+		for i, w := range sel.WindowDefs {
+			defs[i] = t.ident(w.Name) + " AS (" + t.window(w.Spec) + ")"
+		}
+		b.WriteString(" WINDOW " + strings.Join(defs, ", "))
+	}
+	if sel.ForClause != nil {
+		t.add(sel.ForClause.Token, "FOR %s is not supported", strings.ToUpper(sel.ForClause.ForType))
+	}
+	for _, o := range sel.Options {
+		t.add(sel.Token, "query hint OPTION (%s) is not supported", strings.ToUpper(o.Name))
+	}
+	return b.String()
+}
+
+// selectColumns translates a select list. alias = expr is the T-SQL
+// spelling of expr AS alias.
+func (t *postgres) selectColumns(cols []ast.SelectColumn) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		if c.AllColumns {
+			parts[i] = "*"
+			continue
+		}
+		expr, alias := c.Expression, ""
+		if c.Alias != nil {
+			alias = c.Alias.Value
+		} else if eq, ok := expr.(*ast.InfixExpression); ok && eq.Operator == "=" {
+			switch left := eq.Left.(type) {
+			case *ast.Identifier:
+				expr, alias = eq.Right, left.Value
+			case *ast.StringLiteral:
+				expr, alias = eq.Right, left.Value
+			}
+		}
+		parts[i] = t.expr(expr)
+		if alias != "" {
+			parts[i] += " AS " + t.ident(alias)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// orderAndLimit translates ORDER BY, TOP and OFFSET/FETCH.
+func (t *postgres) orderAndLimit(sel *ast.SelectStatement, top *ast.TopClause) string {
+	var b strings.Builder
+	if len(sel.OrderBy) > 0 {
+		b.WriteString(" ORDER BY " + t.orderBy(sel.OrderBy))
+	}
+	if top != nil {
+		switch {
+		case top.Percent:
+			t.add(sel.Token, "TOP ... PERCENT is not supported")
+			t.lost = true
+		case top.WithTies:
+			count := t.expr(top.Count)
+			if _, ok := top.Count.(*ast.IntegerLiteral); !ok {
+				count = "(" + count + ")"
+			}
+			b.WriteString(" FETCH FIRST " + count + " ROWS WITH TIES")
+		default:
+			b.WriteString(" LIMIT " + t.expr(top.Count))
+		}
+	}
+	if sel.Fetch != nil {
+		b.WriteString(" LIMIT " + t.expr(sel.Fetch))
+	}
+	if sel.Offset != nil {
+		b.WriteString(" OFFSET " + t.expr(sel.Offset))
+	}
+	return b.String()
+}
+
+func (t *postgres) from(refs []ast.TableReference) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = t.tableRef(ref)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (t *postgres) alias(alias *ast.Identifier, columns []*ast.Identifier) string {
+	if alias == nil {
+		return ""
+	}
+	s := " AS " + t.ident(alias.Value)
+	if len(columns) > 0 {
+		s += " (" + t.idents(columns) + ")"
+	}
+	return s
+}
+
+func (t *postgres) idents(ids []*ast.Identifier) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = t.ident(id.Value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// tableRef translates an item of a FROM clause. APPLY becomes a LATERAL
+// join.
+func (t *postgres) tableRef(ref ast.TableReference) string {
+	switch x := ref.(type) {
+	case *ast.TableName:
+		if t.cte != "" && len(x.Name.Parts) == 1 && strings.EqualFold(x.Name.Parts[0].Value, t.cte) {
+			t.selfRef = true
+		}
+		t.hints(x.Token, x.Hints)
+		if x.TemporalClause != nil {
+			t.add(x.TemporalClause.Token, "FOR SYSTEM_TIME is not supported")
+		}
+		if x.TableSample != nil {
+			t.add(x.Token, "TABLESAMPLE is not supported")
+		}
+		return t.table(x.Name) + t.alias(x.Alias, nil)
+	case *ast.DerivedTable:
+		return "(" + t.query(x.Subquery) + ")" + t.alias(x.Alias, x.ColumnAliases)
+	case *ast.ValuesTable:
+		rows := make([]string, len(x.Rows))
+		for i, row := range x.Rows {
+			rows[i] = "(" + t.list(row) + ")"
+		}
+		return "(VALUES " + strings.Join(rows, ", ") + ")" + t.alias(x.Alias, x.Columns)
+	case *ast.TableValuedFunction:
+		return t.tableFunction(x)
+	case *ast.GenerateSeries:
+		return t.generateSeries(x)
+	case *ast.ParenthesizedTableRef:
+		return "(" + t.tableRef(x.Inner) + ")"
+	case *ast.JoinClause:
+		if x.Hint != "" {
+			t.add(x.Token, "join hint %s is not supported", strings.ToUpper(x.Hint))
+		}
+		left, right := t.tableRef(x.Left), t.tableRef(x.Right)
+		switch strings.ToUpper(x.Type) {
+		case "CROSS":
+			return left + " CROSS JOIN " + right
+		case "CROSS APPLY":
+			return left + " CROSS JOIN LATERAL " + right
+		case "OUTER APPLY":
+			return left + " LEFT JOIN LATERAL " + right + " ON true"
+		}
+		return left + " " + strings.ToUpper(x.Type) + " JOIN " + right + " ON " + t.expr(x.Condition)
+	}
+	t.add(t.at, "%s is not supported", nodeName(ref, ""))
+	return ref.String()
+}
+
+// hints reports table hints. NOLOCK only asks for reads that do not
+// wait for writers, which PostgreSQL always does, and is dropped.
+func (t *postgres) hints(tok token.Token, hints []string) {
+	for _, h := range hints {
+		switch strings.ToUpper(h) {
+		case "NOLOCK", "READUNCOMMITTED":
+		default:
+			t.add(tok, "table hint %s is not supported", strings.ToUpper(h))
+		}
+	}
+}
+
+// tableFunction translates a table-valued function. STRING_SPLIT
+// becomes unnest over string_to_array.
+func (t *postgres) tableFunction(x *ast.TableValuedFunction) string {
+	name := x.Function.Parts[len(x.Function.Parts)-1].Value
+	if len(x.Function.Parts) == 1 {
+		switch strings.ToUpper(name) {
+		case "STRING_SPLIT":
+			if len(x.Arguments) < 2 || len(x.Arguments) > 3 {
+				break
+			}
+			alias := &ast.Identifier{Value: "string_split"}
+			if x.Alias != nil {
+				alias = x.Alias
+			}
+			columns := []*ast.Identifier{{Value: "value"}}
+			s := "unnest(string_to_array(" + t.expr(x.Arguments[0]) + ", " + t.expr(x.Arguments[1]) + "))"
+			if len(x.Arguments) == 3 {
+				s += " WITH ORDINALITY"
+				columns = append(columns, &ast.Identifier{Value: "ordinal"})
+			}
+			return s + t.alias(alias, columns)
+		default:
+			t.add(x.Token, "table function %s is not supported", strings.ToUpper(name))
+			return x.String()
+		}
+	}
+	return t.table(x.Function) + "(" + t.list(x.Arguments) + ")" + t.alias(x.Alias, x.ColumnAliases)
+}
+
+// generateSeries translates GENERATE_SERIES to generate_series, naming
+// its column value. Without a step, GENERATE_SERIES counts down when
+// start is greater than stop, where generate_series returns no rows.
+func (t *postgres) generateSeries(x *ast.GenerateSeries) string {
+	start, stop := t.expr(x.Start), t.expr(x.Stop)
+	s := "generate_series(" + start + ", " + stop
+	switch {
+	case x.Step != nil:
+		s += ", " + t.expr(x.Step)
+	case isIntegerLiteral(x.Start) && isIntegerLiteral(x.Stop):
+		if x.Start.(*ast.IntegerLiteral).Value > x.Stop.(*ast.IntegerLiteral).Value {
+			s += ", -1"
+		}
+	default:
+		s += ", CASE WHEN " + start + " > " + stop + " THEN -1 ELSE 1 END"
+	}
+	alias := &ast.Identifier{Value: "generate_series"}
+	if x.Alias != nil {
+		alias = x.Alias
+	}
+	columns := x.ColumnAliases
+	if len(columns) == 0 {
+		columns = []*ast.Identifier{{Value: "value"}}
+	}
+	return s + ")" + t.alias(alias, columns)
+}
+
+func isIntegerLiteral(e ast.Expression) bool {
+	_, ok := e.(*ast.IntegerLiteral)
+	return ok
+}
+
+// with translates a statement with common table expressions, which are
+// RECURSIVE in PostgreSQL when one refers to itself.
+func (t *postgres) with(x *ast.WithStatement, indent string) (string, bool) {
+	recursive := false
+	defs := make([]string, len(x.CTEs))
+	for i, cte := range x.CTEs {
+		t.cte, t.selfRef = cte.Name.Value, false
+		q := t.query(cte.Query)
+		recursive = recursive || t.selfRef
+		defs[i] = t.ident(cte.Name.Value)
+		if len(cte.Columns) > 0 {
+			defs[i] += " (" + t.idents(cte.Columns) + ")"
+		}
+		defs[i] += " AS (" + q + ")"
+	}
+	t.cte = ""
+	with := "WITH "
+	if recursive {
+		with += "RECURSIVE "
+	}
+	with += strings.Join(defs, ", ") + " "
+
+	if sel, ok := x.Query.(*ast.SelectStatement); ok && sel.Into != nil {
+		// CREATE TABLE ... AS takes the WITH inside it
+		query := *sel
+		query.Into = nil
+		temporary := ""
+		if isTemporary(sel.Into) {
+			temporary = "TEMPORARY "
+		}
+		return "CREATE " + temporary + "TABLE " + t.table(sel.Into) + " AS " + with + t.query(&query), true
+	}
+	body, ok := t.sql(x.Query, indent)
+	if !ok {
+		return "", false
+	}
+	return with + body, true
+}
+
+func (t *postgres) insert(x *ast.InsertStatement) (string, bool) {
+	if x.Top != nil {
+		t.add(x.Token, "INSERT TOP is not supported")
+		return "", false
+	}
+	t.hints(x.Token, x.Hints)
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + t.table(x.Table))
+	if len(x.Columns) > 0 {
+		b.WriteString(" (" + t.idents(x.Columns) + ")")
+	}
+	switch {
+	case x.DefaultValues:
+		b.WriteString(" DEFAULT VALUES")
+	case x.Select != nil:
+		b.WriteString(" " + t.query(x.Select))
+	case len(x.Values) > 0:
+		rows := make([]string, len(x.Values))
+		for i, row := range x.Values {
+			rows[i] = "(" + t.list(row) + ")"
+		}
+		b.WriteString(" VALUES " + strings.Join(rows, ", "))
+	default:
+		t.add(x.Token, "INSERT ... EXEC is not supported")
+		return "", false
+	}
+	return t.output(b.String(), x.Output, map[string]string{"inserted": ""}), true
+}
+
+// output adds the RETURNING clause an OUTPUT clause becomes to a data
+// modification statement. OUTPUT ... INTO becomes a data-modifying CTE
+// whose rows are inserted into the target.
+func (t *postgres) output(dml string, o *ast.OutputClause, pseudo map[string]string) string {
+	if o == nil {
+		return dml
+	}
+	t.pseudo = pseudo
+	dml += " RETURNING " + t.selectColumns(o.Columns)
+	t.pseudo = nil
+
+	var target string
+	switch {
+	case o.Into != nil:
+		target = t.table(o.Into)
+	case o.IntoVariable != nil:
+		target = t.variableName(o.IntoVariable.Name)
+	default:
+		return dml
+	}
+	if len(o.IntoColumns) > 0 {
+		target += " (" + t.idents(o.IntoColumns) + ")"
+	}
+	return "WITH changed AS (" + dml + ") INSERT INTO " + target + " SELECT * FROM changed"
+}
+
+// returnsRows reports whether a data modification statement returns
+// its OUTPUT rows to the caller.
+func returnsRows(stmt ast.Statement) bool {
+	var o *ast.OutputClause
+	switch x := stmt.(type) {
+	case *ast.InsertStatement:
+		o = x.Output
+	case *ast.UpdateStatement:
+		o = x.Output
+	case *ast.DeleteStatement:
+		o = x.Output
+	case *ast.WithStatement:
+		return returnsRows(x.Query)
+	}
+	return o != nil && o.Into == nil && o.IntoVariable == nil
+}
+
+func (t *postgres) update(x *ast.UpdateStatement) (string, bool) {
+	switch {
+	case x.Table == nil:
+		t.add(x.Token, "UPDATE of a rowset function is not supported")
+		return "", false
+	case x.Top != nil:
+		t.add(x.Token, "UPDATE TOP is not supported")
+		return "", false
+	case x.CurrentOfCursor != nil:
+		t.add(x.Token, "WHERE CURRENT OF is not supported")
+		return "", false
+	}
+	t.hints(x.Token, x.Hints)
+	target, ok := t.target(x.Table, x.Alias, x.From, x.Where)
+	if !ok {
+		return "", false
+	}
+
+	sets := make([]string, len(x.SetClauses))
+	for i, sc := range x.SetClauses {
+		column := sc.Column.Parts[len(sc.Column.Parts)-1]
+		switch {
+		case sc.IsMethodCall:
+			t.add(column.Token, "method call in UPDATE SET is not supported")
+			return "", false
+		case strings.HasPrefix(column.Value, "@"):
+			t.add(column.Token, "assigning %s in UPDATE is not supported", column.Value)
+			return "", false
+		}
+		value := sc.Value
+		if sc.Operator != "=" && sc.Operator != "" {
+			value = &ast.InfixExpression{
+				Token:    column.Token,
+				Left:     &ast.Identifier{Token: column.Token, Value: column.Value},
+				Operator: strings.TrimSuffix(sc.Operator, "="),
+				Right:    value,
+			}
+		}
+		sets[i] = t.ident(column.Value) + " = " + t.expr(value)
+	}
+
+	s := "UPDATE " + target.table + " SET " + strings.Join(sets, ", ")
+	if target.from != "" {
+		s += " FROM " + target.from
+	}
+	if target.where != "" {
+		s += " WHERE " + target.where
+	}
+	return t.output(s, x.Output, map[string]string{"inserted": target.name}), true
+}
+
+func (t *postgres) delete(x *ast.DeleteStatement) (string, bool) {
+	name, alias := x.Table, x.Alias
+	if name == nil && alias != nil {
+		// The parser reads the target of DELETE alias FROM ... and of
+		// DELETE t OUTPUT ... as an alias
+		name, alias = &ast.QualifiedIdentifier{Parts: []*ast.Identifier{alias}}, nil
+	}
+	switch {
+	case name == nil:
+		t.add(x.Token, "DELETE from a rowset function is not supported")
+		return "", false
+	case x.Top != nil:
+		t.add(x.Token, "DELETE TOP is not supported")
+		return "", false
+	case x.CurrentOfCursor != nil:
+		t.add(x.Token, "WHERE CURRENT OF is not supported")
+		return "", false
+	}
+	t.hints(x.Token, x.Hints)
+	target, ok := t.target(name, alias, x.From, x.Where)
+	if !ok {
+		return "", false
+	}
+	s := "DELETE FROM " + target.table
+	if target.from != "" {
+		s += " USING " + target.from
+	}
+	if target.where != "" {
+		s += " WHERE " + target.where
+	}
+	return t.output(s, x.Output, map[string]string{"deleted": target.name}), true
+}
+
+// dmlTarget is the target of an UPDATE or DELETE in PostgreSQL's form,
+// where the target is not repeated in FROM or USING and the conditions
+// of the joins to it move to WHERE.
+type dmlTarget struct {
+	table string // Table and alias
+	name  string // What columns of the target are qualified with
+	from  string
+	where string
+}
+
+// target translates the target of an UPDATE or DELETE with a FROM
+// clause. The target may be named by its alias in FROM; when it is one
+// of the inner-joined tables there, it is taken out of FROM.
+func (t *postgres) target(name *ast.QualifiedIdentifier, alias *ast.Identifier, from *ast.FromClause, where ast.Expression) (*dmlTarget, bool) {
+	target := &dmlTarget{
+		table: t.table(name) + t.alias(alias, nil),
+		name:  t.ident(name.Parts[len(name.Parts)-1].Value),
+	}
+	if alias != nil {
+		target.name = t.ident(alias.Value)
+	}
+	var conds []string
+	if from != nil {
+		tn, rest, joins, ok := splitTarget(name, from)
+		if !ok {
+			t.add(t.at, "the target of the statement is outer-joined, which is not supported")
+			return nil, false
+		}
+		if tn != nil {
+			target.table = t.table(tn.Name) + t.alias(tn.Alias, nil)
+			target.name = t.ident(tn.Name.Parts[len(tn.Name.Parts)-1].Value)
+			if tn.Alias != nil {
+				target.name = t.ident(tn.Alias.Value)
+			}
+		}
+		target.from = t.from(rest)
+		for _, j := range joins {
+			conds = append(conds, t.operand(j, precedenceAnd))
+		}
+	}
+	if where != nil {
+		conds = append(conds, t.operand(where, precedenceAnd))
+	}
+	target.where = strings.Join(conds, " AND ")
+	return target, true
+}
+
+// splitTarget finds the target of an UPDATE or DELETE among the inner
+// joins of its FROM clause. It returns the table that is the target, or
+// nil when FROM does not list it, with the other tables and the join
+// conditions. It reports false when the target is outer-joined.
+func splitTarget(name *ast.QualifiedIdentifier, from *ast.FromClause) (*ast.TableName, []ast.TableReference, []ast.Expression, bool) {
+	var refs []ast.TableReference
+	var joins []ast.Expression
+	for _, ref := range from.Tables {
+		r, j := innerJoined(ref)
+		refs, joins = append(refs, r...), append(joins, j...)
+	}
+	var target *ast.TableName
+	var rest []ast.TableReference
+	for _, ref := range refs {
+		if tn, ok := ref.(*ast.TableName); ok && target == nil && names(tn, name) {
+			target = tn
+			continue
+		}
+		rest = append(rest, ref)
+	}
+	if target == nil {
+		for _, ref := range refs {
+			if findTable(ref, name) != nil {
+				return nil, nil, nil, false
+			}
+		}
+	}
+	return target, rest, joins, true
+}
+
+// innerJoined flattens a tree of inner and cross joins into its tables
+// and join conditions.
+func innerJoined(ref ast.TableReference) ([]ast.TableReference, []ast.Expression) {
+	j, ok := ref.(*ast.JoinClause)
+	if !ok || (j.Type != "INNER" && j.Type != "CROSS") {
+		return []ast.TableReference{ref}, nil
+	}
+	left, lj := innerJoined(j.Left)
+	right, rj := innerJoined(j.Right)
+	joins := append(lj, rj...)
+	if j.Condition != nil {
+		joins = append(joins, j.Condition)
+	}
+	return append(left, right...), joins
+}
+
+// names reports whether a table in FROM is the one name refers to,
+// by alias or by name.
+func names(tn *ast.TableName, name *ast.QualifiedIdentifier) bool {
+	last := name.Parts[len(name.Parts)-1].Value
+	if tn.Alias != nil {
+		return len(name.Parts) == 1 && strings.EqualFold(tn.Alias.Value, last)
+	}
+	return strings.EqualFold(tn.Name.Parts[len(tn.Name.Parts)-1].Value, last)
+}
+
+// findTable returns the table of ref that name refers to.
+func findTable(ref ast.TableReference, name *ast.QualifiedIdentifier) *ast.TableName {
+	switch x := ref.(type) {
+	case *ast.TableName:
+		if names(x, name) {
+			return x
+		}
+	case *ast.JoinClause:
+		if tn := findTable(x.Left, name); tn != nil {
+			return tn
+		}
+		return findTable(x.Right, name)
+	case *ast.ParenthesizedTableRef:
+		return findTable(x.Inner, name)
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// UnsupportedError reports T-SQL that has no SQLite equivalent.
+type UnsupportedError struct {
+	Line    int
+	Column  int
+	Feature string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("line %d, col %d: %s is not supported by SQLite", e.Line, e.Column, e.Feature)
+}
+
+// SQLite translates a SELECT, INSERT, UPDATE or DELETE, with or without
+// common table expressions, to SQLite, so that query logic can be tested
+// against SQLite fixtures. It returns an *UnsupportedError for the first
+// construct SQLite cannot express.
+//
+// Variables are left as @name, which SQLite binds as named parameters.
+// Dates are ISO-8601 text, which is what SQLite's date functions read and
+// write; date arithmetic yields 'YYYY-MM-DD HH:MM:SS'. The dbo schema is
+// dropped and #temp tables lose their #. An UPDATE or DELETE with a FROM
+// clause needs SQLite 3.33 or later, and functions such as FLOOR and
+// POWER need a build with the math functions.
+func SQLite(stmt ast.Statement) (string, error) {
+	t := &sqlite{at: statementToken(stmt)}
+	sql := t.statement(stmt)
+	if t.err != nil {
+		return "", t.err
+	}
+	return sql, nil
+}
+
+type sqlite struct {
+	at  token.Token // Start of the statement
+	err *UnsupportedError
+
+	// What the inserted and deleted pseudo-tables of an OUTPUT clause
+	// become in RETURNING
+	pseudo map[string]string
+}
+
+// unsupported records the first construct that cannot be translated.
+func (t *sqlite) unsupported(tok token.Token, format string, args ...any) {
+	if t.err != nil {
+		return
+	}
+	if tok.Line == 0 {
+		tok = t.at
+	}
+	t.err = &UnsupportedError{Line: tok.Line, Column: tok.Column, Feature: fmt.Sprintf(format, args...)}
+}
+
+func (t *sqlite) statement(stmt ast.Statement) string {
+	switch x := stmt.(type) {
+	case *ast.SelectStatement:
+		if assigns(x) {
+			t.unsupported(x.Token, "assigning variables in SELECT")
+			return ""
+		}
+		if x.Into != nil {
+			return t.selectInto(x, "")
+		}
+		return t.query(x)
+	case *ast.WithStatement:
+		return t.with(x)
+	case *ast.InsertStatement:
+		return t.insert(x)
+	case *ast.UpdateStatement:
+		return t.update(x)
+	case *ast.DeleteStatement:
+		return t.delete(x)
+	}
+	t.unsupported(t.at, "%s", nodeName(stmt, "Statement"))
+	return ""
+}
+
+// selectInto translates SELECT ... INTO to CREATE TABLE ... AS, with the
+// WITH clause the query was written with.
+func (t *sqlite) selectInto(sel *ast.SelectStatement, with string) string {
+	query := *sel
+	query.Into = nil
+	temporary := ""
+	if isTemporary(sel.Into) {
+		temporary = "TEMP "
+	}
+	return "CREATE " + temporary + "TABLE " + t.table(sel.Into) + " AS " + with + t.query(&query)
+}
+
+// query translates a SELECT and the set operations chained to it.
+func (t *sqlite) query(sel *ast.SelectStatement) string {
+	var b strings.Builder
+	b.WriteString(t.selectCore(sel))
+	last := sel
+	for u := sel.Union; u != nil; u = last.Union {
+		b.WriteString(" " + strings.ToUpper(u.Type))
+		if u.All {
+			b.WriteString(" ALL")
+		}
+		if u.Right.Top != nil {
+			t.unsupported(u.Right.Token, "TOP in a member of a %s", strings.ToUpper(u.Type))
+		}
+		b.WriteString(" " + t.selectCore(u.Right))
+		last = u.Right
+	}
+	if last != sel && sel.Top != nil {
+		t.unsupported(sel.Token, "TOP in a member of a %s", strings.ToUpper(sel.Union.Type))
+	}
+	b.WriteString(t.orderAndLimit(last, sel.Top))
+	return b.String()
+}
+
+func (t *sqlite) selectCore(sel *ast.SelectStatement) string {
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if sel.Distinct {
+		b.WriteString("DISTINCT ")
+	}
+	b.WriteString(t.selectColumns(sel.Columns))
+	if sel.From != nil {
+		b.WriteString(" FROM " + t.from(sel.From.Tables))
+	}
+	if sel.Where != nil {
+		b.WriteString(" WHERE " + t.expr(sel.Where))
+	}
+	if len(sel.GroupBy) > 0 {
+		b.WriteString(" GROUP BY " + t.list(sel.GroupBy))
+	}
+	if sel.Having != nil {
+		b.WriteString(" HAVING " + t.expr(sel.Having))
+	}
+	if len(sel.WindowDefs) > 0 {
+		defs := make([]string, len(sel.WindowDefs))
+		for i, w := range sel.WindowDefs {
+			defs[i] = t.ident(w.Name) + " AS (" + t.window(w.Spec) + ")"
+		}
+		b.WriteString(" WINDOW " + strings.Join(defs, ", "))
+	}
+	if sel.ForClause != nil {
+		t.unsupported(sel.ForClause.Token, "FOR %s", strings.ToUpper(sel.ForClause.ForType))
+	}
+	for _, o := range sel.Options {
+		t.unsupported(sel.Token, "query hint OPTION (%s)", strings.ToUpper(o.Name))
+	}
+	return b.String()
+}
+
+// selectColumns translates a select list. alias = expr is the T-SQL
+// spelling of expr AS alias.
+func (t *sqlite) selectColumns(cols []ast.SelectColumn) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		if c.AllColumns {
+			parts[i] = "*"
+			continue
+		}
+		expr, alias := c.Expression, ""
+		if c.Alias != nil {
+			alias = c.Alias.Value
+		} else if eq, ok := expr.(*ast.InfixExpression); ok && eq.Operator == "=" {
+			switch left := eq.Left.(type) {
+			case *ast.Identifier:
+				expr, alias = eq.Right, left.Value
+			case *ast.StringLiteral:
+				expr, alias = eq.Right, left.Value
+			}
+		}
+		parts[i] = t.expr(expr)
+		if alias != "" {
+			parts[i] += " AS " + t.ident(alias)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// orderAndLimit translates ORDER BY, TOP and OFFSET/FETCH. SQLite only
+// takes an OFFSET after a LIMIT, where -1 means no limit.
+func (t *sqlite) orderAndLimit(sel *ast.SelectStatement, top *ast.TopClause) string {
+	var b strings.Builder
+	if len(sel.OrderBy) > 0 {
+		b.WriteString(" ORDER BY " + t.orderBy(sel.OrderBy))
+	}
+	switch {
+	case top != nil && top.Percent:
+		t.unsupported(sel.Token, "TOP ... PERCENT")
+	case top != nil && top.WithTies:
+		t.unsupported(sel.Token, "TOP ... WITH TIES")
+	case top != nil:
+		b.WriteString(" LIMIT " + t.expr(top.Count))
+	case sel.Fetch != nil:
+		b.WriteString(" LIMIT " + t.expr(sel.Fetch))
+	case sel.Offset != nil:
+		b.WriteString(" LIMIT -1")
+	}
+	if sel.Offset != nil {
+		b.WriteString(" OFFSET " + t.expr(sel.Offset))
+	}
+	return b.String()
+}
+
+func (t *sqlite) from(refs []ast.TableReference) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = t.tableRef(ref)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (t *sqlite) alias(alias *ast.Identifier) string {
+	if alias == nil {
+		return ""
+	}
+	return " AS " + t.ident(alias.Value)
+}
+
+func (t *sqlite) idents(ids []*ast.Identifier) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = t.ident(id.Value)
+	}
+	return strings.Join(names, ", ")
+}
+
+// tableRef translates an item of a FROM clause.
+func (t *sqlite) tableRef(ref ast.TableReference) string {
+	switch x := ref.(type) {
+	case *ast.TableName:
+		t.hints(x.Token, x.Hints)
+		if x.TemporalClause != nil {
+			t.unsupported(x.TemporalClause.Token, "FOR SYSTEM_TIME")
+		}
+		if x.TableSample != nil {
+			t.unsupported(x.Token, "TABLESAMPLE")
+		}
+		return t.table(x.Name) + t.alias(x.Alias)
+	case *ast.DerivedTable:
+		if len(x.ColumnAliases) > 0 {
+			t.unsupported(x.Token, "column aliases of a derived table")
+		}
+		return "(" + t.query(x.Subquery) + ")" + t.alias(x.Alias)
+	case *ast.ValuesTable:
+		// SQLite names the columns of VALUES column1, column2, ...; a
+		// compound SELECT can name them
+		rows := make([]string, len(x.Rows))
+		for i, row := range x.Rows {
+			values := make([]string, len(row))
+			for j, v := range row {
+				values[j] = t.expr(v)
+				if i == 0 && j < len(x.Columns) {
+					values[j] += " AS " + t.ident(x.Columns[j].Value)
+				}
+			}
+			rows[i] = "SELECT " + strings.Join(values, ", ")
+		}
+		return "(" + strings.Join(rows, " UNION ALL ") + ")" + t.alias(x.Alias)
+	case *ast.ParenthesizedTableRef:
+		return "(" + t.tableRef(x.Inner) + ")"
+	case *ast.JoinClause:
+		if x.Hint != "" {
+			t.unsupported(x.Token, "join hint %s", strings.ToUpper(x.Hint))
+		}
+		typ := strings.ToUpper(x.Type)
+		if strings.HasSuffix(typ, "APPLY") {
+			t.unsupported(x.Token, "%s", typ)
+			return ""
+		}
+		left, right := t.tableRef(x.Left), t.tableRef(x.Right)
+		switch typ {
+		case "CROSS":
+			return left + " CROSS JOIN " + right
+		case "INNER", "LEFT", "RIGHT", "FULL":
+			return left + " " + typ + " JOIN " + right + " ON " + t.expr(x.Condition)
+		default:
+			t.unsupported(x.Token, "%s", typ)
+			return ""
+		}
+	case *ast.TableValuedFunction:
+		t.unsupported(x.Token, "table function %s", x.Function.String())
+		return ""
+	}
+	t.unsupported(t.at, "%s", nodeName(ref, ""))
+	return ""
+}
+
+// hints checks table hints. NOLOCK only asks for reads that do not wait
+// for writers, which SQLite's transactions give, and is dropped.
+func (t *sqlite) hints(tok token.Token, hints []string) {
+	for _, h := range hints {
+		switch strings.ToUpper(h) {
+		case "NOLOCK", "READUNCOMMITTED":
+		default:
+			t.unsupported(tok, "table hint %s", strings.ToUpper(h))
+		}
+	}
+}
+
+// with translates common table expressions. SQLite does not need the
+// RECURSIVE keyword to allow a CTE to refer to itself.
+func (t *sqlite) with(x *ast.WithStatement) string {
+	defs := make([]string, len(x.CTEs))
+	for i, cte := range x.CTEs {
+		defs[i] = t.ident(cte.Name.Value)
+		if len(cte.Columns) > 0 {
+			defs[i] += " (" + t.idents(cte.Columns) + ")"
+		}
+		defs[i] += " AS (" + t.query(cte.Query) + ")"
+	}
+	with := "WITH " + strings.Join(defs, ", ") + " "
+	if sel, ok := x.Query.(*ast.SelectStatement); ok && sel.Into != nil {
+		return t.selectInto(sel, with)
+	}
+	return with + t.statement(x.Query)
+}
+
+func (t *sqlite) insert(x *ast.InsertStatement) string {
+	if x.Top != nil {
+		t.unsupported(x.Token, "INSERT TOP")
+	}
+	t.hints(x.Token, x.Hints)
+	var b strings.Builder
+	b.WriteString("INSERT INTO " + t.table(x.Table))
+	if len(x.Columns) > 0 {
+		b.WriteString(" (" + t.idents(x.Columns) + ")")
+	}
+	switch {
+	case x.DefaultValues:
+		b.WriteString(" DEFAULT VALUES")
+	case x.Select != nil:
+		b.WriteString(" " + t.query(x.Select))
+	case len(x.Values) > 0:
+		rows := make([]string, len(x.Values))
+		for i, row := range x.Values {
+			for _, v := range row {
+				if id, ok := v.(*ast.Identifier); ok && strings.EqualFold(id.Value, "DEFAULT") {
+					t.unsupported(id.Token, "DEFAULT in VALUES")
+				}
+			}
+			rows[i] = "(" + t.list(row) + ")"
+		}
+		b.WriteString(" VALUES " + strings.Join(rows, ", "))
+	default:
+		t.unsupported(x.Token, "INSERT ... EXEC")
+	}
+	b.WriteString(t.returning(x.Output, map[string]string{"inserted": ""}))
+	return b.String()
+}
+
+// returning translates an OUTPUT clause to RETURNING, which can only
+// return the rows to the caller.
+func (t *sqlite) returning(o *ast.OutputClause, pseudo map[string]string) string {
+	if o == nil {
+		return ""
+	}
+	if o.Into != nil || o.IntoVariable != nil {
+		t.unsupported(t.at, "OUTPUT ... INTO")
+	}
+	t.pseudo = pseudo
+	s := " RETURNING " + t.selectColumns(o.Columns)
+	t.pseudo = nil
+	return s
+}
+
+// update translates UPDATE. With a FROM clause the target is taken out
+// of the joins, as UPDATE ... FROM in SQLite lists only the other tables.
+func (t *sqlite) update(x *ast.UpdateStatement) string {
+	switch {
+	case x.Table == nil:
+		t.unsupported(x.Token, "UPDATE of a rowset function")
+		return ""
+	case x.Top != nil:
+		t.unsupported(x.Token, "UPDATE TOP")
+	case x.CurrentOfCursor != nil:
+		t.unsupported(x.Token, "WHERE CURRENT OF")
+	}
+	t.hints(x.Token, x.Hints)
+	table := t.table(x.Table) + t.alias(x.Alias)
+	var from string
+	var conds []string
+	if x.From != nil {
+		tn, rest, joins, ok := splitTarget(x.Table, x.From)
+		if !ok {
+			t.unsupported(x.Token, "UPDATE of an outer-joined table")
+			return ""
+		}
+		if tn != nil {
+			table = t.table(tn.Name) + t.alias(tn.Alias)
+		}
+		from = t.from(rest)
+		for _, j := range joins {
+			conds = append(conds, t.operand(j, sqlitePrecedenceAnd))
+		}
+	}
+	if x.Where != nil {
+		conds = append(conds, t.operand(x.Where, sqlitePrecedenceAnd))
+	}
+
+	sets := make([]string, len(x.SetClauses))
+	for i, sc := range x.SetClauses {
+		column := sc.Column.Parts[len(sc.Column.Parts)-1]
+		switch {
+		case sc.IsMethodCall:
+			t.unsupported(column.Token, "method call in UPDATE SET")
+		case strings.HasPrefix(column.Value, "@"):
+			t.unsupported(column.Token, "assigning variables in UPDATE")
+		}
+		value := sc.Value
+		if sc.Operator != "=" && sc.Operator != "" {
+			value = &ast.InfixExpression{
+				Token:    column.Token,
+				Left:     &ast.Identifier{Token: column.Token, Value: column.Value},
+				Operator: strings.TrimSuffix(sc.Operator, "="),
+				Right:    value,
+			}
+		}
+		sets[i] = t.ident(column.Value) + " = " + t.expr(value)
+	}
+
+	s := "UPDATE " + table + " SET " + strings.Join(sets, ", ")
+	if from != "" {
+		s += " FROM " + from
+	}
+	if len(conds) > 0 {
+		s += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return s + t.returning(x.Output, map[string]string{"inserted": ""})
+}
+
+// delete translates DELETE. SQLite's DELETE has no FROM clause of its
+// own, so a DELETE with one deletes the rowids the joins select.
+func (t *sqlite) delete(x *ast.DeleteStatement) string {
+	name, alias := x.Table, x.Alias
+	if name == nil && alias != nil {
+		// The parser reads the target of DELETE alias FROM ... and of
+		// DELETE t OUTPUT ... as an alias
+		name, alias = &ast.QualifiedIdentifier{Parts: []*ast.Identifier{alias}}, nil
+	}
+	switch {
+	case name == nil:
+		t.unsupported(x.Token, "DELETE from a rowset function")
+		return ""
+	case x.Top != nil:
+		t.unsupported(x.Token, "DELETE TOP")
+	case x.CurrentOfCursor != nil:
+		t.unsupported(x.Token, "WHERE CURRENT OF")
+	}
+	t.hints(x.Token, x.Hints)
+	returning := t.returning(x.Output, map[string]string{"deleted": ""})
+	if x.From == nil {
+		s := "DELETE FROM " + t.table(name) + t.alias(alias)
+		if x.Where != nil {
+			s += " WHERE " + t.expr(x.Where)
+		}
+		return s + returning
+	}
+
+	// The target is found in FROM by its alias or name; a target FROM
+	// does not list is joined with it
+	table, qualifier := name, t.table(name)
+	from := t.from(x.From.Tables)
+	var found *ast.TableName
+	for _, ref := range x.From.Tables {
+		if tn := findTable(ref, name); tn != nil {
+			found = tn
+			break
+		}
+	}
+	switch {
+	case found == nil:
+		from = t.table(name) + ", " + from
+	case found.Alias != nil:
+		table, qualifier = found.Name, t.ident(found.Alias.Value)
+	default:
+		table = found.Name
+	}
+	s := "DELETE FROM " + t.table(table) + " WHERE rowid IN (SELECT " + qualifier + ".rowid FROM " + from
+	if x.Where != nil {
+		s += " WHERE " + t.expr(x.Where)
+	}
+	return s + ")" + returning
+}
@@ -0,0 +1,701 @@
+package translate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// expr translates an expression to SQLite.
+func (t *sqlite) expr(e ast.Expression) string {
+	switch x := e.(type) {
+	case nil:
+		return ""
+	case *ast.Identifier:
+		return t.identifier(x)
+	case *ast.QualifiedIdentifier:
+		return t.column(x)
+	case *ast.Variable:
+		if strings.HasPrefix(x.Name, "@@") {
+			t.unsupported(x.Token, "%s", strings.ToUpper(x.Name))
+		}
+		return x.Name
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(x.Value, 10)
+	case *ast.FloatLiteral:
+		return x.Token.Literal
+	case *ast.MoneyLiteral:
+		return strings.TrimPrefix(x.Value, "$")
+	case *ast.StringLiteral:
+		return quoteString(x.Value)
+	case *ast.BinaryLiteral:
+		return "X'" + x.Value[2:] + "'"
+	case *ast.NullLiteral:
+		return "NULL"
+	case *ast.PrefixExpression:
+		op := strings.ToUpper(x.Operator)
+		if op == "NOT" {
+			return "NOT " + t.operand(x.Right, sqlitePrecedenceNot)
+		}
+		return op + t.operand(x.Right, sqlitePrecedenceUnary)
+	case *ast.InfixExpression:
+		return t.infix(x)
+	case *ast.BetweenExpression:
+		return t.operand(x.Expr, sqlitePrecedenceCompare+1) + not(x.Not) + " BETWEEN " +
+			t.operand(x.Low, sqlitePrecedenceCompare+1) + " AND " + t.operand(x.High, sqlitePrecedenceCompare+1)
+	case *ast.InExpression:
+		list := ""
+		if x.Subquery != nil {
+			list = t.query(x.Subquery)
+		} else {
+			list = t.list(x.Values)
+		}
+		return t.operand(x.Expr, sqlitePrecedenceCompare+1) + not(x.Not) + " IN (" + list + ")"
+	case *ast.LikeExpression:
+		s := t.operand(x.Expr, sqlitePrecedenceCompare+1) + not(x.Not) + " LIKE " + t.operand(x.Pattern, sqlitePrecedenceCompare+1)
+		if x.Escape != nil {
+			s += " ESCAPE " + t.expr(x.Escape)
+		}
+		return s
+	case *ast.IsNullExpression:
+		if x.Not {
+			return t.operand(x.Expr, sqlitePrecedenceCompare+1) + " IS NOT NULL"
+		}
+		return t.operand(x.Expr, sqlitePrecedenceCompare+1) + " IS NULL"
+	case *ast.IsDistinctFromExpression:
+		// IS and IS NOT compare NULLs as values
+		op := " IS NOT "
+		if x.Not {
+			op = " IS "
+		}
+		return t.operand(x.Left, sqlitePrecedenceCompare+1) + op + t.operand(x.Right, sqlitePrecedenceCompare+1)
+	case *ast.ExistsExpression:
+		return "EXISTS (" + t.query(x.Subquery) + ")"
+	case *ast.SubqueryExpression:
+		return "(" + t.query(x.Subquery) + ")"
+	case *ast.SelectStatement:
+		return "(" + t.query(x) + ")"
+	case *ast.TupleExpression:
+		return "(" + t.list(x.Elements) + ")"
+	case *ast.CaseExpression:
+		var b strings.Builder
+		b.WriteString("CASE")
+		if x.Operand != nil {
+			b.WriteString(" " + t.expr(x.Operand))
+		}
+		for _, w := range x.WhenClauses {
+			b.WriteString(" WHEN " + t.expr(w.Condition) + " THEN " + t.expr(w.Result))
+		}
+		if x.ElseClause != nil {
+			b.WriteString(" ELSE " + t.expr(x.ElseClause))
+		}
+		b.WriteString(" END")
+		return b.String()
+	case *ast.CastExpression:
+		if x.IsTry {
+			t.unsupported(x.Token, "TRY_CAST")
+		}
+		return t.cast(x.Token, t.expr(x.Expression), x.TargetType)
+	case *ast.ConvertExpression:
+		return t.convert(x)
+	case *ast.TrimExpression:
+		if x.Characters == nil {
+			return "trim(" + t.expr(x.Expression) + ")"
+		}
+		fn := map[string]string{"": "trim", "BOTH": "trim", "LEADING": "ltrim", "TRAILING": "rtrim"}[strings.ToUpper(x.TrimSpec)]
+		return fn + "(" + t.expr(x.Expression) + ", " + t.expr(x.Characters) + ")"
+	case *ast.FunctionCall:
+		return t.function(x)
+	case *ast.MethodCallExpression:
+		t.unsupported(x.Token, "function %s", x.String())
+		return ""
+	case *ast.CollateExpression:
+		t.unsupported(x.Token, "COLLATE %s", x.Collation)
+		return ""
+	}
+	t.unsupported(t.at, "%s", nodeName(e, "Expression"))
+	return ""
+}
+
+func (t *sqlite) list(exprs []ast.Expression) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = t.expr(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Operator precedence in SQLite, lowest first. || binds more tightly
+// than multiplication.
+const (
+	sqlitePrecedenceOr = iota + 1
+	sqlitePrecedenceAnd
+	sqlitePrecedenceNot
+	sqlitePrecedenceCompare
+	sqlitePrecedenceBitwise
+	sqlitePrecedenceAdd
+	sqlitePrecedenceMultiply
+	sqlitePrecedenceConcat
+	sqlitePrecedenceUnary
+)
+
+func sqlitePrecedence(op string) int {
+	switch op {
+	case "OR":
+		return sqlitePrecedenceOr
+	case "AND":
+		return sqlitePrecedenceAnd
+	case "=", "<>", "!=", "<", ">", "<=", ">=":
+		return sqlitePrecedenceCompare
+	case "&", "|", "<<", ">>":
+		return sqlitePrecedenceBitwise
+	case "+", "-":
+		return sqlitePrecedenceAdd
+	case "*", "/", "%":
+		return sqlitePrecedenceMultiply
+	case "||":
+		return sqlitePrecedenceConcat
+	}
+	return sqlitePrecedenceUnary
+}
+
+// operand translates e as an operand of an operator of precedence p,
+// parenthesising it when it binds less tightly.
+func (t *sqlite) operand(e ast.Expression, p int) string {
+	s := t.expr(e)
+	inner := sqlitePrecedenceUnary
+	switch x := e.(type) {
+	case *ast.InfixExpression:
+		inner = sqlitePrecedence(sqliteOperator(x))
+		if x.Operator == "^" {
+			inner = sqlitePrecedenceAdd
+		}
+	case *ast.PrefixExpression:
+		if strings.EqualFold(x.Operator, "NOT") {
+			inner = sqlitePrecedenceNot
+		}
+	case *ast.BetweenExpression, *ast.InExpression, *ast.LikeExpression, *ast.IsNullExpression,
+		*ast.IsDistinctFromExpression:
+		inner = sqlitePrecedenceCompare
+	}
+	if inner < p {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// sqliteOperator returns the SQLite operator of an infix expression.
+func sqliteOperator(x *ast.InfixExpression) string {
+	op := strings.ToUpper(x.Operator)
+	switch op {
+	case "+":
+		if isText(x.Left, nil) || isText(x.Right, nil) {
+			return "||"
+		}
+	case "!<":
+		return ">="
+	case "!>":
+		return "<="
+	}
+	return op
+}
+
+func (t *sqlite) infix(x *ast.InfixExpression) string {
+	op := sqliteOperator(x)
+	if op == "^" {
+		// SQLite has no exclusive or
+		a, b := t.operand(x.Left, sqlitePrecedenceBitwise+1), t.operand(x.Right, sqlitePrecedenceBitwise+1)
+		return "(" + a + " | " + b + ") - (" + a + " & " + b + ")"
+	}
+	p := sqlitePrecedence(op)
+	right := p
+	if op != "AND" && op != "OR" && op != "+" && op != "*" && op != "||" {
+		// Not associative: a - (b - c) needs its parentheses
+		right++
+	}
+	return t.operand(x.Left, p) + " " + op + " " + t.operand(x.Right, right)
+}
+
+func (t *sqlite) identifier(x *ast.Identifier) string {
+	switch strings.ToUpper(x.Value) {
+	case "*":
+		return "*"
+	case "CURRENT_TIMESTAMP":
+		// SQLite's CURRENT_TIMESTAMP is in UTC
+		return "datetime('now', 'localtime')"
+	case "DEFAULT", "CURRENT_USER", "USER", "SESSION_USER", "SYSTEM_USER":
+		t.unsupported(x.Token, "%s", strings.ToUpper(x.Value))
+		return ""
+	}
+	if t.pseudo != nil {
+		if _, ok := t.pseudo[strings.ToLower(x.Value)]; ok {
+			t.unsupported(x.Token, "%s without a column", x.Value)
+		}
+	}
+	return t.ident(x.Value)
+}
+
+// column translates a column reference. In a RETURNING clause the
+// inserted or deleted pseudo-table is the target table.
+func (t *sqlite) column(q *ast.QualifiedIdentifier) string {
+	parts := q.Parts
+	if t.pseudo != nil && len(parts) == 2 {
+		qual := strings.ToLower(parts[0].Value)
+		if qual == "inserted" || qual == "deleted" {
+			if _, ok := t.pseudo[qual]; !ok {
+				t.unsupported(parts[0].Token, "returning %s values from this statement", qual)
+			}
+			return t.identOrStar(parts[1].Value)
+		}
+	}
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		names[i] = t.identOrStar(p.Value)
+	}
+	return strings.Join(names, ".")
+}
+
+func (t *sqlite) identOrStar(name string) string {
+	if name == "*" {
+		return name
+	}
+	return t.ident(name)
+}
+
+// ident writes a name, quoting it when SQLite requires it.
+func (t *sqlite) ident(name string) string {
+	if isPlainName(name) && !sqliteKeywords[strings.ToUpper(name)] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteKeywords holds SQLite's keywords, which are quoted when used as
+// names.
+var sqliteKeywords = map[string]bool{
+	"ABORT": true, "ACTION": true, "ADD": true, "AFTER": true, "ALL": true, "ALTER": true,
+	"ALWAYS": true, "ANALYZE": true, "AND": true, "AS": true, "ASC": true, "ATTACH": true,
+	"AUTOINCREMENT": true, "BEFORE": true, "BEGIN": true, "BETWEEN": true, "BY": true,
+	"CASCADE": true, "CASE": true, "CAST": true, "CHECK": true, "COLLATE": true,
+	"COLUMN": true, "COMMIT": true, "CONFLICT": true, "CONSTRAINT": true, "CREATE": true,
+	"CROSS": true, "CURRENT": true, "CURRENT_DATE": true, "CURRENT_TIME": true,
+	"CURRENT_TIMESTAMP": true, "DATABASE": true, "DEFAULT": true, "DEFERRABLE": true,
+	"DEFERRED": true, "DELETE": true, "DESC": true, "DETACH": true, "DISTINCT": true,
+	"DO": true, "DROP": true, "EACH": true, "ELSE": true, "END": true, "ESCAPE": true,
+	"EXCEPT": true, "EXCLUDE": true, "EXCLUSIVE": true, "EXISTS": true, "EXPLAIN": true,
+	"FAIL": true, "FILTER": true, "FIRST": true, "FOLLOWING": true, "FOR": true,
+	"FOREIGN": true, "FROM": true, "FULL": true, "GENERATED": true, "GLOB": true,
+	"GROUP": true, "GROUPS": true, "HAVING": true, "IF": true, "IGNORE": true,
+	"IMMEDIATE": true, "IN": true, "INDEX": true, "INDEXED": true, "INITIALLY": true,
+	"INNER": true, "INSERT": true, "INSTEAD": true, "INTERSECT": true, "INTO": true,
+	"IS": true, "ISNULL": true, "JOIN": true, "KEY": true, "LAST": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "MATCH": true, "MATERIALIZED": true, "NATURAL": true,
+	"NO": true, "NOT": true, "NOTHING": true, "NOTNULL": true, "NULL": true, "NULLS": true,
+	"OF": true, "OFFSET": true, "ON": true, "OR": true, "ORDER": true, "OTHERS": true,
+	"OUTER": true, "OVER": true, "PARTITION": true, "PLAN": true, "PRAGMA": true,
+	"PRECEDING": true, "PRIMARY": true, "QUERY": true, "RAISE": true, "RANGE": true,
+	"RECURSIVE": true, "REFERENCES": true, "REGEXP": true, "REINDEX": true,
+	"RELEASE": true, "RENAME": true, "REPLACE": true, "RESTRICT": true, "RETURNING": true,
+	"RIGHT": true, "ROLLBACK": true, "ROW": true, "ROWS": true, "SAVEPOINT": true,
+	"SELECT": true, "SET": true, "TABLE": true, "TEMP": true, "TEMPORARY": true,
+	"THEN": true, "TIES": true, "TO": true, "TRANSACTION": true, "TRIGGER": true,
+	"UNBOUNDED": true, "UNION": true, "UNIQUE": true, "UPDATE": true, "USING": true,
+	"VACUUM": true, "VALUES": true, "VIEW": true, "VIRTUAL": true, "WHEN": true,
+	"WHERE": true, "WINDOW": true, "WITH": true, "WITHOUT": true,
+}
+
+// table translates the name of a table. Other schemas than dbo are kept
+// as the names of attached databases.
+func (t *sqlite) table(q *ast.QualifiedIdentifier) string {
+	var parts []*ast.Identifier
+	for _, p := range q.Parts {
+		if p.Value != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) > 2 {
+		t.unsupported(parts[0].Token, "reference to another database or server in %s", q.String())
+		return ""
+	}
+	if len(parts) == 2 && strings.EqualFold(parts[0].Value, "dbo") {
+		parts = parts[1:]
+	}
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		name := p.Value
+		switch {
+		case strings.HasPrefix(name, "##"):
+			name = name[2:]
+		case strings.HasPrefix(name, "#"):
+			name = name[1:]
+		case strings.HasPrefix(name, "@"):
+			t.unsupported(p.Token, "table variable %s", name)
+		}
+		names[i] = t.ident(name)
+	}
+	return strings.Join(names, ".")
+}
+
+// cast translates a conversion of the SQL v to dt. Dates become the
+// text SQLite's date functions produce, and string lengths truncate as
+// they do in T-SQL.
+func (t *sqlite) cast(tok token.Token, v string, dt *ast.DataType) string {
+	length := -1
+	switch {
+	case dt.Max:
+	case dt.Precision != nil:
+		length = *dt.Precision
+	case dt.Length != nil:
+		length = *dt.Length
+	}
+	switch strings.ToUpper(dt.Name) {
+	case "INT", "INTEGER", "BIGINT", "SMALLINT", "TINYINT":
+		return "CAST(" + v + " AS INTEGER)"
+	case "BIT":
+		return "(CAST(" + v + " AS INTEGER) <> 0)"
+	case "DECIMAL", "NUMERIC", "DEC", "MONEY", "SMALLMONEY":
+		scale := 0
+		switch {
+		case dt.Scale != nil:
+			scale = *dt.Scale
+		case strings.HasSuffix(strings.ToUpper(dt.Name), "MONEY"):
+			scale = 4
+		}
+		if scale == 0 {
+			return "CAST(round(CAST(" + v + " AS REAL)) AS INTEGER)"
+		}
+		return "round(CAST(" + v + " AS REAL), " + strconv.Itoa(scale) + ")"
+	case "FLOAT", "REAL":
+		return "CAST(" + v + " AS REAL)"
+	case "CHAR", "VARCHAR", "NCHAR", "NVARCHAR", "TEXT", "NTEXT", "SYSNAME", "UNIQUEIDENTIFIER":
+		s := "CAST(" + v + " AS TEXT)"
+		if length < 0 && !dt.Max && strings.Contains(strings.ToUpper(dt.Name), "CHAR") {
+			length = 30
+		}
+		if length >= 0 {
+			s = "substr(" + s + ", 1, " + strconv.Itoa(length) + ")"
+		}
+		return s
+	case "DATE":
+		return "date(" + v + ")"
+	case "DATETIME", "DATETIME2", "SMALLDATETIME":
+		return "datetime(" + v + ")"
+	case "TIME":
+		return "time(" + v + ")"
+	case "BINARY", "VARBINARY":
+		return "CAST(" + v + " AS BLOB)"
+	}
+	t.unsupported(tok, "conversion to %s", strings.ToUpper(dt.Name))
+	return ""
+}
+
+// convert translates CONVERT. The date styles people use to format
+// dates as strings become strftime formats.
+func (t *sqlite) convert(x *ast.ConvertExpression) string {
+	if x.IsTry {
+		t.unsupported(x.Token, "TRY_CONVERT")
+	}
+	v := t.expr(x.Expression)
+	if x.Style == nil {
+		return t.cast(x.Token, v, x.TargetType)
+	}
+	style, ok := x.Style.(*ast.IntegerLiteral)
+	format, known := "", false
+	if ok && isStringType(x.TargetType) {
+		format, known = sqliteDateStyles[style.Value]
+	}
+	if !known {
+		t.unsupported(x.Token, "CONVERT style %s", x.Style.String())
+		return ""
+	}
+	return t.cast(x.Token, "strftime("+quoteString(format)+", "+v+")", x.TargetType)
+}
+
+// sqliteDateStyles maps CONVERT styles to strftime formats.
+var sqliteDateStyles = map[int64]string{
+	101: "%m/%d/%Y",
+	102: "%Y.%m.%d",
+	103: "%d/%m/%Y",
+	104: "%d.%m.%Y",
+	108: "%H:%M:%S",
+	111: "%Y/%m/%d",
+	112: "%Y%m%d",
+	120: "%Y-%m-%d %H:%M:%S",
+	121: "%Y-%m-%d %H:%M:%f",
+	126: "%Y-%m-%dT%H:%M:%f",
+	23:  "%Y-%m-%d",
+}
+
+// function translates a function call.
+func (t *sqlite) function(x *ast.FunctionCall) string {
+	name := strings.ToUpper(functionName(x))
+	tok := x.Token
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		tok = id.Token
+	}
+	if q, ok := x.Function.(*ast.QualifiedIdentifier); ok && len(q.Parts) > 1 {
+		t.unsupported(tok, "function %s", q.String())
+		return ""
+	}
+	args := x.Arguments
+	arg := func(i int) string {
+		return t.expr(args[i])
+	}
+	if n, ok := arity[name]; ok && (len(args) < n[0] || len(args) > n[1]) {
+		t.unsupported(tok, "%s with %d arguments", name, len(args))
+		return ""
+	}
+	if len(x.WithinGroup) > 0 {
+		t.unsupported(tok, "%s WITHIN GROUP", name)
+	}
+
+	switch name {
+	case "ISNULL":
+		return "ifnull(" + t.list(args) + ")"
+	case "GETDATE", "SYSDATETIME":
+		return "datetime('now', 'localtime')"
+	case "GETUTCDATE", "SYSUTCDATETIME":
+		return "datetime('now')"
+	case "LEN":
+		return "length(rtrim(" + arg(0) + "))"
+	case "SUBSTRING":
+		return "substr(" + t.list(args) + ")"
+	case "LEFT":
+		return "substr(" + arg(0) + ", 1, " + arg(1) + ")"
+	case "RIGHT":
+		return "substr(" + arg(0) + ", -" + t.operand(args[1], sqlitePrecedenceUnary) + ")"
+	case "CHARINDEX":
+		if len(args) == 2 {
+			return "instr(" + arg(1) + ", " + arg(0) + ")"
+		}
+	case "CONCAT":
+		parts := make([]string, len(args))
+		for i := range args {
+			parts[i] = "ifnull(" + arg(i) + ", '')"
+		}
+		return "(" + strings.Join(parts, " || ") + ")"
+	case "REPLICATE":
+		return "replace(hex(zeroblob(" + arg(1) + ")), '00', " + arg(0) + ")"
+	case "SPACE":
+		return "replace(hex(zeroblob(" + arg(0) + ")), '00', ' ')"
+	case "CHAR", "NCHAR":
+		return "char(" + arg(0) + ")"
+	case "ASCII", "UNICODE":
+		return "unicode(" + arg(0) + ")"
+	case "CEILING":
+		return "ceil(" + arg(0) + ")"
+	case "LOG":
+		if len(args) == 2 {
+			return "log(" + arg(1) + ", " + arg(0) + ")"
+		}
+		return "ln(" + arg(0) + ")"
+	case "ROUND":
+		if len(args) == 2 {
+			return "round(" + t.list(args) + ")"
+		}
+	case "IIF":
+		return "CASE WHEN " + arg(0) + " THEN " + arg(1) + " ELSE " + arg(2) + " END"
+	case "COUNT_BIG":
+		name = "COUNT"
+	case "STRING_AGG":
+		return "group_concat(" + arg(0) + ", " + arg(1) + ")" + t.over(x.Over)
+	case "YEAR", "MONTH", "DAY":
+		return t.datePart(x, &ast.Identifier{Token: tok, Value: name}, args[0])
+	case "DATEPART":
+		return t.datePart(x, args[0], args[1])
+	case "DATEADD":
+		return t.dateAdd(x)
+	case "DATEDIFF":
+		return t.dateDiff(x)
+	case "EOMONTH":
+		if len(args) == 1 {
+			return "date(" + arg(0) + ", 'start of month', '+1 month', '-1 day')"
+		}
+	case "DATEFROMPARTS":
+		return "printf('%04d-%02d-%02d', " + t.list(args) + ")"
+	}
+
+	if !sqliteFunctions[name] {
+		t.unsupported(tok, "function %s", name)
+		return ""
+	}
+	s := strings.ToLower(name) + "("
+	if x.Distinct {
+		s += "DISTINCT "
+	}
+	return s + t.list(args) + ")" + t.over(x.Over)
+}
+
+// sqliteFunctions are the T-SQL functions SQLite has under the same
+// name and with the same arguments.
+var sqliteFunctions = map[string]bool{
+	"COALESCE": true, "NULLIF": true, "UPPER": true, "LOWER": true, "LTRIM": true,
+	"RTRIM": true, "TRIM": true, "REPLACE": true, "ABS": true, "SIGN": true,
+	"FLOOR": true, "POWER": true, "SQRT": true, "EXP": true, "LOG10": true, "PI": true,
+	"COUNT": true, "SUM": true, "AVG": true, "MIN": true, "MAX": true,
+	"ROW_NUMBER": true, "RANK": true, "DENSE_RANK": true, "NTILE": true,
+	"PERCENT_RANK": true, "CUME_DIST": true, "LAG": true, "LEAD": true,
+	"FIRST_VALUE": true, "LAST_VALUE": true,
+}
+
+func (t *sqlite) over(o *ast.OverClause) string {
+	if o == nil {
+		return ""
+	}
+	if o.WindowRef != "" {
+		return " OVER " + t.ident(o.WindowRef)
+	}
+	return " OVER (" + t.window(o) + ")"
+}
+
+// window translates the inside of an OVER clause or WINDOW definition.
+func (t *sqlite) window(o *ast.OverClause) string {
+	var parts []string
+	if len(o.PartitionBy) > 0 {
+		parts = append(parts, "PARTITION BY "+t.list(o.PartitionBy))
+	}
+	if len(o.OrderBy) > 0 {
+		parts = append(parts, "ORDER BY "+t.orderBy(o.OrderBy))
+	}
+	if f := o.Frame; f != nil {
+		frame := strings.ToUpper(f.Type) + " "
+		if f.End != nil {
+			frame += "BETWEEN " + t.frameBound(f.Start) + " AND " + t.frameBound(f.End)
+		} else {
+			frame += t.frameBound(f.Start)
+		}
+		parts = append(parts, frame)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (t *sqlite) frameBound(b *ast.FrameBound) string {
+	if b.Offset != nil {
+		return t.expr(b.Offset) + " " + strings.ToUpper(b.Type)
+	}
+	return strings.ToUpper(b.Type)
+}
+
+func (t *sqlite) orderBy(items []*ast.OrderByItem) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		s := t.expr(item.Expression)
+		if item.Descending {
+			s += " DESC"
+		}
+		if item.NullsFirst != nil {
+			if *item.NullsFirst {
+				s += " NULLS FIRST"
+			} else {
+				s += " NULLS LAST"
+			}
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ", ")
+}
+
+// strftimeInt reads a field of a date as an integer.
+func strftimeInt(format, date string) string {
+	return "CAST(strftime('" + format + "', " + date + ") AS INTEGER)"
+}
+
+// datePart translates DATEPART and the functions like it to strftime.
+func (t *sqlite) datePart(x *ast.FunctionCall, partExpr, date ast.Expression) string {
+	part, _ := datePartName(partExpr)
+	d := t.expr(date)
+	switch part {
+	case "year":
+		return strftimeInt("%Y", d)
+	case "quarter":
+		return "(" + strftimeInt("%m", d) + " + 2) / 3"
+	case "month":
+		return strftimeInt("%m", d)
+	case "dayofyear":
+		return strftimeInt("%j", d)
+	case "day":
+		return strftimeInt("%d", d)
+	case "weekday":
+		// Sunday is 1, as with the default DATEFIRST
+		return "(" + strftimeInt("%w", d) + " + 1)"
+	case "hour":
+		return strftimeInt("%H", d)
+	case "minute":
+		return strftimeInt("%M", d)
+	case "second":
+		return strftimeInt("%S", d)
+	}
+	t.unsupported(x.Token, "DATEPART of %s", partExpr.String())
+	return ""
+}
+
+// dateAdd translates DATEADD to a datetime modifier.
+func (t *sqlite) dateAdd(x *ast.FunctionCall) string {
+	part, _ := datePartName(x.Arguments[0])
+	unit, scale := "", ""
+	switch part {
+	case "year", "month", "day", "hour", "minute", "second":
+		unit = part + "s"
+	case "dayofyear", "weekday":
+		unit = "days"
+	case "quarter":
+		unit, scale = "months", " * 3"
+	case "week":
+		unit, scale = "days", " * 7"
+	default:
+		t.unsupported(x.Token, "DATEADD by %s", x.Arguments[0].String())
+		return ""
+	}
+	var modifier string
+	if n, ok := integerValue(x.Arguments[1]); ok && scale == "" {
+		modifier = quoteString(strconv.FormatInt(n, 10) + " " + unit)
+	} else if scale == "" {
+		modifier = t.operand(x.Arguments[1], sqlitePrecedenceConcat) + " || ' " + unit + "'"
+	} else {
+		modifier = "(" + t.operand(x.Arguments[1], sqlitePrecedenceMultiply) + scale + ") || ' " + unit + "'"
+	}
+	return "datetime(" + t.expr(x.Arguments[2]) + ", " + modifier + ")"
+}
+
+// integerValue returns the value of an integer literal, which may be
+// negated.
+func integerValue(e ast.Expression) (int64, bool) {
+	switch x := e.(type) {
+	case *ast.IntegerLiteral:
+		return x.Value, true
+	case *ast.PrefixExpression:
+		if n, ok := x.Right.(*ast.IntegerLiteral); ok && x.Operator == "-" {
+			return -n.Value, true
+		}
+	}
+	return 0, false
+}
+
+// dateDiff translates DATEDIFF, which counts the boundaries of the unit
+// crossed between the two dates.
+func (t *sqlite) dateDiff(x *ast.FunctionCall) string {
+	part, _ := datePartName(x.Arguments[0])
+	a, b := t.expr(x.Arguments[1]), t.expr(x.Arguments[2])
+	switch part {
+	case "year":
+		return "(" + strftimeInt("%Y", b) + " - " + strftimeInt("%Y", a) + ")"
+	case "quarter":
+		return "((" + strftimeInt("%Y", b) + " - " + strftimeInt("%Y", a) + ") * 4 + (" +
+			strftimeInt("%m", b) + " + 2) / 3 - (" + strftimeInt("%m", a) + " + 2) / 3)"
+	case "month":
+		return "((" + strftimeInt("%Y", b) + " - " + strftimeInt("%Y", a) + ") * 12 + " +
+			strftimeInt("%m", b) + " - " + strftimeInt("%m", a) + ")"
+	case "day", "dayofyear":
+		return "CAST(julianday(date(" + b + ")) - julianday(date(" + a + ")) AS INTEGER)"
+	case "hour", "minute", "second":
+		// Truncate both to the unit, then count whole units between them
+		format := map[string]string{"hour": "%Y-%m-%d %H:00:00", "minute": "%Y-%m-%d %H:%M:00", "second": "%Y-%m-%d %H:%M:%S"}[part]
+		perDay := map[string]string{"hour": "24", "minute": "1440", "second": "86400"}[part]
+		return "CAST(round((julianday(strftime('" + format + "', " + b + ")) - julianday(strftime('" + format + "', " + a +
+			"))) * " + perDay + ") AS INTEGER)"
+	}
+	t.unsupported(x.Token, "DATEDIFF in %s", x.Arguments[0].String())
+	return ""
+}
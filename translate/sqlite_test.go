@@ -0,0 +1,107 @@
+package translate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSQLiteStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			"SELECT TOP 10 Id, FirstName + ' ' + LastName AS Name FROM dbo.Customers WITH (NOLOCK) ORDER BY Id",
+			"SELECT Id, FirstName || ' ' || LastName AS Name FROM Customers ORDER BY Id LIMIT 10",
+		},
+		{
+			"SELECT a FROM t ORDER BY a OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+			"SELECT a FROM t ORDER BY a LIMIT 10 OFFSET 20",
+		},
+		{
+			"SELECT a FROM t ORDER BY a OFFSET 20 ROWS",
+			"SELECT a FROM t ORDER BY a LIMIT -1 OFFSET 20",
+		},
+		{
+			"SELECT ISNULL(Note, ''), LEN(Name), GETDATE(), CHARINDEX('x', Name), LEFT(Name, 3) FROM t",
+			"SELECT ifnull(Note, ''), length(rtrim(Name)), datetime('now', 'localtime'), instr(Name, 'x'), substr(Name, 1, 3) FROM t",
+		},
+		{
+			"SELECT YEAR(d), DATEPART(weekday, d), DATEADD(day, -7, d), DATEADD(month, @n, d), DATEDIFF(day, a, b) FROM t",
+			"SELECT CAST(strftime('%Y', d) AS INTEGER), (CAST(strftime('%w', d) AS INTEGER) + 1), datetime(d, '-7 days'), " +
+				"datetime(d, @n || ' months'), CAST(julianday(date(b)) - julianday(date(a)) AS INTEGER) FROM t",
+		},
+		{
+			"SELECT CONVERT(VARCHAR(10), d, 120), CONVERT(CHAR(8), d, 112) FROM t",
+			"SELECT substr(CAST(strftime('%Y-%m-%d %H:%M:%S', d) AS TEXT), 1, 10), substr(CAST(strftime('%Y%m%d', d) AS TEXT), 1, 8) FROM t",
+		},
+		{
+			"SELECT CAST(x AS VARCHAR), CAST(y AS BIT), CAST(z AS DECIMAL(10, 2)), CAST(w AS DATE) FROM t",
+			"SELECT substr(CAST(x AS TEXT), 1, 30), (CAST(y AS INTEGER) <> 0), round(CAST(z AS REAL), 2), date(w) FROM t",
+		},
+		{
+			"SELECT a ^ b, a - (b - c), (a + b) * c FROM t WHERE x !< 3",
+			"SELECT (a | b) - (a & b), a - (b - c), (a + b) * c FROM t WHERE x >= 3",
+		},
+		{
+			"SELECT * INTO #Recent FROM dbo.Orders WHERE Placed > '2024-01-01'",
+			"CREATE TEMP TABLE Recent AS SELECT * FROM Orders WHERE Placed > '2024-01-01'",
+		},
+		{
+			"SELECT v.n FROM (VALUES (1, 'a'), (2, 'b')) AS v (n, s)",
+			"SELECT v.n FROM (SELECT 1 AS n, 'a' AS s UNION ALL SELECT 2, 'b') AS v",
+		},
+		{
+			"INSERT INTO dbo.Orders (CustomerId, Total) OUTPUT inserted.Id VALUES (@customer, 10.5)",
+			"INSERT INTO Orders (CustomerId, Total) VALUES (@customer, 10.5) RETURNING Id",
+		},
+		{
+			"UPDATE o SET Total = s.Total FROM dbo.Orders o JOIN Staging s ON s.Id = o.Id WHERE s.Ready = 1",
+			"UPDATE Orders AS o SET Total = s.Total FROM Staging AS s WHERE s.Id = o.Id AND s.Ready = 1",
+		},
+		{
+			"DELETE o FROM dbo.Orders o JOIN Staging s ON s.Id = o.Id WHERE s.Gone = 1",
+			"DELETE FROM Orders WHERE rowid IN (SELECT o.rowid FROM Orders AS o INNER JOIN Staging AS s ON s.Id = o.Id WHERE s.Gone = 1)",
+		},
+		{
+			"DELETE FROM #Work WHERE [Order] < 5",
+			`DELETE FROM Work WHERE "Order" < 5`,
+		},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		got, err := SQLite(program.Statements[0])
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteUnsupported(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"SELECT a FROM t CROSS APPLY f(t.x) y", "line 1, col 23: CROSS APPLY is not supported by SQLite"},
+		{"SELECT FORMAT(d, 'yyyy') FROM t", "line 1, col 8: function FORMAT is not supported by SQLite"},
+		{"SELECT TOP 10 PERCENT a FROM t", "line 1, col 1: TOP ... PERCENT is not supported by SQLite"},
+		{"SELECT a FROM @items", "line 1, col 15: table variable @items is not supported by SQLite"},
+		{"SELECT @@ROWCOUNT", "line 1, col 8: @@ROWCOUNT is not supported by SQLite"},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		_, err := SQLite(program.Statements[0])
+		var unsupported *UnsupportedError
+		if !errors.As(err, &unsupported) {
+			t.Errorf("%s: got %v, want an UnsupportedError", tt.input, err)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.input, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package translate converts T-SQL into other SQL dialects.
+//
+// Postgres translates a whole script to PostgreSQL. Queries, data
+// modification and DDL become plain SQL; procedures, functions and
+// batches with control flow become PL/pgSQL. Constructs that have no
+// translation are reported as Issues with the position of the T-SQL
+// that caused them, and are left in the output as T-SQL (statements as
+// comments) so that the result never silently differs from the source.
+//
+// Example usage:
+//
+//	program, _ := tsqlparser.Parse(script)
+//	sql, issues := translate.Postgres(program)
+//	for _, issue := range issues {
+//	    fmt.Println(issue)
+//	}
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ha1tch/tsqlparser/token"
+)
+
+// Issue is a construct that could not be translated.
+type Issue struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (i *Issue) String() string {
+	return fmt.Sprintf("line %d, col %d: %s", i.Line, i.Column, i.Message)
+}
+
+// issues collects the Issues of a translation.
+type issues []*Issue
+
+func (is *issues) add(tok token.Token, format string, args ...any) {
+	*is = append(*is, &Issue{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// quoteString returns s as a string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// comment turns untranslated T-SQL into a comment, one line at a time.
+func comment(sql string) string {
+	return "-- " + strings.ReplaceAll(sql, "\n", "\n-- ")
+}
+
+// isPlainName reports whether name can be written without quotes:
+// letters, digits and underscores, not starting with a digit.
+func isPlainName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// nodeName names the kind of an AST node for Issues, such as MERGE or
+// CREATE TRIGGER for statements.
+func nodeName(node any, suffix string) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	name = strings.TrimSuffix(name, suffix)
+	var words []string
+	start := 0
+	for i, c := range name {
+		if i > 0 && unicode.IsUpper(c) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	words = append(words, name[start:])
+	return strings.ToUpper(strings.Join(words, " "))
+}
@@ -0,0 +1,406 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ha1tch/tsqlparser/ast"
+	"github.com/ha1tch/tsqlparser/lexer"
+	"github.com/ha1tch/tsqlparser/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+// toPostgres translates input and fails on any issue.
+func toPostgres(t *testing.T, input string) string {
+	t.Helper()
+	sql, issues := Postgres(parse(t, input))
+	for _, issue := range issues {
+		t.Errorf("unexpected issue: %s", issue)
+	}
+	return strings.TrimSpace(sql)
+}
+
+func TestPostgresStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			"SELECT TOP 10 Id, FirstName + ' ' + LastName AS Name FROM dbo.Customers WITH (NOLOCK) ORDER BY Id",
+			"SELECT Id, FirstName || ' ' || LastName AS Name FROM Customers ORDER BY Id LIMIT 10;",
+		},
+		{
+			"SELECT Total = SUM(Amount), [Order Count] = COUNT(*) FROM Orders",
+			`SELECT sum(Amount) AS Total, count(*) AS "Order Count" FROM Orders;`,
+		},
+		{
+			"SELECT a FROM t ORDER BY a OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY",
+			"SELECT a FROM t ORDER BY a LIMIT 10 OFFSET 20;",
+		},
+		{
+			"SELECT TOP (5) WITH TIES a FROM t ORDER BY a",
+			"SELECT a FROM t ORDER BY a FETCH FIRST 5 ROWS WITH TIES;",
+		},
+		{
+			"SELECT ISNULL(Note, ''), LEN(Name), GETDATE(), CHARINDEX('x', Name), IIF(a > 1, 'y', 'n') FROM t",
+			"SELECT coalesce(Note, ''), length(rtrim(Name)), now(), strpos(Name, 'x'), CASE WHEN a > 1 THEN 'y' ELSE 'n' END FROM t;",
+		},
+		{
+			"SELECT DATEADD(day, -7, GETDATE()), DATEDIFF(day, a, b), CONVERT(VARCHAR(10), d, 120) FROM t",
+			"SELECT (now() + -7 * interval '1 day'), (CAST(b AS date) - CAST(a AS date)), CAST(to_char(d, 'YYYY-MM-DD HH24:MI:SS') AS varchar(10)) FROM t;",
+		},
+		{
+			"SELECT CAST(x AS VARCHAR), CAST(y AS BIT), CAST(z AS DATETIME2) FROM t",
+			"SELECT CAST(x AS varchar(30)), CAST(y AS smallint), CAST(z AS timestamp(6)) FROM t;",
+		},
+		{
+			"SELECT s.value FROM dbo.Docs d CROSS APPLY STRING_SPLIT(d.Tags, ',') s",
+			"SELECT s.value FROM Docs AS d CROSS JOIN LATERAL unnest(string_to_array(d.Tags, ',')) AS s (value);",
+		},
+		{
+			"SELECT d.Id, z.x FROM d OUTER APPLY (SELECT TOP 1 x FROM e WHERE e.Id = d.Id ORDER BY x) z",
+			"SELECT d.Id, z.x FROM d LEFT JOIN LATERAL (SELECT x FROM e WHERE e.Id = d.Id ORDER BY x LIMIT 1) AS z ON true;",
+		},
+		{
+			"WITH n AS (SELECT 1 AS i UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT i FROM n",
+			"WITH RECURSIVE n AS (SELECT 1 AS i UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT i FROM n;",
+		},
+		{
+			"SELECT * INTO #Recent FROM dbo.Orders WHERE Placed > '2024-01-01'",
+			"CREATE TEMPORARY TABLE Recent AS SELECT * FROM Orders WHERE Placed > '2024-01-01';",
+		},
+		{
+			"INSERT INTO #t (a, b) OUTPUT inserted.a VALUES (1, N'x'), (2, DEFAULT)",
+			"INSERT INTO t (a, b) VALUES (1, 'x'), (2, DEFAULT) RETURNING a;",
+		},
+		{
+			"UPDATE o SET o.Total += 1 FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId WHERE c.Vip = 1",
+			"UPDATE Orders AS o SET Total = Total + 1 FROM Customers AS c WHERE c.Id = o.CustomerId AND c.Vip = 1;",
+		},
+		{
+			"DELETE o FROM dbo.Orders o JOIN dbo.Customers c ON c.Id = o.CustomerId WHERE c.Closed = 1",
+			"DELETE FROM Orders AS o USING Customers AS c WHERE c.Id = o.CustomerId AND c.Closed = 1;",
+		},
+		{
+			"DELETE FROM dbo.Queue OUTPUT deleted.Id INTO dbo.Done (Id) WHERE Id < 5",
+			"WITH changed AS (DELETE FROM Queue WHERE Id < 5 RETURNING Queue.Id) INSERT INTO Done (Id) SELECT * FROM changed;",
+		},
+		{
+			"CREATE INDEX IX_Orders ON dbo.Orders (CustomerId DESC) INCLUDE (Total) WHERE Total > 0",
+			"CREATE INDEX IX_Orders ON Orders (CustomerId DESC) INCLUDE (Total) WHERE Total > 0;",
+		},
+		{
+			"DROP PROCEDURE IF EXISTS dbo.GetOrders",
+			"DROP FUNCTION IF EXISTS GetOrders;",
+		},
+		{
+			"SELECT GREATEST(a, 1), LEAST(a, b), DATETRUNC(mm, d), Name || N'!' FROM t WHERE REGEXP_LIKE(Name, '^a', 'i')",
+			"SELECT greatest(a, 1), least(a, b), date_trunc('month', d), Name || '!' FROM t WHERE regexp_like(Name, '^a', 'i');",
+		},
+		{
+			"SELECT REGEXP_REPLACE(Name, '[0-9]+'), REGEXP_SUBSTR(Name, 'a(b)', 1, 1, 'i', 1) FROM t",
+			"SELECT regexp_replace(Name, '[0-9]+', '', 1, 0), regexp_substr(Name, 'a(b)', 1, 1, 'i', 1) FROM t;",
+		},
+		{
+			"SELECT g.value FROM GENERATE_SERIES(10, 1) g",
+			"SELECT g.value FROM generate_series(10, 1, -1) AS g (value);",
+		},
+		{
+			"SET NOCOUNT ON; SET ANSI_NULLS ON; TRUNCATE TABLE dbo.Log",
+			"TRUNCATE TABLE Log;",
+		},
+	}
+	for _, tt := range tests {
+		if got := toPostgres(t, tt.input); got != tt.want {
+			t.Errorf("%s\ngot:  %s\nwant: %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresCreateTable(t *testing.T) {
+	got := toPostgres(t, `CREATE TABLE dbo.Orders (
+    OrderId INT IDENTITY(1,1) NOT NULL CONSTRAINT PK_Orders PRIMARY KEY CLUSTERED,
+    CustomerId INT NOT NULL REFERENCES dbo.Customers (Id) ON DELETE CASCADE,
+    Total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    Note NVARCHAR(MAX) NULL,
+    Placed DATETIME NOT NULL DEFAULT GETDATE(),
+    Code VARCHAR(20),
+    Shipped BIT,
+    [Order Date] DATE,
+    CONSTRAINT CK_Total CHECK (Total >= 0)
+) ON [PRIMARY]`)
+	want := `CREATE TABLE Orders (
+    OrderId integer GENERATED BY DEFAULT AS IDENTITY NOT NULL CONSTRAINT PK_Orders PRIMARY KEY,
+    CustomerId integer NOT NULL REFERENCES Customers (Id) ON DELETE CASCADE,
+    Total numeric(10, 2) NOT NULL DEFAULT 0,
+    Note text NULL,
+    Placed timestamp(3) NOT NULL DEFAULT now(),
+    Code varchar(20),
+    Shipped smallint,
+    "Order Date" date,
+    CONSTRAINT CK_Total CHECK (Total >= 0)
+);`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresProcedure(t *testing.T) {
+	got := toPostgres(t, `CREATE TABLE dbo.Orders (OrderId INT NOT NULL, CustomerId INT NOT NULL, Total DECIMAL(10, 2) NOT NULL)
+GO
+CREATE PROCEDURE dbo.GetOrders @CustomerId INT, @Min DECIMAL(10, 2) = 0
+AS
+BEGIN
+    SET NOCOUNT ON;
+    IF @CustomerId IS NULL
+        THROW 50001, 'Customer required', 1;
+    SELECT OrderId, Total FROM dbo.Orders WHERE CustomerId = @CustomerId AND Total >= @Min;
+END
+GO
+EXEC dbo.GetOrders 1`)
+	want := `CREATE TABLE Orders (
+    OrderId integer NOT NULL,
+    CustomerId integer NOT NULL,
+    Total numeric(10, 2) NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION GetOrders(p_CustomerId integer, p_Min numeric(10, 2) DEFAULT 0)
+RETURNS TABLE (OrderId integer, Total numeric(10, 2))
+LANGUAGE plpgsql
+AS $$
+#variable_conflict use_column
+BEGIN
+    IF p_CustomerId IS NULL THEN
+        RAISE EXCEPTION '%', 'Customer required' USING ERRCODE = '50001';
+    END IF;
+    RETURN QUERY SELECT OrderId, Total FROM Orders WHERE CustomerId = p_CustomerId AND Total >= p_Min;
+END;
+$$;
+
+SELECT * FROM GetOrders(1);`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresOutputParameters(t *testing.T) {
+	got := toPostgres(t, `CREATE PROCEDURE dbo.Bump @Id INT, @Count INT OUTPUT
+AS
+BEGIN
+    UPDATE dbo.Orders SET Total = Total + 1 WHERE OrderId = @Id;
+    SET @Count = @@ROWCOUNT;
+    IF @Count = 0
+        RAISERROR('Order %d not found', 16, 1, @Id);
+END
+GO
+DECLARE @n INT;
+EXEC dbo.Bump @Id = 7, @Count = @n OUTPUT;
+PRINT 'updated ' + CAST(@n AS VARCHAR(10));`)
+	want := `CREATE OR REPLACE FUNCTION Bump(p_Id integer, INOUT p_Count integer)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_rowcount integer;
+BEGIN
+    UPDATE Orders SET Total = Total + 1 WHERE OrderId = p_Id;
+    GET DIAGNOSTICS v_rowcount = ROW_COUNT;
+    p_Count := v_rowcount;
+    IF p_Count = 0 THEN
+        RAISE EXCEPTION 'Order % not found', p_Id;
+    END IF;
+END;
+$$;
+
+DO $$
+DECLARE
+    v_n integer;
+BEGIN
+    SELECT p_Count INTO v_n FROM Bump(p_Id => 7, p_Count => v_n);
+    RAISE NOTICE '%', 'updated ' || CAST(v_n AS varchar(10));
+END;
+$$;`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresProcedureSchemas(t *testing.T) {
+	got := toPostgres(t, `CREATE PROCEDURE dbo.Total @Id INT, @Sum INT OUTPUT
+AS
+BEGIN
+    SELECT @Sum = 1;
+END
+GO
+CREATE PROCEDURE sales.Total @Sum INT OUTPUT
+AS
+BEGIN
+    SELECT @Sum = 2;
+END
+GO
+DECLARE @n INT;
+EXEC dbo.Total 7, @n OUTPUT;
+EXEC sales.Total @n OUTPUT;`)
+	for _, want := range []string{
+		"SELECT p_Sum INTO v_n FROM Total(7, v_n);",
+		"SELECT p_Sum INTO v_n FROM sales.Total(v_n);",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestPostgresControlFlow(t *testing.T) {
+	got := toPostgres(t, `CREATE PROCEDURE dbo.Purge @Days INT
+AS
+BEGIN
+    DECLARE @Cutoff DATETIME = DATEADD(day, -@Days, GETDATE()), @Batches INT = 0;
+    DECLARE @Ids TABLE (Id INT PRIMARY KEY);
+    BEGIN TRY
+        WHILE 1 = 1
+        BEGIN
+            DELETE FROM dbo.Log WHERE Logged < @Cutoff AND Id IN (SELECT Id FROM @Ids);
+            IF @@ROWCOUNT = 0 BREAK;
+            SET @Batches += 1;
+        END
+    END TRY
+    BEGIN CATCH
+        PRINT 'failed: ' + ERROR_MESSAGE();
+        RETURN 1;
+    END CATCH
+END`)
+	want := `CREATE OR REPLACE FUNCTION Purge(p_Days integer)
+RETURNS integer
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_Cutoff timestamp(3);
+    v_Batches integer;
+    v_rowcount integer;
+BEGIN
+    v_Cutoff := (now() + -p_Days * interval '1 day');
+    v_Batches := 0;
+    DROP TABLE IF EXISTS v_Ids;
+    CREATE TEMPORARY TABLE v_Ids (
+        Id integer PRIMARY KEY
+    );
+    BEGIN
+        WHILE 1 = 1 LOOP
+            DELETE FROM Log WHERE Logged < v_Cutoff AND Id IN (SELECT Id FROM v_Ids);
+            GET DIAGNOSTICS v_rowcount = ROW_COUNT;
+            IF v_rowcount = 0 THEN
+                EXIT;
+            END IF;
+            v_Batches := v_Batches + 1;
+        END LOOP;
+    EXCEPTION WHEN OTHERS THEN
+        RAISE NOTICE '%', 'failed: ' || SQLERRM;
+        RETURN 1;
+    END;
+    RETURN 0;
+END;
+$$;`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresFunctions(t *testing.T) {
+	got := toPostgres(t, `CREATE FUNCTION dbo.Twice (@x INT) RETURNS INT AS BEGIN RETURN @x * 2 END
+GO
+CREATE FUNCTION dbo.Since (@d DATE) RETURNS TABLE AS RETURN (SELECT CAST(Id AS INT) AS Id FROM dbo.Log WHERE Logged > @d)
+GO
+CREATE FUNCTION dbo.Pairs () RETURNS @r TABLE (Id INT, Name NVARCHAR(50)) AS
+BEGIN
+    INSERT INTO @r VALUES (1, N'one');
+    RETURN;
+END`)
+	want := `CREATE OR REPLACE FUNCTION Twice(p_x integer)
+RETURNS integer
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    RETURN p_x * 2;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION Since(p_d date)
+RETURNS TABLE (Id integer)
+LANGUAGE sql
+AS $$
+    SELECT CAST(Id AS integer) AS Id FROM Log WHERE Logged > p_d;
+$$;
+
+CREATE OR REPLACE FUNCTION Pairs()
+RETURNS TABLE (Id integer, Name varchar(50))
+LANGUAGE plpgsql
+AS $$
+#variable_conflict use_column
+BEGIN
+    DROP TABLE IF EXISTS v_r;
+    CREATE TEMPORARY TABLE v_r (
+        Id integer,
+        Name varchar(50)
+    );
+    INSERT INTO v_r VALUES (1, 'one');
+    RETURN QUERY SELECT * FROM v_r;
+    RETURN;
+END;
+$$;`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPostgresIssues(t *testing.T) {
+	input := `SELECT a FROM t OPTION (MAXRECURSION 10)
+GO
+MERGE dbo.T USING dbo.S ON T.Id = S.Id WHEN MATCHED THEN DELETE;
+GO
+SET ANSI_NULLS OFF
+GO
+DECLARE @sql NVARCHAR(MAX) = N'SELECT 1';
+EXEC (@sql);
+SELECT @sql = @sql + Name FROM dbo.T;
+SELECT 1;
+GO
+SELECT TOP 10 PERCENT a FROM t ORDER BY a;`
+	sql, issues := Postgres(parse(t, input))
+	want := []string{
+		"line 1, col 1: query hint OPTION (MAXRECURSION) is not supported",
+		"line 3, col 1: MERGE is not supported",
+		"line 5, col 1: SET ANSI_NULLS OFF has no PostgreSQL equivalent",
+		"line 8, col 1: dynamic SQL is not translated and runs as written",
+		"line 9, col 1: accumulating @sql over the rows of a SELECT is not supported",
+		"line 10, col 1: result sets cannot be returned from a DO block",
+		"line 12, col 1: TOP ... PERCENT is not supported",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].String() != w {
+			t.Errorf("issue %d = %q, want %q", i, issues[i].String(), w)
+		}
+	}
+	for _, s := range []string{
+		"-- MERGE INTO dbo.T",
+		"-- SET ANSI_NULLS OFF",
+		"    EXECUTE v_sql;",
+		"    -- SELECT 1",
+		"-- SELECT TOP 10 PERCENT a FROM t ORDER BY a",
+	} {
+		if !strings.Contains(sql, s) {
+			t.Errorf("translation does not contain %q:\n%s", s, sql)
+		}
+	}
+}
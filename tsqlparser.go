@@ -28,6 +28,15 @@ func Parse(input string) (*ast.Program, []string) {
 	return program, p.Errors()
 }
 
+// ParseDialect parses T-SQL code written for the given dialect, such as
+// parser.Synapse, and returns the AST and any errors.
+func ParseDialect(input string, dialect parser.Dialect) (*ast.Program, []string) {
+	p := parser.New(lexer.New(input))
+	p.SetDialect(dialect)
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
 // Tokenize returns all tokens from the input.
 func Tokenize(input string) []token.Token {
 	return lexer.Tokenize(input)